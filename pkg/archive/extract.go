@@ -0,0 +1,111 @@
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ExtractTarGz extracts a repository tar.gz archive into dest. The single
+// top-level directory that GitLab and GitHub add to archives
+// (e.g. repo-v1.2.3-<sha>/) is stripped. When paths is not empty, only files
+// under those repository paths are extracted. It returns the number of files
+// written.
+func ExtractTarGz(r io.Reader, dest string, paths []string) (int, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer gz.Close()
+
+	absDest, err := filepath.Abs(dest)
+	if err != nil {
+		return 0, err
+	}
+
+	extracted := 0
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return extracted, fmt.Errorf("failed to read archive: %w", err)
+		}
+
+		name := stripTopLevel(header.Name)
+		if name == "" || !selected(name, paths) {
+			continue
+		}
+
+		target := filepath.Join(absDest, filepath.FromSlash(name))
+		if target != absDest && !strings.HasPrefix(target, absDest+string(os.PathSeparator)) {
+			return extracted, fmt.Errorf("archive entry escapes destination: %s", header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return extracted, err
+			}
+		case tar.TypeReg:
+			if err := writeFile(target, tr, header.FileInfo().Mode().Perm()); err != nil {
+				return extracted, fmt.Errorf("failed to extract %s: %w", name, err)
+			}
+			extracted++
+		default:
+			// Symlinks and other special entries are skipped on purpose
+		}
+	}
+
+	return extracted, nil
+}
+
+// stripTopLevel removes the first path component of an archive entry
+func stripTopLevel(name string) string {
+	name = path.Clean(strings.TrimPrefix(name, "./"))
+	if i := strings.Index(name, "/"); i >= 0 {
+		return name[i+1:]
+	}
+	return ""
+}
+
+// selected reports whether name is one of paths or lies under one of them
+func selected(name string, paths []string) bool {
+	if len(paths) == 0 {
+		return true
+	}
+	for _, p := range paths {
+		p = strings.Trim(path.Clean(p), "/")
+		if p == "." || name == p || strings.HasPrefix(name, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// writeFile writes the content of r to target, creating parent directories
+func writeFile(target string, r io.Reader, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if perm == 0 {
+		perm = 0644
+	}
+
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,144 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+)
+
+// FileName is the name of the audit log inside the data directory
+const FileName = "audit.jsonl"
+
+// Actions recorded in the audit log
+const (
+	ActionTag        = "tag"        // a version tag was created
+	ActionCommit     = "commit"     // a file was committed to a branch
+	ActionPush       = "push"       // refs were pushed to a remote
+	ActionPublish    = "publish"    // a release was created or its description set
+	ActionReview     = "review"     // a merge or pull request was opened
+	ActionTransition = "transition" // a ticket was moved to a status
+	ActionApply      = "apply"      // a ConfigMap or Secret was created or updated
+	ActionComment    = "comment"    // a merge or pull request was commented on
+	ActionAnnotate   = "annotate"   // a deployment was annotated on dashboards
+)
+
+// Actions lists the actions recorded in the audit log
+var Actions = []string{ActionTag, ActionCommit, ActionPush, ActionPublish, ActionReview, ActionTransition, ActionApply, ActionComment, ActionAnnotate}
+
+// Entry records a mutating action: who did what, when and how
+type Entry struct {
+	Time time.Time `json:"time"`
+	// User is the CI user who started the job (GITHUB_ACTOR,
+	// GITLAB_USER_LOGIN) when set, the local user otherwise
+	User   string `json:"user"`
+	Host   string `json:"host,omitempty"`
+	Action string `json:"action"`
+	// Target is what the action changed, e.g. owner/repo@v1.2.0, a ticket
+	// key or namespace/ConfigMap/name
+	Target  string            `json:"target"`
+	URL     string            `json:"url,omitempty"`
+	Details map[string]string `json:"details,omitempty"`
+	// Command is the command line of the action, credentials masked
+	Command string `json:"command"`
+	Dir     string `json:"dir,omitempty"` // working directory of the command
+}
+
+// Log is the append-only audit log of a data directory: one JSON entry per
+// line, never rewritten
+type Log struct {
+	path string
+}
+
+// NewLog returns the audit log of the given data directory
+func NewLog(dataDir string) *Log {
+	return &Log{path: filepath.Join(dataDir, FileName)}
+}
+
+// Path returns the path of the log file
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Append adds an entry at the end of the log, setting its time, user, host
+// and directory when empty. The file is readable by its owner only.
+func (l *Log) Append(entry Entry) error {
+	if entry.Time.IsZero() {
+		entry.Time = time.Now()
+	}
+	entry.Time = entry.Time.UTC()
+	if entry.User == "" {
+		entry.User = CurrentUser()
+	}
+	if entry.Host == "" {
+		entry.Host, _ = os.Hostname()
+	}
+	if entry.Dir == "" {
+		entry.Dir, _ = os.Getwd()
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	// A single write in append mode keeps concurrent entries whole
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return f.Close()
+}
+
+// List returns all recorded entries, oldest first
+func (l *Log) List() ([]Entry, error) {
+	f, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse line %d of audit log %s: %w", n, l.path, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return entries, nil
+}
+
+// CurrentUser returns who runs drivio: the user who started the CI job when
+// known, the local user otherwise
+func CurrentUser() string {
+	for _, name := range []string{"GITHUB_ACTOR", "GITLAB_USER_LOGIN"} {
+		if actor := os.Getenv(name); actor != "" {
+			return actor
+		}
+	}
+	if current, err := user.Current(); err == nil {
+		return current.Username
+	}
+	return os.Getenv("USER")
+}
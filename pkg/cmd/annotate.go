@@ -0,0 +1,261 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+	"time"
+
+	"drivio/pkg/audit"
+	"drivio/pkg/config"
+	"drivio/pkg/grafana"
+	"drivio/pkg/ui"
+	"drivio/pkg/workdir"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	annotateGrafanaURL   string
+	annotateGrafanaToken string
+	annotateOrgID        int64
+	annotateVersion      string
+	annotateEnvironment  string
+	annotateRepo         string
+	annotateNotesURL     string
+	annotateDashboardUID string
+	annotatePanelID      int64
+	annotateTags         []string
+	annotateTime         string
+	annotateDuration     time.Duration
+	annotateDryRun       bool
+)
+
+// annotateCmd represents the annotate command
+var annotateCmd = &cobra.Command{
+	Use:   "annotate",
+	Short: "Mark deployments on monitoring dashboards",
+}
+
+// annotateGrafanaCmd represents the annotate grafana command
+var annotateGrafanaCmd = &cobra.Command{
+	Use:   "grafana",
+	Short: "Add a deployment annotation to Grafana",
+	Long: `Add an annotation marking a deployment to Grafana, with the version, the
+environment and a link to the release notes, so that dashboards show when
+each version shipped, e.g. after release or once a promotion is merged.
+
+The version, repository and link default to those of the latest release or
+promote run recorded in the work directory: the tag released and its GitHub
+release page, or the value promoted, its environment and the merge request
+(--repo naming the component promoted). The annotation is then placed at the
+time of the run, and at the current time otherwise; --time sets it, as a
+date, a time or an age (30m ago).
+
+The annotation is tagged deployment, version:VERSION, and environment:ENV
+and repo:REPO when known, plus the --tag values, for the annotation queries
+of dashboards. It is an organization annotation, shown by every dashboard
+querying its tags, unless --dashboard-uid, and --panel-id, restrict it.
+--duration makes it a region, e.g. of a deployment window.
+
+Grafana is reached at --grafana-url with --grafana-token, a service account
+token with the annotations:write permission. --dry-run prints the annotation
+without adding it.
+
+Examples:
+  drivio annotate grafana --grafana-url https://grafana.example.com --environment production
+  drivio annotate grafana --version v1.4.0 --repo myorg/myrepo --environment staging --tag team:payments
+  drivio annotate grafana --version v1.4.0 --environment production --dashboard-uid app-overview --duration 15m --dry-run`,
+	Args: cobra.NoArgs,
+	RunE: runAnnotateGrafana,
+}
+
+func init() {
+	rootCmd.AddCommand(annotateCmd)
+	annotateCmd.AddCommand(annotateGrafanaCmd)
+
+	// Add flags
+	annotateGrafanaCmd.Flags().StringVar(&annotateGrafanaURL, "grafana-url", "", "Grafana URL")
+	annotateGrafanaCmd.Flags().StringVar(&annotateGrafanaToken, "grafana-token", "", "Grafana service account token")
+	annotateGrafanaCmd.Flags().Int64Var(&annotateOrgID, "org-id", 0, "Grafana organization of the annotation (default: the one of the token)")
+	annotateGrafanaCmd.Flags().StringVar(&annotateVersion, "version", "", "Version deployed (default: the one of the latest release or promote run)")
+	annotateGrafanaCmd.Flags().StringVar(&annotateEnvironment, "environment", "", "Environment deployed to, e.g. production")
+	annotateGrafanaCmd.Flags().StringVar(&annotateRepo, "repo", "", "Repository or component deployed, e.g. myorg/myrepo")
+	annotateGrafanaCmd.Flags().StringVar(&annotateNotesURL, "notes-url", "", "Link to the release notes (default: the release page or merge request of the latest run)")
+	annotateGrafanaCmd.Flags().StringVar(&annotateDashboardUID, "dashboard-uid", "", "Only annotate this dashboard")
+	annotateGrafanaCmd.Flags().Int64Var(&annotatePanelID, "panel-id", 0, "Only annotate this panel of --dashboard-uid")
+	annotateGrafanaCmd.Flags().StringSliceVar(&annotateTags, "tag", nil, "Additional tag of the annotation (repeatable)")
+	annotateGrafanaCmd.Flags().StringVar(&annotateTime, "time", "", "Time of the deployment: date, time or age (default: the time of the latest run, or now)")
+	annotateGrafanaCmd.Flags().DurationVar(&annotateDuration, "duration", 0, "Make the annotation a region lasting this long")
+	annotateGrafanaCmd.Flags().BoolVar(&annotateDryRun, "dry-run", false, "Print the annotation without adding it")
+
+	// Environment variables that take precedence over the config file
+	bindFlagEnv(annotateGrafanaCmd.Flags(), "grafana-url", config.EnvGrafanaURL...)
+	bindFlagEnv(annotateGrafanaCmd.Flags(), "grafana-token", config.EnvGrafanaToken...)
+}
+
+func runAnnotateGrafana(cmd *cobra.Command, args []string) error {
+	if !annotateDryRun && (annotateGrafanaURL == "" || annotateGrafanaToken == "") {
+		return fmt.Errorf("Grafana URL and token are required. Set GRAFANA_URL and GRAFANA_TOKEN environment variables or use --grafana-url and --grafana-token flags")
+	}
+	if annotatePanelID != 0 && annotateDashboardUID == "" {
+		return fmt.Errorf("--panel-id needs --dashboard-uid")
+	}
+	if annotateDuration < 0 {
+		return fmt.Errorf("invalid --duration %s", annotateDuration)
+	}
+	cmd.SilenceUsage = true
+
+	deployment := deployment{
+		version:     annotateVersion,
+		environment: annotateEnvironment,
+		repo:        annotateRepo,
+		notesURL:    annotateNotesURL,
+		time:        time.Now(),
+	}
+	if deployment.version == "" {
+		latest, err := latestDeployment(workDir)
+		if err != nil {
+			return err
+		}
+		deployment.version, deployment.time = latest.version, latest.time
+		if deployment.environment == "" {
+			deployment.environment = latest.environment
+		}
+		if deployment.repo == "" {
+			deployment.repo = latest.repo
+		}
+		if deployment.notesURL == "" {
+			deployment.notesURL = latest.notesURL
+		}
+	}
+	if deployment.version == "" {
+		return fmt.Errorf("the latest run has no version: give the --version deployed")
+	}
+	if annotateTime != "" {
+		t, err := parseNotesDate(annotateTime, time.Now(), false)
+		if err != nil {
+			return fmt.Errorf("invalid --time: %w", err)
+		}
+		deployment.time = t
+	}
+
+	annotation := grafana.Annotation{
+		DashboardUID: annotateDashboardUID,
+		PanelID:      annotatePanelID,
+		Time:         deployment.time,
+		Tags:         append(deployment.tags(), annotateTags...),
+		Text:         deployment.text(),
+	}
+	if annotateDuration > 0 {
+		annotation.TimeEnd = annotation.Time.Add(annotateDuration)
+	}
+
+	if annotateDryRun {
+		fmt.Printf("Time:      %s\n", annotation.Time.Local().Format(time.RFC3339))
+		if !annotation.TimeEnd.IsZero() {
+			fmt.Printf("Time end:  %s\n", annotation.TimeEnd.Local().Format(time.RFC3339))
+		}
+		fmt.Printf("Dashboard: %s\n", orDash(annotation.DashboardUID))
+		if annotation.PanelID != 0 {
+			fmt.Printf("Panel:     %d\n", annotation.PanelID)
+		}
+		fmt.Printf("Tags:      %s\n", strings.Join(annotation.Tags, ", "))
+		fmt.Printf("Text:      %s\n", annotation.Text)
+		return nil
+	}
+
+	client := grafana.NewClient(annotateGrafanaURL, annotateGrafanaToken, annotateOrgID)
+	var id int64
+	if err := ui.RunSpinner(fmt.Sprintf("Annotating the deployment of %s...", deployment.version), func() error {
+		var err error
+		id, err = client.CreateAnnotation(context.Background(), annotation)
+		return err
+	}); err != nil {
+		return err
+	}
+	ui.Printf("📍 Annotation %d added at %s\n", id, deployment.time.Local().Format("2006-01-02 15:04:05"))
+	target := deployment.version
+	if deployment.repo != "" {
+		target = deployment.repo + "@" + deployment.version
+	}
+	auditAction(audit.ActionAnnotate, target, strings.TrimRight(annotateGrafanaURL, "/"), map[string]string{
+		"annotation":  fmt.Sprint(id),
+		"environment": deployment.environment,
+	})
+	return nil
+}
+
+// deployment is a version shipped, as annotated
+type deployment struct {
+	version     string
+	environment string
+	repo        string
+	notesURL    string
+	time        time.Time
+}
+
+// tags returns the tags of the annotation of the deployment
+func (d deployment) tags() []string {
+	tags := []string{"deployment", "version:" + d.version}
+	if d.environment != "" {
+		tags = append(tags, "environment:"+d.environment)
+	}
+	if d.repo != "" {
+		tags = append(tags, "repo:"+d.repo)
+	}
+	return tags
+}
+
+// text returns the HTML text of the annotation of the deployment, e.g.
+// "myorg/myrepo v1.4.0 deployed to production" and a link to its notes
+func (d deployment) text() string {
+	text := html.EscapeString(d.version)
+	if d.repo != "" {
+		text = html.EscapeString(d.repo) + " " + text
+	}
+	text = "<b>" + text + "</b> deployed"
+	if d.environment != "" {
+		text += " to " + html.EscapeString(d.environment)
+	}
+	if d.notesURL != "" {
+		text += fmt.Sprintf(`<br><a href="%s" target="_blank">Release notes</a>`, html.EscapeString(d.notesURL))
+	}
+	return text
+}
+
+// latestDeployment returns the deployment of the latest release or promote
+// run of the artifact index
+func latestDeployment(dir string) (*deployment, error) {
+	records, err := workdir.NewIndex(dir).List()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(records) - 1; i >= 0; i-- {
+		record := records[i]
+		inputs := record.Inputs
+		switch record.Command {
+		case "release":
+			d := &deployment{version: inputs["version"], repo: inputs["repo"], time: record.CreatedAt}
+			if d.repo != "" && d.version != "" {
+				d.notesURL = fmt.Sprintf("https://github.com/%s/releases/tag/%s", d.repo, url.PathEscape(d.version))
+			}
+			return d, nil
+		case "promote":
+			// The value of the first --set, e.g. .image.tag=v1.2.4
+			first, _, _ := strings.Cut(inputs["set"], ",")
+			_, value, _ := strings.Cut(first, "=")
+			// The repository of the run is the config repository, not the
+			// component promoted
+			return &deployment{
+				version:     value,
+				environment: inputs["env"],
+				notesURL:    inputs["merge-request"],
+				time:        record.CreatedAt,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("no release or promote run in %s: give the --version deployed", dir)
+}
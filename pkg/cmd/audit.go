@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"drivio/pkg/audit"
+	"drivio/pkg/redact"
+	"drivio/pkg/ui"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var (
+	auditActions []string
+	auditTarget  string
+	auditUser    string
+	auditSince   string
+	auditUntil   string
+	auditLast    int
+	auditFormat  string
+
+	// commandLine is the command line run, recorded with its actions
+	commandLine string
+)
+
+// auditCmd represents the audit command
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "List the actions recorded in the audit log",
+	Long: `List the mutating actions of drivio recorded in the audit log of the data
+directory, oldest first: who ran what, when, and the command line.
+
+Every action changing something outside the work directory is recorded:
+
+  tag         a version tag was created (bump, release)
+  commit      a file was committed to a branch (bump --version-file)
+  push        refs were pushed to a remote (bump --push, backport --local)
+  publish     a release was created or its description set (release, serve)
+  review      a merge or pull request was opened (promote, bump --review,
+              backport)
+  transition  a ticket was moved to a status (jira transition)
+  apply       a ConfigMap or Secret was created or updated (fetch --apply-as)
+  comment     a merge or pull request was commented on (ci)
+  annotate    a deployment was annotated on dashboards (annotate grafana)
+
+The log, audit.jsonl, has one JSON entry per line. Entries are only ever
+appended: clean and the retention policy leave the log alone. The user is
+the CI user who started the job (GITHUB_ACTOR, GITLAB_USER_LOGIN) when set,
+the local user otherwise. Credentials are masked in the command lines.
+
+Examples:
+  drivio audit
+  drivio audit --action publish,tag --since 2w
+  drivio audit --target openshift/hypershift --last 20
+  drivio audit --user alice --format json`,
+	Args: cobra.NoArgs,
+	RunE: runAudit,
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+
+	auditCmd.Flags().StringSliceVar(&auditActions, "action", nil, "Only list these actions: "+strings.Join(audit.Actions, ", "))
+	auditCmd.Flags().StringVar(&auditTarget, "target", "", "Only list the actions whose target contains this text, e.g. a repository or a ticket")
+	auditCmd.Flags().StringVar(&auditUser, "user", "", "Only list the actions of this user")
+	auditCmd.Flags().StringVar(&auditSince, "since", "", "Only list the actions since this date, time or age (e.g. 2024-01-15 or 2w)")
+	auditCmd.Flags().StringVar(&auditUntil, "until", "", "Only list the actions until this date, time or age")
+	auditCmd.Flags().IntVar(&auditLast, "last", 0, "Only list the last N actions (default: all)")
+	auditCmd.Flags().StringVar(&auditFormat, "format", "table", "Output format: table or json")
+}
+
+func runAudit(cmd *cobra.Command, args []string) error {
+	if auditFormat != "table" && auditFormat != "json" {
+		return fmt.Errorf("invalid --format %q: use table or json", auditFormat)
+	}
+	for _, action := range auditActions {
+		if !containsFold(audit.Actions, action) {
+			return fmt.Errorf("unknown action %q (available: %s)", action, strings.Join(audit.Actions, ", "))
+		}
+	}
+	now := time.Now()
+	var since, until time.Time
+	var err error
+	if auditSince != "" {
+		if since, err = parseNotesDate(auditSince, now, false); err != nil {
+			return fmt.Errorf("invalid --since: %w", err)
+		}
+	}
+	if auditUntil != "" {
+		if until, err = parseNotesDate(auditUntil, now, true); err != nil {
+			return fmt.Errorf("invalid --until: %w", err)
+		}
+	}
+
+	log := audit.NewLog(dataRoot(workDir))
+	entries, err := log.List()
+	if err != nil {
+		return err
+	}
+
+	var filtered []audit.Entry
+	for _, entry := range entries {
+		switch {
+		case len(auditActions) > 0 && !containsFold(auditActions, entry.Action),
+			auditTarget != "" && !strings.Contains(strings.ToLower(entry.Target), strings.ToLower(auditTarget)),
+			auditUser != "" && entry.User != auditUser,
+			!since.IsZero() && entry.Time.Before(since),
+			!until.IsZero() && entry.Time.After(until):
+			continue
+		}
+		filtered = append(filtered, entry)
+	}
+	if auditLast > 0 && len(filtered) > auditLast {
+		filtered = filtered[len(filtered)-auditLast:]
+	}
+
+	if auditFormat == "json" {
+		if filtered == nil {
+			filtered = []audit.Entry{}
+		}
+		data, err := json.MarshalIndent(filtered, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode audit entries: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(filtered) == 0 {
+		ui.Printf("📭 No actions recorded in: %s\n", log.Path())
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tUSER\tACTION\tTARGET\tCOMMAND")
+	for _, entry := range filtered {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			entry.Time.Local().Format("2006-01-02 15:04:05"),
+			orDash(entry.User),
+			entry.Action,
+			entry.Target,
+			entry.Command,
+		)
+	}
+	return w.Flush()
+}
+
+// auditAction appends an action to the audit log of the data directory,
+// with the command line run. The action is done, so failing to record it
+// only warns.
+func auditAction(action, target, url string, details map[string]string) {
+	entry := audit.Entry{
+		Action:  action,
+		Target:  target,
+		URL:     url,
+		Details: details,
+		Command: commandLine,
+	}
+	if err := audit.NewLog(dataRoot(workDir)).Append(entry); err != nil {
+		ui.Printf("⚠️  Warning: failed to update the audit log: %v\n", err)
+		return
+	}
+	ui.Debugf(1, "Recorded %s %s in the audit log", action, target)
+}
+
+// auditCommandLine returns the command line of a command: its path, the
+// flags given, with the values of the flags holding credentials masked, and
+// its arguments. Called before the environment and the configuration file
+// set flags, it only has the flags of the command line.
+func auditCommandLine(cmd *cobra.Command) string {
+	redactor, _ := redact.New(nil)
+	words := []string{cmd.CommandPath()}
+	cmd.Flags().Visit(func(flag *pflag.Flag) {
+		value := flag.Value.String()
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			value = strings.Join(slice.GetSlice(), ",")
+		}
+		if redactor.IsSensitive(flag.Name) {
+			value = redact.Mask
+		}
+		words = append(words, "--"+flag.Name+"="+shellQuote(value))
+	})
+	for _, arg := range cmd.Flags().Args() {
+		words = append(words, shellQuote(arg))
+	}
+	return strings.Join(words, " ")
+}
+
+// shellQuote quotes a word holding spaces or quotes
+func shellQuote(word string) string {
+	if word == "" || strings.ContainsAny(word, " \t\n'\"\\$`") {
+		return strconv.Quote(word)
+	}
+	return word
+}
+
+// containsFold reports whether values holds value, ignoring case
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,276 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"drivio/pkg/audit"
+	"drivio/pkg/config"
+	"drivio/pkg/git"
+	"drivio/pkg/github"
+	"drivio/pkg/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	backportOwner        string
+	backportRepo         string
+	backportPR           int
+	backportBranch       string
+	backportSourceBranch string
+	backportTitle        string
+	backportLabels       []string
+	backportCopyLabels   bool
+	backportReviewers    []string
+	backportLocal        string
+	backportRemote       string
+	backportGitHubToken  string
+	backportDryRun       bool
+)
+
+// backportCmd represents the backport command
+var backportCmd = &cobra.Command{
+	Use:   "backport",
+	Short: "Cherry-pick a merged pull request onto a release branch",
+	Long: `Cherry-pick the commits of a merged pull request onto a new branch created from
+a release branch, and open the backport pull request: the most tedious part
+of maintaining release branches.
+
+The backport pull request is standardized:
+
+  title        the title of the original, followed by the release branch,
+               e.g. "OCPBUGS-1234: Fix the node pool upgrade [release-4.15]"
+  labels       --labels, backport by default, and the labels of the original
+               unless --copy-labels=false
+  description  links the original and lists the commits picked, followed
+               by the description of the original
+
+Keeping the ticket line first and the labels of the original, the backport
+is selected by the release notes of the release branch like the original.
+
+The commits are picked through the GitHub API by default. --local picks them
+with git cherry-pick -x in a clone instead, in a temporary worktree so the
+checkout is left alone, and pushes the branch to --remote; the commits and
+the release branch are fetched from --remote first. Either way, when the
+changes of a commit conflict with the release branch, nothing is pushed nor
+opened: backport the pull request by hand.
+
+--dry-run lists the commits and prints the pull request without creating
+anything.
+
+Examples:
+  drivio backport --owner openshift --repo hypershift --pr 4242 --branch release-4.15
+  drivio backport --owner openshift --repo hypershift --pr 4242 --branch release-4.15 --reviewers alice --labels backport,cherry-pick-approved
+  drivio backport --owner openshift --repo hypershift --pr 4242 --branch release-4.15 --local . --remote upstream
+  drivio backport --owner openshift --repo hypershift --pr 4242 --branch release-4.15 --dry-run`,
+	Args: cobra.NoArgs,
+	RunE: runBackport,
+}
+
+func init() {
+	rootCmd.AddCommand(backportCmd)
+
+	backportCmd.Flags().StringVar(&backportOwner, "owner", "", "GitHub repository owner/organization")
+	backportCmd.Flags().StringVar(&backportRepo, "repo", "", "GitHub repository name")
+	backportCmd.Flags().IntVar(&backportPR, "pr", 0, "Merged pull request to backport")
+	backportCmd.Flags().StringVar(&backportBranch, "branch", "", "Release branch to backport the pull request to")
+	backportCmd.Flags().StringVar(&backportSourceBranch, "source-branch", "", "Branch created with the picked commits (default: drivio/backport-<pr>-<branch>)")
+	backportCmd.Flags().StringVar(&backportTitle, "title", "", "Title of the backport pull request (default: the title of the original, followed by the branch)")
+	backportCmd.Flags().StringSliceVar(&backportLabels, "labels", []string{"backport"}, "Labels of the backport pull request")
+	backportCmd.Flags().BoolVar(&backportCopyLabels, "copy-labels", true, "Also label the backport pull request with the labels of the original")
+	backportCmd.Flags().StringSliceVar(&backportReviewers, "reviewers", nil, "Reviewers of the backport pull request: logins, or org/team")
+	backportCmd.Flags().StringVar(&backportLocal, "local", "", "Cherry-pick in this local clone with git instead of through the GitHub API")
+	backportCmd.Flags().StringVar(&backportRemote, "remote", "origin", "Remote of the GitHub repository, with --local")
+	backportCmd.Flags().StringVar(&backportGitHubToken, "github-token", "", "GitHub token with write access to the repository contents and pull requests")
+	backportCmd.Flags().BoolVar(&backportDryRun, "dry-run", false, "List the commits and print the pull request without creating anything")
+
+	// Environment variables that take precedence over the config file
+	bindFlagEnv(backportCmd.Flags(), "github-token", config.EnvGitHubToken...)
+
+	// Mark required flags
+	backportCmd.MarkFlagRequired("owner")
+	backportCmd.MarkFlagRequired("repo")
+	backportCmd.MarkFlagRequired("pr")
+	backportCmd.MarkFlagRequired("branch")
+}
+
+func runBackport(cmd *cobra.Command, args []string) error {
+	if backportGitHubToken == "" && !backportDryRun {
+		return fmt.Errorf("GitHub token is required to open the backport pull request. Set GITHUB_TOKEN environment variable or use --github-token flag")
+	}
+
+	ctx := context.Background()
+	client := github.NewClient(backportGitHubToken)
+	if !backportDryRun {
+		if err := preflightGitHub(ctx, backportGitHubToken, backportOwner, backportRepo); err != nil {
+			return err
+		}
+	}
+
+	// Step 1: Find the commits of the pull request
+	var pr *github.PullRequest
+	var commits []github.Commit
+	if err := ui.RunSpinner(fmt.Sprintf("Getting pull request #%d...", backportPR), func() error {
+		var err error
+		if pr, err = client.GetPullRequest(ctx, backportOwner, backportRepo, backportPR); err != nil {
+			return err
+		}
+		switch {
+		case !pr.Merged:
+			return fmt.Errorf("pull request #%d is not merged: only merged pull requests are backported", backportPR)
+		case pr.Base.Ref == backportBranch:
+			return fmt.Errorf("pull request #%d was merged into %s already", backportPR, backportBranch)
+		}
+		commits, err = client.PullRequestCommits(ctx, backportOwner, backportRepo, backportPR)
+		return err
+	}); err != nil {
+		return err
+	}
+	if len(commits) == 0 {
+		return fmt.Errorf("pull request #%d has no commits", backportPR)
+	}
+
+	shas := make([]string, 0, len(commits))
+	ui.Printf("🍒 %d commits of #%d to pick onto %s:\n", len(commits), backportPR, backportBranch)
+	for _, commit := range commits {
+		shas = append(shas, commit.SHA)
+		ui.Printf("   %s %s\n", shortSHA(commit.SHA), commit.Title())
+	}
+
+	sourceBranch := backportSourceBranch
+	if sourceBranch == "" {
+		sourceBranch = backportBranchName(backportPR, backportBranch)
+	}
+	title := backportTitle
+	if title == "" {
+		title = fmt.Sprintf("%s [%s]", pr.Title, backportBranch)
+	}
+	labels := append([]string{}, backportLabels...)
+	if backportCopyLabels {
+		for _, label := range pr.LabelNames() {
+			if !containsFold(labels, label) {
+				labels = append(labels, label)
+			}
+		}
+	}
+	description := backportDescription(pr, commits)
+
+	if backportDryRun {
+		fmt.Printf("\n%s → %s\n%s\n", sourceBranch, backportBranch, title)
+		if len(labels) > 0 {
+			fmt.Printf("Labels: %s\n", strings.Join(labels, ", "))
+		}
+		fmt.Printf("\n%s", description)
+		return nil
+	}
+
+	// Step 2: Pick the commits onto a new branch
+	var head string
+	if err := ui.RunSpinner(fmt.Sprintf("Cherry-picking onto %s...", sourceBranch), func() error {
+		var err error
+		if backportLocal != "" {
+			head, err = cherryPickLocal(ctx, sourceBranch, shas)
+		} else {
+			head, err = cherryPickGitHub(ctx, client, sourceBranch, shas)
+		}
+		return err
+	}); err != nil {
+		return fmt.Errorf("failed to backport pull request #%d to %s: %w", backportPR, backportBranch, err)
+	}
+	ui.Printf("🍒 Picked %d commits onto %s: %s\n", len(shas), sourceBranch, shortSHA(head))
+	if backportLocal != "" {
+		auditAction(audit.ActionPush, backportTarget(), "", map[string]string{"remote": backportRemote, "refs": "refs/heads/" + sourceBranch})
+	}
+
+	// Step 3: Open the backport pull request
+	var backport *github.PullRequest
+	err := ui.RunSpinner("Opening the backport pull request...", func() error {
+		var err error
+		backport, err = client.CreatePullRequest(ctx, backportOwner, backportRepo, github.PullRequestOptions{
+			Head:      sourceBranch,
+			Base:      backportBranch,
+			Title:     title,
+			Body:      description,
+			Labels:    labels,
+			Reviewers: backportReviewers,
+		})
+		return err
+	})
+	if backport != nil {
+		ui.Printf("🚀 Pull request opened: %s\n", backport.HTMLURL)
+		auditAction(audit.ActionReview, backportOwner+"/"+backportRepo+"@"+backportBranch, backport.HTMLURL, map[string]string{"title": title, "backport-of": fmt.Sprintf("#%d", backportPR), "source-branch": sourceBranch})
+	}
+	return err
+}
+
+// cherryPickLocal picks the commits in the --local clone onto a new branch
+// of the fetched release branch, and pushes it
+func cherryPickLocal(ctx context.Context, branch string, shas []string) (string, error) {
+	analyzer, err := git.NewLocalAnalyzer(backportLocal)
+	if err != nil {
+		return "", err
+	}
+	if err := analyzer.Fetch(ctx, backportRemote, backportBranch, fmt.Sprintf("pull/%d/head", backportPR)); err != nil {
+		return "", err
+	}
+	head, err := analyzer.CherryPick(ctx, branch, backportRemote+"/"+backportBranch, shas)
+	if err != nil {
+		return "", err
+	}
+	if err := analyzer.Push(ctx, backportRemote, "refs/heads/"+branch); err != nil {
+		return "", err
+	}
+	return head, nil
+}
+
+// cherryPickGitHub picks the commits through the GitHub API onto a new
+// branch of the release branch, deleted when a commit cannot be picked
+func cherryPickGitHub(ctx context.Context, client *github.Client, branch string, shas []string) (string, error) {
+	base, err := client.BranchHead(ctx, backportOwner, backportRepo, backportBranch)
+	if err != nil {
+		return "", err
+	}
+	if err := client.CreateBranch(ctx, backportOwner, backportRepo, branch, base); err != nil {
+		return "", err
+	}
+	head, err := client.CherryPick(ctx, backportOwner, backportRepo, branch, shas)
+	if err != nil {
+		if deleteErr := client.DeleteBranch(ctx, backportOwner, backportRepo, branch); deleteErr != nil {
+			ui.Debugf(1, "Failed to delete branch %s: %v", branch, deleteErr)
+		}
+		return "", err
+	}
+	return head, nil
+}
+
+// backportDescription returns the description of the backport pull
+// request: the original and the commits picked, followed by the
+// description of the original, with its ticket and release note
+func backportDescription(pr *github.PullRequest, commits []github.Commit) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Backport of #%d to `%s`.\n\nCherry-picked commits:\n\n", pr.Number, backportBranch)
+	for _, commit := range commits {
+		fmt.Fprintf(&sb, "- %s %s\n", commit.SHA, commit.Title())
+	}
+	if body := strings.TrimSpace(pr.Body); body != "" {
+		fmt.Fprintf(&sb, "\n---\n\n%s\n", body)
+	}
+	return sb.String()
+}
+
+// backportBranchName returns the default branch of a backport, e.g.
+// drivio/backport-4242-release-4.15
+func backportBranchName(pr int, branch string) string {
+	return "drivio/backport-" + strings.Trim(branchUnsafe.ReplaceAllString(fmt.Sprintf("%d-%s", pr, branch), "-"), "-.")
+}
+
+// backportTarget names the clone backport pushes from in the audit log
+func backportTarget() string {
+	if abs, err := filepath.Abs(backportLocal); err == nil {
+		return abs
+	}
+	return backportLocal
+}
@@ -0,0 +1,501 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"drivio/pkg/audit"
+	"drivio/pkg/config"
+	"drivio/pkg/git"
+	"drivio/pkg/github"
+	"drivio/pkg/review"
+	"drivio/pkg/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	bumpOwner       string
+	bumpRepo        string
+	bumpSHA         string
+	bumpBranch      string
+	bumpFrom        string
+	bumpTagPrefix   string
+	bumpVersion     string
+	bumpIncrement   string
+	bumpMessage     string
+	bumpVersionFile string
+	bumpGitHubToken string
+	bumpLocal       string
+	bumpRemote      string
+	bumpSign        bool
+	bumpPush        bool
+	bumpDryRun      bool
+	bumpReview      bool
+	bumpReviewers   []string
+	bumpLabels      []string
+)
+
+// bumpCmd represents the bump command
+var bumpCmd = &cobra.Command{
+	Use:   "bump",
+	Short: "Tag the next version of a repository",
+	Long: `Compute the next version of a repository and create an annotated tag for it on
+a commit, the step right before generating its release notes.
+
+The next version follows the latest release tag (e.g. v1.2.3), or --from:
+--version sets it, --increment names the part to increment (major, minor or
+patch) and otherwise it comes from the changes since the latest release: major
+for a breaking change, minor for a feature, patch otherwise. Changes are typed
+by Conventional Commits and the classification rules of the config file.
+Before v1.0.0, breaking changes only increment the minor version. Without any
+release tag, the first version is v0.1.0 unless --version or --increment says
+otherwise.
+
+In a monorepo whose components are tagged apart, e.g. api/v1.4.0, --tag-prefix
+selects the tags of a component, e.g. api/v; the tags with a slash are
+otherwise ignored. --version selects those of the component it is tagged for.
+
+The tag is created on --sha, by default the head of --branch (the default
+branch of the repository), through the GitHub API. --version-file also
+commits the version to a file of --branch, e.g. VERSION, and tags that commit.
+
+The GitHub API cannot sign tags: --sign needs --local, a clone in which the tag
+is created with git tag -s, with the key git is configured with; --push then
+pushes it to --remote. --owner and --repo are not needed with --local.
+
+--review lands the --version-file change through review instead: it is
+committed to a new branch, drivio/bump-<version>, with a pull request to
+--branch, and nothing is tagged; tag the merged commit with --version once
+the pull request is merged.
+
+--dry-run prints the next version without creating anything. The version is
+printed on stdout, so it can be captured, e.g. VERSION=$(drivio bump ...).
+
+Examples:
+  drivio bump --owner myorg --repo myrepo
+  drivio bump --owner myorg --repo myrepo --increment minor --sha 1a2b3c4
+  drivio bump --owner myorg --repo myrepo --version v2.0.0-rc.1 --version-file VERSION
+  drivio bump --owner myorg --repo myrepo --version-file VERSION --review --reviewers alice,myorg/release-team
+  drivio bump --local . --sign --push
+  drivio bump --owner myorg --repo myrepo --dry-run`,
+	RunE: runBump,
+}
+
+func init() {
+	rootCmd.AddCommand(bumpCmd)
+
+	// Add flags
+	bumpCmd.Flags().StringVar(&bumpOwner, "owner", "", "GitHub repository owner/organization")
+	bumpCmd.Flags().StringVar(&bumpRepo, "repo", "", "GitHub repository name")
+	bumpCmd.Flags().StringVar(&bumpSHA, "sha", "", "Commit to tag (default: the head of --branch)")
+	bumpCmd.Flags().StringVar(&bumpBranch, "branch", "", "Branch to tag the head of and to commit --version-file to (default: the default branch, or the current branch with --local)")
+	bumpCmd.Flags().StringVar(&bumpFrom, "from", "", "Tag of the previous version (default: the latest release tag)")
+	bumpCmd.Flags().StringVar(&bumpTagPrefix, "tag-prefix", "", "Prefix of the release tags of the component to tag in a monorepo, e.g. api/v (default: the tags of the whole repository)")
+	bumpCmd.Flags().StringVar(&bumpVersion, "version", "", "Version to tag (default: computed from the previous version)")
+	bumpCmd.Flags().StringVar(&bumpIncrement, "increment", "", "Part of the version to increment: major, minor or patch (default: from the changes)")
+	bumpCmd.Flags().StringVar(&bumpMessage, "message", "", "Message of the tag (default: Release <version>)")
+	bumpCmd.Flags().StringVar(&bumpVersionFile, "version-file", "", "File of the repository to commit the version to before tagging, e.g. VERSION")
+	bumpCmd.Flags().StringVar(&bumpGitHubToken, "github-token", "", "GitHub token with write access to the repository contents")
+	bumpCmd.Flags().StringVar(&bumpLocal, "local", "", "Tag in this local clone with git instead of through the GitHub API")
+	bumpCmd.Flags().StringVar(&bumpRemote, "remote", "origin", "Remote --push pushes to, with --local")
+	bumpCmd.Flags().BoolVar(&bumpSign, "sign", false, "Sign the tag with the GPG or SSH key of git, with --local")
+	bumpCmd.Flags().BoolVar(&bumpPush, "push", false, "Push the tag, and the --version-file commit, with --local")
+	bumpCmd.Flags().BoolVar(&bumpDryRun, "dry-run", false, "Print the next version without creating anything")
+	bumpCmd.Flags().BoolVar(&bumpReview, "review", false, "Open a pull request with the --version-file change instead of committing and tagging")
+	bumpCmd.Flags().StringSliceVar(&bumpReviewers, "reviewers", nil, "Reviewers of the --review pull request: logins, or org/team")
+	bumpCmd.Flags().StringSliceVar(&bumpLabels, "labels", nil, "Labels of the --review pull request")
+
+	// Environment variables that take precedence over the config file
+	bindFlagEnv(bumpCmd.Flags(), "github-token", config.EnvGitHubToken...)
+}
+
+// bumpRepository is the repository bump tags: through the GitHub API, or a
+// local clone
+type bumpRepository interface {
+	// head returns the branch and commit tagged by default
+	head(ctx context.Context, branch string) (string, string, error)
+	resolve(ctx context.Context, ref string) (string, error)
+	tags(ctx context.Context) ([]string, error)
+	commits(ctx context.Context, from, to string) ([]git.CommitInfo, error)
+	readFile(ctx context.Context, ref, path string) ([]byte, bool, error)
+	commitFile(ctx context.Context, branch, path string, content []byte, message string) (string, error)
+	tag(ctx context.Context, name, message, sha string) error
+}
+
+func runBump(cmd *cobra.Command, args []string) error {
+	if bumpVersion != "" && bumpIncrement != "" {
+		return fmt.Errorf("--version and --increment are exclusive")
+	}
+	if bumpLocal == "" {
+		if bumpOwner == "" || bumpRepo == "" {
+			return fmt.Errorf("--owner and --repo are required, unless tagging in a clone with --local")
+		}
+		if bumpSign || bumpPush {
+			return fmt.Errorf("--sign and --push need --local: the GitHub API cannot sign tags, create the tag in a clone instead")
+		}
+	}
+	if bumpReview && (bumpVersionFile == "" || bumpLocal != "") {
+		return fmt.Errorf("--review opens a pull request with the --version-file change, through the GitHub API: it needs --version-file and not --local")
+	}
+	rules, err := classificationRules(activeFileConfig)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	var repository bumpRepository
+	if bumpLocal != "" {
+		analyzer, err := git.NewLocalAnalyzer(bumpLocal)
+		if err != nil {
+			return err
+		}
+		repository = localBumpRepository{analyzer}
+	} else {
+		client := github.NewClient(bumpGitHubToken)
+		if !bumpDryRun {
+			if err := preflightGitHub(ctx, bumpGitHubToken, bumpOwner, bumpRepo); err != nil {
+				return err
+			}
+		}
+		repository = githubBumpRepository{client: client, analyzer: git.NewAnalyzerWithClient(client), owner: bumpOwner, repo: bumpRepo}
+	}
+
+	// Step 1: Find the commit to tag and the previous version
+	var branch, sha, previousTag string
+	var previous git.Version
+	var tags []string
+	if err := ui.RunSpinner("Finding the previous version...", func() error {
+		var err error
+		branch, sha, err = repository.head(ctx, bumpBranch)
+		if err != nil {
+			return err
+		}
+		if bumpSHA != "" {
+			if sha, err = repository.resolve(ctx, bumpSHA); err != nil {
+				return err
+			}
+		}
+		if tags, err = repository.tags(ctx); err != nil {
+			return err
+		}
+		if bumpFrom != "" {
+			previousTag = bumpFrom
+			previous, err = git.ParseVersion(bumpFrom)
+			return err
+		}
+		// The previous version is that of the component tagged
+		prefix := bumpTagPrefix
+		if version, err := git.ParseVersion(bumpVersion); err == nil {
+			prefix = version.Prefix
+		}
+		previousTag, previous, _ = git.LatestVersion(tags, prefix)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	// Step 2: Compute the next version
+	next, reason, err := nextVersion(ctx, repository, previousTag, previous, sha, bumpVersion, bumpIncrement, bumpTagPrefix, rules)
+	if err != nil {
+		return err
+	}
+	if previousTag != "" && next.Compare(previous) <= 0 {
+		return fmt.Errorf("version %s does not follow the previous version %s", next, previousTag)
+	}
+	for _, tag := range tags {
+		if tag == next.String() {
+			return fmt.Errorf("tag %s already exists", tag)
+		}
+	}
+	if previousTag != "" {
+		ui.Printf("🔢 %s → %s (%s)\n", previousTag, next, reason)
+	} else {
+		ui.Printf("🔢 First version %s (%s)\n", next, reason)
+	}
+
+	if bumpDryRun {
+		fmt.Println(next)
+		return nil
+	}
+
+	if bumpReview {
+		return reviewVersionFile(ctx, repository.(githubBumpRepository), branch, sha, next)
+	}
+
+	// Step 3: Commit the version file and tag
+	if bumpVersionFile != "" {
+		head, err := repository.resolve(ctx, branch)
+		if err != nil {
+			return err
+		}
+		if head != sha {
+			return fmt.Errorf("--version-file commits on top of %s, whose head %s is not the commit to tag %s", branch, shortSHA(head), shortSHA(sha))
+		}
+		if err := ui.RunSpinner(fmt.Sprintf("Committing %s...", bumpVersionFile), func() error {
+			current, _, err := repository.readFile(ctx, sha, bumpVersionFile)
+			if err != nil {
+				return err
+			}
+			sha, err = repository.commitFile(ctx, branch, bumpVersionFile, versionFileContent(current, next), "Bump version to "+next.String())
+			return err
+		}); err != nil {
+			return err
+		}
+		auditAction(audit.ActionCommit, bumpTarget()+"@"+branch, "", map[string]string{"file": bumpVersionFile, "sha": sha, "version": next.String()})
+	}
+
+	message := bumpMessage
+	if message == "" {
+		message = "Release " + next.String()
+	}
+	if err := ui.RunSpinner(fmt.Sprintf("Tagging %s...", next), func() error {
+		return repository.tag(ctx, next.String(), message, sha)
+	}); err != nil {
+		return err
+	}
+	ui.Printf("🏷️  Tagged %s on %s\n", next, shortSHA(sha))
+	auditAction(audit.ActionTag, bumpTarget()+"@"+next.String(), "", map[string]string{"sha": sha})
+
+	if bumpPush {
+		refs := []string{"refs/tags/" + next.String()}
+		if bumpVersionFile != "" {
+			refs = append([]string{"refs/heads/" + branch}, refs...)
+		}
+		if err := ui.RunSpinner(fmt.Sprintf("Pushing to %s...", bumpRemote), func() error {
+			return repository.(localBumpRepository).Push(ctx, bumpRemote, refs...)
+		}); err != nil {
+			return err
+		}
+		auditAction(audit.ActionPush, bumpTarget(), "", map[string]string{"remote": bumpRemote, "refs": strings.Join(refs, ",")})
+	}
+
+	fmt.Println(next)
+	return nil
+}
+
+// bumpTarget names the repository bump changes in the audit log: the path
+// of the clone with --local, owner/repo otherwise
+func bumpTarget() string {
+	if bumpLocal == "" {
+		return bumpOwner + "/" + bumpRepo
+	}
+	if abs, err := filepath.Abs(bumpLocal); err == nil {
+		return abs
+	}
+	return bumpLocal
+}
+
+// reviewVersionFile opens a pull request committing the next version to the
+// version file, to tag once merged
+func reviewVersionFile(ctx context.Context, repository githubBumpRepository, branch, sha string, next git.Version) error {
+	head, err := repository.resolve(ctx, branch)
+	if err != nil {
+		return err
+	}
+	if head != sha {
+		return fmt.Errorf("--review proposes a change on top of %s, whose head %s is not the commit to tag %s", branch, shortSHA(head), shortSHA(sha))
+	}
+	current, exists, err := repository.readFile(ctx, sha, bumpVersionFile)
+	if err != nil {
+		return err
+	}
+	revision := ""
+	if exists {
+		if revision, err = repository.client.FileSHA(ctx, repository.owner, repository.repo, sha, bumpVersionFile); err != nil {
+			return err
+		}
+	}
+
+	provider := review.NewGitHub(repository.client, repository.owner, repository.repo)
+	title := "Bump version to " + next.String()
+	var result *review.Result
+	if err := ui.RunSpinner(fmt.Sprintf("Opening a %s...", provider.Kind()), func() error {
+		result, err = provider.Open(ctx, review.Request{
+			SourceBranch: "drivio/bump-" + next.String(),
+			TargetBranch: branch,
+			Title:        title,
+			Description:  fmt.Sprintf("Sets `%s` to %s. Tag the merged commit with `drivio bump --version %s`.", bumpVersionFile, next, next),
+			Files:        []review.File{{Path: bumpVersionFile, Content: versionFileContent(current, next), Revision: revision, New: !exists}},
+			Labels:       bumpLabels,
+			Reviewers:    bumpReviewers,
+		})
+		return err
+	}); err != nil {
+		if result != nil {
+			ui.Printf("⚠️  Pull request opened: %s\n", result.URL)
+			auditAction(audit.ActionReview, bumpTarget()+"@"+branch, result.URL, map[string]string{"title": title})
+		}
+		return err
+	}
+	ui.Printf("🚀 Pull request opened: %s\n", result.URL)
+	auditAction(audit.ActionReview, bumpTarget()+"@"+branch, result.URL, map[string]string{"title": title})
+	ui.Printf("🏷️  Tag %s once merged\n", next)
+
+	fmt.Println(next)
+	return nil
+}
+
+// nextVersion returns the version to tag, with why it was chosen: version
+// when given, else previous with increment, else with the increment of the
+// changes since previous
+func nextVersion(ctx context.Context, repository bumpRepository, previousTag string, previous git.Version, sha, version, increment, prefix string, rules []git.TypeRule) (git.Version, string, error) {
+	if version != "" {
+		next, err := git.ParseVersion(version)
+		if err == nil && prefix != "" && !git.SameComponent(next.Prefix, prefix) {
+			return git.Version{}, "", fmt.Errorf("version %s is not tagged with --tag-prefix %s", version, prefix)
+		}
+		return next, "--version", err
+	}
+	if previousTag == "" {
+		// Release tags are usually prefixed with v
+		previous.Prefix = "v"
+		if prefix != "" {
+			previous.Prefix = prefix
+		}
+	}
+	if increment != "" {
+		inc, err := git.ParseIncrement(increment)
+		if err != nil {
+			return git.Version{}, "", err
+		}
+		return previous.Bump(inc), string(inc) + " increment", nil
+	}
+	if previousTag == "" {
+		return previous.Bump(git.IncrementMinor), "no previous release", nil
+	}
+
+	var commits []git.CommitInfo
+	if err := ui.RunSpinner(fmt.Sprintf("Classifying the changes since %s...", previousTag), func() error {
+		var err error
+		commits, err = repository.commits(ctx, previousTag, sha)
+		return err
+	}); err != nil {
+		return git.Version{}, "", fmt.Errorf("failed to get commits: %w", err)
+	}
+	if len(commits) == 0 {
+		return git.Version{}, "", fmt.Errorf("no changes since %s", previousTag)
+	}
+	inc := git.NextIncrement(previous, commits, rules)
+	return previous.Bump(inc), fmt.Sprintf("%s increment for %d changes", inc, len(commits)), nil
+}
+
+// versionFileContent returns the new content of the version file, keeping
+// whether the current one writes the prefix of the version
+func versionFileContent(current []byte, next git.Version) []byte {
+	version := next
+	if len(current) == 0 || !strings.HasPrefix(strings.TrimSpace(string(current)), next.Prefix) {
+		version.Prefix = ""
+	}
+	return []byte(version.String() + "\n")
+}
+
+// githubBumpRepository tags through the GitHub API
+type githubBumpRepository struct {
+	client      *github.Client
+	analyzer    *git.Analyzer
+	owner, repo string
+}
+
+func (r githubBumpRepository) head(ctx context.Context, branch string) (string, string, error) {
+	if branch == "" {
+		var err error
+		if branch, err = r.client.DefaultBranch(ctx, r.owner, r.repo); err != nil {
+			return "", "", err
+		}
+	}
+	sha, err := r.client.BranchHead(ctx, r.owner, r.repo, branch)
+	return branch, sha, err
+}
+
+func (r githubBumpRepository) resolve(ctx context.Context, ref string) (string, error) {
+	var commit github.Commit
+	if err := r.client.GetJSON(ctx, fmt.Sprintf("/repos/%s/%s/commits/%s", r.owner, r.repo, ref), &commit); err != nil {
+		return "", fmt.Errorf("unknown reference %s: %w", ref, err)
+	}
+	return commit.SHA, nil
+}
+
+func (r githubBumpRepository) tags(ctx context.Context) ([]string, error) {
+	return r.client.ListTags(ctx, r.owner, r.repo)
+}
+
+func (r githubBumpRepository) commits(ctx context.Context, from, to string) ([]git.CommitInfo, error) {
+	return r.analyzer.Commits(ctx, r.owner, r.repo, from, to)
+}
+
+func (r githubBumpRepository) readFile(ctx context.Context, ref, path string) ([]byte, bool, error) {
+	content, err := r.client.GetFile(ctx, r.owner, r.repo, ref, path)
+	if err != nil {
+		if errors.Is(err, github.ErrNotFound) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return content, true, nil
+}
+
+func (r githubBumpRepository) commitFile(ctx context.Context, branch, path string, content []byte, message string) (string, error) {
+	return r.client.UpdateFile(ctx, r.owner, r.repo, github.FileUpdate{Path: path, Branch: branch, Message: message, Content: content})
+}
+
+func (r githubBumpRepository) tag(ctx context.Context, name, message, sha string) error {
+	_, err := r.client.CreateTag(ctx, r.owner, r.repo, github.TagOptions{Name: name, Message: message, SHA: sha})
+	return err
+}
+
+// localBumpRepository tags in a clone with git
+type localBumpRepository struct {
+	*git.LocalAnalyzer
+}
+
+func (r localBumpRepository) head(ctx context.Context, branch string) (string, string, error) {
+	if branch == "" {
+		current, err := r.CurrentBranch(ctx)
+		if err != nil {
+			// A detached HEAD can be tagged, but not committed to
+			sha, resolveErr := r.ResolveRef(ctx, "HEAD")
+			if resolveErr != nil || bumpVersionFile != "" {
+				return "", "", err
+			}
+			return "", sha, nil
+		}
+		branch = current
+	}
+	sha, err := r.ResolveRef(ctx, branch)
+	return branch, sha, err
+}
+
+func (r localBumpRepository) resolve(ctx context.Context, ref string) (string, error) {
+	return r.ResolveRef(ctx, ref)
+}
+
+func (r localBumpRepository) tags(ctx context.Context) ([]string, error) {
+	return r.Tags(ctx)
+}
+
+func (r localBumpRepository) commits(ctx context.Context, from, to string) ([]git.CommitInfo, error) {
+	return r.Commits(ctx, from, to)
+}
+
+func (r localBumpRepository) readFile(ctx context.Context, ref, path string) ([]byte, bool, error) {
+	return r.ReadFile(ctx, ref, path)
+}
+
+func (r localBumpRepository) commitFile(ctx context.Context, branch, path string, content []byte, message string) (string, error) {
+	current, err := r.CurrentBranch(ctx)
+	if err != nil {
+		return "", err
+	}
+	if current != branch {
+		return "", fmt.Errorf("--version-file commits to the current branch %s, not %s", current, branch)
+	}
+	return r.CommitFile(ctx, path, content, message)
+}
+
+func (r localBumpRepository) tag(ctx context.Context, name, message, sha string) error {
+	return r.CreateTag(ctx, name, message, sha, bumpSign)
+}
@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"drivio/pkg/config"
+	"drivio/pkg/git"
+	"drivio/pkg/github"
+	"drivio/pkg/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifyOwner       string
+	verifyRepo        string
+	verifyPR          int
+	verifyFrom        string
+	verifyTo          string
+	verifyLocal       string
+	verifyGitHubToken string
+	verifyRequire     []string
+	verifyLabel       string
+	verifySkipLabels  []string
+	verifyCommits     bool
+)
+
+// changelogVerifyCmd represents the changelog-verify command
+var changelogVerifyCmd = &cobra.Command{
+	Use:   "changelog-verify",
+	Short: "Check that changes follow the conventions of the release notes",
+	Long: `Check that a pull request, or the changes between two references, follow the
+conventions release-notes relies on, and fail otherwise: run it as a CI gate
+to catch at merge time the changes the notes would silently leave out.
+
+The checks, selected with --require, are:
+
+  ticket        a "TICKET-123: description" line in the title or description
+                of the pull request, or in the message of the commit
+  label         the --label of the noted pull requests (GitHub API only)
+  release-note  a non-empty fenced release-note block in the description,
+                NONE for changes not worth noting:
+
+                  ` + "```release-note" + `
+                  Fixes the reconciliation of node pools
+                  ` + "```" + `
+
+The default is ticket and label, ticket alone with --local. Pull requests
+with one of the --skip-label labels are exempt. With --from and --to, every
+pull request merged between the references is checked; the commits of the
+merged branches belong to their pull request. With --commits, every commit
+outside of a pull request merge is checked as well, for repositories where
+changes are pushed without pull requests.
+
+Examples:
+  drivio changelog-verify --owner openshift --repo hypershift --pr 4242
+  drivio changelog-verify --owner openshift --repo hypershift --pr 4242 --require ticket,release-note --skip-label no-changelog
+  drivio changelog-verify --owner openshift --repo hypershift --from v0.1.62 --to main
+  drivio changelog-verify --local . --from origin/main --to HEAD --commits`,
+	Args: cobra.NoArgs,
+	RunE: runChangelogVerify,
+}
+
+func init() {
+	rootCmd.AddCommand(changelogVerifyCmd)
+
+	changelogVerifyCmd.Flags().StringVar(&verifyOwner, "owner", "", "GitHub repository owner/organization")
+	changelogVerifyCmd.Flags().StringVar(&verifyRepo, "repo", "", "GitHub repository name")
+	changelogVerifyCmd.Flags().IntVar(&verifyPR, "pr", 0, "Pull request to check")
+	changelogVerifyCmd.Flags().StringVar(&verifyFrom, "from", "", "Check the changes since this reference (tag, commit, or branch)")
+	changelogVerifyCmd.Flags().StringVar(&verifyTo, "to", "", "Check the changes until this reference")
+	changelogVerifyCmd.Flags().StringVar(&verifyLocal, "local", "", "Read the commits from this local clone instead of the GitHub API")
+	changelogVerifyCmd.Flags().StringVar(&verifyGitHubToken, "github-token", "", "GitHub token for authentication (optional)")
+	changelogVerifyCmd.Flags().StringSliceVar(&verifyRequire, "require", nil, "Checks every change must pass: "+strings.Join(git.ChangelogChecks, ", ")+" (default: ticket,label, or ticket with --local)")
+	changelogVerifyCmd.Flags().StringVar(&verifyLabel, "label", git.DefaultLabel, "Label required by the label check")
+	changelogVerifyCmd.Flags().StringSliceVar(&verifySkipLabels, "skip-label", nil, "Exempt the pull requests with one of these labels, e.g. no-changelog")
+	changelogVerifyCmd.Flags().BoolVar(&verifyCommits, "commits", false, "With --from/--to, also check the commits outside of a pull request merge")
+
+	// Environment variables that take precedence over the config file
+	bindFlagEnv(changelogVerifyCmd.Flags(), "github-token", config.EnvGitHubToken...)
+}
+
+func runChangelogVerify(cmd *cobra.Command, args []string) error {
+	switch {
+	case verifyPR != 0 && (verifyFrom != "" || verifyTo != ""):
+		return fmt.Errorf("--pr and --from/--to are exclusive")
+	case verifyPR != 0 && verifyLocal != "":
+		return fmt.Errorf("--pr needs the GitHub API: --local only checks --from/--to ranges")
+	case verifyPR == 0 && (verifyFrom == "" || verifyTo == ""):
+		return fmt.Errorf("either --pr, or --from and --to are required")
+	case verifyLocal == "" && (verifyOwner == "" || verifyRepo == ""):
+		return fmt.Errorf("--owner and --repo are required, unless --local is given")
+	}
+
+	checks := verifyRequire
+	if len(checks) == 0 {
+		checks = []string{git.CheckTicket, git.CheckLabel}
+		if verifyLocal != "" {
+			checks = []string{git.CheckTicket}
+		}
+	}
+	if err := git.CheckChecks(checks); err != nil {
+		return err
+	}
+	if verifyLocal != "" && containsFold(checks, git.CheckLabel) {
+		return fmt.Errorf("the label check needs the GitHub API: labels are not known to local clones")
+	}
+	verifier := &git.ChangelogVerifier{
+		Checks:        checks,
+		TicketPattern: git.DefaultTicketPattern,
+		Label:         verifyLabel,
+		SkipLabels:    verifySkipLabels,
+	}
+	// A failed check is the result, not a misuse of the command
+	cmd.SilenceUsage = true
+
+	ctx := context.Background()
+	changes, err := proposedChanges(ctx)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		ui.Printf("✅ No pull requests merged between %s and %s\n", verifyFrom, verifyTo)
+		return nil
+	}
+
+	failed := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "CHANGE\tTITLE\t%s\n", strings.ToUpper(strings.Join(checks, "\t")))
+	for _, change := range changes {
+		skipped, results := verifier.Verify(change)
+		if skipped != "" {
+			ui.Fprintf(w, "%s\t%s\t⏭️  skipped (%s)\n", change.Name, truncateTitle(change.Title), skipped)
+			continue
+		}
+		cells := make([]string, 0, len(results))
+		ok := true
+		for _, result := range results {
+			if result.OK {
+				cells = append(cells, "✅ "+result.Detail)
+			} else {
+				cells = append(cells, "❌ "+result.Detail)
+				ok = false
+			}
+		}
+		if !ok {
+			failed++
+		}
+		ui.Fprintf(w, "%s\t%s\t%s\n", change.Name, truncateTitle(change.Title), strings.Join(cells, "\t"))
+	}
+	w.Flush()
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d changes do not follow the conventions of the release notes (%s)", failed, len(changes), strings.Join(checks, ", "))
+	}
+	ui.Printf("\n✅ %d changes follow the conventions of the release notes\n", len(changes))
+	return nil
+}
+
+// proposedChanges returns the pull request of --pr, or the pull requests
+// merged between --from and --to, and the commits outside of them with
+// --commits
+func proposedChanges(ctx context.Context) ([]git.ProposedChange, error) {
+	client := github.NewClient(verifyGitHubToken)
+	if verifyPR != 0 {
+		var pr *github.PullRequest
+		if err := ui.RunSpinner(fmt.Sprintf("Getting pull request #%d...", verifyPR), func() error {
+			var err error
+			pr, err = client.GetPullRequest(ctx, verifyOwner, verifyRepo, verifyPR)
+			return err
+		}); err != nil {
+			return nil, err
+		}
+		return []git.ProposedChange{pullRequestChange(pr)}, nil
+	}
+
+	var commits []git.CommitInfo
+	if err := ui.RunSpinner("Getting commits between references...", func() error {
+		if verifyLocal != "" {
+			analyzer, err := git.NewLocalAnalyzer(verifyLocal)
+			if err != nil {
+				return err
+			}
+			commits, err = analyzer.Commits(ctx, verifyFrom, verifyTo)
+			return err
+		}
+		var err error
+		commits, err = git.NewAnalyzerWithClient(client).Commits(ctx, verifyOwner, verifyRepo, verifyFrom, verifyTo)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("failed to get commits: %w", err)
+	}
+
+	changes := make([]git.ProposedChange, 0, len(commits))
+	err := ui.RunProgress("Getting the pull requests of the changes...", ui.UnitItems, func(progress chan<- ui.ProgressMsg) error {
+		for i, commit := range commits {
+			progress <- ui.ProgressMsg{Current: int64(i), Total: int64(len(commits)), Message: "Commit " + commit.ShortHash()}
+			switch {
+			case commit.PR != 0 && verifyLocal == "":
+				pr, err := client.GetPullRequest(ctx, verifyOwner, verifyRepo, commit.PR)
+				if err != nil {
+					return err
+				}
+				changes = append(changes, pullRequestChange(pr))
+			case commit.PR != 0:
+				// The merge commit holds the title of the pull request, and
+				// the description too when squashed
+				changes = append(changes, git.ProposedChange{Name: fmt.Sprintf("#%d", commit.PR), Title: strings.SplitN(commit.Body, "\n", 2)[0], Text: commit.Body, PullRequest: true})
+			case !verifyCommits || strings.HasPrefix(commit.Subject, "Merge "):
+				// The commits of merged branches belong to their pull
+				// request, and merges of branches bring no change of their own
+			default:
+				changes = append(changes, git.ProposedChange{Name: commit.ShortHash(), Title: commit.Subject, Text: commit.Subject + "\n" + commit.Body})
+			}
+		}
+		progress <- ui.ProgressMsg{Current: int64(len(commits)), Total: int64(len(commits))}
+		return nil
+	})
+	return changes, err
+}
+
+// pullRequestChange returns the change of a pull request
+func pullRequestChange(pr *github.PullRequest) git.ProposedChange {
+	return git.ProposedChange{
+		Name:        fmt.Sprintf("#%d", pr.Number),
+		Title:       pr.Title,
+		Text:        pr.Title + "\n" + pr.Body,
+		Labels:      pr.LabelNames(),
+		PullRequest: true,
+	}
+}
+
+// truncateTitle shortens a title to fit a table column
+func truncateTitle(title string) string {
+	if runes := []rune(title); len(runes) > 60 {
+		return string(runes[:59]) + "…"
+	}
+	return title
+}
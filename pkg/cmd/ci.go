@@ -0,0 +1,332 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"drivio/pkg/audit"
+	"drivio/pkg/config"
+	"drivio/pkg/diff"
+	"drivio/pkg/drivio"
+	"drivio/pkg/redact"
+	"drivio/pkg/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	ciReport   string
+	ciMR       int
+	ciFrom     string
+	ciTo       string
+	ciSections bool
+	ciTemplate string
+	ciExitCode bool
+	ciDryRun   bool
+)
+
+// Exit statuses of ci, beside 0 and 1 for errors
+const (
+	// ciStatusAttention is the status of --exit-code when the comment calls
+	// for attention, for allow_failure: exit_codes
+	ciStatusAttention = 2
+)
+
+// ciCmd represents the ci command
+var ciCmd = &cobra.Command{
+	Use:   "ci",
+	Short: "Comment the release notes or config diff of a GitLab merge request pipeline",
+	Long: `Run in a GitLab CI merge request pipeline, generate a report of the merge
+request and post it as a comment on the merge request, so reviewers see it
+without opening the job log:
+
+  notes  the release notes of the changes of the merge request (default)
+  diff   the differences of the config file --file between the target branch
+         and the merge request, key by key as by diff
+
+The merge request, the project and the instance are those of the pipeline,
+read from the predefined CI/CD variables (CI_MERGE_REQUEST_IID,
+CI_PROJECT_PATH, CI_SERVER_URL), and the changes are those between
+CI_MERGE_REQUEST_DIFF_BASE_SHA and CI_COMMIT_SHA; --mr, --repo, --url,
+--from and --to set them outside a pipeline. The CI_JOB_TOKEN cannot
+comment: --token (DRIVIO_GITLAB_TOKEN, a masked CI/CD variable) must have
+the api scope.
+
+The comment is posted once and updated by the following pipelines of the
+merge request. In a pipeline that is not for a merge request, e.g. of a
+branch, there is nothing to comment on and ci succeeds.
+
+Exit statuses, for the rules of the job:
+
+  0  the comment was posted, or there is no merge request
+  1  the report or the comment failed
+  2  with --exit-code, the comment calls for attention: the config file
+     differs (diff), or no change of the merge request is noted (notes).
+     allow_failure: exit_codes: 2 shows it as a warning.
+
+Examples:
+  drivio ci
+  drivio ci --report diff --file config/app.yaml --exit-code
+  drivio ci --repo mygroup/myproject --mr 42 --from main --to my-branch --dry-run`,
+	RunE: runCI,
+}
+
+func init() {
+	rootCmd.AddCommand(ciCmd)
+
+	// Add flags
+	ciCmd.Flags().StringVar(&ciReport, "report", "notes", "Report posted on the merge request: notes or diff")
+	ciCmd.Flags().StringVar(&gitlabURL, "url", "", "GitLab URL (default: CI_SERVER_URL)")
+	ciCmd.Flags().StringVar(&gitlabToken, "token", "", "GitLab access token with the api scope")
+	ciCmd.Flags().StringVar(&gitlabInstance, "instance", "", "GitLab instance of the config file providing the URL and token")
+	ciCmd.Flags().StringVar(&repositoryPath, "repo", "", "Repository path (default: CI_PROJECT_PATH)")
+	ciCmd.Flags().IntVar(&ciMR, "mr", 0, "IID of the merge request commented on (default: CI_MERGE_REQUEST_IID)")
+	ciCmd.Flags().StringVar(&ciFrom, "from", "", "Branch, tag or commit the merge request starts from (default: CI_MERGE_REQUEST_DIFF_BASE_SHA)")
+	ciCmd.Flags().StringVar(&ciTo, "to", "", "Branch, tag or commit of the merge request (default: CI_COMMIT_SHA)")
+	ciCmd.Flags().StringVar(&filePath, "file", "", "Config file compared by --report diff")
+	ciCmd.Flags().BoolVar(&ciSections, "sections", true, "Group the changes of the notes by type")
+	ciCmd.Flags().StringVar(&ciTemplate, "template", "", "Go template file rendering the notes")
+	ciCmd.Flags().BoolVar(&showSecrets, "show-secrets", false, "Do not redact sensitive values of the diff")
+	ciCmd.Flags().StringSliceVar(&redactPatterns, "redact-keys", nil, "Key patterns (regular expressions) whose values are redacted (default: password,passwd,token,secret,key,credential)")
+	ciCmd.Flags().BoolVar(&ciExitCode, "exit-code", false, "Exit with status 2 when the config file differs or no change is noted")
+	ciCmd.Flags().BoolVar(&ciDryRun, "dry-run", false, "Print the comment instead of posting it")
+
+	// Environment variables that take precedence over the config file
+	bindFlagEnv(ciCmd.Flags(), "url", config.EnvGitLabURL...)
+	bindFlagEnv(ciCmd.Flags(), "token", config.EnvGitLabToken...)
+	bindFlagEnv(ciCmd.Flags(), "instance", config.EnvInstance...)
+}
+
+// ciComment is a report posted on a merge request
+type ciComment struct {
+	// marker identifies the comment of the report among those of the merge
+	// request, to update it
+	marker    string
+	body      string
+	attention error
+}
+
+func runCI(cmd *cobra.Command, args []string) error {
+	if ciReport != "notes" && ciReport != "diff" {
+		return fmt.Errorf("invalid --report %q: use notes or diff", ciReport)
+	}
+	if ciReport == "diff" && filePath == "" {
+		return fmt.Errorf("--report diff needs --file, the config file compared")
+	}
+
+	gitlabCI := os.Getenv("GITLAB_CI") == "true"
+	if err := applyCIVariables(cmd); err != nil {
+		return err
+	}
+	if ciMR == 0 {
+		if gitlabCI {
+			ui.Printf("⏭️  Not a merge request pipeline: nothing to comment on\n")
+			return nil
+		}
+		return fmt.Errorf("not in a GitLab CI merge request pipeline: run it in a job of merge request pipelines, or set --mr")
+	}
+	if repositoryPath == "" {
+		return fmt.Errorf("no repository: set --repo")
+	}
+	if ciFrom == "" || ciTo == "" {
+		return fmt.Errorf("no range of changes: set --from and --to")
+	}
+	if gitlabToken == "" && !ciDryRun {
+		return fmt.Errorf("GitLab token with the api scope is required to comment on the merge request, which the CI_JOB_TOKEN cannot: set DRIVIO_GITLAB_TOKEN as a masked CI/CD variable or use --token")
+	}
+
+	// A failure of the job is reported by its status, not a misuse of the
+	// command
+	cmd.SilenceUsage = true
+
+	ctx := context.Background()
+	cfg := loadFetchConfig()
+	var comment *ciComment
+	var err error
+	if ciReport == "diff" {
+		comment, err = ciDiffComment(ctx, cfg)
+	} else {
+		comment, err = ciNotesComment(ctx, cfg)
+	}
+	if err != nil {
+		return err
+	}
+
+	if ciDryRun {
+		ui.Printf("💬 Would comment on !%d of %s:\n\n", ciMR, cfg.RepositoryPath)
+		fmt.Print(comment.body)
+	} else if err := postCIComment(ctx, cfg, comment); err != nil {
+		return err
+	}
+
+	if comment.attention != nil {
+		ui.Printf("⚠️  %v\n", comment.attention)
+		if ciExitCode {
+			return &exitStatusError{status: ciStatusAttention, err: comment.attention}
+		}
+	}
+	return nil
+}
+
+// applyCIVariables fills the flags not set otherwise with the predefined
+// variables of GitLab CI
+func applyCIVariables(cmd *cobra.Command) error {
+	fallbacks := []struct {
+		value *string
+		name  string
+	}{
+		{&gitlabURL, "CI_SERVER_URL"},
+		{&repositoryPath, "CI_PROJECT_PATH"},
+		{&ciFrom, "CI_MERGE_REQUEST_DIFF_BASE_SHA"},
+		{&ciTo, "CI_COMMIT_SHA"},
+	}
+	for _, fallback := range fallbacks {
+		if *fallback.value == "" {
+			*fallback.value = os.Getenv(fallback.name)
+		}
+	}
+
+	if !cmd.Flags().Changed("mr") {
+		if iid := os.Getenv("CI_MERGE_REQUEST_IID"); iid != "" {
+			var err error
+			if ciMR, err = strconv.Atoi(iid); err != nil {
+				return fmt.Errorf("invalid CI_MERGE_REQUEST_IID %q: %w", iid, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ciNotesComment returns the comment of the release notes of the changes
+// of the merge request, read from a mirror of the project like those of
+// serve
+func ciNotesComment(ctx context.Context, cfg *config.Config) (*ciComment, error) {
+	rules, err := classificationRules(activeFileConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	var src drivio.Source
+	dir := filepath.Join(cacheRoot(workDir), "clones", cloneDirName(cfg.RepositoryPath, "", true))
+	if err := ui.RunProgress(fmt.Sprintf("Mirroring %s...", cfg.RepositoryPath), ui.UnitItems, func(progress chan<- ui.ProgressMsg) error {
+		var err error
+		src, err = drivio.NewGitLabWithProgress(ctx, cfg.GitLabURL, cfg.GitLabToken, cfg.RepositoryPath, dir, func(done, total int64, phase string) {
+			progress <- ui.ProgressMsg{Current: done, Total: total, Message: phase}
+		})
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	notes, err := drivio.Generate(ctx, src, ciFrom, ciTo, drivio.Options{Label: drivio.DefaultLabel, Rules: rules})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate release notes: %w", err)
+	}
+	renderOpts := drivio.RenderOptions{Sections: ciSections}
+	if ciTemplate != "" {
+		renderOpts.TemplateFiles = []string{ciTemplate}
+	}
+	output, err := drivio.Render(src, notes, renderOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to format release notes: %w", err)
+	}
+	ui.Printf("📝 %d of %d changes noted\n", notes.Statistics.Selected, notes.Statistics.Total)
+
+	comment := &ciComment{marker: "<!-- drivio:ci:notes -->"}
+	var body strings.Builder
+	fmt.Fprintf(&body, "%s\n## 📝 Release notes of this merge request\n\n", comment.marker)
+	if notes.Statistics.Selected == 0 {
+		comment.attention = fmt.Errorf("none of the %d changes of the merge request is noted", notes.Statistics.Total)
+		fmt.Fprintf(&body, "None of the %d changes of this merge request is noted: their messages need a `TICKET: description` line.\n", notes.Statistics.Total)
+	} else {
+		body.WriteString(strings.TrimSpace(nestMarkdown(output)) + "\n")
+	}
+	fmt.Fprintf(&body, "\n<sub>drivio, changes %s..%s</sub>\n", shortSHA(ciFrom), shortSHA(ciTo))
+	comment.body = body.String()
+	return comment, nil
+}
+
+// ciDiffComment returns the comment of the differences of the config file
+// between the start of the merge request and its changes
+func ciDiffComment(ctx context.Context, cfg *config.Config) (*ciComment, error) {
+	redactor, err := redact.New(redactPatterns)
+	if err != nil {
+		return nil, err
+	}
+	from, err := diffConfig(cfg, ciFrom, "")
+	if err != nil {
+		return nil, err
+	}
+	to, err := diffConfig(cfg, ciTo, "")
+	if err != nil {
+		return nil, err
+	}
+	changes, err := diffSides(ctx, []*diffSide{{cfg: from}, {cfg: to}}, redactor)
+	if err != nil {
+		return nil, err
+	}
+	ui.Printf("🔍 %d difference(s)\n", len(changes))
+
+	comment := &ciComment{marker: "<!-- drivio:ci:diff:" + cfg.FilePath + " -->"}
+	var body strings.Builder
+	fmt.Fprintf(&body, "%s\n## 🔍 Changes of `%s`\n\n", comment.marker, cfg.FilePath)
+	if len(changes) == 0 {
+		body.WriteString("This merge request does not change the configuration.\n")
+	} else {
+		comment.attention = fmt.Errorf("%s differs: %d difference(s)", cfg.FilePath, len(changes))
+		body.WriteString("```diff\n")
+		for _, change := range changes {
+			body.WriteString(ciDiffLines(change) + "\n")
+		}
+		body.WriteString("```\n")
+	}
+	fmt.Fprintf(&body, "\n<sub>drivio, changes %s..%s</sub>\n", shortSHA(ciFrom), shortSHA(ciTo))
+	comment.body = body.String()
+	return comment, nil
+}
+
+// ciDiffLines returns a change as lines of a diff code block, whose first
+// character GitLab colors: a changed value is removed and added
+func ciDiffLines(change diff.Change) string {
+	if change.Kind == diff.Changed {
+		return fmt.Sprintf("- %s: %s\n+ %s: %s", change.Path, change.Old, change.Path, change.New)
+	}
+	return change.String()
+}
+
+// postCIComment posts the comment on the merge request, or updates the one
+// posted by a previous pipeline
+func postCIComment(ctx context.Context, cfg *config.Config, comment *ciComment) error {
+	client, err := newFetchClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	updated := false
+	if err := ui.RunSpinner(fmt.Sprintf("Commenting on !%d...", ciMR), func() error {
+		existing, err := client.FindMergeRequestNote(ctx, ciMR, comment.marker)
+		if err != nil {
+			return err
+		}
+		noteID := 0
+		if existing != nil {
+			noteID, updated = existing.ID, true
+		}
+		_, err = client.PostMergeRequestNote(ctx, ciMR, noteID, comment.body)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/%s/-/merge_requests/%d", strings.TrimRight(cfg.GitLabURL, "/"), cfg.RepositoryPath, ciMR)
+	if updated {
+		ui.Printf("💬 Updated the comment of %s\n", url)
+	} else {
+		ui.Printf("💬 Commented on %s\n", url)
+	}
+	auditAction(audit.ActionComment, fmt.Sprintf("%s!%d", cfg.RepositoryPath, ciMR), url, map[string]string{"report": ciReport, "from": ciFrom, "to": ciTo})
+	return nil
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+
+	"drivio/pkg/config"
+	"drivio/pkg/git"
+)
+
+// classificationRule is a rule of the classification list of the
+// configuration file
+type classificationRule struct {
+	Type    string `mapstructure:"type"`
+	Subject string `mapstructure:"subject"`
+	Body    string `mapstructure:"body"`
+	Label   string `mapstructure:"label"`
+}
+
+// classificationRules reads the rules giving a type to the commits without a
+// conventional one, e.g.
+//
+//	classification:
+//	  - type: fix
+//	    subject: '^(BUGFIX|HOTFIX):'
+//	  - type: feat
+//	    subject: '^\[feat\]'
+//	  - type: fix
+//	    label: kind/bug
+func classificationRules(fileConfig *config.FileConfig) ([]git.TypeRule, error) {
+	if fileConfig == nil || !fileConfig.IsSet(config.ClassificationKey) {
+		return nil, nil
+	}
+
+	var entries []classificationRule
+	if err := fileConfig.Unmarshal(config.ClassificationKey, &entries); err != nil {
+		return nil, fmt.Errorf("invalid %s in config file: %w", config.ClassificationKey, err)
+	}
+
+	rules := make([]git.TypeRule, 0, len(entries))
+	for i, entry := range entries {
+		rule, err := git.NewTypeRule(entry.Type, entry.Subject, entry.Body, entry.Label)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s rule %d in config file: %w", config.ClassificationKey, i+1, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
@@ -4,13 +4,25 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
+
+	"drivio/pkg/ui"
+	"drivio/pkg/workdir"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	cleanWorkDir string
-	cleanForce   bool
+	cleanForce     bool
+	cleanOlderThan string
+	cleanMaxSize   string
+	cleanOnly      []string
+	cleanMatch     []string
+	cleanCache     bool
+	cleanData      bool
+	cleanTrashFor  string
+	cleanRestore   string
 )
 
 // cleanCmd represents the clean command
@@ -20,10 +32,36 @@ var cleanCmd = &cobra.Command{
 	Long: `Clean up the work directory by removing all downloaded files and cloned repositories.
 
 This command removes all files and directories in the work directory to free up disk space.
+With --older-than, only the entries last modified before the given age are
+removed; a directory counts as modified when any file inside it is. With
+--max-size, the oldest entries are removed until the work directory fits in
+the given size. Both can be combined.
+
+--only and --match select the entries to consider by type or by name (glob),
+e.g. to purge bulky repository archives while keeping generated release
+notes. The types are archives, artifacts, clones, files (fetched files),
+groups, history, manifest, notes (release notes), releases and other.
+
+When caches and data live in their own directories (see --cache-dir,
+--data-dir and --xdg), --cache and --data clean them as well.
+
+Cleaned entries are moved to the .trash directory of the directory cleaned
+and removed for good on a later clean, once older than --trash-for. Until
+then, --restore puts the most recently cleaned entry with the given name
+back. Use --trash-for 0 to remove entries right away. Entries exceeding
+--older-than or --max-size are removed right away, to free the space now,
+and the trash counts toward --max-size: its oldest entries go first.
 
 Examples:
   drivio clean
   drivio clean --work-dir /tmp/drivio-work
+  drivio clean --older-than 7d
+  drivio clean --max-size 500MB --force
+  drivio clean --only archives,artifacts
+  drivio clean --match 'release-notes-*' --older-than 30d
+  drivio clean --xdg --cache --only archives
+  drivio clean --restore release-notes-myorg-myrepo-v1.0.0-v1.1.0.md
+  drivio clean --trash-for 0 --force
   drivio clean --force`,
 	RunE: runClean,
 }
@@ -32,65 +70,279 @@ func init() {
 	rootCmd.AddCommand(cleanCmd)
 
 	// Add flags
-	cleanCmd.Flags().StringVar(&cleanWorkDir, "work-dir", ".drivio-work", "Working directory to clean")
 	cleanCmd.Flags().BoolVar(&cleanForce, "force", false, "Force cleanup without confirmation")
+	cleanCmd.Flags().StringVar(&cleanMaxSize, "max-size", "", "Remove the oldest entries until the work directory fits in this size, e.g. 500MB or 2GB")
+	cleanCmd.Flags().StringSliceVar(&cleanOnly, "only", nil, "Only remove entries of these types: "+strings.Join(workdir.Types(), ", "))
+	cleanCmd.Flags().StringSliceVar(&cleanMatch, "match", nil, "Only remove entries whose name matches one of these glob patterns, e.g. 'release-notes-*'")
+	cleanCmd.Flags().BoolVar(&cleanCache, "cache", false, "Also clean the cache directory, when separate from the work directory")
+	cleanCmd.Flags().BoolVar(&cleanData, "data", false, "Also clean the data directory, when separate from the work directory")
+	cleanCmd.Flags().StringVar(&cleanOlderThan, "older-than", "", "Only remove entries last modified longer ago than this age, e.g. 36h, 7d or 2w")
+	cleanCmd.Flags().StringVar(&cleanTrashFor, "trash-for", "7d", "Keep cleaned entries in the trash for this long before removing them for good; 0 removes them right away")
+	cleanCmd.Flags().StringVar(&cleanRestore, "restore", "", "Restore the most recently cleaned entry with this name from the trash")
 }
 
 func runClean(cmd *cobra.Command, args []string) error {
-	// Check if work directory exists
-	if _, err := os.Stat(cleanWorkDir); os.IsNotExist(err) {
-		fmt.Printf("📁 Work directory does not exist: %s\n", cleanWorkDir)
+	var maxAge time.Duration
+	if cleanOlderThan != "" {
+		age, err := workdir.ParseAge(cleanOlderThan)
+		if err != nil {
+			return fmt.Errorf("invalid --older-than: %w", err)
+		}
+		maxAge = age
+	}
+	var maxSize int64
+	if cleanMaxSize != "" {
+		size, err := workdir.ParseSize(cleanMaxSize)
+		if err != nil {
+			return fmt.Errorf("invalid --max-size: %w", err)
+		}
+		maxSize = size
+	}
+
+	trashFor, err := workdir.ParseAge(cleanTrashFor)
+	if err != nil {
+		return fmt.Errorf("invalid --trash-for: %w", err)
+	}
+
+	if cleanRestore != "" {
+		return restoreCleaned(cleanRestore)
+	}
+
+	dirs := []cleanTarget{{"Work directory", workDir}}
+	if cleanCache && cacheRoot(workDir) != workDir {
+		dirs = append(dirs, cleanTarget{"Cache directory", cacheRoot(workDir)})
+	}
+	if cleanData && dataRoot(workDir) != workDir && dataRoot(workDir) != cacheRoot(workDir) {
+		dirs = append(dirs, cleanTarget{"Data directory", dataRoot(workDir)})
+	}
+
+	for _, dir := range dirs {
+		if err := cleanDirectory(dir.label, dir.path, maxAge, maxSize, trashFor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cleanTarget is a directory cleaned by clean, with its label for messages
+type cleanTarget struct {
+	label string
+	path  string
+}
+
+// cleanDirectory moves the entries of a directory selected by the flags to
+// its trash, or removes them when trashFor is 0 or they exceed the limits,
+// after purging the trash of the entries older than trashFor
+func cleanDirectory(label, dir string, maxAge time.Duration, maxSize int64, trashFor time.Duration) error {
+	// Check if the directory exists
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		ui.Printf("📁 %s does not exist: %s\n", label, dir)
 		return nil
 	}
 
-	// Get directory contents for confirmation
-	entries, err := os.ReadDir(cleanWorkDir)
+	// Don't pull the directory from under a running drivio process, nor
+	// the artifact index of the work directory, which records its entries
+	unlock, err := lockCleanedDirs(dir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	defer pruneArtifactIndex(workDir)
+
+	now := time.Now()
+	purged, err := workdir.PurgeTrash(dir, trashFor, now)
+	for _, entry := range purged {
+		ui.Debugf(1, "Purged %s from the trash, cleaned at %s", entry.Name, entry.TrashedAt.Local().Format("2006-01-02 15:04:05"))
+	}
+	if err != nil {
+		return err
+	}
+
+	// Get directory contents for confirmation, leaving our own lock alone
+	entries, err := workdir.List(dir)
 	if err != nil {
-		return fmt.Errorf("failed to read work directory: %w", err)
+		return err
 	}
 
-	if len(entries) == 0 {
-		fmt.Printf("📁 Work directory is already empty: %s\n", cleanWorkDir)
+	// The trash counts toward --max-size, even once the directory is empty
+	if len(entries) == 0 && cleanMaxSize == "" {
+		ui.Printf("📁 %s is already empty: %s\n", label, dir)
 		return nil
 	}
 
+	if len(cleanOnly) > 0 || len(cleanMatch) > 0 {
+		if entries, err = workdir.Filter(entries, cleanOnly, cleanMatch); err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			ui.Printf("📁 No entries of the selected types or names in: %s\n", dir)
+			return nil
+		}
+	}
+
+	var trashOver []workdir.TrashedEntry
+	if cleanOlderThan != "" || cleanMaxSize != "" {
+		// Trashing the entries would not free any space for --trash-for
+		trashFor = 0
+		var selected []workdir.Entry
+		if cleanOlderThan != "" {
+			selected = workdir.OlderThan(entries, maxAge, time.Now())
+		}
+		if cleanMaxSize != "" {
+			// What is left after removing the old entries must fit, with
+			// the trash, whose oldest entries go first
+			left := workdir.Without(entries, selected)
+			var size int64
+			for _, entry := range left {
+				size += entry.Size
+			}
+			if trashOver, err = workdir.TrashOver(dir, max(maxSize-size, 0)); err != nil {
+				return err
+			}
+			selected = append(selected, workdir.OverSize(left, maxSize)...)
+		}
+		if len(selected) == 0 && len(trashOver) == 0 {
+			ui.Printf("📁 Nothing to clean in %s: %s\n", dir, describeCleanLimits())
+			return nil
+		}
+		entries = selected
+	}
+
 	// Show what will be deleted
-	fmt.Printf("📁 Work directory: %s\n", cleanWorkDir)
-	fmt.Printf("🗑️  Found %d items to clean:\n", len(entries))
+	ui.Printf("📁 %s: %s\n", label, dir)
+	ui.Printf("🗑️  Found %d items to clean:\n", len(entries)+len(trashOver))
 	for _, entry := range entries {
-		info, err := entry.Info()
-		if err != nil {
-			fmt.Printf("  - %s (error getting info)\n", entry.Name())
+		if entry.IsDir {
+			ui.Printf("  - 📁 %s (directory, %s)\n", entry.Name, ui.FormatSize(entry.Size))
 		} else {
-			if entry.IsDir() {
-				fmt.Printf("  - 📁 %s (directory)\n", entry.Name())
-			} else {
-				fmt.Printf("  - 📄 %s (%d bytes)\n", entry.Name(), info.Size())
-			}
+			ui.Printf("  - 📄 %s (%s)\n", entry.Name, ui.FormatSize(entry.Size))
 		}
 	}
+	for _, entry := range trashOver {
+		ui.Printf("  - 🗑️  %s (in the trash since %s, %s)\n", entry.Name, entry.TrashedAt.Local().Format("2006-01-02 15:04"), ui.FormatSize(entry.Size))
+	}
 
 	// Ask for confirmation unless --force is used
 	if !cleanForce {
-		fmt.Print("\n❓ Are you sure you want to delete all these files? (y/N): ")
+		ui.Printf("\n❓ Are you sure you want to delete all these files? (y/N): ")
 		var response string
 		fmt.Scanln(&response)
 		if response != "y" && response != "Y" {
-			fmt.Println("❌ Cleanup cancelled")
+			ui.Println("❌ Cleanup cancelled")
 			return nil
 		}
 	}
 
+	for _, entry := range trashOver {
+		if err := workdir.Purge(entry); err != nil {
+			ui.Printf("⚠️  Warning: %v\n", err)
+		} else {
+			ui.Printf("✅ Removed from the trash: %s\n", entry.Name)
+		}
+	}
+
 	// Remove all contents
+	ix := workdir.NewIndex(workDir)
+	trashed := 0
 	for _, entry := range entries {
-		entryPath := filepath.Join(cleanWorkDir, entry.Name())
-		if err := os.RemoveAll(entryPath); err != nil {
-			fmt.Printf("⚠️  Warning: failed to remove %s: %v\n", entryPath, err)
+		if trashFor == 0 {
+			if err := os.RemoveAll(entry.Path); err != nil {
+				ui.Printf("⚠️  Warning: failed to remove %s: %v\n", entry.Path, err)
+			} else {
+				ui.Printf("✅ Removed: %s\n", entry.Name)
+			}
+			continue
+		}
+		if err := workdir.MoveToTrash(dir, entry, now, ix); err != nil {
+			ui.Printf("⚠️  Warning: %v\n", err)
 		} else {
-			fmt.Printf("✅ Removed: %s\n", entry.Name())
+			ui.Printf("✅ Moved to trash: %s\n", entry.Name)
+			trashed++
 		}
 	}
 
-	fmt.Printf("🧹 Cleanup completed for: %s\n", cleanWorkDir)
+	ui.Printf("🧹 Cleanup completed for: %s\n", dir)
+	if trashed > 0 {
+		ui.Printf("💡 Kept in %s for %s; undo with: drivio clean --restore <name>\n", filepath.Join(dir, workdir.TrashDir), cleanTrashFor)
+	}
 	return nil
 }
+
+// restoreCleaned puts the most recently cleaned entry with the given name
+// back from the trash of the work, cache or data directory
+func restoreCleaned(name string) error {
+	var (
+		found    *workdir.TrashedEntry
+		foundDir string
+		names    []string
+		seen     = make(map[string]bool)
+	)
+	for _, dir := range managedDirs(workDir) {
+		trashed, err := workdir.ListTrash(dir)
+		if err != nil {
+			return err
+		}
+		for i, entry := range trashed {
+			if !seen[entry.Name] {
+				seen[entry.Name] = true
+				names = append(names, entry.Name)
+			}
+			if entry.Name == name && (found == nil || !entry.TrashedAt.Before(found.TrashedAt)) {
+				found, foundDir = &trashed[i], dir
+			}
+		}
+	}
+	if found == nil {
+		if len(names) == 0 {
+			return fmt.Errorf("%s not found: the trash is empty", name)
+		}
+		return fmt.Errorf("%s not found in the trash (available: %s)", name, strings.Join(names, ", "))
+	}
+
+	unlock, err := lockCleanedDirs(foundDir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	target, err := workdir.Restore(foundDir, *found, workdir.NewIndex(workDir))
+	if err != nil {
+		return err
+	}
+	ui.Printf("⏪ Restored %s, cleaned at %s, to: %s\n", name, found.TrashedAt.Local().Format("2006-01-02 15:04:05"), target)
+	return nil
+}
+
+// lockCleanedDirs locks a directory cleaned and the work directory, whose
+// artifact index records the entries of the cache and data directories too
+func lockCleanedDirs(dir string) (func(), error) {
+	if _, err := os.Stat(workDir); err != nil || dir == workDir {
+		return lockWorkDir(dir)
+	}
+	unlockWorkDir, err := lockWorkDir(workDir)
+	if err != nil {
+		return nil, err
+	}
+	unlock, err := lockWorkDir(dir)
+	if err != nil {
+		unlockWorkDir()
+		return nil, err
+	}
+	return func() {
+		unlock()
+		unlockWorkDir()
+	}, nil
+}
+
+// describeCleanLimits describes the limits given to clean when nothing
+// exceeds them, e.g. "nothing older than 7d, within 500MB"
+func describeCleanLimits() string {
+	var limits []string
+	if cleanOlderThan != "" {
+		limits = append(limits, "nothing older than "+cleanOlderThan)
+	}
+	if cleanMaxSize != "" {
+		limits = append(limits, "within "+cleanMaxSize)
+	}
+	return strings.Join(limits, ", ")
+}
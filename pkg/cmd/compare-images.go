@@ -0,0 +1,281 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"text/tabwriter"
+
+	"drivio/pkg/config"
+	"drivio/pkg/diff"
+	"drivio/pkg/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	compareImagesFrom     string
+	compareImagesTo       string
+	compareImagesFromEnv  string
+	compareImagesToEnv    string
+	compareImagesFromFile string
+	compareImagesToFile   string
+	compareImagesMap      []string
+	compareImagesFormat   string
+	compareImagesExitCode bool
+)
+
+// compareImagesCmd represents the compare-images command
+var compareImagesCmd = &cobra.Command{
+	Use:   "compare-images",
+	Short: "Compare the container images of two environments",
+	Long: `Fetch the config files of two environments, or one file at two references, and
+report the components whose container images differ: the images added,
+removed, or whose tag or digest changed.
+
+The sides are selected as by diff: --from and --to are branches, tags or
+commits, and --from-env and --to-env resolve the file of each side through
+the {env} placeholder of --file or --file-pattern. --from-file and --to-file
+compare two files of the repository whatever their names.
+
+A component is an image repository, e.g. quay.io/openshift/hypershift,
+wherever it is referenced: values of keys ending in image (image,
+releaseImage...), mappings with repository and tag or digest keys, as in Helm
+values, and any other value that is a full reference, with a registry and a
+tag or a digest.
+
+Changed components are linked to the release notes of the GitHub repository
+they are built from, when mapped in the images list of the config file or
+with --map. The image tags are the release tags, after tag-prefix:
+
+  images:
+    - image: quay.io/openshift/hypershift
+      repo: openshift/hypershift
+    - image: quay.io/myorg/*      # path.Match pattern
+      repo: myorg/{name}          # {name} is the last element of the image
+      tag-prefix: v               # image tag 1.2.3 is release tag v1.2.3
+
+Examples:
+  drivio compare-images --repo myorg/configs --file "envs/{env}.yaml" --from-env staging --to-env production
+  drivio compare-images --repo myorg/configs --file envs/production.yaml --from v1.2.0 --to main
+  drivio compare-images --repo myorg/configs --from-file clusters/a/values.yaml --to-file clusters/b/values.yaml --map quay.io/myorg/app=myorg/app
+  drivio compare-images --repo myorg/configs --file "envs/{env}.yaml" --from-env staging --to-env production --format json`,
+	Args: cobra.NoArgs,
+	RunE: runCompareImages,
+}
+
+func init() {
+	rootCmd.AddCommand(compareImagesCmd)
+
+	// Add flags
+	compareImagesCmd.Flags().StringVar(&gitlabURL, "url", "", "GitLab URL (default: https://gitlab.com)")
+	compareImagesCmd.Flags().StringVar(&gitlabToken, "token", "", "GitLab access token")
+	compareImagesCmd.Flags().StringVar(&gitlabInstance, "instance", "", "GitLab instance of the config file providing the URL and token")
+	compareImagesCmd.Flags().StringVar(&repositoryPath, "repo", "", "Repository path (e.g., owner/repo)")
+	compareImagesCmd.Flags().StringVar(&filePath, "file", "", "File path in repository, possibly with an {env} placeholder")
+	compareImagesCmd.Flags().StringVar(&filePattern, "file-pattern", "", "File path pattern with an {env} placeholder (e.g., envs/{env}/values.yaml)")
+	compareImagesCmd.Flags().StringVar(&branch, "branch", "", "Reference of both sides unless --from or --to is given (default: main)")
+	compareImagesCmd.Flags().StringVar(&compareImagesFrom, "from", "", "Branch, tag or commit of the first side")
+	compareImagesCmd.Flags().StringVar(&compareImagesTo, "to", "", "Branch, tag or commit of the second side")
+	compareImagesCmd.Flags().StringVar(&compareImagesFromEnv, "from-env", "", "Environment of the first side, resolving the file path through the file pattern")
+	compareImagesCmd.Flags().StringVar(&compareImagesToEnv, "to-env", "", "Environment of the second side, resolving the file path through the file pattern")
+	compareImagesCmd.Flags().StringVar(&compareImagesFromFile, "from-file", "", "File of the first side (default: --file)")
+	compareImagesCmd.Flags().StringVar(&compareImagesToFile, "to-file", "", "File of the second side (default: --file)")
+	compareImagesCmd.Flags().StringArrayVar(&compareImagesMap, "map", nil, "Map images to the GitHub repository they are built from, as IMAGE=OWNER/REPO; repeatable, before the images list of the config file")
+	compareImagesCmd.Flags().StringVar(&compareImagesFormat, "format", "table", "Output format: table or json")
+	compareImagesCmd.Flags().BoolVar(&compareImagesExitCode, "exit-code", false, "Exit with an error when the images differ")
+
+	// Environment variables that take precedence over the config file
+	bindFlagEnv(compareImagesCmd.Flags(), "url", config.EnvGitLabURL...)
+	bindFlagEnv(compareImagesCmd.Flags(), "token", config.EnvGitLabToken...)
+	bindFlagEnv(compareImagesCmd.Flags(), "instance", config.EnvInstance...)
+	bindFlagEnv(compareImagesCmd.Flags(), "repo", config.EnvRepoPath...)
+	bindFlagEnv(compareImagesCmd.Flags(), "file", config.EnvFilePath...)
+	bindFlagEnv(compareImagesCmd.Flags(), "file-pattern", config.EnvFilePattern...)
+	bindFlagEnv(compareImagesCmd.Flags(), "branch", config.EnvBranch...)
+}
+
+// imageMapping maps container images to the GitHub repository they are
+// built from, in the images list of the configuration file
+type imageMapping struct {
+	// Image is an image repository, or a path.Match pattern of them
+	Image string `mapstructure:"image"`
+	// Repo is owner/repo, where {name} is the last element of the image
+	Repo string `mapstructure:"repo"`
+	// TagPrefix turns image tags into release tags, e.g. v for 1.2.3
+	TagPrefix string `mapstructure:"tag-prefix"`
+}
+
+// componentChange is a component whose images changed, linked to its
+// release notes when mapped
+type componentChange struct {
+	diff.ImageChange
+	// Repo is the GitHub repository the component is built from
+	Repo string `json:"repo,omitempty"`
+	// ReleaseNotes is the release of the new version, and Compare the
+	// changes since the old one, when both versions are tags
+	ReleaseNotes string `json:"release_notes,omitempty"`
+	Compare      string `json:"compare,omitempty"`
+}
+
+func runCompareImages(cmd *cobra.Command, args []string) error {
+	if compareImagesFormat != "table" && compareImagesFormat != "json" {
+		return fmt.Errorf("invalid --format %q: use table or json", compareImagesFormat)
+	}
+	mappings, err := imageMappings(activeFileConfig, compareImagesMap)
+	if err != nil {
+		return err
+	}
+
+	base := loadFetchConfig()
+	sides := make([]*diffSide, 0, 2)
+	for _, side := range []struct{ ref, env, file string }{
+		{compareImagesFrom, compareImagesFromEnv, compareImagesFromFile},
+		{compareImagesTo, compareImagesToEnv, compareImagesToFile},
+	} {
+		sideBase := *base
+		if side.file != "" {
+			sideBase.FilePath, sideBase.FilePattern = side.file, ""
+		}
+		cfg, err := diffConfig(&sideBase, side.ref, side.env)
+		if err != nil {
+			return err
+		}
+		sides = append(sides, &diffSide{cfg: cfg})
+	}
+	if sides[0].name() == sides[1].name() {
+		return fmt.Errorf("both sides are %s@%s: set --from and --to, --from-env and --to-env, or --from-file and --to-file", sides[0].cfg.FilePath, sides[0].cfg.Branch)
+	}
+
+	ctx := context.Background()
+	for i, side := range sides {
+		// Both sides are in the same repository, so it is checked once
+		if i > 0 {
+			side.cfg.Visibility = sides[0].cfg.Visibility
+		}
+		client, err := newFetchClient(side.cfg)
+		if err != nil {
+			return err
+		}
+		if err := ui.RunSpinner(fmt.Sprintf("Fetching %s...", side.name()), func() error {
+			var err error
+			side.content, err = client.GetFile(ctx)
+			return err
+		}); err != nil {
+			return fmt.Errorf("failed to fetch %s: %w", side.name(), err)
+		}
+	}
+
+	imageChanges, err := diff.Images(sides[0].content, sides[1].content)
+	if err != nil {
+		return err
+	}
+	changes := make([]componentChange, 0, len(imageChanges))
+	for _, change := range imageChanges {
+		changes = append(changes, linkComponent(change, mappings))
+	}
+
+	if compareImagesFormat == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(struct {
+			From    string            `json:"from"`
+			To      string            `json:"to"`
+			Changes []componentChange `json:"changes"`
+		}{sides[0].name(), sides[1].name(), changes}); err != nil {
+			return err
+		}
+	} else if len(changes) > 0 {
+		fmt.Printf("--- %s\n+++ %s\n", sides[0].name(), sides[1].name())
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "COMPONENT\tFROM\tTO\tRELEASE NOTES")
+		for _, change := range changes {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+				change.Repository,
+				orDash(diff.Versions(change.From)),
+				orDash(diff.Versions(change.To)),
+				orDash(change.ReleaseNotes),
+			)
+		}
+		w.Flush()
+	}
+
+	if len(changes) == 0 {
+		ui.Printf("✅ Same images in %s and %s\n", sides[0].name(), sides[1].name())
+		return nil
+	}
+	ui.Printf("🔍 %d component(s) changed\n", len(changes))
+	if compareImagesExitCode {
+		return fmt.Errorf("the images of %s and %s differ", sides[0].name(), sides[1].name())
+	}
+	return nil
+}
+
+// imageMappings returns the mappings of --map, then those of the images
+// list of the configuration file
+func imageMappings(fileConfig *config.FileConfig, flags []string) ([]imageMapping, error) {
+	var mappings []imageMapping
+	for _, value := range flags {
+		image, repo, ok := strings.Cut(value, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --map %q: use IMAGE=OWNER/REPO, e.g. quay.io/myorg/app=myorg/app", value)
+		}
+		mappings = append(mappings, imageMapping{Image: strings.TrimSpace(image), Repo: strings.TrimSpace(repo)})
+	}
+	if fileConfig != nil && fileConfig.IsSet(config.ImagesKey) {
+		var entries []imageMapping
+		if err := fileConfig.Unmarshal(config.ImagesKey, &entries); err != nil {
+			return nil, fmt.Errorf("invalid %s in config file: %w", config.ImagesKey, err)
+		}
+		mappings = append(mappings, entries...)
+	}
+
+	for i, mapping := range mappings {
+		if _, err := path.Match(mapping.Image, ""); err != nil || mapping.Image == "" {
+			return nil, fmt.Errorf("invalid image pattern %q of mapping %d", mapping.Image, i+1)
+		}
+		owner, repo, ok := strings.Cut(mapping.Repo, "/")
+		if !ok || owner == "" || repo == "" {
+			return nil, fmt.Errorf("invalid repo %q of image %s: use owner/repo", mapping.Repo, mapping.Image)
+		}
+	}
+	return mappings, nil
+}
+
+// linkComponent links a changed component to the release notes of the
+// repository of the first mapping matching its image
+func linkComponent(change diff.ImageChange, mappings []imageMapping) componentChange {
+	component := componentChange{ImageChange: change}
+	for _, mapping := range mappings {
+		if ok, _ := path.Match(mapping.Image, change.Repository); !ok {
+			continue
+		}
+		component.Repo = strings.ReplaceAll(mapping.Repo, "{name}", path.Base(change.Repository))
+
+		// Several versions on a side, or digests only, name no release
+		if change.Kind == diff.Removed || len(change.To) != 1 || change.To[0].Tag == "" {
+			return component
+		}
+		repoURL := "https://github.com/" + component.Repo
+		to := releaseTag(mapping.TagPrefix, change.To[0].Tag)
+		component.ReleaseNotes = repoURL + "/releases/tag/" + to
+		if len(change.From) == 1 && change.From[0].Tag != "" && change.From[0].Tag != change.To[0].Tag {
+			component.Compare = repoURL + "/compare/" + releaseTag(mapping.TagPrefix, change.From[0].Tag) + "..." + to
+		}
+		return component
+	}
+	return component
+}
+
+// releaseTag returns the release tag of an image tag, prefixed unless it
+// already is
+func releaseTag(prefix, tag string) string {
+	if strings.HasPrefix(tag, prefix) {
+		return tag
+	}
+	return prefix + tag
+}
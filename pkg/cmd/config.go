@@ -0,0 +1,351 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"drivio/pkg/config"
+	"drivio/pkg/redact"
+	"drivio/pkg/ui"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage the drivio configuration file",
+	Long: `Manage the drivio configuration file (default: $HOME/.drivio.yaml).
+
+Profiles group the settings of one environment, like kubeconfig contexts:
+
+  current-profile: prod-gitlab
+  profiles:
+    prod-gitlab:
+      url: https://gitlab.company.com
+      repo: mycompany/configs
+      work-dir: /var/tmp/drivio-prod
+    upstream-github:
+      owner: openshift
+      repo: hypershift
+
+Examples:
+  drivio config view
+  drivio config get url
+  drivio config set url https://gitlab.company.com
+  drivio config set repo mycompany/configs --profile prod-gitlab
+  drivio config set release-notes.output RELEASE-NOTES.md
+  drivio config unset token
+  drivio config get-contexts
+  drivio config use-context prod-gitlab
+  drivio fetch --profile upstream-github`,
+	// The config commands must keep working when the file selects a profile
+	// that does not exist, so the file is not applied to their flags
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return prepareCommand(cmd, false)
+	},
+}
+
+// configUseContextCmd represents the config use-context command
+var configUseContextCmd = &cobra.Command{
+	Use:     "use-context NAME",
+	Aliases: []string{"use-profile"},
+	Short:   "Select the profile used by default",
+	Args:    cobra.ExactArgs(1),
+	RunE:    runConfigUseContext,
+}
+
+// configGetContextsCmd represents the config get-contexts command
+var configGetContextsCmd = &cobra.Command{
+	Use:     "get-contexts",
+	Aliases: []string{"get-profiles"},
+	Short:   "List the profiles of the configuration file",
+	Args:    cobra.NoArgs,
+	RunE:    runConfigGetContexts,
+}
+
+// configViewCmd represents the config view command
+var configViewCmd = &cobra.Command{
+	Use:   "view",
+	Short: "Show the effective configuration and where each value comes from",
+	Long: `Show the settings of the configuration file and the environment variables
+that override them, with the values of sensitive keys (tokens, passwords)
+masked unless --show-secrets is set.`,
+	Args: cobra.NoArgs,
+	RunE: runConfigView,
+}
+
+// configGetCmd represents the config get command
+var configGetCmd = &cobra.Command{
+	Use:   "get KEY",
+	Short: "Print the effective value of a setting",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runConfigGet,
+}
+
+// configSetCmd represents the config set command
+var configSetCmd = &cobra.Command{
+	Use:   "set KEY VALUE",
+	Short: "Set a value in the configuration file (in the profile given with --profile)",
+	Args:  cobra.ExactArgs(2),
+	RunE:  runConfigSet,
+}
+
+// configUnsetCmd represents the config unset command
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset KEY",
+	Short: "Remove a value from the configuration file (from the profile given with --profile)",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runConfigUnset,
+}
+
+var configShowSecrets bool
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configViewCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	configCmd.AddCommand(configUseContextCmd)
+	configCmd.AddCommand(configGetContextsCmd)
+
+	configViewCmd.Flags().BoolVar(&configShowSecrets, "show-secrets", false, "Show the values of sensitive keys")
+}
+
+// configSetting is the effective value of a setting and where it comes from
+type configSetting struct {
+	value  string
+	source string
+}
+
+// effectiveSettings returns the settings of the configuration file, with the
+// environment variables that take precedence over them applied
+func effectiveSettings(fileConfig *config.FileConfig) map[string]configSetting {
+	settings := make(map[string]configSetting)
+	for _, key := range fileConfig.Keys() {
+		source := "file"
+		if fileConfig.InProfile(key) {
+			source = "profile " + fileConfig.Profile
+		}
+		settings[key] = configSetting{value: fileConfig.GetString(key), source: source}
+	}
+
+	for key, envs := range settingEnvBindings() {
+		if value, env, ok := config.LookupEnv(envs...); ok {
+			settings[key] = configSetting{value: value, source: "env " + env}
+		}
+	}
+	return settings
+}
+
+// settingEnvBindings collects the flags of all commands with the environment
+// variables bound to them
+func settingEnvBindings() map[string][]string {
+	bindings := make(map[string][]string)
+	walkFlags(rootCmd, func(f *pflag.Flag) {
+		if envs := f.Annotations[envAnnotation]; len(envs) > 0 {
+			bindings[f.Name] = envs
+		}
+	})
+	return bindings
+}
+
+// isKnownSetting reports whether any command has a flag with that name or,
+// for keys of a command section like fetch.output, whether that command has
+// the flag
+func isKnownSetting(key string) bool {
+	parts := strings.Split(key, ".")
+	name := parts[len(parts)-1]
+
+	if parts[0] == config.RetentionKey {
+		return isRetentionSetting(parts[1:])
+	}
+	if parts[0] == config.ClassificationKey {
+		return len(parts) == 1
+	}
+
+	if len(parts) > 1 {
+		cmd, rest, err := rootCmd.Find(parts[:len(parts)-1])
+		if err != nil || cmd == rootCmd || len(rest) > 0 {
+			return false
+		}
+		return cmd.Flags().Lookup(name) != nil || cmd.InheritedFlags().Lookup(name) != nil
+	}
+
+	known := false
+	walkFlags(rootCmd, func(f *pflag.Flag) {
+		if f.Name == name {
+			known = true
+		}
+	})
+	return known
+}
+
+// walkFlags calls fn for the local and persistent flags of a command and all
+// its subcommands
+func walkFlags(cmd *cobra.Command, fn func(f *pflag.Flag)) {
+	cmd.LocalFlags().VisitAll(fn)
+	cmd.PersistentFlags().VisitAll(fn)
+	for _, child := range cmd.Commands() {
+		walkFlags(child, fn)
+	}
+}
+
+func runConfigView(cmd *cobra.Command, args []string) error {
+	fileConfig, err := config.LoadConfigFile(cfgFile, profile)
+	if err != nil {
+		return err
+	}
+
+	redactor, err := redact.New(nil)
+	if err != nil {
+		return err
+	}
+
+	activeProfile := fileConfig.Profile
+	if activeProfile == "" {
+		activeProfile = "none"
+	}
+	ui.Printf("📄 Config file: %s (profile: %s)\n", fileConfig.Path(), activeProfile)
+
+	settings := effectiveSettings(fileConfig)
+	if len(settings) == 0 {
+		ui.Println("📭 No settings configured")
+		return nil
+	}
+
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tVALUE\tSOURCE")
+	for _, key := range keys {
+		setting := settings[key]
+		value := setting.value
+		if !configShowSecrets && value != "" && redactor.IsSensitive(key) {
+			value = redact.Mask
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", key, value, setting.source)
+	}
+	return w.Flush()
+}
+
+func runConfigGet(cmd *cobra.Command, args []string) error {
+	fileConfig, err := config.LoadConfigFile(cfgFile, profile)
+	if err != nil {
+		return err
+	}
+
+	setting, ok := effectiveSettings(fileConfig)[strings.ToLower(args[0])]
+	if !ok {
+		return fmt.Errorf("%s is not set", args[0])
+	}
+	fmt.Println(setting.value)
+	return nil
+}
+
+func runConfigSet(cmd *cobra.Command, args []string) error {
+	key, value := strings.ToLower(args[0]), args[1]
+	if section := strings.Split(key, ".")[0]; section == config.ProfilesKey || section == config.CurrentProfileKey {
+		return fmt.Errorf("use drivio config use-context to select a profile")
+	}
+	if !isKnownSetting(key) {
+		ui.Fprintf(os.Stderr, "⚠️  Warning: %s is not a flag of any command (or of the command of its section) and will be ignored\n", key)
+	}
+
+	fileConfig, err := config.LoadConfigFile(cfgFile, "")
+	if err != nil {
+		return err
+	}
+
+	keys := settingPath(key)
+	if err := config.SetFileValue(fileConfig.Path(), keys, value); err != nil {
+		return err
+	}
+
+	ui.Printf("✅ Set %s in %s\n", strings.Join(keys, "."), fileConfig.Path())
+	if profile == "" && fileConfig.Profile != "" && fileConfig.InProfile(key) {
+		ui.Printf("ℹ️  The current profile %s overrides it; use --profile %s to change that value\n", fileConfig.Profile, fileConfig.Profile)
+	}
+	return nil
+}
+
+func runConfigUnset(cmd *cobra.Command, args []string) error {
+	key := strings.ToLower(args[0])
+
+	fileConfig, err := config.LoadConfigFile(cfgFile, "")
+	if err != nil {
+		return err
+	}
+
+	keys := settingPath(key)
+	removed, err := config.UnsetFileValue(fileConfig.Path(), keys)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return fmt.Errorf("%s is not set in %s", strings.Join(keys, "."), fileConfig.Path())
+	}
+
+	ui.Printf("✅ Removed %s from %s\n", strings.Join(keys, "."), fileConfig.Path())
+	return nil
+}
+
+// settingPath returns the path of a setting in the configuration file: in
+// the profile given with --profile, or at the top level. Dotted keys like
+// fetch.output are set in command sections.
+func settingPath(key string) []string {
+	keys := strings.Split(key, ".")
+	if profile != "" {
+		return append([]string{config.ProfilesKey, strings.ToLower(profile)}, keys...)
+	}
+	return keys
+}
+
+func runConfigUseContext(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	// Loading the file with the profile validates that it exists
+	fileConfig, err := config.LoadConfigFile(cfgFile, name)
+	if err != nil {
+		return err
+	}
+
+	if err := config.SetFileValue(fileConfig.Path(), []string{config.CurrentProfileKey}, fileConfig.Profile); err != nil {
+		return err
+	}
+
+	ui.Printf("✅ Switched to profile %q (%s)\n", fileConfig.Profile, fileConfig.Path())
+	return nil
+}
+
+func runConfigGetContexts(cmd *cobra.Command, args []string) error {
+	fileConfig, err := config.LoadConfigFile(cfgFile, profile)
+	if err != nil {
+		return err
+	}
+
+	profiles := fileConfig.Profiles()
+	if len(profiles) == 0 {
+		ui.Printf("📭 No profiles defined in %s\n", fileConfig.Path())
+		return nil
+	}
+
+	for _, name := range profiles {
+		marker := " "
+		if name == fileConfig.Profile {
+			marker = "*"
+		}
+		fmt.Printf("%s %s\n", marker, name)
+	}
+	return nil
+}
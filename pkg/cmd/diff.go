@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"drivio/pkg/config"
+	"drivio/pkg/diff"
+	"drivio/pkg/redact"
+	"drivio/pkg/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	diffFrom     string
+	diffTo       string
+	diffFromEnv  string
+	diffToEnv    string
+	diffFormat   string
+	diffExitCode bool
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare a config file across branches or environments",
+	Long: `Fetch the same config file from two references or two environments, e.g.
+staging and production, and print what differs key by key rather than line by
+line: the keys added, removed and changed, whatever their order, comments,
+quoting or indentation. Lists are compared item by item.
+
+--from and --to are branches, tags or commits, both defaulting to --branch.
+--from-env and --to-env resolve the file of each side through the {env}
+placeholder of --file or --file-pattern, as fetch --env does. Both can be
+combined, e.g. to compare the production file of a release branch with the
+staging file of main.
+
+Sensitive values are redacted as by fetch, unless --show-secrets is set: a
+changed secret is reported without its values. --exit-code exits with an
+error when the files differ, for CI checks.
+
+Examples:
+  drivio diff --repo myorg/configs --file config/app.yaml --from staging --to production
+  drivio diff --repo myorg/configs --file "config/{env}/app.yaml" --from-env staging --to-env prod
+  drivio diff --repo myorg/configs --file config/app.yaml --from v1.2.0 --to main --format json
+  drivio diff --repo myorg/configs --file config/app.yaml --from main --to release-4.14 --exit-code`,
+	RunE: runDiff,
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	// Add flags
+	diffCmd.Flags().StringVar(&gitlabURL, "url", "", "GitLab URL (default: https://gitlab.com)")
+	diffCmd.Flags().StringVar(&gitlabToken, "token", "", "GitLab access token")
+	diffCmd.Flags().StringVar(&gitlabInstance, "instance", "", "GitLab instance of the config file providing the URL and token")
+	diffCmd.Flags().StringVar(&repositoryPath, "repo", "", "Repository path (e.g., owner/repo)")
+	diffCmd.Flags().StringVar(&filePath, "file", "", "File path in repository, possibly with an {env} placeholder")
+	diffCmd.Flags().StringVar(&filePattern, "file-pattern", "", "File path pattern with an {env} placeholder (e.g., config/{env}/database.yaml)")
+	diffCmd.Flags().StringVar(&branch, "branch", "", "Reference of both sides unless --from or --to is given (default: main)")
+	diffCmd.Flags().StringVar(&diffFrom, "from", "", "Branch, tag or commit of the first side")
+	diffCmd.Flags().StringVar(&diffTo, "to", "", "Branch, tag or commit of the second side")
+	diffCmd.Flags().StringVar(&diffFromEnv, "from-env", "", "Environment of the first side, resolving the file path through the file pattern")
+	diffCmd.Flags().StringVar(&diffToEnv, "to-env", "", "Environment of the second side, resolving the file path through the file pattern")
+	diffCmd.Flags().StringVar(&diffFormat, "format", "text", "Output format: text or json")
+	diffCmd.Flags().BoolVar(&diffExitCode, "exit-code", false, "Exit with an error when the files differ")
+	diffCmd.Flags().BoolVar(&showSecrets, "show-secrets", false, "Do not redact sensitive values")
+	diffCmd.Flags().StringSliceVar(&redactPatterns, "redact-keys", nil, "Key patterns (regular expressions) whose values are redacted (default: password,passwd,token,secret,key,credential)")
+
+	// Environment variables that take precedence over the config file
+	bindFlagEnv(diffCmd.Flags(), "url", config.EnvGitLabURL...)
+	bindFlagEnv(diffCmd.Flags(), "token", config.EnvGitLabToken...)
+	bindFlagEnv(diffCmd.Flags(), "instance", config.EnvInstance...)
+	bindFlagEnv(diffCmd.Flags(), "repo", config.EnvRepoPath...)
+	bindFlagEnv(diffCmd.Flags(), "file", config.EnvFilePath...)
+	bindFlagEnv(diffCmd.Flags(), "file-pattern", config.EnvFilePattern...)
+	bindFlagEnv(diffCmd.Flags(), "branch", config.EnvBranch...)
+}
+
+// diffSide is one of the files compared
+type diffSide struct {
+	cfg     *config.Config
+	content []byte
+}
+
+// name identifies the side in the output, e.g. myorg/configs@main:app.yaml
+func (s diffSide) name() string {
+	return fmt.Sprintf("%s@%s:%s", s.cfg.RepositoryPath, s.cfg.Branch, s.cfg.FilePath)
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	if diffFormat != "text" && diffFormat != "json" {
+		return fmt.Errorf("invalid --format %q: use text or json", diffFormat)
+	}
+	redactor, err := redact.New(redactPatterns)
+	if err != nil {
+		return err
+	}
+
+	base := loadFetchConfig()
+	from, err := diffConfig(base, diffFrom, diffFromEnv)
+	if err != nil {
+		return err
+	}
+	to, err := diffConfig(base, diffTo, diffToEnv)
+	if err != nil {
+		return err
+	}
+	if from.Branch == to.Branch && from.FilePath == to.FilePath {
+		return fmt.Errorf("both sides are %s@%s: set --from and --to, or --from-env and --to-env", from.FilePath, from.Branch)
+	}
+
+	sides := []*diffSide{{cfg: from}, {cfg: to}}
+	changes, err := diffSides(context.Background(), sides, redactor)
+	if err != nil {
+		return err
+	}
+
+	if diffFormat == "json" {
+		if changes == nil {
+			changes = []diff.Change{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(struct {
+			From    string        `json:"from"`
+			To      string        `json:"to"`
+			Changes []diff.Change `json:"changes"`
+		}{sides[0].name(), sides[1].name(), changes}); err != nil {
+			return err
+		}
+	} else if len(changes) > 0 {
+		lines := []string{"--- " + sides[0].name(), "+++ " + sides[1].name()}
+		for _, change := range changes {
+			lines = append(lines, change.String())
+		}
+		for _, line := range lines {
+			if ui.IsTerminal() {
+				line = ui.HighlightDiffLine(line)
+			}
+			fmt.Println(line)
+		}
+	}
+
+	if len(changes) == 0 {
+		ui.Printf("✅ No differences between %s and %s\n", sides[0].name(), sides[1].name())
+		return nil
+	}
+	ui.Printf("🔍 %d difference(s)\n", len(changes))
+	if diffExitCode {
+		return fmt.Errorf("%s and %s differ", sides[0].name(), sides[1].name())
+	}
+	return nil
+}
+
+// diffSides fetches the files of both sides and returns their differences,
+// redacted unless --show-secrets is set
+func diffSides(ctx context.Context, sides []*diffSide, redactor *redact.Redactor) ([]diff.Change, error) {
+	for i, side := range sides {
+		// Both sides are in the same repository, so it is checked once
+		if i > 0 {
+			side.cfg.Visibility = sides[0].cfg.Visibility
+		}
+		client, err := newFetchClient(side.cfg)
+		if err != nil {
+			return nil, err
+		}
+		if err := ui.RunSpinner(fmt.Sprintf("Fetching %s...", side.name()), func() error {
+			var err error
+			side.content, err = client.GetFile(ctx)
+			return err
+		}); err != nil {
+			return nil, fmt.Errorf("failed to fetch %s: %w", side.name(), err)
+		}
+	}
+
+	changes, err := diff.YAML(sides[0].content, sides[1].content)
+	if err != nil {
+		return nil, err
+	}
+	if showSecrets {
+		return changes, nil
+	}
+	return redactChanges(redactor, sides[0].content, sides[1].content, changes)
+}
+
+// diffConfig returns the configuration of a side: the base one at ref, for
+// the file of env
+func diffConfig(base *config.Config, ref, env string) (*config.Config, error) {
+	cfg := *base
+	if ref != "" {
+		cfg.Branch = ref
+	}
+	if err := cfg.ResolveEnvironment(env); err != nil {
+		return nil, fmt.Errorf("configuration error: %w", err)
+	}
+	if err := cfg.ValidateConfig(); err != nil {
+		return nil, fmt.Errorf("configuration error: %w", err)
+	}
+	return &cfg, nil
+}
+
+// redactChanges masks the values of sensitive keys. The documents are
+// compared again once redacted; the changes only found in the originals are
+// secrets that changed, reported without their values.
+func redactChanges(redactor *redact.Redactor, a, b []byte, changes []diff.Change) ([]diff.Change, error) {
+	redactedA, _ := redactor.Redact(a)
+	redactedB, _ := redactor.Redact(b)
+	redacted, err := diff.YAML(redactedA, redactedB)
+	if err != nil {
+		return nil, err
+	}
+
+	shown := make(map[string]diff.Change, len(redacted))
+	for _, change := range redacted {
+		shown[change.Path] = change
+	}
+	result := make([]diff.Change, 0, len(changes))
+	for _, change := range changes {
+		if redactedChange, ok := shown[change.Path]; ok {
+			result = append(result, redactedChange)
+			continue
+		}
+		change.Old, change.New = redact.Mask, redact.Mask
+		result = append(result, change)
+	}
+	return result, nil
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"drivio/pkg/workdir"
+)
+
+var (
+	// workDir is the work directory shared by all commands
+	workDir string
+	// cacheDir holds what drivio can download again, e.g. repository
+	// archives; the work directory when empty
+	cacheDir string
+	// dataDir holds what drivio keeps across runs, e.g. the fetch
+	// history; the work directory when empty
+	dataDir string
+	// xdgDirs defaults cacheDir and dataDir to the XDG base directories
+	xdgDirs bool
+)
+
+// resolveDirs defaults the cache and data directories to the XDG base
+// directories when --xdg is set
+func resolveDirs() error {
+	if !xdgDirs {
+		return nil
+	}
+	if cacheDir == "" {
+		dir, err := workdir.XDGCacheDir()
+		if err != nil {
+			return err
+		}
+		cacheDir = dir
+	}
+	if dataDir == "" {
+		dir, err := workdir.XDGDataDir()
+		if err != nil {
+			return err
+		}
+		dataDir = dir
+	}
+	return nil
+}
+
+// cacheRoot returns the directory of the caches of a work directory
+func cacheRoot(workDir string) string {
+	if cacheDir != "" {
+		return cacheDir
+	}
+	return workDir
+}
+
+// dataRoot returns the directory of the persistent data of a work directory
+func dataRoot(workDir string) string {
+	if dataDir != "" {
+		return dataDir
+	}
+	return workDir
+}
+
+// managedDirs returns the distinct directories drivio writes to for a work
+// directory: the work directory, then the cache and data directories
+func managedDirs(workDir string) []string {
+	dirs := []string{workDir}
+	for _, dir := range []string{cacheRoot(workDir), dataRoot(workDir)} {
+		seen := false
+		for _, existing := range dirs {
+			seen = seen || existing == dir
+		}
+		if !seen {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
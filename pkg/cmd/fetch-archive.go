@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"drivio/pkg/archive"
+	"drivio/pkg/config"
+	"drivio/pkg/github"
+	"drivio/pkg/plugin"
+	"drivio/pkg/ui"
+	"drivio/pkg/workdir"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	archiveRepo        string
+	archiveRef         string
+	archivePaths       []string
+	archiveProvider    string
+	archiveDest        string
+	archiveGitHubToken string
+)
+
+// fetchArchiveCmd represents the fetch archive command
+var fetchArchiveCmd = &cobra.Command{
+	Use:   "archive",
+	Short: "Download a repository archive and extract selected paths",
+	Long: `Download the tar.gz archive of a repository at a given reference through
+the GitLab or GitHub API and extract it, optionally limited to selected paths.
+
+This is much faster than cloning when only a read-only snapshot of some files
+is needed. Other providers can be plugged in: --provider NAME runs the
+drivio-NAME plugin on PATH answering scm.archive (see drivio plugin).
+
+Examples:
+  drivio fetch archive --repo jparrill/my-config --ref v1.2.3
+  drivio fetch archive --repo jparrill/my-config --ref v1.2.3 --path config/production --path config/shared.yaml
+  drivio fetch archive --provider github --repo openshift/hypershift --ref v0.1.63 --path api --dest /tmp/hypershift-api`,
+	RunE: runFetchArchive,
+}
+
+func init() {
+	fetchCmd.AddCommand(fetchArchiveCmd)
+
+	// Add flags
+	fetchArchiveCmd.Flags().StringVar(&archiveRepo, "repo", "", "Repository path (e.g., owner/repo)")
+	fetchArchiveCmd.Flags().StringVar(&archiveRef, "ref", "", "Branch, tag or commit to download (default: main)")
+	fetchArchiveCmd.Flags().StringArrayVar(&archivePaths, "path", nil, "Repository path to extract (repeatable, default: everything)")
+	fetchArchiveCmd.Flags().StringVar(&archiveProvider, "provider", "gitlab", "Repository provider: gitlab, github or a repository provider plugin")
+	fetchArchiveCmd.Flags().StringVar(&archiveDest, "dest", "", "Directory to extract into (default: <cache-dir>/archives/<repo>/<ref>, the cache directory being the work directory unless set)")
+	fetchArchiveCmd.Flags().StringVar(&archiveGitHubToken, "github-token", "", "GitHub token for authentication (optional)")
+
+	// Environment variables that take precedence over the config file
+	bindFlagEnv(fetchArchiveCmd.Flags(), "github-token", config.EnvGitHubToken...)
+
+	// Mark required flags
+	fetchArchiveCmd.MarkFlagRequired("repo")
+}
+
+func runFetchArchive(cmd *cobra.Command, args []string) error {
+	// Create work directory if it doesn't exist
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return fmt.Errorf("failed to create work directory: %w", err)
+	}
+
+	unlock, err := lockWorkDir(workDir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := enforceRetention(workDir, ui.Printf); err != nil {
+		return err
+	}
+
+	ref := archiveRef
+	if ref == "" {
+		ref = loadFetchConfig().Branch
+	}
+
+	dest := archiveDest
+	if dest == "" {
+		dest = filepath.Join(cacheRoot(workDir), workdir.ArchivesDir, archiveRepo, ref)
+	}
+
+	// Download to a temporary file first so a failed download never leaves a
+	// partially extracted tree behind
+	tmp, err := os.CreateTemp(workDir, ".archive-*.tar.gz")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	ctx := context.Background()
+
+	// Step 1: Download the archive
+	if err := ui.RunProgress(fmt.Sprintf("Downloading %s@%s archive...", archiveRepo, ref), ui.UnitBytes, func(progress chan<- ui.ProgressMsg) error {
+		// Archives are generated on the fly, so their size is unknown
+		counter := ui.NewCountingWriter(progress, 0)
+		defer counter.Flush()
+		w := io.MultiWriter(tmp, counter)
+
+		switch strings.ToLower(archiveProvider) {
+		case "gitlab":
+			return downloadGitLabArchive(ctx, ref, w)
+		case "github":
+			return downloadGitHubArchive(ctx, ref, w)
+		default:
+			return downloadPluginArchive(ctx, ref, w)
+		}
+	}); err != nil {
+		return fmt.Errorf("failed to download archive: %w", err)
+	}
+
+	info, err := tmp.Stat()
+	if err != nil {
+		return err
+	}
+	ui.Printf("✅ Archive downloaded successfully (%d bytes)\n", info.Size())
+
+	// Step 2: Extract the selected paths
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	var extracted int
+	if err := ui.RunSpinner("Extracting archive...", func() error {
+		var err error
+		extracted, err = archive.ExtractTarGz(tmp, dest, archivePaths)
+		return err
+	}); err != nil {
+		return fmt.Errorf("failed to extract archive: %w", err)
+	}
+
+	if extracted == 0 && len(archivePaths) > 0 {
+		return fmt.Errorf("no files matched the selected paths: %s", strings.Join(archivePaths, ", "))
+	}
+	ui.Printf("📦 Extracted %d files to: %s\n", extracted, dest)
+
+	recordArtifacts(workDir, "fetch archive", map[string]string{
+		"provider": strings.ToLower(archiveProvider),
+		"repo":     archiveRepo,
+		"ref":      ref,
+		"paths":    strings.Join(archivePaths, ","),
+	}, dest)
+
+	return nil
+}
+
+// downloadGitLabArchive downloads the archive through the GitLab API
+func downloadGitLabArchive(ctx context.Context, ref string, w io.Writer) error {
+	cfg := loadFetchConfig()
+	cfg.RepositoryPath = archiveRepo
+	cfg.Branch = ref
+
+	if err := cfg.ValidateConfig(); err != nil {
+		return fmt.Errorf("configuration error: %w", err)
+	}
+
+	client, err := newFetchClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	return client.DownloadArchive(ctx, w)
+}
+
+// downloadGitHubArchive downloads the archive through the GitHub API
+func downloadGitHubArchive(ctx context.Context, ref string, w io.Writer) error {
+	parts := strings.SplitN(archiveRepo, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf("invalid repository path: %s", archiveRepo)
+	}
+
+	token := archiveGitHubToken
+	if token == "" {
+		token = config.GetEnv(config.EnvGitHubToken...)
+	}
+
+	body, err := github.NewClient(token).DownloadArchive(ctx, parts[0], parts[1], ref)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	_, err = io.Copy(w, body)
+	return err
+}
+
+// downloadPluginArchive downloads the archive through a repository provider
+// plugin, which writes it to a temporary file
+func downloadPluginArchive(ctx context.Context, ref string, w io.Writer) error {
+	p, err := plugin.Find(archiveProvider)
+	if err != nil {
+		return fmt.Errorf("unsupported provider: %s (use gitlab, github or a plugin): %w", archiveProvider, err)
+	}
+	if err := p.Require(ctx, plugin.CapabilitySCM); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(workDir, ".plugin-archive-*.tar.gz")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	path, err := filepath.Abs(tmp.Name())
+	if err != nil {
+		return err
+	}
+	if err := p.Call(ctx, plugin.MethodArchive, plugin.ArchiveParams{Repo: archiveRepo, Ref: ref, Path: path}, nil); err != nil {
+		return err
+	}
+	_, err = io.Copy(w, tmp)
+	return err
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"drivio/pkg/fileutil"
+	"drivio/pkg/ui"
+	"drivio/pkg/workdir"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	artifactProject string
+	artifactJob     string
+	artifactRef     string
+	artifactPath    string
+)
+
+// fetchArtifactCmd represents the fetch artifact command
+var fetchArtifactCmd = &cobra.Command{
+	Use:   "artifact",
+	Short: "Fetch a file from GitLab CI job artifacts",
+	Long: `Fetch a single file from the artifacts of a GitLab CI job.
+
+The artifact is taken from the latest successful pipeline for the given
+reference (branch or tag), which is useful for environment files that are
+generated by a pipeline rather than committed to the repository.
+
+Examples:
+  drivio fetch artifact --project jparrill/my-config --job build --ref main --path dist/config.yaml
+  drivio fetch artifact --project jparrill/my-config --job render --ref v1.2.0 --path out/prod.yaml --output prod.yaml`,
+	RunE: runFetchArtifact,
+}
+
+func init() {
+	fetchCmd.AddCommand(fetchArtifactCmd)
+
+	// Add flags
+	fetchArtifactCmd.Flags().StringVar(&artifactProject, "project", "", "Project path (e.g., owner/repo)")
+	fetchArtifactCmd.Flags().StringVar(&artifactJob, "job", "", "Name of the job that produced the artifacts")
+	fetchArtifactCmd.Flags().StringVar(&artifactRef, "ref", "", "Branch or tag of the pipeline (default: main)")
+	fetchArtifactCmd.Flags().StringVar(&artifactPath, "path", "", "Path of the file inside the artifacts archive")
+
+	// Mark required flags
+	fetchArtifactCmd.MarkFlagRequired("project")
+	fetchArtifactCmd.MarkFlagRequired("job")
+	fetchArtifactCmd.MarkFlagRequired("path")
+}
+
+func runFetchArtifact(cmd *cobra.Command, args []string) error {
+	// Create work directory if it doesn't exist
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return fmt.Errorf("failed to create work directory: %w", err)
+	}
+
+	unlock, err := lockWorkDir(workDir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := enforceRetention(workDir, ui.Printf); err != nil {
+		return err
+	}
+
+	cfg := loadFetchConfig()
+	cfg.RepositoryPath = artifactProject
+	cfg.FilePath = artifactPath
+	if artifactRef != "" {
+		cfg.Branch = artifactRef
+	}
+
+	// Validate configuration
+	if err := cfg.ValidateConfig(); err != nil {
+		return fmt.Errorf("configuration error: %w", err)
+	}
+
+	client, err := newFetchClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	// Step 1: Download the artifact
+	var content []byte
+	if err := ui.RunSpinner("Downloading artifact...", func() error {
+		var err error
+		content, err = client.GetJobArtifact(ctx, artifactJob, artifactPath)
+		return err
+	}); err != nil {
+		return fmt.Errorf("failed to fetch artifact: %w", err)
+	}
+	ui.Printf("✅ Artifact fetched successfully (%d bytes)\n", len(content))
+
+	// Step 2: Save to work directory
+	artifactDir := filepath.Join(workDir, workdir.ArtifactsDir)
+	workFilePath := filepath.Join(artifactDir, filepath.Base(artifactPath))
+	if err := ui.RunSpinner("Saving artifact...", func() error {
+		return fileutil.WriteFileAtomic(workFilePath, content, 0644, false)
+	}); err != nil {
+		return fmt.Errorf("failed to write artifact to work directory: %w", err)
+	}
+	ui.Printf("💾 Artifact saved successfully: %s\n", workFilePath)
+	outputs := []string{workFilePath}
+
+	if outputFile != "" {
+		if outputFile != workFilePath {
+			if err := fileutil.WriteFileAtomic(outputFile, content, 0644, !noBackup); err != nil {
+				return fmt.Errorf("failed to write output file: %w", err)
+			}
+			ui.Printf("💾 Artifact also saved to: %s\n", outputFile)
+			outputs = append(outputs, outputFile)
+		}
+	}
+
+	inputs := fetchInputs(cfg)
+	inputs["job"] = artifactJob
+	recordArtifacts(workDir, "fetch artifact", inputs, outputs...)
+
+	return nil
+}
@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"drivio/pkg/config"
+	"drivio/pkg/git"
+	"drivio/pkg/ui"
+	"drivio/pkg/workdir"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	cloneRepo         string
+	cloneRef          string
+	cloneProvider     string
+	cloneDest         string
+	cloneDepth        int
+	cloneSingleBranch bool
+	cloneFilter       string
+	cloneSubmodules   bool
+	cloneBare         bool
+	cloneMirror       bool
+	cloneGitHubToken  string
+)
+
+// fetchCloneCmd represents the fetch clone command
+var fetchCloneCmd = &cobra.Command{
+	Use:   "clone",
+	Short: "Clone a repository for local analysis",
+	Long: `Clone a GitLab or GitHub repository with git, for the analyses that need a
+local copy rather than a snapshot of some files (see fetch archive).
+
+Large repositories clone in seconds rather than minutes, and without filling
+the work directory with their full history, when the clone is limited:
+--depth truncates the history to the last commits, --single-branch only
+fetches the branch checked out, and --filter makes a partial clone whose file
+contents (blob:none) are only downloaded when needed.
+
+--ref checks out a branch or tag instead of the default branch, and
+--recurse-submodules also clones the submodules. --bare clones without a
+work tree, and --mirror makes a bare clone of every ref of the remote, e.g.
+to analyze a mirror offline.
+
+Examples:
+  drivio fetch clone --repo mycompany/configs
+  drivio fetch clone --provider github --repo openshift/hypershift --depth 1 --single-branch
+  drivio fetch clone --provider github --repo openshift/hypershift --filter blob:none --dest /tmp/hypershift
+  drivio fetch clone --repo mycompany/configs --ref v1.2.3 --depth 1 --recurse-submodules
+  drivio fetch clone --repo mycompany/configs --mirror`,
+	RunE: runFetchClone,
+}
+
+func init() {
+	fetchCmd.AddCommand(fetchCloneCmd)
+
+	// Add flags
+	fetchCloneCmd.Flags().StringVar(&cloneRepo, "repo", "", "Repository path (e.g., owner/repo)")
+	fetchCloneCmd.Flags().StringVar(&cloneRef, "ref", "", "Branch or tag to check out (default: the default branch)")
+	fetchCloneCmd.Flags().StringVar(&cloneProvider, "provider", "gitlab", "Repository provider: gitlab or github")
+	fetchCloneCmd.Flags().StringVar(&cloneDest, "dest", "", "Directory to clone into (default: <cache-dir>/clones/<repo>, the cache directory being the work directory unless set)")
+	fetchCloneCmd.Flags().IntVar(&cloneDepth, "depth", 0, "Only clone the last commits of the history (default: the full history)")
+	fetchCloneCmd.Flags().BoolVar(&cloneSingleBranch, "single-branch", false, "Only clone the history of the branch or tag checked out")
+	fetchCloneCmd.Flags().StringVar(&cloneFilter, "filter", "", "Partial clone filter, e.g. blob:none to download file contents only when needed")
+	fetchCloneCmd.Flags().BoolVar(&cloneSubmodules, "recurse-submodules", false, "Also clone the submodules")
+	fetchCloneCmd.Flags().BoolVar(&cloneBare, "bare", false, "Clone without a work tree")
+	fetchCloneCmd.Flags().BoolVar(&cloneMirror, "mirror", false, "Make a bare clone of every ref of the remote")
+	fetchCloneCmd.Flags().StringVar(&cloneGitHubToken, "github-token", "", "GitHub token for authentication (optional)")
+
+	// Environment variables that take precedence over the config file
+	bindFlagEnv(fetchCloneCmd.Flags(), "github-token", config.EnvGitHubToken...)
+
+	// Mark required flags
+	fetchCloneCmd.MarkFlagRequired("repo")
+}
+
+func runFetchClone(cmd *cobra.Command, args []string) error {
+	// Create work directory if it doesn't exist
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return fmt.Errorf("failed to create work directory: %w", err)
+	}
+
+	unlock, err := lockWorkDir(workDir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := enforceRetention(workDir, ui.Printf); err != nil {
+		return err
+	}
+
+	url, opts, err := cloneSource()
+	if err != nil {
+		return err
+	}
+	opts.Ref = cloneRef
+	opts.Depth = cloneDepth
+	opts.SingleBranch = cloneSingleBranch
+	opts.Filter = cloneFilter
+	opts.RecurseSubmodules = cloneSubmodules
+	opts.Bare = cloneBare
+	opts.Mirror = cloneMirror
+
+	dest := cloneDest
+	if dest == "" {
+		dest = filepath.Join(cacheRoot(workDir), workdir.ClonesDir, cloneDirName(cloneRepo, cloneRef, cloneBare || cloneMirror))
+	}
+	if entries, err := os.ReadDir(dest); err == nil && len(entries) > 0 {
+		return fmt.Errorf("destination %s already exists; remove it first, e.g. with drivio clean --only clones", dest)
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("failed to create destination: %w", err)
+	}
+
+	if err := ui.RunProgress(fmt.Sprintf("Cloning %s...", cloneRepo), ui.UnitItems, func(progress chan<- ui.ProgressMsg) error {
+		return git.CloneWithProgress(context.Background(), url, dest, opts, func(p git.CloneProgress) {
+			progress <- ui.ProgressMsg{Current: p.Current, Total: p.Total, Message: p.Phase}
+		})
+	}); err != nil {
+		return fmt.Errorf("failed to clone repository: %w", err)
+	}
+
+	ui.Printf("📥 Cloned %s to: %s\n", cloneRepo, dest)
+
+	inputs := map[string]string{
+		"provider": strings.ToLower(cloneProvider),
+		"repo":     cloneRepo,
+		"ref":      cloneRef,
+		"filter":   cloneFilter,
+	}
+	if cloneDepth > 0 {
+		inputs["depth"] = strconv.Itoa(cloneDepth)
+	}
+	switch {
+	case cloneMirror:
+		inputs["mode"] = "mirror"
+	case cloneBare:
+		inputs["mode"] = "bare"
+	}
+	recordArtifacts(workDir, "fetch clone", inputs, dest)
+
+	return nil
+}
+
+// cloneSource returns the clone URL of --repo and the credentials to clone
+// it with
+func cloneSource() (string, git.CloneOptions, error) {
+	var opts git.CloneOptions
+	switch strings.ToLower(cloneProvider) {
+	case "gitlab":
+		cfg := loadFetchConfig()
+		cfg.RepositoryPath = cloneRepo
+		if err := cfg.ValidateConfig(); err != nil {
+			return "", opts, fmt.Errorf("configuration error: %w", err)
+		}
+		opts.Username, opts.Token = "oauth2", cfg.GitLabToken
+		return strings.TrimRight(cfg.GitLabURL, "/") + "/" + cloneRepo + ".git", opts, nil
+	case "github":
+		token := cloneGitHubToken
+		if token == "" {
+			token = config.GetEnv(config.EnvGitHubToken...)
+		}
+		opts.Username, opts.Token = "x-access-token", token
+		return "https://github.com/" + cloneRepo + ".git", opts, nil
+	default:
+		return "", opts, fmt.Errorf("unsupported provider: %s (use gitlab or github)", cloneProvider)
+	}
+}
+
+// cloneDirName returns the path of a clone below the clones directory, e.g.
+// owner/repo for the default branch, owner/repo@v1.2.3 for a tag and
+// owner/repo.git for a bare clone
+func cloneDirName(repo, ref string, bare bool) string {
+	name := repo
+	if ref != "" {
+		name += "@" + strings.ReplaceAll(ref, "/", "-")
+	}
+	if bare {
+		name += ".git"
+	}
+	return name
+}
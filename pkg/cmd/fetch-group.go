@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"drivio/pkg/manifest"
+	"drivio/pkg/ui"
+	"drivio/pkg/workdir"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	groupPath             string
+	groupFile             string
+	groupRef              string
+	groupIncludeSubgroups bool
+	groupIncludeArchived  bool
+	groupWorkers          int
+)
+
+// fetchGroupCmd represents the fetch group command
+var fetchGroupCmd = &cobra.Command{
+	Use:   "group",
+	Short: "Fetch the same file from every project of a GitLab group",
+	Long: `Fetch a file at the same path from every project of a GitLab group, e.g.
+every service's deploy/values.yaml.
+
+Files are written to <work-dir>/groups/<project path>/<file>. Projects that
+don't have the file are skipped; any other error, e.g. a project that cannot
+be found, makes the command fail after all projects were processed.
+
+Examples:
+  drivio fetch group --group mycompany/services --file deploy/values.yaml
+  drivio fetch group --group mycompany --file deploy/values.yaml --include-subgroups
+  drivio fetch group --group mycompany/services --file deploy/values.yaml --ref release-4.19,main`,
+	RunE: runFetchGroup,
+}
+
+func init() {
+	fetchCmd.AddCommand(fetchGroupCmd)
+
+	// Add flags
+	fetchGroupCmd.Flags().StringVar(&groupPath, "group", "", "Group path (e.g., mycompany/services)")
+	fetchGroupCmd.Flags().StringVar(&groupFile, "file", "", "Path to the file in every project")
+	fetchGroupCmd.Flags().StringVar(&groupRef, "ref", "", "Branch to fetch from, or a comma-separated fallback list (default: the default branch of each project)")
+	fetchGroupCmd.Flags().BoolVar(&groupIncludeSubgroups, "include-subgroups", false, "Include the projects of subgroups")
+	fetchGroupCmd.Flags().BoolVar(&groupIncludeArchived, "include-archived", false, "Include archived projects")
+	fetchGroupCmd.Flags().IntVar(&groupWorkers, "parallel", 4, "Number of concurrent downloads")
+
+	// Mark required flags
+	fetchGroupCmd.MarkFlagRequired("group")
+	fetchGroupCmd.MarkFlagRequired("file")
+}
+
+func runFetchGroup(cmd *cobra.Command, args []string) error {
+	// Create work directory if it doesn't exist
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return fmt.Errorf("failed to create work directory: %w", err)
+	}
+
+	unlock, err := lockWorkDir(workDir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := enforceRetention(workDir, ui.Printf); err != nil {
+		return err
+	}
+
+	cfg := loadFetchConfig()
+	client, err := newFetchClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	if err := preflightGitLab(ctx, cfg); err != nil {
+		return err
+	}
+
+	// Step 1: List the projects of the group
+	var sources []manifest.Source
+	if err := ui.RunSpinner(fmt.Sprintf("Listing projects of %s...", groupPath), func() error {
+		projects, err := client.ListGroupProjects(ctx, groupPath, groupIncludeSubgroups, groupIncludeArchived)
+		if err != nil {
+			return err
+		}
+		for _, project := range projects {
+			ref := groupRef
+			if ref == "" {
+				ref = project.DefaultBranch
+			}
+			if ref == "" {
+				// Empty repositories have no default branch
+				continue
+			}
+			sources = append(sources, manifest.Source{
+				Name:        project.PathWithNamespace,
+				Repo:        project.PathWithNamespace,
+				Ref:         ref,
+				File:        strings.TrimPrefix(groupFile, "/"),
+				Destination: filepath.Join(workDir, workdir.GroupsDir, project.PathWithNamespace, groupFile),
+			})
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to list group projects: %w", err)
+	}
+
+	if len(sources) == 0 {
+		ui.Printf("📭 No projects found in group %s\n", groupPath)
+		return nil
+	}
+	ui.Printf("👥 Found %d projects in group %s\n", len(sources), groupPath)
+
+	// Step 2: Fetch the file from every project
+	workers := min(max(groupWorkers, 1), len(sources))
+	var results []manifestResult
+	message := fmt.Sprintf("Fetching %s from %d projects with %d workers...", groupFile, len(sources), workers)
+	ui.RunProgress(message, ui.UnitItems, func(progress chan<- ui.ProgressMsg) error {
+		results = fetchManifestSources(ctx, sources, workers, progress)
+		return nil
+	})
+
+	failed, skipped := printManifestSummary(results, true)
+	recordManifestResults("fetch group", results)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d projects failed", failed, len(results))
+	}
+
+	ui.Printf("✅ Fetched %s from %d projects (%d without the file)\n", groupFile, len(results)-skipped, skipped)
+	return nil
+}
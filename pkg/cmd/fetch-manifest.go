@@ -0,0 +1,250 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"text/tabwriter"
+
+	"drivio/pkg/config"
+	"drivio/pkg/fileutil"
+	"drivio/pkg/gitlab"
+	"drivio/pkg/manifest"
+	"drivio/pkg/schema"
+	"drivio/pkg/ui"
+	"drivio/pkg/workdir"
+)
+
+// manifestResult holds the outcome of fetching a single manifest source
+type manifestResult struct {
+	source      manifest.Source
+	destination string
+	size        int
+	err         error
+}
+
+// runFetchManifest fetches every source declared in a batch manifest and
+// prints a summary table
+func runFetchManifest(ctx context.Context, path string) error {
+	m, err := manifest.Load(path)
+	if err != nil {
+		return err
+	}
+
+	ui.Printf("📋 Manifest loaded: %s (%d sources)\n", path, len(m.Sources))
+
+	if err := preflightManifest(ctx, m.Sources); err != nil {
+		return err
+	}
+
+	workers := fetchWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(m.Sources) {
+		workers = len(m.Sources)
+	}
+
+	var results []manifestResult
+	message := fmt.Sprintf("Fetching %d sources with %d workers...", len(m.Sources), workers)
+	ui.RunProgress(message, ui.UnitItems, func(progress chan<- ui.ProgressMsg) error {
+		results = fetchManifestSources(ctx, m.Sources, workers, progress)
+		return nil
+	})
+
+	failed, _ := printManifestSummary(results, false)
+	recordManifestResults("fetch --manifest", results)
+	if failed > 0 {
+		return fmt.Errorf("%d of %d sources failed", failed, len(results))
+	}
+
+	ui.Printf("✅ All %d sources fetched successfully\n", len(results))
+	return nil
+}
+
+// fetchManifestSources fetches all sources concurrently with the given
+// number of workers, reporting the sources done on the progress channel.
+// Results are returned in manifest order.
+func fetchManifestSources(ctx context.Context, sources []manifest.Source, workers int, progress chan<- ui.ProgressMsg) []manifestResult {
+	results := make([]manifestResult, len(sources))
+	jobs := make(chan int)
+
+	var mu sync.Mutex
+	done := 0
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				result := manifestResult{source: sources[i]}
+				result.destination, result.size, result.err = fetchManifestSource(ctx, sources[i])
+				results[i] = result
+
+				mu.Lock()
+				done++
+				progress <- ui.ProgressMsg{Current: int64(done), Total: int64(len(sources)), Message: sources[i].Name}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for i := range sources {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// fetchManifestSource fetches a single source, validates it against its
+// schema and writes it to its destination
+func fetchManifestSource(ctx context.Context, source manifest.Source) (string, int, error) {
+	cfg, err := manifestSourceConfig(source)
+	if err != nil {
+		return "", 0, err
+	}
+
+	client, err := newFetchClient(cfg)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if len(cfg.Branches()) > 1 {
+		if err := resolveBranch(ctx, client, cfg); err != nil {
+			return "", 0, err
+		}
+	}
+
+	content, err := client.GetFile(ctx)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if source.Schema != "" {
+		if err := schema.ValidateYAML(content, source.Schema); err != nil {
+			return "", 0, err
+		}
+	}
+	if source.CUE != "" {
+		if err := schema.ValidateCUE(content, source.CUE); err != nil {
+			return "", 0, err
+		}
+	}
+
+	destination := source.Destination
+	if destination == "" {
+		destination = filepath.Join(workDir, workdir.ManifestDir, source.Repo, source.File)
+	}
+
+	if err := fileutil.WriteFileAtomic(destination, content, 0644, !noBackup); err != nil {
+		return "", 0, fmt.Errorf("failed to write destination: %w", err)
+	}
+
+	return destination, len(content), nil
+}
+
+// manifestSourceConfig returns the configuration used to fetch a source
+func manifestSourceConfig(source manifest.Source) (*config.Config, error) {
+	cfg := loadFetchConfig()
+	cfg.RepositoryPath = source.Repo
+	cfg.FilePath = source.File
+	if source.Ref != "" {
+		cfg.Branch = source.Ref
+	}
+	if source.URL != "" {
+		cfg.GitLabURL = source.URL
+	}
+	if source.Instance != "" {
+		fileConfig, err := config.LoadConfigFile(cfgFile, profile)
+		if err != nil {
+			return nil, err
+		}
+		instance, err := fileConfig.Instance(source.Instance)
+		if err != nil {
+			return nil, err
+		}
+		cfg.GitLabURL = instance.URL
+		cfg.GitLabToken = instance.Token
+	}
+
+	if err := cfg.ValidateConfig(); err != nil {
+		return nil, fmt.Errorf("configuration error: %w", err)
+	}
+	return cfg, nil
+}
+
+// preflightManifest checks the token of every GitLab instance used by the
+// sources before any file is fetched
+func preflightManifest(ctx context.Context, sources []manifest.Source) error {
+	checked := make(map[string]bool)
+	for _, source := range sources {
+		cfg, err := manifestSourceConfig(source)
+		if err != nil {
+			// Reported with the result of the source
+			continue
+		}
+		if key := cfg.GitLabURL + "\x00" + cfg.GitLabToken; !checked[key] {
+			checked[key] = true
+			if err := preflightGitLab(ctx, cfg); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// printManifestSummary prints a table with the result of every source and
+// returns the number of failures. With skipMissing, sources whose file does
+// not exist are reported as skipped instead of failed.
+func printManifestSummary(results []manifestResult, skipMissing bool) (int, int) {
+	failed, skipped := 0, 0
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SOURCE\tREF\tDESTINATION\tSTATUS")
+	for _, result := range results {
+		ref := result.source.Ref
+		if ref == "" {
+			ref = "(default)"
+		}
+
+		if skipMissing && errors.Is(result.err, gitlab.ErrFileNotFound) {
+			skipped++
+			ui.Fprintf(w, "%s\t%s\t%s\t⏭️  file not found\n", result.source.Name, ref, "-")
+			continue
+		}
+		if result.err != nil {
+			failed++
+			ui.Fprintf(w, "%s\t%s\t%s\t❌ %v\n", result.source.Name, ref, "-", result.err)
+			continue
+		}
+		ui.Fprintf(w, "%s\t%s\t%s\t✅ %d bytes\n", result.source.Name, ref, result.destination, result.size)
+	}
+	w.Flush()
+	fmt.Println()
+
+	return failed, skipped
+}
+
+// recordManifestResults adds every fetched source to the artifact index,
+// one record per source
+func recordManifestResults(command string, results []manifestResult) {
+	for _, result := range results {
+		if result.err != nil {
+			continue
+		}
+		recordArtifacts(workDir, command, map[string]string{
+			"source": result.source.Name,
+			"url":    result.source.URL,
+			"repo":   result.source.Repo,
+			"ref":    result.source.Ref,
+			"file":   result.source.File,
+		}, result.destination)
+	}
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"drivio/pkg/fileutil"
+	"drivio/pkg/ui"
+	"drivio/pkg/workdir"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	assetProject string
+	assetTag     string
+	assetName    string
+)
+
+// fetchReleaseAssetCmd represents the fetch release-asset command
+var fetchReleaseAssetCmd = &cobra.Command{
+	Use:   "release-asset",
+	Short: "Fetch an asset attached to a GitLab release",
+	Long: `Fetch an asset link attached to a GitLab release, identified by the
+release tag and the asset name.
+
+This is useful for versioned configuration bundles published as release
+assets rather than committed repository files.
+
+Examples:
+  drivio fetch release-asset --project jparrill/my-config --tag v1.2.0 --name config-bundle.tar.gz
+  drivio fetch release-asset --project jparrill/my-config --tag v1.2.0 --name production.yaml --output production.yaml`,
+	RunE: runFetchReleaseAsset,
+}
+
+func init() {
+	fetchCmd.AddCommand(fetchReleaseAssetCmd)
+
+	// Add flags
+	fetchReleaseAssetCmd.Flags().StringVar(&assetProject, "project", "", "Project path (e.g., owner/repo)")
+	fetchReleaseAssetCmd.Flags().StringVar(&assetTag, "tag", "", "Tag of the release")
+	fetchReleaseAssetCmd.Flags().StringVar(&assetName, "name", "", "Name of the release asset link")
+
+	// Mark required flags
+	fetchReleaseAssetCmd.MarkFlagRequired("project")
+	fetchReleaseAssetCmd.MarkFlagRequired("tag")
+	fetchReleaseAssetCmd.MarkFlagRequired("name")
+}
+
+func runFetchReleaseAsset(cmd *cobra.Command, args []string) error {
+	// Create work directory if it doesn't exist
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return fmt.Errorf("failed to create work directory: %w", err)
+	}
+
+	unlock, err := lockWorkDir(workDir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := enforceRetention(workDir, ui.Printf); err != nil {
+		return err
+	}
+
+	cfg := loadFetchConfig()
+	cfg.RepositoryPath = assetProject
+	cfg.FilePath = assetName
+
+	// Validate configuration
+	if err := cfg.ValidateConfig(); err != nil {
+		return fmt.Errorf("configuration error: %w", err)
+	}
+
+	workFilePath, err := releaseAssetPath(workDir, assetTag, assetName)
+	if err != nil {
+		return err
+	}
+
+	client, err := newFetchClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	// Step 1: Download the asset
+	var content []byte
+	if err := ui.RunSpinner("Downloading release asset...", func() error {
+		var err error
+		content, err = client.GetReleaseAsset(ctx, assetTag, assetName)
+		return err
+	}); err != nil {
+		return fmt.Errorf("failed to fetch release asset: %w", err)
+	}
+	ui.Printf("✅ Release asset fetched successfully (%d bytes)\n", len(content))
+
+	// Step 2: Save to work directory
+	if err := ui.RunSpinner("Saving release asset...", func() error {
+		return fileutil.WriteFileAtomic(workFilePath, content, 0644, false)
+	}); err != nil {
+		return fmt.Errorf("failed to write release asset to work directory: %w", err)
+	}
+	ui.Printf("💾 Release asset saved successfully: %s\n", workFilePath)
+	outputs := []string{workFilePath}
+
+	if outputFile != "" {
+		if outputFile != workFilePath {
+			if err := fileutil.WriteFileAtomic(outputFile, content, 0644, !noBackup); err != nil {
+				return fmt.Errorf("failed to write output file: %w", err)
+			}
+			ui.Printf("💾 Release asset also saved to: %s\n", outputFile)
+			outputs = append(outputs, outputFile)
+		}
+	}
+
+	recordArtifacts(workDir, "fetch release-asset", map[string]string{
+		"url":   cfg.GitLabURL,
+		"repo":  cfg.RepositoryPath,
+		"tag":   assetTag,
+		"asset": assetName,
+	}, outputs...)
+
+	return nil
+}
+
+// releaseAssetPath returns the path of an asset in the work directory,
+// releases/<tag>/<asset>. Slashes of the tag are replaced like in clone
+// directory names, so team/v1.0 is not nested, and paths leading outside the
+// releases directory, e.g. for a tag of .., are rejected.
+func releaseAssetPath(dir, tag, asset string) (string, error) {
+	releasesDir := filepath.Join(dir, workdir.ReleasesDir)
+	releaseDir := filepath.Join(releasesDir, strings.ReplaceAll(tag, "/", "-"))
+	if !strings.HasPrefix(releaseDir, releasesDir+string(os.PathSeparator)) {
+		return "", fmt.Errorf("invalid release tag for a directory name: %s", tag)
+	}
+	path := filepath.Join(releaseDir, filepath.Base(asset))
+	if !strings.HasPrefix(path, releaseDir+string(os.PathSeparator)) {
+		return "", fmt.Errorf("invalid release asset name for a file name: %s", asset)
+	}
+	return path, nil
+}
@@ -1,14 +1,39 @@
 package cmd
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
+	"os/signal"
+	"path"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
+	"drivio/pkg/audit"
 	"drivio/pkg/config"
+	"drivio/pkg/convert"
+	"drivio/pkg/diff"
+	"drivio/pkg/envsubst"
+	"drivio/pkg/fileutil"
 	"drivio/pkg/gitlab"
+	"drivio/pkg/history"
+	"drivio/pkg/kube"
+	"drivio/pkg/merge"
+	"drivio/pkg/notify"
+	"drivio/pkg/query"
+	"drivio/pkg/redact"
+	"drivio/pkg/render"
+	"drivio/pkg/schema"
 	"drivio/pkg/ui"
+	"drivio/pkg/verify"
 
 	"github.com/spf13/cobra"
 	gitlabAPI "gitlab.com/gitlab-org/api/client-go"
@@ -17,13 +42,50 @@ import (
 var (
 	// Configuration flags
 	gitlabURL      string
+	gitlabInstance string
 	gitlabToken    string
 	repositoryPath string
 	branch         string
 	filePath       string
 	outputFile     string
 	validateOnly   bool
-	fetchWorkDir   string
+	expectedSHA256 string
+	checksumFile   string
+	renderTemplate bool
+	valuesFile     string
+	watchMode      bool
+	watchInterval  time.Duration
+	onChangeHook   string
+	fetchNotify    []string
+	retryAttempts  int
+	retryBackoff   time.Duration
+	stdoutOnly     bool
+	manifestPath   string
+	noBackup       bool
+	listHistory    bool
+	restoreID      string
+	verifySig      bool
+	keyringPath    string
+	signaturePath  string
+	mergeOverlays  []string
+	mergeStrategy  string
+	environment    string
+	filePattern    string
+	fetchWorkers   int
+	plainOutput    bool
+	queryPath      string
+	showSecrets    bool
+	redactPatterns []string
+	convertFormat  string
+	applyAs        string
+	kubeNamespace  string
+	applyKey       string
+	kubeconfigPath string
+	kubeContext    string
+	compareWith    string
+	substituteVars bool
+	envFile        string
+	cueSchema      string
 )
 
 // fetchCmd represents the fetch command
@@ -36,72 +98,229 @@ Examples:
   drivio fetch --repo gitlab-org/gitlab-foss --file db/database_connections/ci.yaml
   drivio fetch --repo jparrill/my-config --file config/production.yaml --token YOUR_TOKEN
   drivio fetch --branch develop --output config.yaml
-  drivio fetch --validate-only`,
+  drivio fetch --repo jparrill/my-config --file config/production.yaml --branch main,master,release-4.19
+  drivio fetch --validate-only
+  drivio fetch --repo jparrill/my-config --file config/production.yaml --sha256 3a7bd3e2...
+  drivio fetch --repo jparrill/my-config --file config/production.yaml --sha256-file SHA256SUMS
+  drivio fetch --repo jparrill/my-config --file config/app.yaml.tmpl --render --values values.yaml
+  drivio fetch --repo jparrill/my-config --file config/app.yaml --substitute --env-file staging.env
+  drivio fetch --repo jparrill/my-config --file config/production.yaml --output /etc/app/config.yaml --watch --interval 60s --on-change "systemctl reload app"
+  drivio fetch --repo jparrill/my-config --file k8s/configmap.yaml --stdout-only | kubectl apply -f -
+  drivio fetch --repo jparrill/my-config --file config/production.yaml --cue schemas/config.cue#Config
+  drivio fetch --repo jparrill/my-config --file config/production.yaml --verify-signature --keyring trusted.asc
+  drivio fetch --repo jparrill/my-config --file config/base.yaml --merge config/overlays/production.yaml
+  drivio fetch --repo jparrill/my-config --file-pattern "config/{env}/database.yaml" --env prod
+  drivio fetch --repo jparrill/my-config --file config/production.yaml --query '.database.host' --stdout-only
+  drivio fetch --repo jparrill/my-config --file config/production.yaml --convert json --stdout-only | jq .
+  drivio fetch --repo jparrill/my-config --file config/production.yaml --apply-as configmap/app-config -n prod
+  drivio fetch --repo jparrill/my-config --file config/production.yaml --compare-with configmap/app-config -n prod
+  drivio fetch --repo jparrill/my-config --file config/production.yaml --compare-with configmap/app-config -n prod --notify team-slack
+  drivio fetch --manifest drivio.fetch.yaml
+  drivio fetch --manifest drivio.fetch.yaml --parallel 8
+  drivio fetch --list-history
+  drivio fetch --restore 20250101T120000Z-3a7bd3e2 --output config/production.yaml`,
 	RunE: runFetch,
 }
 
 func init() {
 	rootCmd.AddCommand(fetchCmd)
 
+	// Flags shared with the fetch subcommands
+	fetchCmd.PersistentFlags().StringVar(&gitlabURL, "url", "", "GitLab URL (default: https://gitlab.com)")
+	fetchCmd.PersistentFlags().StringVar(&gitlabToken, "token", "", "GitLab access token (optional for public repositories)")
+	fetchCmd.PersistentFlags().StringVar(&gitlabInstance, "instance", "", "GitLab instance of the config file providing the URL and token")
+	fetchCmd.PersistentFlags().StringVar(&outputFile, "output", "", "Output file path (default: stdout)")
+	fetchCmd.PersistentFlags().IntVar(&retryAttempts, "retries", 0, "Total attempts for transient GitLab errors (default: 3)")
+	fetchCmd.PersistentFlags().BoolVar(&noBackup, "no-backup", false, "Do not keep the previous content of overwritten output files as <file>.bak")
+	fetchCmd.PersistentFlags().DurationVar(&retryBackoff, "retry-backoff", 0, "Initial backoff between retries, doubled on every attempt (default: 1s)")
+
 	// Add flags
-	fetchCmd.Flags().StringVar(&gitlabURL, "url", "", "GitLab URL (default: https://gitlab.com)")
-	fetchCmd.Flags().StringVar(&gitlabToken, "token", "", "GitLab access token (optional for public repositories)")
 	fetchCmd.Flags().StringVar(&repositoryPath, "repo", "", "Repository path (e.g., owner/repo)")
-	fetchCmd.Flags().StringVar(&branch, "branch", "", "Branch name")
+	fetchCmd.Flags().StringVar(&branch, "branch", "", "Branch name, or a comma-separated list tried in order until the file is found")
 	fetchCmd.Flags().StringVar(&filePath, "file", "", "Path to the file in the repository")
-	fetchCmd.Flags().StringVar(&outputFile, "output", "", "Output file path (default: stdout)")
+	fetchCmd.Flags().StringVar(&environment, "env", "", "Environment used to resolve the file path through the file pattern")
+	fetchCmd.Flags().StringVar(&filePattern, "file-pattern", "", "File path pattern with an {env} placeholder (e.g., config/{env}/database.yaml)")
 	fetchCmd.Flags().BoolVar(&validateOnly, "validate-only", false, "Only validate connection and repository access")
-	fetchCmd.Flags().StringVar(&fetchWorkDir, "work-dir", ".drivio-work", "Working directory for downloaded files")
+	fetchCmd.Flags().StringVar(&expectedSHA256, "sha256", "", "Expected SHA-256 digest of the fetched file")
+	fetchCmd.Flags().StringVar(&checksumFile, "sha256-file", "", "Checksum file (sha256sum format) containing the expected digest")
+	fetchCmd.Flags().BoolVar(&verifySig, "verify-signature", false, "Verify a detached GPG signature (<file>.asc or <file>.sig) before writing output")
+	fetchCmd.Flags().StringVar(&keyringPath, "keyring", "", "Keyring with the trusted public keys (armored or binary)")
+	fetchCmd.Flags().StringVar(&signaturePath, "signature", "", "Path of the detached signature in the repository (default: <file>.asc, then <file>.sig)")
+	fetchCmd.Flags().StringArrayVar(&mergeOverlays, "merge", nil, "Overlay file in the repository deep-merged onto --file (repeatable, applied in order)")
+	fetchCmd.Flags().StringVar(&mergeStrategy, "merge-strategy", string(merge.StrategyOverride), "Conflict strategy for --merge: override, append, keep or error")
+	fetchCmd.Flags().StringVar(&convertFormat, "convert", "", "Convert the fetched file to another format: json or yaml")
+	fetchCmd.Flags().StringVar(&applyAs, "apply-as", "", "Create or update a Kubernetes object with the fetched file: configmap/NAME or secret/NAME")
+	fetchCmd.Flags().StringVar(&compareWith, "compare-with", "", "Diff the fetched file against a live Kubernetes object and fail on drift: configmap/NAME or secret/NAME")
+	fetchCmd.Flags().StringVarP(&kubeNamespace, "namespace", "n", "", "Namespace of the --apply-as or --compare-with object (default: namespace of the current context)")
+	fetchCmd.Flags().StringVar(&applyKey, "apply-key", "", "Data key of the --apply-as or --compare-with object (default: base name of --file)")
+	fetchCmd.Flags().StringVar(&kubeconfigPath, "kubeconfig", "", "Path to the kubeconfig file (default: $KUBECONFIG, ~/.kube/config, then in-cluster credentials)")
+	fetchCmd.Flags().StringVar(&kubeContext, "kube-context", "", "Kubeconfig context to use")
+	fetchCmd.Flags().BoolVar(&renderTemplate, "render", false, "Render the fetched file as a Go template")
+	fetchCmd.Flags().StringVar(&valuesFile, "values", "", "YAML values file used when rendering (requires --render)")
+	fetchCmd.Flags().BoolVar(&substituteVars, "substitute", false, "Expand ${VAR} placeholders in the fetched file from the environment")
+	fetchCmd.Flags().StringVar(&envFile, "env-file", "", "Dotenv file with values for --substitute, taking precedence over the environment")
+	fetchCmd.Flags().StringVar(&cueSchema, "cue", "", "Validate the fetched file against a CUE definition (e.g., schema.cue#Config)")
+	fetchCmd.Flags().BoolVar(&watchMode, "watch", false, "Keep polling the remote file and rewrite the output when it changes")
+	fetchCmd.Flags().DurationVar(&watchInterval, "interval", 60*time.Second, "Polling interval for --watch")
+	fetchCmd.Flags().StringVar(&onChangeHook, "on-change", "", "Shell command executed after the file changed (requires --watch)")
+	fetchCmd.Flags().StringArrayVar(&fetchNotify, "notify", nil, "Notification channel to alert on drift (--compare-with) or changes (--watch): a channel of the config file, or TYPE:URL; repeatable")
+	fetchCmd.Flags().BoolVar(&stdoutOnly, "stdout-only", false, "Write the file content to stdout only, without status messages or files on disk")
+	fetchCmd.Flags().BoolVar(&listHistory, "list-history", false, "List previously fetched versions recorded in the work directory")
+	fetchCmd.Flags().StringVar(&restoreID, "restore", "", "Restore a previously fetched version by history ID or SHA-256 prefix")
+	fetchCmd.Flags().StringVar(&queryPath, "query", "", "Extract a sub-document or scalar with a yq-style path (e.g., .database.host)")
+	fetchCmd.Flags().BoolVar(&showSecrets, "show-secrets", false, "Do not redact sensitive values when showing content")
+	fetchCmd.Flags().StringSliceVar(&redactPatterns, "redact-keys", nil, "Key patterns (regular expressions) whose values are redacted when showing content (default: password,passwd,token,secret,key,credential)")
+	fetchCmd.Flags().BoolVar(&plainOutput, "plain", false, "Print content without pretty-printing or syntax highlighting")
+	fetchCmd.Flags().StringVar(&manifestPath, "manifest", "", "Fetch every source declared in a batch manifest (e.g., drivio.fetch.yaml)")
+	fetchCmd.Flags().IntVar(&fetchWorkers, "parallel", 4, "Number of concurrent downloads for --manifest")
 
 	// Remove the required flag for token since it's optional for public repos
+
+	// Environment variables that take precedence over the config file
+	bindFlagEnv(fetchCmd.PersistentFlags(), "url", config.EnvGitLabURL...)
+	bindFlagEnv(fetchCmd.PersistentFlags(), "token", config.EnvGitLabToken...)
+	bindFlagEnv(fetchCmd.PersistentFlags(), "instance", config.EnvInstance...)
+	bindFlagEnv(fetchCmd.PersistentFlags(), "retries", config.EnvRetryAttempts...)
+	bindFlagEnv(fetchCmd.PersistentFlags(), "retry-backoff", config.EnvRetryBackoff...)
+	bindFlagEnv(fetchCmd.Flags(), "repo", config.EnvRepoPath...)
+	bindFlagEnv(fetchCmd.Flags(), "branch", config.EnvBranch...)
+	bindFlagEnv(fetchCmd.Flags(), "file", config.EnvFilePath...)
+	bindFlagEnv(fetchCmd.Flags(), "file-pattern", config.EnvFilePattern...)
 }
 
 func runFetch(cmd *cobra.Command, args []string) error {
-	// Create work directory if it doesn't exist
-	if err := os.MkdirAll(fetchWorkDir, 0755); err != nil {
-		return fmt.Errorf("failed to create work directory: %w", err)
+	if stdoutOnly && (outputFile != "" || watchMode || validateOnly) {
+		return fmt.Errorf("--stdout-only cannot be combined with --output, --watch or --validate-only")
 	}
 
-	// Load configuration
-	cfg := config.LoadConfig()
+	// Create work directory if it doesn't exist
+	unlock := func() {}
+	if !stdoutOnly {
+		if err := os.MkdirAll(workDir, 0755); err != nil {
+			return fmt.Errorf("failed to create work directory: %w", err)
+		}
 
-	// Override with flags if provided
-	if gitlabURL != "" {
-		cfg.GitLabURL = gitlabURL
+		var err error
+		unlock, err = lockWorkDir(workDir)
+		if err != nil {
+			return err
+		}
+		defer unlock()
 	}
-	if gitlabToken != "" {
-		cfg.GitLabToken = gitlabToken
+
+	if listHistory {
+		return printFetchHistory()
 	}
-	if repositoryPath != "" {
-		cfg.RepositoryPath = repositoryPath
+	if restoreID != "" {
+		return restoreFromHistory(restoreID)
 	}
-	if branch != "" {
-		cfg.Branch = branch
+	if !stdoutOnly {
+		if err := enforceRetention(workDir, fetchStatus); err != nil {
+			return err
+		}
 	}
-	if filePath != "" {
-		cfg.FilePath = filePath
+
+	if manifestPath != "" {
+		if repositoryPath != "" || filePath != "" || outputFile != "" || watchMode || stdoutOnly || validateOnly || renderTemplate || expectedSHA256 != "" || checksumFile != "" {
+			return fmt.Errorf("--manifest cannot be combined with single-file fetch flags")
+		}
+		return runFetchManifest(context.Background(), manifestPath)
+	}
+
+	cfg := loadFetchConfig()
+
+	// Resolve the file path for the requested environment
+	if err := cfg.ResolveEnvironment(environment); err != nil {
+		return fmt.Errorf("configuration error: %w", err)
 	}
 
 	// Validate configuration
 	if err := cfg.ValidateConfig(); err != nil {
 		return fmt.Errorf("configuration error: %w", err)
 	}
+	if cfg.Environment != "" {
+		fetchStatus("🌍 Environment %s resolved to %s\n", cfg.Environment, cfg.FilePath)
+	}
 
-	// Check if token is required
-	if cfg.RequiresToken() {
-		return fmt.Errorf("GitLab token is required for this repository. Set GITLAB_TOKEN environment variable or use --token flag")
+	if expectedSHA256 != "" && checksumFile != "" {
+		return fmt.Errorf("--sha256 and --sha256-file cannot be used together")
+	}
+	if checksumFile != "" {
+		digest, err := verify.ReadChecksumFile(checksumFile, cfg.FilePath)
+		if err != nil {
+			return fmt.Errorf("failed to read checksum: %w", err)
+		}
+		expectedSHA256 = digest
 	}
 
-	// Create GitLab client
-	client, err := gitlab.NewClient(cfg)
+	if verifySig && keyringPath == "" {
+		return fmt.Errorf("--verify-signature requires --keyring")
+	}
+	if (keyringPath != "" || signaturePath != "") && !verifySig {
+		return fmt.Errorf("--keyring and --signature require --verify-signature")
+	}
+
+	if _, err := merge.ParseStrategy(mergeStrategy); err != nil {
+		return err
+	}
+	if _, err := redact.New(redactPatterns); err != nil {
+		return err
+	}
+	if convertFormat != "" {
+		if _, err := convert.ParseFormat(convertFormat); err != nil {
+			return err
+		}
+	}
+	if applyAs != "" {
+		if _, err := kube.ParseObjectRef(applyAs); err != nil {
+			return err
+		}
+	}
+	if compareWith != "" {
+		if _, err := kube.ParseObjectRef(compareWith); err != nil {
+			return err
+		}
+		if applyAs != "" || watchMode || stdoutOnly || outputFile != "" {
+			return fmt.Errorf("--compare-with cannot be combined with --apply-as, --watch, --stdout-only or --output")
+		}
+	}
+	if applyAs == "" && compareWith == "" && (kubeNamespace != "" || applyKey != "") {
+		return fmt.Errorf("--namespace and --apply-key require --apply-as or --compare-with")
+	}
+
+	if valuesFile != "" && !renderTemplate {
+		return fmt.Errorf("--values requires --render")
+	}
+	if envFile != "" && !substituteVars {
+		return fmt.Errorf("--env-file requires --substitute")
+	}
+
+	if onChangeHook != "" && !watchMode {
+		return fmt.Errorf("--on-change requires --watch")
+	}
+	// Channels may come from the notify key of the config file, which
+	// applies to every fetch: only those alerting are resolved
+	var channels []notifyChannel
+	if compareWith != "" || watchMode {
+		var err error
+		if channels, err = notifyChannels(fetchNotify); err != nil {
+			return err
+		}
+	}
+	if watchMode && watchInterval <= 0 {
+		return fmt.Errorf("--interval must be greater than zero")
+	}
+
+	client, err := newFetchClient(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to create GitLab client: %w", err)
+		return err
 	}
 
-	ctx := context.Background()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Step 1: Validate connection
-	if err := ui.RunSpinner("Validating GitLab connection...", func() error {
+	if err := fetchStep("Validating GitLab connection...", func() error {
 		if cfg.IsPublicRepository() && cfg.GitLabToken == "" {
 			return nil // No validation needed for public repo
 		}
@@ -112,53 +331,747 @@ func runFetch(cmd *cobra.Command, args []string) error {
 
 	// Step 2: Get repository info
 	var project *gitlabAPI.Project
-	if err := ui.RunSpinner("Getting repository info...", func() error {
+	if err := fetchStep("Getting repository info...", func() error {
 		var err error
 		project, err = client.GetRepositoryInfo(ctx)
 		return err
 	}); err != nil {
 		return fmt.Errorf("failed to get repository info: %w", err)
 	}
-	fmt.Printf("✅ Repository found: %s\n", project.Name)
+	fetchStatus("✅ Repository found: %s\n", project.Name)
 
 	if validateOnly {
-		fmt.Printf("✅ Validation completed successfully\n")
+		ui.Printf("✅ Validation completed successfully\n")
 		return nil
 	}
 
+	// Step 3: Resolve the branch when a fallback list was given
+	if len(cfg.Branches()) > 1 {
+		if err := fetchStep("Resolving branch...", func() error {
+			return resolveBranch(ctx, client, cfg)
+		}); err != nil {
+			return err
+		}
+		fetchStatus("🌿 Using branch: %s\n", cfg.Branch)
+	}
+
+	if watchMode {
+		// The watch never ends: it only locks the work directory while
+		// fetching. Releasing the lock again when returning does nothing.
+		unlock()
+		return watchFile(ctx, client, cfg, channels)
+	}
+
+	if compareWith != "" {
+		return compareWithCluster(ctx, client, cfg, channels)
+	}
+
+	if stdoutOnly {
+		content, err := fetchContent(ctx, client, cfg)
+		if err != nil {
+			return err
+		}
+		if applyAs != "" {
+			if err := applyToCluster(ctx, cfg, content); err != nil {
+				return err
+			}
+		}
+		// Piped content is data for another tool and is kept verbatim;
+		// only redact what is displayed on a terminal
+		if ui.IsTerminal() {
+			return displayContent(content)
+		}
+		return printContent(content)
+	}
+
+	_, err = fetchAndSave(ctx, client, cfg)
+	return err
+}
+
+// displayContent prints the file content for a human reader, with the
+// values of sensitive keys redacted unless --show-secrets is set
+func displayContent(content []byte) error {
+	if !showSecrets {
+		redactor, err := redact.New(redactPatterns)
+		if err != nil {
+			return err
+		}
+		var count int
+		content, count = redactor.Redact(content)
+		if count > 0 {
+			defer ui.Fprintf(os.Stderr, "🙈 %d sensitive value(s) redacted (use --show-secrets to display them)\n", count)
+		}
+	}
+	return printContent(content)
+}
+
+// printContent writes the file content to stdout, pretty-printed and
+// syntax-highlighted when stdout is a terminal unless --plain is set
+func printContent(content []byte) error {
+	if plainOutput || !ui.IsTerminal() {
+		_, err := os.Stdout.Write(content)
+		return err
+	}
+
+	highlighted := ui.Highlight(content)
+	if !strings.HasSuffix(highlighted, "\n") {
+		highlighted += "\n"
+	}
+	_, err := fmt.Print(highlighted)
+	return err
+}
+
+// fetchStep runs a fetch step with a spinner, or silently when stdout is
+// reserved for the file content
+func fetchStep(message string, task func() error) error {
+	if stdoutOnly {
+		return task()
+	}
+	return ui.RunSpinner(message, task)
+}
+
+// fetchProgress runs a fetch step with a progress bar, or silently when
+// stdout is reserved for the file content
+func fetchProgress(title, unit string, task func(progress chan<- ui.ProgressMsg) error) error {
+	if stdoutOnly {
+		return ui.RunWithoutProgress(task)
+	}
+	return ui.RunProgress(title, unit, task)
+}
+
+// fetchStatus prints a status message unless stdout is reserved for the
+// file content or --quiet is set
+func fetchStatus(format string, a ...interface{}) {
+	if stdoutOnly || ui.Quiet() {
+		return
+	}
+	ui.Printf(format, a...)
+}
+
+// resolveBranch tries the candidate branches in order and keeps the first
+// one that contains the file
+func resolveBranch(ctx context.Context, client *gitlab.Client, cfg *config.Config) error {
+	candidates := cfg.Branches()
+	for _, candidate := range candidates {
+		cfg.Branch = candidate
+		_, err := client.GetFileMetadata(ctx)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, gitlab.ErrFileNotFound) {
+			return fmt.Errorf("failed to check branch %s: %w", candidate, err)
+		}
+	}
+	return fmt.Errorf("%w: %s in any of the branches %s", gitlab.ErrFileNotFound, cfg.FilePath, strings.Join(candidates, ", "))
+}
+
+// loadFetchConfig loads the configuration and applies the fetch flags on top
+func loadFetchConfig() *config.Config {
+	cfg := config.LoadConfig()
+
+	// Override with flags if provided
+	if gitlabURL != "" {
+		cfg.GitLabURL = gitlabURL
+	}
+	if gitlabToken != "" {
+		cfg.GitLabToken = gitlabToken
+	}
+	if repositoryPath != "" {
+		cfg.RepositoryPath = repositoryPath
+	}
+	if branch != "" {
+		cfg.Branch = branch
+	}
+	if filePath != "" {
+		cfg.FilePath = filePath
+	}
+	if filePattern != "" {
+		cfg.FilePattern = filePattern
+	}
+	if retryAttempts > 0 {
+		cfg.RetryAttempts = retryAttempts
+	}
+	if retryBackoff > 0 {
+		cfg.RetryBackoff = retryBackoff
+	}
+
+	return cfg
+}
+
+// newFetchClient creates the GitLab client used by fetch and its subcommands
+func newFetchClient(cfg *config.Config) (*gitlab.Client, error) {
+	// Without a token, check whether the repository is public
+	if cfg.GitLabToken == "" && cfg.Visibility == "" {
+		visibility, err := gitlab.ProjectVisibility(context.Background(), cfg)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Visibility = visibility
+	}
+
+	// Check if token is required
+	if cfg.RequiresToken() {
+		return nil, fmt.Errorf("GitLab token is required: %s is not a public repository on %s (or does not exist). Set GITLAB_TOKEN environment variable or use --token flag", cfg.RepositoryPath, cfg.GitLabURL)
+	}
+
+	// Create GitLab client
+	client, err := gitlab.NewClient(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GitLab client: %w", err)
+	}
+	client.SetRetryNotifier(func(attempt int, err error, wait time.Duration) {
+		ui.Fprintf(retryOutput(), "\n⚠️  Attempt %d/%d failed: %v (retrying in %s)\n", attempt, cfg.RetryAttempts, err, wait.Round(time.Millisecond))
+	})
+
+	return client, nil
+}
+
+// retryOutput returns where retry warnings are printed: stderr when stdout is
+// reserved for the file content
+func retryOutput() io.Writer {
+	if stdoutOnly {
+		return os.Stderr
+	}
+	return ui.StatusWriter()
+}
+
+// fetchContent fetches the file, verifies its checksum and renders it
+func fetchContent(ctx context.Context, client *gitlab.Client, cfg *config.Config) ([]byte, error) {
 	// Step 3: Fetch the file
 	var content []byte
-	if err := ui.RunSpinner("Fetching file...", func() error {
+	if err := fetchProgress("Fetching file...", ui.UnitBytes, func(progress chan<- ui.ProgressMsg) error {
 		var err error
-		content, err = client.GetFile(ctx)
+		content, err = client.FetchWithProgress(ctx, progress)
 		return err
 	}); err != nil {
-		return fmt.Errorf("failed to fetch file: %w", err)
+		return nil, fmt.Errorf("failed to fetch file: %w", err)
+	}
+	fetchStatus("✅ File fetched successfully (%d bytes)\n", len(content))
+
+	// Verify checksum before anything is written to disk
+	if expectedSHA256 != "" {
+		if err := verify.VerifySHA256(content, expectedSHA256); err != nil {
+			return nil, fmt.Errorf("checksum verification failed: %w", err)
+		}
+		fetchStatus("🔒 Checksum verified (sha256:%s)\n", verify.SHA256(content))
+	}
+
+	// Verify the detached signature, also before anything is written
+	if verifySig {
+		signer, err := verifyFileSignature(ctx, client, cfg, content)
+		if err != nil {
+			return nil, err
+		}
+		fetchStatus("🔏 Signature verified: signed by %s\n", signer)
+	}
+
+	// Deep-merge the overlays onto the base document
+	if len(mergeOverlays) > 0 {
+		merged, err := mergeOverlayFiles(ctx, client, content)
+		if err != nil {
+			return nil, err
+		}
+		content = merged
+		fetchStatus("🔀 Merged %d overlay(s) using the %s strategy (%d bytes)\n", len(mergeOverlays), mergeStrategy, len(content))
+	}
+
+	// Render the file as a template with the provided values and environment
+	if renderTemplate {
+		var values map[string]interface{}
+		if valuesFile != "" {
+			var err error
+			values, err = render.LoadValues(valuesFile)
+			if err != nil {
+				return nil, err
+			}
+		}
+		rendered, err := render.Render(cfg.FilePath, content, render.NewData(values))
+		if err != nil {
+			return nil, err
+		}
+		content = rendered
+		fetchStatus("🧩 Template rendered successfully (%d bytes)\n", len(content))
+	}
+
+	// Expand ${VAR} placeholders from the env file and the environment
+	if substituteVars {
+		var values map[string]string
+		if envFile != "" {
+			var err error
+			values, err = envsubst.LoadEnvFile(envFile)
+			if err != nil {
+				return nil, err
+			}
+		}
+		expanded, err := envsubst.Expand(content, envsubst.Lookup(values))
+		if err != nil {
+			return nil, fmt.Errorf("substitution failed: %w", err)
+		}
+		content = expanded
+		fetchStatus("💲 Variables substituted (%d bytes)\n", len(content))
+	}
+
+	// Validate the effective document against the CUE definition
+	if cueSchema != "" {
+		if err := schema.ValidateCUE(content, cueSchema); err != nil {
+			return nil, err
+		}
+		fetchStatus("📐 CUE schema validated (%s)\n", cueSchema)
+	}
+
+	// Keep only the requested part of the document
+	if queryPath != "" {
+		extracted, err := query.Extract(content, queryPath)
+		if err != nil {
+			return nil, fmt.Errorf("query failed: %w", err)
+		}
+		content = extracted
+		fetchStatus("🔎 Query %s matched (%d bytes)\n", queryPath, len(content))
+	}
+
+	// Convert the result for tools that only speak one format
+	if convertFormat != "" {
+		format, err := convert.ParseFormat(convertFormat)
+		if err != nil {
+			return nil, err
+		}
+		converted, err := convert.Convert(content, format)
+		if err != nil {
+			return nil, fmt.Errorf("conversion to %s failed: %w", format, err)
+		}
+		content = converted
+		fetchStatus("🔁 Converted to %s (%d bytes)\n", format, len(content))
+	}
+
+	return content, nil
+}
+
+// verifyFileSignature fetches the detached signature of the file from the
+// repository and verifies it against the trusted keyring
+func verifyFileSignature(ctx context.Context, client *gitlab.Client, cfg *config.Config, content []byte) (string, error) {
+	keyring, err := verify.LoadKeyring(keyringPath)
+	if err != nil {
+		return "", err
+	}
+
+	candidates := []string{cfg.FilePath + ".asc", cfg.FilePath + ".sig"}
+	if signaturePath != "" {
+		candidates = []string{signaturePath}
+	}
+
+	var signature []byte
+	if err := fetchStep("Fetching signature...", func() error {
+		var lastErr error
+		for _, candidate := range candidates {
+			signature, lastErr = client.GetFileByPath(ctx, candidate)
+			if lastErr == nil {
+				return nil
+			}
+		}
+		return lastErr
+	}); err != nil {
+		return "", fmt.Errorf("failed to fetch signature: %w", err)
+	}
+
+	return verify.VerifyDetachedSignature(content, signature, keyring)
+}
+
+// mergeOverlayFiles fetches the --merge overlays and deep-merges them onto
+// the base document
+func mergeOverlayFiles(ctx context.Context, client *gitlab.Client, base []byte) ([]byte, error) {
+	strategy, err := merge.ParseStrategy(mergeStrategy)
+	if err != nil {
+		return nil, err
+	}
+
+	overlays := make([][]byte, 0, len(mergeOverlays))
+	for _, overlayPath := range mergeOverlays {
+		var overlay []byte
+		if err := fetchStep(fmt.Sprintf("Fetching overlay %s...", overlayPath), func() error {
+			var err error
+			overlay, err = client.GetFileByPath(ctx, overlayPath)
+			return err
+		}); err != nil {
+			return nil, fmt.Errorf("failed to fetch overlay: %w", err)
+		}
+		overlays = append(overlays, overlay)
+	}
+
+	merged, err := merge.YAML(base, overlays, strategy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge overlays: %w", err)
+	}
+	return merged, nil
+}
+
+// fetchAndSave fetches the file and writes it to the work directory and the
+// output file. It returns the path of the file written to the work directory.
+func fetchAndSave(ctx context.Context, client *gitlab.Client, cfg *config.Config) (string, error) {
+	content, err := fetchContent(ctx, client, cfg)
+	if err != nil {
+		return "", err
 	}
-	fmt.Printf("✅ File fetched successfully (%d bytes)\n", len(content))
 
 	// Step 4: Save to work directory
-	defaultFileName := "fetched_file.yaml"
-	workFilePath := filepath.Join(fetchWorkDir, defaultFileName)
+	workFilePath := filepath.Join(workDir, latestFileName(cfg.Environment))
 	if err := ui.RunSpinner("Saving file...", func() error {
-		return os.WriteFile(workFilePath, content, 0644)
+		return fileutil.WriteFileAtomic(workFilePath, content, 0644, false)
 	}); err != nil {
-		return fmt.Errorf("failed to write file to work directory: %w", err)
+		return "", fmt.Errorf("failed to write file to work directory: %w", err)
 	}
-	fmt.Printf("💾 File saved successfully: %s\n", workFilePath)
+	fetchStatus("💾 File saved successfully: %s\n", workFilePath)
 
+	entry, err := history.NewStore(dataRoot(workDir)).Record(history.Entry{
+		URL:         cfg.GitLabURL,
+		Repo:        cfg.RepositoryPath,
+		Ref:         cfg.Branch,
+		File:        cfg.FilePath,
+		Environment: cfg.Environment,
+	}, content)
+	if err != nil {
+		ui.Printf("⚠️  Warning: failed to record fetch history: %v\n", err)
+	} else {
+		fetchStatus("🗂️  Recorded in history: %s\n", entry.ID)
+	}
+
+	if applyAs != "" {
+		if err := applyToCluster(ctx, cfg, content); err != nil {
+			return "", err
+		}
+	}
+
+	outputs := []string{workFilePath}
 	if outputFile != "" {
 		// If a specific output file is specified, also write there and show content
 		if outputFile != workFilePath {
-			if err := os.WriteFile(outputFile, content, 0644); err != nil {
-				return fmt.Errorf("failed to write output file: %w", err)
+			if err := fileutil.WriteFileAtomic(outputFile, content, 0644, !noBackup); err != nil {
+				return "", fmt.Errorf("failed to write output file: %w", err)
+			}
+			fetchStatus("💾 File also saved to: %s\n", outputFile)
+			outputs = append(outputs, outputFile)
+		}
+		// Show content on stdout when --output is specified, except in watch
+		// mode where it would be repeated on every change
+		if !watchMode {
+			if err := displayContent(content); err != nil {
+				return "", err
 			}
-			fmt.Printf("💾 File also saved to: %s\n", outputFile)
 		}
-		// Show content on stdout when --output is specified
-		fmt.Println(string(content))
 	}
 	// If no --output is specified, don't show content on stdout
 
+	recordArtifacts(workDir, "fetch", fetchInputs(cfg), outputs...)
+
+	return workFilePath, nil
+}
+
+// applyToCluster creates or updates the --apply-as ConfigMap or Secret with
+// the fetched content
+func applyToCluster(ctx context.Context, cfg *config.Config, content []byte) error {
+	kubeClient, ref, key, err := kubeTarget(applyAs, cfg)
+	if err != nil {
+		return err
+	}
+
+	source := fmt.Sprintf("%s@%s:%s", cfg.RepositoryPath, cfg.Branch, cfg.FilePath)
+	var created bool
+	if err := fetchStep(fmt.Sprintf("Applying %s...", ref), func() error {
+		var err error
+		created, err = kubeClient.Apply(ctx, ref, key, content, source)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	action := "updated"
+	if created {
+		action = "created"
+	}
+	fetchStatus("☸️  %s %s in namespace %s (key %s)\n", ref, action, kubeClient.Namespace(ref), key)
+	auditAction(audit.ActionApply, kubeClient.Namespace(ref)+"/"+ref.String(), "", map[string]string{"key": key, "source": source, "result": action})
+	return nil
+}
+
+// compareWithCluster diffs the fetched file against the data held by the
+// --compare-with object and returns an error when they differ, after
+// alerting the channels
+func compareWithCluster(ctx context.Context, client *gitlab.Client, cfg *config.Config, channels []notifyChannel) error {
+	content, err := fetchContent(ctx, client, cfg)
+	if err != nil {
+		return err
+	}
+
+	kubeClient, ref, key, err := kubeTarget(compareWith, cfg)
+	if err != nil {
+		return err
+	}
+	namespace := kubeClient.Namespace(ref)
+
+	var live []byte
+	if err := ui.RunSpinner(fmt.Sprintf("Reading %s...", ref), func() error {
+		var err error
+		live, err = kubeClient.Data(ctx, ref, key)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	if bytes.Equal(live, content) {
+		ui.Printf("✅ No drift: %s in namespace %s matches %s\n", ref, namespace, cfg.FilePath)
+		return nil
+	}
+
+	// The diff is shown to a human, so sensitive values are redacted on both
+	// sides unless --show-secrets is set
+	shownLive, shownContent := live, content
+	if !showSecrets {
+		redactor, err := redact.New(redactPatterns)
+		if err != nil {
+			return err
+		}
+		shownLive, _ = redactor.Redact(live)
+		shownContent, _ = redactor.Redact(content)
+	}
+
+	liveName := fmt.Sprintf("%s/%s (key %s)", namespace, ref, key)
+	remoteName := fmt.Sprintf("%s@%s:%s", cfg.RepositoryPath, cfg.Branch, cfg.FilePath)
+	if unified := diff.Unified(liveName, remoteName, shownLive, shownContent, 3); unified != "" {
+		fmt.Print(unified)
+	} else {
+		ui.Println("🙈 Only redacted values differ (use --show-secrets to display them)")
+	}
+
+	if len(channels) > 0 {
+		sendNotification(ctx, channels, notify.Message{
+			Title: fmt.Sprintf("Drift detected: %s in namespace %s differs from %s", ref, namespace, cfg.FilePath),
+			Text:  fmt.Sprintf("%s@%s:%s no longer matches the live %s (key %s).", cfg.RepositoryPath, cfg.Branch, cfg.FilePath, ref, key),
+		})
+	}
+	return fmt.Errorf("drift detected: %s in namespace %s differs from %s", ref, namespace, cfg.FilePath)
+}
+
+// kubeTarget parses an object reference given on the command line and
+// returns it with the Kubernetes client and data key to use
+func kubeTarget(objectRef string, cfg *config.Config) (*kube.Client, kube.ObjectRef, string, error) {
+	ref, err := kube.ParseObjectRef(objectRef)
+	if err != nil {
+		return nil, kube.ObjectRef{}, "", err
+	}
+	ref.Namespace = kubeNamespace
+
+	key := applyKey
+	if key == "" {
+		key = path.Base(cfg.FilePath)
+	}
+
+	kubeClient, err := kube.NewClient(kubeconfigPath, kubeContext)
+	if err != nil {
+		return nil, kube.ObjectRef{}, "", err
+	}
+	return kubeClient, ref, key, nil
+}
+
+// watchFile polls the remote file metadata and fetches the file again every
+// time its last commit or content digest changes, alerting the channels
+func watchFile(ctx context.Context, client *gitlab.Client, cfg *config.Config, channels []notifyChannel) error {
+	var lastVersion string
+
+	ui.Printf("👀 Watching %s@%s:%s every %s (Ctrl+C to stop)\n", cfg.RepositoryPath, cfg.Branch, cfg.FilePath, watchInterval)
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	for {
+		metadata, err := client.GetFileMetadata(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				ui.Printf("⚠️  Warning: failed to check remote file: %v\n", err)
+			}
+		} else {
+			version := metadata.LastCommitID + ":" + metadata.SHA256
+			if version != lastVersion {
+				firstFetch := lastVersion == ""
+				if !firstFetch {
+					ui.Printf("🔄 Remote file changed (commit %s)\n", shortSHA(metadata.LastCommitID))
+				}
+
+				savedPath, err := fetchLocked(ctx, client, cfg)
+				if err != nil {
+					ui.Printf("⚠️  Warning: %v\n", err)
+				} else {
+					lastVersion = version
+					if !firstFetch && len(channels) > 0 {
+						sendNotification(ctx, channels, notify.Message{
+							Title: fmt.Sprintf("%s changed in %s@%s", cfg.FilePath, cfg.RepositoryPath, cfg.Branch),
+							Text:  fmt.Sprintf("Fetched again at commit %s.", shortSHA(metadata.LastCommitID)),
+						})
+					}
+					if !firstFetch && onChangeHook != "" {
+						if err := runChangeHook(ctx, savedPath); err != nil {
+							ui.Printf("⚠️  Warning: on-change hook failed: %v\n", err)
+						} else {
+							ui.Printf("🪝 On-change hook executed successfully\n")
+						}
+					}
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			ui.Println("\n👋 Stopped watching")
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// fetchLocked fetches and saves the file with the work directory locked, for
+// the fetches of --watch
+func fetchLocked(ctx context.Context, client *gitlab.Client, cfg *config.Config) (string, error) {
+	unlock, err := lockWorkDir(workDir)
+	if err != nil {
+		return "", err
+	}
+	defer unlock()
+	return fetchAndSave(ctx, client, cfg)
+}
+
+// runChangeHook executes the --on-change command through the system shell.
+// The path of the updated file is exposed as DRIVIO_FILE.
+func runChangeHook(ctx context.Context, savedPath string) error {
+	var hook *exec.Cmd
+	if runtime.GOOS == "windows" {
+		hook = exec.CommandContext(ctx, "cmd", "/C", onChangeHook)
+	} else {
+		hook = exec.CommandContext(ctx, "sh", "-c", onChangeHook)
+	}
+
+	changedFile := savedPath
+	if outputFile != "" {
+		changedFile = outputFile
+	}
+
+	hook.Env = append(os.Environ(), "DRIVIO_FILE="+changedFile)
+	hook.Stdout = os.Stdout
+	hook.Stderr = os.Stderr
+	return hook.Run()
+}
+
+// shortSHA returns the abbreviated form of a commit SHA
+func shortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
+
+// shortChecksum returns the abbreviated form of a content checksum, which
+// can be short or empty in a hand-edited history index
+func shortChecksum(sum string) string {
+	if len(sum) > 12 {
+		return sum[:12]
+	}
+	return sum
+}
+
+// printFetchHistory lists the fetch history of the work directory, filtered
+// by --repo and --file when given
+func printFetchHistory() error {
+	entries, err := history.NewStore(dataRoot(workDir)).List()
+	if err != nil {
+		return err
+	}
+
+	var filtered []history.Entry
+	for _, entry := range entries {
+		if repositoryPath != "" && entry.Repo != repositoryPath {
+			continue
+		}
+		if filePath != "" && entry.File != filePath {
+			continue
+		}
+		if environment != "" && entry.Environment != environment {
+			continue
+		}
+		filtered = append(filtered, entry)
+	}
+
+	if len(filtered) == 0 {
+		ui.Printf("📁 No fetch history found in: %s\n", workDir)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tFETCHED\tENV\tREPO\tREF\tFILE\tSHA256\tSIZE")
+	for _, entry := range filtered {
+		env := entry.Environment
+		if env == "" {
+			env = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d\n",
+			entry.ID,
+			entry.FetchedAt.Local().Format("2006-01-02 15:04:05"),
+			env,
+			entry.Repo,
+			entry.Ref,
+			entry.File,
+			shortChecksum(entry.SHA256),
+			entry.Size)
+	}
+	return w.Flush()
+}
+
+// restoreFromHistory writes a previously fetched version back to the output
+// file, or to the work directory when no --output is given
+func restoreFromHistory(id string) error {
+	entry, content, err := history.NewStore(dataRoot(workDir)).Find(id)
+	if err != nil {
+		return err
+	}
+
+	target := outputFile
+	if target == "" {
+		target = filepath.Join(workDir, latestFileName(entry.Environment))
+	}
+
+	if err := fileutil.WriteFileAtomic(target, content, 0644, !noBackup); err != nil {
+		return fmt.Errorf("failed to restore file: %w", err)
+	}
+
+	ui.Printf("⏪ Restored %s@%s:%s fetched at %s (sha256:%s)\n",
+		entry.Repo, entry.Ref, entry.File,
+		entry.FetchedAt.Local().Format("2006-01-02 15:04:05"),
+		shortChecksum(entry.SHA256))
+	ui.Printf("💾 File restored to: %s\n", target)
+
+	recordArtifacts(workDir, "fetch --restore", map[string]string{
+		"restore": entry.ID,
+		"repo":    entry.Repo,
+		"ref":     entry.Ref,
+		"file":    entry.File,
+		"env":     entry.Environment,
+	}, target)
 	return nil
 }
+
+// fetchInputs returns the source of a fetch as recorded in the artifact
+// index
+func fetchInputs(cfg *config.Config) map[string]string {
+	return map[string]string{
+		"url":  cfg.GitLabURL,
+		"repo": cfg.RepositoryPath,
+		"ref":  cfg.Branch,
+		"file": cfg.FilePath,
+		"env":  cfg.Environment,
+	}
+}
+
+// latestFileName returns the name of the file in the work directory holding
+// the latest fetched version, one per environment
+func latestFileName(env string) string {
+	if env == "" {
+		return "fetched_file.yaml"
+	}
+	return fmt.Sprintf("fetched_file.%s.yaml", env)
+}
@@ -0,0 +1,366 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"drivio/pkg/config"
+	"drivio/pkg/github"
+	"drivio/pkg/gitlab"
+	"drivio/pkg/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	freezeProvider        string
+	freezeOwner           string
+	freezeRepo            string
+	freezeBranch          string
+	freezeSince           string
+	freezeUntil           string
+	freezeExceptionLabels []string
+	freezeGitHubToken     string
+	freezeGitLabURL       string
+	freezeGitLabToken     string
+	freezeInstance        string
+	freezeFormat          string
+	freezeExitCode        bool
+)
+
+// freezeReportCmd represents the freeze-report command
+var freezeReportCmd = &cobra.Command{
+	Use:   "freeze-report",
+	Short: "List the changes merged during a change freeze",
+	Long: `List the pull or merge requests merged into a branch during a change freeze,
+from --since to --until, with their labels and approvers, for the release
+managers reviewing the freeze. The changes lacking an exception label,
+freeze-exception unless --exception-label names them, are flagged.
+
+The dates are dates, times or ages (2w); an --until date includes the
+whole day. Repositories are read from GitHub with --owner and --repo, or
+from GitLab with --provider gitlab and --repo GROUP/PROJECT, its URL and
+token coming from the flags, the environment or the config file like for
+fetch.
+
+Approvers are the reviewers whose latest review approves a pull request on
+GitHub, and the users who approved a merge request on GitLab: each change is
+looked up. --exit-code fails when a change lacks an exception label, e.g.
+in the pipeline closing the freeze.
+
+Examples:
+  drivio freeze-report --owner myorg --repo myrepo --since 2024-12-20 --until 2025-01-06
+  drivio freeze-report --owner myorg --repo myrepo --branch release-4.14 --since 2024-12-20 --until 2025-01-06 --format markdown > freeze.md
+  drivio freeze-report --provider gitlab --repo mygroup/myproject --since 2w --until 1d --exception-label hotfix --exit-code`,
+	Args: cobra.NoArgs,
+	RunE: runFreezeReport,
+}
+
+func init() {
+	rootCmd.AddCommand(freezeReportCmd)
+
+	// Add flags
+	freezeReportCmd.Flags().StringVar(&freezeProvider, "provider", "github", "Provider of the repository: github or gitlab")
+	freezeReportCmd.Flags().StringVar(&freezeOwner, "owner", "", "GitHub repository owner/organization")
+	freezeReportCmd.Flags().StringVar(&freezeRepo, "repo", "", "GitHub repository name, or GitLab project path")
+	freezeReportCmd.Flags().StringVar(&freezeBranch, "branch", "main", "Branch frozen")
+	freezeReportCmd.Flags().StringVar(&freezeSince, "since", "", "Start of the freeze: date, time or age")
+	freezeReportCmd.Flags().StringVar(&freezeUntil, "until", "", "End of the freeze: date, time or age")
+	freezeReportCmd.Flags().StringSliceVar(&freezeExceptionLabels, "exception-label", []string{"freeze-exception"}, "Label allowing a change during the freeze, case insensitive (repeatable)")
+	freezeReportCmd.Flags().StringVar(&freezeGitHubToken, "github-token", "", "GitHub token for authentication (optional)")
+	freezeReportCmd.Flags().StringVar(&freezeGitLabURL, "url", "", "GitLab URL, with --provider gitlab")
+	freezeReportCmd.Flags().StringVar(&freezeGitLabToken, "token", "", "GitLab access token, with --provider gitlab")
+	freezeReportCmd.Flags().StringVar(&freezeInstance, "instance", "", "GitLab instance of the config file providing the URL and token")
+	freezeReportCmd.Flags().StringVar(&freezeFormat, "format", "table", "Output format: table, json or markdown")
+	freezeReportCmd.Flags().BoolVar(&freezeExitCode, "exit-code", false, "Exit with an error when a change lacks an exception label")
+
+	// Environment variables that take precedence over the config file
+	bindFlagEnv(freezeReportCmd.Flags(), "github-token", config.EnvGitHubToken...)
+	bindFlagEnv(freezeReportCmd.Flags(), "url", config.EnvGitLabURL...)
+	bindFlagEnv(freezeReportCmd.Flags(), "token", config.EnvGitLabToken...)
+	bindFlagEnv(freezeReportCmd.Flags(), "instance", config.EnvInstance...)
+
+	freezeReportCmd.MarkFlagRequired("since")
+	freezeReportCmd.MarkFlagRequired("until")
+}
+
+// freezeReport lists the changes merged during a change freeze
+type freezeReport struct {
+	Repository      string        `json:"repository"`
+	Branch          string        `json:"branch"`
+	Since           time.Time     `json:"since"`
+	Until           time.Time     `json:"until"`
+	ExceptionLabels []string      `json:"exception_labels"`
+	Changes         []freezeEntry `json:"changes"`
+	// MissingException counts the changes lacking an exception label
+	MissingException int `json:"missing_exception"`
+}
+
+// freezeEntry is a pull or merge request merged during a freeze
+type freezeEntry struct {
+	// Reference is #NUMBER for pull requests and !IID for merge requests
+	Reference string    `json:"reference"`
+	Title     string    `json:"title"`
+	Author    string    `json:"author"`
+	MergedAt  time.Time `json:"merged_at"`
+	Labels    []string  `json:"labels"`
+	Approvers []string  `json:"approvers"`
+	URL       string    `json:"url"`
+	Exception bool      `json:"exception"`
+}
+
+func runFreezeReport(cmd *cobra.Command, args []string) error {
+	switch freezeFormat {
+	case "table", "json", "markdown":
+	default:
+		return fmt.Errorf("invalid --format %q: use table, json or markdown", freezeFormat)
+	}
+	now := time.Now()
+	since, err := parseNotesDate(freezeSince, now, false)
+	if err != nil {
+		return fmt.Errorf("invalid --since: %w", err)
+	}
+	until, err := parseNotesDate(freezeUntil, now, true)
+	if err != nil {
+		return fmt.Errorf("invalid --until: %w", err)
+	}
+	if until.Before(since) {
+		return fmt.Errorf("--until %s is before --since %s", freezeUntil, freezeSince)
+	}
+	if freezeBranch == "" {
+		return fmt.Errorf("--branch is required")
+	}
+
+	ctx := context.Background()
+	var collect func(progress chan<- ui.ProgressMsg) ([]freezeEntry, error)
+	var repository string
+	switch strings.ToLower(freezeProvider) {
+	case "github":
+		if freezeOwner == "" || freezeRepo == "" {
+			return fmt.Errorf("both --owner and --repo are required")
+		}
+		repository = freezeOwner + "/" + freezeRepo
+		client := github.NewClient(freezeGitHubToken)
+		cmd.SilenceUsage = true
+		collect = func(progress chan<- ui.ProgressMsg) ([]freezeEntry, error) {
+			return freezeGitHubChanges(ctx, client, since, until, progress)
+		}
+	case "gitlab":
+		cfg := config.LoadConfig()
+		if freezeGitLabURL != "" {
+			cfg.GitLabURL = freezeGitLabURL
+		}
+		if freezeGitLabToken != "" {
+			cfg.GitLabToken = freezeGitLabToken
+		}
+		if freezeRepo != "" {
+			cfg.RepositoryPath = freezeRepo
+		}
+		if cfg.RepositoryPath == "" {
+			return fmt.Errorf("--repo GROUP/PROJECT is required with --provider gitlab")
+		}
+		repository = cfg.RepositoryPath
+		cmd.SilenceUsage = true
+		client, err := newFetchClient(cfg)
+		if err != nil {
+			return err
+		}
+		collect = func(progress chan<- ui.ProgressMsg) ([]freezeEntry, error) {
+			return freezeGitLabChanges(ctx, client, since, until, progress)
+		}
+	default:
+		return fmt.Errorf("invalid --provider %q: use github or gitlab", freezeProvider)
+	}
+
+	report := &freezeReport{
+		Repository:      repository,
+		Branch:          freezeBranch,
+		Since:           since,
+		Until:           until,
+		ExceptionLabels: freezeExceptionLabels,
+	}
+	if err := ui.RunProgress(fmt.Sprintf("Listing the changes merged into %s during the freeze...", freezeBranch), ui.UnitItems, func(progress chan<- ui.ProgressMsg) error {
+		var err error
+		report.Changes, err = collect(progress)
+		return err
+	}); err != nil {
+		return err
+	}
+	for i := range report.Changes {
+		change := &report.Changes[i]
+		change.Exception = slices.ContainsFunc(change.Labels, func(label string) bool {
+			return slices.ContainsFunc(freezeExceptionLabels, func(exception string) bool {
+				return strings.EqualFold(label, exception)
+			})
+		})
+		if !change.Exception {
+			report.MissingException++
+		}
+	}
+
+	switch freezeFormat {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	case "markdown":
+		writeFreezeMarkdown(os.Stdout, report)
+	default:
+		writeFreezeTable(os.Stdout, report)
+	}
+
+	if freezeExitCode && report.MissingException > 0 {
+		return fmt.Errorf("%d of %d changes merged during the freeze lack an exception label", report.MissingException, len(report.Changes))
+	}
+	return nil
+}
+
+// freezeGitHubChanges lists the pull requests merged during the freeze,
+// looking up their approvers
+func freezeGitHubChanges(ctx context.Context, client *github.Client, since, until time.Time, progress chan<- ui.ProgressMsg) ([]freezeEntry, error) {
+	prs, err := client.MergedPullRequests(ctx, freezeOwner, freezeRepo, freezeBranch, since, until)
+	if err != nil {
+		return nil, err
+	}
+	changes := make([]freezeEntry, 0, len(prs))
+	for i, pr := range prs {
+		progress <- ui.ProgressMsg{Current: int64(i), Total: int64(len(prs)), Message: fmt.Sprintf("#%d", pr.Number)}
+		reviews, err := client.PullRequestReviews(ctx, freezeOwner, freezeRepo, pr.Number)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, freezeEntry{
+			Reference: fmt.Sprintf("#%d", pr.Number),
+			Title:     pr.Title,
+			Author:    pr.User.Login,
+			MergedAt:  *pr.MergedAt,
+			Labels:    pr.LabelNames(),
+			Approvers: github.Approvers(reviews),
+			URL:       pr.HTMLURL,
+		})
+	}
+	progress <- ui.ProgressMsg{Current: int64(len(prs)), Total: int64(len(prs))}
+	return changes, nil
+}
+
+// freezeGitLabChanges lists the merge requests merged during the freeze,
+// looking up their approvers
+func freezeGitLabChanges(ctx context.Context, client *gitlab.Client, since, until time.Time, progress chan<- ui.ProgressMsg) ([]freezeEntry, error) {
+	mrs, err := client.MergedMergeRequests(ctx, freezeBranch, since, until)
+	if err != nil {
+		return nil, err
+	}
+	changes := make([]freezeEntry, 0, len(mrs))
+	for i, mr := range mrs {
+		progress <- ui.ProgressMsg{Current: int64(i), Total: int64(len(mrs)), Message: fmt.Sprintf("!%d", mr.IID)}
+		approvers, err := client.MergeRequestApprovers(ctx, mr.IID)
+		if err != nil {
+			return nil, err
+		}
+		change := freezeEntry{
+			Reference: fmt.Sprintf("!%d", mr.IID),
+			Title:     mr.Title,
+			MergedAt:  *mr.MergedAt,
+			Labels:    []string(mr.Labels),
+			Approvers: approvers,
+			URL:       mr.WebURL,
+		}
+		if mr.Author != nil {
+			change.Author = mr.Author.Username
+		}
+		if change.Labels == nil {
+			change.Labels = []string{}
+		}
+		changes = append(changes, change)
+	}
+	progress <- ui.ProgressMsg{Current: int64(len(mrs)), Total: int64(len(mrs))}
+	return changes, nil
+}
+
+// freezeWindow describes the window of the freeze, e.g. "from 2024-12-20
+// 00:00 to 2025-01-06 23:59"
+func freezeWindow(report *freezeReport) string {
+	return fmt.Sprintf("from %s to %s", report.Since.Local().Format("2006-01-02 15:04"), report.Until.Local().Format("2006-01-02 15:04"))
+}
+
+// freezeSummary sums up the report, e.g. "12 changes merged, 2 without an
+// exception label"
+func freezeSummary(report *freezeReport) string {
+	summary := fmt.Sprintf("%d changes merged", len(report.Changes))
+	if report.MissingException > 0 {
+		summary += fmt.Sprintf(", %d without an exception label (%s)", report.MissingException, strings.Join(report.ExceptionLabels, ", "))
+	}
+	return summary
+}
+
+func writeFreezeTable(out io.Writer, report *freezeReport) {
+	fmt.Fprintf(out, "%s %s frozen %s\n\n", report.Repository, report.Branch, freezeWindow(report))
+	if len(report.Changes) > 0 {
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CHANGE\tMERGED\tAUTHOR\tTITLE\tLABELS\tAPPROVERS\tEXCEPTION")
+		for _, change := range report.Changes {
+			exception := "yes"
+			if !change.Exception {
+				exception = "MISSING"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				change.Reference,
+				change.MergedAt.Local().Format("2006-01-02 15:04"),
+				orDash(change.Author),
+				change.Title,
+				orDash(strings.Join(change.Labels, ", ")),
+				orDash(strings.Join(change.Approvers, ", ")),
+				exception)
+		}
+		w.Flush()
+		fmt.Fprintln(out)
+	}
+	if report.MissingException > 0 {
+		fmt.Fprintf(out, "⚠️  %s\n", freezeSummary(report))
+	} else {
+		fmt.Fprintf(out, "✅ %s\n", freezeSummary(report))
+	}
+}
+
+func writeFreezeMarkdown(out io.Writer, report *freezeReport) {
+	fmt.Fprintf(out, "# Changes merged into %s of %s during the freeze\n\n", report.Branch, report.Repository)
+	fmt.Fprintf(out, "Frozen %s: %s.\n", freezeWindow(report), freezeSummary(report))
+	if len(report.Changes) == 0 {
+		return
+	}
+	fmt.Fprint(out, "\n| Change | Merged | Author | Title | Labels | Approvers | Exception |\n")
+	fmt.Fprintln(out, "|---|---|---|---|---|---|---|")
+	for _, change := range report.Changes {
+		exception := "✅"
+		if !change.Exception {
+			exception = "⚠️ missing"
+		}
+		approvers := make([]string, 0, len(change.Approvers))
+		for _, approver := range change.Approvers {
+			approvers = append(approvers, "@"+approver)
+		}
+		author := "-"
+		if change.Author != "" {
+			author = "@" + change.Author
+		}
+		fmt.Fprintf(out, "| [%s](%s) | %s | %s | %s | %s | %s | %s |\n",
+			change.Reference,
+			change.URL,
+			change.MergedAt.Local().Format("2006-01-02 15:04"),
+			author,
+			strings.ReplaceAll(change.Title, "|", `\|`),
+			orDash(strings.Join(change.Labels, ", ")),
+			orDash(strings.Join(approvers, ", ")),
+			exception)
+	}
+}
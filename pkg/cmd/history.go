@@ -0,0 +1,380 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"drivio/pkg/diff"
+	"drivio/pkg/ui"
+	"drivio/pkg/verify"
+	"drivio/pkg/workdir"
+
+	"github.com/spf13/cobra"
+)
+
+// States of the notes of a run
+const (
+	runStatePresent     = "present"
+	runStateOverwritten = "overwritten"
+	runStateRemoved     = "removed"
+)
+
+var (
+	historyFormat string
+	historyRepo   string
+	historyLimit  int
+	historyEditor bool
+	historyPrint  bool
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "List the previous release notes generations",
+	Long: `List the previous runs of release-notes recorded in the artifact index of
+the work directory, newest first: repository, range, time, number of changes
+and notes file. Runs are numbered from 1, the latest, and are referred to by
+number or ID by the subcommands:
+
+  open    open the notes of a run, like the open command
+  rerun   run release-notes again with the parameters of a run
+  diff    compare the notes of two runs
+
+The notes of a run are overwritten by a later run of the same range, or
+removed by clean; their state says so.
+
+Examples:
+  drivio history
+  drivio history --repo openshift/hypershift --limit 5
+  drivio history open 2 --editor
+  drivio history rerun 1
+  drivio history diff 2 1`,
+	Args: cobra.NoArgs,
+	RunE: runHistory,
+}
+
+var historyOpenCmd = &cobra.Command{
+	Use:   "open RUN",
+	Short: "Open the notes of a previous run",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runHistoryOpen,
+}
+
+var historyRerunCmd = &cobra.Command{
+	Use:   "rerun RUN",
+	Short: "Run release-notes again with the parameters of a previous run",
+	Long: `Run release-notes again with the parameters of a previous run: repository,
+range or period, local clone and rendering. The notes are written to the
+work directory, replacing those of the run when the range is the same; the
+tokens come from the environment and the config file as usual.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runHistoryRerun,
+}
+
+var historyDiffCmd = &cobra.Command{
+	Use:   "diff RUN RUN",
+	Short: "Compare the notes of two previous runs",
+	Long: `Print a unified diff of the notes of two previous runs, e.g. of two ranges,
+or of the notes of a range before and after editing the classification
+rules, written to distinct --output files.`,
+	Args: cobra.ExactArgs(2),
+	RunE: runHistoryDiff,
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.AddCommand(historyOpenCmd)
+	historyCmd.AddCommand(historyRerunCmd)
+	historyCmd.AddCommand(historyDiffCmd)
+
+	// Add flags
+	historyCmd.Flags().StringVar(&historyFormat, "format", "table", "Output format: table or json")
+	historyCmd.Flags().StringVar(&historyRepo, "repo", "", "Only list the runs of this OWNER/REPO")
+	historyCmd.Flags().IntVar(&historyLimit, "limit", 0, "Only list this many runs (default: all)")
+	historyOpenCmd.Flags().BoolVar(&historyEditor, "editor", false, "Open the notes in $VISUAL or $EDITOR")
+	historyOpenCmd.Flags().BoolVar(&historyPrint, "print", false, "Print the path instead of opening it")
+}
+
+// notesRun is a previous run of release-notes
+type notesRun struct {
+	Number    int               `json:"number"`
+	ID        string            `json:"id"`
+	Repo      string            `json:"repo"`
+	Range     string            `json:"range"`
+	Entries   int               `json:"entries"`
+	Output    string            `json:"output,omitempty"`
+	State     string            `json:"state"`
+	Inputs    map[string]string `json:"inputs"`
+	CreatedAt time.Time         `json:"created_at"`
+
+	// path is the notes file on disk
+	path string
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	if historyFormat != "table" && historyFormat != "json" {
+		return fmt.Errorf("invalid --format %q: use table or json", historyFormat)
+	}
+	if historyLimit < 0 {
+		return fmt.Errorf("invalid --limit %d", historyLimit)
+	}
+
+	runs, err := notesRuns(workDir)
+	if err != nil {
+		return err
+	}
+	if historyRepo != "" {
+		var filtered []notesRun
+		for _, run := range runs {
+			if strings.EqualFold(run.Repo, historyRepo) {
+				filtered = append(filtered, run)
+			}
+		}
+		runs = filtered
+	}
+	if historyLimit > 0 && len(runs) > historyLimit {
+		runs = runs[:historyLimit]
+	}
+
+	if historyFormat == "json" {
+		if runs == nil {
+			runs = []notesRun{}
+		}
+		data, err := json.MarshalIndent(runs, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode runs: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(runs) == 0 {
+		ui.Printf("📭 No release-notes runs in: %s\n", workDir)
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tREPO\tRANGE\tCREATED\tCHANGES\tNOTES")
+	for _, run := range runs {
+		notes := orDash(run.Output)
+		if run.State != runStatePresent {
+			notes += " (" + run.State + ")"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%s\n",
+			run.Number,
+			run.Repo,
+			run.Range,
+			run.CreatedAt.Local().Format("2006-01-02 15:04:05"),
+			run.Entries,
+			notes,
+		)
+	}
+	w.Flush()
+	return nil
+}
+
+func runHistoryOpen(cmd *cobra.Command, args []string) error {
+	run, err := findNotesRun(workDir, args[0])
+	if err != nil {
+		return err
+	}
+	if err := run.available(); err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+	return openTarget(run.path, historyEditor, historyPrint)
+}
+
+func runHistoryRerun(cmd *cobra.Command, args []string) error {
+	run, err := findNotesRun(workDir, args[0])
+	if err != nil {
+		return err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the drivio executable: %w", err)
+	}
+	cmd.SilenceUsage = true
+
+	rerunArgs := rerunArguments(run.Inputs)
+	ui.Printf("🔁 Running drivio %s\n", strings.Join(rerunArgs, " "))
+	c := exec.Command(exe, append(forwardedFlags(cmd), rerunArgs...)...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := c.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return &exitStatusError{status: exitErr.ExitCode(), err: fmt.Errorf("release-notes failed")}
+		}
+		return fmt.Errorf("failed to run release-notes: %w", err)
+	}
+	return nil
+}
+
+func runHistoryDiff(cmd *cobra.Command, args []string) error {
+	var runs [2]*notesRun
+	var contents [2][]byte
+	for i, ref := range args {
+		run, err := findNotesRun(workDir, ref)
+		if err != nil {
+			return err
+		}
+		if err := run.available(); err != nil {
+			return err
+		}
+		content, err := os.ReadFile(run.path)
+		if err != nil {
+			return fmt.Errorf("failed to read the notes of run %d: %w", run.Number, err)
+		}
+		runs[i], contents[i] = run, content
+	}
+
+	name := func(run *notesRun) string {
+		return fmt.Sprintf("#%d %s %s (%s)", run.Number, run.Repo, run.Range, run.Output)
+	}
+	unified := diff.Unified(name(runs[0]), name(runs[1]), contents[0], contents[1], 3)
+	if unified == "" {
+		ui.Printf("✅ No differences between runs %d and %d\n", runs[0].Number, runs[1].Number)
+		return nil
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(unified, "\n"), "\n") {
+		if ui.IsTerminal() {
+			line = ui.HighlightDiffLine(line)
+		}
+		fmt.Println(line)
+	}
+	return nil
+}
+
+// notesRuns returns the runs of release-notes of the artifact index of the
+// work directory, newest first
+func notesRuns(dir string) ([]notesRun, error) {
+	index := workdir.NewIndex(dir)
+	records, err := index.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []notesRun
+	for i := len(records) - 1; i >= 0; i-- {
+		record := records[i]
+		if record.Command != "release-notes" {
+			continue
+		}
+		run := notesRun{
+			Number:    len(runs) + 1,
+			ID:        record.ID,
+			Repo:      record.Inputs["repo"],
+			Range:     notesRunRange(record.Inputs),
+			Entries:   record.Entries,
+			State:     runStateRemoved,
+			Inputs:    record.Inputs,
+			CreatedAt: record.CreatedAt,
+		}
+		// The notes of the work directory come first, then those of --output
+		for _, output := range record.Outputs {
+			state := outputState(index.Path(output), output.SHA256)
+			if run.Output == "" || (run.State != runStatePresent && state == runStatePresent) {
+				run.Output, run.State, run.path = output.Path, state, index.Path(output)
+			}
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// outputState tells whether a recorded output is still on disk as written
+func outputState(path, digest string) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return runStateRemoved
+	}
+	if digest != "" && verify.SHA256(content) != digest {
+		return runStateOverwritten
+	}
+	return runStatePresent
+}
+
+// notesRunRange describes the range, or period, of a run
+func notesRunRange(inputs map[string]string) string {
+	from := inputs["from"]
+	if from == "" {
+		from = "start"
+	}
+	notesRange := from + ".." + orDash(inputs["to"])
+
+	date := func(value string) string {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t.Local().Format("2006-01-02")
+		}
+		return value
+	}
+	switch since, until := inputs["since"], inputs["until"]; {
+	case since != "" && until != "":
+		notesRange += fmt.Sprintf(" (%s to %s)", date(since), date(until))
+	case since != "":
+		notesRange += fmt.Sprintf(" (since %s)", date(since))
+	case until != "":
+		notesRange += fmt.Sprintf(" (until %s)", date(until))
+	}
+	return notesRange
+}
+
+// findNotesRun returns the run of the given number, or ID or ID prefix
+func findNotesRun(dir, ref string) (*notesRun, error) {
+	runs, err := notesRuns(dir)
+	if err != nil {
+		return nil, err
+	}
+	if number, err := strconv.Atoi(ref); err == nil && number >= 1 && number <= len(runs) {
+		return &runs[number-1], nil
+	}
+
+	var match *notesRun
+	for i := range runs {
+		if strings.HasPrefix(runs[i].ID, ref) {
+			if match != nil {
+				return nil, fmt.Errorf("run %q is ambiguous", ref)
+			}
+			match = &runs[i]
+		}
+	}
+	if match == nil {
+		return nil, fmt.Errorf("run not found: %s (%d release-notes runs in %s)", ref, len(runs), dir)
+	}
+	return match, nil
+}
+
+// available fails when the notes of the run are no longer those it wrote
+func (r *notesRun) available() error {
+	switch r.State {
+	case runStateRemoved:
+		return fmt.Errorf("the notes of run %d were removed: use history rerun %d", r.Number, r.Number)
+	case runStateOverwritten:
+		return fmt.Errorf("the notes of run %d, %s, were overwritten by a later run", r.Number, r.Output)
+	}
+	return nil
+}
+
+// rerunArguments returns the release-notes arguments of the inputs of a run
+func rerunArguments(inputs map[string]string) []string {
+	owner, repo, _ := strings.Cut(inputs["repo"], "/")
+	args := []string{"release-notes", "--owner", owner, "--repo", repo}
+	for _, flag := range []string{"from", "to", "local", "since", "until", "range", "format", "max-per-section", "template"} {
+		if value := inputs[flag]; value != "" {
+			args = append(args, "--"+flag, value)
+		}
+	}
+	for _, flag := range []string{"verify-signatures", "sections", "summary", "compact"} {
+		if inputs[flag] == "true" {
+			args = append(args, "--"+flag)
+		}
+	}
+	if depth := inputs["ownership-depth"]; depth != "" {
+		args = append(args, "--ownership", "--ownership-depth", depth)
+	}
+	return args
+}
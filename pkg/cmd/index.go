@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"drivio/pkg/ui"
+	"drivio/pkg/workdir"
+)
+
+// recordArtifacts adds the outputs of a run to the artifact index of the
+// work directory. The index is a record, so failing to update it only warns.
+func recordArtifacts(workDir, command string, inputs map[string]string, paths ...string) {
+	recordNotes(workDir, command, inputs, 0, paths...)
+}
+
+// recordNotes is recordArtifacts for release notes of the given number of
+// changes, listed by the history command
+func recordNotes(workDir, command string, inputs map[string]string, entries int, paths ...string) {
+	record, err := workdir.NewIndex(workDir).AddNotes(command, inputs, entries, paths...)
+	if err != nil {
+		ui.Printf("⚠️  Warning: failed to update the artifact index: %v\n", err)
+		return
+	}
+	ui.Debugf(1, "Recorded %d outputs of %s in the artifact index (%s)", len(record.Outputs), command, record.ID)
+}
+
+// pruneArtifactIndex drops the outputs removed from disk from the artifact
+// index of the work directory
+func pruneArtifactIndex(workDir string) {
+	pruned, err := workdir.NewIndex(workDir).Prune()
+	if err != nil {
+		ui.Printf("⚠️  Warning: failed to update the artifact index: %v\n", err)
+		return
+	}
+	if pruned > 0 {
+		ui.Debugf(1, "Dropped %d removed outputs from the artifact index", pruned)
+	}
+}
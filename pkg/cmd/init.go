@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"drivio/pkg/config"
+	"drivio/pkg/github"
+	"drivio/pkg/gitlab"
+	"drivio/pkg/ui"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// initCmd represents the init command
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Interactively create a configuration profile",
+	Long: `Interactively set up drivio: ask for the GitLab URL and token, the GitHub
+token, the default repository and the work directory, validate the tokens
+against the live APIs, and write them as a profile of the configuration file.
+
+Leave an answer empty to keep the default shown in brackets, or to skip an
+optional setting.
+
+Examples:
+  drivio init
+  drivio init --profile prod-gitlab
+  drivio init --config ./drivio.yaml`,
+	Args: cobra.NoArgs,
+	// The file is being written, so it is not applied to the flags
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return prepareCommand(cmd, false)
+	},
+	RunE: runInit,
+}
+
+func init() {
+	rootCmd.AddCommand(initCmd)
+}
+
+// initPrompter reads the answers of the init wizard
+type initPrompter struct {
+	in *bufio.Reader
+}
+
+// ask prints a question and returns the answer, or def when it is empty
+func (p *initPrompter) ask(question, def string) (string, error) {
+	if def != "" {
+		ui.Printf("❓ %s [%s]: ", question, def)
+	} else {
+		ui.Printf("❓ %s: ", question)
+	}
+
+	line, err := p.in.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		fmt.Println()
+		return "", fmt.Errorf("no answer: %w", err)
+	}
+
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer, nil
+	}
+	return def, nil
+}
+
+// askSecret asks for a secret without echoing it when stdin is a terminal
+func (p *initPrompter) askSecret(question string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return p.ask(question, "")
+	}
+
+	ui.Printf("❓ %s: ", question)
+	secret, err := term.ReadPassword(fd)
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("failed to read answer: %w", err)
+	}
+	return strings.TrimSpace(string(secret)), nil
+}
+
+// confirm asks a yes/no question
+func (p *initPrompter) confirm(question string, def bool) (bool, error) {
+	hint := "y/N"
+	if def {
+		hint = "Y/n"
+	}
+	answer, err := p.ask(fmt.Sprintf("%s (%s)", question, hint), "")
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToLower(answer) {
+	case "":
+		return def, nil
+	case "y", "yes":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// askValidated asks for a secret until it passes validate or is left empty
+func (p *initPrompter) askValidated(question string, validate func(string) error) (string, error) {
+	for {
+		secret, err := p.askSecret(question)
+		if err != nil || secret == "" {
+			return secret, err
+		}
+		if err := validate(secret); err != nil {
+			ui.Printf("❌ %v\n", err)
+			continue
+		}
+		return secret, nil
+	}
+}
+
+func runInit(cmd *cobra.Command, args []string) error {
+	fileConfig, err := config.LoadConfigFile(cfgFile, "")
+	if err != nil {
+		return err
+	}
+	if fileConfig.Path() == "" {
+		return fmt.Errorf("cannot locate the home directory; use --config to choose the configuration file")
+	}
+
+	p := &initPrompter{in: bufio.NewReader(os.Stdin)}
+	ctx := context.Background()
+
+	ui.Printf("👋 Setting up drivio in %s\n\n", fileConfig.Path())
+
+	name := profile
+	if name == "" {
+		if name, err = p.ask("Profile name", "default"); err != nil {
+			return err
+		}
+	}
+	name = strings.ToLower(name)
+	if strings.ContainsAny(name, ". ") {
+		return fmt.Errorf("invalid profile name %q: dots and spaces are not allowed", name)
+	}
+
+	exists := false
+	for _, existing := range fileConfig.Profiles() {
+		exists = exists || existing == name
+	}
+	if exists {
+		overwrite, err := p.confirm(fmt.Sprintf("Profile %s already exists. Update it?", name), false)
+		if err != nil {
+			return err
+		}
+		if !overwrite {
+			ui.Println("❌ Setup cancelled")
+			return nil
+		}
+	}
+
+	// GitLab
+	cfg := config.LoadConfig()
+	if cfg.GitLabURL, err = p.ask("GitLab URL", cfg.GitLabURL); err != nil {
+		return err
+	}
+	cfg.GitLabURL = strings.TrimRight(cfg.GitLabURL, "/")
+
+	cfg.GitLabToken, err = p.askValidated("GitLab token (empty to skip)", func(token string) error {
+		check := *cfg
+		check.GitLabToken = token
+		return ui.RunSpinner("Validating GitLab token...", func() error {
+			client, err := gitlab.NewClient(&check)
+			if err != nil {
+				return err
+			}
+			return client.ValidateConnection(ctx)
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	for {
+		if cfg.RepositoryPath, err = p.ask("Default GitLab repository, owner/repo (empty to skip)", ""); err != nil {
+			return err
+		}
+		if cfg.RepositoryPath == "" {
+			break
+		}
+		cfg.Visibility = ""
+		var project string
+		err := ui.RunSpinner("Checking repository access...", func() error {
+			client, err := newFetchClient(cfg)
+			if err != nil {
+				return err
+			}
+			info, err := client.GetRepositoryInfo(ctx)
+			if err == nil {
+				project = info.Name
+			}
+			return err
+		})
+		if err == nil {
+			ui.Printf("✅ Repository found: %s\n", project)
+			break
+		}
+		ui.Printf("❌ %v\n", err)
+	}
+
+	// GitHub
+	var login string
+	githubToken, err := p.askValidated("GitHub token (empty to skip)", func(token string) error {
+		return ui.RunSpinner("Validating GitHub token...", func() error {
+			user, err := github.NewClient(token).CurrentUser(ctx)
+			if err == nil {
+				login = user.Login
+			}
+			return err
+		})
+	})
+	if err != nil {
+		return err
+	}
+	if login != "" {
+		ui.Printf("✅ Authenticated on GitHub as %s\n", login)
+	}
+
+	workDir, err := p.ask("Work directory", ".drivio-work")
+	if err != nil {
+		return err
+	}
+
+	makeCurrent, err := p.confirm(fmt.Sprintf("Use %s as the default profile?", name), fileConfig.Profile == "")
+	if err != nil {
+		return err
+	}
+
+	// Write the profile
+	settings := []struct{ key, value string }{
+		{"url", cfg.GitLabURL},
+		{"token", cfg.GitLabToken},
+		{"repo", cfg.RepositoryPath},
+		{"github-token", githubToken},
+		{"work-dir", workDir},
+	}
+	for _, setting := range settings {
+		if setting.value == "" {
+			continue
+		}
+		if err := config.SetFileValue(fileConfig.Path(), []string{config.ProfilesKey, name, setting.key}, setting.value); err != nil {
+			return err
+		}
+	}
+	if makeCurrent {
+		if err := config.SetFileValue(fileConfig.Path(), []string{config.CurrentProfileKey}, name); err != nil {
+			return err
+		}
+	}
+
+	ui.Printf("\n✅ Profile %s written to %s\n", name, fileConfig.Path())
+	if !makeCurrent {
+		ui.Printf("💡 Use it with --profile %s, or make it the default with: drivio config use-context %s\n", name, name)
+	}
+	return nil
+}
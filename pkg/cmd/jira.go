@@ -0,0 +1,300 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"drivio/pkg/audit"
+	"drivio/pkg/config"
+	"drivio/pkg/git"
+	"drivio/pkg/jira"
+	"drivio/pkg/plugin"
+	"drivio/pkg/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	jiraOwner       string
+	jiraRepo        string
+	jiraFrom        string
+	jiraTo          string
+	jiraStatus      string
+	jiraComment     string
+	jiraLocal       string
+	jiraGitHubToken string
+	jiraURL         string
+	jiraUser        string
+	jiraToken       string
+	jiraDryRun      bool
+	jiraPlugin      string
+)
+
+// jiraCmd represents the jira command
+var jiraCmd = &cobra.Command{
+	Use:   "jira",
+	Short: "Update the Jira tickets of a release",
+}
+
+// jiraTransitionCmd represents the jira transition command
+var jiraTransitionCmd = &cobra.Command{
+	Use:   "transition",
+	Short: "Move the tickets of a release to a status",
+	Long: `Collect the tickets of the changes between two references, the same ones as
+release-notes lists, and move them to a status in Jira, e.g. Released once
+the version is out.
+
+Tickets already in the status are left as they are. A ticket without a
+transition to the status from its current one is reported, with the
+statuses it can move to, and fails the command once the others are moved.
+--comment adds a comment to the tickets moved, e.g. naming the release.
+
+Jira is reached at --jira-url (default: https://issues.redhat.com) with
+--jira-token: a personal access token on Jira Data Center, or an API token
+with --jira-user, the email of its account, on Jira Cloud.
+
+--dry-run lists the tickets and the transitions they would go through,
+without moving any.
+
+--plugin moves the tickets in another ticket system, through the
+drivio-NAME plugin on PATH answering tickets.transition (see drivio plugin).
+
+Examples:
+  drivio jira transition --owner openshift --repo hypershift --from v0.1.59 --to v0.1.63 --status Released --dry-run
+  drivio jira transition --owner openshift --repo hypershift --from v0.1.59 --to v0.1.63 --status Released --comment "Released in v0.1.63"
+  drivio jira transition --owner myorg --repo myrepo --from v1.0.0 --to v1.1.0 --status Done --jira-url https://myorg.atlassian.net --jira-user me@myorg.com`,
+	RunE: runJiraTransition,
+}
+
+func init() {
+	rootCmd.AddCommand(jiraCmd)
+	jiraCmd.AddCommand(jiraTransitionCmd)
+
+	// Add flags
+	jiraTransitionCmd.Flags().StringVar(&jiraOwner, "owner", "", "GitHub repository owner/organization")
+	jiraTransitionCmd.Flags().StringVar(&jiraRepo, "repo", "", "GitHub repository name")
+	jiraTransitionCmd.Flags().StringVar(&jiraFrom, "from", "", "From reference (tag, commit, or branch)")
+	jiraTransitionCmd.Flags().StringVar(&jiraTo, "to", "", "To reference (tag, commit, or branch)")
+	jiraTransitionCmd.Flags().StringVar(&jiraStatus, "status", "", "Status to move the tickets to (e.g. Released)")
+	jiraTransitionCmd.Flags().StringVar(&jiraComment, "comment", "", "Comment to add to the tickets moved")
+	jiraTransitionCmd.Flags().StringVar(&jiraLocal, "local", "", "Read the commits from this local clone instead of the GitHub API")
+	jiraTransitionCmd.Flags().StringVar(&jiraGitHubToken, "github-token", "", "GitHub token for authentication (optional)")
+	jiraTransitionCmd.Flags().StringVar(&jiraURL, "jira-url", jira.DefaultURL, "Jira URL")
+	jiraTransitionCmd.Flags().StringVar(&jiraUser, "jira-user", "", "Email of the Jira Cloud account of --jira-token")
+	jiraTransitionCmd.Flags().StringVar(&jiraToken, "jira-token", "", "Jira personal access token, or API token with --jira-user")
+	jiraTransitionCmd.Flags().BoolVar(&jiraDryRun, "dry-run", false, "List the tickets and their transitions without moving any")
+	jiraTransitionCmd.Flags().StringVar(&jiraPlugin, "plugin", "", "Move the tickets through this ticket system plugin instead of Jira")
+
+	// Environment variables that take precedence over the config file
+	bindFlagEnv(jiraTransitionCmd.Flags(), "github-token", config.EnvGitHubToken...)
+	bindFlagEnv(jiraTransitionCmd.Flags(), "jira-url", config.EnvJiraURL...)
+	bindFlagEnv(jiraTransitionCmd.Flags(), "jira-user", config.EnvJiraUser...)
+	bindFlagEnv(jiraTransitionCmd.Flags(), "jira-token", config.EnvJiraToken...)
+
+	// Mark required flags
+	jiraTransitionCmd.MarkFlagRequired("owner")
+	jiraTransitionCmd.MarkFlagRequired("repo")
+	jiraTransitionCmd.MarkFlagRequired("from")
+	jiraTransitionCmd.MarkFlagRequired("to")
+	jiraTransitionCmd.MarkFlagRequired("status")
+}
+
+// ticketTransition is the outcome of moving a ticket
+type ticketTransition struct {
+	key  string
+	from string
+	// result describes what was, or would be, done
+	result string
+	err    error
+}
+
+func runJiraTransition(cmd *cobra.Command, args []string) error {
+	if jiraToken == "" && !jiraDryRun && jiraPlugin == "" {
+		return fmt.Errorf("Jira token is required to move tickets. Set JIRA_API_TOKEN environment variable or use --jira-token flag")
+	}
+	rules, err := classificationRules(activeFileConfig)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	var tracker *plugin.Plugin
+	if jiraPlugin != "" {
+		if tracker, err = plugin.Find(jiraPlugin); err != nil {
+			return err
+		}
+		if err := tracker.Require(ctx, plugin.CapabilityTickets); err != nil {
+			return err
+		}
+	}
+	tickets, err := releaseTickets(ctx, rules)
+	if err != nil {
+		return err
+	}
+	if len(tickets) == 0 {
+		ui.Printf("✅ No tickets between %s and %s\n", jiraFrom, jiraTo)
+		return nil
+	}
+	ui.Printf("🎫 %d tickets between %s and %s\n", len(tickets), jiraFrom, jiraTo)
+
+	client := jira.NewClient(jiraURL, jiraUser, jiraToken)
+	results := make([]ticketTransition, 0, len(tickets))
+	message := fmt.Sprintf("Moving %d tickets to %s...", len(tickets), jiraStatus)
+	if jiraDryRun {
+		message = fmt.Sprintf("Checking the transitions of %d tickets to %s...", len(tickets), jiraStatus)
+	}
+	if err := ui.RunProgress(message, ui.UnitItems, func(progress chan<- ui.ProgressMsg) error {
+		for i, key := range tickets {
+			if tracker != nil {
+				results = append(results, pluginTransition(ctx, tracker, key))
+			} else {
+				results = append(results, transitionTicket(ctx, client, key))
+			}
+			progress <- ui.ProgressMsg{Current: int64(i + 1), Total: int64(len(tickets)), Message: key}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	failed := 0
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TICKET\tSTATUS\tRESULT")
+	for _, result := range results {
+		if result.err != nil {
+			failed++
+			ui.Fprintf(w, "%s\t%s\t❌ %v\n", result.key, orDash(result.from), result.err)
+			continue
+		}
+		ui.Fprintf(w, "%s\t%s\t%s\n", result.key, result.from, result.result)
+	}
+	w.Flush()
+	fmt.Println()
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d tickets could not be moved to %s", failed, len(results), jiraStatus)
+	}
+	return nil
+}
+
+// releaseTickets returns the tickets of the changes between the references,
+// selected as for the release notes, in the order of the changes
+func releaseTickets(ctx context.Context, rules []git.TypeRule) ([]string, error) {
+	var commits []git.CommitInfo
+	var classifier *git.Classifier
+	if err := ui.RunSpinner("Getting commits between references...", func() error {
+		if jiraLocal != "" {
+			// Pull request labels are only known to GitHub, so any pull
+			// request naming a ticket is selected
+			analyzer, err := git.NewLocalAnalyzer(jiraLocal)
+			if err != nil {
+				return err
+			}
+			classifier = &git.Classifier{TicketPattern: git.DefaultTicketPattern}
+			commits, err = analyzer.Commits(ctx, jiraFrom, jiraTo)
+			return err
+		}
+		analyzer := git.NewAnalyzer(jiraGitHubToken)
+		classifier = git.NewClassifier(analyzer, jiraOwner, jiraRepo)
+		var err error
+		commits, err = analyzer.Commits(ctx, jiraOwner, jiraRepo, jiraFrom, jiraTo)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("failed to get commits: %w", err)
+	}
+	classifier.Rules = rules
+
+	var selected []git.CommitInfo
+	if err := ui.RunProgress("Finding the tickets of the changes...", ui.UnitItems, func(progress chan<- ui.ProgressMsg) error {
+		classifier.Progress = func(done, total int, message string) {
+			progress <- ui.ProgressMsg{Current: int64(done), Total: int64(total), Message: message}
+		}
+		selected = classifier.Classify(ctx, commits)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	var tickets []string
+	seen := make(map[string]bool)
+	for _, commit := range selected {
+		if commit.Ticket != "" && !seen[commit.Ticket] {
+			seen[commit.Ticket] = true
+			tickets = append(tickets, commit.Ticket)
+		}
+	}
+	return tickets, nil
+}
+
+// transitionTicket moves a ticket to --status, unless it is there already
+func transitionTicket(ctx context.Context, client *jira.Client, key string) ticketTransition {
+	result := ticketTransition{key: key}
+	issue, err := client.GetIssue(ctx, key)
+	if err != nil {
+		result.err = err
+		return result
+	}
+	result.from = issue.Status
+	if strings.EqualFold(issue.Status, jiraStatus) {
+		result.result = "⏭️  already " + issue.Status
+		return result
+	}
+
+	transitions, err := client.Transitions(ctx, key)
+	if err != nil {
+		result.err = err
+		return result
+	}
+	transition, ok := jira.FindTransition(transitions, jiraStatus)
+	if !ok {
+		var available []string
+		for _, t := range transitions {
+			available = append(available, t.To)
+		}
+		result.err = fmt.Errorf("no transition to %s (can move to: %s)", jiraStatus, orDash(strings.Join(available, ", ")))
+		return result
+	}
+
+	if jiraDryRun {
+		result.result = fmt.Sprintf("🔎 would move to %s (%s)", transition.To, transition.Name)
+		return result
+	}
+	if err := client.Transition(ctx, key, transition.ID, jiraComment); err != nil {
+		result.err = err
+		return result
+	}
+	result.result = "✅ moved to " + transition.To
+	auditAction(audit.ActionTransition, key, strings.TrimRight(jiraURL, "/")+"/browse/"+key, map[string]string{"from": result.from, "to": transition.To})
+	return result
+}
+
+// pluginTransition moves a ticket to --status through a ticket system
+// plugin, which leaves tickets in the status already as they are
+func pluginTransition(ctx context.Context, tracker *plugin.Plugin, key string) ticketTransition {
+	result := ticketTransition{key: key}
+	var moved plugin.TransitionResult
+	if err := tracker.Call(ctx, plugin.MethodTransition, plugin.TransitionParams{
+		Ticket:  key,
+		Status:  jiraStatus,
+		Comment: jiraComment,
+		DryRun:  jiraDryRun,
+	}, &moved); err != nil {
+		result.err = err
+		return result
+	}
+	result.from = moved.From
+	switch {
+	case moved.To == "":
+		result.result = "⏭️  already " + moved.From
+	case jiraDryRun:
+		result.result = "🔎 would move to " + moved.To
+	default:
+		result.result = "✅ moved to " + moved.To
+		auditAction(audit.ActionTransition, key, "", map[string]string{"from": moved.From, "to": moved.To, "plugin": tracker.Name})
+	}
+	return result
+}
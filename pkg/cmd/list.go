@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"drivio/pkg/history"
+	"drivio/pkg/ui"
+	"drivio/pkg/workdir"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	listFormat string
+	listOnly   []string
+)
+
+// listCmd represents the list command
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the artifacts of the work directory",
+	Long: `List what the work directory contains: fetched files, fetch history,
+archives, release assets, release notes and other artifacts, with their type,
+source repository and ref when known, size and creation time. The cache and
+data directories are listed too when they are separate (see --cache-dir,
+--data-dir and --xdg).
+
+Use it to review the work directory before running drivio clean; --only
+takes the same types as clean.
+
+Examples:
+  drivio list
+  drivio list --only history,archives
+  drivio list --format json`,
+	Args: cobra.NoArgs,
+	RunE: runList,
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+
+	listCmd.Flags().StringVar(&listFormat, "format", "table", "Output format: table or json")
+	listCmd.Flags().StringSliceVar(&listOnly, "only", nil, "Only list artifacts of these types: "+strings.Join(workdir.Types(), ", "))
+}
+
+func runList(cmd *cobra.Command, args []string) error {
+	if listFormat != "table" && listFormat != "json" {
+		return fmt.Errorf("invalid --format %q: use table or json", listFormat)
+	}
+	if err := workdir.CheckTypes(listOnly); err != nil {
+		return err
+	}
+
+	fetches, err := history.NewStore(dataRoot(workDir)).List()
+	if err != nil {
+		return err
+	}
+
+	var artifacts []workdir.Artifact
+	for _, dir := range managedDirs(workDir) {
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		found, err := workdir.Inventory(dir, fetches)
+		if err != nil {
+			return err
+		}
+		artifacts = append(artifacts, found...)
+	}
+	if err := workdir.NewIndex(workDir).Annotate(artifacts); err != nil {
+		return err
+	}
+
+	if len(listOnly) > 0 {
+		var filtered []workdir.Artifact
+		for _, artifact := range artifacts {
+			for _, t := range listOnly {
+				if strings.EqualFold(artifact.Type, t) {
+					filtered = append(filtered, artifact)
+					break
+				}
+			}
+		}
+		artifacts = filtered
+	}
+
+	if listFormat == "json" {
+		if artifacts == nil {
+			artifacts = []workdir.Artifact{}
+		}
+		data, err := json.MarshalIndent(artifacts, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode artifacts: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(artifacts) == 0 {
+		ui.Printf("📭 No artifacts in: %s\n", strings.Join(managedDirs(workDir), ", "))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tPATH\tREPO\tREF\tSIZE\tCREATED")
+	var total int64
+	for _, artifact := range artifacts {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			artifact.Type,
+			artifact.Path,
+			orDash(artifact.Repo),
+			orDash(artifact.Ref),
+			ui.FormatSize(artifact.Size),
+			artifact.Created.Local().Format("2006-01-02 15:04:05"),
+		)
+		total += artifact.Size
+	}
+	w.Flush()
+
+	ui.Printf("\n📦 %d artifacts, %s\n", len(artifacts), ui.FormatSize(total))
+	return nil
+}
+
+// orDash returns s, or "-" when it is empty
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
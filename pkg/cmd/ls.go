@@ -0,0 +1,303 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"drivio/pkg/config"
+	"drivio/pkg/github"
+	"drivio/pkg/ui"
+
+	"github.com/spf13/cobra"
+)
+
+// lsWorkers is the number of entries whose details are looked up concurrently
+const lsWorkers = 8
+
+var (
+	lsRepo           string
+	lsRef            string
+	lsProvider       string
+	lsRecursive      bool
+	lsGitLabURL      string
+	lsGitLabToken    string
+	lsGitLabInstance string
+	lsGitHubToken    string
+)
+
+// lsEntry is a file or directory of a remote repository
+type lsEntry struct {
+	Path string
+	Dir  bool
+	// Size is the size in bytes of a file, or -1 when unknown
+	Size int64
+
+	CommitID    string
+	CommitDate  time.Time
+	CommitTitle string
+	Author      string
+}
+
+// lsCmd represents the ls command
+var lsCmd = &cobra.Command{
+	Use:   "ls [path]",
+	Short: "List the files of a remote repository",
+	Long: `List the files and directories of a GitLab or GitHub repository at a given
+reference, with their sizes and the last commit that touched them.
+
+Use it to discover the right --file path for drivio fetch without opening the
+web UI.
+
+Examples:
+  drivio ls --repo jparrill/my-config
+  drivio ls --repo jparrill/my-config config --ref develop
+  drivio ls --repo jparrill/my-config config -r
+  drivio ls --provider github --repo openshift/hypershift api/hypershift/v1beta1`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runLs,
+}
+
+func init() {
+	rootCmd.AddCommand(lsCmd)
+
+	// Add flags
+	lsCmd.Flags().StringVar(&lsRepo, "repo", "", "Repository path (e.g., owner/repo)")
+	lsCmd.Flags().StringVar(&lsRef, "ref", "", "Branch, tag or commit to list (default: main on GitLab, the default branch on GitHub)")
+	lsCmd.Flags().StringVar(&lsProvider, "provider", "gitlab", "Repository provider: gitlab or github")
+	lsCmd.Flags().BoolVarP(&lsRecursive, "recursive", "r", false, "List subdirectories recursively")
+	lsCmd.Flags().StringVar(&lsGitLabURL, "url", "", "GitLab instance URL")
+	lsCmd.Flags().StringVar(&lsGitLabToken, "token", "", "GitLab personal access token")
+	lsCmd.Flags().StringVar(&lsGitLabInstance, "instance", "", "GitLab instance of the config file providing the URL and token")
+	lsCmd.Flags().StringVar(&lsGitHubToken, "github-token", "", "GitHub token for authentication (optional)")
+
+	// Environment variables that take precedence over the config file
+	bindFlagEnv(lsCmd.Flags(), "url", config.EnvGitLabURL...)
+	bindFlagEnv(lsCmd.Flags(), "token", config.EnvGitLabToken...)
+	bindFlagEnv(lsCmd.Flags(), "instance", config.EnvInstance...)
+	bindFlagEnv(lsCmd.Flags(), "github-token", config.EnvGitHubToken...)
+
+	// Mark required flags
+	lsCmd.MarkFlagRequired("repo")
+}
+
+func runLs(cmd *cobra.Command, args []string) error {
+	path := ""
+	if len(args) > 0 {
+		path = strings.Trim(args[0], "/")
+	}
+
+	ctx := context.Background()
+
+	var entries []*lsEntry
+	var ref string
+	if err := ui.RunSpinner(fmt.Sprintf("Listing %s...", lsRepo), func() error {
+		var err error
+		switch strings.ToLower(lsProvider) {
+		case "gitlab":
+			entries, ref, err = listGitLabTree(ctx, path)
+		case "github":
+			entries, ref, err = listGitHubTree(ctx, path)
+		default:
+			err = fmt.Errorf("unsupported provider: %s (use gitlab or github)", lsProvider)
+		}
+		return err
+	}); err != nil {
+		return fmt.Errorf("failed to list repository: %w", err)
+	}
+
+	if len(entries) == 0 {
+		ui.Printf("📭 No files found in %s@%s:/%s\n", lsRepo, ref, path)
+		return nil
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if !lsRecursive && entries[i].Dir != entries[j].Dir {
+			return entries[i].Dir
+		}
+		return entries[i].Path < entries[j].Path
+	})
+
+	ui.Printf("📂 %s@%s:/%s\n", lsRepo, ref, path)
+	printLsEntries(entries)
+	return nil
+}
+
+// listGitLabTree lists the entries of a GitLab repository and looks up the
+// size and last commit of each one
+func listGitLabTree(ctx context.Context, path string) ([]*lsEntry, string, error) {
+	cfg := config.LoadConfig()
+	if lsGitLabURL != "" {
+		cfg.GitLabURL = lsGitLabURL
+	}
+	if lsGitLabToken != "" {
+		cfg.GitLabToken = lsGitLabToken
+	}
+	if lsRef != "" {
+		cfg.Branch = lsRef
+	}
+	cfg.RepositoryPath = lsRepo
+
+	client, err := newFetchClient(cfg)
+	if err != nil {
+		return nil, "", err
+	}
+
+	nodes, err := client.ListTree(ctx, path, lsRecursive)
+	if err != nil {
+		return nil, "", err
+	}
+
+	entries := make([]*lsEntry, 0, len(nodes))
+	for _, node := range nodes {
+		entries = append(entries, &lsEntry{Path: node.Path, Dir: node.Type == "tree", Size: -1})
+	}
+
+	enrichLsEntries(entries, func(entry *lsEntry) {
+		if !entry.Dir {
+			if size, err := client.GetFileSize(ctx, entry.Path); err == nil {
+				entry.Size = size
+			}
+		}
+		if commit, err := client.GetLastCommit(ctx, entry.Path); err == nil {
+			entry.CommitID = commit.ID
+			entry.CommitTitle = commit.Title
+			entry.Author = commit.AuthorName
+			if commit.CommittedDate != nil {
+				entry.CommitDate = *commit.CommittedDate
+			}
+		}
+	})
+
+	return entries, cfg.Branch, nil
+}
+
+// listGitHubTree lists the entries of a GitHub repository and looks up the
+// last commit of each one
+func listGitHubTree(ctx context.Context, path string) ([]*lsEntry, string, error) {
+	parts := strings.SplitN(lsRepo, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, "", fmt.Errorf("invalid repository path: %s", lsRepo)
+	}
+	owner, repo := parts[0], parts[1]
+
+	token := lsGitHubToken
+	if token == "" {
+		token = config.GetEnv(config.EnvGitHubToken...)
+	}
+	client := github.NewClient(token)
+
+	var entries []*lsEntry
+	if lsRecursive {
+		tree, truncated, err := client.ListTree(ctx, owner, repo, lsRef)
+		if err != nil {
+			return nil, "", err
+		}
+		if truncated {
+			ui.Fprintf(os.Stderr, "\n⚠️  The repository is too large to be listed completely; narrow it down with a path\n")
+		}
+		for _, node := range tree {
+			if path != "" && !strings.HasPrefix(node.Path, path+"/") {
+				continue
+			}
+			entry := &lsEntry{Path: node.Path, Dir: node.Type == "tree", Size: node.Size}
+			if entry.Dir {
+				entry.Size = -1
+			}
+			entries = append(entries, entry)
+		}
+	} else {
+		contents, err := client.ListContents(ctx, owner, repo, lsRef, path)
+		if err != nil {
+			return nil, "", err
+		}
+		for _, content := range contents {
+			entry := &lsEntry{Path: content.Path, Dir: content.Type == "dir", Size: content.Size}
+			if entry.Dir || content.Type == "submodule" {
+				entry.Size = -1
+			}
+			entries = append(entries, entry)
+		}
+	}
+
+	enrichLsEntries(entries, func(entry *lsEntry) {
+		if commit, err := client.GetLastCommit(ctx, owner, repo, lsRef, entry.Path); err == nil {
+			entry.CommitID = commit.SHA
+			entry.CommitTitle = commit.Title()
+			entry.Author = commit.Commit.Author.Name
+			entry.CommitDate = commit.Commit.Author.Date
+		}
+	})
+
+	ref := lsRef
+	if ref == "" {
+		ref = "HEAD"
+	}
+	return entries, ref, nil
+}
+
+// enrichLsEntries runs lookup on every entry using a pool of workers.
+// Lookups that fail leave the entry details empty.
+func enrichLsEntries(entries []*lsEntry, lookup func(entry *lsEntry)) {
+	jobs := make(chan *lsEntry)
+	var wg sync.WaitGroup
+
+	for w := 0; w < min(lsWorkers, len(entries)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for entry := range jobs {
+				lookup(entry)
+			}
+		}()
+	}
+
+	for _, entry := range entries {
+		jobs <- entry
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// printLsEntries prints the entries as a table
+func printLsEntries(entries []*lsEntry) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PATH\tSIZE\tCOMMIT\tDATE\tAUTHOR\tMESSAGE")
+
+	for _, entry := range entries {
+		name := entry.Path
+		if entry.Dir {
+			name += "/"
+		}
+
+		size := "-"
+		if entry.Size >= 0 {
+			size = ui.FormatSize(entry.Size)
+		}
+
+		commit, date := "-", "-"
+		if entry.CommitID != "" {
+			commit = shortSHA(entry.CommitID)
+		}
+		if !entry.CommitDate.IsZero() {
+			date = entry.CommitDate.Format("2006-01-02")
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", name, size, commit, date, entry.Author, truncate(entry.CommitTitle, 60))
+	}
+	w.Flush()
+}
+
+// truncate shortens s to at most max characters
+func truncate(s string, limit int) string {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s
+	}
+	return string(runes[:limit-1]) + "…"
+}
@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"drivio/pkg/config"
+	"drivio/pkg/notify"
+	"drivio/pkg/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	notifyTitle string
+	notifyText  string
+	notifyFile  string
+	notifyURL   string
+)
+
+// notifyCmd represents the notify command
+var notifyCmd = &cobra.Command{
+	Use:   "notify [CHANNEL...]",
+	Short: "Send a message to notification channels",
+	Long: `Send a message to notification channels: Slack, Microsoft Teams, a generic
+webhook or email, e.g. to announce a deployment from a pipeline, or a Notion
+page, e.g. to publish release notes.
+
+Channels are declared in the notifications section of the configuration
+file, at the top level or in a profile, and named on the command line. Without
+channels, those of the notify key of the configuration file are used, the
+same as for the --notify flag of release, status and fetch:
+
+  notifications:
+    team-slack:
+      type: slack
+      url-env: SLACK_WEBHOOK_URL
+    releases-mail:
+      type: email
+      smtp: smtp.example.com:587
+      from: drivio@example.com
+      to: [team@example.com]
+      username: drivio
+      password-env: SMTP_PASSWORD
+    releases-notion:
+      type: notion
+      token-env: NOTION_TOKEN
+      database: 0123456789abcdef0123456789abcdef
+  notify: [team-slack]
+
+A channel can also be given as TYPE:URL for slack, teams and webhook, e.g.
+slack:https://hooks.slack.com/services/..., or as plugin:NAME for a plugin
+channel without settings.
+
+The text is given with --text, or read from --file (- for stdin), e.g.
+release notes.
+
+Examples:
+  drivio notify --title "Deploying v1.4.0 to production"
+  drivio notify team-slack releases-mail --title "v1.4.0 released" --file release-notes.md --url https://github.com/myorg/myrepo/releases/tag/v1.4.0
+  drivio notify teams:https://myorg.webhook.office.com/... --title "Maintenance window" --text "From 22:00 to 23:00 UTC"`,
+	RunE: runNotify,
+}
+
+func init() {
+	rootCmd.AddCommand(notifyCmd)
+
+	// Add flags
+	notifyCmd.Flags().StringVar(&notifyTitle, "title", "", "Title of the message")
+	notifyCmd.Flags().StringVar(&notifyText, "text", "", "Text of the message")
+	notifyCmd.Flags().StringVar(&notifyFile, "file", "", "Read the text of the message from this file (- for stdin)")
+	notifyCmd.Flags().StringVar(&notifyURL, "url", "", "URL the message links to")
+
+	// Mark required flags
+	notifyCmd.MarkFlagRequired("title")
+}
+
+func runNotify(cmd *cobra.Command, args []string) error {
+	if notifyText != "" && notifyFile != "" {
+		return fmt.Errorf("--text and --file are mutually exclusive")
+	}
+	if len(args) == 0 && activeFileConfig != nil {
+		args = activeFileConfig.GetStringSlice("notify")
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("no notification channel: name one, or set notify in the config file")
+	}
+	channels, err := notifyChannels(args)
+	if err != nil {
+		return err
+	}
+
+	text := notifyText
+	if notifyFile != "" {
+		var content []byte
+		if notifyFile == "-" {
+			content, err = io.ReadAll(os.Stdin)
+		} else {
+			content, err = os.ReadFile(notifyFile)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", notifyFile, err)
+		}
+		text = strings.TrimSpace(string(content))
+	}
+
+	msg := notify.Message{Title: notifyTitle, Text: text, URL: notifyURL}
+	if failed := sendNotification(context.Background(), channels, msg); failed > 0 {
+		return fmt.Errorf("%d of %d notifications failed", failed, len(channels))
+	}
+	ui.Printf("✅ Notified %d channel(s)\n", len(channels))
+	return nil
+}
+
+// notifyChannel is a channel named with --notify
+type notifyChannel struct {
+	name     string
+	notifier notify.Notifier
+}
+
+// notifyChannels returns the channels named by --notify values: channels of
+// the configuration file, or TYPE:URL for the webhook channels
+func notifyChannels(values []string) ([]notifyChannel, error) {
+	fileConfig := activeFileConfig
+	var channels []notifyChannel
+	for _, value := range values {
+		channel, name := adHocChannel(value)
+		if channel == nil {
+			if fileConfig == nil {
+				var err error
+				if fileConfig, err = config.LoadConfigFile(cfgFile, profile); err != nil {
+					return nil, err
+				}
+			}
+			var err error
+			if channel, err = fileConfig.Channel(value); err != nil {
+				return nil, err
+			}
+			name = channel.Name
+		}
+
+		notifier, err := notify.New(channel)
+		if err != nil {
+			return nil, err
+		}
+		channels = append(channels, notifyChannel{name: name, notifier: notifier})
+	}
+	return channels, nil
+}
+
+// adHocChannel parses a TYPE:URL or plugin:NAME channel, returning nil for
+// other values. Webhook URLs are secrets, so the channel is named after the
+// host.
+func adHocChannel(value string) (*config.Channel, string) {
+	kind, target, ok := strings.Cut(value, ":")
+	if !ok || strings.EqualFold(kind, notify.TypeEmail) || strings.EqualFold(kind, notify.TypeNotion) {
+		return nil, ""
+	}
+	if strings.EqualFold(kind, notify.TypePlugin) && target != "" {
+		return &config.Channel{Name: target, Type: notify.TypePlugin, Plugin: target}, "plugin " + target
+	}
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, ""
+	}
+	for _, known := range notify.Types {
+		if strings.EqualFold(kind, known) {
+			return &config.Channel{Name: value, Type: known, URL: target}, fmt.Sprintf("%s (%s)", known, u.Host)
+		}
+	}
+	return nil, ""
+}
+
+// sendNotification sends a message to the channels, reporting each failure,
+// and returns the number of channels that failed
+func sendNotification(ctx context.Context, channels []notifyChannel, msg notify.Message) int {
+	failed := 0
+	for _, channel := range channels {
+		if err := ui.RunSpinner(fmt.Sprintf("Notifying %s...", channel.name), func() error {
+			return channel.notifier.Notify(ctx, msg)
+		}); err != nil {
+			ui.Printf("⚠️  Notification to %s failed: %v\n", channel.name, err)
+			failed++
+		}
+	}
+	return failed
+}
+
+// notifyUnattended sends a message to the channels for the commands running
+// unattended, logging failures instead of showing spinners
+func notifyUnattended(ctx context.Context, channels []notifyChannel, msg notify.Message) {
+	for _, channel := range channels {
+		if err := channel.notifier.Notify(ctx, msg); err != nil {
+			daemonLog("⚠️  Notification to %s failed: %v\n", channel.name, err)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"drivio/pkg/ui"
+	"drivio/pkg/workdir"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	openOwner   string
+	openRepo    string
+	openFrom    string
+	openTo      string
+	openCompare bool
+	openRelease bool
+	openEditor  bool
+	openPrint   bool
+)
+
+// openCmd represents the open command
+var openCmd = &cobra.Command{
+	Use:   "open [FILE]",
+	Short: "Open the latest release notes, or their GitHub compare or release page",
+	Long: `Open release notes, or the GitHub pages of their changes, to speed up the
+review loop.
+
+By default, the latest notes generated in the work directory by
+release-notes, release, serve or payload-notes, or FILE, are opened with the
+default application of the system (xdg-open on Linux, open on macOS), or in
+$VISUAL or $EDITOR with --editor.
+
+--compare opens the GitHub page comparing --from to --to, and --release the
+GitHub release page of --to. The repository and references default to the
+ones of the latest notes. --print prints the path or URL instead of opening
+it, e.g. on a machine without a browser.
+
+Examples:
+  drivio open
+  drivio open --editor
+  drivio open .drivio-work/release-notes-openshift-hypershift-v0.1.59-v0.1.63.md
+  drivio open --compare
+  drivio open --compare --owner openshift --repo hypershift --from v0.1.59 --to v0.1.63
+  drivio open --release --owner openshift --repo hypershift --to v0.1.63 --print`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runOpen,
+}
+
+func init() {
+	rootCmd.AddCommand(openCmd)
+
+	// Add flags
+	openCmd.Flags().StringVar(&openOwner, "owner", "", "GitHub repository owner/organization (default: the one of the latest notes)")
+	openCmd.Flags().StringVar(&openRepo, "repo", "", "GitHub repository name (default: the one of the latest notes)")
+	openCmd.Flags().StringVar(&openFrom, "from", "", "Compare from this reference (default: the one of the latest notes)")
+	openCmd.Flags().StringVar(&openTo, "to", "", "Compare to, or open the release of, this reference (default: the one of the latest notes)")
+	openCmd.Flags().BoolVar(&openCompare, "compare", false, "Open the GitHub compare page of --from and --to")
+	openCmd.Flags().BoolVar(&openRelease, "release", false, "Open the GitHub release page of --to")
+	openCmd.Flags().BoolVar(&openEditor, "editor", false, "Open the notes in $VISUAL or $EDITOR")
+	openCmd.Flags().BoolVar(&openPrint, "print", false, "Print the path or URL instead of opening it")
+	openCmd.MarkFlagsMutuallyExclusive("compare", "release", "editor")
+}
+
+func runOpen(cmd *cobra.Command, args []string) error {
+	if (openCompare || openRelease) && len(args) > 0 {
+		return fmt.Errorf("FILE cannot be given with --compare or --release")
+	}
+	cmd.SilenceUsage = true
+
+	if openCompare || openRelease {
+		target, err := openPageURL()
+		if err != nil {
+			return err
+		}
+		return openTarget(target, false, openPrint)
+	}
+
+	var path string
+	if len(args) > 0 {
+		path = args[0]
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Errorf("failed to open notes: %w", err)
+		}
+	} else {
+		var err error
+		if path, _, err = latestNotes(workDir); err != nil {
+			return err
+		}
+	}
+	return openTarget(path, openEditor, openPrint)
+}
+
+// openPageURL returns the GitHub compare or release page of the flags,
+// completed by the inputs of the latest notes
+func openPageURL() (string, error) {
+	owner, repo, from, to := openOwner, openRepo, openFrom, openTo
+	if owner == "" || repo == "" || to == "" || (openCompare && from == "") {
+		_, record, err := latestNotes(workDir)
+		if err != nil {
+			return "", fmt.Errorf("give --owner, --repo, --from and --to, or generate the notes first: %w", err)
+		}
+		recordOwner, recordRepo, recordFrom, recordTo, err := notesRange(record)
+		if err != nil {
+			return "", err
+		}
+		if owner == "" && repo == "" {
+			owner, repo = recordOwner, recordRepo
+		}
+		if from == "" {
+			from = recordFrom
+		}
+		if to == "" {
+			to = recordTo
+		}
+	}
+	if owner == "" || repo == "" {
+		return "", fmt.Errorf("both --owner and --repo are required")
+	}
+
+	base := fmt.Sprintf("https://github.com/%s/%s", owner, repo)
+	if openRelease {
+		if to == "" {
+			return "", fmt.Errorf("--to is required to open a release page")
+		}
+		return base + "/releases/tag/" + url.PathEscape(to), nil
+	}
+	if from == "" || to == "" {
+		return "", fmt.Errorf("both --from and --to are required to open a compare page")
+	}
+	return base + "/compare/" + url.PathEscape(from) + "..." + url.PathEscape(to), nil
+}
+
+// notesRange returns the GitHub repository and references recorded for
+// notes in the artifact index
+func notesRange(record *workdir.Record) (owner, repo, from, to string, err error) {
+	inputs := record.Inputs
+	if inputs["provider"] != "" && inputs["provider"] != "github" {
+		return "", "", "", "", fmt.Errorf("the latest notes are of %s repository %s: give --owner and --repo of a GitHub repository", inputs["provider"], inputs["repo"])
+	}
+	owner, repo, ok := strings.Cut(inputs["repo"], "/")
+	if !ok {
+		return "", "", "", "", fmt.Errorf("the latest notes, of %s, are not of a GitHub repository: give --owner and --repo", record.Command)
+	}
+	to = inputs["to"]
+	if record.Command == "release" {
+		to = inputs["version"]
+	}
+	return owner, repo, inputs["from"], to, nil
+}
+
+// openTarget prints, or opens a path or URL with the application of the
+// system, or a path in the editor of the user
+func openTarget(target string, editor, print bool) error {
+	if print {
+		fmt.Println(target)
+		return nil
+	}
+
+	var command []string
+	switch {
+	case editor:
+		command = strings.Fields(os.Getenv("VISUAL"))
+		if len(command) == 0 {
+			command = strings.Fields(os.Getenv("EDITOR"))
+		}
+		if len(command) == 0 {
+			command = []string{"vi"}
+		}
+	case runtime.GOOS == "darwin":
+		command = []string{"open"}
+	case runtime.GOOS == "windows":
+		command = []string{"rundll32", "url.dll,FileProtocolHandler"}
+	default:
+		command = []string{"xdg-open"}
+	}
+
+	ui.Printf("🔗 Opening %s\n", target)
+	cmd := exec.Command(command[0], append(command[1:], target)...)
+	if editor {
+		// The editor takes over the terminal until it exits
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("editor %s failed: %w", command[0], err)
+		}
+		return nil
+	}
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to open %s with %s (use --print to print it instead): %w", target, command[0], err)
+	}
+	return nil
+}
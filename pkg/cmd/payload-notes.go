@@ -0,0 +1,393 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"drivio/pkg/config"
+	"drivio/pkg/diff"
+	"drivio/pkg/git"
+	"drivio/pkg/payload"
+	"drivio/pkg/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	payloadFrom        string
+	payloadTo          string
+	payloadArch        string
+	payloadPullSecret  string
+	payloadComponents  []string
+	payloadLabels      map[string]string
+	payloadFormat      string
+	payloadSections    bool
+	payloadOutput      string
+	payloadStdout      bool
+	payloadGitHubToken string
+)
+
+// payloadNotesCmd represents the payload-notes command
+var payloadNotesCmd = &cobra.Command{
+	Use:   "payload-notes",
+	Short: "Generate the release notes of the components changed between two OpenShift release payloads",
+	Long: `Read the metadata of two OpenShift (or HyperShift) release images, the payloads
+listing the image of each component with the repository and commit it was
+built from, and generate the release notes of every repository whose commit
+changed, aggregated in one document.
+
+--from and --to are release versions, e.g. 4.15.2, read from
+quay.io/openshift-release-dev/ocp-release for --arch, release image pull
+specs, or files: the output of oc adm release info -o json, or the
+release-manifests/image-references file of a release image. Release images
+are read through the registry API; --pull-secret gives the credentials of
+private registries, e.g. the pull secret of a cluster.
+
+Components built from the same commits of a repository, like an operator
+and its CLI, share their notes. The changes of each repository are selected
+as by release-notes: pull requests naming a ticket, with the label of the
+repository in --labels when it has one. By default, only the HyperShift
+changes need the area/hypershift-operator label, as in release-notes.
+
+Examples:
+  drivio payload-notes --from 4.15.1 --to 4.15.2
+  drivio payload-notes --from 4.15.1 --to 4.15.2 --component hypershift,cluster-version-operator
+  drivio payload-notes --from quay.io/openshift-release-dev/ocp-release:4.16.0-multi --to 4.16.1 --arch arm64 --pull-secret ~/pull-secret.json
+  drivio payload-notes --from 4.15.1.json --to 4.15.2.json --labels openshift/hypershift=area/hypershift-operator,openshift/installer=approved
+  drivio payload-notes --from 4.15.1 --to 4.15.2 --format json --stdout`,
+	Args: cobra.NoArgs,
+	RunE: runPayloadNotes,
+}
+
+func init() {
+	rootCmd.AddCommand(payloadNotesCmd)
+
+	payloadNotesCmd.Flags().StringVar(&payloadFrom, "from", "", "Release version, release image or release metadata file of the old payload")
+	payloadNotesCmd.Flags().StringVar(&payloadTo, "to", "", "Release version, release image or release metadata file of the new payload")
+	payloadNotesCmd.Flags().StringVar(&payloadArch, "arch", "amd64", "Architecture of the release images: amd64, arm64, ppc64le or s390x")
+	payloadNotesCmd.Flags().StringVar(&payloadPullSecret, "pull-secret", "", "Pull secret or container auth file with the credentials of the registries")
+	payloadNotesCmd.Flags().StringSliceVar(&payloadComponents, "component", nil, "Only note these components (e.g. hypershift) or repositories (e.g. openshift/hypershift)")
+	payloadNotesCmd.Flags().StringToStringVar(&payloadLabels, "labels", map[string]string{"openshift/hypershift": git.DefaultLabel}, "Label the pull requests of a repository need to be noted, as OWNER/REPO=LABEL")
+	payloadNotesCmd.Flags().StringVar(&payloadFormat, "format", string(git.FormatMarkdown), "Output format: markdown or json")
+	payloadNotesCmd.Flags().BoolVar(&payloadSections, "sections", false, "Group the changes of each repository by type (Features, Bug Fixes, ...)")
+	payloadNotesCmd.Flags().StringVar(&payloadOutput, "output", "", "Output file path (default: stdout)")
+	payloadNotesCmd.Flags().BoolVar(&payloadStdout, "stdout", false, "Show content on stdout")
+	payloadNotesCmd.Flags().StringVar(&payloadGitHubToken, "github-token", "", "GitHub token for authentication (optional)")
+
+	// Environment variables that take precedence over the config file
+	bindFlagEnv(payloadNotesCmd.Flags(), "github-token", config.EnvGitHubToken...)
+	bindFlagEnv(payloadNotesCmd.Flags(), "pull-secret", config.EnvRegistryAuthFile...)
+
+	// Mark required flags
+	payloadNotesCmd.MarkFlagRequired("from")
+	payloadNotesCmd.MarkFlagRequired("to")
+}
+
+// payloadChange is a repository whose commit changed between the payloads,
+// with its release notes when it is on GitHub and in both payloads
+type payloadChange struct {
+	payload.SourceChange
+	// Repo is the GitHub repository, owner/repo
+	Repo  string            `json:"repo,omitempty"`
+	Notes *git.ReleaseNotes `json:"notes,omitempty"`
+	// Error is why the notes could not be generated, e.g. a commit missing
+	// from the repository
+	Error string `json:"error,omitempty"`
+}
+
+// payloadNotes are the aggregated notes of two payloads
+type payloadNotes struct {
+	From    *payloadRelease `json:"from"`
+	To      *payloadRelease `json:"to"`
+	Changes []payloadChange `json:"changes"`
+}
+
+// payloadRelease identifies a payload in the notes
+type payloadRelease struct {
+	Version string `json:"version"`
+	Image   string `json:"image,omitempty"`
+}
+
+func runPayloadNotes(cmd *cobra.Command, args []string) error {
+	format := git.OutputFormat(strings.ToLower(payloadFormat))
+	if format != git.FormatMarkdown && format != git.FormatJSON {
+		return fmt.Errorf("invalid --format %q: use markdown or json", payloadFormat)
+	}
+	for repo := range payloadLabels {
+		if !strings.Contains(repo, "/") {
+			return fmt.Errorf("invalid --labels %q: use OWNER/REPO=LABEL", repo)
+		}
+	}
+
+	ctx := context.Background()
+	registry := payload.NewRegistry()
+	registry.Architecture = payloadArch
+	if payloadPullSecret != "" {
+		if err := registry.LoadAuthFile(payloadPullSecret); err != nil {
+			return err
+		}
+	}
+
+	// Step 1: Read the metadata of both payloads
+	releases := make([]*payload.Release, 2)
+	for i, side := range []string{payloadFrom, payloadTo} {
+		if err := ui.RunSpinner("Reading release "+side+"...", func() error {
+			var err error
+			releases[i], err = readRelease(ctx, registry, side)
+			return err
+		}); err != nil {
+			return err
+		}
+		ui.Printf("📦 Release %s: %d components\n", orDash(releases[i].Version), len(releases[i].Components))
+	}
+
+	var changes []payloadChange
+	for _, change := range payload.Compare(releases[0], releases[1]) {
+		c := payloadChange{SourceChange: change}
+		if owner, repo, ok := change.GitHubRepository(); ok {
+			c.Repo = owner + "/" + repo
+		}
+		if payloadSelected(c) {
+			changes = append(changes, c)
+		}
+	}
+	ui.Printf("✅ Found %d repositories with changes\n", len(changes))
+
+	// Step 2: Generate the notes of each repository
+	if payloadGitHubToken == "" {
+		ui.Println("⚠️  No GitHub token provided. Using unauthenticated requests (may hit rate limits)")
+	}
+	analyzer := git.NewAnalyzer(payloadGitHubToken)
+	if err := ui.RunProgress("Generating release notes...", ui.UnitItems, func(progress chan<- ui.ProgressMsg) error {
+		for i := range changes {
+			change := &changes[i]
+			progress <- ui.ProgressMsg{Current: int64(i), Total: int64(len(changes)), Message: orDash(change.Repo)}
+			if change.Repo == "" || change.Kind != diff.Changed {
+				continue
+			}
+			notes, err := repositoryNotes(ctx, analyzer, change)
+			if err != nil {
+				change.Error = err.Error()
+				ui.Debugf(1, "Failed to generate the notes of %s: %v", change.Repo, err)
+				continue
+			}
+			change.Notes = notes
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	for _, change := range changes {
+		if change.Error != "" {
+			ui.Printf("⚠️  No notes for %s: %s\n", change.Repo, change.Error)
+		}
+	}
+
+	notes := &payloadNotes{Changes: changes}
+	for i, side := range []**payloadRelease{&notes.From, &notes.To} {
+		*side = &payloadRelease{Version: releases[i].Version, Image: releases[i].Image}
+	}
+	output, err := formatPayloadNotes(notes, format)
+	if err != nil {
+		return err
+	}
+
+	// Save to work directory
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return fmt.Errorf("failed to create work directory: %w", err)
+	}
+	unlock, err := lockWorkDir(workDir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := enforceRetention(workDir, ui.Printf); err != nil {
+		return err
+	}
+
+	name := strings.NewReplacer("/", "-", ":", "-", "@", "-").Replace
+	workFilePath := filepath.Join(workDir, fmt.Sprintf("payload-notes-%s-%s%s", name(payloadName(releases[0], payloadFrom)), name(payloadName(releases[1], payloadTo)), notesExtension(format)))
+	if err := os.WriteFile(workFilePath, []byte(output), 0644); err != nil {
+		return fmt.Errorf("failed to write file to work directory: %w", err)
+	}
+	ui.Printf("💾 Release notes saved generated successfully: %s\n", workFilePath)
+	outputs := []string{workFilePath}
+
+	if payloadOutput != "" && payloadOutput != workFilePath {
+		if err := os.WriteFile(payloadOutput, []byte(output), 0644); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+		ui.Printf("💾 Release notes also saved to: %s\n", payloadOutput)
+		outputs = append(outputs, payloadOutput)
+	}
+
+	inputs := map[string]string{
+		"from": payloadFrom,
+		"to":   payloadTo,
+		"arch": payloadArch,
+	}
+	if len(payloadComponents) > 0 {
+		inputs["component"] = strings.Join(payloadComponents, ",")
+	}
+	recordArtifacts(workDir, "payload-notes", inputs, outputs...)
+
+	if payloadStdout {
+		fmt.Println(output)
+	}
+	return nil
+}
+
+// readRelease reads the metadata of a payload: a file when it exists, and a
+// release version or image otherwise
+func readRelease(ctx context.Context, registry *payload.Registry, release string) (*payload.Release, error) {
+	if info, err := os.Stat(release); err == nil && !info.IsDir() {
+		content, err := os.ReadFile(release)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read release metadata: %w", err)
+		}
+		return payload.Parse(content)
+	}
+	return registry.Release(ctx, payload.ReleaseImage(release, payloadArch))
+}
+
+// payloadName names a payload in the work directory file: its version, or
+// the argument selecting it
+func payloadName(release *payload.Release, arg string) string {
+	if release.Version != "" {
+		return release.Version
+	}
+	return strings.TrimSuffix(filepath.Base(arg), filepath.Ext(arg))
+}
+
+// payloadSelected reports whether --component selects a change, by the name
+// of one of its components or by its repository
+func payloadSelected(change payloadChange) bool {
+	if len(payloadComponents) == 0 {
+		return true
+	}
+	for _, selected := range payloadComponents {
+		if change.Repo != "" && strings.EqualFold(selected, change.Repo) || containsFold(change.Components, selected) {
+			return true
+		}
+	}
+	return false
+}
+
+// repositoryNotes generates the release notes of a repository between the
+// commits of the payloads, selecting the changes as release-notes does
+func repositoryNotes(ctx context.Context, analyzer *git.Analyzer, change *payloadChange) (*git.ReleaseNotes, error) {
+	owner, repo, _ := change.GitHubRepository()
+	commits, err := analyzer.Commits(ctx, owner, repo, change.From, change.To)
+	if err != nil {
+		return nil, err
+	}
+	classifier := git.NewClassifier(analyzer, owner, repo)
+	classifier.Label = ""
+	for labeled, label := range payloadLabels {
+		if strings.EqualFold(labeled, change.Repo) {
+			classifier.Label = label
+		}
+	}
+	rules, err := classificationRules(activeFileConfig)
+	if err != nil {
+		return nil, err
+	}
+	classifier.Rules = rules
+	return git.NewReleaseNotes(owner, repo, change.From, change.To, commits, classifier.Classify(ctx, commits)), nil
+}
+
+// formatPayloadNotes renders the aggregated notes: a summary of the changed
+// repositories, followed by the notes of each one
+func formatPayloadNotes(notes *payloadNotes, format git.OutputFormat) (string, error) {
+	if format == git.FormatJSON {
+		if notes.Changes == nil {
+			notes.Changes = []payloadChange{}
+		}
+		data, err := json.MarshalIndent(notes, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		return string(data) + "\n", nil
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Release notes from %s to %s\n\n", orDash(notes.From.Version), orDash(notes.To.Version))
+	if notes.From.Image != "" && notes.To.Image != "" {
+		fmt.Fprintf(&sb, "Release images: `%s` → `%s`\n\n", notes.From.Image, notes.To.Image)
+	}
+	if len(notes.Changes) == 0 {
+		sb.WriteString("No component changed.\n")
+		return sb.String(), nil
+	}
+
+	sb.WriteString("| Repository | Components | From | To | Changes |\n|---|---|---|---|---:|\n")
+	for _, change := range notes.Changes {
+		count := "-"
+		if change.Notes != nil {
+			count = fmt.Sprint(len(change.Notes.Commits))
+		}
+		fmt.Fprintf(&sb, "| %s | %s | %s | %s | %s |\n", sourceName(change), strings.Join(change.Components, ", "), orDash(shortSHA(change.From)), orDash(shortSHA(change.To)), count)
+	}
+
+	formatter := git.NewFormatter(git.FormatMarkdown)
+	formatter.Sections = payloadSections
+	for _, change := range notes.Changes {
+		fmt.Fprintf(&sb, "\n## %s\n\n", sourceName(change))
+		fmt.Fprintf(&sb, "Components: %s\n\n", strings.Join(change.Components, ", "))
+		switch {
+		case change.Kind == diff.Added:
+			fmt.Fprintf(&sb, "Added at %s.\n", change.To)
+			continue
+		case change.Kind == diff.Removed:
+			fmt.Fprintf(&sb, "Removed, last built at %s.\n", change.From)
+			continue
+		case change.Notes == nil:
+			reason := "the repository is not on GitHub"
+			if change.Error != "" {
+				reason = change.Error
+			}
+			fmt.Fprintf(&sb, "Changed from %s to %s; no notes: %s.\n", change.From, change.To, reason)
+			continue
+		}
+
+		fmt.Fprintf(&sb, "[%s...%s](https://github.com/%s/compare/%s...%s)\n\n", shortSHA(change.From), shortSHA(change.To), change.Repo, change.From, change.To)
+		if len(change.Notes.Commits) == 0 {
+			fmt.Fprintf(&sb, "No changes noted in %d commits.\n", change.Notes.Statistics.Total)
+			continue
+		}
+		rendered, err := formatter.Format(change.Notes)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(nestMarkdown(rendered))
+	}
+	return sb.String(), nil
+}
+
+// sourceName names the repository of a change: owner/repo on GitHub, and
+// its address elsewhere
+func sourceName(change payloadChange) string {
+	if change.Repo != "" {
+		return change.Repo
+	}
+	return change.Source
+}
+
+// nestMarkdown nests release notes under the heading of their repository:
+// their title is dropped and their headings are one level deeper
+func nestMarkdown(markdown string) string {
+	lines := strings.Split(strings.TrimLeft(markdown, "\n"), "\n")
+	if len(lines) > 0 && strings.HasPrefix(lines[0], "# ") {
+		lines = lines[1:]
+	}
+	for i, line := range lines {
+		if strings.HasPrefix(line, "#") {
+			lines[i] = "#" + line
+		}
+	}
+	return strings.TrimLeft(strings.Join(lines, "\n"), "\n")
+}
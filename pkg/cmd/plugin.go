@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"text/tabwriter"
+
+	"drivio/pkg/plugin"
+	"drivio/pkg/ui"
+
+	"github.com/spf13/cobra"
+)
+
+// pluginCmd represents the plugin command
+var pluginCmd = &cobra.Command{
+	Use:   "plugin",
+	Short: "List the plugins extending drivio",
+	Long: `Plugins extend drivio without forking it: they are drivio-NAME executables
+on PATH, written in any language.
+
+Run as drivio NAME [ARGS...], a plugin is a subcommand of drivio, unless
+NAME is a built-in command. Run with the --drivio-plugin argument, a plugin
+answers a request of drivio: a JSON object on its stdin,
+
+  {"protocol": 1, "method": "notify", "params": {...}}
+
+answered by a JSON object on its stdout, {"result": ...}, or {"error":
+"message"} on failure. Messages for the user go to stderr.
+
+Every plugin answers describe with its name, version, description and
+capabilities, which tell the other methods it answers:
+
+  notify   notify: a publish target, used by notification channels of type
+           plugin (params: channel, options, title, text, url)
+  tickets  tickets.transition: a ticket system, used by jira transition
+           --plugin (params: ticket, status, comment, dry_run; result: from,
+           to, empty when the ticket is in the status already)
+  scm      scm.archive: a repository provider, used by fetch archive
+           --provider (params: repo, ref, and path, the file to write the
+           tar.gz archive to)`,
+}
+
+// pluginListCmd represents the plugin list command
+var pluginListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the plugins on PATH",
+	Long: `List the drivio-NAME executables on PATH with their descriptions.
+
+Plugins hidden by a plugin of the same name earlier on PATH, or by a
+built-in command, are reported.
+
+Examples:
+  drivio plugin list`,
+	Args: cobra.NoArgs,
+	RunE: runPluginList,
+}
+
+func init() {
+	rootCmd.AddCommand(pluginCmd)
+	pluginCmd.AddCommand(pluginListCmd)
+}
+
+func runPluginList(cmd *cobra.Command, args []string) error {
+	plugins := plugin.Discover()
+	if len(plugins) == 0 {
+		ui.Printf("No plugins: no %sNAME executable on PATH\n", plugin.Prefix)
+		return nil
+	}
+
+	infos := make([]*plugin.Info, len(plugins))
+	errs := make([]error, len(plugins))
+	if err := ui.RunProgress("Describing plugins...", ui.UnitItems, func(progress chan<- ui.ProgressMsg) error {
+		for i, p := range plugins {
+			if !p.Shadowed {
+				infos[i], errs[i] = p.Describe(context.Background())
+			}
+			progress <- ui.ProgressMsg{Current: int64(i + 1), Total: int64(len(plugins)), Message: p.Name}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tVERSION\tCAPABILITIES\tPATH\tDESCRIPTION")
+	for i, p := range plugins {
+		switch {
+		case p.Shadowed:
+			ui.Fprintf(w, "%s\t-\t-\t%s\t⚠️  hidden by the %s plugin earlier on PATH\n", p.Name, p.Path, p.Name)
+		case errs[i] != nil:
+			ui.Fprintf(w, "%s\t-\t-\t%s\t❌ %v\n", p.Name, p.Path, errs[i])
+		default:
+			info := infos[i]
+			description := info.Description
+			if builtinCommand(p.Name) {
+				description = fmt.Sprintf("⚠️  hidden by the built-in %s command", p.Name)
+			}
+			ui.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", p.Name, orDash(info.Version), orDash(strings.Join(info.Capabilities, ",")), p.Path, orDash(description))
+		}
+	}
+	return w.Flush()
+}
+
+// builtinCommand reports whether a name is a command of drivio
+func builtinCommand(name string) bool {
+	switch name {
+	case "help", "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		return true
+	}
+	for _, c := range rootCmd.Commands() {
+		if c.Name() == name || c.HasAlias(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// runPluginCommand runs drivio NAME ARGS... as the drivio-NAME plugin when
+// NAME is not a built-in command, reporting whether a plugin ran
+func runPluginCommand(args []string) (bool, error) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") || builtinCommand(args[0]) {
+		return false, nil
+	}
+	p, err := plugin.Find(args[0])
+	if err != nil {
+		return false, nil
+	}
+
+	c := exec.Command(p.Path, args[1:]...)
+	c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return true, c.Run()
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"drivio/pkg/config"
+	"drivio/pkg/github"
+	"drivio/pkg/gitlab"
+	"drivio/pkg/ui"
+)
+
+// preflightGitLab verifies the GitLab token and its scopes before a long
+// operation, so a bad token fails fast with the missing scope instead of a
+// bare 401 or 403 halfway through
+func preflightGitLab(ctx context.Context, cfg *config.Config) error {
+	if cfg.GitLabToken == "" {
+		return nil
+	}
+
+	return ui.RunSpinner(fmt.Sprintf("Checking GitLab token for %s...", cfg.GitLabURL), func() error {
+		client, err := gitlab.NewClient(cfg)
+		if err != nil {
+			return err
+		}
+		return client.ValidateConnection(ctx)
+	})
+}
+
+// preflightGitHub verifies the GitHub token before a long operation and,
+// when the repository is not visible with it, reports the missing scope
+func preflightGitHub(ctx context.Context, token, owner, repo string) error {
+	if token == "" {
+		return nil
+	}
+
+	return ui.RunSpinner("Checking GitHub token...", func() error {
+		client := github.NewClient(token)
+		scopes, known, err := client.TokenScopes(ctx)
+		if err != nil {
+			return err
+		}
+
+		exists, err := client.RepositoryExists(ctx, owner, repo)
+		if err != nil {
+			return fmt.Errorf("failed to check repository %s/%s: %w", owner, repo, err)
+		}
+		if exists {
+			return nil
+		}
+
+		if known && !hasScope(scopes, "repo") {
+			have := strings.Join(scopes, ", ")
+			if have == "" {
+				have = "none"
+			}
+			return fmt.Errorf("repository %s/%s not found; reading private repositories requires the repo scope, which the GitHub token is missing (token scopes: %s)", owner, repo, have)
+		}
+		return fmt.Errorf("repository %s/%s not found or not accessible with the GitHub token", owner, repo)
+	})
+}
+
+// hasScope reports whether scope is one of scopes
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
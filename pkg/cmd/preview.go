@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"drivio/pkg/config"
+	"drivio/pkg/drivio"
+	"drivio/pkg/ui"
+	"drivio/pkg/workdir"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	previewOwner       string
+	previewRepo        string
+	previewFrom        string
+	previewTo          string
+	previewLocal       string
+	previewGitHubToken string
+	previewSections    bool
+	previewTemplate    string
+	previewStyle       string
+	previewWidth       int
+	previewNoPager     bool
+)
+
+// notesCommands are the commands whose outputs are release notes
+var notesCommands = []string{"release-notes", "release", "serve", "payload-notes"}
+
+// previewCmd represents the preview command
+var previewCmd = &cobra.Command{
+	Use:   "preview [FILE]",
+	Short: "Render release notes in the terminal",
+	Long: `Render Markdown release notes in the terminal, with headings, lists, links
+and code styled, and page them when taller than the terminal, to review them
+without opening a browser.
+
+The notes are FILE (- for stdin), by default the latest notes generated in
+the work directory by release-notes, release, serve or payload-notes. With
+--from and --to, they are generated and rendered in one go instead, as by
+release-notes with --sections: from GitHub with --owner and --repo, or from
+the clone of --local. Generated notes are not saved.
+
+--style picks the colors: auto (dark or light from the background of the
+terminal, without colors when not printing to one), dark, light or notty.
+The pager is $PAGER, less -R by default; --no-pager prints the notes
+directly.
+
+Examples:
+  drivio preview
+  drivio preview .drivio-work/release-notes-openshift-hypershift-v0.1.59-v0.1.63.md
+  drivio release-notes --owner openshift --repo hypershift --from v0.1.59 --to v0.1.63 --stdout -q | drivio preview -
+  drivio preview --owner openshift --repo hypershift --from v0.1.59 --to v0.1.63
+  drivio preview --local . --owner openshift --repo hypershift --from v0.1.59 --to HEAD --style light`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runPreview,
+}
+
+func init() {
+	rootCmd.AddCommand(previewCmd)
+
+	// Add flags
+	previewCmd.Flags().StringVar(&previewOwner, "owner", "", "GitHub repository owner/organization of the generated notes")
+	previewCmd.Flags().StringVar(&previewRepo, "repo", "", "GitHub repository name of the generated notes")
+	previewCmd.Flags().StringVar(&previewFrom, "from", "", "Generate the notes from this reference (tag, commit, or branch)")
+	previewCmd.Flags().StringVar(&previewTo, "to", "", "Generate the notes to this reference")
+	previewCmd.Flags().StringVar(&previewLocal, "local", "", "Read the commits from this local clone instead of the GitHub API")
+	previewCmd.Flags().StringVar(&previewGitHubToken, "github-token", "", "GitHub token for authentication (optional)")
+	previewCmd.Flags().BoolVar(&previewSections, "sections", true, "Group the changes of the generated notes by type")
+	previewCmd.Flags().StringVar(&previewTemplate, "template", "", "Go template file rendering the generated notes")
+	previewCmd.Flags().StringVar(&previewStyle, "style", ui.StyleAuto, "Colors of the rendering: "+strings.Join(ui.MarkdownStyles, ", "))
+	previewCmd.Flags().IntVar(&previewWidth, "width", 0, "Wrap the text at this many columns (default: the width of the terminal, up to 100)")
+	previewCmd.Flags().BoolVar(&previewNoPager, "no-pager", false, "Print the notes without a pager")
+
+	// Environment variables that take precedence over the config file
+	bindFlagEnv(previewCmd.Flags(), "github-token", config.EnvGitHubToken...)
+}
+
+func runPreview(cmd *cobra.Command, args []string) error {
+	if !slices.Contains(ui.MarkdownStyles, previewStyle) {
+		return fmt.Errorf("invalid --style %q: use %s", previewStyle, strings.Join(ui.MarkdownStyles, ", "))
+	}
+	generate := previewFrom != "" || previewTo != ""
+	if generate && len(args) > 0 {
+		return fmt.Errorf("preview FILE or generate the notes with --from and --to, not both")
+	}
+
+	var markdown, path string
+	var err error
+	switch {
+	case generate:
+		markdown, err = previewGenerate()
+	case len(args) > 0:
+		path = args[0]
+		markdown, err = readNotesFile(path)
+	default:
+		if path, _, err = latestNotes(workDir); err == nil {
+			ui.Printf("📄 %s\n", path)
+			markdown, err = readNotesFile(path)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	// Text notes are shown as they are, their lines would be joined
+	rendered := markdown
+	if path == "" || filepath.Ext(path) != ".txt" {
+		if rendered, err = ui.RenderMarkdown(markdown, previewStyle, previewWidth); err != nil {
+			return err
+		}
+	}
+	if previewNoPager {
+		fmt.Print(rendered)
+		return nil
+	}
+	return ui.Page(rendered)
+}
+
+// previewGenerate generates the notes of --from and --to as Markdown
+func previewGenerate() (string, error) {
+	if previewFrom == "" || previewTo == "" {
+		return "", fmt.Errorf("generating the notes needs both --from and --to")
+	}
+	if previewOwner == "" || previewRepo == "" {
+		return "", fmt.Errorf("generating the notes needs --owner and --repo")
+	}
+	rules, err := classificationRules(activeFileConfig)
+	if err != nil {
+		return "", err
+	}
+
+	src := drivio.NewGitHub(previewOwner, previewRepo, previewGitHubToken)
+	if previewLocal != "" {
+		if src, err = drivio.NewLocal(previewLocal, previewOwner, previewRepo); err != nil {
+			return "", err
+		}
+	}
+
+	ctx := context.Background()
+	var notes *drivio.Notes
+	if err := ui.RunSpinner(fmt.Sprintf("Generating the notes of %s/%s %s..%s...", previewOwner, previewRepo, previewFrom, previewTo), func() error {
+		var err error
+		notes, err = drivio.Generate(ctx, src, previewFrom, previewTo, drivio.Options{Label: drivio.DefaultLabel, Rules: rules})
+		return err
+	}); err != nil {
+		return "", fmt.Errorf("failed to generate release notes: %w", err)
+	}
+	renderOpts := drivio.RenderOptions{Sections: previewSections}
+	if previewTemplate != "" {
+		renderOpts.TemplateFiles = []string{previewTemplate}
+	}
+	output, err := drivio.Render(src, notes, renderOpts)
+	if err != nil {
+		return "", fmt.Errorf("failed to format release notes: %w", err)
+	}
+	return output, nil
+}
+
+// readNotesFile reads Markdown or text notes, from stdin for -
+func readNotesFile(path string) (string, error) {
+	var content []byte
+	var err error
+	if path == "-" {
+		content, err = io.ReadAll(os.Stdin)
+	} else {
+		content, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read notes: %w", err)
+	}
+	if ui.DetectFormat(content) == ui.FormatJSON {
+		return "", fmt.Errorf("%s holds JSON notes: preview Markdown or text notes", path)
+	}
+	return string(content), nil
+}
+
+// latestNotes returns the path of the latest Markdown or text notes
+// recorded in the artifact index of the work directory, and their record
+func latestNotes(dir string) (string, *workdir.Record, error) {
+	index := workdir.NewIndex(dir)
+	records, err := index.List()
+	if err != nil {
+		return "", nil, err
+	}
+	for i := len(records) - 1; i >= 0; i-- {
+		if !slices.Contains(notesCommands, records[i].Command) {
+			continue
+		}
+		for _, output := range records[i].Outputs {
+			path := index.Path(output)
+			switch filepath.Ext(path) {
+			case ".md", ".txt":
+			default:
+				continue
+			}
+			if _, err := os.Stat(path); err == nil {
+				return path, &records[i], nil
+			}
+		}
+	}
+	return "", nil, fmt.Errorf("no release notes in %s: generate them with release-notes, or give the notes file", dir)
+}
@@ -0,0 +1,339 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"drivio/pkg/audit"
+	"drivio/pkg/config"
+	"drivio/pkg/diff"
+	"drivio/pkg/fileutil"
+	"drivio/pkg/git"
+	"drivio/pkg/gitlab"
+	"drivio/pkg/query"
+	"drivio/pkg/review"
+	"drivio/pkg/ui"
+	"drivio/pkg/workdir"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	promoteRepo         string
+	promoteFile         string
+	promoteBranch       string
+	promoteSet          []string
+	promoteSourceBranch string
+	promoteTitle        string
+	promoteLabels       []string
+	promoteReviewers    []string
+	promoteNotesFile    string
+	promoteNotesRepo    string
+	promoteFrom         string
+	promoteTo           string
+	promoteGitHubToken  string
+	promoteKeepBranch   bool
+	promoteDryRun       bool
+)
+
+// promoteCmd represents the promote command
+var promoteCmd = &cobra.Command{
+	Use:   "promote",
+	Short: "Update a value of a config file and open a merge request",
+	Long: `Update a value of a YAML file in a GitLab config repository, e.g. the image tag
+of an environment, on a new branch, and open a merge request with the change
+and its release notes as description: the GitOps way of updating a
+production environment.
+
+--set takes the path of the value, as for fetch --query, and its new value;
+it can be repeated. Only the values change in the file: comments, indentation
+and key order are kept, as in a change made by hand.
+
+The release notes come from a file, e.g. written by drivio release-notes
+(--notes), or are generated from the GitHub repository of the promoted
+component (--notes-repo), between the previous and the new value of the first
+--set unless --from and --to are given, e.g. from the image tag deployed to
+the one promoted.
+
+--dry-run prints the change and the description without writing anything.
+
+Examples:
+  drivio promote --repo myorg/configs --file envs/production.yaml --set .image.tag=v1.2.4
+  drivio promote --repo myorg/configs --file envs/production.yaml --set .image.tag=v1.2.4 --notes-repo myorg/app
+  drivio promote --repo myorg/configs --file envs/production.yaml --set '.components[0].version=4.15.2' --notes release-notes.md --labels promotion,production
+  drivio promote --repo myorg/configs --file envs/production.yaml --set .image.tag=v1.2.4 --notes-repo myorg/app --dry-run`,
+	RunE: runPromote,
+}
+
+func init() {
+	rootCmd.AddCommand(promoteCmd)
+
+	// Add flags
+	promoteCmd.Flags().StringVar(&gitlabURL, "url", "", "GitLab URL (default: https://gitlab.com)")
+	promoteCmd.Flags().StringVar(&gitlabToken, "token", "", "GitLab access token with the api scope")
+	promoteCmd.Flags().StringVar(&gitlabInstance, "instance", "", "GitLab instance of the config file providing the URL and token")
+	promoteCmd.Flags().StringVar(&promoteRepo, "repo", "", "Config repository path (e.g., owner/repo)")
+	promoteCmd.Flags().StringVar(&promoteFile, "file", "", "Path of the YAML file in the repository")
+	promoteCmd.Flags().StringVar(&promoteBranch, "branch", "", "Branch to update through the merge request (default: main)")
+	promoteCmd.Flags().StringArrayVar(&promoteSet, "set", nil, "Value to update, as PATH=VALUE (e.g. .image.tag=v1.2.4); repeatable")
+	promoteCmd.Flags().StringVar(&promoteSourceBranch, "source-branch", "", "Branch created for the merge request (default: drivio/promote-<file>-<value>)")
+	promoteCmd.Flags().StringVar(&promoteTitle, "title", "", "Title of the merge request and message of the commit (default: describes the change)")
+	promoteCmd.Flags().StringSliceVar(&promoteLabels, "labels", nil, "Labels of the merge request")
+	promoteCmd.Flags().StringSliceVar(&promoteReviewers, "reviewers", nil, "Usernames of the reviewers of the merge request")
+	promoteCmd.Flags().StringVar(&promoteNotesFile, "notes", "", "Markdown release notes file to describe the merge request with")
+	promoteCmd.Flags().StringVar(&promoteNotesRepo, "notes-repo", "", "GitHub repository (owner/repo) to generate the release notes from")
+	promoteCmd.Flags().StringVar(&promoteFrom, "from", "", "From reference of the release notes (default: the previous value)")
+	promoteCmd.Flags().StringVar(&promoteTo, "to", "", "To reference of the release notes (default: the new value)")
+	promoteCmd.Flags().StringVar(&promoteGitHubToken, "github-token", "", "GitHub token for --notes-repo (optional)")
+	promoteCmd.Flags().BoolVar(&promoteKeepBranch, "keep-source-branch", false, "Keep the source branch once the merge request is merged")
+	promoteCmd.Flags().BoolVar(&promoteDryRun, "dry-run", false, "Print the change and the description without writing anything")
+
+	// Environment variables that take precedence over the config file
+	bindFlagEnv(promoteCmd.Flags(), "url", config.EnvGitLabURL...)
+	bindFlagEnv(promoteCmd.Flags(), "token", config.EnvGitLabToken...)
+	bindFlagEnv(promoteCmd.Flags(), "instance", config.EnvInstance...)
+	bindFlagEnv(promoteCmd.Flags(), "github-token", config.EnvGitHubToken...)
+
+	// Mark required flags
+	promoteCmd.MarkFlagRequired("repo")
+	promoteCmd.MarkFlagRequired("file")
+	promoteCmd.MarkFlagRequired("set")
+}
+
+// promotion is a value updated by promote
+type promotion struct {
+	path     string
+	previous string
+	value    string
+}
+
+func runPromote(cmd *cobra.Command, args []string) error {
+	if promoteNotesFile != "" && promoteNotesRepo != "" {
+		return fmt.Errorf("--notes and --notes-repo are exclusive")
+	}
+	promotions, err := parsePromotions(promoteSet)
+	if err != nil {
+		return err
+	}
+
+	// Create work directory if it doesn't exist
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return fmt.Errorf("failed to create work directory: %w", err)
+	}
+
+	unlock, err := lockWorkDir(workDir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := enforceRetention(workDir, ui.Printf); err != nil {
+		return err
+	}
+
+	cfg := loadFetchConfig()
+	cfg.RepositoryPath = promoteRepo
+	cfg.FilePath = promoteFile
+	if promoteBranch != "" {
+		cfg.Branch = promoteBranch
+	}
+	if err := cfg.ValidateConfig(); err != nil {
+		return fmt.Errorf("configuration error: %w", err)
+	}
+	if cfg.GitLabToken == "" && !promoteDryRun {
+		return fmt.Errorf("GitLab token is required to push a branch and open a merge request. Set GITLAB_TOKEN environment variable or use --token flag")
+	}
+
+	client, err := newFetchClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	// Step 1: Fetch the file and update its values
+	var file *gitlab.FileRevision
+	if err := ui.RunSpinner(fmt.Sprintf("Fetching %s...", promoteFile), func() error {
+		var err error
+		file, err = client.GetFileRevision(ctx, promoteFile)
+		return err
+	}); err != nil {
+		return fmt.Errorf("failed to fetch file: %w", err)
+	}
+
+	content := file.Content
+	changed := false
+	for i := range promotions {
+		p := &promotions[i]
+		content, p.previous, err = query.Set(content, p.path, p.value)
+		if err != nil {
+			return fmt.Errorf("failed to set %s in %s: %w", p.path, promoteFile, err)
+		}
+		changed = changed || p.previous != p.value
+		ui.Printf("✏️  %s: %s → %s\n", p.path, p.previous, p.value)
+	}
+	if !changed {
+		return fmt.Errorf("nothing to promote: %s already has these values in branch %s", promoteFile, cfg.Branch)
+	}
+
+	// Step 2: Describe the change with its release notes
+	notes, err := promotionNotes(ctx, promotions[0])
+	if err != nil {
+		return err
+	}
+	description := promotionDescription(cfg.Branch, promotions, notes)
+	title := promoteTitle
+	if title == "" {
+		title = promotionTitle(promotions)
+	}
+	sourceBranch := promoteSourceBranch
+	if sourceBranch == "" {
+		sourceBranch = promotionBranch(promoteFile, promotions[0].value)
+	}
+
+	if promoteDryRun {
+		fmt.Print(diff.Unified(cfg.Branch+":"+promoteFile, sourceBranch+":"+promoteFile, file.Content, content, 3))
+		fmt.Printf("\n%s\n\n%s", title, description)
+		return nil
+	}
+
+	// Step 3: Commit the change to a new branch and open the merge request
+	provider := review.NewGitLab(client)
+	var mrURL string
+	if err := ui.RunSpinner(fmt.Sprintf("Opening a %s from %s...", provider.Kind(), sourceBranch), func() error {
+		result, err := provider.Open(ctx, review.Request{
+			SourceBranch:       sourceBranch,
+			TargetBranch:       cfg.Branch,
+			Title:              title,
+			Description:        description,
+			Files:              []review.File{{Path: file.Path, Content: content, Revision: file.LastCommitID}},
+			Labels:             promoteLabels,
+			Reviewers:          promoteReviewers,
+			RemoveSourceBranch: !promoteKeepBranch,
+		})
+		if err != nil {
+			return err
+		}
+		mrURL = result.URL
+		return nil
+	}); err != nil {
+		return err
+	}
+	ui.Printf("🚀 Merge request opened: %s\n", mrURL)
+	auditAction(audit.ActionReview, cfg.RepositoryPath+"@"+cfg.Branch, mrURL, map[string]string{"file": promoteFile, "set": strings.Join(promoteSet, ","), "source-branch": sourceBranch})
+
+	// Keep the description, e.g. to edit the merge request later
+	descriptionPath := filepath.Join(workDir, workdir.PromotionsDir, strings.ReplaceAll(sourceBranch, "/", "-")+".md")
+	if err := fileutil.WriteFileAtomic(descriptionPath, []byte(description), 0644, false); err != nil {
+		ui.Printf("⚠️  Warning: failed to save the description: %v\n", err)
+		return nil
+	}
+
+	inputs := fetchInputs(cfg)
+	inputs["set"] = strings.Join(promoteSet, ",")
+	inputs["source-branch"] = sourceBranch
+	inputs["merge-request"] = mrURL
+	recordArtifacts(workDir, "promote", inputs, descriptionPath)
+
+	return nil
+}
+
+// parsePromotions parses the PATH=VALUE arguments of --set
+func parsePromotions(values []string) ([]promotion, error) {
+	promotions := make([]promotion, 0, len(values))
+	for _, value := range values {
+		path, v, ok := strings.Cut(value, "=")
+		if !ok || strings.TrimSpace(path) == "" {
+			return nil, fmt.Errorf("invalid --set %q: use PATH=VALUE, e.g. .image.tag=v1.2.4", value)
+		}
+		promotions = append(promotions, promotion{path: strings.TrimSpace(path), value: v})
+	}
+	return promotions, nil
+}
+
+// promotionNotes returns the release notes describing the promotion: those
+// of --notes, those generated from --notes-repo, or none
+func promotionNotes(ctx context.Context, first promotion) (string, error) {
+	if promoteNotesFile != "" {
+		notes, err := os.ReadFile(promoteNotesFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read release notes: %w", err)
+		}
+		return string(notes), nil
+	}
+	if promoteNotesRepo == "" {
+		return "", nil
+	}
+
+	owner, repo, ok := strings.Cut(promoteNotesRepo, "/")
+	if !ok || owner == "" || repo == "" {
+		return "", fmt.Errorf("invalid --notes-repo %q: use owner/repo", promoteNotesRepo)
+	}
+	from, to := promoteFrom, promoteTo
+	if from == "" {
+		from = first.previous
+	}
+	if to == "" {
+		to = first.value
+	}
+	rules, err := classificationRules(activeFileConfig)
+	if err != nil {
+		return "", err
+	}
+
+	var output string
+	if err := ui.RunSpinner(fmt.Sprintf("Generating release notes from %s to %s...", from, to), func() error {
+		analyzer := git.NewAnalyzer(promoteGitHubToken)
+		classifier := git.NewClassifier(analyzer, owner, repo)
+		classifier.Rules = rules
+		notes, err := analyzer.GenerateReleaseNotes(ctx, owner, repo, from, to, classifier)
+		if err != nil {
+			return err
+		}
+		output, err = git.NewFormatter(git.FormatMarkdown).Format(notes)
+		return err
+	}); err != nil {
+		return "", fmt.Errorf("failed to generate release notes: %w", err)
+	}
+	return output, nil
+}
+
+// promotionTitle describes the promotion in a line, e.g. "Promote
+// production.yaml: .image.tag to v1.2.4"
+func promotionTitle(promotions []promotion) string {
+	changes := make([]string, 0, len(promotions))
+	for _, p := range promotions {
+		changes = append(changes, fmt.Sprintf("%s to %s", p.path, p.value))
+	}
+	return fmt.Sprintf("Promote %s: %s", path.Base(promoteFile), strings.Join(changes, ", "))
+}
+
+// promotionDescription returns the description of the merge request: the
+// values changed, followed by the release notes
+func promotionDescription(branch string, promotions []promotion, notes string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Updates `%s` in `%s`:\n\n", promoteFile, branch)
+	sb.WriteString("| Value | From | To |\n|---|---|---|\n")
+	for _, p := range promotions {
+		fmt.Fprintf(&sb, "| `%s` | `%s` | `%s` |\n", p.path, p.previous, p.value)
+	}
+	if notes != "" {
+		sb.WriteString("\n" + strings.TrimRight(notes, "\n") + "\n")
+	}
+	return sb.String()
+}
+
+// branchUnsafe matches the characters left out of generated branch names
+var branchUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// promotionBranch returns the default source branch of a promotion, e.g.
+// drivio/promote-production-v1.2.4
+func promotionBranch(file, value string) string {
+	name := strings.TrimSuffix(path.Base(file), path.Ext(file))
+	return "drivio/promote-" + strings.Trim(branchUnsafe.ReplaceAllString(name+"-"+value, "-"), "-.")
+}
@@ -3,58 +3,46 @@ package cmd
 import (
 	"bufio"
 	"context"
-	"encoding/json"
 	"fmt"
-	"net/http"
 	"os"
 	"path/filepath"
-	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
+	"drivio/pkg/config"
+	"drivio/pkg/git"
 	"drivio/pkg/ui"
+	"drivio/pkg/workdir"
 
 	"github.com/spf13/cobra"
 )
 
 var (
 	// Configuration flags
-	owner               string
-	repo                string
-	fromRef             string
-	toRef               string
-	releaseOutput       string
-	releaseNotesWorkDir string
-	githubToken         string
-	showStdout          bool
-	useTable            bool
+	owner         string
+	repo          string
+	fromRef       string
+	toRef         string
+	releaseOutput string
+	githubToken   string
+	showStdout    bool
+	useTable      bool
+	notesFormat   string
+	compactJSON   bool
+	localClone    string
+	verifySigs    bool
+	ownership     bool
+	ownerDepth    int
+	sections      bool
+	summary       bool
+	templateFile  string
+	rangeMode     string
+	notesSince    string
+	notesUntil    string
+	maxPerSection int
 )
 
-// GitHubCommit represents a commit from GitHub API
-type GitHubCommit struct {
-	Sha    string `json:"sha"`
-	Commit struct {
-		Author struct {
-			Name  string    `json:"name"`
-			Email string    `json:"email"`
-			Date  time.Time `json:"date"`
-		} `json:"author"`
-		Message string `json:"message"`
-	} `json:"commit"`
-	Parents []struct {
-		Sha string `json:"sha"`
-	} `json:"parents"`
-}
-
-// GitHubPR represents a pull request from GitHub API
-type GitHubPR struct {
-	Number int `json:"number"`
-	Labels []struct {
-		Name string `json:"name"`
-	} `json:"labels"`
-}
-
 // releaseNotesCmd represents the release-notes command
 var releaseNotesCmd = &cobra.Command{
 	Use:   "release-notes",
@@ -64,9 +52,54 @@ var releaseNotesCmd = &cobra.Command{
 This command uses GitHub's API to fetch commits between two references and generates
 release notes without cloning the repository.
 
+With --local, the commits are read from a local clone instead (e.g. one made by
+drivio fetch clone, or a mirror not hosted on GitHub), entirely offline. The
+labels of pull requests are not checked then, as they are only known to GitHub;
+--owner and --repo still name the repository in the notes.
+
+When --from and --to are diverged branches, e.g. a release branch and main,
+the merge base of the references is found and noted: --range three-dot (the
+default) lists the changes of --to since the merge base, what was merged into
+it, and --range two-dot compares the tips, also listing the changes of --from
+since the merge base.
+
+--since and --until, with --local, only keep the changes committed in a
+period, e.g. for "what changed this sprint" reports without tags. They take a
+date (2024-01-31, inclusive), a time (2024-01-31T12:00:00Z) or an age (2w for
+two weeks ago). --from is then optional, and --to defaults to HEAD.
+
+--verify-signatures checks the GPG or SSH signatures of the commits and of the
+references that are tags, as reported by GitHub or, with --local, with the keys
+known to git. Changes whose signature is not verified are annotated in the
+notes, and the count of unverified commits is added to the statistics.
+
+--ownership appends a "Code ownership" section with the commits and lines
+added and removed by author and by directory (--ownership-depth levels deep).
+Through the GitHub API it costs one request per commit.
+
+--sections groups the changes by type (Features, Bug Fixes, ...). The type
+comes from Conventional Commits or, for other commits, from the
+classification rules of the configuration file.
+
+--max-per-section keeps huge releases readable: sections longer than this are
+cut, ending with "…and N more changes (see full compare)" and a link to the
+full comparison on GitHub.
+
+--summary appends the number of changes by type, scope, author and label,
+e.g. to answer how many networking changes a release has.
+
+--template renders the markdown, table or text output with a Go template file
+instead of the built-in one. The template can reuse and redefine the blocks of
+the built-in templates, e.g. {{ template "changes" . }}.
+
 Examples:
   drivio release-notes --owner openshift --repo hypershift --from v0.1.59 --to v0.1.63
-  drivio release-notes --owner myorg --repo myrepo --from v1.0.0 --to v1.1.0 --output release-notes.md`,
+  drivio release-notes --owner myorg --repo myrepo --from v1.0.0 --to v1.1.0 --output release-notes.md
+  drivio release-notes --owner openshift --repo hypershift --from v0.1.59 --to v0.1.63 --local .drivio-work/clones/openshift/hypershift.git
+  drivio release-notes --owner openshift --repo hypershift --from v0.1.59 --to v0.1.63 --verify-signatures
+  drivio release-notes --owner openshift --repo hypershift --from v0.1.59 --to v0.1.63 --ownership --ownership-depth 2
+  drivio release-notes --owner openshift --repo hypershift --from release-4.14 --to main --range two-dot
+  drivio release-notes --owner openshift --repo hypershift --local .drivio-work/clones/openshift/hypershift --since 2w`,
 	RunE: runReleaseNotes,
 }
 
@@ -79,16 +112,29 @@ func init() {
 	releaseNotesCmd.Flags().StringVar(&fromRef, "from", "", "From reference (tag, commit, or branch)")
 	releaseNotesCmd.Flags().StringVar(&toRef, "to", "", "To reference (tag, commit, or branch)")
 	releaseNotesCmd.Flags().StringVar(&releaseOutput, "output", "", "Output file path (default: stdout)")
-	releaseNotesCmd.Flags().StringVar(&releaseNotesWorkDir, "work-dir", ".drivio-work", "Working directory for generated files")
 	releaseNotesCmd.Flags().StringVar(&githubToken, "github-token", "", "GitHub token for authentication (optional)")
 	releaseNotesCmd.Flags().BoolVar(&showStdout, "stdout", false, "Show content on stdout")
-	releaseNotesCmd.Flags().BoolVar(&useTable, "table", false, "Generate a markdown table format")
+	releaseNotesCmd.Flags().BoolVar(&useTable, "table", false, "Generate a markdown table format (same as --format table)")
+	releaseNotesCmd.Flags().StringVar(&notesFormat, "format", string(git.FormatMarkdown), "Output format: markdown, table, json or text")
+	releaseNotesCmd.Flags().BoolVar(&compactJSON, "compact", false, "Render --format json on a single line")
+	releaseNotesCmd.Flags().BoolVar(&verifySigs, "verify-signatures", false, "Verify the signatures of the commits and tags and annotate the unverified ones")
+	releaseNotesCmd.Flags().BoolVar(&sections, "sections", false, "Group the changes by type (Features, Bug Fixes, ...)")
+	releaseNotesCmd.Flags().IntVar(&maxPerSection, "max-per-section", 0, "Fold the sections of markdown, table and text output after this many changes (default: no limit)")
+	releaseNotesCmd.Flags().BoolVar(&summary, "summary", false, "Append the number of changes by type, scope, author and label")
+	releaseNotesCmd.Flags().StringVar(&templateFile, "template", "", "Go template file rendering the markdown, table or text output")
+	releaseNotesCmd.Flags().BoolVar(&ownership, "ownership", false, "Append the churn by author and by directory (Code ownership)")
+	releaseNotesCmd.Flags().IntVar(&ownerDepth, "ownership-depth", 1, "Directory levels of the Code ownership appendix")
+	releaseNotesCmd.Flags().StringVar(&rangeMode, "range", string(git.RangeThreeDot), "Commits listed when --from and --to have diverged: three-dot (those of --to since the merge base) or two-dot (those of both)")
+	releaseNotesCmd.Flags().StringVar(&notesSince, "since", "", "With --local, only keep the changes committed since this date, time or age (e.g. 2024-01-15 or 2w)")
+	releaseNotesCmd.Flags().StringVar(&notesUntil, "until", "", "With --local, only keep the changes committed until this date, time or age")
+	releaseNotesCmd.Flags().StringVar(&localClone, "local", "", "Read the commits from this local clone instead of the GitHub API (offline)")
+
+	// Environment variables that take precedence over the config file
+	bindFlagEnv(releaseNotesCmd.Flags(), "github-token", config.EnvGitHubToken...)
 
 	// Mark required flags
 	releaseNotesCmd.MarkFlagRequired("owner")
 	releaseNotesCmd.MarkFlagRequired("repo")
-	releaseNotesCmd.MarkFlagRequired("from")
-	releaseNotesCmd.MarkFlagRequired("to")
 }
 
 // loadEnvrc loads environment variables from .envrc file
@@ -129,38 +175,96 @@ func loadEnvrc() error {
 }
 
 func runReleaseNotes(cmd *cobra.Command, args []string) error {
+	switch releaseNotesFormat() {
+	case git.FormatMarkdown, git.FormatMarkdownTable, git.FormatJSON, git.FormatText:
+	default:
+		return fmt.Errorf("invalid --format %q: use markdown, table, json or text", notesFormat)
+	}
+	if maxPerSection < 0 {
+		return fmt.Errorf("invalid --max-per-section %d", maxPerSection)
+	}
+	if templateFile != "" && releaseNotesFormat() == git.FormatJSON {
+		return fmt.Errorf("--template applies to the markdown, table and text formats, not json")
+	}
+
+	if _, err := git.ParseRangeMode(rangeMode); err != nil {
+		return err
+	}
+	since, until, err := notesPeriod(time.Now())
+	if err != nil {
+		return err
+	}
+
+	rules, err := classificationRules(activeFileConfig)
+	if err != nil {
+		return err
+	}
+
 	// Load environment variables from .envrc
 	if err := loadEnvrc(); err != nil {
-		fmt.Printf("⚠️  Warning: failed to load .envrc: %v\n", err)
+		ui.Printf("⚠️  Warning: failed to load .envrc: %v\n", err)
 	}
 
 	// Create work directory if it doesn't exist
-	if err := os.MkdirAll(releaseNotesWorkDir, 0755); err != nil {
+	if err := os.MkdirAll(workDir, 0755); err != nil {
 		return fmt.Errorf("failed to create work directory: %w", err)
 	}
 
-	// Load GitHub token from environment if not provided via flag
-	if githubToken == "" {
-		githubToken = os.Getenv("GITHUB_TOKEN")
+	unlock, err := lockWorkDir(workDir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := enforceRetention(workDir, ui.Printf); err != nil {
+		return err
+	}
+
+	var output string
+	var entries int
+	if localClone != "" {
+		// Generate release notes from the local clone, offline
+		output, entries, err = generateLocalReleaseNotesWithProgress(owner, repo, fromRef, toRef, localClone, since, until, rules)
+	} else {
+		// Load GitHub token from environment if not provided via flag
 		if githubToken == "" {
-			fmt.Println("⚠️  No GitHub token provided. Using unauthenticated requests (may hit rate limits)")
+			githubToken = config.GetEnv(config.EnvGitHubToken...)
+			if githubToken == "" {
+				ui.Println("⚠️  No GitHub token provided. Using unauthenticated requests (may hit rate limits)")
+			}
+		}
+
+		// Check the token before the many API calls of the generation
+		if err := preflightGitHub(context.Background(), githubToken, owner, repo); err != nil {
+			return err
 		}
-	}
 
-	// Generate release notes with progress bar
-	output, err := generateReleaseNotesWithProgress(owner, repo, fromRef, toRef, githubToken)
+		// Generate release notes with progress bar
+		output, entries, err = generateReleaseNotesWithProgress(owner, repo, fromRef, toRef, githubToken, rules)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to generate release notes: %w", err)
 	}
 
 	// Save to work directory
-	defaultFileName := fmt.Sprintf("release-notes-%s-%s-%s-%s.md", owner, repo, fromRef, toRef)
-	workFilePath := filepath.Join(releaseNotesWorkDir, defaultFileName)
+	// Branch names like release/4.14 would otherwise name a subdirectory
+	refName := strings.NewReplacer("/", "-").Replace
+	fromName := refName(fromRef)
+	if fromName == "" {
+		// The whole history of --to in the period
+		fromName = "start"
+		if !since.IsZero() {
+			fromName = since.Format("2006-01-02")
+		}
+	}
+	defaultFileName := fmt.Sprintf("release-notes-%s-%s-%s-%s%s", owner, repo, fromName, refName(toRef), notesExtension(releaseNotesFormat()))
+	workFilePath := filepath.Join(workDir, defaultFileName)
 
 	if err := os.WriteFile(workFilePath, []byte(output), 0644); err != nil {
 		return fmt.Errorf("failed to write file to work directory: %w", err)
 	}
-	fmt.Printf("💾 Release notes saved generated successfully: %s\n", workFilePath)
+	ui.Printf("💾 Release notes saved generated successfully: %s\n", workFilePath)
+	outputs := []string{workFilePath}
 
 	// If a specific output file is specified, also write there
 	if releaseOutput != "" {
@@ -168,10 +272,51 @@ func runReleaseNotes(cmd *cobra.Command, args []string) error {
 			if err := os.WriteFile(releaseOutput, []byte(output), 0644); err != nil {
 				return fmt.Errorf("failed to write output file: %w", err)
 			}
-			fmt.Printf("💾 Release notes also saved to: %s\n", releaseOutput)
+			ui.Printf("💾 Release notes also saved to: %s\n", releaseOutput)
+			outputs = append(outputs, releaseOutput)
 		}
 	}
 
+	inputs := map[string]string{
+		"repo":  owner + "/" + repo,
+		"from":  fromRef,
+		"to":    toRef,
+		"local": localClone,
+	}
+	if verifySigs {
+		inputs["verify-signatures"] = "true"
+	}
+	if ownership {
+		inputs["ownership-depth"] = strconv.Itoa(ownerDepth)
+	}
+	if rangeMode != string(git.RangeThreeDot) {
+		inputs["range"] = rangeMode
+	}
+	if !since.IsZero() {
+		inputs["since"] = since.Format(time.RFC3339)
+	}
+	if !until.IsZero() {
+		inputs["until"] = until.Format(time.RFC3339)
+	}
+	// The rendering, for history rerun to generate the same notes
+	if format := releaseNotesFormat(); format != git.FormatMarkdown {
+		inputs["format"] = string(format)
+	}
+	if sections {
+		inputs["sections"] = "true"
+	}
+	if summary {
+		inputs["summary"] = "true"
+	}
+	if compactJSON {
+		inputs["compact"] = "true"
+	}
+	if maxPerSection > 0 {
+		inputs["max-per-section"] = strconv.Itoa(maxPerSection)
+	}
+	inputs["template"] = templateFile
+	recordNotes(workDir, "release-notes", inputs, entries, outputs...)
+
 	// Show content on stdout only when --stdout flag is specified
 	if showStdout {
 		fmt.Println(output)
@@ -180,268 +325,290 @@ func runReleaseNotes(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-// getCommitsBetween gets all commits between two references using GitHub API
-func getCommitsBetween(owner, repo, fromRef, toRef, token string) ([]GitHubCommit, error) {
-	// Use GitHub compare API to get commits between two references
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/compare/%s...%s", owner, repo, fromRef, toRef)
-
-	req, err := http.NewRequestWithContext(context.Background(), "GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	// Add headers
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
-	req.Header.Set("User-Agent", "drivio-release-notes")
-	if token != "" {
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+// generateReleaseNotesWithProgress generates release notes with a progress bar
+func generateReleaseNotesWithProgress(owner, repo, fromRef, toRef, token string, rules []git.TypeRule) (string, int, error) {
+	ctx := context.Background()
+	analyzer := git.NewAnalyzer(token)
+	analyzer.VerifySignatures = verifySigs
+	analyzer.Range = git.RangeMode(rangeMode)
+
+	// Step 1: Getting commits between references
+	var commits []git.CommitInfo
+	var appendix releaseNotesAppendix
+	if err := ui.RunSpinner("Getting commits between references...", func() error {
+		var err error
+		appendix.mergeBase, appendix.diverged, err = analyzer.Diverged(ctx, owner, repo, fromRef, toRef)
+		if err != nil {
+			return err
+		}
+		commits, err = analyzer.Commits(ctx, owner, repo, fromRef, toRef)
+		return err
+	}); err != nil {
+		return "", 0, fmt.Errorf("failed to get commits: %w", err)
 	}
+	ui.Printf("✅ Found %d commits\n", len(commits))
+	appendix.reportDivergence()
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+	var err error
+	appendix.tags, err = verifyTagsWithSpinner(func(ref string) (*git.Verification, error) {
+		return analyzer.VerifyTag(ctx, owner, repo, ref)
+	})
 	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("GitHub API returned status %d", resp.StatusCode)
-	}
-
-	var compareResult struct {
-		Commits []GitHubCommit `json:"commits"`
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&compareResult); err != nil {
-		return nil, err
+		return "", 0, err
+	}
+
+	if ownership {
+		if err := ui.RunProgress("Computing code ownership...", ui.UnitItems, func(progress chan<- ui.ProgressMsg) error {
+			var err error
+			appendix.ownership, err = analyzer.Ownership(ctx, owner, repo, commits, ownerDepth, func(done, total int) {
+				progress <- ui.ProgressMsg{Current: int64(done), Total: int64(total)}
+			})
+			return err
+		}); err != nil {
+			return "", 0, fmt.Errorf("failed to compute code ownership: %w", err)
+		}
 	}
 
-	return compareResult.Commits, nil
+	classifier := git.NewClassifier(analyzer, owner, repo)
+	classifier.Rules = rules
+	return classifyAndFormat(ctx, owner, repo, fromRef, toRef, commits, appendix, classifier)
 }
 
-// getPRLabels gets the labels for a specific PR number
-func getPRLabels(owner, repo string, prNumber int, token string) ([]string, error) {
-	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/pulls/%d", owner, repo, prNumber)
-
-	req, err := http.NewRequestWithContext(context.Background(), "GET", url, nil)
+// generateLocalReleaseNotesWithProgress generates release notes from a local
+// clone. Pull request labels are only known to GitHub, so any pull request
+// naming a ticket is selected.
+func generateLocalReleaseNotesWithProgress(owner, repo, fromRef, toRef, dir string, since, until time.Time, rules []git.TypeRule) (string, int, error) {
+	ctx := context.Background()
+	analyzer, err := git.NewLocalAnalyzer(dir)
 	if err != nil {
-		return nil, err
+		return "", 0, err
 	}
+	analyzer.VerifySignatures = verifySigs
+	analyzer.Range = git.RangeMode(rangeMode)
+	analyzer.Since, analyzer.Until = since, until
 
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
-	req.Header.Set("User-Agent", "drivio-release-notes")
-	if token != "" {
-		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	// Step 1: Walking the commits between references
+	var commits []git.CommitInfo
+	appendix := releaseNotesAppendix{since: since, until: until}
+	if err := ui.RunSpinner("Walking commits between references...", func() error {
+		var err error
+		if fromRef != "" {
+			appendix.mergeBase, appendix.diverged, err = analyzer.Diverged(ctx, fromRef, toRef)
+			if err != nil {
+				return err
+			}
+		}
+		commits, err = analyzer.Commits(ctx, fromRef, toRef)
+		return err
+	}); err != nil {
+		return "", 0, fmt.Errorf("failed to get commits: %w", err)
 	}
+	ui.Printf("✅ Found %d commits in %s\n", len(commits), dir)
+	appendix.reportDivergence()
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+	appendix.tags, err = verifyTagsWithSpinner(func(ref string) (*git.Verification, error) {
+		return analyzer.VerifyTag(ctx, ref)
+	})
 	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("GitHub API returned status %d for PR %d", resp.StatusCode, prNumber)
+		return "", 0, err
 	}
 
-	var pr GitHubPR
-	if err := json.NewDecoder(resp.Body).Decode(&pr); err != nil {
-		return nil, err
+	if ownership {
+		if err := ui.RunSpinner("Computing code ownership...", func() error {
+			var err error
+			appendix.ownership, err = analyzer.Ownership(ctx, fromRef, toRef, ownerDepth)
+			return err
+		}); err != nil {
+			return "", 0, fmt.Errorf("failed to compute code ownership: %w", err)
+		}
 	}
 
-	var labels []string
-	for _, label := range pr.Labels {
-		labels = append(labels, label.Name)
-	}
+	classifier := &git.Classifier{TicketPattern: git.DefaultTicketPattern, Rules: rules}
+	return classifyAndFormat(ctx, owner, repo, fromRef, toRef, commits, appendix, classifier)
+}
 
-	return labels, nil
+// releaseNotesAppendix is what the release notes hold besides the changes,
+// when asked for by the flags
+type releaseNotesAppendix struct {
+	tags      map[string]*git.Verification
+	ownership *git.Ownership
+	// mergeBase is the best common ancestor of the references, noted when
+	// they have diverged
+	mergeBase string
+	diverged  bool
+	// since and until are the period of --since and --until
+	since time.Time
+	until time.Time
 }
 
-// extractPRNumber extracts PR number from merge commit message
-func extractPRNumber(message string) (int, bool) {
-	// Look for "Merge pull request #123 from" pattern
-	lines := strings.Split(message, "\n")
-	if len(lines) == 0 {
-		return 0, false
-	}
-
-	subject := strings.TrimSpace(lines[0])
-	// Pattern: "Merge pull request #123 from owner/branch"
-	if strings.HasPrefix(subject, "Merge pull request #") {
-		// Extract the part after "Merge pull request #"
-		afterPrefix := strings.TrimPrefix(subject, "Merge pull request #")
-		// Split by space to get the number
-		parts := strings.Split(afterPrefix, " ")
-		if len(parts) > 0 {
-			if prNumber, err := strconv.Atoi(parts[0]); err == nil {
-				return prNumber, true
-			}
-		}
+// reportDivergence tells which commits are listed when the references have
+// diverged
+func (a releaseNotesAppendix) reportDivergence() {
+	if !a.diverged {
+		return
 	}
-
-	return 0, false
+	listed := "those of " + toRef
+	if git.RangeMode(rangeMode) == git.RangeTwoDot {
+		listed = "those of both"
+	}
+	ui.Printf("🔀 %s and %s have diverged at %.8s; listing %s since then (--range %s)\n", fromRef, toRef, a.mergeBase, listed, rangeMode)
 }
 
-// generateReleaseNotesWithProgress generates release notes with a progress bar
-func generateReleaseNotesWithProgress(owner, repo, fromRef, toRef, token string) (string, error) {
-	var result string
-	var commits []GitHubCommit
-
-	// Step 1: Validating GitHub connection
-	if err := ui.RunSpinner("Validating GitHub connection...", func() error {
-		time.Sleep(500 * time.Millisecond) // Simulate validation
-		return nil
-	}); err != nil {
-		return "", err
+// verifyTagsWithSpinner verifies --from and --to when they are tags and
+// --verify-signatures is given
+func verifyTagsWithSpinner(verify func(ref string) (*git.Verification, error)) (map[string]*git.Verification, error) {
+	if !verifySigs {
+		return nil, nil
 	}
 
-	// Step 2: Getting commits between references
-	if err := ui.RunSpinner("Getting commits between references...", func() error {
+	var tags map[string]*git.Verification
+	if err := ui.RunSpinner("Verifying tag signatures...", func() error {
 		var err error
-		commits, err = getCommitsBetween(owner, repo, fromRef, toRef, token)
+		tags, err = git.VerifyTags(verify, fromRef, toRef)
 		return err
 	}); err != nil {
-		return "", fmt.Errorf("failed to get commits: %w", err)
-	}
-	fmt.Printf("✅ Found %d commits\n", len(commits))
-
-	// Step 3: Filtering commits by label and format
-	var filteredCommits []struct {
-		hash   string
-		ticket string
-		desc   string
+		return nil, fmt.Errorf("failed to verify tags: %w", err)
 	}
+	return tags, nil
+}
 
-	if err := ui.RunSpinner("Filtering commits by label and format...", func() error {
-		filteredCommits = filterCommitsByLabelAndFormat(commits, owner, repo, token)
+// classifyAndFormat selects the commits worth noting and renders the release
+// notes in the format selected by the flags, returning them and the number
+// of changes
+func classifyAndFormat(ctx context.Context, owner, repo, fromRef, toRef string, commits []git.CommitInfo, appendix releaseNotesAppendix, classifier *git.Classifier) (string, int, error) {
+	// Step 2: Filtering commits by label and format
+	var selected []git.CommitInfo
+	if err := ui.RunProgress("Filtering commits by label and format...", ui.UnitItems, func(progress chan<- ui.ProgressMsg) error {
+		classifier.Progress = func(done, total int, message string) {
+			progress <- ui.ProgressMsg{Current: int64(done), Total: int64(total), Message: message}
+		}
+		selected = classifier.Classify(ctx, commits)
 		return nil
 	}); err != nil {
-		return "", err
+		return "", 0, err
 	}
-	fmt.Printf("✅ Found %d relevant commits\n", len(filteredCommits))
+	ui.Printf("✅ Found %d relevant commits\n", len(selected))
 
-	// Step 4: Generating release notes
+	// Step 3: Generating release notes
+	format := releaseNotesFormat()
+	notes := git.NewReleaseNotes(owner, repo, fromRef, toRef, commits, selected)
+	notes.Tags = appendix.tags
+	notes.Ownership = appendix.ownership
+	if appendix.diverged {
+		notes.SetMergeBase(appendix.mergeBase, git.RangeMode(rangeMode))
+	}
+	notes.SetPeriod(appendix.since, appendix.until)
+	warnUnverified(notes)
+	var result string
 	if err := ui.RunSpinner("Generating release notes...", func() error {
-		result = generateReleaseNotesContent(filteredCommits, fromRef, toRef, owner, repo)
-		return nil
+		var err error
+		var opts []git.FormatterOption
+		if templateFile != "" {
+			opts = append(opts, git.WithTemplateFiles(templateFile))
+		}
+		formatter := git.NewFormatter(format, opts...)
+		formatter.Compact = compactJSON
+		formatter.Sections = sections
+		formatter.Summary = summary
+		formatter.MaxPerSection = maxPerSection
+		result, err = formatter.Format(notes)
+		return err
 	}); err != nil {
-		return "", err
+		return "", 0, err
 	}
 
-	return result, nil
+	return result, len(selected), nil
 }
 
-// filterCommitsByLabelAndFormat filters commits by label and ticket format
-func filterCommitsByLabelAndFormat(commits []GitHubCommit, owner, repo, token string) []struct {
-	hash   string
-	ticket string
-	desc   string
-} {
-	var filteredCommits []struct {
-		hash   string
-		ticket string
-		desc   string
-	}
-	ticketPattern := regexp.MustCompile(`^[A-Z]+-\d+:\s.+`)
-	targetLabel := "area/hypershift-operator"
-
-	for _, commit := range commits {
-		lines := strings.Split(commit.Commit.Message, "\n")
-		if len(lines) == 0 {
-			continue
-		}
-		subject := strings.TrimSpace(lines[0])
-
-		// Only process merge commits
-		if !strings.HasPrefix(subject, "Merge pull request") {
-			continue
-		}
-
-		// Extract PR number from merge commit message
-		prNumber, ok := extractPRNumber(commit.Commit.Message)
-		if !ok {
-			continue
+// warnUnverified reports the commits and tags whose signature failed
+// verification
+func warnUnverified(notes *git.ReleaseNotes) {
+	if !verifySigs {
+		return
+	}
+	for _, tag := range []string{notes.FromRef, notes.ToRef} {
+		if v, ok := notes.Tags[tag]; ok && !v.Verified {
+			ui.Printf("⚠️  Tag %s is not verified: %s\n", tag, v.Reason)
 		}
+	}
+	if notes.Statistics.Unverified > 0 {
+		ui.Printf("⚠️  %d of %d commits are not verified\n", notes.Statistics.Unverified, notes.Statistics.Total)
+	} else {
+		ui.Printf("🔏 All %d commits are verified\n", notes.Statistics.Total)
+	}
+}
 
-		// Get PR labels
-		labels, err := getPRLabels(owner, repo, prNumber, token)
-		if err != nil {
-			continue
+// notesPeriod returns the period of --since and --until, zero when not
+// set, and checks the references required without a period
+func notesPeriod(now time.Time) (time.Time, time.Time, error) {
+	var since, until time.Time
+	if notesSince == "" && notesUntil == "" {
+		if fromRef == "" || toRef == "" {
+			return since, until, fmt.Errorf("--from and --to are required, unless --since or --until is given with --local")
 		}
+		return since, until, nil
+	}
+	if localClone == "" {
+		return since, until, fmt.Errorf("--since and --until need --local: the GitHub API only compares references")
+	}
 
-		// Check if PR has the target label
-		hasTargetLabel := false
-		for _, label := range labels {
-			if label == targetLabel {
-				hasTargetLabel = true
-				break
-			}
+	var err error
+	if notesSince != "" {
+		if since, err = parseNotesDate(notesSince, now, false); err != nil {
+			return since, until, fmt.Errorf("invalid --since: %w", err)
 		}
-
-		if !hasTargetLabel {
-			continue
+	}
+	if notesUntil != "" {
+		if until, err = parseNotesDate(notesUntil, now, true); err != nil {
+			return since, until, fmt.Errorf("invalid --until: %w", err)
 		}
+	}
+	if !since.IsZero() && !until.IsZero() && until.Before(since) {
+		return since, until, fmt.Errorf("--until %s is before --since %s", notesUntil, notesSince)
+	}
+	if toRef == "" {
+		toRef = "HEAD"
+	}
+	return since, until, nil
+}
 
-		// Search for ticket line in the rest of the message
-		for _, line := range lines[1:] {
-			line = strings.TrimSpace(line)
-			if ticketPattern.MatchString(line) {
-				// ticketAndDesc: <TICKET>: <desc>
-				ticketParts := strings.SplitN(line, ":", 2)
-				if len(ticketParts) == 2 {
-					ticket := strings.TrimSpace(ticketParts[0])
-					desc := strings.TrimSpace(ticketParts[1])
-
-					filteredCommits = append(filteredCommits, struct {
-						hash   string
-						ticket string
-						desc   string
-					}{
-						hash:   commit.Sha[:8],
-						ticket: ticket,
-						desc:   desc,
-					})
-				}
-				break
-			}
+// parseNotesDate parses a date (2024-01-31), a time (2024-01-31T12:00:00Z)
+// or an age before now (2w). A date until which changes are kept includes
+// the whole day.
+func parseNotesDate(value string, now time.Time, endOfDay bool) (time.Time, error) {
+	if date, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		if endOfDay {
+			date = date.AddDate(0, 0, 1).Add(-time.Second)
 		}
+		return date, nil
 	}
-
-	return filteredCommits
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	age, err := workdir.ParseAge(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a date (2024-01-31), a time (2024-01-31T12:00:00Z) or an age (2w)", value)
+	}
+	return now.Add(-age), nil
 }
 
-// generateReleaseNotesContent generates the markdown content for release notes
-func generateReleaseNotesContent(filteredCommits []struct {
-	hash   string
-	ticket string
-	desc   string
-}, fromRef, toRef, owner, repo string) string {
-	var output strings.Builder
-	output.WriteString(fmt.Sprintf("# Release notes from %s to %s\n\n", fromRef, toRef))
-
+// releaseNotesFormat returns the format selected by --format, or by --table
+func releaseNotesFormat() git.OutputFormat {
 	if useTable {
-		// Generate table format
-		output.WriteString("| Commit | JIRA | Description |\n")
-		output.WriteString("|--------|------|-------------|\n")
-
-		for _, commit := range filteredCommits {
-			commitURL := fmt.Sprintf("https://github.com/%s/%s/commit/%s", owner, repo, commit.hash)
-			ticketURL := fmt.Sprintf("https://issues.redhat.com/browse/%s", commit.ticket)
-
-			output.WriteString(fmt.Sprintf("| [%s](%s) | [%s](%s) | %s |\n",
-				commit.hash, commitURL, commit.ticket, ticketURL, commit.desc))
-		}
-	} else {
-		// Generate list format (current format)
-		for _, commit := range filteredCommits {
-			commitURL := fmt.Sprintf("https://github.com/%s/%s/commit/%s", owner, repo, commit.hash)
-			ticketURL := fmt.Sprintf("https://issues.redhat.com/browse/%s", commit.ticket)
-
-			output.WriteString(fmt.Sprintf("[%s](%s) - [%s](%s): %s\n",
-				commit.hash, commitURL, commit.ticket, ticketURL, commit.desc))
-		}
+		return git.FormatMarkdownTable
 	}
+	return git.OutputFormat(strings.ToLower(notesFormat))
+}
 
-	return output.String()
+// notesExtension returns the extension of the release notes file written in
+// the work directory
+func notesExtension(format git.OutputFormat) string {
+	switch format {
+	case git.FormatJSON:
+		return ".json"
+	case git.FormatText:
+		return ".txt"
+	default:
+		return ".md"
+	}
 }
@@ -0,0 +1,369 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"drivio/pkg/audit"
+	"drivio/pkg/config"
+	"drivio/pkg/git"
+	"drivio/pkg/github"
+	"drivio/pkg/notify"
+	"drivio/pkg/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	releaseOwner       string
+	releaseRepo        string
+	releaseSHA         string
+	releaseBranch      string
+	releaseFrom        string
+	releaseTagPrefix   string
+	releaseVersion     string
+	releaseIncrement   string
+	releaseMessage     string
+	releaseName        string
+	releaseDraft       bool
+	releaseSections    bool
+	releaseTemplate    string
+	releaseNotesOutput string
+	releaseNotify      []string
+	releaseWebhooks    []string
+	releaseGitHubToken string
+	releaseSkipTag     bool
+	releaseSkipRelease bool
+	releaseSkipNotify  bool
+	releaseDryRun      bool
+)
+
+// releaseCmd represents the release command
+var releaseCmd = &cobra.Command{
+	Use:   "release",
+	Short: "Tag, publish and announce the next release of a repository",
+	Long: `Run the whole release of a GitHub repository in one command:
+
+  1. find the previous release tag and the next version, as bump does
+  2. generate the release notes of the changes since the previous release
+  3. tag the next version, on --sha or the head of --branch
+  4. create the GitHub release of the tag, with the notes as description
+  5. notify the release to the --notify channels and --notify-webhook URLs
+
+The version is computed as by bump: --version sets it, --increment names the
+part to increment, and otherwise it comes from the changes. --tag-prefix
+selects the tags of a component of a monorepo, e.g. api/v. The notes are
+generated as by release-notes, grouped by type unless --sections=false, and
+saved in the work directory.
+
+--skip-tag, --skip-release and --skip-notify skip a step, e.g. --skip-tag to
+release a tag pushed by CI: --version then names the existing tag. --dry-run
+prints the version and the notes without creating or sending anything.
+
+--notify names a notification channel of the configuration file, or gives
+one as TYPE:URL (see drivio notify); the notify key of the configuration file
+sets the default channels. --notify-webhook URLs receive a JSON object whose
+text field is the announcement, as Slack incoming webhooks expect, with title
+and url fields for other receivers.
+
+Examples:
+  drivio release --owner myorg --repo myrepo --dry-run
+  drivio release --owner myorg --repo myrepo --notify team-slack --notify releases-mail
+  drivio release --owner myorg --repo myrepo --increment minor --draft
+  drivio release --owner myorg --repo myrepo --version v1.4.0 --skip-tag`,
+	RunE: runRelease,
+}
+
+func init() {
+	rootCmd.AddCommand(releaseCmd)
+
+	// Add flags
+	releaseCmd.Flags().StringVar(&releaseOwner, "owner", "", "GitHub repository owner/organization")
+	releaseCmd.Flags().StringVar(&releaseRepo, "repo", "", "GitHub repository name")
+	releaseCmd.Flags().StringVar(&releaseSHA, "sha", "", "Commit to tag (default: the head of --branch)")
+	releaseCmd.Flags().StringVar(&releaseBranch, "branch", "", "Branch to tag the head of (default: the default branch)")
+	releaseCmd.Flags().StringVar(&releaseFrom, "from", "", "Tag of the previous release (default: the latest release tag)")
+	releaseCmd.Flags().StringVar(&releaseTagPrefix, "tag-prefix", "", "Prefix of the release tags of the component to release in a monorepo, e.g. api/v (default: the tags of the whole repository)")
+	releaseCmd.Flags().StringVar(&releaseVersion, "version", "", "Version to release (default: computed from the previous version)")
+	releaseCmd.Flags().StringVar(&releaseIncrement, "increment", "", "Part of the version to increment: major, minor or patch (default: from the changes)")
+	releaseCmd.Flags().StringVar(&releaseMessage, "message", "", "Message of the tag (default: Release <version>)")
+	releaseCmd.Flags().StringVar(&releaseName, "name", "", "Name of the GitHub release (default: the version)")
+	releaseCmd.Flags().BoolVar(&releaseDraft, "draft", false, "Create the GitHub release as a draft")
+	releaseCmd.Flags().BoolVar(&releaseSections, "sections", true, "Group the changes of the notes by type")
+	releaseCmd.Flags().StringVar(&releaseTemplate, "template", "", "Go template file rendering the notes")
+	releaseCmd.Flags().StringVar(&releaseNotesOutput, "output", "", "Also write the notes to this file")
+	releaseCmd.Flags().StringArrayVar(&releaseNotify, "notify", nil, "Notification channel to announce the release to: a channel of the config file, or TYPE:URL; repeatable")
+	releaseCmd.Flags().StringArrayVar(&releaseWebhooks, "notify-webhook", nil, "Webhook URL to notify the release to, e.g. a Slack incoming webhook; repeatable")
+	releaseCmd.Flags().StringVar(&releaseGitHubToken, "github-token", "", "GitHub token with write access to the repository contents")
+	releaseCmd.Flags().BoolVar(&releaseSkipTag, "skip-tag", false, "Do not create the tag: release the existing tag --version")
+	releaseCmd.Flags().BoolVar(&releaseSkipRelease, "skip-release", false, "Do not create the GitHub release")
+	releaseCmd.Flags().BoolVar(&releaseSkipNotify, "skip-notify", false, "Do not send the notifications")
+	releaseCmd.Flags().BoolVar(&releaseDryRun, "dry-run", false, "Print the version and the notes without creating or sending anything")
+
+	// Environment variables that take precedence over the config file
+	bindFlagEnv(releaseCmd.Flags(), "github-token", config.EnvGitHubToken...)
+
+	// Mark required flags
+	releaseCmd.MarkFlagRequired("owner")
+	releaseCmd.MarkFlagRequired("repo")
+}
+
+func runRelease(cmd *cobra.Command, args []string) error {
+	if releaseVersion != "" && releaseIncrement != "" {
+		return fmt.Errorf("--version and --increment are exclusive")
+	}
+	if releaseSkipTag && releaseVersion == "" {
+		return fmt.Errorf("--skip-tag releases an existing tag: give it with --version")
+	}
+	rules, err := classificationRules(activeFileConfig)
+	if err != nil {
+		return err
+	}
+	// Resolved first, so a typo in a channel fails before anything is
+	// released
+	var channels []notifyChannel
+	if !releaseSkipNotify {
+		values := releaseNotify
+		for _, webhook := range releaseWebhooks {
+			values = append(values, notify.TypeWebhook+":"+webhook)
+		}
+		if channels, err = notifyChannels(values); err != nil {
+			return err
+		}
+	}
+
+	ctx := context.Background()
+	client := github.NewClient(releaseGitHubToken)
+	if !releaseDryRun {
+		if err := preflightGitHub(ctx, releaseGitHubToken, releaseOwner, releaseRepo); err != nil {
+			return err
+		}
+	}
+	analyzer := git.NewAnalyzerWithClient(client)
+	repository := githubBumpRepository{client: client, analyzer: analyzer, owner: releaseOwner, repo: releaseRepo}
+
+	// Step 1: Find the previous release and the next version
+	var sha, previousTag string
+	var previous git.Version
+	var tags []string
+	if err := ui.RunSpinner("Finding the previous release...", func() error {
+		var err error
+		if tags, err = repository.tags(ctx); err != nil {
+			return err
+		}
+		switch {
+		case releaseSkipTag:
+			if sha, err = repository.resolve(ctx, releaseVersion); err != nil {
+				return err
+			}
+		case releaseSHA != "":
+			if sha, err = repository.resolve(ctx, releaseSHA); err != nil {
+				return err
+			}
+		default:
+			if _, sha, err = repository.head(ctx, releaseBranch); err != nil {
+				return err
+			}
+		}
+		if releaseFrom != "" {
+			previousTag = releaseFrom
+			previous, err = git.ParseVersion(releaseFrom)
+			return err
+		}
+		previousTag, previous, err = git.PreviousVersion(tags, releaseVersion, releaseTagPrefix)
+		return err
+	}); err != nil {
+		return err
+	}
+	if previousTag == "" {
+		return fmt.Errorf("no previous release tag to generate the notes from: give it with --from")
+	}
+
+	next, reason, err := nextVersion(ctx, repository, previousTag, previous, sha, releaseVersion, releaseIncrement, releaseTagPrefix, rules)
+	if err != nil {
+		return err
+	}
+	if next.Compare(previous) <= 0 {
+		return fmt.Errorf("version %s does not follow the previous version %s", next, previousTag)
+	}
+	if !releaseSkipTag {
+		for _, tag := range tags {
+			if tag == next.String() {
+				return fmt.Errorf("tag %s already exists: release it with --skip-tag", tag)
+			}
+		}
+	}
+	ui.Printf("🔢 %s → %s (%s)\n", previousTag, next, reason)
+
+	// Step 2: Generate the release notes
+	var output string
+	var notes *git.ReleaseNotes
+	if err := ui.RunSpinner(fmt.Sprintf("Generating the release notes since %s...", previousTag), func() error {
+		classifier := git.NewClassifier(analyzer, releaseOwner, releaseRepo)
+		classifier.Rules = rules
+		var err error
+		if notes, err = analyzer.GenerateReleaseNotes(ctx, releaseOwner, releaseRepo, previousTag, sha, classifier); err != nil {
+			return err
+		}
+		// The notes are those of the tag about to be created
+		notes.ToRef = next.String()
+
+		var opts []git.FormatterOption
+		if releaseTemplate != "" {
+			opts = append(opts, git.WithTemplateFiles(releaseTemplate))
+		}
+		formatter := git.NewFormatter(git.FormatMarkdown, opts...)
+		formatter.Sections = releaseSections
+		output, err = formatter.Format(notes)
+		return err
+	}); err != nil {
+		return fmt.Errorf("failed to generate release notes: %w", err)
+	}
+	ui.Printf("✅ %d relevant changes out of %d commits\n", notes.Statistics.Selected, notes.Statistics.Total)
+
+	if releaseDryRun {
+		for _, step := range releaseSteps(len(channels)) {
+			ui.Printf("🔎 Would %s\n", step)
+		}
+		fmt.Println(next)
+		fmt.Println()
+		fmt.Println(output)
+		return nil
+	}
+
+	// Create work directory if it doesn't exist
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return fmt.Errorf("failed to create work directory: %w", err)
+	}
+
+	unlock, err := lockWorkDir(workDir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := enforceRetention(workDir, ui.Printf); err != nil {
+		return err
+	}
+
+	refName := strings.NewReplacer("/", "-").Replace
+	notesPath := filepath.Join(workDir, fmt.Sprintf("release-notes-%s-%s-%s-%s.md", releaseOwner, releaseRepo, refName(previousTag), refName(next.String())))
+	if err := os.WriteFile(notesPath, []byte(output), 0644); err != nil {
+		return fmt.Errorf("failed to write file to work directory: %w", err)
+	}
+	ui.Printf("💾 Release notes saved: %s\n", notesPath)
+	outputs := []string{notesPath}
+	if releaseNotesOutput != "" && releaseNotesOutput != notesPath {
+		if err := os.WriteFile(releaseNotesOutput, []byte(output), 0644); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+		outputs = append(outputs, releaseNotesOutput)
+	}
+	inputs := map[string]string{
+		"repo":    releaseOwner + "/" + releaseRepo,
+		"from":    previousTag,
+		"version": next.String(),
+		"sha":     sha,
+	}
+	defer func() {
+		recordArtifacts(workDir, "release", inputs, outputs...)
+	}()
+
+	// Step 3: Tag the next version
+	if !releaseSkipTag {
+		message := releaseMessage
+		if message == "" {
+			message = "Release " + next.String()
+		}
+		if err := ui.RunSpinner(fmt.Sprintf("Tagging %s...", next), func() error {
+			return repository.tag(ctx, next.String(), message, sha)
+		}); err != nil {
+			return err
+		}
+		ui.Printf("🏷️  Tagged %s on %s\n", next, shortSHA(sha))
+		auditAction(audit.ActionTag, releaseOwner+"/"+releaseRepo+"@"+next.String(), "", map[string]string{"sha": sha})
+	}
+
+	// Step 4: Publish the notes as the GitHub release
+	releaseURL := fmt.Sprintf("https://github.com/%s/%s/releases/tag/%s", releaseOwner, releaseRepo, next)
+	if !releaseSkipRelease {
+		name := releaseName
+		if name == "" {
+			name = next.String()
+		}
+		var release *github.Release
+		if err := ui.RunSpinner(fmt.Sprintf("Creating the release %s...", name), func() error {
+			var err error
+			release, err = client.CreateRelease(ctx, releaseOwner, releaseRepo, github.ReleaseOptions{
+				Tag:        next.String(),
+				Name:       name,
+				Body:       output,
+				Draft:      releaseDraft,
+				Prerelease: next.Prerelease != "",
+			})
+			return err
+		}); err != nil {
+			return err
+		}
+		releaseURL = release.HTMLURL
+		inputs["release"] = releaseURL
+		ui.Printf("📦 Release published: %s\n", releaseURL)
+		auditAction(audit.ActionPublish, releaseOwner+"/"+releaseRepo+"@"+next.String(), releaseURL, map[string]string{"name": name, "from": previousTag})
+	}
+
+	// Step 5: Notify
+	if len(channels) > 0 {
+		msg := notify.Message{
+			Title: fmt.Sprintf("%s/%s %s released", releaseOwner, releaseRepo, next),
+			Text:  releaseSummary(notes),
+			URL:   releaseURL,
+			Notes: output,
+		}
+		// The release is done: failures are reported without undoing it
+		if failed := sendNotification(ctx, channels, msg); failed > 0 {
+			return fmt.Errorf("%s released, but %d of %d notifications failed", next, failed, len(channels))
+		}
+	}
+
+	fmt.Println(next)
+	return nil
+}
+
+// releaseSteps describes the steps release would run after the notes, for
+// --dry-run
+func releaseSteps(channels int) []string {
+	var steps []string
+	if !releaseSkipTag {
+		steps = append(steps, "create the tag")
+	}
+	if !releaseSkipRelease {
+		steps = append(steps, "create the GitHub release with the notes")
+	}
+	if channels > 0 {
+		steps = append(steps, fmt.Sprintf("notify %d channel(s)", channels))
+	}
+	return steps
+}
+
+// releaseSummary describes the changes of a release in a line, e.g. "12
+// changes: 3 feat, 8 fix, 1 breaking"
+func releaseSummary(notes *git.ReleaseNotes) string {
+	stats := notes.Statistics
+	var parts []string
+	for _, kind := range []string{"feat", "fix"} {
+		if n := stats.ByType[kind]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, kind))
+		}
+	}
+	if stats.Breaking > 0 {
+		parts = append(parts, fmt.Sprintf("%d breaking", stats.Breaking))
+	}
+	summary := fmt.Sprintf("%d changes", stats.Selected)
+	if len(parts) > 0 {
+		summary += ": " + strings.Join(parts, ", ")
+	}
+	return summary
+}
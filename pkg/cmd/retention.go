@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"drivio/pkg/config"
+	"drivio/pkg/ui"
+	"drivio/pkg/workdir"
+)
+
+// Keys of the limits in the retention section of the configuration file
+const (
+	retentionMaxAge  = "max-age"
+	retentionMaxSize = "max-size"
+)
+
+// retentionPolicy reads the retention policy of the configuration file,
+// e.g.
+//
+//	retention:
+//	  max-age: 30d
+//	  max-size: 2GB
+//	  archives:
+//	    max-age: 7d
+func retentionPolicy(fileConfig *config.FileConfig) (workdir.Policy, error) {
+	policy := workdir.Policy{Types: make(map[string]workdir.Limits)}
+
+	limits, err := retentionLimits(fileConfig, config.RetentionKey+".")
+	if err != nil {
+		return policy, err
+	}
+	policy.Limits = limits
+
+	for _, t := range workdir.Types() {
+		limits, err := retentionLimits(fileConfig, config.RetentionKey+"."+t+".")
+		if err != nil {
+			return policy, err
+		}
+		if !limits.IsZero() {
+			policy.Types[t] = limits
+		}
+	}
+	return policy, nil
+}
+
+// retentionLimits reads the limits under a prefix of the retention section
+func retentionLimits(fileConfig *config.FileConfig, prefix string) (workdir.Limits, error) {
+	var limits workdir.Limits
+	if fileConfig.IsSet(prefix + retentionMaxAge) {
+		age, err := workdir.ParseAge(fileConfig.GetString(prefix + retentionMaxAge))
+		if err != nil {
+			return limits, fmt.Errorf("invalid %s%s in config file: %w", prefix, retentionMaxAge, err)
+		}
+		limits.MaxAge = age
+	}
+	if fileConfig.IsSet(prefix + retentionMaxSize) {
+		size, err := workdir.ParseSize(fileConfig.GetString(prefix + retentionMaxSize))
+		if err != nil {
+			return limits, fmt.Errorf("invalid %s%s in config file: %w", prefix, retentionMaxSize, err)
+		}
+		limits.MaxSize = size
+	}
+	return limits, nil
+}
+
+// isRetentionSetting reports whether the keys below the retention section
+// name a limit, e.g. max-age or notes.max-size
+func isRetentionSetting(keys []string) bool {
+	switch len(keys) {
+	case 1:
+	case 2:
+		known := false
+		for _, t := range workdir.Types() {
+			known = known || keys[0] == t
+		}
+		if !known {
+			return false
+		}
+	default:
+		return false
+	}
+	limit := keys[len(keys)-1]
+	return limit == retentionMaxAge || limit == retentionMaxSize
+}
+
+// enforceRetention removes the entries of the work directory, and of the
+// cache and data directories, exceeding the retention policy of the
+// configuration file, reporting what was removed through status
+func enforceRetention(workDir string, status func(format string, a ...interface{})) error {
+	if activeFileConfig == nil {
+		return nil
+	}
+	policy, err := retentionPolicy(activeFileConfig)
+	if err != nil || policy.IsZero() {
+		return err
+	}
+
+	for _, dir := range managedDirs(workDir) {
+		if _, err := os.Stat(dir); err != nil {
+			continue
+		}
+		entries, err := workdir.List(dir)
+		if err != nil {
+			return err
+		}
+
+		removed, freed := 0, int64(0)
+		for _, entry := range policy.Select(entries, time.Now()) {
+			if err := os.RemoveAll(entry.Path); err != nil {
+				ui.Fprintf(os.Stderr, "⚠️  Warning: failed to remove %s: %v\n", entry.Path, err)
+				continue
+			}
+			ui.Debugf(1, "Retention policy removed %s", entry.Path)
+			removed++
+			freed += entry.Size
+		}
+		if removed > 0 {
+			status("🧹 Retention policy removed %d entries (%s) from %s\n", removed, ui.FormatSize(freed), dir)
+		}
+	}
+	pruneArtifactIndex(workDir)
+	return nil
+}
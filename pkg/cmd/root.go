@@ -1,9 +1,21 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"drivio/pkg/config"
+	"drivio/pkg/lock"
+	"drivio/pkg/telemetry"
+	"drivio/pkg/ui"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var (
@@ -11,8 +23,35 @@ var (
 	Version    = "0.1.0"
 	CommitHash = "unknown"
 	BuildTime  = "unknown"
+
+	// lockTimeout is how long to wait for a work directory locked by
+	// another drivio process
+	lockTimeout time.Duration
+
+	// cfgFile is the path of the configuration file
+	cfgFile string
+	// profile is the configuration profile to use
+	profile string
+
+	// quiet suppresses spinners, progress bars and status messages
+	quiet bool
+	// noProgress replaces spinners and progress bars with timestamped lines
+	noProgress bool
+	// noColor disables colors and replaces emoji with text markers
+	noColor bool
+	// logFile receives every debug message, whatever the verbosity
+	logFile string
+	// ascii restricts the output to ASCII characters
+	ascii bool
+	// verbose is the level of the debug messages: 1 with -v, 2 with -vv,
+	// which also logs every HTTP request
+	verbose int
 )
 
+// envAnnotation is the flag annotation listing the environment variables
+// that take precedence over the configuration file for that flag
+const envAnnotation = "drivio_env"
+
 var rootCmd = &cobra.Command{
 	Use:   "drivio",
 	Short: "Drivio - CLI tool for production environment updates",
@@ -30,14 +69,321 @@ Examples:
   drivio fetch --validate-only
   drivio --version`,
 	Version: fmt.Sprintf("%s (commit: %s, built: %s)", Version, CommitHash, BuildTime),
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return prepareCommand(cmd, true)
+	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() error {
-	return rootCmd.Execute()
+	if ran, err := runPluginCommand(os.Args[1:]); ran {
+		// The plugin reported its own errors: only its exit status is kept
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.ExitCode())
+		}
+		return err
+	}
+
+	err := rootCmd.Execute()
+	if err != nil {
+		ui.Logf("Error: %v", err)
+	}
+	telemetry.EndCommand(err)
+	shutdownTelemetry()
+	ui.CloseLogFile()
+
+	var statusErr *exitStatusError
+	if errors.As(err, &statusErr) {
+		os.Exit(statusErr.status)
+	}
+	return err
+}
+
+// exitStatusError makes drivio exit with its status rather than 1, for the
+// commands telling pipelines more than success or failure
+type exitStatusError struct {
+	status int
+	err    error
+}
+
+func (e *exitStatusError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitStatusError) Unwrap() error {
+	return e.err
 }
 
 func init() {
 	// Here you can define your flags and configuration settings
-	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.drivio.yaml)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.drivio.yaml)")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "Configuration profile to use (default: current-profile of the config file)")
+	rootCmd.PersistentFlags().DurationVar(&lockTimeout, "lock-timeout", 0, "How long to wait for a work directory used by another drivio process (default: fail immediately)")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Only print errors and results, no progress or status messages")
+	rootCmd.PersistentFlags().BoolVar(&noProgress, "no-progress", false, "Print timestamped status lines instead of spinners and progress bars (default when neither stdout nor stderr is a terminal)")
+	rootCmd.PersistentFlags().StringVar(&workDir, "work-dir", ".drivio-work", "Working directory for downloaded and generated files")
+	rootCmd.PersistentFlags().StringVar(&cacheDir, "cache-dir", "", "Directory of the caches, e.g. repository archives (default: the work directory)")
+	rootCmd.PersistentFlags().StringVar(&dataDir, "data-dir", "", "Directory of the data kept across runs, e.g. the fetch history (default: the work directory)")
+	rootCmd.PersistentFlags().BoolVar(&xdgDirs, "xdg", false, "Default --cache-dir and --data-dir to the XDG base directories ($XDG_CACHE_HOME/drivio, $XDG_DATA_HOME/drivio)")
+	rootCmd.PersistentFlags().StringVar(&logFile, "log-file", "", "Append full debug output, including HTTP requests, to this file (e.g. .drivio-work/drivio.log)")
+	rootCmd.PersistentFlags().BoolVar(&ascii, "ascii", false, "Use ASCII spinners and progress bars and no emoji (default: detected from the locale and terminal)")
+	rootCmd.PersistentFlags().CountVarP(&verbose, "verbose", "v", "Print debug messages on stderr; -vv also logs every HTTP request")
+	rootCmd.PersistentFlags().BoolVar(&noColor, "no-color", false, "Disable colors and emoji in the output (also set by the NO_COLOR environment variable)")
+
+	// Environment variables that take precedence over the config file
+	bindFlagEnv(rootCmd.PersistentFlags(), "config", config.EnvConfigFile...)
+	bindFlagEnv(rootCmd.PersistentFlags(), "profile", config.EnvProfile...)
+	bindFlagEnv(rootCmd.PersistentFlags(), "quiet", config.EnvQuiet...)
+	bindFlagEnv(rootCmd.PersistentFlags(), "no-progress", config.EnvNoProgress...)
+	bindFlagEnv(rootCmd.PersistentFlags(), "no-color", config.EnvNoColor...)
+	bindFlagEnv(rootCmd.PersistentFlags(), "verbose", config.EnvVerbose...)
+	bindFlagEnv(rootCmd.PersistentFlags(), "ascii", config.EnvASCII...)
+	bindFlagEnv(rootCmd.PersistentFlags(), "log-file", config.EnvLogFile...)
+	bindFlagEnv(rootCmd.PersistentFlags(), "work-dir", config.EnvWorkDir...)
+	bindFlagEnv(rootCmd.PersistentFlags(), "cache-dir", config.EnvCacheDir...)
+	bindFlagEnv(rootCmd.PersistentFlags(), "data-dir", config.EnvDataDir...)
+	bindFlagEnv(rootCmd.PersistentFlags(), "xdg", config.EnvXDG...)
+}
+
+// prepareCommand fills the flags the command line left unset from the
+// environment and, when useConfigFile is set, from the configuration file,
+// then sets up the output
+func prepareCommand(cmd *cobra.Command, useConfigFile bool) error {
+	explicit := explicitFlags(cmd)
+	commandLine = auditCommandLine(cmd)
+	// Set early to report where the settings come from
+	ui.SetVerbosity(verbose)
+	if err := applyEnvironment(cmd); err != nil {
+		return err
+	}
+	ui.SetVerbosity(verbose)
+	// Opened before reading the config file to log where settings come
+	// from, and again in case the config file sets it
+	if err := openLogFile(cmd); err != nil {
+		return err
+	}
+	if useConfigFile {
+		if err := applyConfigFile(cmd); err != nil {
+			return err
+		}
+		if err := applyInstance(cmd, explicit); err != nil {
+			return err
+		}
+		if err := openLogFile(cmd); err != nil {
+			return err
+		}
+	}
+
+	if err := resolveDirs(); err != nil {
+		return err
+	}
+
+	ui.SetOutputMode(ui.DetectOutputMode(quiet, noProgress))
+	// NO_COLOR disables color with any non-empty value, so it is not bound
+	// to the flag like the other environment variables
+	ui.SetColor(!noColor && !ui.NoColorRequested())
+	if cmd.Flags().Changed("ascii") {
+		ui.SetASCII(ascii)
+	} else {
+		ui.SetASCII(ui.DetectASCII())
+	}
+	ui.SetVerbosity(verbose)
+	return startTelemetry(cmd)
+}
+
+// telemetryShutdown flushes the telemetry and stops its export
+var telemetryShutdown func(context.Context) error
+
+// startTelemetry starts exporting the traces and metrics when an OTLP
+// endpoint is configured, and the span of the command run
+func startTelemetry(cmd *cobra.Command) error {
+	if telemetryShutdown == nil {
+		shutdown, err := telemetry.Setup(context.Background(), Version, func(err error) {
+			ui.Debugf(1, "Telemetry: %v", err)
+		})
+		if err != nil {
+			return err
+		}
+		telemetryShutdown = shutdown
+	}
+	telemetry.StartCommand(cmd.CommandPath())
+	return nil
+}
+
+// shutdownTelemetry exports the telemetry left, waiting for the collector
+// for a few seconds at most
+func shutdownTelemetry() {
+	if telemetryShutdown == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := telemetryShutdown(ctx); err != nil {
+		ui.Debugf(1, "Telemetry: %v", err)
+	}
+}
+
+// openedLogFile is the path of the log file opened by openLogFile
+var openedLogFile string
+
+// openLogFile opens the file given with --log-file, unless it is already
+// open
+func openLogFile(cmd *cobra.Command) error {
+	if logFile == "" || logFile == openedLogFile {
+		return nil
+	}
+	if err := ui.OpenLogFile(logFile); err != nil {
+		return err
+	}
+	openedLogFile = logFile
+	ui.Logf("drivio %s: %s", Version, cmd.CommandPath())
+	return nil
+}
+
+// applyEnvironment fills the flags of the command that were not given on the
+// command line with the environment variables bound to them (see
+// bindFlagEnv)
+func applyEnvironment(cmd *cobra.Command) error {
+	var errs []error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if f.Changed {
+			return
+		}
+		value, name, ok := config.LookupEnv(f.Annotations[envAnnotation]...)
+		if !ok {
+			return
+		}
+		if err := cmd.Flags().Set(f.Name, value); err != nil {
+			errs = append(errs, fmt.Errorf("invalid value for %q in %s: %w", f.Name, name, err))
+		}
+	})
+
+	return errors.Join(errs...)
+}
+
+// activeFileConfig is the configuration file applied to the running
+// command, nil for the commands that don't apply it
+var activeFileConfig *config.FileConfig
+
+// applyConfigFile fills the flags of the command that were given neither on
+// the command line nor through the environment with the values of the
+// configuration file, which makes the precedence flags > environment >
+// config file > defaults. Keys of the file are flag names, looked up in the
+// active profile first and in the sections of the command (see
+// config.FileConfig.ForCommand).
+func applyConfigFile(cmd *cobra.Command) error {
+	fileConfig, err := config.LoadConfigFile(cfgFile, profile)
+	if err != nil {
+		return err
+	}
+	fileConfig = fileConfig.ForCommand(commandSection(cmd)...)
+	activeFileConfig = fileConfig
+	switch _, err := os.Stat(fileConfig.Path()); {
+	case err != nil:
+		ui.Debugf(1, "No config file at %s", fileConfig.Path())
+	case fileConfig.Profile != "":
+		ui.Debugf(1, "Using config file %s, profile %s", fileConfig.Path(), fileConfig.Profile)
+	default:
+		ui.Debugf(1, "Using config file %s", fileConfig.Path())
+	}
+
+	var errs []error
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		switch f.Name {
+		case "help", "version", "config", "profile":
+			return
+		}
+		if f.Changed || !fileConfig.IsSet(f.Name) {
+			return
+		}
+
+		values := []string{fileConfig.GetString(f.Name)}
+		if _, ok := f.Value.(pflag.SliceValue); ok {
+			values = fileConfig.GetStringSlice(f.Name)
+		}
+		for _, value := range values {
+			if err := cmd.Flags().Set(f.Name, value); err != nil {
+				errs = append(errs, fmt.Errorf("invalid value for %q in config file: %w", f.Name, err))
+				return
+			}
+		}
+		ui.Debugf(1, "--%s set from the config file", f.Name)
+	})
+
+	return errors.Join(errs...)
+}
+
+// explicitFlags returns the names of the flags given on the command line
+func explicitFlags(cmd *cobra.Command) map[string]bool {
+	explicit := make(map[string]bool)
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		explicit[f.Name] = true
+	})
+	return explicit
+}
+
+// applyInstance points the url and token flags at the GitLab instance
+// selected with --instance, unless they were given on the command line. The
+// instance takes precedence over the GitLab environment variables, which
+// usually hold the credentials of another instance.
+func applyInstance(cmd *cobra.Command, explicit map[string]bool) error {
+	f := cmd.Flags().Lookup("instance")
+	if f == nil || f.Value.String() == "" {
+		return nil
+	}
+
+	fileConfig, err := config.LoadConfigFile(cfgFile, profile)
+	if err != nil {
+		return err
+	}
+	instance, err := fileConfig.Instance(f.Value.String())
+	if err != nil {
+		return err
+	}
+
+	for name, value := range map[string]string{"url": instance.URL, "token": instance.Token} {
+		if value == "" || explicit[name] {
+			continue
+		}
+		if err := cmd.Flags().Set(name, value); err != nil {
+			return fmt.Errorf("invalid %s for GitLab instance %q: %w", name, instance.Name, err)
+		}
+		ui.Debugf(1, "--%s set from GitLab instance %s", name, instance.Name)
+	}
+	return nil
+}
+
+// commandSection returns the path of the configuration file section of a
+// command, e.g. ["fetch", "archive"] for drivio fetch archive
+func commandSection(cmd *cobra.Command) []string {
+	return strings.Fields(cmd.CommandPath())[1:]
+}
+
+// bindFlagEnv binds environment variables to a flag, in order of precedence.
+// They are used when the flag is not given and take precedence over the
+// configuration file.
+func bindFlagEnv(flags *pflag.FlagSet, name string, envs ...string) {
+	flags.SetAnnotation(name, envAnnotation, envs)
+}
+
+// lockWorkDir takes the advisory lock on the work directory so concurrent
+// drivio runs don't clobber each other. The returned function releases it.
+func lockWorkDir(dir string) (func(), error) {
+	l, err := lock.Acquire(context.Background(), dir, lockTimeout, func(holder lock.Info) {
+		ui.Fprintf(os.Stderr, "⏳ Waiting up to %s for %s, in use by %s\n", lockTimeout, dir, holder)
+	})
+	if err != nil {
+		var lockedErr *lock.LockedError
+		if errors.As(err, &lockedErr) {
+			return nil, fmt.Errorf("%w; retry later, or use --lock-timeout to wait for it", err)
+		}
+		return nil, err
+	}
+
+	return func() {
+		if err := l.Release(); err != nil {
+			ui.Fprintf(os.Stderr, "⚠️  Warning: %v\n", err)
+		}
+	}, nil
 }
@@ -0,0 +1,432 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"drivio/pkg/config"
+	"drivio/pkg/notify"
+	"drivio/pkg/schedule"
+	"drivio/pkg/telemetry"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	runSchedule       string
+	runHealthAddr     string
+	runNotifyChannels []string
+	runReportChannels []string
+	runTimeout        time.Duration
+	runNow            bool
+)
+
+// taskOutputSize is the size of the end of the output of a run kept for the
+// messages about it
+const taskOutputSize = 64 << 10
+
+// failureLines is the number of the last lines of output sent with a
+// failure alert
+const failureLines = 20
+
+// runCmd represents the run command
+var runCmd = &cobra.Command{
+	Use:   "run [TASK...] [-- COMMAND...]",
+	Short: "Run drivio commands on a schedule",
+	Long: `Run drivio commands periodically, on cron schedules, for teams without
+access to the cron of their CI: a weekly report of the pending changes, a
+drift check every hour, a refresh of the fetched files every night...
+
+Tasks are declared in the tasks section of the configuration file, at the
+top level or in a profile, and named on the command line; without names,
+all of them run:
+
+  tasks:
+    pending:
+      schedule: "0 9 * * MON"
+      command: release-notes --owner myorg --repo myrepo --from v1.4.0 --to main --summary --stdout
+      title: Pending changes of myrepo
+      report: [team-slack]      # channels receiving the output of every run
+    drift:
+      schedule: "*/30 * * * *"
+      command: status --notify oncall
+      notify: [oncall]          # channels alerted when a run fails
+      timeout: 5m
+    refresh:
+      schedule: "@daily"
+      command: [fetch, --manifest, drivio.fetch.yaml]
+
+A single command can also be given after --, with --schedule.
+
+Schedules are cron expressions of five fields, minute, hour, day of month,
+month and day of week, in the local time zone, e.g. "0 9 * * MON-FRI", or
+@hourly, @daily, @weekly, @monthly and @every DURATION (e.g. @every 10m).
+--schedule replaces the schedule of a single task.
+
+Each run is a new drivio process, with the global flags given to run, and
+its output is printed as it goes. A run still going when its next time comes
+delays it; runs of different tasks can overlap. --notify and --report add
+channels to those of every task.
+
+--health-addr serves the state of the tasks as JSON on GET /healthz, e.g.
+for a liveness probe. SIGINT and SIGTERM stop scheduling and wait for the
+runs in progress; a second signal stops at once.
+
+Examples:
+  drivio run
+  drivio run drift --schedule "@every 5m" --now
+  drivio run --schedule "0 9 * * MON" --report team-slack -- release-notes --owner myorg --repo myrepo --from v1.4.0 --to main --summary --stdout
+  drivio run --health-addr :8081 --notify oncall`,
+	RunE: runRun,
+}
+
+func init() {
+	rootCmd.AddCommand(runCmd)
+
+	// Add flags
+	runCmd.Flags().StringVar(&runSchedule, "schedule", "", "Cron expression of the schedule, e.g. \"0 9 * * MON\" (required with a command)")
+	runCmd.Flags().StringVar(&runHealthAddr, "health-addr", "", "Address serving the state of the tasks on /healthz, e.g. :8081")
+	runCmd.Flags().StringArrayVar(&runNotifyChannels, "notify", nil, "Notification channel alerted when a run fails: a channel of the config file, or TYPE:URL; repeatable")
+	runCmd.Flags().StringArrayVar(&runReportChannels, "report", nil, "Notification channel receiving the output of every successful run; repeatable")
+	runCmd.Flags().DurationVar(&runTimeout, "timeout", 0, "Stop runs lasting longer, for tasks without a timeout (default: no limit)")
+	runCmd.Flags().BoolVar(&runNow, "now", false, "Also run the tasks once at start")
+}
+
+func runRun(cmd *cobra.Command, args []string) error {
+	tasks, err := scheduledTasks(cmd, args)
+	if err != nil {
+		return err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to find the drivio executable: %w", err)
+	}
+	globalArgs := forwardedFlags(cmd)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var health *http.Server
+	if runHealthAddr != "" {
+		listener, err := net.Listen("tcp", runHealthAddr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", runHealthAddr, err)
+		}
+		mux := http.NewServeMux()
+		mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(tasksHealth(tasks))
+		})
+		health = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			if err := health.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				daemonLog("⚠️  Health endpoint failed: %v\n", err)
+			}
+		}()
+		daemonLog("💓 Health endpoint on %s/healthz\n", runHealthAddr)
+	}
+
+	var wg sync.WaitGroup
+	for _, task := range tasks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			task.loop(ctx, exe, globalArgs)
+		}()
+	}
+
+	<-ctx.Done()
+	// A second signal stops at once
+	stop()
+	running := 0
+	for _, task := range tasks {
+		if task.isRunning() {
+			running++
+		}
+	}
+	if running > 0 {
+		daemonLog("👋 Stopping: waiting for %d run(s) in progress...\n", running)
+	} else {
+		daemonLog("👋 Stopping...\n")
+	}
+	wg.Wait()
+
+	if health != nil {
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		health.Shutdown(shutdown)
+	}
+	return nil
+}
+
+// scheduledTasks returns the tasks named on the command line, all the tasks
+// of the configuration file without names, or the command given after --
+func scheduledTasks(cmd *cobra.Command, args []string) ([]*scheduledTask, error) {
+	var tasks []*config.Task
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+		if dash > 0 {
+			return nil, fmt.Errorf("task names and a command after -- are mutually exclusive")
+		}
+		command := args
+		if len(command) > 0 && command[0] == "drivio" {
+			command = command[1:]
+		}
+		if len(command) == 0 {
+			return nil, fmt.Errorf("no command after --")
+		}
+		if runSchedule == "" {
+			return nil, fmt.Errorf("--schedule is required with a command")
+		}
+		tasks = append(tasks, &config.Task{Name: command[0], Schedule: runSchedule, Command: command})
+	} else {
+		names := args
+		if len(names) == 0 {
+			names = activeFileConfig.Tasks()
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("no task: declare tasks in the config file, or give a command after -- with --schedule")
+		}
+		if runSchedule != "" && len(names) > 1 {
+			return nil, fmt.Errorf("--schedule applies to a single task, got %d", len(names))
+		}
+		for _, name := range names {
+			task, err := activeFileConfig.Task(name)
+			if err != nil {
+				return nil, err
+			}
+			if runSchedule != "" {
+				task.Schedule = runSchedule
+			}
+			if task.Schedule == "" {
+				return nil, fmt.Errorf("task %q has no schedule: set its schedule in the config file or use --schedule", task.Name)
+			}
+			tasks = append(tasks, task)
+		}
+	}
+
+	var scheduled []*scheduledTask
+	for _, task := range tasks {
+		s, err := schedule.Parse(task.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("task %q: %w", task.Name, err)
+		}
+		notifyTo, err := notifyChannels(append(task.Notify, runNotifyChannels...))
+		if err != nil {
+			return nil, fmt.Errorf("task %q: %w", task.Name, err)
+		}
+		reportTo, err := notifyChannels(append(task.Report, runReportChannels...))
+		if err != nil {
+			return nil, fmt.Errorf("task %q: %w", task.Name, err)
+		}
+		if task.Timeout == 0 {
+			task.Timeout = runTimeout
+		}
+		if task.Title == "" {
+			task.Title = "drivio " + task.Name
+		}
+		scheduled = append(scheduled, &scheduledTask{Task: *task, schedule: s, notify: notifyTo, report: reportTo})
+	}
+	return scheduled, nil
+}
+
+// forwardedFlags returns the global flags set for cmd, for the commands it
+// runs. They are parsed by the flag set of cmd, which only knows they were
+// set.
+func forwardedFlags(cmd *cobra.Command) []string {
+	var args []string
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		if cmd.Root().PersistentFlags().Lookup(f.Name) != nil {
+			args = append(args, "--"+f.Name+"="+f.Value.String())
+		}
+	})
+	return args
+}
+
+// scheduledTask is a task with its schedule, and the state of its runs
+type scheduledTask struct {
+	config.Task
+	schedule schedule.Schedule
+	notify   []notifyChannel
+	report   []notifyChannel
+
+	mu       sync.Mutex
+	next     time.Time
+	running  bool
+	lastRun  time.Time
+	lastTook time.Duration
+	lastErr  error
+	runs     int
+	failures int
+}
+
+// loop runs the task on its schedule until the context is done
+func (t *scheduledTask) loop(ctx context.Context, exe string, globalArgs []string) {
+	if runNow {
+		t.run(exe, globalArgs)
+	}
+	for {
+		next := t.schedule.Next(time.Now())
+		if next.IsZero() {
+			daemonLog("⚠️  %s: the schedule %q never runs\n", t.Name, t.Schedule)
+			return
+		}
+		t.mu.Lock()
+		t.next = next
+		t.mu.Unlock()
+		daemonLog("⏰ %s: next run at %s\n", t.Name, next.Format(time.RFC3339))
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		t.run(exe, globalArgs)
+	}
+}
+
+// run runs the command of the task once, then alerts or reports
+func (t *scheduledTask) run(exe string, globalArgs []string) {
+	start := time.Now()
+	t.mu.Lock()
+	t.running = true
+	t.mu.Unlock()
+	daemonLog("▶️  %s: running drivio %s\n", t.Name, t.Command[0])
+
+	// Runs in progress are not canceled on shutdown, only on timeout
+	ctx := context.Background()
+	if t.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.Timeout)
+		defer cancel()
+	}
+	// Every run is a trace, continued by the run of drivio
+	ctx, end := telemetry.StartRoot(ctx, "task "+t.Name, attribute.String("drivio.task", t.Name))
+	stdout := &tailBuffer{max: taskOutputSize}
+	stderr := &tailBuffer{max: taskOutputSize}
+	c := exec.CommandContext(ctx, exe, append(append([]string{}, globalArgs...), t.Command...)...)
+	c.Stdout = io.MultiWriter(os.Stdout, stdout)
+	c.Stderr = io.MultiWriter(os.Stderr, stderr)
+	if env := telemetry.Environ(ctx); env != nil {
+		c.Env = append(os.Environ(), env...)
+	}
+	err := c.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("timed out after %s", t.Timeout)
+	}
+	end(err)
+	took := time.Since(start).Round(time.Second)
+
+	t.mu.Lock()
+	t.running = false
+	t.lastRun, t.lastTook, t.lastErr = start, took, err
+	t.runs++
+	if err != nil {
+		t.failures++
+	}
+	t.mu.Unlock()
+
+	// Messages are sent even when stopping, the run being over
+	if err != nil {
+		daemonLog("❌ %s failed after %s: %v\n", t.Name, took, err)
+		output := stderr.String()
+		if strings.TrimSpace(output) == "" {
+			output = stdout.String()
+		}
+		text := fmt.Sprintf("drivio %s failed after %s: %v", strings.Join(t.Command, " "), took, err)
+		if tail := lastLines(output, failureLines); tail != "" {
+			text += "\n\n" + tail
+		}
+		notifyUnattended(context.Background(), t.notify, notify.Message{Title: t.Title + " failed", Text: text})
+		return
+	}
+	daemonLog("✅ %s done in %s\n", t.Name, took)
+	if output := strings.TrimSpace(stdout.String()); output != "" {
+		notifyUnattended(context.Background(), t.report, notify.Message{Title: t.Title, Text: output})
+	}
+}
+
+func (t *scheduledTask) isRunning() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.running
+}
+
+// taskHealth is the state of a task served on /healthz
+type taskHealth struct {
+	Name      string     `json:"name"`
+	Schedule  string     `json:"schedule"`
+	Running   bool       `json:"running"`
+	Next      *time.Time `json:"next,omitempty"`
+	LastRun   *time.Time `json:"last_run,omitempty"`
+	LastTook  string     `json:"last_duration,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+	Runs      int        `json:"runs"`
+	Failures  int        `json:"failures"`
+}
+
+// tasksHealth returns the state of the tasks served on /healthz
+func tasksHealth(tasks []*scheduledTask) map[string]interface{} {
+	var states []taskHealth
+	for _, t := range tasks {
+		t.mu.Lock()
+		state := taskHealth{Name: t.Name, Schedule: t.Schedule, Running: t.running, Runs: t.runs, Failures: t.failures}
+		if !t.next.IsZero() {
+			next := t.next
+			state.Next = &next
+		}
+		if !t.lastRun.IsZero() {
+			lastRun := t.lastRun
+			state.LastRun = &lastRun
+			state.LastTook = t.lastTook.String()
+		}
+		if t.lastErr != nil {
+			state.LastError = t.lastErr.Error()
+		}
+		t.mu.Unlock()
+		states = append(states, state)
+	}
+	return map[string]interface{}{"status": "ok", "tasks": states}
+}
+
+// tailBuffer keeps the end of what is written to it
+type tailBuffer struct {
+	data []byte
+	max  int
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.data = append(b.data, p...)
+	if len(b.data) > b.max {
+		b.data = b.data[len(b.data)-b.max:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	return string(b.data)
+}
+
+// lastLines returns the last n lines of a text
+func lastLines(text string, n int) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
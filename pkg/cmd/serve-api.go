@@ -0,0 +1,404 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"drivio/pkg/config"
+	"drivio/pkg/drivio"
+	"drivio/pkg/git"
+	"drivio/pkg/telemetry"
+	"drivio/pkg/webhook"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Statuses of the jobs of the API
+const (
+	jobQueued    = "queued"
+	jobRunning   = "running"
+	jobSucceeded = "succeeded"
+	jobFailed    = "failed"
+)
+
+// apiRequest is the body of POST /release-notes. The settings not given are
+// those of the profile for release-notes, the tokens always are.
+type apiRequest struct {
+	Profile  string `json:"profile"`
+	Provider string `json:"provider"`
+	Repo     string `json:"repo"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Format   string `json:"format"`
+	Sections *bool  `json:"sections"`
+	Summary  *bool  `json:"summary"`
+}
+
+// apiJob is a generation requested through the API, as answered by GET
+// /jobs/{id}
+type apiJob struct {
+	ID         string     `json:"id"`
+	Status     string     `json:"status"`
+	Profile    string     `json:"profile,omitempty"`
+	Provider   string     `json:"provider"`
+	Repo       string     `json:"repo"`
+	From       string     `json:"from,omitempty"`
+	To         string     `json:"to"`
+	Format     string     `json:"format"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	// Changes is the number of changes of the notes
+	Changes *int   `json:"changes,omitempty"`
+	Notes   string `json:"notes,omitempty"`
+	Error   string `json:"error,omitempty"`
+
+	settings apiSettings
+}
+
+// apiSettings are the settings of a job resolved from its request, its
+// profile and the flags of the server
+type apiSettings struct {
+	owner, repo string
+	// token is the GitHub token, or the GitLab token with url
+	token, url string
+	sections   bool
+	summary    bool
+	template   string
+	rules      []git.TypeRule
+}
+
+// apiServer runs the generations requested through the API, --api-workers
+// at a time
+type apiServer struct {
+	token string
+	queue chan string
+
+	mu   sync.Mutex
+	jobs map[string]*apiJob
+}
+
+func newAPIServer(token string) *apiServer {
+	return &apiServer{
+		token: token,
+		queue: make(chan string, serveQueueSize),
+		jobs:  make(map[string]*apiJob),
+	}
+}
+
+// authorized reports whether a request carries the --api-token bearer token
+func (s *apiServer) authorized(w http.ResponseWriter, r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+		daemonLog("🚫 Refused an API request from %s: invalid or missing token\n", r.RemoteAddr)
+		writeAPIError(w, http.StatusUnauthorized, "invalid or missing bearer token")
+		return false
+	}
+	return true
+}
+
+func (s *apiServer) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(w, r) {
+		return
+	}
+
+	var req apiRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeAPIError(w, http.StatusBadRequest, fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+	job, err := newAPIJob(req)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	s.prune()
+	select {
+	case s.queue <- job.ID:
+		s.jobs[job.ID] = job
+	default:
+		s.mu.Unlock()
+		writeAPIError(w, http.StatusServiceUnavailable, "too many jobs waiting, retry later")
+		return
+	}
+	snapshot := *job
+	s.mu.Unlock()
+
+	daemonLog("📥 Job %s queued: notes of %s %s\n", job.ID, job.Repo, job.To)
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	writeAPIJSON(w, http.StatusAccepted, snapshot)
+}
+
+func (s *apiServer) handleJob(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(w, r) {
+		return
+	}
+
+	s.mu.Lock()
+	job, ok := s.jobs[r.PathValue("id")]
+	var snapshot apiJob
+	if ok {
+		snapshot = *job
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeAPIError(w, http.StatusNotFound, "no such job")
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, snapshot)
+}
+
+// prune forgets the jobs finished more than --api-job-ttl ago; s.mu must be
+// held
+func (s *apiServer) prune() {
+	for id, job := range s.jobs {
+		if job.FinishedAt != nil && time.Since(*job.FinishedAt) > serveAPIJobTTL {
+			delete(s.jobs, id)
+		}
+	}
+}
+
+// work runs the queued jobs until the queue is closed, skipping those
+// waiting once the server stops
+func (s *apiServer) work(ctx context.Context) {
+	for id := range s.queue {
+		if ctx.Err() != nil {
+			continue
+		}
+		s.run(ctx, id)
+	}
+}
+
+// run generates the notes of a job, recording the outcome in the job
+func (s *apiServer) run(ctx context.Context, id string) {
+	s.mu.Lock()
+	job := s.jobs[id]
+	started := time.Now()
+	job.Status, job.StartedAt = jobRunning, &started
+	request := *job
+	s.mu.Unlock()
+
+	ctx, end := telemetry.StartRoot(ctx, "job "+id,
+		attribute.String("drivio.provider", request.Provider),
+		attribute.String("drivio.repo", request.Repo),
+		attribute.String("drivio.tag", request.To))
+	from, notes, output, err := generateJobNotes(ctx, request)
+	end(err)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	finished := time.Now()
+	job.FinishedAt = &finished
+	if from != "" {
+		job.From = from
+	}
+	if err != nil {
+		job.Status, job.Error = jobFailed, err.Error()
+		daemonLog("❌ Job %s: %v\n", id, err)
+		return
+	}
+	job.Status, job.Notes = jobSucceeded, output
+	job.Changes = &notes.Statistics.Selected
+	daemonLog("📝 Job %s: %d relevant changes in %s %s..%s\n", id, notes.Statistics.Selected, job.Repo, job.From, job.To)
+}
+
+// generateJobNotes generates, renders and saves the notes of a job,
+// returning the reference they start from
+func generateJobNotes(ctx context.Context, job apiJob) (string, *drivio.Notes, string, error) {
+	settings := job.settings
+	src, err := jobSource(ctx, job)
+	if err != nil {
+		return "", nil, "", err
+	}
+
+	from := job.From
+	if from == "" {
+		if from, err = drivio.PreviousRelease(ctx, src, job.To); err != nil {
+			return "", nil, "", err
+		}
+		if from == "" {
+			return "", nil, "", fmt.Errorf("no release before %s to compare with: set from", job.To)
+		}
+	}
+
+	notes, err := drivio.Generate(ctx, src, from, job.To, drivio.Options{Label: drivio.DefaultLabel, Rules: settings.rules})
+	if err != nil {
+		return from, nil, "", fmt.Errorf("failed to generate release notes: %w", err)
+	}
+	format := git.OutputFormat(job.Format)
+	renderOpts := drivio.RenderOptions{Format: format, Sections: settings.sections, Summary: settings.summary}
+	if settings.template != "" {
+		renderOpts.TemplateFiles = []string{settings.template}
+	}
+	output, err := drivio.Render(src, notes, renderOpts)
+	if err != nil {
+		return from, nil, "", fmt.Errorf("failed to format release notes: %w", err)
+	}
+
+	if err := saveServedNotes(job.Provider, job.Repo, from, job.To, format, output); err != nil {
+		return from, nil, "", err
+	}
+	return from, notes, output, nil
+}
+
+// jobSource returns the repository of a job, read like those of the
+// webhooks with the credentials of the job
+func jobSource(ctx context.Context, job apiJob) (drivio.Source, error) {
+	settings := job.settings
+	if job.Provider == webhook.GitHub {
+		return drivio.NewGitHub(settings.owner, settings.repo, settings.token), nil
+	}
+
+	dir := filepath.Join(cacheRoot(workDir), "clones", cloneDirName(job.Repo, "", true))
+	if _, err := os.Stat(dir); err != nil {
+		daemonLog("📥 Mirroring %s into %s\n", job.Repo, dir)
+	}
+	return drivio.NewGitLab(ctx, settings.url, settings.token, job.Repo, dir)
+}
+
+// newAPIJob returns the job of a request, its settings completed from the
+// profile of the config file it names (the active one when it names none)
+// and the flags of the server
+func newAPIJob(req apiRequest) (*apiJob, error) {
+	fileConfig, err := config.LoadConfigFile(cfgFile, req.Profile)
+	if err != nil {
+		return nil, err
+	}
+	notesConfig := fileConfig.ForCommand("release-notes")
+
+	job := &apiJob{
+		Status:    jobQueued,
+		Profile:   fileConfig.Profile,
+		Provider:  strings.ToLower(req.Provider),
+		Repo:      req.Repo,
+		From:      req.From,
+		To:        req.To,
+		Format:    strings.ToLower(req.Format),
+		CreatedAt: time.Now(),
+	}
+	if job.To == "" {
+		return nil, fmt.Errorf("to is required")
+	}
+
+	settings := &job.settings
+	switch job.Provider {
+	case "", webhook.GitHub:
+		job.Provider = webhook.GitHub
+		if job.Repo == "" && notesConfig.IsSet("owner") && notesConfig.IsSet("repo") {
+			job.Repo = notesConfig.GetString("owner") + "/" + notesConfig.GetString("repo")
+		}
+		settings.token = fileSetting(notesConfig, "github-token", serveGitHubToken)
+	case webhook.GitLab:
+		if job.Repo == "" {
+			job.Repo = fileConfig.ForCommand("fetch").GetString("repo")
+		}
+		cfg := loadFetchConfig()
+		settings.url = fileSetting(fileConfig, "url", cfg.GitLabURL)
+		settings.token = fileSetting(fileConfig, "token", cfg.GitLabToken)
+	default:
+		return nil, fmt.Errorf("invalid provider %q: use github or gitlab", req.Provider)
+	}
+	owner, name, ok := strings.Cut(job.Repo, "/")
+	if !ok || owner == "" || name == "" {
+		return nil, fmt.Errorf("invalid repo %q: use OWNER/NAME, or a profile with an owner and a repo", job.Repo)
+	}
+	settings.owner, settings.repo = owner, name
+
+	if job.Format == "" {
+		job.Format = strings.ToLower(fileSetting(notesConfig, "format", string(git.FormatMarkdown)))
+	}
+	switch git.OutputFormat(job.Format) {
+	case git.FormatMarkdown, git.FormatMarkdownTable, git.FormatJSON, git.FormatText:
+	default:
+		return nil, fmt.Errorf("invalid format %q: use markdown, table, json or text", job.Format)
+	}
+
+	if settings.sections, err = boolSetting(req.Sections, notesConfig, "sections", serveSections); err != nil {
+		return nil, err
+	}
+	if settings.summary, err = boolSetting(req.Summary, notesConfig, "summary", false); err != nil {
+		return nil, err
+	}
+	settings.template = fileSetting(notesConfig, "template", serveTemplate)
+	if settings.rules, err = classificationRules(notesConfig); err != nil {
+		return nil, err
+	}
+
+	if job.ID, err = newJobID(); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// fileSetting returns the value of a key of the config file, or the
+// fallback when it is not set
+func fileSetting(fileConfig *config.FileConfig, key, fallback string) string {
+	if fileConfig.IsSet(key) {
+		return fileConfig.GetString(key)
+	}
+	return fallback
+}
+
+// boolSetting returns the value of a request, or else of a key of the config
+// file, or else the fallback
+func boolSetting(value *bool, fileConfig *config.FileConfig, key string, fallback bool) (bool, error) {
+	if value != nil {
+		return *value, nil
+	}
+	if !fileConfig.IsSet(key) {
+		return fallback, nil
+	}
+	b, err := strconv.ParseBool(fileConfig.GetString(key))
+	if err != nil {
+		return false, fmt.Errorf("invalid value for %q in config file: %w", key, err)
+	}
+	return b, nil
+}
+
+// newJobID returns a random identifier of a job
+func newJobID() (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("failed to generate a job identifier: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// writeAPIJSON answers a request of the API with a JSON document
+func writeAPIJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	_ = encoder.Encode(v)
+}
+
+// writeAPIError answers a request of the API with an error
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeAPIJSON(w, status, map[string]string{"error": message})
+}
+
+// apiProfiles returns the profiles of the config file jobs can name, for
+// the startup log
+func apiProfiles() []string {
+	fileConfig, err := config.LoadConfigFile(cfgFile, "")
+	if err != nil {
+		return nil
+	}
+	return fileConfig.Profiles()
+}
@@ -0,0 +1,456 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"drivio/pkg/audit"
+	"drivio/pkg/config"
+	"drivio/pkg/drivio"
+	"drivio/pkg/git"
+	"drivio/pkg/notify"
+	"drivio/pkg/telemetry"
+	"drivio/pkg/ui"
+	"drivio/pkg/webhook"
+
+	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	serveListen       string
+	serveWebhookPath  string
+	serveGitHubSecret string
+	serveGitLabSecret string
+	serveGitHubToken  string
+	serveRepos        []string
+	servePublish      bool
+	serveOverwrite    bool
+	serveSections     bool
+	serveTemplate     string
+	serveNotify       []string
+	serveAPI          bool
+	serveAPIToken     string
+	serveAPIWorkers   int
+	serveAPIJobTTL    time.Duration
+)
+
+// serveQueueSize is the number of events waiting to be processed beyond
+// which webhooks are refused
+const serveQueueSize = 64
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Generate and publish release notes when tags are pushed",
+	Long: `Listen for GitHub and GitLab webhooks and, for every version tag pushed or
+release published, generate the release notes of the changes since the
+previous version, set them as the description of the release and announce it
+on the --notify channels: hands-off release notes.
+
+Point the webhooks of the repositories at http://HOST:PORT/webhook:
+
+  GitHub  push and release events, content type application/json, with
+          --github-secret as the secret
+  GitLab  tag push and release events, with --gitlab-secret as the secret
+          token
+
+Requests from a provider without a secret are refused. --repos limits the
+repositories acted on, e.g. myorg/*.
+
+GitHub repositories are read through the API with --github-token. GitLab
+projects, of the instance at --url, are mirrored in the cache directory with
+--token, and their merge requests recognized by their merge commits. The
+notes are saved in the work directory, as by release-notes, and each event is
+processed once, in the order received.
+
+--publish=false skips the release: the notes are only saved and announced.
+A release with a description is left as is, unless --overwrite is set; one
+is created for a pushed tag without a release.
+
+--api also serves a REST API generating notes on request, for portals and
+bots, authenticated by the bearer token --api-token:
+
+  POST /release-notes  queue a generation, answering 202 with the job; the
+                       body is JSON: profile, provider (github or gitlab),
+                       repo (OWNER/NAME), from, to, format, sections, summary
+  GET /jobs/{id}       the job: its status (queued, running, succeeded or
+                       failed), then its notes or error
+
+The settings a request leaves out are those of release-notes in its profile
+of the config file (the active one when it names none), e.g. owner, repo,
+format and github-token; tokens only come from the profile and the flags.
+from defaults to the release before to. --api-workers jobs run at a time,
+and finished jobs are kept --api-job-ttl. With --api, webhook secrets are
+optional: the webhooks are only received with one.
+
+GET /healthz answers ok while the server runs. SIGINT and SIGTERM stop it
+once the events and jobs being processed are done.
+
+Examples:
+  drivio serve --github-secret "$WEBHOOK_SECRET" --notify team-slack
+  drivio serve --listen :9000 --gitlab-secret "$WEBHOOK_SECRET" --url https://gitlab.example.com --repos "platform/*"
+  drivio serve --github-secret "$WEBHOOK_SECRET" --publish=false --notify releases-mail
+  drivio serve --api --api-token "$API_TOKEN" --api-workers 4`,
+	RunE: runServe,
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+
+	// Add flags
+	serveCmd.Flags().StringVar(&serveListen, "listen", ":8080", "Address to listen on")
+	serveCmd.Flags().StringVar(&serveWebhookPath, "webhook-path", "/webhook", "Path receiving the webhooks")
+	serveCmd.Flags().StringVar(&serveGitHubSecret, "github-secret", "", "Secret of the GitHub webhooks")
+	serveCmd.Flags().StringVar(&serveGitLabSecret, "gitlab-secret", "", "Secret token of the GitLab webhooks")
+	serveCmd.Flags().StringVar(&serveGitHubToken, "github-token", "", "GitHub token reading the repositories and publishing their releases")
+	serveCmd.Flags().StringVar(&gitlabURL, "url", "", "GitLab URL of the projects (default: https://gitlab.com)")
+	serveCmd.Flags().StringVar(&gitlabToken, "token", "", "GitLab access token mirroring the projects and publishing their releases")
+	serveCmd.Flags().StringSliceVar(&serveRepos, "repos", nil, "Patterns of the repositories to act on, e.g. myorg/* (default: all)")
+	serveCmd.Flags().BoolVar(&servePublish, "publish", true, "Set the notes as the description of the release, creating it for a pushed tag")
+	serveCmd.Flags().BoolVar(&serveOverwrite, "overwrite", false, "Replace the description of releases that have one")
+	serveCmd.Flags().BoolVar(&serveSections, "sections", true, "Group the changes of the notes by type")
+	serveCmd.Flags().StringVar(&serveTemplate, "template", "", "Go template file rendering the notes")
+	serveCmd.Flags().StringArrayVar(&serveNotify, "notify", nil, "Notification channel to announce the releases to: a channel of the config file, or TYPE:URL; repeatable")
+	serveCmd.Flags().BoolVar(&serveAPI, "api", false, "Serve the REST API generating notes on request (POST /release-notes, GET /jobs/{id})")
+	serveCmd.Flags().StringVar(&serveAPIToken, "api-token", "", "Bearer token of the requests of the API")
+	serveCmd.Flags().IntVar(&serveAPIWorkers, "api-workers", 2, "Jobs of the API run at a time")
+	serveCmd.Flags().DurationVar(&serveAPIJobTTL, "api-job-ttl", time.Hour, "How long finished jobs of the API are kept")
+
+	// Environment variables that take precedence over the config file
+	bindFlagEnv(serveCmd.Flags(), "github-secret", config.EnvGitHubWebhookSecret...)
+	bindFlagEnv(serveCmd.Flags(), "gitlab-secret", config.EnvGitLabWebhookSecret...)
+	bindFlagEnv(serveCmd.Flags(), "api-token", config.EnvAPIToken...)
+	bindFlagEnv(serveCmd.Flags(), "github-token", config.EnvGitHubToken...)
+	bindFlagEnv(serveCmd.Flags(), "url", config.EnvGitLabURL...)
+	bindFlagEnv(serveCmd.Flags(), "token", config.EnvGitLabToken...)
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	webhooks := serveGitHubSecret != "" || serveGitLabSecret != ""
+	if !webhooks && !serveAPI {
+		return fmt.Errorf("no webhook secret: use --github-secret or --gitlab-secret, or serve the API with --api")
+	}
+	if serveAPI && serveAPIToken == "" {
+		return fmt.Errorf("--api requires --api-token")
+	}
+	if serveAPIWorkers < 1 {
+		return fmt.Errorf("--api-workers must be at least 1")
+	}
+	for _, pattern := range serveRepos {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid --repos pattern %q: %w", pattern, err)
+		}
+	}
+	rules, err := classificationRules(activeFileConfig)
+	if err != nil {
+		return err
+	}
+	channels, err := notifyChannels(serveNotify)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return fmt.Errorf("failed to create work directory: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	s := &releaseServer{
+		secrets:  webhook.Secrets{GitHub: serveGitHubSecret, GitLab: serveGitLabSecret},
+		rules:    rules,
+		channels: channels,
+		queue:    make(chan webhook.Event, serveQueueSize),
+		seen:     make(map[string]bool),
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for event := range s.queue {
+			s.process(ctx, event)
+		}
+	}()
+
+	mux := http.NewServeMux()
+	if webhooks {
+		mux.HandleFunc(serveWebhookPath, s.handleWebhook)
+	}
+	var api *apiServer
+	if serveAPI {
+		api = newAPIServer(serveAPIToken)
+		for i := 0; i < serveAPIWorkers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				api.work(ctx)
+			}()
+		}
+		mux.HandleFunc("POST /release-notes", api.handleSubmit)
+		mux.HandleFunc("GET /jobs/{id}", api.handleJob)
+	}
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	server := &http.Server{Addr: serveListen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+	if webhooks {
+		daemonLog("👂 Listening on %s%s\n", serveListen, serveWebhookPath)
+	}
+	if serveAPI {
+		daemonLog("👂 Serving the API on %s (profiles: %s)\n", serveListen, orDash(strings.Join(apiProfiles(), ", ")))
+	}
+
+	select {
+	case err := <-errs:
+		close(s.queue)
+		if api != nil {
+			close(api.queue)
+		}
+		wg.Wait()
+		return fmt.Errorf("server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	daemonLog("👋 Stopping...\n")
+	shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdown); err != nil {
+		return err
+	}
+	// No more events or jobs can be queued: let the workers finish those at
+	// hand, skipping those waiting
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	close(s.queue)
+	if api != nil {
+		close(api.queue)
+	}
+	wg.Wait()
+	return nil
+}
+
+// releaseServer receives the webhooks and processes their events one at a
+// time
+type releaseServer struct {
+	secrets  webhook.Secrets
+	rules    []git.TypeRule
+	channels []notifyChannel
+	queue    chan webhook.Event
+
+	mu sync.Mutex
+	// seen are the provider/repo@tag already queued, as a release published
+	// with a new tag sends both a tag push and a release event
+	seen   map[string]bool
+	closed bool
+}
+
+func (s *releaseServer) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	event, err := webhook.Parse(r, s.secrets)
+	switch {
+	case errors.Is(err, webhook.ErrUnauthorized):
+		daemonLog("🚫 Refused a webhook from %s: invalid or missing secret\n", r.RemoteAddr)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	case errors.Is(err, webhook.ErrIgnored):
+		ui.Debugf(1, "Webhook %v", err)
+		fmt.Fprintln(w, err)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !repoSelected(event.Repo, serveRepos) {
+		fmt.Fprintf(w, "ignored: %s is not selected by --repos\n", event.Repo)
+		return
+	}
+
+	key := event.Provider + "/" + event.Repo + "@" + event.Tag
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.seen[key] {
+		fmt.Fprintf(w, "ignored: %s@%s already received\n", event.Repo, event.Tag)
+		return
+	}
+	select {
+	case s.queue <- *event:
+		s.seen[key] = true
+		daemonLog("📥 %s %s of %s queued\n", event.Kind, event.Tag, event.Repo)
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprintf(w, "queued: %s@%s\n", event.Repo, event.Tag)
+	default:
+		http.Error(w, "too many events waiting, retry later", http.StatusServiceUnavailable)
+	}
+}
+
+// repoSelected reports whether a repository matches one of the patterns,
+// any repository matching no pattern
+func repoSelected(repo string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, repo); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// process generates the notes of an event, publishes and announces them,
+// logging the outcome
+func (s *releaseServer) process(ctx context.Context, event webhook.Event) {
+	s.mu.Lock()
+	closed := s.closed
+	s.mu.Unlock()
+	if closed {
+		daemonLog("⏭️  Skipped %s@%s: stopping\n", event.Repo, event.Tag)
+		return
+	}
+
+	ctx, end := telemetry.StartRoot(ctx, "release "+event.Repo+"@"+event.Tag,
+		attribute.String("drivio.provider", event.Provider),
+		attribute.String("drivio.repo", event.Repo),
+		attribute.String("drivio.tag", event.Tag))
+	err := s.release(ctx, event)
+	end(err)
+	if err != nil {
+		daemonLog("❌ %s@%s: %v\n", event.Repo, event.Tag, err)
+		// Received again, e.g. redelivered, it is processed again
+		s.mu.Lock()
+		delete(s.seen, event.Provider+"/"+event.Repo+"@"+event.Tag)
+		s.mu.Unlock()
+	}
+}
+
+// release runs the steps of an event
+func (s *releaseServer) release(ctx context.Context, event webhook.Event) error {
+	if _, err := git.ParseVersion(event.Tag); err != nil {
+		daemonLog("⏭️  Skipped %s@%s: not a version tag\n", event.Repo, event.Tag)
+		return nil
+	}
+
+	src, err := eventSource(ctx, event)
+	if err != nil {
+		return err
+	}
+	previousTag, err := drivio.PreviousRelease(ctx, src, event.Tag)
+	if err != nil {
+		return err
+	}
+	if previousTag == "" {
+		daemonLog("⏭️  Skipped %s@%s: no previous release to compare with\n", event.Repo, event.Tag)
+		return nil
+	}
+
+	notes, err := drivio.Generate(ctx, src, previousTag, event.Tag, drivio.Options{Label: drivio.DefaultLabel, Rules: s.rules})
+	if err != nil {
+		return fmt.Errorf("failed to generate release notes: %w", err)
+	}
+	renderOpts := drivio.RenderOptions{Sections: serveSections}
+	if serveTemplate != "" {
+		renderOpts.TemplateFiles = []string{serveTemplate}
+	}
+	output, err := drivio.Render(src, notes, renderOpts)
+	if err != nil {
+		return fmt.Errorf("failed to format release notes: %w", err)
+	}
+	daemonLog("📝 %s %s: %d relevant changes since %s\n", event.Repo, event.Tag, notes.Statistics.Selected, previousTag)
+
+	if err := saveServedNotes(event.Provider, event.Repo, previousTag, event.Tag, git.FormatMarkdown, output); err != nil {
+		return err
+	}
+
+	releaseURL := src.ReleaseURL(event.Tag)
+	if servePublish {
+		release, err := drivio.Publish(ctx, src, event.Tag, output, serveOverwrite)
+		if err != nil {
+			return err
+		}
+		releaseURL = release.URL
+		if release.Changed {
+			daemonLog("📦 Release published: %s\n", releaseURL)
+			auditAction(audit.ActionPublish, event.Repo+"@"+event.Tag, releaseURL, map[string]string{"provider": event.Provider, "from": previousTag})
+		} else {
+			daemonLog("⏭️  Release %s already has a description (use --overwrite to replace it)\n", releaseURL)
+		}
+	}
+
+	notifyUnattended(ctx, s.channels, notify.Message{
+		Title: fmt.Sprintf("%s %s released", event.Repo, event.Tag),
+		Text:  releaseSummary(notes),
+		URL:   releaseURL,
+		Notes: output,
+	})
+	return nil
+}
+
+// eventSource returns the repository of an event: GitHub repositories are
+// read through the API, GitLab projects of the --url instance from their
+// mirror in the cache directory
+func eventSource(ctx context.Context, event webhook.Event) (drivio.Source, error) {
+	if event.Provider == webhook.GitHub {
+		owner, repo, _ := strings.Cut(event.Repo, "/")
+		return drivio.NewGitHub(owner, repo, serveGitHubToken), nil
+	}
+
+	cfg := loadFetchConfig()
+	dir := filepath.Join(cacheRoot(workDir), "clones", cloneDirName(event.Repo, "", true))
+	if _, err := os.Stat(dir); err != nil {
+		daemonLog("📥 Mirroring %s into %s\n", event.Repo, dir)
+	}
+	return drivio.NewGitLab(ctx, cfg.GitLabURL, cfg.GitLabToken, event.Repo, dir)
+}
+
+// saveServedNotes saves the notes of a repository between two references in
+// the work directory
+func saveServedNotes(provider, repo, from, to string, format git.OutputFormat, output string) error {
+	unlock, err := lockWorkDir(workDir)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if err := enforceRetention(workDir, daemonLog); err != nil {
+		return err
+	}
+
+	refName := strings.NewReplacer("/", "-").Replace
+	notesPath := filepath.Join(workDir, fmt.Sprintf("release-notes-%s-%s-%s%s", refName(repo), refName(from), refName(to), notesExtension(format)))
+	if err := os.WriteFile(notesPath, []byte(output), 0644); err != nil {
+		return fmt.Errorf("failed to write file to work directory: %w", err)
+	}
+	recordArtifacts(workDir, "serve", map[string]string{
+		"provider": provider,
+		"repo":     repo,
+		"from":     from,
+		"to":       to,
+	}, notesPath)
+	return nil
+}
+
+// daemonLog prints a status line prefixed with the time, for the commands
+// running unattended
+func daemonLog(format string, a ...interface{}) {
+	ui.Printf("%s "+format, append([]interface{}{time.Now().Format(time.RFC3339)}, a...)...)
+}
@@ -0,0 +1,286 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"drivio/pkg/config"
+	"drivio/pkg/drivio"
+	"drivio/pkg/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	statsProvider    string
+	statsOwner       string
+	statsRepo        string
+	statsFrom        string
+	statsTo          string
+	statsLocal       string
+	statsGitHubToken string
+	statsGitLabURL   string
+	statsGitLabToken string
+	statsInstance    string
+	statsFormat      string
+	statsTop         int
+)
+
+// statsCmd represents the stats command
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Report contribution statistics between two references",
+	Long: `Report contribution statistics of the changes between two references, for
+sprint and quarterly reports: commits, authors, pull or merge requests
+merged, merges per week, and the review latency of pull requests.
+
+Repositories are read like release notes: from GitHub with --owner and
+--repo, from the clone of --local, or from a mirror of a GitLab project with
+--provider gitlab and --repo GROUP/PROJECT, its URL and token coming from
+the flags, the environment or the config file like for fetch.
+
+The time to the first review and the time to merge, from the opening of the
+pull requests, need GitHub, which knows when they were opened and reviewed:
+each one is looked up. Clones only know the merge commits.
+
+Examples:
+  drivio stats --owner openshift --repo hypershift --from v0.1.59 --to v0.1.63
+  drivio stats --owner openshift --repo hypershift --from v0.1.59 --to v0.1.63 --format markdown > sprint.md
+  drivio stats --local . --owner openshift --repo hypershift --from v0.1.59 --to HEAD --format json
+  drivio stats --provider gitlab --repo mygroup/myproject --from v1.2.0 --to v1.3.0`,
+	Args: cobra.NoArgs,
+	RunE: runStats,
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+
+	// Add flags
+	statsCmd.Flags().StringVar(&statsProvider, "provider", "github", "Provider of the repository: github or gitlab")
+	statsCmd.Flags().StringVar(&statsOwner, "owner", "", "GitHub repository owner/organization")
+	statsCmd.Flags().StringVar(&statsRepo, "repo", "", "GitHub repository name, or GitLab project path")
+	statsCmd.Flags().StringVar(&statsFrom, "from", "", "From reference (tag, commit, or branch)")
+	statsCmd.Flags().StringVar(&statsTo, "to", "", "To reference (tag, commit, or branch)")
+	statsCmd.Flags().StringVar(&statsLocal, "local", "", "Read the commits from this local clone instead of the GitHub API")
+	statsCmd.Flags().StringVar(&statsGitHubToken, "github-token", "", "GitHub token for authentication (optional)")
+	statsCmd.Flags().StringVar(&statsGitLabURL, "url", "", "GitLab URL, with --provider gitlab")
+	statsCmd.Flags().StringVar(&statsGitLabToken, "token", "", "GitLab access token, with --provider gitlab")
+	statsCmd.Flags().StringVar(&statsInstance, "instance", "", "GitLab instance of the config file providing the URL and token")
+	statsCmd.Flags().StringVar(&statsFormat, "format", "table", "Output format: table, json or markdown")
+	statsCmd.Flags().IntVar(&statsTop, "top", 10, "Number of authors and pull request authors listed in table and markdown output (0: all)")
+
+	// Environment variables that take precedence over the config file
+	bindFlagEnv(statsCmd.Flags(), "github-token", config.EnvGitHubToken...)
+	bindFlagEnv(statsCmd.Flags(), "url", config.EnvGitLabURL...)
+	bindFlagEnv(statsCmd.Flags(), "token", config.EnvGitLabToken...)
+	bindFlagEnv(statsCmd.Flags(), "instance", config.EnvInstance...)
+
+	statsCmd.MarkFlagRequired("from")
+	statsCmd.MarkFlagRequired("to")
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	switch statsFormat {
+	case "table", "json", "markdown":
+	default:
+		return fmt.Errorf("invalid --format %q: use table, json or markdown", statsFormat)
+	}
+	if statsTop < 0 {
+		return fmt.Errorf("invalid --top %d", statsTop)
+	}
+
+	ctx := context.Background()
+	src, err := statsSource(ctx)
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+
+	var stats *drivio.Stats
+	if err := ui.RunProgress(fmt.Sprintf("Computing the statistics of %s..%s...", statsFrom, statsTo), ui.UnitItems, func(progress chan<- ui.ProgressMsg) error {
+		var err error
+		stats, err = drivio.ComputeStats(ctx, src, statsFrom, statsTo, drivio.StatsOptions{
+			Progress: func(done, total int, message string) {
+				progress <- ui.ProgressMsg{Current: int64(done), Total: int64(total), Message: message}
+			},
+		})
+		return err
+	}); err != nil {
+		return fmt.Errorf("failed to compute the statistics: %w", err)
+	}
+
+	switch statsFormat {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(stats)
+	case "markdown":
+		writeStatsMarkdown(os.Stdout, stats)
+	default:
+		writeStatsTable(os.Stdout, stats)
+	}
+	return nil
+}
+
+// statsSource returns the source of the flags
+func statsSource(ctx context.Context) (drivio.Source, error) {
+	switch strings.ToLower(statsProvider) {
+	case "github":
+		if statsOwner == "" || statsRepo == "" {
+			return nil, fmt.Errorf("both --owner and --repo are required")
+		}
+		if statsLocal != "" {
+			return drivio.NewLocal(statsLocal, statsOwner, statsRepo)
+		}
+		return drivio.NewGitHub(statsOwner, statsRepo, statsGitHubToken), nil
+	case "gitlab":
+		if statsLocal != "" {
+			return nil, fmt.Errorf("--local reads GitHub repositories: give its --owner and --repo instead of --provider gitlab")
+		}
+		cfg := config.LoadConfig()
+		if statsGitLabURL != "" {
+			cfg.GitLabURL = statsGitLabURL
+		}
+		if statsGitLabToken != "" {
+			cfg.GitLabToken = statsGitLabToken
+		}
+		if statsRepo != "" {
+			cfg.RepositoryPath = statsRepo
+		}
+		if cfg.RepositoryPath == "" {
+			return nil, fmt.Errorf("--repo GROUP/PROJECT is required with --provider gitlab")
+		}
+		var src drivio.Source
+		dir := filepath.Join(cacheRoot(workDir), "clones", cloneDirName(cfg.RepositoryPath, "", true))
+		if err := ui.RunProgress(fmt.Sprintf("Mirroring %s...", cfg.RepositoryPath), ui.UnitItems, func(progress chan<- ui.ProgressMsg) error {
+			var err error
+			src, err = drivio.NewGitLabWithProgress(ctx, cfg.GitLabURL, cfg.GitLabToken, cfg.RepositoryPath, dir, func(done, total int64, phase string) {
+				progress <- ui.ProgressMsg{Current: done, Total: total, Message: phase}
+			})
+			return err
+		}); err != nil {
+			return nil, err
+		}
+		return src, nil
+	}
+	return nil, fmt.Errorf("invalid --provider %q: use github or gitlab", statsProvider)
+}
+
+// statsLatency describes a latency, e.g. "5h 20m median, 9h 5m mean, 3d 2h max (12)"
+func statsLatency(latency *drivio.Latency) string {
+	if latency == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%s median, %s mean, %s max (%d)",
+		statsDuration(latency.Median), statsDuration(latency.Mean), statsDuration(latency.Max), latency.Samples)
+}
+
+// statsDuration renders a duration in days, hours and minutes, down to the
+// two largest units
+func statsDuration(d time.Duration) string {
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	minutes := int(d.Minutes()) % 60
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	}
+	return fmt.Sprintf("%dm", minutes)
+}
+
+// topContributors returns the contributors listed with --top
+func topContributors(contributors []drivio.Contributor) []drivio.Contributor {
+	if statsTop > 0 && len(contributors) > statsTop {
+		return contributors[:statsTop]
+	}
+	return contributors
+}
+
+func writeStatsTable(out io.Writer, stats *drivio.Stats) {
+	fmt.Fprintf(out, "%s/%s %s..%s\n\n", stats.Owner, stats.Repo, stats.From, stats.To)
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Commits\t%d\n", stats.Commits)
+	fmt.Fprintf(w, "Pull requests\t%d\n", len(stats.PullRequests))
+	fmt.Fprintf(w, "Authors\t%d\n", len(stats.Authors))
+	fmt.Fprintf(w, "Merges per week\t%.1f\n", stats.MergesPerWeek)
+	fmt.Fprintf(w, "Time to first review\t%s\n", statsLatency(stats.TimeToFirstReview))
+	fmt.Fprintf(w, "Time to merge\t%s\n", statsLatency(stats.TimeToMerge))
+	w.Flush()
+
+	for _, list := range []struct {
+		header       string
+		contributors []drivio.Contributor
+	}{
+		{"AUTHOR\tCOMMITS", stats.Authors},
+		{"PULL REQUEST AUTHOR\tPULL REQUESTS", stats.PullRequestAuthors},
+	} {
+		if len(list.contributors) == 0 {
+			continue
+		}
+		fmt.Fprintln(out)
+		w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, list.header)
+		for _, contributor := range topContributors(list.contributors) {
+			fmt.Fprintf(w, "%s\t%d\n", contributor.Name, contributor.Count)
+		}
+		w.Flush()
+	}
+
+	if len(stats.Weeks) > 0 {
+		fmt.Fprintln(out)
+		w = tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "WEEK\tMERGES\t")
+		for _, week := range stats.Weeks {
+			fmt.Fprintf(w, "%s\t%d\t%s\n", week.Week, week.Merges, strings.Repeat("▇", week.Merges))
+		}
+		w.Flush()
+	}
+}
+
+func writeStatsMarkdown(out io.Writer, stats *drivio.Stats) {
+	fmt.Fprintf(out, "# Contributions to %s/%s from %s to %s\n\n", stats.Owner, stats.Repo, stats.From, stats.To)
+	fmt.Fprintln(out, "| | |")
+	fmt.Fprintln(out, "|---|---|")
+	fmt.Fprintf(out, "| Commits | %d |\n", stats.Commits)
+	fmt.Fprintf(out, "| Pull requests | %d |\n", len(stats.PullRequests))
+	fmt.Fprintf(out, "| Authors | %d |\n", len(stats.Authors))
+	fmt.Fprintf(out, "| Merges per week | %.1f |\n", stats.MergesPerWeek)
+	fmt.Fprintf(out, "| Time to first review | %s |\n", statsLatency(stats.TimeToFirstReview))
+	fmt.Fprintf(out, "| Time to merge | %s |\n", statsLatency(stats.TimeToMerge))
+
+	if len(stats.Authors) > 0 {
+		fmt.Fprint(out, "\n## Authors\n\n")
+		fmt.Fprintln(out, "| Author | Commits |")
+		fmt.Fprintln(out, "|---|---:|")
+		for _, author := range topContributors(stats.Authors) {
+			fmt.Fprintf(out, "| %s | %d |\n", author.Name, author.Count)
+		}
+	}
+	if len(stats.PullRequestAuthors) > 0 {
+		fmt.Fprint(out, "\n## Pull Request Authors\n\n")
+		fmt.Fprintln(out, "| Author | Pull requests |")
+		fmt.Fprintln(out, "|---|---:|")
+		for _, author := range topContributors(stats.PullRequestAuthors) {
+			fmt.Fprintf(out, "| @%s | %d |\n", author.Name, author.Count)
+		}
+	}
+
+	if len(stats.Weeks) > 0 {
+		fmt.Fprint(out, "\n## Merges per Week\n\n")
+		fmt.Fprintln(out, "| Week | Merges |")
+		fmt.Fprintln(out, "|---|---:|")
+		for _, week := range stats.Weeks {
+			fmt.Fprintf(out, "| %s | %d |\n", week.Week, week.Merges)
+		}
+	}
+}
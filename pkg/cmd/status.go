@@ -0,0 +1,370 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"drivio/pkg/manifest"
+	"drivio/pkg/notify"
+	"drivio/pkg/query"
+	"drivio/pkg/redact"
+	"drivio/pkg/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	statusManifest string
+	statusFields   []string
+	statusWorkers  int
+	statusFormat   string
+	statusExitCode bool
+	statusNotify   []string
+)
+
+// statusCmd represents the status command
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show what version is deployed in every environment",
+	Long: `Fetch the config file of every environment declared in a manifest and show the
+values that tell what is deployed, e.g. the version or the image tag, side by
+side in a table, so drift between environments is seen at a glance.
+
+The manifest is a batch fetch manifest (see fetch --manifest) whose sources are
+the environments, named by their name, with the fields to extract:
+
+  defaults:
+    repo: myorg/configs
+  fields:
+    - name: version
+      query: .image.tag
+    - name: image
+      query: .image.repository
+  sources:
+    - name: staging
+      file: envs/staging.yaml
+    - name: production
+      file: envs/production.yaml
+
+--field NAME=QUERY (repeatable) replaces the fields of the manifest. A field
+missing from an environment is shown as -. The fields whose values differ
+between environments are listed below the table; --exit-code then fails, e.g.
+to check in CI that the environments are in sync. --notify alerts a
+notification channel (see drivio notify) when they differ or cannot be
+fetched.
+
+Sensitive values are redacted as by fetch, unless --show-secrets is set.
+
+Examples:
+  drivio status
+  drivio status --manifest envs/drivio.status.yaml
+  drivio status --field version=.image.tag --field replicas=.replicas
+  drivio status --format json
+  drivio status --notify team-slack`,
+	RunE: runStatus,
+}
+
+func init() {
+	rootCmd.AddCommand(statusCmd)
+
+	// Add flags
+	statusCmd.Flags().StringVar(&statusManifest, "manifest", manifest.StatusFileName, "Manifest declaring the environments and the fields to show")
+	statusCmd.Flags().StringArrayVar(&statusFields, "field", nil, "Field to show as NAME=QUERY, replacing those of the manifest; repeatable")
+	statusCmd.Flags().IntVar(&statusWorkers, "parallel", 4, "Number of concurrent downloads")
+	statusCmd.Flags().StringVar(&statusFormat, "format", "text", "Output format: text or json")
+	statusCmd.Flags().BoolVar(&statusExitCode, "exit-code", false, "Exit with an error when the environments differ")
+	statusCmd.Flags().StringArrayVar(&statusNotify, "notify", nil, "Notification channel to alert when the environments differ: a channel of the config file, or TYPE:URL; repeatable")
+	statusCmd.Flags().BoolVar(&showSecrets, "show-secrets", false, "Do not redact sensitive values")
+	statusCmd.Flags().StringSliceVar(&redactPatterns, "redact-keys", nil, "Key patterns (regular expressions) whose values are redacted (default: password,passwd,token,secret,key,credential)")
+}
+
+// environmentStatus holds the values of the fields in an environment
+type environmentStatus struct {
+	Environment string `json:"environment"`
+	Repo        string `json:"repo"`
+	Ref         string `json:"ref,omitempty"`
+	File        string `json:"file"`
+	// Values are the values of the fields, missing when not in the file
+	Values map[string]string `json:"values"`
+	Error  string            `json:"error,omitempty"`
+}
+
+func runStatus(cmd *cobra.Command, args []string) error {
+	if statusFormat != "text" && statusFormat != "json" {
+		return fmt.Errorf("invalid --format %q: use text or json", statusFormat)
+	}
+	redactor, err := redact.New(redactPatterns)
+	if err != nil {
+		return err
+	}
+
+	m, err := manifest.Load(statusManifest)
+	if err != nil {
+		return err
+	}
+	fields := m.Fields
+	if len(statusFields) > 0 {
+		if fields, err = parseStatusFields(statusFields); err != nil {
+			return err
+		}
+	}
+	if len(fields) == 0 {
+		return fmt.Errorf("no fields to show: declare them in %s or use --field", statusManifest)
+	}
+	for _, field := range fields {
+		if err := query.Validate(field.Query); err != nil {
+			return fmt.Errorf("field %s: %w", field.Name, err)
+		}
+	}
+
+	channels, err := notifyChannels(statusNotify)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if err := preflightManifest(ctx, m.Sources); err != nil {
+		return err
+	}
+
+	workers := statusWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	var statuses []environmentStatus
+	message := fmt.Sprintf("Fetching %d environments...", len(m.Sources))
+	ui.RunProgress(message, ui.UnitItems, func(progress chan<- ui.ProgressMsg) error {
+		statuses = fetchStatuses(ctx, m.Sources, fields, redactor, workers, progress)
+		return nil
+	})
+
+	drift := driftingFields(statuses, fields)
+	failed := 0
+	for _, status := range statuses {
+		if status.Error != "" {
+			failed++
+		}
+	}
+
+	if statusFormat == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		if drift == nil {
+			drift = []string{}
+		}
+		if err := encoder.Encode(struct {
+			Environments []environmentStatus `json:"environments"`
+			Drift        []string            `json:"drift"`
+		}{statuses, drift}); err != nil {
+			return err
+		}
+	} else {
+		printStatusTable(statuses, fields)
+	}
+	if len(channels) > 0 && (failed > 0 || len(drift) > 0) {
+		sendNotification(ctx, channels, statusAlert(statuses, fields, drift, failed))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d environments could not be fetched", failed, len(statuses))
+	}
+	if len(drift) == 0 {
+		ui.Printf("✅ All %d environments are in sync\n", len(statuses))
+		return nil
+	}
+	ui.Printf("⚠️  %s differ between environments\n", strings.Join(drift, ", "))
+	if statusExitCode {
+		return fmt.Errorf("the environments differ: %s", strings.Join(drift, ", "))
+	}
+	return nil
+}
+
+// statusAlert is the notification of environments that differ or could not
+// be fetched, listing the values of every environment
+func statusAlert(statuses []environmentStatus, fields []manifest.Field, drift []string, failed int) notify.Message {
+	var problems []string
+	if failed > 0 {
+		problems = append(problems, fmt.Sprintf("%d of %d environments could not be fetched", failed, len(statuses)))
+	}
+	if len(drift) > 0 {
+		problems = append(problems, strings.Join(drift, ", ")+" differ between environments")
+	}
+
+	var lines []string
+	for _, status := range statuses {
+		if status.Error != "" {
+			lines = append(lines, fmt.Sprintf("%s: %s", status.Environment, status.Error))
+			continue
+		}
+		values := make([]string, 0, len(fields))
+		for _, field := range fields {
+			value, ok := status.Values[field.Name]
+			if !ok {
+				value = "-"
+			}
+			values = append(values, field.Name+"="+value)
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", status.Environment, strings.Join(values, ", ")))
+	}
+	return notify.Message{
+		Title: "drivio status: " + strings.Join(problems, "; "),
+		Text:  strings.Join(lines, "\n"),
+	}
+}
+
+// parseStatusFields parses the --field flags, given as NAME=QUERY
+func parseStatusFields(values []string) ([]manifest.Field, error) {
+	fields := make([]manifest.Field, 0, len(values))
+	for _, value := range values {
+		name, path, ok := strings.Cut(value, "=")
+		if !ok || name == "" || path == "" {
+			return nil, fmt.Errorf("invalid --field %q: use NAME=QUERY, e.g. version=.image.tag", value)
+		}
+		fields = append(fields, manifest.Field{Name: name, Query: path})
+	}
+	return fields, nil
+}
+
+// fetchStatuses fetches every environment with the given number of workers
+// and extracts the fields. Statuses are returned in manifest order.
+func fetchStatuses(ctx context.Context, sources []manifest.Source, fields []manifest.Field, redactor *redact.Redactor, workers int, progress chan<- ui.ProgressMsg) []environmentStatus {
+	statuses := make([]environmentStatus, len(sources))
+	jobs := make(chan int)
+
+	var mu sync.Mutex
+	done := 0
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				statuses[i] = fetchEnvironmentStatus(ctx, sources[i], fields, redactor)
+
+				mu.Lock()
+				done++
+				progress <- ui.ProgressMsg{Current: int64(done), Total: int64(len(sources)), Message: sources[i].Name}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for i := range sources {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return statuses
+}
+
+// fetchEnvironmentStatus fetches the file of an environment and extracts the
+// fields
+func fetchEnvironmentStatus(ctx context.Context, source manifest.Source, fields []manifest.Field, redactor *redact.Redactor) environmentStatus {
+	status := environmentStatus{Environment: source.Name, Repo: source.Repo, Ref: source.Ref, File: source.File, Values: map[string]string{}}
+
+	content, err := fetchStatusFile(ctx, source)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	if !showSecrets {
+		content, _ = redactor.Redact(content)
+	}
+
+	for _, field := range fields {
+		value, err := query.Extract(content, field.Query)
+		if errors.Is(err, query.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			status.Error = err.Error()
+			return status
+		}
+		// Mappings and sequences are shown on a single line
+		status.Values[field.Name] = strings.Join(strings.Fields(string(value)), " ")
+	}
+	return status
+}
+
+// fetchStatusFile fetches the file of an environment, on the first existing
+// branch when several are given
+func fetchStatusFile(ctx context.Context, source manifest.Source) ([]byte, error) {
+	cfg, err := manifestSourceConfig(source)
+	if err != nil {
+		return nil, err
+	}
+	client, err := newFetchClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.Branches()) > 1 {
+		if err := resolveBranch(ctx, client, cfg); err != nil {
+			return nil, err
+		}
+	}
+	return client.GetFile(ctx)
+}
+
+// driftingFields returns the names of the fields whose values differ between
+// the environments fetched, a missing value differing from any other
+func driftingFields(statuses []environmentStatus, fields []manifest.Field) []string {
+	var drift []string
+	for _, field := range fields {
+		values := make(map[string]bool)
+		for _, status := range statuses {
+			if status.Error != "" {
+				continue
+			}
+			value, ok := status.Values[field.Name]
+			if !ok {
+				value = "\x00missing"
+			}
+			values[value] = true
+		}
+		if len(values) > 1 {
+			drift = append(drift, field.Name)
+		}
+	}
+	return drift
+}
+
+// printStatusTable prints a row per environment and a column per field
+func printStatusTable(statuses []environmentStatus, fields []manifest.Field) {
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := []string{"ENVIRONMENT", "REF"}
+	for _, field := range fields {
+		header = append(header, strings.ToUpper(field.Name))
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, status := range statuses {
+		ref := status.Ref
+		if ref == "" {
+			ref = "(default)"
+		}
+		row := []string{status.Environment, ref}
+		if status.Error != "" {
+			ui.Fprintf(w, "%s\t❌ %s\n", strings.Join(row, "\t"), status.Error)
+			continue
+		}
+		for _, field := range fields {
+			value, ok := status.Values[field.Name]
+			if !ok {
+				value = "-"
+			}
+			row = append(row, value)
+		}
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	w.Flush()
+	fmt.Println()
+}
@@ -0,0 +1,335 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	"drivio/pkg/audit"
+	"drivio/pkg/config"
+	"drivio/pkg/git"
+	"drivio/pkg/github"
+	"drivio/pkg/gitlab"
+	"drivio/pkg/ui"
+	"drivio/pkg/verify"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	tagProvider    string
+	tagTarget      string
+	tagMessage     string
+	tagSign        bool
+	tagSigningKey  string
+	tagTagger      string
+	tagGitHubToken string
+	tagDryRun      bool
+)
+
+// tagCmd represents the tag command
+var tagCmd = &cobra.Command{
+	Use:   "tag NAME",
+	Short: "Create a tag through the GitHub or GitLab API",
+	Long: `Create a tag of a GitHub or GitLab repository through its API, without a local
+clone: lightweight by default, annotated with --message, and signed with
+--sign.
+
+The tag points to --target, a branch, tag or commit, by default the head of
+the default branch (GitHub) or of --branch (GitLab). The target must be a
+commit of the repository. Existing tags are never moved: when the tag exists
+already, tag succeeds if it points to the target, and fails otherwise.
+
+--sign signs an annotated tag on GitHub with the OpenPGP private key of
+--signing-key, decrypted with DRIVIO_SIGNING_KEY_PASSPHRASE when protected;
+set it in the tag section of the config file to sign every tag. The tagger
+is --tagger, by default the primary user ID of the key, and must match an
+email of the GitHub account holding the public key for GitHub to verify the
+signature. The GitLab API cannot create signed tags.
+
+Examples:
+  drivio tag v1.2.3 --provider github --repo myorg/myrepo
+  drivio tag v1.2.3 --provider github --repo myorg/myrepo --target 1a2b3c4 --message "Release v1.2.3"
+  drivio tag v1.2.3 --provider github --repo myorg/myrepo --message "Release v1.2.3" --sign --signing-key release-key.asc
+  drivio tag v1.2.3 --repo mygroup/myproject --target release-1.2 --message "Release v1.2.3"`,
+	Args: cobra.ExactArgs(1),
+	RunE: runTag,
+}
+
+func init() {
+	rootCmd.AddCommand(tagCmd)
+
+	tagCmd.Flags().StringVar(&repositoryPath, "repo", "", "Repository path (e.g., owner/repo)")
+	tagCmd.Flags().StringVar(&tagProvider, "provider", "gitlab", "Repository provider: gitlab or github")
+	tagCmd.Flags().StringVar(&tagTarget, "target", "", "Branch, tag or commit to tag (default: the default branch on GitHub, --branch on GitLab)")
+	tagCmd.Flags().StringVar(&tagMessage, "message", "", "Message of an annotated tag (default: a lightweight tag)")
+	tagCmd.Flags().BoolVar(&tagSign, "sign", false, "Sign the annotated tag with --signing-key (GitHub)")
+	tagCmd.Flags().StringVar(&tagSigningKey, "signing-key", "", "OpenPGP private key file signing the tag with --sign, armored or binary")
+	tagCmd.Flags().StringVar(&tagTagger, "tagger", "", `Tagger of a signed tag, as "Name <email>" (default: the primary user ID of the key)`)
+	tagCmd.Flags().StringVar(&tagGitHubToken, "github-token", "", "GitHub token with write access to the repository contents")
+	tagCmd.Flags().StringVar(&gitlabURL, "url", "", "GitLab URL (default: https://gitlab.com)")
+	tagCmd.Flags().StringVar(&gitlabToken, "token", "", "GitLab access token with the api scope")
+	tagCmd.Flags().StringVar(&gitlabInstance, "instance", "", "GitLab instance of the config file providing the URL and token")
+	tagCmd.Flags().StringVar(&branch, "branch", "", "GitLab branch tagged without --target (default: main)")
+	tagCmd.Flags().BoolVar(&tagDryRun, "dry-run", false, "Check the target and the tag without creating it")
+
+	// Environment variables that take precedence over the config file
+	bindFlagEnv(tagCmd.Flags(), "github-token", config.EnvGitHubToken...)
+	bindFlagEnv(tagCmd.Flags(), "url", config.EnvGitLabURL...)
+	bindFlagEnv(tagCmd.Flags(), "token", config.EnvGitLabToken...)
+	bindFlagEnv(tagCmd.Flags(), "instance", config.EnvInstance...)
+	bindFlagEnv(tagCmd.Flags(), "branch", config.EnvBranch...)
+
+	// Mark required flags
+	tagCmd.MarkFlagRequired("repo")
+}
+
+// tagProviderAPI is what tag needs from the API of a provider
+type tagProviderAPI struct {
+	// name names the repository, e.g. in errors
+	name string
+	// resolve returns the commit of the target, the default one when empty
+	resolve func(ctx context.Context, ref string) (string, error)
+	// existing returns the commit the tag points to, "" when it does not
+	// exist
+	existing func(ctx context.Context, name string) (string, error)
+	// create creates the tag, annotated when message is set, and returns
+	// its URL
+	create func(ctx context.Context, name, sha, message string) (string, error)
+}
+
+func runTag(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	if err := validTagName(name); err != nil {
+		return err
+	}
+	if tagSign && tagMessage == "" {
+		return fmt.Errorf("--sign needs --message: only annotated tags are signed")
+	}
+	if tagSign && tagSigningKey == "" {
+		return fmt.Errorf("--sign needs --signing-key, the OpenPGP private key signing the tag")
+	}
+
+	var api *tagProviderAPI
+	var err error
+	switch strings.ToLower(tagProvider) {
+	case "github":
+		api, err = githubTagAPI()
+	case "gitlab":
+		if tagSign {
+			return fmt.Errorf("the GitLab API cannot create signed tags: sign the tag in a clone with git tag -s and push it")
+		}
+		api, err = gitlabTagAPI()
+	default:
+		err = fmt.Errorf("unsupported provider: %s (use gitlab or github)", tagProvider)
+	}
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	var sha, current string
+	if err := ui.RunSpinner(fmt.Sprintf("Checking %s...", api.name), func() error {
+		var err error
+		if sha, err = api.resolve(ctx, tagTarget); err != nil {
+			target := tagTarget
+			if target == "" {
+				target = "the default branch"
+			}
+			return fmt.Errorf("cannot tag %s of %s: %w", target, api.name, err)
+		}
+		current, err = api.existing(ctx, name)
+		return err
+	}); err != nil {
+		return err
+	}
+
+	switch {
+	case current == sha:
+		ui.Printf("✅ Tag %s already points to %s\n", name, shortSHA(sha))
+		return nil
+	case current != "":
+		return fmt.Errorf("tag %s already exists at %s: tags are never moved, delete it first or choose another name", name, shortSHA(current))
+	}
+
+	kind := "lightweight tag"
+	switch {
+	case tagSign:
+		kind = "signed tag"
+	case tagMessage != "":
+		kind = "annotated tag"
+	}
+	if tagDryRun {
+		ui.Printf("🏷️  Would create %s %s at %s in %s\n", kind, name, shortSHA(sha), api.name)
+		return nil
+	}
+
+	var url string
+	if err := ui.RunSpinner(fmt.Sprintf("Creating %s %s...", kind, name), func() error {
+		var err error
+		url, err = api.create(ctx, name, sha, tagMessage)
+		return err
+	}); err != nil {
+		return err
+	}
+	ui.Printf("🏷️  Created %s %s at %s\n", kind, name, shortSHA(sha))
+	auditAction(audit.ActionTag, api.name+"@"+name, url, map[string]string{"sha": sha, "kind": kind})
+
+	if tagSign {
+		reportTagVerification(ctx, name)
+	}
+	return nil
+}
+
+// githubTagAPI returns the API of the GitHub repository of --repo
+func githubTagAPI() (*tagProviderAPI, error) {
+	owner, repo, ok := strings.Cut(repositoryPath, "/")
+	if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+		return nil, fmt.Errorf("invalid repository path: %s", repositoryPath)
+	}
+	if tagGitHubToken == "" && !tagDryRun {
+		return nil, fmt.Errorf("GitHub token is required to create tags. Set GITHUB_TOKEN environment variable or use --github-token flag")
+	}
+	if !tagDryRun {
+		if err := preflightGitHub(context.Background(), tagGitHubToken, owner, repo); err != nil {
+			return nil, err
+		}
+	}
+
+	var key *verify.SigningKey
+	if tagSign {
+		var err error
+		if key, err = verify.LoadSigningKey(tagSigningKey, config.GetEnv(config.EnvSigningKeyPassphrase...)); err != nil {
+			return nil, err
+		}
+	}
+
+	client := github.NewClient(tagGitHubToken)
+	return &tagProviderAPI{
+		name: owner + "/" + repo,
+		resolve: func(ctx context.Context, ref string) (string, error) {
+			if ref == "" {
+				var err error
+				if ref, err = client.DefaultBranch(ctx, owner, repo); err != nil {
+					return "", err
+				}
+			}
+			return client.ResolveCommit(ctx, owner, repo, ref)
+		},
+		existing: func(ctx context.Context, name string) (string, error) {
+			sha, err := client.TagCommit(ctx, owner, repo, name)
+			if errors.Is(err, github.ErrNotFound) {
+				return "", nil
+			}
+			return sha, err
+		},
+		create: func(ctx context.Context, name, sha, message string) (string, error) {
+			url := fmt.Sprintf("https://github.com/%s/%s/releases/tag/%s", owner, repo, name)
+			if message == "" {
+				return url, client.CreateLightweightTag(ctx, owner, repo, name, sha)
+			}
+			opts := github.TagOptions{Name: name, Message: message, SHA: sha}
+			if key != nil {
+				signed, tagger, err := signTag(key, name, sha, message)
+				if err != nil {
+					return "", err
+				}
+				opts.Message, opts.Tagger = signed, tagger
+			}
+			_, err := client.CreateTag(ctx, owner, repo, opts)
+			return url, err
+		},
+	}, nil
+}
+
+// gitlabTagAPI returns the API of the GitLab repository of --repo
+func gitlabTagAPI() (*tagProviderAPI, error) {
+	cfg := loadFetchConfig()
+	client, err := newFetchClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &tagProviderAPI{
+		name: cfg.RepositoryPath,
+		resolve: func(ctx context.Context, ref string) (string, error) {
+			if ref == "" {
+				ref = cfg.Branch
+			}
+			return client.ResolveCommit(ctx, ref)
+		},
+		existing: func(ctx context.Context, name string) (string, error) {
+			sha, err := client.TagCommit(ctx, name)
+			if errors.Is(err, gitlab.ErrNotFound) {
+				return "", nil
+			}
+			return sha, err
+		},
+		create: func(ctx context.Context, name, sha, message string) (string, error) {
+			if _, err := client.CreateTag(ctx, name, sha, message); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%s/%s/-/tags/%s", strings.TrimRight(cfg.GitLabURL, "/"), cfg.RepositoryPath, name), nil
+		},
+	}, nil
+}
+
+// signTag signs the tag object GitHub will create from the message and the
+// tagger, and returns the message with the signature appended, as git tag
+// -s writes it. The date is in UTC, the only time zone the API keeps.
+func signTag(key *verify.SigningKey, name, sha, message string) (string, *github.Tagger, error) {
+	taggerName, taggerEmail := key.Identity()
+	if tagTagger != "" {
+		address, err := mail.ParseAddress(tagTagger)
+		if err != nil {
+			return "", nil, fmt.Errorf(`invalid --tagger %q: use "Name <email>"`, tagTagger)
+		}
+		taggerName, taggerEmail = address.Name, address.Address
+	}
+	if taggerName == "" || taggerEmail == "" {
+		return "", nil, fmt.Errorf(`the signing key has no "Name <email>" user ID: set the tagger with --tagger`)
+	}
+
+	if !strings.HasSuffix(message, "\n") {
+		message += "\n"
+	}
+	date := time.Now().UTC().Truncate(time.Second)
+	object := git.TagObject{Object: sha, Name: name, TaggerName: taggerName, TaggerEmail: taggerEmail, Date: date, Message: message}
+	signature, err := key.SignDetached(object.Payload())
+	if err != nil {
+		return "", nil, err
+	}
+	ui.Debugf(1, "Signed tag %s with %s", name, key)
+	return message + string(signature), &github.Tagger{Name: taggerName, Email: taggerEmail, Date: date.Format(time.RFC3339)}, nil
+}
+
+// reportTagVerification reports whether GitHub verifies the signature of the
+// tag created, which needs the key in the account of the tagger
+func reportTagVerification(ctx context.Context, name string) {
+	owner, repo, _ := strings.Cut(repositoryPath, "/")
+	analyzer := git.NewAnalyzerWithClient(github.NewClient(tagGitHubToken))
+	verification, err := analyzer.VerifyTag(ctx, owner, repo, name)
+	switch {
+	case err != nil:
+		ui.Printf("⚠️  Failed to check the signature of tag %s: %v\n", name, err)
+	case verification == nil:
+	case verification.Verified:
+		ui.Printf("🔏 GitHub verified the signature of tag %s\n", name)
+	default:
+		ui.Printf("⚠️  GitHub does not verify the signature of tag %s (%s): add the public key to the account of the tagger\n", name, verification.Reason)
+	}
+}
+
+// tagNameInvalid matches what git check-ref-format rejects in a tag name
+var tagNameInvalid = regexp.MustCompile(`[\x00-\x20\x7f~^:?*\[\\]|\.\.|@\{|//|^[-/.]|[/.]$|\.lock$|/\.|\.lock/`)
+
+// validTagName checks a tag name is a valid git reference name
+func validTagName(name string) error {
+	if name == "" || name == "@" || tagNameInvalid.MatchString(name) {
+		return fmt.Errorf("invalid tag name %q", name)
+	}
+	return nil
+}
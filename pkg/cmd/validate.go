@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"drivio/pkg/config"
+	"drivio/pkg/schema"
+	"drivio/pkg/ui"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	validateJSONSchema  string
+	validateCUESchema   string
+	validateRemoteFiles []string
+	validateFormat      string
+)
+
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate [FILE...]",
+	Short: "Validate config files against a JSON Schema or CUE definition",
+	Long: `Validate YAML (or JSON) files against a JSON Schema (--schema, written in JSON
+or YAML) or a CUE definition (--cue), and report every violation of every file
+with its path, e.g. /database/port. The command fails when any file is invalid,
+so it can gate the merge requests of a config repository.
+
+Files are local paths, or paths in a GitLab repository given with --remote
+(repeatable), read from --repo at --branch with the GitLab settings of fetch.
+Both can be mixed, e.g. to check the files of a merge request locally and
+the production file as it is.
+
+Examples:
+  drivio validate config/*.yaml --schema schemas/config.schema.json
+  drivio validate config/production.yaml --cue schemas/config.cue#Config
+  drivio validate --repo myorg/configs --branch main --remote config/production.yaml --remote config/staging.yaml --schema schema.yaml
+  drivio validate config/*.yaml --schema schema.json --format json`,
+	RunE: runValidate,
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+
+	// Add flags
+	validateCmd.Flags().StringVar(&validateJSONSchema, "schema", "", "JSON Schema file (JSON or YAML) to validate against")
+	validateCmd.Flags().StringVar(&validateCUESchema, "cue", "", "CUE definition to validate against (e.g., schema.cue#Config)")
+	validateCmd.Flags().StringArrayVar(&validateRemoteFiles, "remote", nil, "Path of a file of --repo to validate; repeatable")
+	validateCmd.Flags().StringVar(&validateFormat, "format", "text", "Output format: text or json")
+	validateCmd.Flags().StringVar(&gitlabURL, "url", "", "GitLab URL (default: https://gitlab.com)")
+	validateCmd.Flags().StringVar(&gitlabToken, "token", "", "GitLab access token")
+	validateCmd.Flags().StringVar(&gitlabInstance, "instance", "", "GitLab instance of the config file providing the URL and token")
+	validateCmd.Flags().StringVar(&repositoryPath, "repo", "", "Repository of the --remote files (e.g., owner/repo)")
+	validateCmd.Flags().StringVar(&branch, "branch", "", "Branch of the --remote files (default: main)")
+
+	// Environment variables that take precedence over the config file
+	bindFlagEnv(validateCmd.Flags(), "url", config.EnvGitLabURL...)
+	bindFlagEnv(validateCmd.Flags(), "token", config.EnvGitLabToken...)
+	bindFlagEnv(validateCmd.Flags(), "instance", config.EnvInstance...)
+	bindFlagEnv(validateCmd.Flags(), "repo", config.EnvRepoPath...)
+	bindFlagEnv(validateCmd.Flags(), "branch", config.EnvBranch...)
+}
+
+// fileValidation is the result of the validation of a file
+type fileValidation struct {
+	File       string             `json:"file"`
+	Valid      bool               `json:"valid"`
+	Violations []schema.Violation `json:"violations,omitempty"`
+}
+
+func runValidate(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 && len(validateRemoteFiles) == 0 {
+		return fmt.Errorf("no file to validate: give local files as arguments or remote ones with --remote")
+	}
+	if validateJSONSchema == "" && validateCUESchema == "" {
+		return fmt.Errorf("--schema or --cue is required")
+	}
+	if validateFormat != "text" && validateFormat != "json" {
+		return fmt.Errorf("invalid --format %q: use text or json", validateFormat)
+	}
+
+	// Compile the schemas once for all the files
+	var validators []*schema.Validator
+	if validateJSONSchema != "" {
+		validator, err := schema.NewJSONSchemaValidator(validateJSONSchema)
+		if err != nil {
+			return err
+		}
+		validators = append(validators, validator)
+	}
+	if validateCUESchema != "" {
+		validator, err := schema.NewCUEValidator(validateCUESchema)
+		if err != nil {
+			return err
+		}
+		validators = append(validators, validator)
+	}
+
+	var results []fileValidation
+	for _, path := range args {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		results = append(results, validateContent(path, content, validators))
+	}
+
+	if len(validateRemoteFiles) > 0 {
+		cfg := loadFetchConfig()
+		cfg.FilePath = validateRemoteFiles[0]
+		if err := cfg.ValidateConfig(); err != nil {
+			return fmt.Errorf("configuration error: %w", err)
+		}
+		client, err := newFetchClient(cfg)
+		if err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		for _, path := range validateRemoteFiles {
+			name := fmt.Sprintf("%s@%s:%s", cfg.RepositoryPath, cfg.Branch, path)
+			var content []byte
+			if err := ui.RunSpinner(fmt.Sprintf("Fetching %s...", name), func() error {
+				var err error
+				content, err = client.GetFileByPath(ctx, path)
+				return err
+			}); err != nil {
+				return fmt.Errorf("failed to fetch %s: %w", name, err)
+			}
+			results = append(results, validateContent(name, content, validators))
+		}
+	}
+
+	invalid, violations := 0, 0
+	for _, result := range results {
+		if !result.Valid {
+			invalid++
+			violations += len(result.Violations)
+		}
+	}
+
+	if validateFormat == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		for _, result := range results {
+			if result.Valid {
+				ui.Fprintf(os.Stdout, "✅ %s\n", result.File)
+				continue
+			}
+			ui.Fprintf(os.Stdout, "❌ %s\n", result.File)
+			for _, v := range result.Violations {
+				fmt.Printf("   %s: %s\n", v.Path, v.Message)
+			}
+		}
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("%d of %d file(s) failed validation (%d violation(s))", invalid, len(results), violations)
+	}
+	ui.Printf("📐 %d file(s) valid\n", len(results))
+	return nil
+}
+
+// validateContent validates a file against every schema, collecting all the
+// violations. Documents that cannot be parsed are reported as a violation
+// of the whole document.
+func validateContent(name string, content []byte, validators []*schema.Validator) fileValidation {
+	result := fileValidation{File: name, Valid: true}
+	for _, validator := range validators {
+		err := validator.Validate(content)
+		if err == nil {
+			continue
+		}
+		result.Valid = false
+		var validationErr *schema.ValidationError
+		if errors.As(err, &validationErr) {
+			result.Violations = append(result.Violations, validationErr.Violations...)
+		} else {
+			result.Violations = append(result.Violations, schema.Violation{Path: "/", Message: err.Error()})
+		}
+	}
+	return result
+}
@@ -0,0 +1,291 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"drivio/pkg/httplog"
+	"drivio/pkg/ui"
+
+	"github.com/spf13/cobra"
+)
+
+// States of a checked link
+const (
+	linkAlive   = "ok"
+	linkDead    = "dead"
+	linkUnknown = "unknown"
+)
+
+var (
+	linksWorkers int
+	linksRate    float64
+	linksTimeout time.Duration
+	linksFormat  string
+	linksAll     bool
+)
+
+// linkPattern matches the URLs of Markdown, text and JSON notes; the
+// trailing punctuation of a sentence is trimmed after
+var linkPattern = regexp.MustCompile(`https?://[^\s<>()\[\]"'` + "`" + `]+`)
+
+// verifyLinksCmd represents the verify-links command
+var verifyLinksCmd = &cobra.Command{
+	Use:   "verify-links [FILE]",
+	Short: "Check that the commit, pull request and ticket links of release notes resolve",
+	Long: `Check every link of release notes, commits, pull requests, merge requests
+and tickets, with a HEAD request, and report the dead ones, to catch typos in
+the ticket URL templates before publishing the notes.
+
+The notes are FILE (- for stdin), by default the latest notes generated in
+the work directory. Links are checked by --parallel workers, at most --rate
+requests per second. A link is dead when it answers 404, 410, another client
+or server error, or not at all; 401, 403 and 429 tell nothing about the link
+and are reported as unknown, e.g. for pages that need signing in. Links of
+private repositories and trackers are checked without credentials.
+
+drivio fails when a link is dead, so it can gate publishing in CI.
+
+Examples:
+  drivio verify-links
+  drivio verify-links .drivio-work/release-notes-openshift-hypershift-v0.1.59-v0.1.63.md
+  drivio verify-links notes.md --parallel 4 --rate 2 --all
+  drivio verify-links notes.md --format json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runVerifyLinks,
+}
+
+func init() {
+	rootCmd.AddCommand(verifyLinksCmd)
+
+	// Add flags
+	verifyLinksCmd.Flags().IntVar(&linksWorkers, "parallel", 8, "Number of concurrent requests")
+	verifyLinksCmd.Flags().Float64Var(&linksRate, "rate", 10, "Maximum requests per second (0: no limit)")
+	verifyLinksCmd.Flags().DurationVar(&linksTimeout, "timeout", 10*time.Second, "Timeout of each request")
+	verifyLinksCmd.Flags().StringVar(&linksFormat, "format", "text", "Output format: text or json")
+	verifyLinksCmd.Flags().BoolVar(&linksAll, "all", false, "List every link, not only the dead and unknown ones")
+}
+
+// noteLink is a link of the notes and the result of its check
+type noteLink struct {
+	URL    string `json:"url"`
+	Kind   string `json:"kind"`
+	Line   int    `json:"line"`
+	State  string `json:"state"`
+	Status int    `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+func runVerifyLinks(cmd *cobra.Command, args []string) error {
+	if linksFormat != "text" && linksFormat != "json" {
+		return fmt.Errorf("invalid --format %q: use text or json", linksFormat)
+	}
+	if linksRate < 0 {
+		return fmt.Errorf("invalid --rate %g", linksRate)
+	}
+
+	var content []byte
+	var err error
+	switch {
+	case len(args) > 0 && args[0] == "-":
+		content, err = io.ReadAll(os.Stdin)
+	case len(args) > 0:
+		content, err = os.ReadFile(args[0])
+	default:
+		var path string
+		if path, _, err = latestNotes(workDir); err == nil {
+			ui.Printf("📄 %s\n", path)
+			content, err = os.ReadFile(path)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read notes: %w", err)
+	}
+	cmd.SilenceUsage = true
+
+	links := extractLinks(string(content))
+	if len(links) == 0 {
+		ui.Println("📭 No links in the notes")
+		return nil
+	}
+
+	workers := linksWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	ctx := context.Background()
+	message := fmt.Sprintf("Checking %d links...", len(links))
+	ui.RunProgress(message, ui.UnitItems, func(progress chan<- ui.ProgressMsg) error {
+		checkLinks(ctx, links, workers, progress)
+		return nil
+	})
+
+	dead, unknown := 0, 0
+	for _, link := range links {
+		switch link.State {
+		case linkDead:
+			dead++
+		case linkUnknown:
+			unknown++
+		}
+	}
+
+	if linksFormat == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(links); err != nil {
+			return err
+		}
+	} else if linksAll || dead+unknown > 0 {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "STATE\tSTATUS\tKIND\tLINE\tURL")
+		for _, link := range links {
+			if link.State == linkAlive && !linksAll {
+				continue
+			}
+			status := "-"
+			if link.Status != 0 {
+				status = fmt.Sprint(link.Status)
+			} else if link.Error != "" {
+				status = link.Error
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", link.State, status, link.Kind, link.Line, link.URL)
+		}
+		w.Flush()
+	}
+
+	if unknown > 0 {
+		ui.Printf("⚠️  %d of %d links could not be checked\n", unknown, len(links))
+	}
+	if dead > 0 {
+		return fmt.Errorf("%d of %d links are dead", dead, len(links))
+	}
+	ui.Printf("✅ %d links checked, none dead\n", len(links)-unknown)
+	return nil
+}
+
+// extractLinks returns the distinct links of the notes, with the line they
+// first appear on
+func extractLinks(content string) []*noteLink {
+	var links []*noteLink
+	seen := make(map[string]bool)
+	for i, line := range strings.Split(content, "\n") {
+		for _, match := range linkPattern.FindAllString(line, -1) {
+			match = strings.TrimRight(match, ".,;:!?*_")
+			if seen[match] {
+				continue
+			}
+			seen[match] = true
+			links = append(links, &noteLink{URL: match, Kind: linkKind(match), Line: i + 1})
+		}
+	}
+	return links
+}
+
+// linkKind tells what a link of the notes points to, from the URL schemes
+// of GitHub, GitLab and Jira
+func linkKind(link string) string {
+	switch {
+	case strings.Contains(link, "/commit/"):
+		return "commit"
+	case strings.Contains(link, "/pull/"):
+		return "pull request"
+	case strings.Contains(link, "/merge_requests/"):
+		return "merge request"
+	case strings.Contains(link, "/browse/"), strings.Contains(link, "/issues/"):
+		return "ticket"
+	}
+	return "link"
+}
+
+// checkLinks checks the links with the given number of workers, sharing
+// the rate limit, and reports the links done on the progress channel
+func checkLinks(ctx context.Context, links []*noteLink, workers int, progress chan<- ui.ProgressMsg) {
+	client := httplog.NewClient(linksTimeout)
+	var limit <-chan time.Time
+	if linksRate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / linksRate))
+		defer ticker.Stop()
+		limit = ticker.C
+	}
+
+	jobs := make(chan *noteLink)
+	var mu sync.Mutex
+	done := 0
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for link := range jobs {
+				if limit != nil {
+					<-limit
+				}
+				checkLink(ctx, client, link)
+
+				mu.Lock()
+				done++
+				progress <- ui.ProgressMsg{Current: int64(done), Total: int64(len(links)), Message: link.URL}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, link := range links {
+		jobs <- link
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// checkLink requests a link with HEAD, or with GET when the server does not
+// support HEAD, and sets its state
+func checkLink(ctx context.Context, client *http.Client, link *noteLink) {
+	status, err := requestLink(ctx, client, http.MethodHead, link.URL)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		status, err = requestLink(ctx, client, http.MethodGet, link.URL)
+	}
+	link.Status = status
+	switch {
+	case err != nil:
+		link.State, link.Error = linkDead, err.Error()
+	case status == http.StatusUnauthorized, status == http.StatusForbidden, status == http.StatusTooManyRequests:
+		link.State = linkUnknown
+	case status >= 400:
+		link.State = linkDead
+	default:
+		link.State = linkAlive
+	}
+}
+
+func requestLink(ctx context.Context, client *http.Client, method, link string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, link, nil)
+	if err != nil {
+		return 0, fmt.Errorf("invalid URL: %w", err)
+	}
+	req.Header.Set("User-Agent", "drivio")
+	resp, err := client.Do(req)
+	if err != nil {
+		// The URL is listed already
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return 0, urlErr.Err
+		}
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
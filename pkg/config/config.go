@@ -1,8 +1,9 @@
 package config
 
 import (
-	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Config holds the application configuration
@@ -12,6 +13,13 @@ type Config struct {
 	RepositoryPath string
 	Branch         string
 	FilePath       string
+	FilePattern    string
+	Environment    string
+	RetryAttempts  int
+	RetryBackoff   time.Duration
+	// Visibility is the visibility of the repository (public, internal or
+	// private) as reported by GitLab, empty until it has been checked
+	Visibility string
 }
 
 // Default values
@@ -20,29 +28,60 @@ const (
 	DefaultRepositoryPath = "jparrill/drivio-config"
 	DefaultBranch         = "main"
 	DefaultFilePath       = "config/environment.yaml"
+	DefaultRetryAttempts  = 3
+	DefaultRetryBackoff   = 1 * time.Second
+
+	// VisibilityPublic is the visibility of repositories readable without a token
+	VisibilityPublic = "public"
+
+	// EnvironmentPlaceholder is replaced by the environment name in file patterns
+	EnvironmentPlaceholder = "{env}"
 )
 
 // LoadConfig loads configuration from environment variables and defaults
 func LoadConfig() *Config {
 	config := &Config{
-		GitLabURL:      getEnvOrDefault("GITLAB_URL", DefaultGitLabURL),
-		GitLabToken:    getEnvOrDefault("GITLAB_TOKEN", ""),
-		RepositoryPath: getEnvOrDefault("GITLAB_REPO_PATH", DefaultRepositoryPath),
-		Branch:         getEnvOrDefault("GITLAB_BRANCH", DefaultBranch),
-		FilePath:       getEnvOrDefault("GITLAB_FILE_PATH", DefaultFilePath),
+		GitLabURL:      getEnvOrDefault(EnvGitLabURL, DefaultGitLabURL),
+		GitLabToken:    getEnvOrDefault(EnvGitLabToken, ""),
+		RepositoryPath: getEnvOrDefault(EnvRepoPath, DefaultRepositoryPath),
+		Branch:         getEnvOrDefault(EnvBranch, DefaultBranch),
+		FilePath:       getEnvOrDefault(EnvFilePath, DefaultFilePath),
+		FilePattern:    getEnvOrDefault(EnvFilePattern, ""),
+		RetryAttempts:  getEnvIntOrDefault(EnvRetryAttempts, DefaultRetryAttempts),
+		RetryBackoff:   getEnvDurationOrDefault(EnvRetryBackoff, DefaultRetryBackoff),
 	}
 
 	return config
 }
 
 // getEnvOrDefault returns environment variable value or default
-func getEnvOrDefault(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
+func getEnvOrDefault(keys []string, defaultValue string) string {
+	if value := GetEnv(keys...); value != "" {
 		return value
 	}
 	return defaultValue
 }
 
+// getEnvIntOrDefault returns environment variable value as an int or default
+func getEnvIntOrDefault(keys []string, defaultValue int) int {
+	if value := GetEnv(keys...); value != "" {
+		if parsed, err := strconv.Atoi(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
+// getEnvDurationOrDefault returns environment variable value as a duration or default
+func getEnvDurationOrDefault(keys []string, defaultValue time.Duration) time.Duration {
+	if value := GetEnv(keys...); value != "" {
+		if parsed, err := time.ParseDuration(value); err == nil {
+			return parsed
+		}
+	}
+	return defaultValue
+}
+
 // ValidateConfig validates the configuration
 func (c *Config) ValidateConfig() error {
 	// For public repositories, token is optional
@@ -55,26 +94,50 @@ func (c *Config) ValidateConfig() error {
 		return &ConfigError{Message: "File path is required"}
 	}
 
+	if c.RetryAttempts < 1 {
+		return &ConfigError{Message: "Retry attempts must be at least 1"}
+	}
+
 	return nil
 }
 
-// IsPublicRepository checks if this is likely a public repository
-func (c *Config) IsPublicRepository() bool {
-	// Common public repositories that don't require authentication
-	publicRepos := []string{
-		"gitlab-org/gitlab-foss",
-		"gitlab-org/gitlab",
-		"gitlab-org/gitlab-runner",
-		"gitlab-org/charts",
+// ResolveEnvironment sets the file path for the given environment by
+// expanding the {env} placeholder of the file pattern. When no pattern is
+// configured, the file path itself is used as the pattern.
+func (c *Config) ResolveEnvironment(env string) error {
+	if env == "" {
+		return nil
+	}
+
+	pattern := c.FilePattern
+	if pattern == "" {
+		pattern = c.FilePath
+	}
+	if !strings.Contains(pattern, EnvironmentPlaceholder) {
+		return &ConfigError{Message: "File pattern must contain the " + EnvironmentPlaceholder + " placeholder to resolve an environment (e.g., config/{env}/database.yaml)"}
 	}
 
-	for _, repo := range publicRepos {
-		if strings.Contains(c.RepositoryPath, repo) {
-			return true
+	c.Environment = env
+	c.FilePath = strings.ReplaceAll(pattern, EnvironmentPlaceholder, env)
+	return nil
+}
+
+// Branches returns the candidate branches when Branch holds a
+// comma-separated fallback list (e.g., "main,master,release-4.19")
+func (c *Config) Branches() []string {
+	var branches []string
+	for _, b := range strings.Split(c.Branch, ",") {
+		if b = strings.TrimSpace(b); b != "" {
+			branches = append(branches, b)
 		}
 	}
+	return branches
+}
 
-	return false
+// IsPublicRepository reports whether the repository can be read without a
+// token, as determined by a visibility check (see Visibility)
+func (c *Config) IsPublicRepository() bool {
+	return c.Visibility == VisibilityPublic
 }
 
 // RequiresToken checks if a token is required for this configuration
@@ -102,11 +165,12 @@ func (e *ConfigError) Error() string {
 	return e.Message
 }
 
-// GetRepositoryOwnerAndName extracts owner and name from repository path
+// GetRepositoryOwnerAndName extracts owner and name from repository path.
+// The owner is the whole namespace of the project, subgroups included, e.g.
+// mycompany/services for mycompany/services/api.
 func (c *Config) GetRepositoryOwnerAndName() (string, string) {
-	parts := strings.Split(c.RepositoryPath, "/")
-	if len(parts) >= 2 {
-		return parts[0], parts[1]
+	if i := strings.LastIndex(c.RepositoryPath, "/"); i >= 0 {
+		return c.RepositoryPath[:i], c.RepositoryPath[i+1:]
 	}
 	return "", ""
 }
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"drivio/pkg/fileutil"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SetFileValue sets a value in a YAML configuration file, creating the file
+// and the intermediate sections as needed. keys is the path to the value,
+// e.g. ["profiles", "prod", "url"]. Comments and key order of the rest of
+// the file are preserved.
+func SetFileValue(path string, keys []string, value string) error {
+	doc, err := readEditableFile(path)
+	if err != nil {
+		return err
+	}
+
+	node := doc.Content[0]
+	for i, key := range keys {
+		if node.Kind != yaml.MappingNode {
+			return fmt.Errorf("%s is not a section", strings.Join(keys[:i], "."))
+		}
+		child := mappingValue(node, key)
+		last := i == len(keys)-1
+		if child == nil {
+			child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			if last {
+				child = &yaml.Node{Kind: yaml.ScalarNode}
+			}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, child)
+		}
+		if last {
+			if child.Kind != yaml.ScalarNode {
+				return fmt.Errorf("%s is a section, not a value", strings.Join(keys, "."))
+			}
+			// Let the encoder pick the tag (and quoting) of the new value
+			child.Tag = ""
+			child.Style = 0
+			child.Value = value
+		}
+		node = child
+	}
+
+	return writeEditableFile(path, doc)
+}
+
+// UnsetFileValue removes a value or a whole section from a YAML
+// configuration file. It reports whether the key existed.
+func UnsetFileValue(path string, keys []string) (bool, error) {
+	doc, err := readEditableFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	node := doc.Content[0]
+	for i, key := range keys {
+		if node.Kind != yaml.MappingNode {
+			return false, nil
+		}
+		if i == len(keys)-1 {
+			for j := 0; j+1 < len(node.Content); j += 2 {
+				if node.Content[j].Value == key {
+					node.Content = append(node.Content[:j], node.Content[j+2:]...)
+					return true, writeEditableFile(path, doc)
+				}
+			}
+			return false, nil
+		}
+		if node = mappingValue(node, key); node == nil {
+			return false, nil
+		}
+	}
+	return false, nil
+}
+
+// readEditableFile parses a YAML configuration file into a document node;
+// a missing file yields an empty document
+func readEditableFile(path string) (*yaml.Node, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case "", ".yaml", ".yml":
+	default:
+		return nil, fmt.Errorf("only YAML configuration files can be edited: %s", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config file %s must contain a mapping", path)
+	}
+	return &doc, nil
+}
+
+// writeEditableFile writes the document back. New files are only readable
+// by their owner since they may hold tokens.
+func writeEditableFile(path string, doc *yaml.Node) error {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode config file: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to encode config file: %w", err)
+	}
+
+	if err := fileutil.WriteFileAtomic(path, buf.Bytes(), 0600, false); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// mappingValue returns the value of key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package config
+
+import "os"
+
+// Environment variables honored by drivio. Each list starts with the
+// DRIVIO_-prefixed name, which takes precedence, followed by the legacy
+// names kept as fallbacks.
+var (
+	EnvConfigFile           = []string{"DRIVIO_CONFIG"}
+	EnvProfile              = []string{"DRIVIO_PROFILE"}
+	EnvWorkDir              = []string{"DRIVIO_WORK_DIR"}
+	EnvQuiet                = []string{"DRIVIO_QUIET"}
+	EnvNoProgress           = []string{"DRIVIO_NO_PROGRESS"}
+	EnvNoColor              = []string{"DRIVIO_NO_COLOR"}
+	EnvVerbose              = []string{"DRIVIO_VERBOSE"}
+	EnvASCII                = []string{"DRIVIO_ASCII"}
+	EnvLogFile              = []string{"DRIVIO_LOG_FILE"}
+	EnvCacheDir             = []string{"DRIVIO_CACHE_DIR"}
+	EnvDataDir              = []string{"DRIVIO_DATA_DIR"}
+	EnvXDG                  = []string{"DRIVIO_XDG"}
+	EnvGitLabURL            = []string{"DRIVIO_GITLAB_URL", "GITLAB_URL"}
+	EnvGitLabToken          = []string{"DRIVIO_GITLAB_TOKEN", "GITLAB_TOKEN"}
+	EnvInstance             = []string{"DRIVIO_GITLAB_INSTANCE"}
+	EnvRepoPath             = []string{"DRIVIO_GITLAB_REPO", "GITLAB_REPO_PATH"}
+	EnvBranch               = []string{"DRIVIO_GITLAB_BRANCH", "GITLAB_BRANCH"}
+	EnvFilePath             = []string{"DRIVIO_GITLAB_FILE", "GITLAB_FILE_PATH"}
+	EnvFilePattern          = []string{"DRIVIO_GITLAB_FILE_PATTERN", "GITLAB_FILE_PATTERN"}
+	EnvRetryAttempts        = []string{"DRIVIO_RETRY_ATTEMPTS", "GITLAB_RETRY_ATTEMPTS"}
+	EnvRetryBackoff         = []string{"DRIVIO_RETRY_BACKOFF", "GITLAB_RETRY_BACKOFF"}
+	EnvGitHubToken          = []string{"DRIVIO_GITHUB_TOKEN", "GITHUB_TOKEN"}
+	EnvGitHubWebhookSecret  = []string{"DRIVIO_GITHUB_WEBHOOK_SECRET"}
+	EnvGitLabWebhookSecret  = []string{"DRIVIO_GITLAB_WEBHOOK_SECRET"}
+	EnvAPIToken             = []string{"DRIVIO_API_TOKEN"}
+	EnvJiraURL              = []string{"DRIVIO_JIRA_URL", "JIRA_URL"}
+	EnvJiraUser             = []string{"DRIVIO_JIRA_USER", "JIRA_USER"}
+	EnvJiraToken            = []string{"DRIVIO_JIRA_TOKEN", "JIRA_API_TOKEN"}
+	EnvGrafanaURL           = []string{"DRIVIO_GRAFANA_URL", "GRAFANA_URL"}
+	EnvGrafanaToken         = []string{"DRIVIO_GRAFANA_TOKEN", "GRAFANA_TOKEN"}
+	EnvRegistryAuthFile     = []string{"DRIVIO_REGISTRY_AUTH_FILE", "REGISTRY_AUTH_FILE"}
+	EnvSigningKeyPassphrase = []string{"DRIVIO_SIGNING_KEY_PASSPHRASE"}
+)
+
+// LookupEnv returns the value of the first of the environment variables that
+// is set and not empty, and its name
+func LookupEnv(names ...string) (value, name string, ok bool) {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value, name, true
+		}
+	}
+	return "", "", false
+}
+
+// GetEnv returns the value of the first of the environment variables that is
+// set and not empty
+func GetEnv(names ...string) string {
+	value, _, _ := LookupEnv(names...)
+	return value
+}
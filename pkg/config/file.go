@@ -0,0 +1,494 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// DefaultConfigFileName is the name of the configuration file looked up in
+// the home directory
+const DefaultConfigFileName = ".drivio.yaml"
+
+const (
+	// ProfilesKey is the section of the configuration file holding the
+	// named profiles
+	ProfilesKey = "profiles"
+	// CurrentProfileKey selects the profile used when --profile is not given
+	CurrentProfileKey = "current-profile"
+	// InstancesKey is the section of the configuration file holding the
+	// named GitLab instances
+	InstancesKey = "instances"
+	// RetentionKey is the section of the configuration file holding the
+	// retention policy of the work directory
+	RetentionKey = "retention"
+	// ClassificationKey is the list of the configuration file holding the
+	// rules giving a type to commits without a conventional one
+	ClassificationKey = "classification"
+	// NotificationsKey is the section of the configuration file holding the
+	// named notification channels
+	NotificationsKey = "notifications"
+	// ImagesKey is the list of the configuration file mapping container
+	// images to the GitHub repositories they are built from
+	ImagesKey = "images"
+	// TasksKey is the section of the configuration file holding the named
+	// tasks run on a schedule
+	TasksKey = "tasks"
+)
+
+// Instance is a GitLab instance declared in the instances section of the
+// configuration file
+type Instance struct {
+	Name string
+	URL  string
+	// Token is the token of the instance, read from the file or from the
+	// environment variable named by its token-env key
+	Token string
+}
+
+// Channel is a notification channel declared in the notifications section
+// of the configuration file
+type Channel struct {
+	Name string
+	// Type is slack, teams, webhook, email, notion or plugin
+	Type string
+	// URL is the webhook URL of the channel, read from the file or from the
+	// environment variable named by its url-env key
+	URL string
+	// SMTP is the host:port of the mail server of email channels
+	SMTP string
+	From string
+	To   []string
+	// Username and Password authenticate to the mail server when set; the
+	// password is read from the file or from the environment variable named
+	// by its password-env key
+	Username string
+	Password string
+	// Token is the integration token of notion channels, read from the file
+	// or from the environment variable named by its token-env key
+	Token string
+	// Database and Page are where notion channels publish their pages: in a
+	// database, or under a parent page
+	Database string
+	Page     string
+	// Plugin is the plugin of plugin channels, and Options their other
+	// keys; a key ending in -env is replaced by the key without the suffix,
+	// read from the environment variable it names
+	Plugin  string
+	Options map[string]string
+}
+
+// Task is a drivio command run on a schedule, declared in the tasks section
+// of the configuration file
+type Task struct {
+	Name string
+	// Schedule is a cron expression
+	Schedule string
+	// Command is the drivio command line, without drivio, e.g.
+	// ["status", "--notify", "oncall"]
+	Command []string
+	// Title is the title of the messages about the task
+	Title string
+	// Notify are the notification channels alerted when the task fails
+	Notify []string
+	// Report are the notification channels receiving the output of every
+	// successful run
+	Report []string
+	// Timeout stops runs lasting longer, when set
+	Timeout time.Duration
+}
+
+// DefaultConfigFilePath returns the path of the configuration file in the
+// home directory, or an empty string when the home directory is unknown
+func DefaultConfigFilePath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, DefaultConfigFileName)
+}
+
+// FileConfig is a loaded configuration file. Keys are flag names, e.g. url,
+// token, repo or work-dir. Keys of the active profile take precedence over
+// the top-level keys, and keys of a command section (e.g. fetch or
+// release-notes) take precedence over the keys around it.
+type FileConfig struct {
+	v    *viper.Viper
+	path string
+	// Profile is the active profile, empty when none is selected
+	Profile string
+	// command is the path of the command whose sections are looked up,
+	// e.g. ["fetch", "archive"]
+	command []string
+}
+
+// LoadConfigFile reads a configuration file (YAML, JSON or TOML, by
+// extension) and activates the given profile, or the file's current-profile
+// when profile is empty. When path is empty the default file is used, and a
+// missing default file yields an empty configuration.
+func LoadConfigFile(path, profile string) (*FileConfig, error) {
+	v := viper.New()
+
+	explicit := path != ""
+	if !explicit {
+		path = DefaultConfigFilePath()
+	}
+	fc := &FileConfig{v: v, path: path}
+
+	if path != "" {
+		v.SetConfigFile(path)
+		if filepath.Ext(path) == "" {
+			v.SetConfigType("yaml")
+		}
+
+		if err := v.ReadInConfig(); err != nil {
+			if explicit || !errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+			}
+		}
+	}
+
+	if profile == "" {
+		profile = v.GetString(CurrentProfileKey)
+	}
+	if profile != "" {
+		if !v.IsSet(ProfilesKey + "." + profile) {
+			available := strings.Join(fc.Profiles(), ", ")
+			if available == "" {
+				available = "none"
+			}
+			return nil, fmt.Errorf("profile %q not found in %s (available: %s)", profile, path, available)
+		}
+		// Viper keys are case-insensitive
+		fc.Profile = strings.ToLower(profile)
+	}
+
+	return fc, nil
+}
+
+// Path returns the path of the configuration file
+func (f *FileConfig) Path() string {
+	return f.path
+}
+
+// Profiles returns the names of the profiles declared in the file
+func (f *FileConfig) Profiles() []string {
+	var names []string
+	for name := range f.v.GetStringMap(ProfilesKey) {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ForCommand returns the configuration seen by a command: a copy whose keys
+// are looked up in the sections of the command first, e.g. fetch.archive,
+// then fetch, then the top level for drivio fetch archive
+func (f *FileConfig) ForCommand(path ...string) *FileConfig {
+	scoped := *f
+	scoped.command = path
+	return &scoped
+}
+
+// Instances returns the names of the GitLab instances declared in the active
+// profile and at the top level of the file
+func (f *FileConfig) Instances() []string {
+	return f.sectionNames(InstancesKey)
+}
+
+// sectionNames returns the names of the entries of a section, e.g.
+// instances, declared in the active profile and at the top level of the file
+func (f *FileConfig) sectionNames(section string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	roots := []string{section}
+	if f.Profile != "" {
+		roots = append(roots, ProfilesKey+"."+f.Profile+"."+section)
+	}
+	for _, root := range roots {
+		for name := range f.v.GetStringMap(root) {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Instance returns a GitLab instance declared in the file. An instance of
+// the active profile replaces the top-level instance with the same name.
+func (f *FileConfig) Instance(name string) (*Instance, error) {
+	name = strings.ToLower(name)
+	root := InstancesKey + "." + name
+	if f.Profile != "" && f.v.IsSet(ProfilesKey+"."+f.Profile+"."+root) {
+		root = ProfilesKey + "." + f.Profile + "." + root
+	} else if !f.v.IsSet(root) {
+		available := strings.Join(f.Instances(), ", ")
+		if available == "" {
+			available = "none"
+		}
+		return nil, fmt.Errorf("GitLab instance %q not found in %s (available: %s)", name, f.path, available)
+	}
+
+	instance := &Instance{
+		Name:  name,
+		URL:   f.v.GetString(root + ".url"),
+		Token: f.v.GetString(root + ".token"),
+	}
+	if instance.URL == "" {
+		return nil, fmt.Errorf("GitLab instance %q has no url in %s", name, f.path)
+	}
+	if env := f.v.GetString(root + ".token-env"); env != "" && instance.Token == "" {
+		instance.Token = os.Getenv(env)
+	}
+	return instance, nil
+}
+
+// Channels returns the names of the notification channels declared in the
+// active profile and at the top level of the file
+func (f *FileConfig) Channels() []string {
+	return f.sectionNames(NotificationsKey)
+}
+
+// Channel returns a notification channel declared in the file. A channel of
+// the active profile replaces the top-level channel with the same name.
+func (f *FileConfig) Channel(name string) (*Channel, error) {
+	name = strings.ToLower(name)
+	root := NotificationsKey + "." + name
+	if f.Profile != "" && f.v.IsSet(ProfilesKey+"."+f.Profile+"."+root) {
+		root = ProfilesKey + "." + f.Profile + "." + root
+	} else if !f.v.IsSet(root) {
+		available := strings.Join(f.Channels(), ", ")
+		if available == "" {
+			available = "none"
+		}
+		return nil, fmt.Errorf("notification channel %q not found in %s (available: %s)", name, f.path, available)
+	}
+
+	channel := &Channel{
+		Name:     name,
+		Type:     f.v.GetString(root + ".type"),
+		URL:      f.v.GetString(root + ".url"),
+		SMTP:     f.v.GetString(root + ".smtp"),
+		From:     f.v.GetString(root + ".from"),
+		To:       f.v.GetStringSlice(root + ".to"),
+		Username: f.v.GetString(root + ".username"),
+		Password: f.v.GetString(root + ".password"),
+		Token:    f.v.GetString(root + ".token"),
+		Database: f.v.GetString(root + ".database"),
+		Page:     f.v.GetString(root + ".page"),
+	}
+	if channel.Type == "" {
+		return nil, fmt.Errorf("notification channel %q has no type in %s", name, f.path)
+	}
+	if env := f.v.GetString(root + ".url-env"); env != "" && channel.URL == "" {
+		channel.URL = os.Getenv(env)
+	}
+	if env := f.v.GetString(root + ".password-env"); env != "" && channel.Password == "" {
+		channel.Password = os.Getenv(env)
+	}
+	if env := f.v.GetString(root + ".token-env"); env != "" && channel.Token == "" {
+		channel.Token = os.Getenv(env)
+	}
+	if plugin := f.v.GetString(root + ".plugin"); plugin != "" {
+		channel.Plugin = plugin
+		channel.Options = make(map[string]string)
+		for key, value := range f.v.GetStringMapString(root) {
+			switch key {
+			case "type", "plugin":
+			default:
+				if name, ok := strings.CutSuffix(key, "-env"); ok {
+					channel.Options[name] = os.Getenv(value)
+				} else {
+					channel.Options[key] = value
+				}
+			}
+		}
+	}
+	return channel, nil
+}
+
+// Tasks returns the names of the tasks declared in the active profile and at
+// the top level of the file
+func (f *FileConfig) Tasks() []string {
+	return f.sectionNames(TasksKey)
+}
+
+// Task returns a task declared in the file. A task of the active profile
+// replaces the top-level task with the same name. Its command is a list of
+// arguments, or a command line split as by a shell.
+func (f *FileConfig) Task(name string) (*Task, error) {
+	name = strings.ToLower(name)
+	root := TasksKey + "." + name
+	if f.Profile != "" && f.v.IsSet(ProfilesKey+"."+f.Profile+"."+root) {
+		root = ProfilesKey + "." + f.Profile + "." + root
+	} else if !f.v.IsSet(root) {
+		available := strings.Join(f.Tasks(), ", ")
+		if available == "" {
+			available = "none"
+		}
+		return nil, fmt.Errorf("task %q not found in %s (available: %s)", name, f.path, available)
+	}
+
+	task := &Task{
+		Name:     name,
+		Schedule: f.v.GetString(root + ".schedule"),
+		Title:    f.v.GetString(root + ".title"),
+		Notify:   f.v.GetStringSlice(root + ".notify"),
+		Report:   f.v.GetStringSlice(root + ".report"),
+		Timeout:  f.v.GetDuration(root + ".timeout"),
+	}
+	if line, ok := f.v.Get(root + ".command").(string); ok {
+		command, err := splitCommandLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("task %q has an invalid command in %s: %w", name, f.path, err)
+		}
+		task.Command = command
+	} else {
+		task.Command = f.v.GetStringSlice(root + ".command")
+	}
+	if len(task.Command) > 0 && task.Command[0] == "drivio" {
+		task.Command = task.Command[1:]
+	}
+	if len(task.Command) == 0 {
+		return nil, fmt.Errorf("task %q has no command in %s", name, f.path)
+	}
+	return task, nil
+}
+
+// splitCommandLine splits a command line into arguments at spaces outside
+// single and double quotes, a backslash escaping the next character outside
+// single quotes
+func splitCommandLine(line string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg, escaped := false, false
+	var quote rune
+	for _, r := range line {
+		switch {
+		case escaped:
+			arg.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errors.New("unterminated quote or escape")
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+// IsSet reports whether the key is set in the active profile or at the top
+// level, or in their sections for the command
+func (f *FileConfig) IsSet(key string) bool {
+	return f.isValue(f.resolve(key))
+}
+
+// GetString returns the value of the key as a string
+func (f *FileConfig) GetString(key string) string {
+	return f.v.GetString(f.resolve(key))
+}
+
+// GetStringSlice returns the value of the key as a list of strings
+func (f *FileConfig) GetStringSlice(key string) []string {
+	return f.v.GetStringSlice(f.resolve(key))
+}
+
+// Unmarshal decodes the value of the key, e.g. a list of objects, into v
+// with mapstructure tags
+func (f *FileConfig) Unmarshal(key string, v interface{}) error {
+	return f.v.UnmarshalKey(f.resolve(key), v)
+}
+
+// Keys returns the settings defined at the top level of the file or in the
+// active profile
+func (f *FileConfig) Keys() []string {
+	seen := make(map[string]bool)
+	profilePrefix := ProfilesKey + "." + f.Profile + "."
+
+	var keys []string
+	for _, key := range f.v.AllKeys() {
+		switch {
+		case key == CurrentProfileKey:
+			continue
+		case f.Profile != "" && strings.HasPrefix(key, profilePrefix):
+			key = strings.TrimPrefix(key, profilePrefix)
+		case strings.HasPrefix(key, ProfilesKey+"."):
+			continue
+		}
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// InProfile reports whether the value of the key comes from the active
+// profile rather than the top level of the file
+func (f *FileConfig) InProfile(key string) bool {
+	return strings.HasPrefix(f.resolve(key), ProfilesKey+".")
+}
+
+// resolve returns the viper key of a setting, trying from the most to the
+// least specific: the command sections of the active profile, the active
+// profile, the command sections of the file and the top level
+func (f *FileConfig) resolve(key string) string {
+	var roots []string
+	if f.Profile != "" {
+		roots = append(roots, ProfilesKey+"."+f.Profile+".")
+	}
+	roots = append(roots, "")
+
+	for _, root := range roots {
+		for i := len(f.command); i >= 0; i-- {
+			candidate := root + key
+			if i > 0 {
+				candidate = root + strings.Join(f.command[:i], ".") + "." + key
+			}
+			if f.isValue(candidate) {
+				return candidate
+			}
+		}
+	}
+	return key
+}
+
+// isValue reports whether the viper key holds a value rather than a section
+func (f *FileConfig) isValue(key string) bool {
+	if !f.v.IsSet(key) {
+		return false
+	}
+	_, section := f.v.Get(key).(map[string]interface{})
+	return !section
+}
@@ -0,0 +1,244 @@
+package convert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is an output format a document can be converted to
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+)
+
+// ParseFormat validates a format name
+func ParseFormat(name string) (Format, error) {
+	switch f := Format(strings.ToLower(name)); f {
+	case FormatJSON, FormatYAML:
+		return f, nil
+	case "yml":
+		return FormatYAML, nil
+	default:
+		return "", fmt.Errorf("unsupported format: %s (use json or yaml)", name)
+	}
+}
+
+// Extension returns the file extension for the format
+func (f Format) Extension() string {
+	return "." + string(f)
+}
+
+// Convert converts a YAML or JSON document to the given format. Key order
+// is preserved in both directions.
+func Convert(content []byte, to Format) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse document: %w", err)
+	}
+
+	switch to {
+	case FormatJSON:
+		return ToJSON(&doc)
+	case FormatYAML:
+		return ToYAML(&doc)
+	default:
+		return nil, fmt.Errorf("unsupported format: %s", to)
+	}
+}
+
+// ToJSON encodes a parsed YAML document as indented JSON
+func ToJSON(doc *yaml.Node) ([]byte, error) {
+	if doc.Kind == yaml.DocumentNode {
+		if len(doc.Content) == 0 {
+			return []byte("null\n"), nil
+		}
+		doc = doc.Content[0]
+	}
+	if doc.Kind == 0 {
+		return []byte("null\n"), nil
+	}
+
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, doc); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+		return nil, fmt.Errorf("failed to format JSON: %w", err)
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+// ToYAML encodes a parsed document as block-style YAML
+func ToYAML(doc *yaml.Node) ([]byte, error) {
+	blockStyle(doc)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return nil, fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode YAML: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// blockStyle drops the flow and quoting styles JSON input comes with, so the
+// encoder picks the idiomatic YAML representation
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// writeJSON writes a node as compact JSON
+func writeJSON(buf *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.AliasNode:
+		return writeJSON(buf, node.Alias)
+	case yaml.MappingNode:
+		keys, values, err := mappingPairs(node)
+		if err != nil {
+			return err
+		}
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			encoded, _ := json.Marshal(key)
+			buf.Write(encoded)
+			buf.WriteByte(':')
+			if err := writeJSON(buf, values[i]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	case yaml.SequenceNode:
+		buf.WriteByte('[')
+		for i, item := range node.Content {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSON(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case yaml.ScalarNode:
+		encoded, err := scalarJSON(node)
+		if err != nil {
+			return err
+		}
+		buf.Write(encoded)
+	default:
+		return fmt.Errorf("unsupported YAML node at line %d", node.Line)
+	}
+	return nil
+}
+
+// mappingPairs returns the keys and values of a mapping in document order,
+// with YAML merge keys (<<) expanded and later keys overriding earlier ones
+func mappingPairs(node *yaml.Node) ([]string, []*yaml.Node, error) {
+	var keys []string
+	var values []*yaml.Node
+	index := make(map[string]int)
+
+	set := func(key string, value *yaml.Node, override bool) {
+		if i, ok := index[key]; ok {
+			if override {
+				values[i] = value
+			}
+			return
+		}
+		index[key] = len(keys)
+		keys = append(keys, key)
+		values = append(values, value)
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, value := node.Content[i], node.Content[i+1]
+		if keyNode.Tag == "!!merge" {
+			merged, err := mergeSources(value)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, source := range merged {
+				sourceKeys, sourceValues, err := mappingPairs(source)
+				if err != nil {
+					return nil, nil, err
+				}
+				for j, key := range sourceKeys {
+					set(key, sourceValues[j], false)
+				}
+			}
+			continue
+		}
+		if keyNode.Kind != yaml.ScalarNode {
+			return nil, nil, fmt.Errorf("line %d: only scalar keys can be converted to JSON", keyNode.Line)
+		}
+		set(keyNode.Value, value, true)
+	}
+	return keys, values, nil
+}
+
+// mergeSources returns the mappings referenced by a merge key value
+func mergeSources(value *yaml.Node) ([]*yaml.Node, error) {
+	if value.Kind == yaml.AliasNode {
+		value = value.Alias
+	}
+	switch value.Kind {
+	case yaml.MappingNode:
+		return []*yaml.Node{value}, nil
+	case yaml.SequenceNode:
+		var sources []*yaml.Node
+		for _, item := range value.Content {
+			nested, err := mergeSources(item)
+			if err != nil {
+				return nil, err
+			}
+			sources = append(sources, nested...)
+		}
+		return sources, nil
+	default:
+		return nil, fmt.Errorf("line %d: merge key must reference a mapping", value.Line)
+	}
+}
+
+// scalarJSON encodes a scalar with the type YAML resolves it to
+func scalarJSON(node *yaml.Node) ([]byte, error) {
+	switch node.ShortTag() {
+	case "!!null":
+		return []byte("null"), nil
+	case "!!bool", "!!int":
+		var v interface{}
+		if err := node.Decode(&v); err != nil {
+			return nil, fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		return json.Marshal(v)
+	case "!!float":
+		var f float64
+		if err := node.Decode(&f); err != nil {
+			return nil, fmt.Errorf("line %d: %w", node.Line, err)
+		}
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, fmt.Errorf("line %d: %s cannot be represented in JSON", node.Line, node.Value)
+		}
+		return json.Marshal(f)
+	default:
+		// Strings, timestamps and binary data keep their textual form
+		return json.Marshal(node.Value)
+	}
+}
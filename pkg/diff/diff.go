@@ -0,0 +1,169 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// OpKind is the kind of a line in a diff
+type OpKind int
+
+const (
+	Equal OpKind = iota
+	Delete
+	Insert
+)
+
+// Op is a single line of a diff
+type Op struct {
+	Kind OpKind
+	Line string
+}
+
+// Lines computes the line-based difference between a and b using the
+// longest common subsequence of their lines
+func Lines(a, b []byte) []Op {
+	aLines, bLines := splitLines(a), splitLines(b)
+	n, m := len(aLines), len(bLines)
+
+	// lcs[i][j] is the length of the LCS of aLines[i:] and bLines[j:]
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if aLines[i] == bLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	ops := make([]Op, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case aLines[i] == bLines[j]:
+			ops = append(ops, Op{Kind: Equal, Line: aLines[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, Op{Kind: Delete, Line: aLines[i]})
+			i++
+		default:
+			ops = append(ops, Op{Kind: Insert, Line: bLines[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, Op{Kind: Delete, Line: aLines[i]})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, Op{Kind: Insert, Line: bLines[j]})
+	}
+	return ops
+}
+
+// Unified returns a unified diff of a and b with the given number of context
+// lines, or an empty string when they are identical
+func Unified(aName, bName string, a, b []byte, context int) string {
+	ops := Lines(a, b)
+
+	var sb strings.Builder
+	for _, h := range hunks(ops, context) {
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", aName, bName)
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(h.aStart, h.aLines), hunkRange(h.bStart, h.bLines))
+		for _, op := range ops[h.start:h.end] {
+			switch op.Kind {
+			case Equal:
+				sb.WriteString(" ")
+			case Delete:
+				sb.WriteString("-")
+			case Insert:
+				sb.WriteString("+")
+			}
+			sb.WriteString(op.Line)
+			sb.WriteString("\n")
+		}
+	}
+	return sb.String()
+}
+
+// hunk is a range of ops shown together, with its line positions
+type hunk struct {
+	start, end     int
+	aStart, aLines int
+	bStart, bLines int
+}
+
+// hunks groups the changed ops with their surrounding context, merging
+// groups whose context overlaps
+func hunks(ops []Op, context int) []hunk {
+	var result []hunk
+	aLine, bLine := 1, 1
+	var current *hunk
+	lastChange := -1
+
+	for i, op := range ops {
+		if op.Kind != Equal {
+			if current == nil || i-lastChange > 2*context {
+				if current != nil {
+					current.end = min(lastChange+context+1, len(ops))
+					result = append(result, *current)
+				}
+				start := max(i-context, 0)
+				current = &hunk{start: start, aStart: aLine - (i - start), bStart: bLine - (i - start)}
+			}
+			lastChange = i
+		}
+		switch op.Kind {
+		case Equal:
+			aLine++
+			bLine++
+		case Delete:
+			aLine++
+		case Insert:
+			bLine++
+		}
+	}
+	if current != nil {
+		current.end = min(lastChange+context+1, len(ops))
+		result = append(result, *current)
+	}
+
+	for i := range result {
+		h := &result[i]
+		for _, op := range ops[h.start:h.end] {
+			if op.Kind != Insert {
+				h.aLines++
+			}
+			if op.Kind != Delete {
+				h.bLines++
+			}
+		}
+	}
+	return result
+}
+
+// hunkRange formats a hunk position the way diff -u does
+func hunkRange(start, lines int) string {
+	if lines == 0 {
+		start--
+	}
+	if lines == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, lines)
+}
+
+func splitLines(content []byte) []string {
+	text := strings.TrimSuffix(string(content), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
@@ -0,0 +1,289 @@
+package diff
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Image is a container image reference, e.g.
+// quay.io/openshift/hypershift:v0.1.63 or registry.example.com/app@sha256:...
+type Image struct {
+	// Repository is the registry and path of the image, e.g.
+	// quay.io/openshift/hypershift
+	Repository string `json:"repository"`
+	Tag        string `json:"tag,omitempty"`
+	Digest     string `json:"digest,omitempty"`
+}
+
+// imageReference matches an image reference: an optional registry, a path,
+// an optional tag and an optional sha256 digest
+var imageReference = regexp.MustCompile(`^((?:[A-Za-z0-9-]+\.)*[A-Za-z0-9-]+(?::[0-9]+)?/)?([a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*)(?::([A-Za-z0-9_][A-Za-z0-9_.-]{0,127}))?(?:@(sha256:[a-f0-9]{64}))?$`)
+
+// ParseImage parses an image reference. Unless loose, the reference must
+// name its registry, e.g. quay.io or localhost:5000, and have a tag or a
+// digest, so other strings like host:port are not taken for images.
+func ParseImage(ref string, loose bool) (Image, bool) {
+	m := imageReference.FindStringSubmatch(strings.TrimSpace(ref))
+	if m == nil {
+		return Image{}, false
+	}
+	registry := strings.TrimSuffix(m[1], "/")
+	if registry != "" && !strings.ContainsAny(registry, ".:") && registry != "localhost" {
+		// The first component is part of the path, e.g. library/nginx
+		m[2] = registry + "/" + m[2]
+		registry = ""
+	}
+	if !loose && (registry == "" || m[3] == "" && m[4] == "") {
+		return Image{}, false
+	}
+	image := Image{Repository: m[2], Tag: m[3], Digest: m[4]}
+	if registry != "" {
+		image.Repository = registry + "/" + m[2]
+	}
+	return image, true
+}
+
+// Version identifies the image within its repository: its tag, its digest
+// shortened to 12 characters, or both, e.g. v1.2.3@sha256:0a1b2c3d4e5f
+func (i Image) Version() string {
+	digest := i.Digest
+	if len(digest) > len("sha256:")+12 {
+		digest = digest[:len("sha256:")+12]
+	}
+	switch {
+	case i.Tag != "" && digest != "":
+		return i.Tag + "@" + digest
+	case digest != "":
+		return digest
+	case i.Tag != "":
+		return i.Tag
+	}
+	return "latest"
+}
+
+// String returns the reference of the image
+func (i Image) String() string {
+	ref := i.Repository
+	if i.Tag != "" {
+		ref += ":" + i.Tag
+	}
+	if i.Digest != "" {
+		ref += "@" + i.Digest
+	}
+	return ref
+}
+
+// ImageChange is a component whose images differ between two documents
+type ImageChange struct {
+	Kind ChangeKind `json:"kind"`
+	// Repository identifies the component, e.g. quay.io/openshift/hypershift
+	Repository string `json:"repository"`
+	// Paths locate the images of the component in the documents, as
+	// accepted by fetch --query
+	Paths []string `json:"paths"`
+	// From and To are the distinct images of the component in each
+	// document; empty on the side the component is missing from
+	From []Image `json:"from"`
+	To   []Image `json:"to"`
+}
+
+// Versions renders the versions of images, e.g. "v1.2.3" or "v1, v2"
+func Versions(images []Image) string {
+	versions := make([]string, 0, len(images))
+	for _, image := range images {
+		versions = append(versions, image.Version())
+	}
+	return strings.Join(versions, ", ")
+}
+
+// imageUse is an image referenced at a path of a document
+type imageUse struct {
+	path  string
+	image Image
+}
+
+// Images compares the container images referenced by two YAML (or JSON)
+// documents, e.g. the config files of two environments, component by
+// component: a component is an image repository, whatever the path of its
+// references. Images are the values of keys ending in image (image,
+// releaseImage...), the repository and tag or digest keys of a mapping (as
+// in Helm values), and any other value that is a full reference, with a
+// registry and a tag or a digest. Multi-document files are read whole.
+func Images(a, b []byte) ([]ImageChange, error) {
+	aUses, err := findImages(a)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse first document: %w", err)
+	}
+	bUses, err := findImages(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse second document: %w", err)
+	}
+	aImages, aPaths := groupImages(aUses)
+	bImages, bPaths := groupImages(bUses)
+
+	repositories := make([]string, 0, len(aImages)+len(bImages))
+	for repository := range aImages {
+		repositories = append(repositories, repository)
+	}
+	for repository := range bImages {
+		if _, ok := aImages[repository]; !ok {
+			repositories = append(repositories, repository)
+		}
+	}
+	sort.Strings(repositories)
+
+	var changes []ImageChange
+	for _, repository := range repositories {
+		from, to := aImages[repository], bImages[repository]
+		change := ImageChange{Repository: repository, From: from, To: to, Paths: mergePaths(aPaths[repository], bPaths[repository])}
+		switch {
+		case from == nil:
+			change.Kind = Added
+		case to == nil:
+			change.Kind = Removed
+		case imagesString(from) != imagesString(to):
+			change.Kind = Changed
+		default:
+			continue
+		}
+		if change.From == nil {
+			change.From = []Image{}
+		}
+		if change.To == nil {
+			change.To = []Image{}
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// findImages returns the images referenced by the documents of a file
+func findImages(content []byte) ([]imageUse, error) {
+	var uses []imageUse
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	for i := 0; ; i++ {
+		var document yaml.Node
+		if err := decoder.Decode(&document); err != nil {
+			if errors.Is(err, io.EOF) {
+				return uses, nil
+			}
+			return nil, err
+		}
+		if len(document.Content) == 0 {
+			continue
+		}
+		prefix := ""
+		if i > 0 {
+			// Paths of the later documents name them, e.g. [1].image
+			prefix = fmt.Sprintf("[%d]", i)
+		}
+		walkImages(resolveAlias(document.Content[0]), prefix, "", &uses)
+	}
+}
+
+// walkImages collects the images of a node; key is the key of the node in
+// its mapping, empty otherwise
+func walkImages(node *yaml.Node, path, key string, uses *[]imageUse) {
+	node = resolveAlias(node)
+	switch node.Kind {
+	case yaml.MappingNode:
+		if image, ok := mappingImage(node); ok {
+			*uses = append(*uses, imageUse{path: rootPath(path), image: image})
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			k := node.Content[i].Value
+			walkImages(node.Content[i+1], path+keySegment(k), k, uses)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			walkImages(item, fmt.Sprintf("%s[%d]", path, i), key, uses)
+		}
+	case yaml.ScalarNode:
+		if node.ShortTag() != "!!str" {
+			return
+		}
+		loose := strings.HasSuffix(strings.ToLower(key), "image")
+		if image, ok := ParseImage(node.Value, loose); ok {
+			*uses = append(*uses, imageUse{path: rootPath(path), image: image})
+		}
+	}
+}
+
+// mappingImage returns the image of a mapping with repository and tag or
+// digest keys, and an optional registry key, as in Helm values
+func mappingImage(mapping *yaml.Node) (Image, bool) {
+	value := func(key string) string {
+		if node := lookup(mapping, key); node != nil && resolveAlias(node).Kind == yaml.ScalarNode {
+			return resolveAlias(node).Value
+		}
+		return ""
+	}
+	repository := value("repository")
+	tag, digest := value("tag"), value("digest")
+	if repository == "" || tag == "" && digest == "" {
+		return Image{}, false
+	}
+	if registry := value("registry"); registry != "" {
+		repository = strings.TrimSuffix(registry, "/") + "/" + repository
+	}
+	ref := repository
+	if tag != "" {
+		ref += ":" + tag
+	}
+	if digest != "" {
+		ref += "@" + digest
+	}
+	return ParseImage(ref, true)
+}
+
+// groupImages returns the distinct images of each repository, sorted, and
+// the paths referencing them
+func groupImages(uses []imageUse) (map[string][]Image, map[string][]string) {
+	images := make(map[string][]Image)
+	paths := make(map[string][]string)
+	for _, use := range uses {
+		repository := use.image.Repository
+		paths[repository] = append(paths[repository], use.path)
+		known := false
+		for _, image := range images[repository] {
+			known = known || image == use.image
+		}
+		if !known {
+			images[repository] = append(images[repository], use.image)
+		}
+	}
+	for _, list := range images {
+		sort.Slice(list, func(i, j int) bool { return list[i].String() < list[j].String() })
+	}
+	return images, paths
+}
+
+// mergePaths returns the paths of both documents, without duplicates
+func mergePaths(a, b []string) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, path := range append(append([]string{}, a...), b...) {
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// imagesString renders the full references of images
+func imagesString(images []Image) string {
+	refs := make([]string, 0, len(images))
+	for _, image := range images {
+		refs = append(refs, image.String())
+	}
+	return strings.Join(refs, ", ")
+}
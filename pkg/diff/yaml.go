@@ -0,0 +1,185 @@
+package diff
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"drivio/pkg/merge"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ChangeKind is the kind of a change between two documents
+type ChangeKind string
+
+const (
+	Added   ChangeKind = "added"
+	Removed ChangeKind = "removed"
+	Changed ChangeKind = "changed"
+)
+
+// Change is a value added, removed or changed between two documents
+type Change struct {
+	Kind ChangeKind `json:"kind"`
+	// Path locates the value, as accepted by fetch --query, e.g.
+	// .image.tag or .containers[0].env
+	Path string `json:"path"`
+	// Old and New are the values as YAML flow, e.g. v1.2.3 or {a: 1};
+	// empty on the side the value is missing from
+	Old string `json:"old,omitempty"`
+	New string `json:"new,omitempty"`
+}
+
+// String renders the change on a line, e.g. "~ .image.tag: v1 → v2"
+func (c Change) String() string {
+	switch c.Kind {
+	case Added:
+		return fmt.Sprintf("+ %s: %s", c.Path, c.New)
+	case Removed:
+		return fmt.Sprintf("- %s: %s", c.Path, c.Old)
+	default:
+		return fmt.Sprintf("~ %s: %s → %s", c.Path, c.Old, c.New)
+	}
+}
+
+// YAML compares two YAML (or JSON) documents key by key rather than line by
+// line: the order of the keys, comments, quoting and indentation don't
+// matter, only the data. Lists are compared item by item. The changes are
+// listed in the order of the documents, those of a first, then the keys
+// only b has.
+func YAML(a, b []byte) ([]Change, error) {
+	aRoot, err := parseDocument(a)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse first document: %w", err)
+	}
+	bRoot, err := parseDocument(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse second document: %w", err)
+	}
+
+	var changes []Change
+	compareNodes(aRoot, bRoot, "", &changes)
+	return changes, nil
+}
+
+// parseDocument returns the root of the first document, nil when empty
+func parseDocument(content []byte) (*yaml.Node, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, err
+	}
+	if len(document.Content) == 0 {
+		return nil, nil
+	}
+	return resolveAlias(document.Content[0]), nil
+}
+
+func compareNodes(a, b *yaml.Node, path string, changes *[]Change) {
+	switch {
+	case a == nil && b == nil:
+		return
+	case a == nil:
+		*changes = append(*changes, Change{Kind: Added, Path: rootPath(path), New: flow(b)})
+		return
+	case b == nil:
+		*changes = append(*changes, Change{Kind: Removed, Path: rootPath(path), Old: flow(a)})
+		return
+	}
+
+	a, b = resolveAlias(a), resolveAlias(b)
+	switch {
+	case a.Kind == yaml.MappingNode && b.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(a.Content); i += 2 {
+			key := a.Content[i].Value
+			compareNodes(a.Content[i+1], lookup(b, key), path+keySegment(key), changes)
+		}
+		for i := 0; i+1 < len(b.Content); i += 2 {
+			key := b.Content[i].Value
+			if lookup(a, key) == nil {
+				compareNodes(nil, b.Content[i+1], path+keySegment(key), changes)
+			}
+		}
+	case a.Kind == yaml.SequenceNode && b.Kind == yaml.SequenceNode:
+		for i := 0; i < len(a.Content) || i < len(b.Content); i++ {
+			var aItem, bItem *yaml.Node
+			if i < len(a.Content) {
+				aItem = a.Content[i]
+			}
+			if i < len(b.Content) {
+				bItem = b.Content[i]
+			}
+			compareNodes(aItem, bItem, fmt.Sprintf("%s[%d]", path, i), changes)
+		}
+	default:
+		if !merge.EqualNodes(a, b) {
+			*changes = append(*changes, Change{Kind: Changed, Path: rootPath(path), Old: flow(a), New: flow(b)})
+		}
+	}
+}
+
+// plainKey matches the keys written as .key in paths; others are quoted,
+// e.g. ["app.kubernetes.io/name"]
+var plainKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+func keySegment(key string) string {
+	if plainKey.MatchString(key) {
+		return "." + key
+	}
+	return fmt.Sprintf("[%q]", key)
+}
+
+func rootPath(path string) string {
+	if path == "" {
+		return "."
+	}
+	return path
+}
+
+// flow renders a value as YAML flow on a single line
+func flow(node *yaml.Node) string {
+	node = resolveAlias(node)
+	if node.Kind == yaml.ScalarNode {
+		if node.Value == "" || strings.ContainsAny(node.Value, "\n") || node.ShortTag() == "!!str" && !plainString(node.Value) {
+			return fmt.Sprintf("%q", node.Value)
+		}
+		return node.Value
+	}
+
+	copied := *node
+	copied.Style = yaml.FlowStyle
+	copied.HeadComment, copied.LineComment, copied.FootComment = "", "", ""
+	out, err := yaml.Marshal(&copied)
+	if err != nil {
+		return node.Value
+	}
+	return strings.Join(strings.Fields(strings.TrimSpace(string(out))), " ")
+}
+
+// plainString reports whether a string reads back as itself when written
+// unquoted, unlike e.g. "true" or "1.10"
+func plainString(value string) bool {
+	var decoded interface{}
+	if err := yaml.Unmarshal([]byte(value), &decoded); err != nil {
+		return false
+	}
+	s, ok := decoded.(string)
+	return ok && s == value
+}
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node != nil && node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+// lookup returns the value of key in a mapping node, nil when missing
+func lookup(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
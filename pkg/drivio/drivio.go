@@ -0,0 +1,170 @@
+// Package drivio is the library API of drivio: it generates, renders and
+// publishes release notes, for programs embedding note generation instead
+// of running the drivio command.
+//
+// The API follows semantic versioning, APIVersion being its version: the
+// exported identifiers of this package, and the fields of the types it
+// aliases, are only removed or changed in a new major version. The other
+// packages of drivio are internal to the command and may change at any
+// time.
+//
+// Nothing is printed and no environment variable or configuration file is
+// read: everything is passed explicitly.
+//
+//	src := drivio.NewGitHub("openshift", "hypershift", os.Getenv("GITHUB_TOKEN"))
+//	notes, err := drivio.Generate(ctx, src, "v0.1.59", "v0.1.63", drivio.Options{Label: drivio.DefaultLabel})
+//	if err != nil {
+//		return err
+//	}
+//	markdown, err := drivio.Render(src, notes, drivio.RenderOptions{Sections: true})
+package drivio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"drivio/pkg/git"
+)
+
+// APIVersion is the semantic version of the library API
+const APIVersion = "1.3.0"
+
+// ErrNotSupported is returned for operations a source does not support,
+// e.g. publishing the release of a local clone
+var ErrNotSupported = errors.New("not supported")
+
+// Notes are the release notes of the changes between two references
+type Notes = git.ReleaseNotes
+
+// Commit is a change of the notes
+type Commit = git.CommitInfo
+
+// Rule gives a type to the changes without a conventional one
+type Rule = git.TypeRule
+
+// NewRule returns a rule giving a type to the changes whose subject, body
+// or label match the regular expressions that are not empty
+func NewRule(commitType, subject, body, label string) (Rule, error) {
+	return git.NewTypeRule(commitType, subject, body, label)
+}
+
+// Format is the format notes are rendered in
+type Format = git.OutputFormat
+
+// Formats of the notes
+const (
+	FormatMarkdown      = git.FormatMarkdown
+	FormatMarkdownTable = git.FormatMarkdownTable
+	FormatJSON          = git.FormatJSON
+	FormatText          = git.FormatText
+)
+
+// DefaultLabel is the label the drivio command requires on pull requests
+const DefaultLabel = git.DefaultLabel
+
+// DefaultTicketPattern matches the line of a commit message naming the
+// ticket of the change, e.g. "OCPBUGS-1234: Fix the reconciliation loop"
+var DefaultTicketPattern = git.DefaultTicketPattern
+
+// Options select the changes of the notes
+type Options struct {
+	// Label is the label pull requests need to be noted; empty accepts any
+	// pull request. Labels are only known to GitHub sources.
+	Label string
+	// TicketPattern matches the "<TICKET>: <description>" line of the
+	// messages of the changes noted; nil is DefaultTicketPattern
+	TicketPattern *regexp.Regexp
+	// Rules give a type to the changes without a conventional one, the
+	// first matching rule winning
+	Rules []Rule
+	// Progress, when set, is called as the changes are classified
+	Progress func(done, total int, message string)
+}
+
+// classifier returns the classifier of the options, looking up the labels
+// of pull requests with labels when not nil
+func (o Options) classifier(labels func(ctx context.Context, pr int) ([]string, error)) *git.Classifier {
+	classifier := &git.Classifier{
+		TicketPattern: o.TicketPattern,
+		Labels:        labels,
+		Rules:         o.Rules,
+		Progress:      o.Progress,
+	}
+	if classifier.TicketPattern == nil {
+		classifier.TicketPattern = git.DefaultTicketPattern
+	}
+	if labels != nil {
+		classifier.Label = o.Label
+	}
+	return classifier
+}
+
+// Generate generates the notes of the changes between two references of a
+// source, e.g. two tags
+func Generate(ctx context.Context, src Source, from, to string, opts Options) (*Notes, error) {
+	if from == "" || to == "" {
+		return nil, fmt.Errorf("both references are required")
+	}
+	return src.generate(ctx, from, to, opts)
+}
+
+// PreviousRelease returns the tag with the highest release version before
+// version among the tags of a source, or the highest one when version is
+// empty; empty when there is none. Only the tags of the component of
+// version are compared, e.g. api/v1.4.0 for api/v1.5.0 in a monorepo, or
+// those of the whole repository when version is empty.
+func PreviousRelease(ctx context.Context, src Source, version string) (string, error) {
+	tags, err := src.Tags(ctx)
+	if err != nil {
+		return "", err
+	}
+	tag, _, err := git.PreviousVersion(tags, version, "")
+	return tag, err
+}
+
+// RenderOptions tell how notes are rendered
+type RenderOptions struct {
+	// Format is the format of the notes; empty is FormatMarkdown
+	Format Format
+	// Sections groups the changes by type, under a heading per type
+	Sections bool
+	// Summary appends the counts of the changes by type, scope, author and
+	// label
+	Summary bool
+	// MaxPerSection folds the sections after this many changes; 0 renders
+	// every change
+	MaxPerSection int
+	// TicketURL is the address of the tickets, followed by their key; empty
+	// is https://issues.redhat.com/browse/
+	TicketURL string
+	// TemplateFiles are Go templates rendering Markdown and text notes
+	// instead of the built-in ones
+	TemplateFiles []string
+	// Compact renders JSON on a single line
+	Compact bool
+}
+
+// Render renders the notes of a source, linking the changes to its web
+// interface
+func Render(src Source, notes *Notes, opts RenderOptions) (string, error) {
+	format := opts.Format
+	if format == "" {
+		format = FormatMarkdown
+	}
+	var formatterOpts []git.FormatterOption
+	if len(opts.TemplateFiles) > 0 {
+		formatterOpts = append(formatterOpts, git.WithTemplateFiles(opts.TemplateFiles...))
+	}
+	formatter := git.NewFormatter(format, formatterOpts...)
+	formatter.Sections = opts.Sections
+	formatter.Summary = opts.Summary
+	formatter.MaxPerSection = opts.MaxPerSection
+	formatter.Compact = opts.Compact
+	if opts.TicketURL != "" {
+		formatter.TicketURL = opts.TicketURL
+	}
+	src.configure(formatter)
+	return formatter.Format(notes)
+}
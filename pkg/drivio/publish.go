@@ -0,0 +1,38 @@
+package drivio
+
+import (
+	"context"
+
+	"drivio/pkg/config"
+	"drivio/pkg/notify"
+)
+
+// Release is a release published with notes
+type Release struct {
+	URL string
+	// Changed is false when the release kept the description it had
+	Changed bool
+}
+
+// Publish sets notes as the description of the release of a tag, creating
+// the release when the tag has none. A release with a description keeps it
+// unless overwrite is set. Local clones have no releases: ErrNotSupported.
+func Publish(ctx context.Context, src Source, tag, body string, overwrite bool) (*Release, error) {
+	return src.publish(ctx, tag, body, overwrite)
+}
+
+// Channel is a notification channel: Slack, Microsoft Teams, a generic
+// webhook, email or a plugin
+type Channel = config.Channel
+
+// Message is a notification, e.g. of a release
+type Message = notify.Message
+
+// Notify sends a message to a channel
+func Notify(ctx context.Context, channel *Channel, msg Message) error {
+	notifier, err := notify.New(channel)
+	if err != nil {
+		return err
+	}
+	return notifier.Notify(ctx, msg)
+}
@@ -0,0 +1,273 @@
+package drivio
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"drivio/pkg/config"
+	"drivio/pkg/git"
+	"drivio/pkg/github"
+	"drivio/pkg/gitlab"
+	"drivio/pkg/lock"
+)
+
+// Source is a repository notes are generated for: a GitHub repository, a
+// GitLab project or a local clone. Sources are created with NewGitHub,
+// NewGitLab and NewLocal.
+type Source interface {
+	// Repository returns the owner and the name of the repository; for
+	// GitLab projects, the owner is the path of their group
+	Repository() (owner, name string)
+	// Tags returns the tags of the repository
+	Tags(ctx context.Context) ([]string, error)
+	// ReleaseURL returns the address of the release of a tag, empty when
+	// the source has no releases
+	ReleaseURL(tag string) string
+
+	generate(ctx context.Context, from, to string, opts Options) (*Notes, error)
+	// commits returns every commit between the references
+	commits(ctx context.Context, from, to string) ([]Commit, error)
+	// pullRequest returns the author and times of a merged pull request,
+	// nil when the source does not know them
+	pullRequest(ctx context.Context, number int) (*PullRequestStats, error)
+	// configure sets the links of the formatter
+	configure(formatter *git.Formatter)
+	publish(ctx context.Context, tag, body string, overwrite bool) (*Release, error)
+}
+
+// gitHubSource reads a GitHub repository through the API
+type gitHubSource struct {
+	client      *github.Client
+	analyzer    *git.Analyzer
+	owner, repo string
+}
+
+// NewGitHub returns the source of a GitHub repository, read through the
+// API. The token is optional for public repositories, but raises the rate
+// limits.
+func NewGitHub(owner, repo, token string) Source {
+	client := github.NewClient(token)
+	return &gitHubSource{client: client, analyzer: git.NewAnalyzerWithClient(client), owner: owner, repo: repo}
+}
+
+func (s *gitHubSource) Repository() (string, string) {
+	return s.owner, s.repo
+}
+
+func (s *gitHubSource) Tags(ctx context.Context) ([]string, error) {
+	return s.client.ListTags(ctx, s.owner, s.repo)
+}
+
+func (s *gitHubSource) ReleaseURL(tag string) string {
+	return fmt.Sprintf("https://github.com/%s/%s/releases/tag/%s", s.owner, s.repo, tag)
+}
+
+func (s *gitHubSource) generate(ctx context.Context, from, to string, opts Options) (*Notes, error) {
+	classifier := opts.classifier(func(ctx context.Context, pr int) ([]string, error) {
+		return s.analyzer.PullRequestLabels(ctx, s.owner, s.repo, pr)
+	})
+	return s.analyzer.GenerateReleaseNotes(ctx, s.owner, s.repo, from, to, classifier)
+}
+
+func (s *gitHubSource) commits(ctx context.Context, from, to string) ([]Commit, error) {
+	return s.analyzer.Commits(ctx, s.owner, s.repo, from, to)
+}
+
+func (s *gitHubSource) pullRequest(ctx context.Context, number int) (*PullRequestStats, error) {
+	pr, err := s.client.GetPullRequest(ctx, s.owner, s.repo, number)
+	if err != nil {
+		return nil, err
+	}
+	reviews, err := s.client.PullRequestReviews(ctx, s.owner, s.repo, number)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &PullRequestStats{Number: number, Title: pr.Title, Author: pr.User.Login, CreatedAt: &pr.CreatedAt}
+	if pr.MergedAt != nil {
+		stats.MergedAt = *pr.MergedAt
+	}
+	// The first review of someone else than the author
+	for _, review := range reviews {
+		if review.SubmittedAt != nil && review.User.Login != pr.User.Login && review.State != "PENDING" {
+			if stats.FirstReviewAt == nil || review.SubmittedAt.Before(*stats.FirstReviewAt) {
+				stats.FirstReviewAt = review.SubmittedAt
+			}
+		}
+	}
+	return stats, nil
+}
+
+func (s *gitHubSource) configure(formatter *git.Formatter) {}
+
+func (s *gitHubSource) publish(ctx context.Context, tag, body string, overwrite bool) (*Release, error) {
+	release, changed, err := s.client.PublishRelease(ctx, s.owner, s.repo, tag, body, overwrite)
+	if err != nil {
+		return nil, err
+	}
+	return &Release{URL: release.HTMLURL, Changed: changed}, nil
+}
+
+// localSource reads a clone with git
+type localSource struct {
+	analyzer    *git.LocalAnalyzer
+	owner, repo string
+	// gitLab is set for the mirrors of GitLab projects, which are linked on
+	// the instance at webURL and publish their releases with cfg
+	gitLab *config.Config
+	webURL string
+}
+
+// NewLocal returns the source of a local clone, read with git; bare and
+// mirror clones work too. The owner and name of the repository name it in
+// the notes, whose links go to GitHub. Pull request labels are only known
+// to GitHub, so every pull request naming a ticket is noted.
+func NewLocal(dir, owner, repo string) (Source, error) {
+	analyzer, err := git.NewLocalAnalyzer(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &localSource{analyzer: analyzer, owner: owner, repo: repo}, nil
+}
+
+// NewGitLab returns the source of a GitLab project, e.g. group/project, of
+// the instance at baseURL (https://gitlab.com when empty). The project is
+// mirrored into mirrorDir, or the mirror there updated, and read with git:
+// merge requests are recognized by their merge commits. The token is
+// optional for public projects.
+func NewGitLab(ctx context.Context, baseURL, token, project, mirrorDir string) (Source, error) {
+	return NewGitLabWithProgress(ctx, baseURL, token, project, mirrorDir, nil)
+}
+
+// NewGitLabWithProgress is NewGitLab reporting the progress of the clone of
+// the mirror, when made, as git reports it: the objects of each phase, e.g.
+// "Receiving objects", received or resolved out of their total
+func NewGitLabWithProgress(ctx context.Context, baseURL, token, project, mirrorDir string, progress func(done, total int64, phase string)) (Source, error) {
+	i := strings.LastIndex(project, "/")
+	if i <= 0 || i == len(project)-1 {
+		return nil, fmt.Errorf("invalid GitLab project %q", project)
+	}
+	if baseURL == "" {
+		baseURL = config.DefaultGitLabURL
+	}
+	webURL := strings.TrimRight(baseURL, "/")
+	if _, err := url.Parse(webURL); err != nil {
+		return nil, fmt.Errorf("invalid GitLab URL %s: %w", baseURL, err)
+	}
+
+	if err := syncMirror(ctx, webURL+"/"+project+".git", mirrorDir, git.CloneOptions{Mirror: true, Username: "oauth2", Token: token}, progress); err != nil {
+		return nil, err
+	}
+	analyzer, err := git.NewLocalAnalyzer(mirrorDir)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &config.Config{
+		GitLabURL:      webURL,
+		GitLabToken:    token,
+		RepositoryPath: project,
+		RetryAttempts:  config.DefaultRetryAttempts,
+		RetryBackoff:   config.DefaultRetryBackoff,
+	}
+	return &localSource{analyzer: analyzer, owner: project[:i], repo: project[i+1:], gitLab: cfg, webURL: webURL}, nil
+}
+
+// syncMirror clones a mirror into dir, or updates the mirror there. Runs
+// syncing the same mirror, e.g. the jobs of serve, wait for each other on
+// the lock kept next to it. The clone is made aside and moved into place
+// once complete, so that a failed clone leaves no partial mirror behind.
+func syncMirror(ctx context.Context, remote, dir string, opts git.CloneOptions, progress func(done, total int64, phase string)) error {
+	if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+		return err
+	}
+	l, err := lock.AcquireFile(ctx, dir+".lock", -1, nil)
+	if err != nil {
+		return fmt.Errorf("failed to lock mirror %s: %w", dir, err)
+	}
+	defer l.Release()
+
+	if _, err := os.Stat(dir); err == nil {
+		return git.UpdateMirror(ctx, dir, opts)
+	}
+	tmp, err := os.MkdirTemp(filepath.Dir(dir), filepath.Base(dir)+".clone-")
+	if err != nil {
+		return err
+	}
+	var report func(git.CloneProgress)
+	if progress != nil {
+		report = func(p git.CloneProgress) { progress(p.Current, p.Total, p.Phase) }
+	}
+	if err := git.CloneWithProgress(ctx, remote, tmp, opts, report); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, dir); err != nil {
+		os.RemoveAll(tmp)
+		return fmt.Errorf("failed to move mirror into place: %w", err)
+	}
+	return nil
+}
+
+func (s *localSource) Repository() (string, string) {
+	return s.owner, s.repo
+}
+
+func (s *localSource) Tags(ctx context.Context) ([]string, error) {
+	return s.analyzer.Tags(ctx)
+}
+
+func (s *localSource) ReleaseURL(tag string) string {
+	if s.gitLab == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s/%s/-/releases/%s", s.webURL, s.owner, s.repo, url.PathEscape(tag))
+}
+
+func (s *localSource) generate(ctx context.Context, from, to string, opts Options) (*Notes, error) {
+	commits, err := s.analyzer.Commits(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commits between references: %w", err)
+	}
+	selected := opts.classifier(nil).Classify(ctx, commits)
+	return git.NewReleaseNotes(s.owner, s.repo, from, to, commits, selected), nil
+}
+
+func (s *localSource) commits(ctx context.Context, from, to string) ([]Commit, error) {
+	commits, err := s.analyzer.Commits(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commits between references: %w", err)
+	}
+	return commits, nil
+}
+
+// pullRequest returns nil: clones only know the merge commits
+func (s *localSource) pullRequest(ctx context.Context, number int) (*PullRequestStats, error) {
+	return nil, nil
+}
+
+func (s *localSource) configure(formatter *git.Formatter) {
+	if s.gitLab != nil {
+		formatter.WebURL = s.webURL
+		formatter.GitLab = true
+	}
+}
+
+func (s *localSource) publish(ctx context.Context, tag, body string, overwrite bool) (*Release, error) {
+	if s.gitLab == nil {
+		return nil, fmt.Errorf("publishing the release of a local clone: %w", ErrNotSupported)
+	}
+	client, err := gitlab.NewClient(s.gitLab)
+	if err != nil {
+		return nil, err
+	}
+	_, changed, err := client.PublishRelease(ctx, tag, body, overwrite)
+	if err != nil {
+		return nil, err
+	}
+	return &Release{URL: s.ReleaseURL(tag), Changed: changed}, nil
+}
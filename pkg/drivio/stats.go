@@ -0,0 +1,233 @@
+package drivio
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Stats are the contribution statistics of the changes between two
+// references
+type Stats struct {
+	Owner string `json:"owner"`
+	Repo  string `json:"repo"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+	// Commits is the number of commits between the references, merges
+	// included
+	Commits int `json:"commits"`
+	// Authors count the commits of their authors, merges left out, most
+	// commits first
+	Authors []Contributor `json:"authors"`
+	// PullRequestAuthors count the pull requests merged of their authors,
+	// by GitHub login, most first; only GitHub sources know them
+	PullRequestAuthors []Contributor `json:"pull_request_authors"`
+	// PullRequests are the pull or merge requests merged between the
+	// references, oldest first
+	PullRequests []PullRequestStats `json:"pull_requests"`
+	// Weeks count the merges by ISO week, oldest first, weeks without
+	// merges included
+	Weeks []WeekStats `json:"weeks"`
+	// MergesPerWeek is the mean number of merges per week between the first
+	// and the last one, over a week at least
+	MergesPerWeek float64 `json:"merges_per_week"`
+	// TimeToFirstReview and TimeToMerge sum up the latencies of the pull
+	// requests from their creation, nil when the source does not know them
+	TimeToFirstReview *Latency `json:"time_to_first_review,omitempty"`
+	TimeToMerge       *Latency `json:"time_to_merge,omitempty"`
+}
+
+// Contributor is an author and the number of their contributions
+type Contributor struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// PullRequestStats is a merged pull or merge request
+type PullRequestStats struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	// Author, CreatedAt and FirstReviewAt are only known to GitHub sources
+	Author        string     `json:"author,omitempty"`
+	CreatedAt     *time.Time `json:"created_at,omitempty"`
+	FirstReviewAt *time.Time `json:"first_review_at,omitempty"`
+	MergedAt      time.Time  `json:"merged_at"`
+}
+
+// WeekStats are the merges of an ISO week, e.g. 2024-W05
+type WeekStats struct {
+	Week   string `json:"week"`
+	Merges int    `json:"merges"`
+}
+
+// Latency sums up durations
+type Latency struct {
+	Samples int
+	Median  time.Duration
+	Mean    time.Duration
+	Max     time.Duration
+}
+
+// MarshalJSON renders the durations as strings, e.g. 26h30m0s
+func (l Latency) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Samples int    `json:"samples"`
+		Median  string `json:"median"`
+		Mean    string `json:"mean"`
+		Max     string `json:"max"`
+	}{l.Samples, l.Median.String(), l.Mean.String(), l.Max.String()})
+}
+
+// StatsOptions tell how statistics are computed
+type StatsOptions struct {
+	// Progress, when set, is called as the pull requests are looked up
+	Progress func(done, total int, message string)
+}
+
+// ComputeStats computes the contribution statistics of the changes between
+// two references of a source. GitHub sources look up every pull request
+// for its author and latencies.
+func ComputeStats(ctx context.Context, src Source, from, to string, opts StatsOptions) (*Stats, error) {
+	if from == "" || to == "" {
+		return nil, fmt.Errorf("both references are required")
+	}
+	commits, err := src.commits(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &Stats{From: from, To: to, Commits: len(commits), PullRequests: []PullRequestStats{}}
+	stats.Owner, stats.Repo = src.Repository()
+
+	authors := make(map[string]int)
+	prAuthors := make(map[string]int)
+	var merges []Commit
+	for _, commit := range commits {
+		if commit.PR != 0 {
+			merges = append(merges, commit)
+		} else {
+			authors[commit.Author]++
+		}
+	}
+
+	var reviews, merged []time.Duration
+	for i, merge := range merges {
+		if opts.Progress != nil {
+			opts.Progress(i, len(merges), fmt.Sprintf("#%d", merge.PR))
+		}
+		pr, err := src.pullRequest(ctx, merge.PR)
+		if err != nil {
+			return nil, err
+		}
+		if pr == nil {
+			pr = &PullRequestStats{Number: merge.PR, Title: mergeTitle(merge), MergedAt: merge.Date}
+		}
+		if pr.Author != "" {
+			prAuthors[pr.Author]++
+		}
+		if pr.CreatedAt != nil {
+			merged = append(merged, pr.MergedAt.Sub(*pr.CreatedAt))
+			if pr.FirstReviewAt != nil {
+				reviews = append(reviews, pr.FirstReviewAt.Sub(*pr.CreatedAt))
+			}
+		}
+		stats.PullRequests = append(stats.PullRequests, *pr)
+	}
+	if opts.Progress != nil && len(merges) > 0 {
+		opts.Progress(len(merges), len(merges), "")
+	}
+
+	stats.Authors = contributors(authors)
+	stats.PullRequestAuthors = contributors(prAuthors)
+	sort.SliceStable(stats.PullRequests, func(i, j int) bool {
+		return stats.PullRequests[i].MergedAt.Before(stats.PullRequests[j].MergedAt)
+	})
+
+	stats.Weeks, stats.MergesPerWeek = mergeFrequency(stats.PullRequests)
+	stats.TimeToFirstReview = latency(reviews)
+	stats.TimeToMerge = latency(merged)
+	return stats, nil
+}
+
+// contributors returns the contributors of the counts, most contributions
+// first
+func contributors(counts map[string]int) []Contributor {
+	list := make([]Contributor, 0, len(counts))
+	for name, count := range counts {
+		list = append(list, Contributor{Name: name, Count: count})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// mergeTitle returns the title of the pull request merged by a commit: the
+// first line of the body of GitHub and GitLab merge commits
+func mergeTitle(merge Commit) string {
+	for _, line := range strings.Split(merge.Body, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return merge.Subject
+}
+
+// mergeFrequency counts the merges by ISO week, and per week on average
+func mergeFrequency(prs []PullRequestStats) ([]WeekStats, float64) {
+	weeks := []WeekStats{}
+	if len(prs) == 0 {
+		return weeks, 0
+	}
+
+	first, last := prs[0].MergedAt, prs[len(prs)-1].MergedAt
+	// Monday of the week of the first merge
+	day := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, first.Location())
+	day = day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	for ; !day.After(last); day = day.AddDate(0, 0, 7) {
+		year, week := day.ISOWeek()
+		weeks = append(weeks, WeekStats{Week: fmt.Sprintf("%d-W%02d", year, week)})
+	}
+	for _, pr := range prs {
+		year, week := pr.MergedAt.ISOWeek()
+		name := fmt.Sprintf("%d-W%02d", year, week)
+		if i := slices.IndexFunc(weeks, func(w WeekStats) bool { return w.Week == name }); i >= 0 {
+			weeks[i].Merges++
+		}
+	}
+
+	span := last.Sub(first).Hours() / (24 * 7)
+	if span < 1 {
+		span = 1
+	}
+	return weeks, float64(len(prs)) / span
+}
+
+// latency sums up durations, nil without any
+func latency(durations []time.Duration) *Latency {
+	if len(durations) == 0 {
+		return nil
+	}
+	slices.Sort(durations)
+	var total time.Duration
+	for _, d := range durations {
+		total += d
+	}
+	median := durations[len(durations)/2]
+	if len(durations)%2 == 0 {
+		median = (durations[len(durations)/2-1] + median) / 2
+	}
+	return &Latency{
+		Samples: len(durations),
+		Median:  median.Round(time.Minute),
+		Mean:    (total / time.Duration(len(durations))).Round(time.Minute),
+		Max:     durations[len(durations)-1].Round(time.Minute),
+	}
+}
@@ -0,0 +1,154 @@
+package envsubst
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// placeholderPattern matches $${VAR} (an escaped placeholder), ${VAR} and
+// ${VAR:-default}
+var placeholderPattern = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+var envKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// LookupFunc returns the value of a variable and whether it is set
+type LookupFunc func(name string) (string, bool)
+
+// UnsetError lists the placeholders without a value or a default
+type UnsetError struct {
+	Names []string
+}
+
+func (e *UnsetError) Error() string {
+	return fmt.Sprintf("unset variable(s): %s", strings.Join(e.Names, ", "))
+}
+
+// Expand replaces the ${VAR} placeholders in content with the values
+// returned by lookup. ${VAR:-default} falls back to default when VAR is
+// unset or empty, and $${VAR} is kept as a literal ${VAR}. All unset
+// variables are reported together in an *UnsetError.
+func Expand(content []byte, lookup LookupFunc) ([]byte, error) {
+	unset := make(map[string]bool)
+
+	result := placeholderPattern.ReplaceAllFunc(content, func(match []byte) []byte {
+		if bytes.HasPrefix(match, []byte("$$")) {
+			return match[1:]
+		}
+
+		groups := placeholderPattern.FindSubmatch(match)
+		name := string(groups[1])
+		value, ok := lookup(name)
+		if groups[2] != nil && value == "" {
+			return groups[3]
+		}
+		if !ok {
+			unset[name] = true
+			return match
+		}
+		return []byte(value)
+	})
+
+	if len(unset) > 0 {
+		names := make([]string, 0, len(unset))
+		for name := range unset {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, &UnsetError{Names: names}
+	}
+	return result, nil
+}
+
+// LoadEnvFile reads a dotenv file: KEY=VALUE lines, with optional export
+// prefixes, quoted values and # comments
+func LoadEnvFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read env file: %w", err)
+	}
+
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !envKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, lineNumber)
+		}
+
+		value, err := parseEnvValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNumber, err)
+		}
+		values[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read env file: %w", err)
+	}
+
+	return values, nil
+}
+
+// parseEnvValue unquotes a dotenv value. Double-quoted values support the
+// usual escapes; unquoted values end at an inline " #" comment.
+func parseEnvValue(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, `"`):
+		end := closingQuote(value)
+		if end < 0 {
+			return "", fmt.Errorf("unterminated double-quoted value")
+		}
+		unquoted, err := strconv.Unquote(value[:end+1])
+		if err != nil {
+			return "", fmt.Errorf("invalid double-quoted value: %w", err)
+		}
+		return unquoted, nil
+	case strings.HasPrefix(value, "'"):
+		end := strings.Index(value[1:], "'")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated single-quoted value")
+		}
+		return value[1 : end+1], nil
+	default:
+		if i := strings.Index(value, " #"); i >= 0 {
+			value = value[:i]
+		}
+		return strings.TrimSpace(value), nil
+	}
+}
+
+// closingQuote returns the index of the unescaped double quote closing value
+func closingQuote(value string) int {
+	for i := 1; i < len(value); i++ {
+		switch value[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// Lookup returns a LookupFunc reading values first, then the process
+// environment
+func Lookup(values map[string]string) LookupFunc {
+	return func(name string) (string, bool) {
+		if value, ok := values[name]; ok {
+			return value, true
+		}
+		return os.LookupEnv(name)
+	}
+}
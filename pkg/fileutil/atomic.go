@@ -0,0 +1,83 @@
+package fileutil
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// BackupSuffix is appended to the name of the backup kept by WriteFileAtomic
+const BackupSuffix = ".bak"
+
+// WriteFileAtomic writes data to a temporary file in the same directory and
+// renames it over path, so readers never observe a partially written file.
+//
+// If backup is true and path already exists with different content, the
+// previous content is preserved as path + BackupSuffix. The mode of an
+// existing file is kept; perm is only used for new files.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode, backup bool) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+
+	existing, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if info, statErr := os.Stat(path); statErr == nil {
+			perm = info.Mode().Perm()
+		}
+		if bytes.Equal(existing, data) {
+			// Nothing changed, leave the file and its backup untouched
+			return nil
+		}
+		if backup {
+			if err := writeAtomic(path+BackupSuffix, existing, perm); err != nil {
+				return fmt.Errorf("failed to back up %s: %w", path, err)
+			}
+		}
+	case !os.IsNotExist(err):
+		return fmt.Errorf("failed to read existing file %s: %w", path, err)
+	}
+
+	return writeAtomic(path, data, perm)
+}
+
+// writeAtomic writes data to a temporary file and renames it over path
+func writeAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	// Remove the temporary file unless it was successfully renamed
+	committed := false
+	defer func() {
+		if !committed {
+			os.Remove(tmpPath)
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+
+	committed = true
+	return nil
+}
@@ -1,41 +1,118 @@
+// Package git generates release notes from the history of a GitHub
+// repository, without cloning it, and clones repositories when a local copy
+// is needed (see CloneWithProgress). An Analyzer lists the commits between
+// two references, or a LocalAnalyzer those of a local clone, offline; a
+// Classifier selects the changes worth noting, and a Formatter renders them:
+//
+//	analyzer := git.NewAnalyzer(token)
+//	notes, err := analyzer.GenerateReleaseNotes(ctx, owner, repo, from, to, git.NewClassifier(analyzer, owner, repo))
+//	...
+//	output, err := git.NewFormatter(git.FormatMarkdown).Format(notes)
 package git
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"net/http"
+	"net/url"
 	"strings"
 	"time"
+
+	"drivio/pkg/github"
 )
 
 // CommitInfo represents information about a commit
 type CommitInfo struct {
-	Hash    string
-	Author  string
-	Email   string
-	Date    time.Time
-	Subject string
+	Hash    string    `json:"hash"`
+	Author  string    `json:"author"`
+	Email   string    `json:"email"`
+	Date    time.Time `json:"date"`
+	Subject string    `json:"subject"`
+	// Body is the message after the subject line
+	Body string `json:"body,omitempty"`
+	// PR is the number of the pull request merged by the commit, 0 when it
+	// is not a pull request merge
+	PR int `json:"pr,omitempty"`
+	// Type, Scope, Breaking and Footers come from the Conventional Commits
+	// header and trailers of the message; for pull request merges, from the
+	// title of the pull request in the body. Type is empty when the message
+	// does not follow the specification.
+	Type     string   `json:"type,omitempty"`
+	Scope    string   `json:"scope,omitempty"`
+	Breaking bool     `json:"breaking"`
+	Footers  []Footer `json:"footers,omitempty"`
+	// Labels are the labels of the pull request, set by the Classifier
+	Labels []string `json:"labels,omitempty"`
+	// Ticket and Description come from the "<TICKET>: <description>" line of
+	// the message, set by the Classifier
+	Ticket      string `json:"ticket,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Verification is the result of checking the signature of the commit,
+	// nil unless the analyzer verifies signatures
+	Verification *Verification `json:"verification,omitempty"`
+}
+
+// ShortHash returns the abbreviated hash of the commit
+func (c CommitInfo) ShortHash() string {
+	if len(c.Hash) > 8 {
+		return c.Hash[:8]
+	}
+	return c.Hash
 }
 
 // ReleaseNotes represents the generated release notes
 type ReleaseNotes struct {
-	FromRef     string
-	ToRef       string
-	FromHash    string
-	ToHash      string
-	GeneratedAt time.Time
-	Commits     []CommitInfo
-	Statistics  CommitStatistics
+	Owner   string `json:"owner"`
+	Repo    string `json:"repo"`
+	FromRef string `json:"from"`
+	ToRef   string `json:"to"`
+	// Since and Until bound the commit dates of the changes, when the
+	// analyzer only kept those of a period
+	Since       *time.Time `json:"since,omitempty"`
+	Until       *time.Time `json:"until,omitempty"`
+	GeneratedAt time.Time  `json:"generated_at"`
+	// Commits are the commits selected by the Classifier, oldest first
+	Commits    []CommitInfo     `json:"commits"`
+	Statistics CommitStatistics `json:"statistics"`
+	// Tags are the verifications of the references that are tags, set when
+	// the analyzer verifies signatures
+	Tags map[string]*Verification `json:"tags,omitempty"`
+	// Ownership is the churn between the references by author and by
+	// directory, rendered as an appendix when set
+	Ownership *Ownership `json:"ownership,omitempty"`
+	// MergeBase and Range are set when the references have diverged: the
+	// best common ancestor of the references, and which commits are listed
+	MergeBase string    `json:"merge_base,omitempty"`
+	Range     RangeMode `json:"range,omitempty"`
 }
 
 // CommitStatistics represents statistics about commits
 type CommitStatistics struct {
-	Total int
+	// Total is the number of commits between the references
+	Total int `json:"total"`
+	// PullRequests is the number of pull request merges among them
+	PullRequests int `json:"pull_requests"`
+	// Selected is the number of commits kept by the Classifier
+	Selected int `json:"selected"`
+	// ByType counts the selected commits by conventional commit type, ""
+	// counting those without one
+	ByType map[string]int `json:"by_type"`
+	// ByScope counts the selected commits by conventional commit scope, ""
+	// counting those without one
+	ByScope map[string]int `json:"by_scope"`
+	// ByAuthor counts the selected commits by author name
+	ByAuthor map[string]int `json:"by_author"`
+	// ByLabel counts the selected commits by pull request label; a commit
+	// counts for each of its labels
+	ByLabel map[string]int `json:"by_label"`
+	// Breaking is the number of selected commits with breaking changes
+	Breaking int `json:"breaking"`
+	// Unverified is the number of commits between the references whose
+	// signature failed verification, when signatures are verified
+	Unverified int `json:"unverified,omitempty"`
 }
 
-// GitHubCommit represents a commit from GitHub API
-type GitHubCommit struct {
+// githubCommit is a commit as returned by the GitHub compare API
+type githubCommit struct {
 	Sha    string `json:"sha"`
 	Commit struct {
 		Author struct {
@@ -43,174 +120,250 @@ type GitHubCommit struct {
 			Email string    `json:"email"`
 			Date  time.Time `json:"date"`
 		} `json:"author"`
-		Message string `json:"message"`
+		Message      string             `json:"message"`
+		Verification githubVerification `json:"verification"`
 	} `json:"commit"`
 }
 
-// OutputFormat represents the output format for release notes
-type OutputFormat string
+// comparePageSize is the number of commits requested per page of the
+// compare API, its maximum
+const comparePageSize = 100
 
-const (
-	FormatMarkdown OutputFormat = "markdown"
-	FormatJSON     OutputFormat = "json"
-	FormatText     OutputFormat = "text"
-)
-
-// Formatter represents a release notes formatter
-type Formatter struct {
-	format OutputFormat
+// Analyzer lists the commits of a GitHub repository through its API, with
+// the client of the CLI: authenticated when it has a token, following the
+// pages of long comparisons, and reporting exhausted rate limits as such
+type Analyzer struct {
+	client *github.Client
+	// VerifySignatures sets the verification of the commits, as reported by
+	// GitHub, and of the references that are tags
+	VerifySignatures bool
+	// Range selects the commits listed when the references have diverged;
+	// empty is RangeThreeDot
+	Range RangeMode
 }
 
-// NewFormatter creates a new formatter with the specified format
-func NewFormatter(format OutputFormat) *Formatter {
-	return &Formatter{format: format}
+// NewAnalyzer creates a new GitHub commit analyzer. The token is optional;
+// without it requests are unauthenticated and subject to lower rate limits.
+func NewAnalyzer(token string) *Analyzer {
+	return NewAnalyzerWithClient(github.NewClient(token))
 }
 
-// Format formats release notes according to the specified format
-func (f *Formatter) Format(notes *ReleaseNotes) (string, error) {
-	switch f.format {
-	case FormatMarkdown:
-		return f.formatMarkdown(notes)
-	case FormatJSON:
-		return f.formatJSON(notes)
-	case FormatText:
-		return f.formatText(notes)
-	default:
-		return "", fmt.Errorf("unsupported format: %s", f.format)
-	}
+// NewAnalyzerWithClient creates an analyzer using a GitHub client, e.g. one
+// pointed at GitHub Enterprise Server with SetBaseURL
+func NewAnalyzerWithClient(client *github.Client) *Analyzer {
+	return &Analyzer{client: client}
 }
 
-// formatMarkdown formats release notes as Markdown
-func (f *Formatter) formatMarkdown(notes *ReleaseNotes) (string, error) {
-	var sb strings.Builder
-
-	// Formato exacto del script notes.go de HyperShift
-	for _, commit := range notes.Commits {
-		sb.WriteString(fmt.Sprintf("%s %s\n", commit.Hash[:8], commit.Subject))
+// GenerateReleaseNotes lists the commits between two references and keeps
+// those selected by the classifier
+func (a *Analyzer) GenerateReleaseNotes(ctx context.Context, owner, repo, fromRef, toRef string, classifier *Classifier) (*ReleaseNotes, error) {
+	commits, err := a.Commits(ctx, owner, repo, fromRef, toRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commits between references: %w", err)
 	}
 
-	return sb.String(), nil
-}
-
-// formatJSON formats release notes as JSON
-func (f *Formatter) formatJSON(notes *ReleaseNotes) (string, error) {
-	data, err := json.MarshalIndent(notes, "", "  ")
-	if err != nil {
-		return "", fmt.Errorf("failed to marshal JSON: %w", err)
+	notes := NewReleaseNotes(owner, repo, fromRef, toRef, commits, classifier.Classify(ctx, commits))
+	if mergeBase, diverged, err := a.Diverged(ctx, owner, repo, fromRef, toRef); err != nil {
+		return nil, err
+	} else if diverged {
+		notes.SetMergeBase(mergeBase, a.Range)
 	}
-	return string(data), nil
+	if a.VerifySignatures {
+		notes.Tags, err = VerifyTags(func(ref string) (*Verification, error) {
+			return a.VerifyTag(ctx, owner, repo, ref)
+		}, fromRef, toRef)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return notes, nil
 }
 
-// formatText formats release notes as plain text
-func (f *Formatter) formatText(notes *ReleaseNotes) (string, error) {
-	var sb strings.Builder
-
-	// Formato exacto del script notes.go de HyperShift
-	for _, commit := range notes.Commits {
-		sb.WriteString(fmt.Sprintf("%s %s\n", commit.Hash[:8], commit.Subject))
+// VerifyTags verifies the references that are tags with verify, keyed by
+// reference; nil when none is a tag
+func VerifyTags(verify func(ref string) (*Verification, error), refs ...string) (map[string]*Verification, error) {
+	var tags map[string]*Verification
+	for _, ref := range refs {
+		if ref == "" {
+			continue
+		}
+		verification, err := verify(ref)
+		if err != nil {
+			return nil, err
+		}
+		if verification == nil {
+			continue
+		}
+		if tags == nil {
+			tags = make(map[string]*Verification)
+		}
+		tags[ref] = verification
 	}
-
-	return sb.String(), nil
+	return tags, nil
 }
 
-// Analyzer represents a GitHub commit analyzer
-type Analyzer struct {
-	client  *http.Client
-	baseURL string
+// SetMergeBase records that the references have diverged at mergeBase, and
+// the mode of the range of the commits
+func (n *ReleaseNotes) SetMergeBase(mergeBase string, mode RangeMode) {
+	if mode == "" {
+		mode = RangeThreeDot
+	}
+	n.MergeBase = mergeBase
+	n.Range = mode
 }
 
-// NewAnalyzer creates a new GitHub commit analyzer
-func NewAnalyzer() *Analyzer {
-	return &Analyzer{
-		client:  &http.Client{Timeout: 30 * time.Second},
-		baseURL: "https://api.github.com",
+// SetPeriod records the period the changes were committed in; zero times
+// are left unset
+func (n *ReleaseNotes) SetPeriod(since, until time.Time) {
+	if !since.IsZero() {
+		n.Since = &since
+	}
+	if !until.IsZero() {
+		n.Until = &until
 	}
 }
 
-// GenerateReleaseNotes generates release notes between two references using GitHub API
-func (a *Analyzer) GenerateReleaseNotes(owner, repo, fromRef, toRef string) (*ReleaseNotes, error) {
-	// Get commits between the two references using GitHub API
-	commits, err := a.getCommitsBetween(owner, repo, fromRef, toRef)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get commits between references: %w", err)
+// NewReleaseNotes creates the release notes of the commits selected among
+// all the commits between the references
+func NewReleaseNotes(owner, repo, fromRef, toRef string, commits, selected []CommitInfo) *ReleaseNotes {
+	stats := CommitStatistics{
+		Total:    len(commits),
+		Selected: len(selected),
+		ByType:   make(map[string]int),
+		ByScope:  make(map[string]int),
+		ByAuthor: make(map[string]int),
+		ByLabel:  make(map[string]int),
 	}
-
-	analyzedCommits := make([]CommitInfo, 0)
-	stats := CommitStatistics{}
-
-	fmt.Printf("🔍 Found %d total commits between references\n", len(commits))
-
-	// El API de GitHub los devuelve en orden del más antiguo al más reciente, pero lo aseguramos
 	for _, commit := range commits {
-		// Excluir merges
-		lines := strings.Split(commit.Commit.Message, "\n")
-		if len(lines) == 0 {
-			continue
+		if commit.PR != 0 {
+			stats.PullRequests++
 		}
-		subject := strings.TrimSpace(lines[0])
-		if strings.HasPrefix(subject, "Merge pull request") {
-			continue
+		if commit.Verification != nil && !commit.Verification.Verified {
+			stats.Unverified++
 		}
-
-		commitInfo := CommitInfo{
-			Hash:    commit.Sha,
-			Author:  commit.Commit.Author.Name,
-			Email:   commit.Commit.Author.Email,
-			Date:    commit.Commit.Author.Date,
-			Subject: strings.TrimSpace(commit.Commit.Message), // Mensaje completo
+	}
+	for _, commit := range selected {
+		stats.ByType[commit.Type]++
+		stats.ByScope[commit.Scope]++
+		stats.ByAuthor[commit.Author]++
+		for _, label := range commit.Labels {
+			stats.ByLabel[label]++
+		}
+		if commit.Breaking {
+			stats.Breaking++
 		}
-		analyzedCommits = append(analyzedCommits, commitInfo)
 	}
 
-	stats.Total = len(analyzedCommits)
-
 	return &ReleaseNotes{
+		Owner:       owner,
+		Repo:        repo,
 		FromRef:     fromRef,
 		ToRef:       toRef,
-		FromHash:    "",
-		ToHash:      "",
 		GeneratedAt: time.Now(),
-		Commits:     analyzedCommits,
+		Commits:     selected,
 		Statistics:  stats,
-	}, nil
+	}
 }
 
-// getCommitsBetween gets all commits between two references using GitHub API
-func (a *Analyzer) getCommitsBetween(owner, repo, fromRef, toRef string) ([]GitHubCommit, error) {
-	url := fmt.Sprintf("%s/repos/%s/%s/compare/%s...%s", a.baseURL, owner, repo, fromRef, toRef)
-
-	fmt.Printf("🔗 Calling GitHub API: %s\n", url)
-
-	req, err := http.NewRequestWithContext(context.Background(), "GET", url, nil)
+// Commits returns the commits between two references, oldest first, as
+// selected by Range. Long comparisons are read page by page, as the compare
+// API returns at most 250 commits at once.
+func (a *Analyzer) Commits(ctx context.Context, owner, repo, fromRef, toRef string) ([]CommitInfo, error) {
+	compared, err := a.compare(ctx, owner, repo, fromRef, toRef)
 	if err != nil {
 		return nil, err
 	}
+	if a.Range == RangeTwoDot {
+		// The commits of fromRef since the merge base
+		behind, err := a.compare(ctx, owner, repo, toRef, fromRef)
+		if err != nil {
+			return nil, err
+		}
+		compared = append(compared, behind...)
+	}
 
-	// Add headers for better rate limiting
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
-	req.Header.Set("User-Agent", "drivio-release-notes")
-
-	resp, err := a.client.Do(req)
-	if err != nil {
-		return nil, err
+	commits := make([]CommitInfo, 0, len(compared))
+	for _, commit := range compared {
+		author := commit.Commit.Author
+		info := newCommitInfo(commit.Sha, author.Name, author.Email, author.Date, commit.Commit.Message)
+		if a.VerifySignatures {
+			info.Verification = commit.Commit.Verification.toVerification(fmt.Sprintf("%s <%s>", author.Name, author.Email))
+		}
+		commits = append(commits, info)
+	}
+	if a.Range == RangeTwoDot {
+		sortByDate(commits)
 	}
-	defer resp.Body.Close()
+	return commits, nil
+}
 
-	fmt.Printf("📡 GitHub API response status: %d\n", resp.StatusCode)
+// compare returns the commits of head since its merge base with base, page
+// by page
+func (a *Analyzer) compare(ctx context.Context, owner, repo, base, head string) ([]githubCommit, error) {
+	var compared []githubCommit
+	endpoint := fmt.Sprintf("/repos/%s/%s/compare/%s...%s?per_page=%d", owner, repo,
+		url.PathEscape(base), url.PathEscape(head), comparePageSize)
+	for endpoint != "" {
+		var page struct {
+			TotalCommits int            `json:"total_commits"`
+			Commits      []githubCommit `json:"commits"`
+		}
+		next, err := a.client.GetPage(ctx, endpoint, &page)
+		if err != nil {
+			return nil, err
+		}
+		compared = append(compared, page.Commits...)
+		if len(page.Commits) == 0 || len(compared) >= page.TotalCommits {
+			break
+		}
+		endpoint = next
+	}
+	return compared, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		// Read the response body to get more details about the error
-		body, _ := json.Marshal(resp.Body)
-		return nil, fmt.Errorf("GitHub API returned status %d: %s", resp.StatusCode, string(body))
+// newCommitInfo creates the information of a commit from its message
+func newCommitInfo(hash, author, email string, date time.Time, message string) CommitInfo {
+	subject, body, _ := strings.Cut(message, "\n")
+	info := CommitInfo{
+		Hash:    hash,
+		Author:  author,
+		Email:   email,
+		Date:    date,
+		Subject: strings.TrimSpace(subject),
+		Body:    strings.TrimSpace(body),
+	}
+	info.PR, _ = PullRequestNumber(info.Subject)
+	if info.PR == 0 && strings.HasPrefix(info.Subject, "Merge branch ") {
+		info.PR, _ = MergeRequestNumber(info.Body)
 	}
 
-	var compareResult struct {
-		Commits []GitHubCommit `json:"commits"`
+	// The title of a merged pull request is the first line of the body
+	conventional := ParseConventionalCommit(message)
+	if info.PR != 0 {
+		conventional = ParseConventionalCommit(info.Body)
 	}
+	info.Type = conventional.Type
+	info.Scope = conventional.Scope
+	info.Breaking = conventional.Breaking
+	info.Footers = conventional.Footers
+	return info
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&compareResult); err != nil {
-		return nil, err
+// PullRequestLabels returns the labels of a pull request
+func (a *Analyzer) PullRequestLabels(ctx context.Context, owner, repo string, number int) ([]string, error) {
+	var pr struct {
+		Labels []struct {
+			Name string `json:"name"`
+		} `json:"labels"`
+	}
+	endpoint := fmt.Sprintf("/repos/%s/%s/pulls/%d", owner, repo, number)
+	if err := a.client.GetJSON(ctx, endpoint, &pr); err != nil {
+		return nil, fmt.Errorf("failed to get PR %d: %w", number, err)
 	}
 
-	return compareResult.Commits, nil
+	var labels []string
+	for _, label := range pr.Labels {
+		labels = append(labels, label.Name)
+	}
+	return labels, nil
 }
@@ -0,0 +1,152 @@
+package git
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Checks of the conventions of a change the release notes rely on
+const (
+	// CheckTicket requires a "<TICKET>: <description>" line
+	CheckTicket = "ticket"
+	// CheckLabel requires the label of the noted pull requests
+	CheckLabel = "label"
+	// CheckReleaseNote requires a ```release-note block in the description
+	CheckReleaseNote = "release-note"
+)
+
+// ChangelogChecks lists the checks of the conventions of a change
+var ChangelogChecks = []string{CheckTicket, CheckLabel, CheckReleaseNote}
+
+// releaseNoteBlock matches a fenced ```release-note block
+var releaseNoteBlock = regexp.MustCompile("(?ms)^\\s*```release-note[ \\t]*\\r?\\n(.*?)^\\s*```")
+
+// ReleaseNoteBlock returns the text of the first ```release-note block of a
+// description, and whether it has one. The text is trimmed, and may be
+// NONE for changes not worth noting.
+func ReleaseNoteBlock(description string) (string, bool) {
+	match := releaseNoteBlock.FindStringSubmatch(description)
+	if match == nil {
+		return "", false
+	}
+	return strings.TrimSpace(match[1]), true
+}
+
+// FindTicket returns the ticket and the description of the first line of a
+// text matching the ticket pattern, e.g. "OCPBUGS-1234: Fix the loop"
+func FindTicket(pattern *regexp.Regexp, text string) (string, string, bool) {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if !pattern.MatchString(line) {
+			continue
+		}
+		ticket, description, ok := strings.Cut(line, ":")
+		if !ok {
+			return "", "", false
+		}
+		return strings.TrimSpace(ticket), strings.TrimSpace(description), true
+	}
+	return "", "", false
+}
+
+// ProposedChange is a change checked against the conventions: a pull request,
+// or a commit outside of one
+type ProposedChange struct {
+	// Name identifies the change, e.g. #123 or a short commit hash
+	Name string
+	// Title is the title of the pull request, or the subject of the commit
+	Title string
+	// Text is the title and description of the pull request, or the
+	// message of the commit
+	Text string
+	// Labels are the labels of the pull request; nil when unknown, e.g. for
+	// commits or local clones
+	Labels []string
+	// PullRequest is false for commits outside of a pull request
+	PullRequest bool
+}
+
+// CheckResult is the outcome of a check of a change
+type CheckResult struct {
+	Check string
+	OK    bool
+	// Detail is what was found, or what is missing
+	Detail string
+}
+
+// ChangelogVerifier checks changes against the conventions of the notes
+type ChangelogVerifier struct {
+	// Checks are the checks every change must pass, see ChangelogChecks
+	Checks []string
+	// TicketPattern matches the ticket line
+	TicketPattern *regexp.Regexp
+	// Label is the label required by CheckLabel
+	Label string
+	// SkipLabels exempt the pull requests with one of them from the checks,
+	// e.g. no-changelog
+	SkipLabels []string
+}
+
+// CheckChecks returns an error naming the first unknown check
+func CheckChecks(checks []string) error {
+	for _, check := range checks {
+		if !contains(ChangelogChecks, check) {
+			return fmt.Errorf("unknown check %q (available: %s)", check, strings.Join(ChangelogChecks, ", "))
+		}
+	}
+	return nil
+}
+
+// Verify runs the checks on a change. The skipped label is returned when
+// the change is exempt, with no results.
+func (v *ChangelogVerifier) Verify(change ProposedChange) (string, []CheckResult) {
+	for _, label := range v.SkipLabels {
+		if contains(change.Labels, label) {
+			return label, nil
+		}
+	}
+
+	results := make([]CheckResult, 0, len(v.Checks))
+	for _, check := range v.Checks {
+		result := CheckResult{Check: check}
+		switch check {
+		case CheckTicket:
+			if ticket, _, ok := FindTicket(v.TicketPattern, change.Text); ok {
+				result.OK, result.Detail = true, ticket
+			} else {
+				result.Detail = "no \"TICKET-123: description\" line"
+			}
+		case CheckLabel:
+			switch {
+			case !change.PullRequest:
+				result.Detail = "not a pull request"
+			case contains(change.Labels, v.Label):
+				result.OK, result.Detail = true, v.Label
+			default:
+				result.Detail = "no " + v.Label + " label"
+			}
+		case CheckReleaseNote:
+			note, ok := ReleaseNoteBlock(change.Text)
+			switch {
+			case !ok:
+				result.Detail = "no ```release-note block"
+			case note == "":
+				result.Detail = "empty ```release-note block"
+			default:
+				result.OK, result.Detail = true, firstLine(note)
+			}
+		}
+		results = append(results, result)
+	}
+	return "", results
+}
+
+// firstLine returns the first line of a text, shortened to 50 characters
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(text, "\n")
+	if runes := []rune(line); len(runes) > 50 {
+		line = string(runes[:49]) + "…"
+	}
+	return line
+}
@@ -0,0 +1,51 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Fetch fetches references from a remote of the clone, e.g. release-4.15 or
+// pull/123/head; branches also update their remote-tracking branch
+func (a *LocalAnalyzer) Fetch(ctx context.Context, remote string, refspecs ...string) error {
+	if _, err := a.git(ctx, append([]string{"fetch", "--end-of-options", remote}, refspecs...)...); err != nil {
+		return fmt.Errorf("failed to fetch from %s: %w", remote, err)
+	}
+	return nil
+}
+
+// CherryPick creates a branch from onto with the changes of commits, oldest
+// first, picked with git cherry-pick -x, and returns its head. The commits
+// are picked in a temporary worktree, so the checkout of the clone is left
+// alone. When a commit conflicts, the branch is not created and the error
+// names the conflicting files.
+func (a *LocalAnalyzer) CherryPick(ctx context.Context, branch, onto string, commits []string) (string, error) {
+	dir, err := os.MkdirTemp("", "drivio-cherry-pick-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	// worktree add wants to create the directory itself
+	if err := os.Remove(dir); err != nil {
+		return "", err
+	}
+	if _, err := a.git(ctx, "worktree", "add", "-b", branch, "--", dir, onto); err != nil {
+		return "", fmt.Errorf("failed to create branch %s from %s: %w", branch, onto, err)
+	}
+	worktree := &LocalAnalyzer{dir: dir}
+	defer a.git(context.Background(), "worktree", "remove", "--force", dir)
+
+	if _, err := worktree.git(ctx, append([]string{"cherry-pick", "-x", "--end-of-options"}, commits...)...); err != nil {
+		conflicts, _ := worktree.git(ctx, "diff", "--name-only", "--diff-filter=U")
+		worktree.git(context.Background(), "cherry-pick", "--abort")
+		a.git(context.Background(), "worktree", "remove", "--force", dir)
+		a.git(context.Background(), "branch", "-D", "--", branch)
+		if files := strings.Fields(conflicts); len(files) > 0 {
+			return "", fmt.Errorf("the commits conflict with %s in: %s", onto, strings.Join(files, ", "))
+		}
+		return "", err
+	}
+	return worktree.ResolveRef(ctx, "HEAD")
+}
@@ -0,0 +1,203 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Churn counts the changes of an author or directory between two references
+type Churn struct {
+	// Name is the author name, or the directory, "." for the root
+	Name      string `json:"name"`
+	Commits   int    `json:"commits"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+}
+
+// Lines returns the number of lines changed
+func (c Churn) Lines() int {
+	return c.Additions + c.Deletions
+}
+
+// Ownership is the churn between two references by author and by directory,
+// the most changed first. Merge commits are not counted, as their changes
+// are those of the merged commits.
+type Ownership struct {
+	Authors     []Churn `json:"authors"`
+	Directories []Churn `json:"directories"`
+}
+
+// FileChange is the number of lines changed in a file by a commit
+type FileChange struct {
+	Path      string
+	Additions int
+	Deletions int
+}
+
+// ownershipBuilder accumulates the churn of commits
+type ownershipBuilder struct {
+	depth       int
+	authors     map[string]*Churn
+	directories map[string]*Churn
+}
+
+func newOwnershipBuilder(depth int) *ownershipBuilder {
+	if depth < 1 {
+		depth = 1
+	}
+	return &ownershipBuilder{
+		depth:       depth,
+		authors:     make(map[string]*Churn),
+		directories: make(map[string]*Churn),
+	}
+}
+
+// add counts a commit of author changing files
+func (b *ownershipBuilder) add(author string, files []FileChange) {
+	authorChurn := churnOf(b.authors, author)
+	authorChurn.Commits++
+
+	touched := make(map[string]bool)
+	for _, file := range files {
+		authorChurn.Additions += file.Additions
+		authorChurn.Deletions += file.Deletions
+
+		dir := directoryOf(file.Path, b.depth)
+		dirChurn := churnOf(b.directories, dir)
+		dirChurn.Additions += file.Additions
+		dirChurn.Deletions += file.Deletions
+		if !touched[dir] {
+			touched[dir] = true
+			dirChurn.Commits++
+		}
+	}
+}
+
+func (b *ownershipBuilder) build() *Ownership {
+	return &Ownership{Authors: sortChurn(b.authors), Directories: sortChurn(b.directories)}
+}
+
+func churnOf(churns map[string]*Churn, name string) *Churn {
+	churn, ok := churns[name]
+	if !ok {
+		churn = &Churn{Name: name}
+		churns[name] = churn
+	}
+	return churn
+}
+
+// sortChurn returns the churns by lines changed, then commits, then name
+func sortChurn(churns map[string]*Churn) []Churn {
+	sorted := make([]Churn, 0, len(churns))
+	for _, churn := range churns {
+		sorted = append(sorted, *churn)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if a.Lines() != b.Lines() {
+			return a.Lines() > b.Lines()
+		}
+		if a.Commits != b.Commits {
+			return a.Commits > b.Commits
+		}
+		return a.Name < b.Name
+	})
+	return sorted
+}
+
+// directoryOf returns the directory of a file, truncated to depth levels,
+// e.g. pkg/git for pkg/git/churn.go at depth 2
+func directoryOf(file string, depth int) string {
+	dir := path.Dir(file)
+	if dir == "." {
+		return dir
+	}
+	parts := strings.Split(dir, "/")
+	if len(parts) > depth {
+		parts = parts[:depth]
+	}
+	return strings.Join(parts, "/")
+}
+
+// Ownership returns the churn between two references by author and by
+// directory, the directories being truncated to depth levels
+func (a *LocalAnalyzer) Ownership(ctx context.Context, fromRef, toRef string, depth int) (*Ownership, error) {
+	revisions, err := a.revisions(ctx, fromRef, toRef)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each commit starts with an RS and its author, followed by its
+	// "<added>\t<deleted>\t<path>" lines; renames are not detected, so paths
+	// are plain
+	out, err := a.git(ctx, append([]string{"log", "--no-merges", "--no-renames", "--numstat", "--format=%x1e%an"}, revisions...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	builder := newOwnershipBuilder(depth)
+	for _, record := range strings.Split(out, "\x1e") {
+		lines := strings.Split(strings.TrimSpace(record), "\n")
+		if lines[0] == "" {
+			continue
+		}
+		var files []FileChange
+		for _, line := range lines[1:] {
+			fields := strings.SplitN(line, "\t", 3)
+			if len(fields) != 3 {
+				continue
+			}
+			// Binary files have "-" instead of line counts
+			added, _ := strconv.Atoi(fields[0])
+			deleted, _ := strconv.Atoi(fields[1])
+			files = append(files, FileChange{Path: fields[2], Additions: added, Deletions: deleted})
+		}
+		builder.add(lines[0], files)
+	}
+	return builder.build(), nil
+}
+
+// Ownership returns the churn of the commits by author and by directory, the
+// directories being truncated to depth levels. The files of every commit
+// are looked up through the API, calling progress, when not nil, before
+// each one.
+func (a *Analyzer) Ownership(ctx context.Context, owner, repo string, commits []CommitInfo, depth int, progress func(done, total int)) (*Ownership, error) {
+	builder := newOwnershipBuilder(depth)
+	for i, commit := range commits {
+		if progress != nil {
+			progress(i, len(commits))
+		}
+
+		var details struct {
+			Parents []struct {
+				Sha string `json:"sha"`
+			} `json:"parents"`
+			Files []struct {
+				Filename  string `json:"filename"`
+				Additions int    `json:"additions"`
+				Deletions int    `json:"deletions"`
+			} `json:"files"`
+		}
+		endpoint := fmt.Sprintf("/repos/%s/%s/commits/%s", owner, repo, commit.Hash)
+		if err := a.client.GetJSON(ctx, endpoint, &details); err != nil {
+			return nil, fmt.Errorf("failed to get commit %s: %w", commit.ShortHash(), err)
+		}
+		if len(details.Parents) > 1 {
+			continue
+		}
+
+		files := make([]FileChange, 0, len(details.Files))
+		for _, file := range details.Files {
+			files = append(files, FileChange{Path: file.Filename, Additions: file.Additions, Deletions: file.Deletions})
+		}
+		builder.add(commit.Author, files)
+	}
+	if progress != nil {
+		progress(len(commits), len(commits))
+	}
+	return builder.build(), nil
+}
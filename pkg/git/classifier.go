@@ -0,0 +1,144 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// DefaultLabel is the label a pull request needs for its changes to be noted
+const DefaultLabel = "area/hypershift-operator"
+
+// DefaultTicketPattern matches the line of a commit message naming the
+// ticket of the change, e.g. "OCPBUGS-1234: Fix the reconciliation loop"
+var DefaultTicketPattern = regexp.MustCompile(`^[A-Z]+-\d+:\s.+`)
+
+// Classifier selects the commits worth noting: merges of pull requests with
+// the label whose message names a ticket
+type Classifier struct {
+	// Label is the label the pull request needs; empty accepts any pull
+	// request
+	Label string
+	// TicketPattern matches the "<TICKET>: <description>" line of the
+	// message
+	TicketPattern *regexp.Regexp
+	// Labels looks up the labels of a pull request; nil when they are not
+	// available, e.g. for a local clone
+	Labels func(ctx context.Context, pr int) ([]string, error)
+	// Rules give a type to the selected commits without a conventional
+	// one, the first matching rule winning
+	Rules []TypeRule
+	// Progress, when set, is called before each commit is classified, with
+	// the number of commits done so far
+	Progress func(done, total int, message string)
+}
+
+// NewClassifier creates a classifier with the default label and ticket
+// pattern, looking up labels in the repository through the analyzer
+func NewClassifier(analyzer *Analyzer, owner, repo string) *Classifier {
+	return &Classifier{
+		Label:         DefaultLabel,
+		TicketPattern: DefaultTicketPattern,
+		Labels: func(ctx context.Context, pr int) ([]string, error) {
+			return analyzer.PullRequestLabels(ctx, owner, repo, pr)
+		},
+	}
+}
+
+// Classify returns the selected commits with their labels, ticket and
+// description. Pull requests whose labels cannot be looked up are skipped.
+func (c *Classifier) Classify(ctx context.Context, commits []CommitInfo) []CommitInfo {
+	var selected []CommitInfo
+	for i, commit := range commits {
+		c.progress(i, len(commits), "Commit "+commit.ShortHash())
+
+		if commit.PR == 0 {
+			continue
+		}
+
+		if c.Label != "" {
+			c.progress(i, len(commits), fmt.Sprintf("Checking labels of PR #%d", commit.PR))
+			labels, err := c.labelsOf(ctx, commit)
+			if err != nil || !contains(labels, c.Label) {
+				continue
+			}
+			commit.Labels = labels
+		}
+
+		ticket, description, ok := c.ticket(commit.Body)
+		if !ok {
+			continue
+		}
+		commit.Ticket, commit.Description = ticket, description
+
+		// Label rules need the labels, even when any label is accepted
+		if commit.Labels == nil && commit.Type == "" && c.Labels != nil && needsLabels(c.Rules) {
+			c.progress(i, len(commits), fmt.Sprintf("Checking labels of PR #%d", commit.PR))
+			commit.Labels, _ = c.labelsOf(ctx, commit)
+		}
+		applyRules(c.Rules, &commit)
+		selected = append(selected, commit)
+	}
+
+	c.progress(len(commits), len(commits), "")
+	return selected
+}
+
+// ticket finds the ticket line in the body of a commit message
+func (c *Classifier) ticket(body string) (string, string, bool) {
+	return FindTicket(c.TicketPattern, body)
+}
+
+func (c *Classifier) progress(done, total int, message string) {
+	if c.Progress != nil {
+		c.Progress(done, total, message)
+	}
+}
+
+// PullRequestNumber extracts the pull request number from the subject of a
+// merge commit, e.g. "Merge pull request #123 from owner/branch"
+func PullRequestNumber(subject string) (int, bool) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(subject), "Merge pull request #")
+	if !ok {
+		return 0, false
+	}
+	number, _, _ := strings.Cut(rest, " ")
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// MergeRequestNumber extracts the merge request number from the message of a
+// GitLab merge commit, whose last line is e.g. "See merge request
+// group/project!123"
+func MergeRequestNumber(message string) (int, bool) {
+	for _, line := range strings.Split(message, "\n") {
+		rest, ok := strings.CutPrefix(strings.TrimSpace(line), "See merge request ")
+		if !ok {
+			continue
+		}
+		_, number, ok := strings.Cut(rest, "!")
+		if !ok {
+			return 0, false
+		}
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return 0, false
+		}
+		return n, true
+	}
+	return 0, false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,201 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// CloneOptions selects what CloneWithProgress fetches and how. The zero
+// value clones the full history of every branch and checks out the default
+// branch.
+type CloneOptions struct {
+	// Ref is the branch or tag to check out; empty for the default branch
+	Ref string
+	// Depth truncates the history to the given number of commits; 0 clones
+	// the full history
+	Depth int
+	// SingleBranch only fetches the history of the branch or tag checked
+	// out
+	SingleBranch bool
+	// RecurseSubmodules also clones the submodules, as shallow as the
+	// repository
+	RecurseSubmodules bool
+	// Bare clones the repository without a work tree
+	Bare bool
+	// Mirror makes a bare clone mapping every ref of the remote, e.g. to
+	// keep a local mirror up to date with git remote update
+	Mirror bool
+	// Filter is a partial clone filter, e.g. blob:none or blob:limit=1m;
+	// the filtered objects are fetched by git when first needed
+	Filter string
+	// Username and Token authenticate HTTPS remotes. They are passed to git
+	// through its environment, so they never show in the process list or
+	// the configuration of the clone, and only sent to the remote cloned,
+	// not to the hosts of its submodules.
+	Username string
+	Token    string
+}
+
+// CloneProgress reports the progress of a phase of the clone, e.g.
+// "Receiving objects" 120 out of 450
+type CloneProgress struct {
+	Phase   string
+	Current int64
+	Total   int64
+}
+
+// progressPattern matches the progress lines git prints on stderr, e.g.
+// "remote: Counting objects:  45% (123/456)" or "Receiving objects: 12% (5/40), 1.2 MiB"
+var progressPattern = regexp.MustCompile(`^(?:remote: )?([A-Za-z ]+):\s+\d+% \((\d+)/(\d+)\)`)
+
+// CloneWithProgress clones the repository at url into dir with the git
+// command, calling progress, when not nil, as git reports it
+func CloneWithProgress(ctx context.Context, url, dir string, opts CloneOptions, progress func(CloneProgress)) error {
+	if _, err := exec.LookPath("git"); err != nil {
+		return fmt.Errorf("git is required to clone repositories: %w", err)
+	}
+	if opts.Depth < 0 {
+		return fmt.Errorf("invalid depth %d", opts.Depth)
+	}
+	if (opts.Bare || opts.Mirror) && opts.RecurseSubmodules {
+		return fmt.Errorf("submodules cannot be cloned without a work tree (bare or mirror clone)")
+	}
+	if opts.Mirror && opts.Ref != "" {
+		return fmt.Errorf("a mirror clone maps every ref; no ref can be selected")
+	}
+
+	args := []string{"clone", "--progress"}
+	if opts.Ref != "" {
+		args = append(args, "--branch", opts.Ref)
+	}
+	if opts.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(opts.Depth))
+	}
+	if opts.SingleBranch {
+		args = append(args, "--single-branch")
+	}
+	if opts.RecurseSubmodules {
+		args = append(args, "--recurse-submodules")
+		if opts.Depth > 0 {
+			args = append(args, "--shallow-submodules")
+		}
+	}
+	switch {
+	case opts.Mirror:
+		args = append(args, "--mirror")
+	case opts.Bare:
+		args = append(args, "--bare")
+	}
+	if opts.Filter != "" {
+		args = append(args, "--filter="+opts.Filter)
+	}
+	args = append(args, "--", url, dir)
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = gitEnv(opts, url)
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to run git clone: %w", err)
+	}
+
+	// Progress lines are rewritten in place with \r; keep the other lines,
+	// which explain failures
+	var messages []string
+	scanner := bufio.NewScanner(stderr)
+	scanner.Split(scanLinesOrReturns)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if m := progressPattern.FindStringSubmatch(line); m != nil {
+			if progress != nil {
+				current, _ := strconv.ParseInt(m[2], 10, 64)
+				total, _ := strconv.ParseInt(m[3], 10, 64)
+				progress(CloneProgress{Phase: strings.TrimSpace(m[1]), Current: current, Total: total})
+			}
+			continue
+		}
+		if line != "" && !strings.HasPrefix(line, "Cloning into") {
+			messages = append(messages, line)
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		if len(messages) > 0 {
+			return fmt.Errorf("git clone failed: %s", strings.Join(messages, "; "))
+		}
+		return fmt.Errorf("git clone failed: %w", err)
+	}
+	return nil
+}
+
+// UpdateMirror fetches the refs of a mirror clone made by CloneWithProgress
+// from its remote, removing those deleted there. Only the credentials of the
+// options are used.
+func UpdateMirror(ctx context.Context, dir string, opts CloneOptions) error {
+	var remote string
+	if opts.Token != "" {
+		output, err := exec.CommandContext(ctx, "git", "-C", dir, "config", "--get", "remote.origin.url").Output()
+		if err != nil {
+			return fmt.Errorf("failed to read the remote of mirror %s: %w", dir, err)
+		}
+		remote = strings.TrimSpace(string(output))
+	}
+	cmd := exec.CommandContext(ctx, "git", "-C", dir, "remote", "update", "--prune")
+	cmd.Env = gitEnv(opts, remote)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		if message := strings.TrimSpace(string(output)); message != "" {
+			return fmt.Errorf("git remote update failed: %s", message)
+		}
+		return fmt.Errorf("git remote update failed: %w", err)
+	}
+	return nil
+}
+
+// gitEnv returns the environment of git commands reaching the remote at url
+// with the credentials of the options, without prompting for others. The
+// credentials are an http.<url>.extraHeader setting, only sent to the URLs
+// under the remote, added to the settings of the environment, if any.
+func gitEnv(opts CloneOptions, url string) []string {
+	env := append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if opts.Token == "" {
+		return env
+	}
+
+	count := 0
+	if value := os.Getenv("GIT_CONFIG_COUNT"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err == nil && n > 0 {
+			count = n
+		}
+	}
+	env = slices.DeleteFunc(env, func(v string) bool { return strings.HasPrefix(v, "GIT_CONFIG_COUNT=") })
+	credentials := base64.StdEncoding.EncodeToString([]byte(opts.Username + ":" + opts.Token))
+	return append(env,
+		fmt.Sprintf("GIT_CONFIG_COUNT=%d", count+1),
+		fmt.Sprintf("GIT_CONFIG_KEY_%d=http.%s.extraHeader", count, url),
+		fmt.Sprintf("GIT_CONFIG_VALUE_%d=Authorization: Basic %s", count, credentials),
+	)
+}
+
+// scanLinesOrReturns is a bufio.SplitFunc splitting on \n and \r
+func scanLinesOrReturns(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
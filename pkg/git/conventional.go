@@ -0,0 +1,128 @@
+package git
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ConventionalCommit is a commit message following the Conventional Commits
+// specification (https://www.conventionalcommits.org), e.g.
+//
+//	feat(api)!: drop the v1 endpoints
+//
+//	The v1 endpoints were deprecated in 1.4.
+//
+//	BREAKING CHANGE: clients must use /v2
+//	Refs: #123
+type ConventionalCommit struct {
+	// Type is the lowercased type, e.g. feat or fix; empty when the header
+	// does not follow the specification
+	Type  string
+	Scope string
+	// Subject is the description of the header, or the whole header when it
+	// does not follow the specification
+	Subject string
+	Body    string
+	Footers []Footer
+	// Breaking is set by a ! before the colon of the header or by a
+	// BREAKING CHANGE footer
+	Breaking bool
+}
+
+// Footer is a trailer of a commit message, e.g. "Refs: #123"
+type Footer struct {
+	Token string `json:"token"`
+	Value string `json:"value"`
+}
+
+// breakingTokens are the footer tokens announcing a breaking change
+var breakingTokens = []string{"BREAKING CHANGE", "BREAKING-CHANGE"}
+
+// The type is a word, possibly hyphenated, so ticket lines like
+// "OCPBUGS-123: Fix" have no type
+var (
+	headerPattern = regexp.MustCompile(`^([A-Za-z]+(?:-[A-Za-z]+)*)(?:\(([^()]*)\))?(!)?: (.+)$`)
+	footerPattern = regexp.MustCompile(`^(BREAKING CHANGE|BREAKING-CHANGE|[\w-]+)(?:: | #)(.*)$`)
+)
+
+// ParseConventionalCommit parses a commit message. Messages that don't
+// follow the specification yield a commit without type, whose subject is
+// the first line.
+func ParseConventionalCommit(message string) ConventionalCommit {
+	header, rest, _ := strings.Cut(strings.TrimSpace(strings.ReplaceAll(message, "\r\n", "\n")), "\n")
+	header = strings.TrimSpace(header)
+
+	commit := ConventionalCommit{Subject: header}
+	if m := headerPattern.FindStringSubmatch(header); m != nil {
+		commit.Type = strings.ToLower(m[1])
+		commit.Scope = strings.TrimSpace(m[2])
+		commit.Breaking = m[3] == "!"
+		commit.Subject = strings.TrimSpace(m[4])
+	}
+
+	paragraphs := splitParagraphs(rest)
+	if n := len(paragraphs); n > 0 {
+		if footers, ok := parseFooters(paragraphs[n-1]); ok {
+			commit.Footers = footers
+			paragraphs = paragraphs[:n-1]
+		}
+	}
+	commit.Body = strings.Join(paragraphs, "\n\n")
+
+	for _, footer := range commit.Footers {
+		for _, token := range breakingTokens {
+			commit.Breaking = commit.Breaking || footer.Token == token
+		}
+	}
+	return commit
+}
+
+// Footer returns the value of the first footer with the given token, compared
+// case-insensitively
+func (c ConventionalCommit) Footer(token string) (string, bool) {
+	for _, footer := range c.Footers {
+		if strings.EqualFold(footer.Token, token) {
+			return footer.Value, true
+		}
+	}
+	return "", false
+}
+
+// parseFooters parses the last paragraph of a message as footers. Lines not
+// starting with a token continue the value of the previous footer.
+func parseFooters(paragraph string) ([]Footer, bool) {
+	var footers []Footer
+	for _, line := range strings.Split(paragraph, "\n") {
+		if m := footerPattern.FindStringSubmatch(line); m != nil {
+			footers = append(footers, Footer{Token: m[1], Value: strings.TrimSpace(m[2])})
+			continue
+		}
+		if len(footers) == 0 {
+			return nil, false
+		}
+		last := &footers[len(footers)-1]
+		last.Value = strings.TrimSpace(last.Value + "\n" + line)
+	}
+	return footers, len(footers) > 0
+}
+
+// splitParagraphs splits text on blank lines, dropping the empty paragraphs
+func splitParagraphs(text string) []string {
+	var paragraphs []string
+	var current []string
+	flush := func() {
+		if len(current) > 0 {
+			paragraphs = append(paragraphs, strings.Join(current, "\n"))
+			current = nil
+		}
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		current = append(current, strings.TrimRight(line, " \t"))
+	}
+	flush()
+	return paragraphs
+}
@@ -0,0 +1,128 @@
+package git
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// OutputFormat represents the output format for release notes
+type OutputFormat string
+
+const (
+	// FormatMarkdown renders one line per change with links to the commit
+	// and the ticket
+	FormatMarkdown OutputFormat = "markdown"
+	// FormatMarkdownTable renders the changes as a Markdown table
+	FormatMarkdownTable OutputFormat = "table"
+	FormatJSON          OutputFormat = "json"
+	// FormatText renders one "<hash> <ticket>: <description>" line per change
+	FormatText OutputFormat = "text"
+)
+
+// DefaultTicketURL is the address of the tickets, followed by their key
+const DefaultTicketURL = "https://issues.redhat.com/browse/"
+
+// Formatter represents a release notes formatter
+type Formatter struct {
+	format OutputFormat
+	// WebURL is the address of the GitHub web interface, for commit links
+	WebURL string
+	// GitLab renders the links of the GitLab web interface at WebURL, e.g.
+	// to merge requests, instead of GitHub's
+	GitLab bool
+	// TicketURL is the address of the tickets, followed by their key
+	TicketURL string
+	// Compact renders JSON on a single line instead of indented
+	Compact bool
+	// Sections groups the changes of Markdown and text output by type, under
+	// a heading per type (see SectionTitle)
+	Sections bool
+	// Summary appends the counts of the changes by type, scope, author and
+	// label to Markdown and text output
+	Summary bool
+	// MaxPerSection folds the sections of Markdown and text output after
+	// this many changes, mentioning how many more there are with a link to
+	// the full comparison; 0 renders every change
+	MaxPerSection int
+
+	// templates are the built-in templates, with the custom ones parsed
+	// over them; entry is the custom template to execute
+	templates *template.Template
+	entry     string
+	err       error
+}
+
+// NewFormatter creates a new formatter with the specified format. Markdown,
+// table and text output are rendered with the built-in templates unless an
+// option gives custom ones (see WithTemplateFiles).
+func NewFormatter(format OutputFormat, opts ...FormatterOption) *Formatter {
+	f := &Formatter{
+		format:    format,
+		WebURL:    "https://github.com",
+		TicketURL: DefaultTicketURL,
+		templates: builtinTemplates,
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// Format formats release notes according to the specified format
+func (f *Formatter) Format(notes *ReleaseNotes) (string, error) {
+	var sb strings.Builder
+	if err := f.FormatTo(&sb, notes); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// FormatTo writes the formatted release notes to w as they are rendered,
+// without holding the output in memory. See Stream to also avoid holding the
+// changes.
+func (f *Formatter) FormatTo(w io.Writer, notes *ReleaseNotes) error {
+	switch f.format {
+	case FormatMarkdown, FormatMarkdownTable:
+		return f.execute(w, "markdown", notes)
+	case FormatJSON:
+		return f.formatJSON(w, notes)
+	case FormatText:
+		return f.execute(w, "text", notes)
+	default:
+		return fmt.Errorf("unsupported format: %s", f.format)
+	}
+}
+
+// formatJSON formats release notes as JSON. Fields keep the order of the
+// types and maps are sorted by key, so the same notes always render the
+// same. Characters like <, > and & are kept as is, e.g. in author emails.
+func (f *Formatter) formatJSON(w io.Writer, notes *ReleaseNotes) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	if !f.Compact {
+		encoder.SetIndent("", "  ")
+	}
+
+	// Empty lists are rendered as [] rather than null
+	if notes.Commits == nil {
+		copied := *notes
+		copied.Commits = []CommitInfo{}
+		notes = &copied
+	}
+	if err := encoder.Encode(notes); err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	return nil
+}
+
+// unverifiedReason returns why the signature of a commit failed
+// verification; false when it is verified or signatures were not verified
+func unverifiedReason(commit CommitInfo) (string, bool) {
+	if commit.Verification == nil || commit.Verification.Verified {
+		return "", false
+	}
+	return commit.Verification.Reason, true
+}
@@ -0,0 +1,189 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// LocalAnalyzer lists the commits of a local clone with the git command, so
+// release notes can be generated offline or for mirrors not hosted on
+// GitHub. Bare and mirror clones work as well as clones with a work tree.
+//
+// The commits are walked with git log and git merge-base rather than with
+// go-git: drivio does not depend on go-git, its mirrors are already cloned
+// with the git command, and the signatures are checked with the keys
+// configured for git, which go-git does not support for SSH signatures.
+type LocalAnalyzer struct {
+	dir string
+	// VerifySignatures checks the GPG or SSH signatures of the commits and
+	// of the references that are tags, with the keys known to git (gpg
+	// keyring, gpg.ssh.allowedSignersFile)
+	VerifySignatures bool
+	// Range selects the commits listed when the references have diverged;
+	// empty is RangeThreeDot
+	Range RangeMode
+	// Since and Until only keep the commits committed in this period, when
+	// not zero, e.g. for the changes of a sprint; fromRef can then be empty
+	// to walk the whole history of toRef
+	Since time.Time
+	Until time.Time
+}
+
+// NewLocalAnalyzer creates an analyzer of the clone in dir
+func NewLocalAnalyzer(dir string) (*LocalAnalyzer, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("git is required to analyze local clones: %w", err)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	a := &LocalAnalyzer{dir: dir}
+	if _, err := a.git(context.Background(), "rev-parse", "--git-dir"); err != nil {
+		return nil, fmt.Errorf("%s is not a git repository", dir)
+	}
+	return a, nil
+}
+
+// GenerateReleaseNotes lists the commits between two references and keeps
+// those selected by the classifier. Owner and repo only name the
+// repository in the notes, e.g. for commit links.
+func (a *LocalAnalyzer) GenerateReleaseNotes(ctx context.Context, owner, repo, fromRef, toRef string, classifier *Classifier) (*ReleaseNotes, error) {
+	commits, err := a.Commits(ctx, fromRef, toRef)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get commits between references: %w", err)
+	}
+
+	notes := NewReleaseNotes(owner, repo, fromRef, toRef, commits, classifier.Classify(ctx, commits))
+	notes.SetPeriod(a.Since, a.Until)
+	if fromRef != "" {
+		if mergeBase, diverged, err := a.Diverged(ctx, fromRef, toRef); err != nil {
+			return nil, err
+		} else if diverged {
+			notes.SetMergeBase(mergeBase, a.Range)
+		}
+	}
+	if a.VerifySignatures {
+		notes.Tags, err = VerifyTags(func(ref string) (*Verification, error) {
+			return a.VerifyTag(ctx, ref)
+		}, fromRef, toRef)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return notes, nil
+}
+
+// Commits returns the commits reachable from toRef but not from fromRef,
+// oldest first: like the GitHub compare API, those since the merge base of
+// the references, including the commits of merged branches. With
+// RangeTwoDot, those reachable from fromRef but not from toRef as well.
+// Since and Until narrow them down by committer date.
+func (a *LocalAnalyzer) Commits(ctx context.Context, fromRef, toRef string) ([]CommitInfo, error) {
+	revisions, err := a.revisions(ctx, fromRef, toRef)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fields are separated by NUL and commits by RS, which don't appear in
+	// commit messages. Checking signatures runs gpg or ssh-keygen for every
+	// commit, so it is only done when asked.
+	fields := 5
+	format := "%H%x00%an%x00%ae%x00%aI%x00"
+	if a.VerifySignatures {
+		fields = 7
+		format += "%G?%x00%GS%x00"
+	}
+	out, err := a.git(ctx, append([]string{"log", "--reverse", "--date-order", "--format=" + format + "%B%x1e"}, revisions...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []CommitInfo
+	for _, record := range strings.Split(out, "\x1e") {
+		record = strings.TrimLeft(record, "\n")
+		if record == "" {
+			continue
+		}
+		values := strings.SplitN(record, "\x00", fields)
+		if len(values) != fields {
+			return nil, fmt.Errorf("unexpected git log output: %q", record)
+		}
+		date, err := time.Parse(time.RFC3339, values[3])
+		if err != nil {
+			return nil, fmt.Errorf("invalid date of commit %s: %w", values[0], err)
+		}
+		info := newCommitInfo(values[0], values[1], values[2], date, values[fields-1])
+		if a.VerifySignatures {
+			info.Verification = localVerification(values[4], values[5])
+		}
+		commits = append(commits, info)
+	}
+	return commits, nil
+}
+
+// revisions returns the git log arguments selecting the commits between two
+// references, fromRef being optional when a period is set
+func (a *LocalAnalyzer) revisions(ctx context.Context, fromRef, toRef string) ([]string, error) {
+	var args []string
+	if !a.Since.IsZero() {
+		args = append(args, "--since="+a.Since.Format(time.RFC3339))
+	}
+	if !a.Until.IsZero() {
+		args = append(args, "--until="+a.Until.Format(time.RFC3339))
+	}
+	if fromRef == "" && len(args) == 0 {
+		return nil, fmt.Errorf("a from reference or a period is required")
+	}
+
+	to, err := a.ResolveRef(ctx, toRef)
+	if err != nil {
+		return nil, err
+	}
+	if fromRef == "" {
+		return append(args, to), nil
+	}
+	from, err := a.ResolveRef(ctx, fromRef)
+	if err != nil {
+		return nil, err
+	}
+	return append(args, a.Range.revisionRange(from, to)), nil
+}
+
+// MergeBase returns the hash of the best common ancestor of two references
+func (a *LocalAnalyzer) MergeBase(ctx context.Context, ref1, ref2 string) (string, error) {
+	out, err := a.git(ctx, "merge-base", ref1, ref2)
+	if err != nil {
+		return "", fmt.Errorf("no merge base between %s and %s: %w", ref1, ref2, err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// ResolveRef returns the hash of the commit a branch, tag or commit refers to
+func (a *LocalAnalyzer) ResolveRef(ctx context.Context, ref string) (string, error) {
+	out, err := a.git(ctx, "rev-parse", "--verify", "--quiet", "--end-of-options", ref+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("unknown reference %s in %s", ref, a.dir)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// git runs a git command in the clone and returns its output
+func (a *LocalAnalyzer) git(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", a.dir}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("git %s failed: %s", args[0], message)
+		}
+		return "", fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+	return string(out), nil
+}
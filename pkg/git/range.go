@@ -0,0 +1,89 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+)
+
+// RangeMode selects the commits listed between two references when they
+// have diverged, e.g. a release branch and main. Both modes list the same
+// commits when the first reference is an ancestor of the second.
+type RangeMode string
+
+const (
+	// RangeThreeDot lists the commits of the second reference since its merge
+	// base with the first, like the three-dot compare of GitHub and git log
+	// from..to: the changes merged into the second reference. The default.
+	RangeThreeDot RangeMode = "three-dot"
+	// RangeTwoDot compares the tips of the references, like the two-dot
+	// compare of GitHub and git log from...to: the commits of the first
+	// reference since the merge base are listed as well.
+	RangeTwoDot RangeMode = "two-dot"
+)
+
+// ParseRangeMode parses a range mode; empty is RangeThreeDot
+func ParseRangeMode(mode string) (RangeMode, error) {
+	switch RangeMode(mode) {
+	case "", RangeThreeDot:
+		return RangeThreeDot, nil
+	case RangeTwoDot:
+		return RangeTwoDot, nil
+	default:
+		return "", fmt.Errorf("invalid range %q: use two-dot or three-dot", mode)
+	}
+}
+
+// revisionRange returns the git revision range of the commits between two
+// commits
+func (m RangeMode) revisionRange(from, to string) string {
+	if m == RangeTwoDot {
+		return from + "..." + to
+	}
+	return from + ".." + to
+}
+
+// Diverged returns the merge base of two references when they have
+// diverged, i.e. fromRef is not an ancestor of toRef
+func (a *LocalAnalyzer) Diverged(ctx context.Context, fromRef, toRef string) (string, bool, error) {
+	from, err := a.ResolveRef(ctx, fromRef)
+	if err != nil {
+		return "", false, err
+	}
+	mergeBase, err := a.MergeBase(ctx, fromRef, toRef)
+	if err != nil {
+		return "", false, err
+	}
+	return mergeBase, mergeBase != from, nil
+}
+
+// githubComparison is the part of a comparison of the GitHub compare API
+// telling how the references relate
+type githubComparison struct {
+	// Status is ahead, behind, identical or diverged
+	Status          string `json:"status"`
+	MergeBaseCommit struct {
+		Sha string `json:"sha"`
+	} `json:"merge_base_commit"`
+}
+
+// Diverged returns the merge base of two references when they have
+// diverged, i.e. fromRef is not an ancestor of toRef
+func (a *Analyzer) Diverged(ctx context.Context, owner, repo, fromRef, toRef string) (string, bool, error) {
+	var comparison githubComparison
+	endpoint := fmt.Sprintf("/repos/%s/%s/compare/%s...%s?per_page=1", owner, repo,
+		url.PathEscape(fromRef), url.PathEscape(toRef))
+	if err := a.client.GetJSON(ctx, endpoint, &comparison); err != nil {
+		return "", false, fmt.Errorf("failed to compare %s and %s: %w", fromRef, toRef, err)
+	}
+	return comparison.MergeBaseCommit.Sha, comparison.Status == "diverged" || comparison.Status == "behind", nil
+}
+
+// sortByDate sorts commits listed from both sides of a two-dot range,
+// oldest first
+func sortByDate(commits []CommitInfo) {
+	sort.SliceStable(commits, func(i, j int) bool {
+		return commits[i].Date.Before(commits[j].Date)
+	})
+}
@@ -0,0 +1,106 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TypeRule gives a type to the commits it matches, for repositories that
+// don't follow Conventional Commits, e.g. "BUGFIX: ..." or "[feat] ...". A
+// rule matches when all its conditions that are set match.
+type TypeRule struct {
+	// Type is the type given, e.g. fix
+	Type string
+	// Subject matches the title of the change: the subject of the commit,
+	// or the title of the pull request for merges
+	Subject *regexp.Regexp
+	// Body matches the message after the title
+	Body *regexp.Regexp
+	// Label is a label the pull request must have, compared
+	// case-insensitively
+	Label string
+}
+
+// NewTypeRule creates a rule from regular expressions; empty conditions are
+// not checked
+func NewTypeRule(commitType, subject, body, label string) (TypeRule, error) {
+	rule := TypeRule{Type: strings.ToLower(strings.TrimSpace(commitType)), Label: label}
+	if rule.Type == "" {
+		return rule, fmt.Errorf("rule without type")
+	}
+	if subject == "" && body == "" && label == "" {
+		return rule, fmt.Errorf("rule for type %s has no subject, body or label", rule.Type)
+	}
+
+	var err error
+	if subject != "" {
+		if rule.Subject, err = regexp.Compile(subject); err != nil {
+			return rule, fmt.Errorf("invalid subject of rule for type %s: %w", rule.Type, err)
+		}
+	}
+	if body != "" {
+		if rule.Body, err = regexp.Compile(body); err != nil {
+			return rule, fmt.Errorf("invalid body of rule for type %s: %w", rule.Type, err)
+		}
+	}
+	return rule, nil
+}
+
+// Matches reports whether the rule matches the commit
+func (r TypeRule) Matches(commit CommitInfo) bool {
+	title, body := commit.Subject, commit.Body
+	if commit.PR != 0 {
+		title, body, _ = strings.Cut(commit.Body, "\n")
+	}
+
+	if r.Subject != nil && !r.Subject.MatchString(strings.TrimSpace(title)) {
+		return false
+	}
+	if r.Body != nil && !r.Body.MatchString(strings.TrimSpace(body)) {
+		return false
+	}
+	if r.Label != "" {
+		found := false
+		for _, label := range commit.Labels {
+			found = found || strings.EqualFold(label, r.Label)
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// needsLabels reports whether any of the rules checks labels
+func needsLabels(rules []TypeRule) bool {
+	for _, rule := range rules {
+		if rule.Label != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// applyRules gives the commit the type of the first matching rule, unless
+// it has a conventional type
+func applyRules(rules []TypeRule, commit *CommitInfo) {
+	if commit.Type != "" {
+		return
+	}
+	for _, rule := range rules {
+		if rule.Matches(*commit) {
+			commit.Type = rule.Type
+			return
+		}
+	}
+}
+
+// labelsOf looks up the labels of the pull request of a commit
+func (c *Classifier) labelsOf(ctx context.Context, commit CommitInfo) ([]string, error) {
+	if c.Labels == nil {
+		return nil, fmt.Errorf("labels of pull requests are not available")
+	}
+	return c.Labels(ctx, commit.PR)
+}
@@ -0,0 +1,107 @@
+package git
+
+import (
+	"sort"
+	"strings"
+)
+
+// Section is a group of changes of the release notes
+type Section struct {
+	// Title is the heading of the section, empty when the changes are not
+	// grouped
+	Title string
+	// Type is the commit type of the changes
+	Type    string
+	Commits []CommitInfo
+	// More is the number of changes left out of Commits by the limit of the
+	// formatter, which the templates mention after them
+	More int
+}
+
+// sectionTypes are the commit types with a section of their own, in the
+// order of the sections; other types follow alphabetically, and the changes
+// without type come last
+var sectionTypes = []string{"feat", "fix", "perf", "refactor", "revert", "docs", "style", "test", "build", "ci", "chore"}
+
+// sectionTitles are the headings of the well-known commit types
+var sectionTitles = map[string]string{
+	"feat":     "Features",
+	"fix":      "Bug Fixes",
+	"perf":     "Performance Improvements",
+	"refactor": "Code Refactoring",
+	"revert":   "Reverts",
+	"docs":     "Documentation",
+	"style":    "Styles",
+	"test":     "Tests",
+	"build":    "Build System",
+	"ci":       "Continuous Integration",
+	"chore":    "Chores",
+	"":         "Other Changes",
+}
+
+// SectionTitle returns the heading of the section of a commit type, e.g.
+// "Bug Fixes" for fix, or the capitalized type for unknown types
+func SectionTitle(commitType string) string {
+	if title, ok := sectionTitles[commitType]; ok {
+		return title
+	}
+	return strings.ToUpper(commitType[:1]) + commitType[1:]
+}
+
+// GroupByType groups commits by type in section order, keeping the order of
+// the commits within a section
+func GroupByType(commits []CommitInfo) []Section {
+	byType := make(map[string][]CommitInfo)
+	for _, commit := range commits {
+		byType[commit.Type] = append(byType[commit.Type], commit)
+	}
+
+	rank := make(map[string]int, len(sectionTypes))
+	for i, t := range sectionTypes {
+		rank[t] = i
+	}
+	types := make([]string, 0, len(byType))
+	for t := range byType {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		a, b := types[i], types[j]
+		ra, knownA := rank[a]
+		rb, knownB := rank[b]
+		switch {
+		case a == "" || b == "":
+			return b == ""
+		case knownA && knownB:
+			return ra < rb
+		case knownA != knownB:
+			return knownA
+		default:
+			return a < b
+		}
+	})
+
+	sections := make([]Section, 0, len(types))
+	for _, t := range types {
+		sections = append(sections, Section{Title: SectionTitle(t), Type: t, Commits: byType[t]})
+	}
+	return sections
+}
+
+// sections returns the sections rendered by the formatter: one per type
+// with Sections, otherwise a single untitled one, each folded after
+// MaxPerSection changes
+func (f *Formatter) sections(commits []CommitInfo) []Section {
+	sections := []Section{{Commits: commits}}
+	if f.Sections {
+		sections = GroupByType(commits)
+	}
+	if f.MaxPerSection > 0 {
+		for i := range sections {
+			if n := len(sections[i].Commits); n > f.MaxPerSection {
+				sections[i].More = n - f.MaxPerSection
+				sections[i].Commits = sections[i].Commits[:f.MaxPerSection]
+			}
+		}
+	}
+	return sections
+}
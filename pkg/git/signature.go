@@ -0,0 +1,147 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"drivio/pkg/github"
+)
+
+// Verification is the result of checking the GPG or SSH signature of a
+// commit or tag
+type Verification struct {
+	Verified bool `json:"verified"`
+	// Reason explains the result with the reasons of the GitHub API, e.g.
+	// valid, unsigned, unknown_key or bad_signature
+	Reason string `json:"reason"`
+	// Signer identifies the key or its owner, when known
+	Signer string `json:"signer,omitempty"`
+}
+
+// signatureStatuses maps the %G? status of git log to a verification. Good
+// signatures whose key is not trusted (U) are verified, like git
+// verify-commit does.
+var signatureStatuses = map[string]Verification{
+	"G": {Verified: true, Reason: "valid"},
+	"U": {Verified: true, Reason: "unknown_validity"},
+	"B": {Reason: "bad_signature"},
+	"X": {Reason: "expired_signature"},
+	"Y": {Reason: "expired_key"},
+	"R": {Reason: "revoked_key"},
+	"E": {Reason: "unknown_key"},
+	"N": {Reason: "unsigned"},
+}
+
+// localVerification returns the verification of a commit from its %G? status
+// and %GS signer
+func localVerification(status, signer string) *Verification {
+	v, ok := signatureStatuses[status]
+	if !ok {
+		v = Verification{Reason: "unknown_signature_type"}
+	}
+	if status != "N" {
+		v.Signer = signer
+	}
+	return &v
+}
+
+// tagSigner finds the signer in the output of git verify-tag, e.g.
+// Good signature from "Jane Doe <jane@example.com>" (GPG) or
+// Good "git" signature for jane@example.com with ED25519 key (SSH)
+var tagSigner = regexp.MustCompile(`Good (?:"git" )?signature (?:from "([^"]+)"|for (\S+))`)
+
+// VerifyTag checks the signature of a tag of the clone. It returns nil when
+// ref is not a tag, e.g. a branch or a commit, and an unsigned verification
+// for lightweight tags, which cannot be signed.
+func (a *LocalAnalyzer) VerifyTag(ctx context.Context, ref string) (*Verification, error) {
+	name := strings.TrimPrefix(ref, "refs/tags/")
+	objectType, err := a.git(ctx, "cat-file", "-t", "refs/tags/"+name)
+	if err != nil {
+		return nil, nil
+	}
+	if strings.TrimSpace(objectType) != "tag" {
+		return &Verification{Reason: "unsigned"}, nil
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "-C", a.dir, "verify-tag", "--", name)
+	out, err := cmd.CombinedOutput()
+	output := string(out)
+	signer := ""
+	if m := tagSigner.FindStringSubmatch(output); m != nil {
+		signer = m[1] + m[2]
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return &Verification{Verified: true, Reason: "valid", Signer: signer}, nil
+	case !errors.As(err, &exitErr):
+		return nil, fmt.Errorf("failed to verify tag %s: %w", name, err)
+	case strings.Contains(output, "no signature found"):
+		return &Verification{Reason: "unsigned"}, nil
+	case strings.Contains(output, "No public key"), strings.Contains(output, "allowedSignersFile"):
+		return &Verification{Reason: "unknown_key", Signer: signer}, nil
+	case strings.Contains(output, "EXPKEYSIG"), strings.Contains(output, "expired"):
+		return &Verification{Reason: "expired_key", Signer: signer}, nil
+	default:
+		return &Verification{Reason: "bad_signature", Signer: signer}, nil
+	}
+}
+
+// VerifyTag checks the signature of a tag through the GitHub API. It returns
+// nil when ref is not a tag, e.g. a branch or a commit, and an unsigned
+// verification for lightweight tags, which cannot be signed.
+func (a *Analyzer) VerifyTag(ctx context.Context, owner, repo, ref string) (*Verification, error) {
+	name := strings.TrimPrefix(ref, "refs/tags/")
+	var tagRef struct {
+		Object struct {
+			Type string `json:"type"`
+			Sha  string `json:"sha"`
+		} `json:"object"`
+	}
+	refEndpoint := fmt.Sprintf("/repos/%s/%s/git/ref/tags/%s", owner, repo, url.PathEscape(name))
+	if err := a.client.GetJSON(ctx, refEndpoint, &tagRef); err != nil {
+		if errors.Is(err, github.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get tag %s: %w", name, err)
+	}
+	if tagRef.Object.Type != "tag" {
+		return &Verification{Reason: "unsigned"}, nil
+	}
+
+	var tag struct {
+		Tagger struct {
+			Name  string `json:"name"`
+			Email string `json:"email"`
+		} `json:"tagger"`
+		Verification githubVerification `json:"verification"`
+	}
+	tagEndpoint := fmt.Sprintf("/repos/%s/%s/git/tags/%s", owner, repo, tagRef.Object.Sha)
+	if err := a.client.GetJSON(ctx, tagEndpoint, &tag); err != nil {
+		return nil, fmt.Errorf("failed to get tag %s: %w", name, err)
+	}
+	return tag.Verification.toVerification(fmt.Sprintf("%s <%s>", tag.Tagger.Name, tag.Tagger.Email)), nil
+}
+
+// githubVerification is the verification of a commit or tag as returned by
+// the GitHub API
+type githubVerification struct {
+	Verified bool   `json:"verified"`
+	Reason   string `json:"reason"`
+}
+
+// toVerification returns the verification, naming the signer when it is
+// verified, as GitHub only verifies signatures of the committer or tagger
+func (v githubVerification) toVerification(signer string) *Verification {
+	verification := &Verification{Verified: v.Verified, Reason: v.Reason}
+	if v.Verified {
+		verification.Signer = signer
+	}
+	return verification
+}
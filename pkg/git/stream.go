@@ -0,0 +1,256 @@
+package git
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/template"
+	"time"
+)
+
+// Stream writes release notes change by change, for histories of tens of
+// thousands of commits that are not worth holding in memory:
+//
+//	stream, err := formatter.Stream(w, notes) // notes without commits
+//	for each selected commit {
+//		err = stream.Add(commit)
+//	}
+//	notes.Statistics = ... // optional, rendered by Close
+//	err = stream.Close()
+//
+// The output is the same as Format's for the same changes. Markdown and text
+// are rendered with the blocks of the templates (title, range, tag-warnings,
+// table-header, change, change-row, summary and ownership, or text-change,
+// text-summary and text-ownership), so a custom template only applies
+// through the blocks it redefines. Changes cannot be grouped in sections, as
+// that needs all of them; MaxPerSection applies to the single section.
+type Stream struct {
+	f     *Formatter
+	w     io.Writer
+	notes *ReleaseNotes
+	data  *TemplateData
+	// templates are those of the formatter, bound to the notes
+	templates *template.Template
+	// count is the number of changes written, and more the number folded
+	// away after MaxPerSection
+	count  int
+	more   int
+	closed bool
+}
+
+// Stream starts writing release notes to w: the title, the note on diverged
+// references, the tag warnings and the table header. The commits of notes are ignored; its other fields are
+// read again by Close, so statistics and ownership can be set meanwhile.
+func (f *Formatter) Stream(w io.Writer, notes *ReleaseNotes) (*Stream, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if f.Sections {
+		return nil, fmt.Errorf("sections need all the changes at once; use Format")
+	}
+
+	s := &Stream{f: f, w: w, notes: notes, data: &TemplateData{
+		Notes:          notes,
+		Table:          f.format == FormatMarkdownTable,
+		UnverifiedTags: unverifiedTags(notes.Tags),
+		webURL:         f.WebURL,
+		gitLab:         f.GitLab,
+		ticketURL:      f.TicketURL,
+	}}
+	var err error
+	if s.templates, err = f.bind(s.data); err != nil {
+		return nil, err
+	}
+
+	switch f.format {
+	case FormatMarkdown, FormatMarkdownTable:
+		blocks := []string{"title", "range", "tag-warnings"}
+		if s.data.Table {
+			blocks = append(blocks, "table-header")
+		}
+		for _, block := range blocks {
+			if err = executeBlock(s.templates, w, block, s.data); err != nil {
+				break
+			}
+		}
+	case FormatJSON:
+		err = s.jsonHeader()
+	case FormatText:
+	default:
+		err = fmt.Errorf("unsupported format: %s", f.format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Add writes a change
+func (s *Stream) Add(commit CommitInfo) error {
+	if s.closed {
+		return fmt.Errorf("stream closed")
+	}
+	if s.f.format != FormatJSON && s.f.MaxPerSection > 0 && s.count >= s.f.MaxPerSection {
+		s.more++
+		return nil
+	}
+	defer func() { s.count++ }()
+
+	switch s.f.format {
+	case FormatMarkdown:
+		return executeBlock(s.templates, s.w, "change", s.data.Change(commit))
+	case FormatMarkdownTable:
+		return executeBlock(s.templates, s.w, "change-row", s.data.Change(commit))
+	case FormatText:
+		return executeBlock(s.templates, s.w, "text-change", s.data.Change(commit))
+	default:
+		separator := ",\n    "
+		if s.count == 0 {
+			separator = "\n    "
+		}
+		return s.jsonField(s.jsonSeparator(separator), "", commit, "    ")
+	}
+}
+
+// Close writes what follows the changes: the number of changes folded away,
+// the summary and ownership appendices, or the statistics, tags and
+// ownership of JSON output
+func (s *Stream) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	if s.f.Summary {
+		s.data.Summary = summaryCounts(s.notes.Statistics)
+	}
+	folded := Section{More: s.more}
+	switch s.f.format {
+	case FormatMarkdown, FormatMarkdownTable:
+		if err := executeBlock(s.templates, s.w, "folded", folded); err != nil {
+			return err
+		}
+		return s.executeBlocks("summary", "ownership")
+	case FormatText:
+		if err := executeBlock(s.templates, s.w, "text-folded", folded); err != nil {
+			return err
+		}
+		return s.executeBlocks("text-summary", "text-ownership")
+	default:
+		return s.jsonTrailer()
+	}
+}
+
+func (s *Stream) executeBlocks(blocks ...string) error {
+	for _, block := range blocks {
+		if err := executeBlock(s.templates, s.w, block, s.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonHeader writes the fields of the notes before the commits, laid out
+// like the JSON of Format
+func (s *Stream) jsonHeader() error {
+	if _, err := io.WriteString(s.w, "{"); err != nil {
+		return err
+	}
+	for i, field := range []struct {
+		key   string
+		value interface{}
+	}{
+		{"owner", s.notes.Owner},
+		{"repo", s.notes.Repo},
+		{"from", s.notes.FromRef},
+		{"to", s.notes.ToRef},
+		{"since", s.notes.Since},
+		{"until", s.notes.Until},
+		{"generated_at", s.notes.GeneratedAt},
+	} {
+		if value, ok := field.value.(*time.Time); ok && value == nil {
+			continue
+		}
+		separator := ",\n  "
+		if i == 0 {
+			separator = "\n  "
+		}
+		if err := s.jsonField(s.jsonSeparator(separator), field.key, field.value, "  "); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(s.w, s.jsonSeparator(",\n  ")+`"commits":`+s.jsonSeparator(" ")+"[")
+	return err
+}
+
+// jsonTrailer closes the list of commits and writes the fields after it
+func (s *Stream) jsonTrailer() error {
+	closing := "]"
+	if s.count > 0 && !s.f.Compact {
+		closing = "\n  ]"
+	}
+	if _, err := io.WriteString(s.w, closing); err != nil {
+		return err
+	}
+
+	if err := s.jsonField(s.jsonSeparator(",\n  "), "statistics", s.notes.Statistics, "  "); err != nil {
+		return err
+	}
+	if len(s.notes.Tags) > 0 {
+		if err := s.jsonField(s.jsonSeparator(",\n  "), "tags", s.notes.Tags, "  "); err != nil {
+			return err
+		}
+	}
+	if s.notes.Ownership != nil {
+		if err := s.jsonField(s.jsonSeparator(",\n  "), "ownership", s.notes.Ownership, "  "); err != nil {
+			return err
+		}
+	}
+	if s.notes.MergeBase != "" {
+		if err := s.jsonField(s.jsonSeparator(",\n  "), "merge_base", s.notes.MergeBase, "  "); err != nil {
+			return err
+		}
+	}
+	if s.notes.Range != "" {
+		if err := s.jsonField(s.jsonSeparator(",\n  "), "range", s.notes.Range, "  "); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(s.w, s.jsonSeparator("\n")+"}\n")
+	return err
+}
+
+// jsonSeparator drops the whitespace of a separator for compact output,
+// e.g. ",\n  " becomes ","
+func (s *Stream) jsonSeparator(separator string) string {
+	if !s.f.Compact {
+		return separator
+	}
+	return string(bytes.TrimRight([]byte(separator), " \n"))
+}
+
+// jsonField writes a separator and a value, with its key unless empty,
+// indented at prefix unless compact
+func (s *Stream) jsonField(separator, key string, value interface{}, prefix string) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if !s.f.Compact {
+		encoder.SetIndent(prefix, "  ")
+	}
+	if err := encoder.Encode(value); err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	field := bytes.TrimRight(buf.Bytes(), "\n")
+	if key != "" {
+		name, _ := json.Marshal(key)
+		field = append(append(name, s.jsonSeparator(": ")...), field...)
+	}
+	if _, err := io.WriteString(s.w, separator); err != nil {
+		return err
+	}
+	_, err := s.w.Write(field)
+	return err
+}
@@ -0,0 +1,111 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Tags returns the names of the tags of the clone
+func (a *LocalAnalyzer) Tags(ctx context.Context) ([]string, error) {
+	out, err := a.git(ctx, "tag", "--list")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(out), nil
+}
+
+// CreateTag creates an annotated tag of a commit. Signed tags are signed
+// with the key and format git is configured with (user.signingKey,
+// gpg.format), like git tag -s.
+func (a *LocalAnalyzer) CreateTag(ctx context.Context, name, message, sha string, sign bool) error {
+	mode := "--annotate"
+	if sign {
+		mode = "--sign"
+	}
+	if _, err := a.git(ctx, "tag", mode, "--message", message, "--end-of-options", name, sha); err != nil {
+		return fmt.Errorf("failed to create tag %s: %w", name, err)
+	}
+	return nil
+}
+
+// CommitFile writes a file of the work tree and commits it alone to the
+// current branch, returning the commit made
+func (a *LocalAnalyzer) CommitFile(ctx context.Context, path string, content []byte, message string) (string, error) {
+	if _, err := a.git(ctx, "rev-parse", "--show-toplevel"); err != nil {
+		return "", fmt.Errorf("%s has no work tree to commit %s in", a.dir, path)
+	}
+	if err := os.WriteFile(filepath.Join(a.dir, path), content, 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if _, err := a.git(ctx, "add", "--", path); err != nil {
+		return "", err
+	}
+	if _, err := a.git(ctx, "commit", "--message", message, "--", path); err != nil {
+		return "", err
+	}
+	return a.ResolveRef(ctx, "HEAD")
+}
+
+// ReadFile returns the content of a file at a reference; false when it does
+// not exist there
+func (a *LocalAnalyzer) ReadFile(ctx context.Context, ref, path string) ([]byte, bool, error) {
+	if _, err := a.git(ctx, "cat-file", "-e", ref+":"+path); err != nil {
+		return nil, false, nil
+	}
+	out, err := a.git(ctx, "show", ref+":"+path)
+	if err != nil {
+		return nil, false, err
+	}
+	return []byte(out), true, nil
+}
+
+// CurrentBranch returns the branch checked out, or an error when HEAD is
+// detached
+func (a *LocalAnalyzer) CurrentBranch(ctx context.Context) (string, error) {
+	out, err := a.git(ctx, "symbolic-ref", "--quiet", "--short", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("HEAD of %s is not a branch", a.dir)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// Push pushes references to a remote of the clone, e.g. refs/tags/v1.2.3
+func (a *LocalAnalyzer) Push(ctx context.Context, remote string, refs ...string) error {
+	if _, err := a.git(ctx, append([]string{"push", "--end-of-options", remote}, refs...)...); err != nil {
+		return fmt.Errorf("failed to push to %s: %w", remote, err)
+	}
+	return nil
+}
+
+// TagObject is the content of an annotated tag object, as git hashes and
+// signs it
+type TagObject struct {
+	// Object is the commit tagged
+	Object      string
+	Name        string
+	TaggerName  string
+	TaggerEmail string
+	Date        time.Time
+	// Message ends with a newline, as git writes it
+	Message string
+}
+
+// Payload returns the content of the tag object without signature: the
+// content signed by git tag -s, whose signature is appended to the message
+func (t TagObject) Payload() []byte {
+	_, offset := t.Date.Zone()
+	sign := '+'
+	if offset < 0 {
+		sign, offset = '-', -offset
+	}
+	message := t.Message
+	if !strings.HasSuffix(message, "\n") {
+		message += "\n"
+	}
+	return []byte(fmt.Sprintf("object %s\ntype commit\ntag %s\ntagger %s <%s> %d %c%02d%02d\n\n%s",
+		t.Object, t.Name, t.TaggerName, t.TaggerEmail, t.Date.Unix(), sign, offset/3600, offset%3600/60, message))
+}
@@ -0,0 +1,429 @@
+package git
+
+import (
+	"embed"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// builtinTemplates are the templates of the Markdown ("markdown") and text
+// ("text") formats, and the blocks they are made of
+var builtinTemplates = template.Must(template.New("release-notes").Funcs(templateFuncs).ParseFS(templateFS, "templates/*.tmpl"))
+
+// templateFuncs are the functions available to the templates, named and
+// ordered like their Sprig (https://masterminds.github.io/sprig/)
+// counterparts so they read the same in pipelines, e.g.
+// {{ .Subject | trimPrefix "chore: " | trunc 72 }}:
+//
+//	lower, upper, title      change the case of a string
+//	trim                     removes the surrounding white space
+//	trimPrefix, trimSuffix   remove a prefix or suffix: trimPrefix "v" .ToRef
+//	replace                  replaces every occurrence: replace "-" " " .Scope
+//	contains, hasPrefix,     test a string: contains "CVE" .Description
+//	hasSuffix
+//	trunc                    keeps the first n characters, or the last -n
+//	join                     joins a list: join ", " .Labels
+//	default                  returns a value unless empty: default "none" .Scope
+//	date                     formats a time with a Go layout: date "Jan 2, 2006" .Date
+//	now                      returns the current time
+//	link                     makes a Markdown link: link .Ticket (ticketURL .)
+//	unverified               returns why the signature of a commit failed
+//	                         verification, empty when it is verified or
+//	                         signatures were not verified
+//
+// The link functions are bound to the repository of the notes when the
+// templates are executed (see linkFuncs).
+var templateFuncs = template.FuncMap{
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"title":      title,
+	"trim":       strings.TrimSpace,
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+	"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"contains":   func(substr, s string) bool { return strings.Contains(s, substr) },
+	"hasPrefix":  func(prefix, s string) bool { return strings.HasPrefix(s, prefix) },
+	"hasSuffix":  func(suffix, s string) bool { return strings.HasSuffix(s, suffix) },
+	"trunc":      trunc,
+	"join":       func(sep string, elems []string) string { return strings.Join(elems, sep) },
+	"default":    defaultValue,
+	"date":       date,
+	"now":        time.Now,
+	"link":       link,
+	"unverified": func(commit CommitInfo) string {
+		reason, _ := unverifiedReason(commit)
+		return reason
+	},
+	// Placeholders until bound by linkFuncs
+	"commitURL":  func(CommitInfo) string { return "" },
+	"prURL":      func(CommitInfo) string { return "" },
+	"ticketURL":  func(CommitInfo) string { return "" },
+	"compareURL": func() string { return "" },
+}
+
+// linkFuncs are the functions building links to the repository of the
+// data:
+//
+//	commitURL, prURL, ticketURL   link to the commit, pull request and ticket
+//	                              of a change: commitURL .
+//	compareURL                    links to the comparison of the references
+func linkFuncs(data *TemplateData) template.FuncMap {
+	return template.FuncMap{
+		"commitURL":  data.CommitURL,
+		"prURL":      data.PullRequestURL,
+		"ticketURL":  data.TicketURL,
+		"compareURL": data.CompareURL,
+	}
+}
+
+// TemplateData is the data the templates are executed with
+type TemplateData struct {
+	Notes *ReleaseNotes
+	// Sections are the changes: one section per type when the formatter
+	// groups them, otherwise a single section without title
+	Sections []Section
+	// Table is set for the table format, which renders the changes of the
+	// markdown template as a table
+	Table bool
+	// Summary are the counts of the changes by type, scope, author and
+	// label; nil unless the formatter appends the summary
+	Summary []SummaryCount
+	// UnverifiedTags are the references that are tags whose signature
+	// failed verification, by name
+	UnverifiedTags []TagVerification
+
+	webURL    string
+	gitLab    bool
+	ticketURL string
+}
+
+// TagVerification is the verification of a tag
+type TagVerification struct {
+	Name         string
+	Verification *Verification
+}
+
+// SummaryCount is a table of the summary, e.g. the changes by scope
+type SummaryCount struct {
+	// Title names what is counted: Type, Scope, Author or Label
+	Title   string
+	Entries []CountEntry
+}
+
+// CountEntry is a row of a summary table
+type CountEntry struct {
+	Name  string
+	Count int
+}
+
+// CommitURL returns the address of a commit in the web interface
+func (d *TemplateData) CommitURL(commit CommitInfo) string {
+	return fmt.Sprintf("%s/commit/%s", d.repoURL(), commit.ShortHash())
+}
+
+// TicketURL returns the address of the ticket of a commit
+func (d *TemplateData) TicketURL(commit CommitInfo) string {
+	return d.ticketURL + commit.Ticket
+}
+
+// PullRequestURL returns the address of the pull request merged by a commit,
+// empty when it is not a pull request merge
+func (d *TemplateData) PullRequestURL(commit CommitInfo) string {
+	if commit.PR == 0 {
+		return ""
+	}
+	if d.gitLab {
+		return fmt.Sprintf("%s/merge_requests/%d", d.repoURL(), commit.PR)
+	}
+	return fmt.Sprintf("%s/pull/%d", d.repoURL(), commit.PR)
+}
+
+// CompareURL returns the address of the comparison of the references in the
+// web interface, empty without a from reference
+func (d *TemplateData) CompareURL() string {
+	if d.Notes.FromRef == "" {
+		return ""
+	}
+	separator := "..."
+	if d.Notes.Range == RangeTwoDot {
+		separator = ".."
+	}
+	return fmt.Sprintf("%s/compare/%s%s%s", d.repoURL(), d.Notes.FromRef, separator, d.Notes.ToRef)
+}
+
+// repoURL returns the address of the repository in the web interface, below
+// which GitLab serves the pages of the repository under /-/
+func (d *TemplateData) repoURL() string {
+	if d.gitLab {
+		return fmt.Sprintf("%s/%s/%s/-", d.webURL, d.Notes.Owner, d.Notes.Repo)
+	}
+	return fmt.Sprintf("%s/%s/%s", d.webURL, d.Notes.Owner, d.Notes.Repo)
+}
+
+// Change returns the data of the blocks rendering a change: change,
+// change-row and text-change
+func (d *TemplateData) Change(commit CommitInfo) Change {
+	reason, _ := unverifiedReason(commit)
+	return Change{CommitInfo: commit, URL: d.CommitURL(commit), TicketURL: d.TicketURL(commit), Unverified: reason}
+}
+
+// Change is a change with its links, as rendered by the change blocks
+type Change struct {
+	CommitInfo
+	// URL and TicketURL link to the commit and to its ticket
+	URL       string
+	TicketURL string
+	// Unverified is why the signature of the commit failed verification,
+	// empty when it is verified or signatures were not verified
+	Unverified string
+}
+
+// FormatterOption configures a Formatter
+type FormatterOption func(*Formatter)
+
+// WithTemplateFiles renders Markdown, table and text output with the
+// template files instead of the built-in templates. The first file is
+// executed with a TemplateData; it can use the blocks of the built-in
+// templates, e.g. {{ template "changes" . }}, and the files can redefine
+// them, e.g. to only restyle the title:
+//
+//	{{ define "title" }}## {{ .Notes.ToRef }}{{ "\n\n" }}{{ end }}
+//	{{- template "markdown" . -}}
+func WithTemplateFiles(paths ...string) FormatterOption {
+	return func(f *Formatter) {
+		if len(paths) == 0 {
+			f.err = fmt.Errorf("no template file given")
+			return
+		}
+		f.templates, f.err = parseTemplates(func(t *template.Template) (*template.Template, error) {
+			return t.ParseFiles(paths...)
+		})
+		f.entry = filepath.Base(paths[0])
+	}
+}
+
+// WithTemplateFS is WithTemplateFiles for the files of fsys matching the
+// patterns, e.g. an embed.FS; the first file matching the first pattern is
+// executed
+func WithTemplateFS(fsys fs.FS, patterns ...string) FormatterOption {
+	return func(f *Formatter) {
+		if len(patterns) == 0 {
+			f.err = fmt.Errorf("no template pattern given")
+			return
+		}
+		matches, err := fs.Glob(fsys, patterns[0])
+		if err != nil || len(matches) == 0 {
+			f.err = fmt.Errorf("no template matches %s", patterns[0])
+			return
+		}
+		f.templates, f.err = parseTemplates(func(t *template.Template) (*template.Template, error) {
+			return t.ParseFS(fsys, patterns...)
+		})
+		f.entry = path.Base(matches[0])
+	}
+}
+
+// parseTemplates parses custom templates over a copy of the built-in ones
+func parseTemplates(parse func(*template.Template) (*template.Template, error)) (*template.Template, error) {
+	t, err := builtinTemplates.Clone()
+	if err != nil {
+		return nil, err
+	}
+	if t, err = parse(t); err != nil {
+		return nil, fmt.Errorf("failed to parse templates: %w", err)
+	}
+	return t, nil
+}
+
+// execute renders the notes to w with the custom template, or with the
+// built-in template of the format
+func (f *Formatter) execute(w io.Writer, name string, notes *ReleaseNotes) error {
+	if f.err != nil {
+		return f.err
+	}
+	if f.entry != "" {
+		name = f.entry
+	}
+	data := f.templateData(notes)
+	t, err := f.bind(data)
+	if err != nil {
+		return err
+	}
+	return executeBlock(t, w, name, data)
+}
+
+// bind returns a copy of the templates whose link functions point at the
+// repository of the data. The templates of the formatter are never executed
+// themselves, which would prevent copying them.
+func (f *Formatter) bind(data *TemplateData) (*template.Template, error) {
+	t, err := f.templates.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return t.Funcs(linkFuncs(data)), nil
+}
+
+// executeBlock renders a template or block to w
+func executeBlock(t *template.Template, w io.Writer, name string, data interface{}) error {
+	if err := t.ExecuteTemplate(w, name, data); err != nil {
+		return fmt.Errorf("failed to render template %s: %w", name, err)
+	}
+	return nil
+}
+
+// title uppercases the first letter of each word
+func title(s string) string {
+	runes := []rune(s)
+	for i, r := range runes {
+		if i == 0 || !unicode.IsLetter(runes[i-1]) && !unicode.IsDigit(runes[i-1]) && runes[i-1] != '\'' {
+			runes[i] = unicode.ToUpper(r)
+		}
+	}
+	return string(runes)
+}
+
+// trunc keeps the first n characters of s, or its last -n when n is
+// negative
+func trunc(n int, s string) string {
+	runes := []rune(s)
+	switch {
+	case n >= 0 && len(runes) > n:
+		return string(runes[:n])
+	case n < 0 && len(runes) > -n:
+		return string(runes[len(runes)+n:])
+	}
+	return s
+}
+
+// defaultValue returns value unless it is empty (nil, zero, or of length
+// 0), def otherwise
+func defaultValue(def, value interface{}) interface{} {
+	if value == nil {
+		return def
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		if v.Len() == 0 {
+			return def
+		}
+	default:
+		if v.IsZero() {
+			return def
+		}
+	}
+	return value
+}
+
+// date formats a time, or a time pointer as set for optional times, with a
+// Go layout; nil pointers format as empty
+func date(layout string, t interface{}) (string, error) {
+	switch t := t.(type) {
+	case time.Time:
+		return t.Format(layout), nil
+	case *time.Time:
+		if t == nil {
+			return "", nil
+		}
+		return t.Format(layout), nil
+	default:
+		return "", fmt.Errorf("date: %T is not a time", t)
+	}
+}
+
+// link returns a Markdown link, or the text alone without an address
+func link(text, url string) string {
+	if url == "" {
+		return text
+	}
+	return "[" + text + "](" + url + ")"
+}
+
+// templateData returns the data the templates are executed with
+func (f *Formatter) templateData(notes *ReleaseNotes) *TemplateData {
+	data := &TemplateData{
+		Notes:     notes,
+		Sections:  f.sections(notes.Commits),
+		Table:     f.format == FormatMarkdownTable,
+		webURL:    f.WebURL,
+		gitLab:    f.GitLab,
+		ticketURL: f.TicketURL,
+	}
+	if f.Summary {
+		data.Summary = summaryCounts(notes.Statistics)
+	}
+	data.UnverifiedTags = unverifiedTags(notes.Tags)
+	return data
+}
+
+// unverifiedTags returns the tags whose signature failed verification,
+// sorted by name
+func unverifiedTags(tags map[string]*Verification) []TagVerification {
+	var unverified []TagVerification
+	for name, verification := range tags {
+		if !verification.Verified {
+			unverified = append(unverified, TagVerification{Name: name, Verification: verification})
+		}
+	}
+	sort.Slice(unverified, func(i, j int) bool {
+		return unverified[i].Name < unverified[j].Name
+	})
+	return unverified
+}
+
+// summaryCounts returns the tables of the summary; empty tables are left
+// out, as are changes without scope, which are most of them in many
+// repositories
+func summaryCounts(stats CommitStatistics) []SummaryCount {
+	var counts []SummaryCount
+	for _, table := range []struct {
+		title  string
+		counts map[string]int
+		none   string
+	}{
+		{"Type", stats.ByType, "(none)"},
+		{"Scope", stats.ByScope, ""},
+		{"Author", stats.ByAuthor, "(unknown)"},
+		{"Label", stats.ByLabel, ""},
+	} {
+		entries := sortedCounts(table.counts, table.none)
+		if len(entries) > 0 {
+			counts = append(counts, SummaryCount{Title: table.title, Entries: entries})
+		}
+	}
+	return counts
+}
+
+// sortedCounts returns the counts by decreasing count, then name. The empty
+// name is shown as none, or left out when none is empty.
+func sortedCounts(counts map[string]int, none string) []CountEntry {
+	entries := make([]CountEntry, 0, len(counts))
+	for name, count := range counts {
+		if name == "" {
+			if none == "" {
+				continue
+			}
+			name = none
+		}
+		entries = append(entries, CountEntry{Name: name, Count: count})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Count != entries[j].Count {
+			return entries[i].Count > entries[j].Count
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
@@ -0,0 +1,229 @@
+package git
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Increment is the part of a semantic version a release increments
+type Increment string
+
+const (
+	IncrementMajor Increment = "major"
+	IncrementMinor Increment = "minor"
+	IncrementPatch Increment = "patch"
+)
+
+// ParseIncrement validates an increment given by name
+func ParseIncrement(value string) (Increment, error) {
+	switch inc := Increment(strings.ToLower(value)); inc {
+	case IncrementMajor, IncrementMinor, IncrementPatch:
+		return inc, nil
+	default:
+		return "", fmt.Errorf("invalid increment %q (available: major, minor, patch)", value)
+	}
+}
+
+// Version is a semantic version (https://semver.org), e.g. v1.2.3-rc.1
+type Version struct {
+	// Prefix is what precedes the numbers, e.g. v, kept by Bump
+	Prefix     string
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string
+	Build      string
+}
+
+var versionPattern = regexp.MustCompile(`^(.*?)(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)(?:-([0-9A-Za-z.-]+))?(?:\+([0-9A-Za-z.-]+))?$`)
+
+// ParseVersion parses a semantic version, with an optional prefix ending
+// with v or a slash, e.g. v1.2.3 or api/v1.2.3
+func ParseVersion(value string) (Version, error) {
+	m := versionPattern.FindStringSubmatch(strings.TrimSpace(value))
+	if m == nil || (m[1] != "" && !strings.HasSuffix(m[1], "v") && !strings.HasSuffix(m[1], "/")) {
+		return Version{}, fmt.Errorf("%q is not a semantic version, e.g. v1.2.3", value)
+	}
+	v := Version{Prefix: m[1], Prerelease: m[5], Build: m[6]}
+	// The pattern only matches digits, so the numbers parse unless they
+	// overflow
+	var err error
+	if v.Major, err = strconv.Atoi(m[2]); err != nil {
+		return Version{}, fmt.Errorf("invalid major version of %q: %w", value, err)
+	}
+	if v.Minor, err = strconv.Atoi(m[3]); err != nil {
+		return Version{}, fmt.Errorf("invalid minor version of %q: %w", value, err)
+	}
+	if v.Patch, err = strconv.Atoi(m[4]); err != nil {
+		return Version{}, fmt.Errorf("invalid patch version of %q: %w", value, err)
+	}
+	return v, nil
+}
+
+// String returns the version as written in a tag
+func (v Version) String() string {
+	s := fmt.Sprintf("%s%d.%d.%d", v.Prefix, v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// Bump returns the next release version. The prerelease and build are
+// dropped; a prerelease of the version the increment leads to, e.g.
+// v1.3.0-rc.1 for a minor increment, is released as is.
+func (v Version) Bump(inc Increment) Version {
+	next := Version{Prefix: v.Prefix, Major: v.Major, Minor: v.Minor, Patch: v.Patch}
+	if v.Prerelease != "" {
+		switch {
+		case inc == IncrementMajor && v.Minor == 0 && v.Patch == 0,
+			inc == IncrementMinor && v.Patch == 0,
+			inc == IncrementPatch:
+			return next
+		}
+	}
+	switch inc {
+	case IncrementMajor:
+		next.Major, next.Minor, next.Patch = v.Major+1, 0, 0
+	case IncrementMinor:
+		next.Minor, next.Patch = v.Minor+1, 0
+	default:
+		next.Patch = v.Patch + 1
+	}
+	return next
+}
+
+// Compare returns -1, 0 or 1 when v precedes, equals or follows o in
+// semantic version order; the prefix and build are ignored
+func (v Version) Compare(o Version) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+	switch {
+	case v.Prerelease == o.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case o.Prerelease == "":
+		return -1
+	}
+	return comparePrerelease(v.Prerelease, o.Prerelease)
+}
+
+// comparePrerelease compares dot-separated identifiers: numbers
+// numerically and before words, words alphabetically
+func comparePrerelease(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if an != bn {
+				return sign(an - bn)
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return sign(len(as) - len(bs))
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+// PreviousVersion returns the tag with the highest release version before
+// version, or the highest one when version is empty; empty when there is
+// none. Only the tags of the component of version are compared, or of the
+// component of prefix when version is empty (see LatestVersion).
+func PreviousVersion(tags []string, version, prefix string) (string, Version, error) {
+	if version == "" {
+		tag, previous, _ := LatestVersion(tags, prefix)
+		return tag, previous, nil
+	}
+	current, err := ParseVersion(version)
+	if err != nil {
+		return "", Version{}, err
+	}
+	var before []string
+	for _, tag := range tags {
+		if v, err := ParseVersion(tag); err == nil && v.Compare(current) < 0 {
+			before = append(before, tag)
+		}
+	}
+	tag, previous, _ := LatestVersion(before, current.Prefix)
+	return tag, previous, nil
+}
+
+// LatestVersion returns the tag with the highest release version, skipping
+// prereleases and the tags that are not semantic versions; false when there
+// is none. Only the tags of the component of prefix are compared, so the
+// components of a monorepo are versioned apart: api/ or api/v for the tags
+// of the api component, e.g. api/v1.4.0, and empty or v for the tags of the
+// whole repository, e.g. v1.2.0.
+func LatestVersion(tags []string, prefix string) (string, Version, bool) {
+	type versionTag struct {
+		tag     string
+		version Version
+	}
+	var versions []versionTag
+	for _, tag := range tags {
+		if v, err := ParseVersion(tag); err == nil && v.Prerelease == "" && SameComponent(v.Prefix, prefix) {
+			versions = append(versions, versionTag{tag, v})
+		}
+	}
+	if len(versions) == 0 {
+		return "", Version{}, false
+	}
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].version.Compare(versions[j].version) > 0
+	})
+	return versions[0].tag, versions[0].version, true
+}
+
+// SameComponent tells whether two tag prefixes version the same component
+// of a repository: the part up to their last slash, e.g. api/ for api/v,
+// is the same
+func SameComponent(prefix1, prefix2 string) bool {
+	return prefix1[:strings.LastIndex(prefix1, "/")+1] == prefix2[:strings.LastIndex(prefix2, "/")+1]
+}
+
+// NextIncrement returns the increment the changes call for: major for a
+// breaking change, minor for a feature, patch otherwise. Commits without a
+// conventional type are typed with the rules; label rules only match commits
+// whose labels were looked up. Before 1.0.0, breaking changes only call for
+// a minor increment, as the API is not considered stable yet.
+func NextIncrement(current Version, commits []CommitInfo, rules []TypeRule) Increment {
+	inc := IncrementPatch
+	for _, commit := range commits {
+		applyRules(rules, &commit)
+		switch {
+		case commit.Breaking && current.Major > 0:
+			return IncrementMajor
+		case commit.Breaking, commit.Type == "feat":
+			inc = IncrementMinor
+		}
+	}
+	return inc
+}
@@ -0,0 +1,146 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// gitCommit is a commit as returned by the git data API
+type gitCommit struct {
+	SHA     string `json:"sha"`
+	Message string `json:"message"`
+	Author  struct {
+		Name  string `json:"name"`
+		Email string `json:"email"`
+		Date  string `json:"date"`
+	} `json:"author"`
+	Tree struct {
+		SHA string `json:"sha"`
+	} `json:"tree"`
+	Parents []struct {
+		SHA string `json:"sha"`
+	} `json:"parents"`
+}
+
+// CherryPick applies the changes of commits, oldest first, on top of a
+// branch, like git cherry-pick -x, and returns the new head of the branch.
+// The API has no cherry-pick: each commit is merged into a temporary commit
+// of the branch tree with the parent of the commit, and the merged tree is
+// committed on top of the branch. The branch is moved to the commits made,
+// so it should be a new branch; ErrConflict is returned when the changes of
+// a commit conflict with the branch. Merge commits cannot be picked.
+func (c *Client) CherryPick(ctx context.Context, owner, repo, branch string, commits []string) (string, error) {
+	base := fmt.Sprintf("%s/repos/%s/%s", c.baseURL, url.PathEscape(owner), url.PathEscape(repo))
+
+	head, err := c.BranchHead(ctx, owner, repo, branch)
+	if err != nil {
+		return "", err
+	}
+	for _, sha := range commits {
+		var commit, current gitCommit
+		if err := c.getJSON(ctx, base+"/git/commits/"+url.PathEscape(sha), &commit); err != nil {
+			return "", fmt.Errorf("failed to get commit %s: %w", sha, err)
+		}
+		if len(commit.Parents) != 1 {
+			return "", fmt.Errorf("commit %s is a merge commit: only commits with one parent can be picked", shortSHA(sha))
+		}
+		if err := c.getJSON(ctx, base+"/git/commits/"+url.PathEscape(head), &current); err != nil {
+			return "", fmt.Errorf("failed to get commit %s: %w", head, err)
+		}
+
+		// The tree of the branch on top of the parent of the commit: merging
+		// the commit into it applies the changes of the commit alone
+		var temporary gitCommit
+		if err := c.sendJSON(ctx, http.MethodPost, base+"/git/commits", map[string]interface{}{
+			"message": "Temporary commit of the cherry-pick of " + sha,
+			"tree":    current.Tree.SHA,
+			"parents": []string{commit.Parents[0].SHA},
+		}, &temporary); err != nil {
+			return "", fmt.Errorf("failed to create commit: %w", err)
+		}
+		if err := c.updateBranch(ctx, owner, repo, branch, temporary.SHA); err != nil {
+			return "", err
+		}
+
+		var merged struct {
+			Commit struct {
+				Tree struct {
+					SHA string `json:"sha"`
+				} `json:"tree"`
+			} `json:"commit"`
+		}
+		if err := c.sendJSON(ctx, http.MethodPost, base+"/merges", map[string]string{
+			"base":           branch,
+			"head":           sha,
+			"commit_message": "Temporary merge of the cherry-pick of " + sha,
+		}, &merged); err != nil {
+			// Put the branch back where the last pick left it
+			_ = c.updateBranch(ctx, owner, repo, branch, head)
+			if errors.Is(err, ErrConflict) {
+				return "", fmt.Errorf("%w: the changes of commit %s do not apply cleanly", ErrConflict, shortSHA(sha))
+			}
+			return "", fmt.Errorf("failed to apply commit %s: %w", shortSHA(sha), err)
+		}
+		if merged.Commit.Tree.SHA == "" {
+			// 204: the changes are already in the branch
+			if err := c.updateBranch(ctx, owner, repo, branch, head); err != nil {
+				return "", err
+			}
+			continue
+		}
+
+		// The picked commit keeps the author and message of the commit
+		var picked gitCommit
+		if err := c.sendJSON(ctx, http.MethodPost, base+"/git/commits", map[string]interface{}{
+			"message": strings.TrimRight(commit.Message, "\n") + "\n\n(cherry picked from commit " + sha + ")",
+			"tree":    merged.Commit.Tree.SHA,
+			"parents": []string{head},
+			"author": map[string]string{
+				"name":  commit.Author.Name,
+				"email": commit.Author.Email,
+				"date":  commit.Author.Date,
+			},
+		}, &picked); err != nil {
+			return "", fmt.Errorf("failed to create commit: %w", err)
+		}
+		if err := c.updateBranch(ctx, owner, repo, branch, picked.SHA); err != nil {
+			return "", err
+		}
+		head = picked.SHA
+	}
+	return head, nil
+}
+
+// updateBranch moves a branch to a commit, even when it is not a descendant
+// of the current one
+func (c *Client) updateBranch(ctx context.Context, owner, repo, branch, sha string) error {
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/git/refs/heads/%s", c.baseURL, url.PathEscape(owner), url.PathEscape(repo), escapePath(branch))
+	if err := c.sendJSON(ctx, http.MethodPatch, endpoint, map[string]interface{}{
+		"sha":   sha,
+		"force": true,
+	}, nil); err != nil {
+		return fmt.Errorf("failed to update branch %s: %w", branch, err)
+	}
+	return nil
+}
+
+// DeleteBranch deletes a branch
+func (c *Client) DeleteBranch(ctx context.Context, owner, repo, branch string) error {
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/git/refs/heads/%s", c.baseURL, url.PathEscape(owner), url.PathEscape(repo), escapePath(branch))
+	if err := c.sendJSON(ctx, http.MethodDelete, endpoint, nil, nil); err != nil {
+		return fmt.Errorf("failed to delete branch %s: %w", branch, err)
+	}
+	return nil
+}
+
+// shortSHA returns the abbreviated form of a commit SHA
+func shortSHA(sha string) string {
+	if len(sha) > 8 {
+		return sha[:8]
+	}
+	return sha
+}
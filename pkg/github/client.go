@@ -0,0 +1,157 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"drivio/pkg/httplog"
+)
+
+// DefaultBaseURL is the GitHub REST API endpoint
+const DefaultBaseURL = "https://api.github.com"
+
+// ErrNotFound is returned when a resource does not exist or is not visible
+// with the token
+var ErrNotFound = errors.New("not found")
+
+// ErrConflict is returned when a write conflicts with the state of the
+// repository, e.g. a merge with conflicting changes
+var ErrConflict = errors.New("conflict")
+
+// ErrRateLimited is returned when the API rate limit of the token, or of the
+// address for unauthenticated requests, is exhausted
+var ErrRateLimited = errors.New("GitHub API rate limit exceeded")
+
+// Client is a minimal GitHub REST API client
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	token      string
+}
+
+// NewClient creates a new GitHub client. The token is optional; without it
+// requests are unauthenticated and subject to lower rate limits.
+func NewClient(token string) *Client {
+	return &Client{
+		httpClient: httplog.NewClient(5 * time.Minute),
+		baseURL:    DefaultBaseURL,
+		token:      token,
+	}
+}
+
+// BaseURL returns the API endpoint the client talks to
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// SetBaseURL points the client at another API endpoint, e.g.
+// https://github.example.com/api/v3 for GitHub Enterprise Server
+func (c *Client) SetBaseURL(baseURL string) {
+	c.baseURL = strings.TrimRight(baseURL, "/")
+}
+
+// newRequest creates a request against the GitHub API with the common headers
+func (c *Client) newRequest(ctx context.Context, method, url string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("User-Agent", "drivio")
+	if c.token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.token))
+	}
+	return req, nil
+}
+
+// User is a GitHub account
+type User struct {
+	Login string `json:"login"`
+}
+
+// CurrentUser returns the account the token belongs to, which validates the
+// token
+func (c *Client) CurrentUser(ctx context.Context) (*User, error) {
+	var user User
+	if err := c.getJSON(ctx, c.baseURL+"/user", &user); err != nil {
+		return nil, fmt.Errorf("failed to validate GitHub token: %w", err)
+	}
+	return &user, nil
+}
+
+// TokenScopes returns the OAuth scopes of the token. known is false when the
+// token does not report scopes, like fine-grained tokens and GitHub App
+// tokens, whose permissions are configured per repository.
+func (c *Client) TokenScopes(ctx context.Context) (scopes []string, known bool, err error) {
+	req, err := c.newRequest(ctx, http.MethodGet, c.baseURL+"/user")
+	if err != nil {
+		return nil, false, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, false, fmt.Errorf("invalid or expired GitHub token")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("GitHub API returned status %d", resp.StatusCode)
+	}
+
+	header, known := resp.Header["X-Oauth-Scopes"]
+	if !known {
+		return nil, false, nil
+	}
+	for _, scope := range strings.Split(strings.Join(header, ","), ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, true, nil
+}
+
+// RepositoryExists reports whether the repository is visible with the token
+func (c *Client) RepositoryExists(ctx context.Context, owner, repo string) (bool, error) {
+	var repository struct{}
+	err := c.getJSON(ctx, fmt.Sprintf("%s/repos/%s/%s", c.baseURL, url.PathEscape(owner), url.PathEscape(repo)), &repository)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// DownloadArchive downloads the tar.gz archive of a repository at the given
+// ref. The caller must close the returned reader.
+func (c *Client) DownloadArchive(ctx context.Context, owner, repo, ref string) (io.ReadCloser, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/tarball/%s", c.baseURL, owner, repo, ref)
+
+	req, err := c.newRequest(ctx, http.MethodGet, url)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("repository or ref not found: %s/%s@%s", owner, repo, ref)
+		}
+		return nil, fmt.Errorf("GitHub API returned status %d", resp.StatusCode)
+	}
+
+	return resp.Body, nil
+}
@@ -0,0 +1,298 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TreeFile is a text file of a commit made with CommitFiles
+type TreeFile struct {
+	Path    string
+	Content []byte
+}
+
+// CommitOptions describes a commit of files on top of a parent commit
+type CommitOptions struct {
+	Parent  string
+	Message string
+	Files   []TreeFile
+}
+
+// CommitFiles creates a commit changing the files on top of the parent
+// commit, in a single commit whatever the number of files, and returns its
+// SHA. No branch points to the commit yet, see CreateBranch.
+func (c *Client) CommitFiles(ctx context.Context, owner, repo string, opts CommitOptions) (string, error) {
+	base := fmt.Sprintf("%s/repos/%s/%s/git", c.baseURL, url.PathEscape(owner), url.PathEscape(repo))
+
+	var parent struct {
+		Tree struct {
+			SHA string `json:"sha"`
+		} `json:"tree"`
+	}
+	if err := c.getJSON(ctx, base+"/commits/"+url.PathEscape(opts.Parent), &parent); err != nil {
+		return "", fmt.Errorf("failed to get commit %s: %w", opts.Parent, err)
+	}
+
+	entries := make([]map[string]string, 0, len(opts.Files))
+	for _, file := range opts.Files {
+		entries = append(entries, map[string]string{
+			"path":    file.Path,
+			"mode":    "100644",
+			"type":    "blob",
+			"content": string(file.Content),
+		})
+	}
+	var tree struct {
+		SHA string `json:"sha"`
+	}
+	if err := c.sendJSON(ctx, http.MethodPost, base+"/trees", map[string]interface{}{
+		"base_tree": parent.Tree.SHA,
+		"tree":      entries,
+	}, &tree); err != nil {
+		return "", fmt.Errorf("failed to create tree: %w", err)
+	}
+
+	var commit struct {
+		SHA string `json:"sha"`
+	}
+	if err := c.sendJSON(ctx, http.MethodPost, base+"/commits", map[string]interface{}{
+		"message": opts.Message,
+		"tree":    tree.SHA,
+		"parents": []string{opts.Parent},
+	}, &commit); err != nil {
+		return "", fmt.Errorf("failed to create commit: %w", err)
+	}
+	return commit.SHA, nil
+}
+
+// CreateBranch creates a branch pointing to a commit
+func (c *Client) CreateBranch(ctx context.Context, owner, repo, branch, sha string) error {
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/git/refs", c.baseURL, url.PathEscape(owner), url.PathEscape(repo))
+	if err := c.sendJSON(ctx, http.MethodPost, endpoint, map[string]string{
+		"ref": "refs/heads/" + branch,
+		"sha": sha,
+	}, nil); err != nil {
+		return fmt.Errorf("failed to create branch %s: %w", branch, err)
+	}
+	return nil
+}
+
+// FileSHA returns the blob SHA of a file at the given ref; ErrNotFound when
+// it does not exist
+func (c *Client) FileSHA(ctx context.Context, owner, repo, ref, path string) (string, error) {
+	file, err := c.getFile(ctx, owner, repo, ref, path)
+	if err != nil {
+		return "", err
+	}
+	return file.SHA, nil
+}
+
+// PullRequestOptions describes a pull request to open
+type PullRequestOptions struct {
+	// Head is the branch with the changes, Base the branch to merge them into
+	Head  string
+	Base  string
+	Title string
+	// Body is Markdown, e.g. release notes
+	Body   string
+	Labels []string
+	// Reviewers are user logins, or org/team for teams
+	Reviewers []string
+	Draft     bool
+}
+
+// PullRequest is a GitHub pull request
+type PullRequest struct {
+	Number  int    `json:"number"`
+	HTMLURL string `json:"html_url"`
+	Title   string `json:"title"`
+	// Body is the description, Markdown
+	Body   string `json:"body"`
+	State  string `json:"state"`
+	Merged bool   `json:"merged"`
+	// MergeCommitSHA is the commit the pull request was merged with
+	MergeCommitSHA string `json:"merge_commit_sha"`
+	Labels         []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+	Base struct {
+		Ref string `json:"ref"`
+	} `json:"base"`
+	User struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// MergedAt is nil until the pull request is merged
+	MergedAt *time.Time `json:"merged_at"`
+}
+
+// Review is a review of a pull request
+type Review struct {
+	User struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	// State is APPROVED, CHANGES_REQUESTED, COMMENTED, DISMISSED or PENDING
+	State string `json:"state"`
+	// SubmittedAt is nil for pending reviews
+	SubmittedAt *time.Time `json:"submitted_at"`
+}
+
+// LabelNames returns the names of the labels of the pull request
+func (pr *PullRequest) LabelNames() []string {
+	names := make([]string, 0, len(pr.Labels))
+	for _, label := range pr.Labels {
+		names = append(names, label.Name)
+	}
+	return names
+}
+
+// GetPullRequest returns a pull request; ErrNotFound when it does not exist
+func (c *Client) GetPullRequest(ctx context.Context, owner, repo string, number int) (*PullRequest, error) {
+	var pr PullRequest
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/pulls/%d", c.baseURL, url.PathEscape(owner), url.PathEscape(repo), number)
+	if err := c.getJSON(ctx, endpoint, &pr); err != nil {
+		return nil, fmt.Errorf("failed to get pull request #%d: %w", number, err)
+	}
+	return &pr, nil
+}
+
+// CreatePullRequest opens a pull request, then labels it and requests its
+// reviews. Like other writes, it is not retried.
+func (c *Client) CreatePullRequest(ctx context.Context, owner, repo string, opts PullRequestOptions) (*PullRequest, error) {
+	base := fmt.Sprintf("%s/repos/%s/%s", c.baseURL, url.PathEscape(owner), url.PathEscape(repo))
+
+	var pr PullRequest
+	if err := c.sendJSON(ctx, http.MethodPost, base+"/pulls", map[string]interface{}{
+		"head":  opts.Head,
+		"base":  opts.Base,
+		"title": opts.Title,
+		"body":  opts.Body,
+		"draft": opts.Draft,
+	}, &pr); err != nil {
+		return nil, fmt.Errorf("failed to open pull request from %s to %s: %w", opts.Head, opts.Base, err)
+	}
+
+	// Labels and reviewers are set once the pull request exists
+	if len(opts.Labels) > 0 {
+		if err := c.sendJSON(ctx, http.MethodPost, fmt.Sprintf("%s/issues/%d/labels", base, pr.Number), map[string][]string{
+			"labels": opts.Labels,
+		}, nil); err != nil {
+			return &pr, fmt.Errorf("failed to label pull request #%d: %w", pr.Number, err)
+		}
+	}
+	if len(opts.Reviewers) > 0 {
+		users, teams := []string{}, []string{}
+		for _, reviewer := range opts.Reviewers {
+			reviewer = strings.TrimPrefix(reviewer, "@")
+			if _, team, ok := strings.Cut(reviewer, "/"); ok {
+				teams = append(teams, team)
+			} else {
+				users = append(users, reviewer)
+			}
+		}
+		if err := c.sendJSON(ctx, http.MethodPost, fmt.Sprintf("%s/pulls/%d/requested_reviewers", base, pr.Number), map[string][]string{
+			"reviewers":      users,
+			"team_reviewers": teams,
+		}, nil); err != nil {
+			return &pr, fmt.Errorf("failed to request reviews of pull request #%d: %w", pr.Number, err)
+		}
+	}
+	return &pr, nil
+}
+
+// MergedPullRequests returns the pull requests merged into a base branch
+// between two times, oldest first
+func (c *Client) MergedPullRequests(ctx context.Context, owner, repo, base string, since, until time.Time) ([]PullRequest, error) {
+	var merged []PullRequest
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/pulls?state=closed&base=%s&sort=updated&direction=desc&per_page=100",
+		c.baseURL, url.PathEscape(owner), url.PathEscape(repo), url.QueryEscape(base))
+	for endpoint != "" {
+		var page []PullRequest
+		next, err := c.GetPage(ctx, endpoint, &page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pull requests merged into %s: %w", base, err)
+		}
+		for _, pr := range page {
+			// Pull requests are updated when merged, at the latest: the
+			// ones updated before the start were merged before too
+			if pr.UpdatedAt.Before(since) {
+				next = ""
+				break
+			}
+			if pr.MergedAt != nil && !pr.MergedAt.Before(since) && !pr.MergedAt.After(until) {
+				merged = append(merged, pr)
+			}
+		}
+		endpoint = next
+	}
+
+	sort.Slice(merged, func(i, j int) bool { return merged[i].MergedAt.Before(*merged[j].MergedAt) })
+	return merged, nil
+}
+
+// Approvers returns the logins of the reviewers whose latest review of a
+// pull request approves it, in the order of their approvals. Comments leave
+// an approval standing; requesting changes or a dismissal withdraws it.
+func Approvers(reviews []Review) []string {
+	approved := make(map[string]bool)
+	var order []string
+	for _, review := range reviews {
+		login := review.User.Login
+		switch review.State {
+		case "APPROVED":
+			if !slices.Contains(order, login) {
+				order = append(order, login)
+			}
+			approved[login] = true
+		case "CHANGES_REQUESTED", "DISMISSED":
+			approved[login] = false
+		}
+	}
+	approvers := []string{}
+	for _, login := range order {
+		if approved[login] {
+			approvers = append(approvers, login)
+		}
+	}
+	return approvers
+}
+
+// PullRequestReviews returns the reviews of a pull request, oldest first
+func (c *Client) PullRequestReviews(ctx context.Context, owner, repo string, number int) ([]Review, error) {
+	var reviews []Review
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/reviews?per_page=100", c.baseURL, url.PathEscape(owner), url.PathEscape(repo), number)
+	for endpoint != "" {
+		var page []Review
+		next, err := c.GetPage(ctx, endpoint, &page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list reviews of pull request #%d: %w", number, err)
+		}
+		reviews = append(reviews, page...)
+		endpoint = next
+	}
+	return reviews, nil
+}
+
+// PullRequestCommits returns the commits of a pull request, oldest first.
+// The API lists at most 250 commits of a pull request.
+func (c *Client) PullRequestCommits(ctx context.Context, owner, repo string, number int) ([]Commit, error) {
+	var commits []Commit
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/pulls/%d/commits?per_page=100", c.baseURL, url.PathEscape(owner), url.PathEscape(repo), number)
+	for endpoint != "" {
+		var page []Commit
+		next, err := c.GetPage(ctx, endpoint, &page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list commits of pull request #%d: %w", number, err)
+		}
+		commits = append(commits, page...)
+		endpoint = next
+	}
+	return commits, nil
+}
@@ -0,0 +1,73 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// ReleaseOptions describes a release to create on an existing tag
+type ReleaseOptions struct {
+	Tag  string
+	Name string
+	// Body is the description of the release, e.g. the release notes in
+	// Markdown
+	Body       string
+	Draft      bool
+	Prerelease bool
+}
+
+// Release is a GitHub release
+type Release struct {
+	ID      int64  `json:"id"`
+	TagName string `json:"tag_name"`
+	Name    string `json:"name"`
+	HTMLURL string `json:"html_url"`
+	Body    string `json:"body"`
+}
+
+// CreateRelease creates a release for a tag
+func (c *Client) CreateRelease(ctx context.Context, owner, repo string, opts ReleaseOptions) (*Release, error) {
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/releases", c.baseURL, url.PathEscape(owner), url.PathEscape(repo))
+
+	var release Release
+	if err := c.sendJSON(ctx, http.MethodPost, endpoint, map[string]interface{}{
+		"tag_name":   opts.Tag,
+		"name":       opts.Name,
+		"body":       opts.Body,
+		"draft":      opts.Draft,
+		"prerelease": opts.Prerelease,
+	}, &release); err != nil {
+		return nil, fmt.Errorf("failed to create release %s: %w", opts.Tag, err)
+	}
+	return &release, nil
+}
+
+// PublishRelease sets the body of the release of a tag, creating the release
+// when the tag has none. The body of an existing release is only replaced
+// when empty, or when overwrite is set. It returns the release and whether
+// it was created or changed.
+func (c *Client) PublishRelease(ctx context.Context, owner, repo, tag, body string, overwrite bool) (*Release, bool, error) {
+	base := fmt.Sprintf("%s/repos/%s/%s/releases", c.baseURL, url.PathEscape(owner), url.PathEscape(repo))
+
+	var release Release
+	err := c.getJSON(ctx, base+"/tags/"+url.PathEscape(tag), &release)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		created, err := c.CreateRelease(ctx, owner, repo, ReleaseOptions{Tag: tag, Name: tag, Body: body})
+		return created, err == nil, err
+	case err != nil:
+		return nil, false, fmt.Errorf("failed to get release %s: %w", tag, err)
+	case release.Body != "" && !overwrite:
+		return &release, false, nil
+	}
+
+	if err := c.sendJSON(ctx, http.MethodPatch, fmt.Sprintf("%s/%d", base, release.ID), map[string]string{
+		"body": body,
+	}, &release); err != nil {
+		return nil, false, fmt.Errorf("failed to update release %s: %w", tag, err)
+	}
+	return &release, true, nil
+}
@@ -0,0 +1,283 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// TagOptions describes an annotated tag to create
+type TagOptions struct {
+	Name    string
+	Message string
+	// SHA is the commit tagged
+	SHA string
+	// Tagger is the identity and date of the tag, those of the token's user
+	// and the current time when nil
+	Tagger *Tagger
+}
+
+// Tagger is the tagger of an annotated tag
+type Tagger struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	// Date is an ISO 8601 time, e.g. 2024-01-31T12:00:00Z
+	Date string `json:"date"`
+}
+
+// Tag is an annotated tag object
+type Tag struct {
+	SHA     string `json:"sha"`
+	Tag     string `json:"tag"`
+	Message string `json:"message"`
+}
+
+// ListTags returns the names of the tags of a repository
+func (c *Client) ListTags(ctx context.Context, owner, repo string) ([]string, error) {
+	var names []string
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/tags?per_page=100", c.baseURL, url.PathEscape(owner), url.PathEscape(repo))
+	for endpoint != "" {
+		var tags []struct {
+			Name string `json:"name"`
+		}
+		next, err := c.GetPage(ctx, endpoint, &tags)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tags of %s/%s: %w", owner, repo, err)
+		}
+		for _, tag := range tags {
+			names = append(names, tag.Name)
+		}
+		endpoint = next
+	}
+	return names, nil
+}
+
+// DefaultBranch returns the default branch of a repository
+func (c *Client) DefaultBranch(ctx context.Context, owner, repo string) (string, error) {
+	var repository struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	if err := c.getJSON(ctx, fmt.Sprintf("%s/repos/%s/%s", c.baseURL, url.PathEscape(owner), url.PathEscape(repo)), &repository); err != nil {
+		return "", fmt.Errorf("failed to get repository %s/%s: %w", owner, repo, err)
+	}
+	return repository.DefaultBranch, nil
+}
+
+// BranchHead returns the commit a branch points to
+func (c *Client) BranchHead(ctx context.Context, owner, repo, branch string) (string, error) {
+	var ref struct {
+		Object struct {
+			SHA string `json:"sha"`
+		} `json:"object"`
+	}
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/git/ref/heads/%s", c.baseURL, url.PathEscape(owner), url.PathEscape(repo), escapePath(branch))
+	if err := c.getJSON(ctx, endpoint, &ref); err != nil {
+		return "", fmt.Errorf("failed to get branch %s: %w", branch, err)
+	}
+	return ref.Object.SHA, nil
+}
+
+// CreateTag creates an annotated tag object and the reference pointing to
+// it. The API cannot sign tags: signed tags are made with git in a clone.
+func (c *Client) CreateTag(ctx context.Context, owner, repo string, opts TagOptions) (*Tag, error) {
+	base := fmt.Sprintf("%s/repos/%s/%s/git", c.baseURL, url.PathEscape(owner), url.PathEscape(repo))
+
+	body := map[string]interface{}{
+		"tag":     opts.Name,
+		"message": opts.Message,
+		"object":  opts.SHA,
+		"type":    "commit",
+	}
+	if opts.Tagger != nil {
+		body["tagger"] = opts.Tagger
+	}
+	var tag Tag
+	if err := c.sendJSON(ctx, http.MethodPost, base+"/tags", body, &tag); err != nil {
+		return nil, fmt.Errorf("failed to create tag %s: %w", opts.Name, err)
+	}
+
+	if err := c.sendJSON(ctx, http.MethodPost, base+"/refs", map[string]string{
+		"ref": "refs/tags/" + opts.Name,
+		"sha": tag.SHA,
+	}, nil); err != nil {
+		return nil, fmt.Errorf("failed to create reference of tag %s: %w", opts.Name, err)
+	}
+	return &tag, nil
+}
+
+// CreateLightweightTag creates a tag reference pointing to a commit, without
+// a tag object
+func (c *Client) CreateLightweightTag(ctx context.Context, owner, repo, name, sha string) error {
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/git/refs", c.baseURL, url.PathEscape(owner), url.PathEscape(repo))
+	if err := c.sendJSON(ctx, http.MethodPost, endpoint, map[string]string{
+		"ref": "refs/tags/" + name,
+		"sha": sha,
+	}, nil); err != nil {
+		return fmt.Errorf("failed to create tag %s: %w", name, err)
+	}
+	return nil
+}
+
+// TagCommit returns the commit a tag points to, through its tag object for
+// annotated tags; ErrNotFound when the tag does not exist
+func (c *Client) TagCommit(ctx context.Context, owner, repo, name string) (string, error) {
+	var ref struct {
+		Object struct {
+			Type string `json:"type"`
+			SHA  string `json:"sha"`
+		} `json:"object"`
+	}
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/git/ref/tags/%s", c.baseURL, url.PathEscape(owner), url.PathEscape(repo), escapePath(name))
+	if err := c.getJSON(ctx, endpoint, &ref); err != nil {
+		return "", fmt.Errorf("failed to get tag %s: %w", name, err)
+	}
+	if ref.Object.Type != "tag" {
+		return ref.Object.SHA, nil
+	}
+
+	var tag struct {
+		Object struct {
+			SHA string `json:"sha"`
+		} `json:"object"`
+	}
+	endpoint = fmt.Sprintf("%s/repos/%s/%s/git/tags/%s", c.baseURL, url.PathEscape(owner), url.PathEscape(repo), url.PathEscape(ref.Object.SHA))
+	if err := c.getJSON(ctx, endpoint, &tag); err != nil {
+		return "", fmt.Errorf("failed to get tag %s: %w", name, err)
+	}
+	return tag.Object.SHA, nil
+}
+
+// ResolveCommit returns the commit a branch, tag or commit points to;
+// ErrNotFound when it does not exist
+func (c *Client) ResolveCommit(ctx context.Context, owner, repo, ref string) (string, error) {
+	var commit struct {
+		SHA string `json:"sha"`
+	}
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/commits/%s", c.baseURL, url.PathEscape(owner), url.PathEscape(repo), escapePath(ref))
+	if err := c.getJSON(ctx, endpoint, &commit); err != nil {
+		return "", fmt.Errorf("failed to get commit %s: %w", ref, err)
+	}
+	return commit.SHA, nil
+}
+
+// FileUpdate is a change of a file committed to a branch
+type FileUpdate struct {
+	Path    string
+	Branch  string
+	Message string
+	Content []byte
+}
+
+// GetFile returns the content of a file at the given ref; ErrNotFound when
+// it does not exist
+func (c *Client) GetFile(ctx context.Context, owner, repo, ref, path string) ([]byte, error) {
+	file, err := c.getFile(ctx, owner, repo, ref, path)
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(strings.ReplaceAll(file.Content, "\n", ""))
+}
+
+// UpdateFile commits a new content of a file, creating it when it does not
+// exist, and returns the commit made
+func (c *Client) UpdateFile(ctx context.Context, owner, repo string, update FileUpdate) (string, error) {
+	body := map[string]string{
+		"message": update.Message,
+		"content": base64.StdEncoding.EncodeToString(update.Content),
+		"branch":  update.Branch,
+	}
+	// Updates name the blob replaced, so a concurrent change fails
+	file, err := c.getFile(ctx, owner, repo, update.Branch, update.Path)
+	switch {
+	case err == nil:
+		body["sha"] = file.SHA
+	case !errors.Is(err, ErrNotFound):
+		return "", err
+	}
+
+	var result struct {
+		Commit struct {
+			SHA string `json:"sha"`
+		} `json:"commit"`
+	}
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/contents/%s", c.baseURL, url.PathEscape(owner), url.PathEscape(repo), escapePath(update.Path))
+	if err := c.sendJSON(ctx, http.MethodPut, endpoint, body, &result); err != nil {
+		return "", fmt.Errorf("failed to commit %s to branch %s: %w", update.Path, update.Branch, err)
+	}
+	return result.Commit.SHA, nil
+}
+
+// contentFile is a file as returned by the contents API
+type contentFile struct {
+	SHA     string `json:"sha"`
+	Content string `json:"content"`
+}
+
+func (c *Client) getFile(ctx context.Context, owner, repo, ref, path string) (*contentFile, error) {
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/contents/%s", c.baseURL, url.PathEscape(owner), url.PathEscape(repo), escapePath(path))
+	if ref != "" {
+		endpoint += "?ref=" + url.QueryEscape(ref)
+	}
+	var file contentFile
+	if err := c.getJSON(ctx, endpoint, &file); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// sendJSON sends body as JSON with the given method and decodes the JSON
+// response into v, unless v is nil. Writes are not retried, as a request
+// that timed out may have been applied anyway.
+func (c *Client) sendJSON(ctx context.Context, method, endpoint string, body, v interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := c.newRequest(ctx, method, endpoint)
+	if err != nil {
+		return err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(payload))
+	req.ContentLength = int64(len(payload))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent {
+		if err := c.rateLimitError(resp); err != nil {
+			return err
+		}
+		err := fmt.Errorf("GitHub API returned status %d", resp.StatusCode)
+		// Validation errors explain themselves, e.g. "Reference already
+		// exists"
+		var apiError struct {
+			Message string `json:"message"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&apiError) == nil && apiError.Message != "" {
+			err = fmt.Errorf("GitHub API returned status %d: %s", resp.StatusCode, apiError.Message)
+		}
+		if resp.StatusCode == http.StatusConflict {
+			return fmt.Errorf("%w: %w", ErrConflict, err)
+		}
+		return err
+	}
+
+	if v == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response of %s: %w", req.URL.Path, err)
+	}
+	return nil
+}
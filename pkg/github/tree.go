@@ -0,0 +1,200 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContentEntry is a file or directory of a repository
+type ContentEntry struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+	// Type is file, dir, symlink or submodule
+	Type string `json:"type"`
+	Size int64  `json:"size"`
+}
+
+// Commit is a commit as returned by the commits API
+type Commit struct {
+	SHA    string `json:"sha"`
+	Commit struct {
+		Author struct {
+			Name string    `json:"name"`
+			Date time.Time `json:"date"`
+		} `json:"author"`
+		Message string `json:"message"`
+	} `json:"commit"`
+}
+
+// Title returns the first line of the commit message
+func (c *Commit) Title() string {
+	title, _, _ := strings.Cut(c.Commit.Message, "\n")
+	return title
+}
+
+// ListContents lists the entries of a directory at the given ref. An empty
+// ref means the default branch.
+func (c *Client) ListContents(ctx context.Context, owner, repo, ref, path string) ([]ContentEntry, error) {
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/contents/%s", c.baseURL, owner, repo, escapePath(path))
+	if ref != "" {
+		endpoint += "?ref=" + url.QueryEscape(ref)
+	}
+
+	// The contents API returns an object instead of a list for files
+	var raw json.RawMessage
+	if err := c.getJSON(ctx, endpoint, &raw); err != nil {
+		return nil, err
+	}
+
+	var entries []ContentEntry
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		var entry ContentEntry
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			return nil, fmt.Errorf("failed to decode contents: %w", err)
+		}
+		entries = []ContentEntry{entry}
+	}
+	return entries, nil
+}
+
+// ListTree lists every entry of the repository at the given ref, recursively.
+// Entries use the git tree types: blob, tree or commit (submodules).
+func (c *Client) ListTree(ctx context.Context, owner, repo, ref string) ([]ContentEntry, bool, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/git/trees/%s?recursive=1", c.baseURL, owner, repo, url.PathEscape(ref))
+
+	var tree struct {
+		Tree []struct {
+			Path string `json:"path"`
+			Type string `json:"type"`
+			Size int64  `json:"size"`
+		} `json:"tree"`
+		Truncated bool `json:"truncated"`
+	}
+	if err := c.getJSON(ctx, endpoint, &tree); err != nil {
+		return nil, false, err
+	}
+
+	entries := make([]ContentEntry, 0, len(tree.Tree))
+	for _, node := range tree.Tree {
+		name := node.Path[strings.LastIndex(node.Path, "/")+1:]
+		entries = append(entries, ContentEntry{Name: name, Path: node.Path, Type: node.Type, Size: node.Size})
+	}
+	return entries, tree.Truncated, nil
+}
+
+// GetLastCommit returns the last commit that touched path at the given ref
+func (c *Client) GetLastCommit(ctx context.Context, owner, repo, ref, path string) (*Commit, error) {
+	query := url.Values{"path": {path}, "per_page": {"1"}}
+	if ref != "" {
+		query.Set("sha", ref)
+	}
+	endpoint := fmt.Sprintf("%s/repos/%s/%s/commits?%s", c.baseURL, owner, repo, query.Encode())
+
+	var commits []Commit
+	if err := c.getJSON(ctx, endpoint, &commits); err != nil {
+		return nil, err
+	}
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("no commits found for %s", path)
+	}
+	return &commits[0], nil
+}
+
+// getJSON performs a GET request and decodes the JSON response into v
+func (c *Client) getJSON(ctx context.Context, endpoint string, v interface{}) error {
+	_, err := c.GetPage(ctx, endpoint, v)
+	return err
+}
+
+// GetJSON performs a GET request against the API and decodes the JSON
+// response into v. Endpoints starting with / are relative to the base URL,
+// e.g. /repos/openshift/hypershift.
+func (c *Client) GetJSON(ctx context.Context, endpoint string, v interface{}) error {
+	return c.getJSON(ctx, endpoint, v)
+}
+
+// GetPage is GetJSON for paginated endpoints: it also returns the URL of the
+// next page, from the Link header, or an empty string for the last page
+func (c *Client) GetPage(ctx context.Context, endpoint string, v interface{}) (string, error) {
+	if strings.HasPrefix(endpoint, "/") {
+		endpoint = c.baseURL + endpoint
+	}
+	req, err := c.newRequest(ctx, http.MethodGet, endpoint)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		// Unknown commits are unprocessable rather than not found, e.g. for
+		// /commits/{ref}
+		if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusUnprocessableEntity {
+			return "", fmt.Errorf("%w: %s", ErrNotFound, req.URL.Path)
+		}
+		if err := c.rateLimitError(resp); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("GitHub API returned status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return "", fmt.Errorf("failed to decode response of %s: %w", req.URL.Path, err)
+	}
+	return nextPage(resp.Header.Get("Link")), nil
+}
+
+// rateLimitError returns ErrRateLimited, with the time the limit resets,
+// when the response reports the rate limit is exhausted
+func (c *Client) rateLimitError(resp *http.Response) error {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return nil
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return nil
+	}
+
+	err := ErrRateLimited
+	if reset, parseErr := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); parseErr == nil {
+		err = fmt.Errorf("%w until %s", ErrRateLimited, time.Unix(reset, 0).Format(time.Kitchen))
+	}
+	if c.token == "" {
+		return fmt.Errorf("%w; authenticated requests have a higher limit, set a GitHub token", err)
+	}
+	return err
+}
+
+// nextPage returns the URL of the next page from a Link header, e.g.
+// <https://api.github.com/...&page=2>; rel="next", <...>; rel="last"
+func nextPage(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		target, params, ok := strings.Cut(strings.TrimSpace(part), ";")
+		if !ok || !strings.Contains(params, `rel="next"`) {
+			continue
+		}
+		return strings.Trim(strings.TrimSpace(target), "<>")
+	}
+	return ""
+}
+
+// escapePath escapes each segment of a repository path
+func escapePath(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
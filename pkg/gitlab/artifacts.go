@@ -0,0 +1,45 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// GetJobArtifact downloads a single file from the artifacts of the given job
+// in the latest successful pipeline for the configured branch or tag
+func (c *Client) GetJobArtifact(ctx context.Context, job, artifactPath string) ([]byte, error) {
+	owner, name := c.config.GetRepositoryOwnerAndName()
+	if owner == "" || name == "" {
+		return nil, fmt.Errorf("invalid repository path: %s", c.config.RepositoryPath)
+	}
+
+	var content []byte
+	resp, err := c.withRetry(ctx, func() (*gitlab.Response, error) {
+		reader, resp, err := c.client.Jobs.DownloadSingleArtifactsFileByTagOrBranch(
+			owner+"/"+name,
+			c.config.Branch,
+			artifactPath,
+			&gitlab.DownloadArtifactsFileOptions{
+				Job: &job,
+			},
+			gitlab.WithContext(ctx),
+		)
+		if err != nil {
+			return resp, err
+		}
+		content, err = io.ReadAll(reader)
+		return resp, err
+	})
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("artifact not found: %s in job %s on ref %s (the job must have succeeded in the latest pipeline)", artifactPath, job, c.config.Branch)
+		}
+		return nil, fmt.Errorf("failed to download artifact: %w", err)
+	}
+
+	return content, nil
+}
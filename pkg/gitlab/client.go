@@ -2,84 +2,198 @@ package gitlab
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"time"
 
 	"drivio/pkg/config"
+	"drivio/pkg/httplog"
+	"drivio/pkg/retry"
 
 	gitlab "gitlab.com/gitlab-org/api/client-go"
 )
 
+// ErrFileNotFound is returned when a file does not exist in the requested branch
+var ErrFileNotFound = errors.New("file not found")
+
 // Client represents a GitLab client
 type Client struct {
-	client *gitlab.Client
-	config *config.Config
+	client      *gitlab.Client
+	config      *config.Config
+	retryPolicy retry.Policy
 }
 
 // NewClient creates a new GitLab client
 func NewClient(cfg *config.Config) (*Client, error) {
-	var client *gitlab.Client
-	var err error
-
-	// For public repositories, we can create a client without token
-	if cfg.IsPublicRepository() && cfg.GitLabToken == "" {
-		client, err = gitlab.NewClient("", gitlab.WithBaseURL(cfg.GitLabURL))
-	} else {
-		client, err = gitlab.NewClient(cfg.GitLabToken, gitlab.WithBaseURL(cfg.GitLabURL))
+	// Retries are handled by our own policy (see withRetry), which also
+	// covers timeouts and dropped connections
+	options := []gitlab.ClientOptionFunc{
+		gitlab.WithBaseURL(cfg.GitLabURL),
+		gitlab.WithoutRetries(),
+		gitlab.WithHTTPClient(&http.Client{Transport: httplog.NewTransport(nil)}),
 	}
 
+	// An empty token makes anonymous requests, enough for public repositories
+	client, err := gitlab.NewClient(cfg.GitLabToken, options...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create GitLab client: %w", err)
 	}
 
+	policy := retry.DefaultPolicy()
+	policy.MaxAttempts = cfg.RetryAttempts
+	policy.InitialBackoff = cfg.RetryBackoff
+
 	return &Client{
-		client: client,
-		config: cfg,
+		client:      client,
+		config:      cfg,
+		retryPolicy: policy,
 	}, nil
 }
 
+// SetRetryNotifier registers a callback invoked before each retry
+func (c *Client) SetRetryNotifier(notify func(attempt int, err error, wait time.Duration)) {
+	c.retryPolicy.OnRetry = notify
+}
+
+// withRetry runs a GitLab API call under the client's retry policy
+func (c *Client) withRetry(ctx context.Context, call func() (*gitlab.Response, error)) (*gitlab.Response, error) {
+	var resp *gitlab.Response
+	err := retry.Do(ctx, c.retryPolicy, func() (*http.Response, error) {
+		var err error
+		resp, err = call()
+		if resp == nil {
+			return nil, err
+		}
+		return resp.Response, err
+	})
+	return resp, err
+}
+
 // GetFile retrieves a file from a GitLab repository
 func (c *Client) GetFile(ctx context.Context) ([]byte, error) {
+	return c.GetFileByPath(ctx, c.config.FilePath)
+}
+
+// GetFileByPath retrieves the file at the given path from the configured
+// repository and branch
+func (c *Client) GetFileByPath(ctx context.Context, path string) ([]byte, error) {
+	content, _, err := c.getFile(ctx, path)
+	return content, err
+}
+
+// getFile retrieves the content and the metadata of the file at the given
+// path from the configured repository and branch
+func (c *Client) getFile(ctx context.Context, path string) ([]byte, *gitlab.File, error) {
 	owner, name := c.config.GetRepositoryOwnerAndName()
 	if owner == "" || name == "" {
-		return nil, fmt.Errorf("invalid repository path: %s", c.config.RepositoryPath)
+		return nil, nil, fmt.Errorf("invalid repository path: %s", c.config.RepositoryPath)
 	}
 
 	// Get the file content
-	file, resp, err := c.client.RepositoryFiles.GetFile(
-		owner+"/"+name,
-		c.config.FilePath,
-		&gitlab.GetFileOptions{
-			Ref: &c.config.Branch,
-		},
-		gitlab.WithContext(ctx),
-	)
+	var file *gitlab.File
+	resp, err := c.withRetry(ctx, func() (*gitlab.Response, error) {
+		var resp *gitlab.Response
+		var err error
+		file, resp, err = c.client.RepositoryFiles.GetFile(
+			owner+"/"+name,
+			path,
+			&gitlab.GetFileOptions{
+				Ref: &c.config.Branch,
+			},
+			gitlab.WithContext(ctx),
+		)
+		return resp, err
+	})
 	if err != nil {
 		if resp != nil && resp.StatusCode == http.StatusNotFound {
-			return nil, fmt.Errorf("file not found: %s in branch %s", c.config.FilePath, c.config.Branch)
+			return nil, nil, c.fileNotFound(ctx, path)
 		}
-		return nil, fmt.Errorf("failed to get file: %w", err)
+		return nil, nil, fmt.Errorf("failed to get file: %w", err)
 	}
 
-	// The content is already decoded in the File struct
-	return []byte(file.Content), nil
+	// The API returns the content base64-encoded
+	if file.Encoding == "base64" {
+		content, err := base64.StdEncoding.DecodeString(file.Content)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decode file content: %w", err)
+		}
+		return content, file, nil
+	}
+
+	return []byte(file.Content), file, nil
+}
+
+// fileNotFound returns the error of a file request answered with 404, which
+// GitLab also answers when the project does not exist: ErrNotFound when the
+// project cannot be found, so callers skipping missing files do not skip it,
+// and ErrFileNotFound otherwise, when the file or the branch does not exist
+func (c *Client) fileNotFound(ctx context.Context, path string) error {
+	resp, err := c.withRetry(ctx, func() (*gitlab.Response, error) {
+		_, resp, err := c.client.Projects.GetProject(c.config.RepositoryPath, nil, gitlab.WithContext(ctx))
+		return resp, err
+	})
+	if err != nil && resp != nil && resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: project %s", ErrNotFound, c.config.RepositoryPath)
+	}
+	return fmt.Errorf("%w: %s in branch %s", ErrFileNotFound, path, c.config.Branch)
 }
 
-// ValidateConnection tests the connection to GitLab
+// GetFileMetadata retrieves the metadata of a file (blob ID, last commit,
+// content SHA-256) without downloading its content
+func (c *Client) GetFileMetadata(ctx context.Context) (*gitlab.File, error) {
+	owner, name := c.config.GetRepositoryOwnerAndName()
+	if owner == "" || name == "" {
+		return nil, fmt.Errorf("invalid repository path: %s", c.config.RepositoryPath)
+	}
+
+	var file *gitlab.File
+	resp, err := c.withRetry(ctx, func() (*gitlab.Response, error) {
+		var resp *gitlab.Response
+		var err error
+		file, resp, err = c.client.RepositoryFiles.GetFileMetaData(
+			owner+"/"+name,
+			c.config.FilePath,
+			&gitlab.GetFileMetaDataOptions{
+				Ref: &c.config.Branch,
+			},
+			gitlab.WithContext(ctx),
+		)
+		return resp, err
+	})
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, c.fileNotFound(ctx, c.config.FilePath)
+		}
+		return nil, fmt.Errorf("failed to get file metadata: %w", err)
+	}
+
+	return file, nil
+}
+
+// ValidateConnection tests the connection to GitLab and checks that the
+// token can read repositories
 func (c *Client) ValidateConnection(ctx context.Context) error {
 	// For public repositories without token, skip user validation
 	if c.config.IsPublicRepository() && c.config.GitLabToken == "" {
 		return nil
 	}
 
-	_, resp, err := c.client.Users.CurrentUser(gitlab.WithContext(ctx))
+	resp, err := c.withRetry(ctx, func() (*gitlab.Response, error) {
+		_, resp, err := c.client.Users.CurrentUser(gitlab.WithContext(ctx))
+		return resp, err
+	})
 	if err != nil {
 		if resp != nil && resp.StatusCode == http.StatusUnauthorized {
 			return fmt.Errorf("invalid GitLab token or insufficient permissions")
 		}
 		return fmt.Errorf("failed to validate GitLab connection: %w", err)
 	}
-	return nil
+
+	// A valid token may still lack the scopes needed to read repositories
+	return c.CheckTokenScopes(ctx)
 }
 
 // GetRepositoryInfo retrieves basic information about the repository
@@ -89,11 +203,17 @@ func (c *Client) GetRepositoryInfo(ctx context.Context) (*gitlab.Project, error)
 		return nil, fmt.Errorf("invalid repository path: %s", c.config.RepositoryPath)
 	}
 
-	project, resp, err := c.client.Projects.GetProject(
-		owner+"/"+name,
-		nil,
-		gitlab.WithContext(ctx),
-	)
+	var project *gitlab.Project
+	resp, err := c.withRetry(ctx, func() (*gitlab.Response, error) {
+		var resp *gitlab.Response
+		var err error
+		project, resp, err = c.client.Projects.GetProject(
+			owner+"/"+name,
+			nil,
+			gitlab.WithContext(ctx),
+		)
+		return resp, err
+	})
 	if err != nil {
 		if resp != nil && resp.StatusCode == http.StatusNotFound {
 			return nil, fmt.Errorf("repository not found: %s", c.config.RepositoryPath)
@@ -103,3 +223,31 @@ func (c *Client) GetRepositoryInfo(ctx context.Context) (*gitlab.Project, error)
 
 	return project, nil
 }
+
+// DownloadArchive downloads the tar.gz archive of the repository at the
+// configured branch, tag or commit and writes it to w
+func (c *Client) DownloadArchive(ctx context.Context, w io.Writer) error {
+	owner, name := c.config.GetRepositoryOwnerAndName()
+	if owner == "" || name == "" {
+		return fmt.Errorf("invalid repository path: %s", c.config.RepositoryPath)
+	}
+
+	format := "tar.gz"
+	resp, err := c.client.Repositories.StreamArchive(
+		owner+"/"+name,
+		w,
+		&gitlab.ArchiveOptions{
+			Format: &format,
+			SHA:    &c.config.Branch,
+		},
+		gitlab.WithContext(ctx),
+	)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("repository or ref not found: %s@%s", c.config.RepositoryPath, c.config.Branch)
+		}
+		return fmt.Errorf("failed to download archive: %w", err)
+	}
+
+	return nil
+}
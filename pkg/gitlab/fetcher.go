@@ -1,88 +1,57 @@
 package gitlab
 
 import (
+	"bytes"
 	"context"
-	"time"
+	"fmt"
+	"io"
+	"net/http"
 
 	"drivio/pkg/ui"
 
-	tea "github.com/charmbracelet/bubbletea"
+	gitlab "gitlab.com/gitlab-org/api/client-go"
 )
 
-// FetchWithProgress fetches a file with a progress bar
-func (c *Client) FetchWithProgress(ctx context.Context) ([]byte, error) {
-	progressBar := ui.NewProgressBar()
-	program := tea.NewProgram(&progressBar)
-
-	var result []byte
-	var fetchError error
-
-	// Start the progress bar in a goroutine
-	go func() {
-		progress := 0.0
-
-		// Step 1: Validating connection (20%)
-		program.Send(ui.ProgressMsg{Progress: 0.0, Message: "Validating connection..."})
-		time.Sleep(300 * time.Millisecond)
-		progress = 0.2
-		program.Send(ui.ProgressMsg{Progress: progress, Message: "Connection validated"})
-
-		// Step 2: Getting repository info (40%)
-		program.Send(ui.ProgressMsg{Progress: 0.3, Message: "Getting repository information..."})
-		time.Sleep(200 * time.Millisecond)
-		progress = 0.4
-		program.Send(ui.ProgressMsg{Progress: progress, Message: "Repository info obtained"})
-
-		// Step 3: Fetching file (80%)
-		program.Send(ui.ProgressMsg{Progress: progress, Message: "Fetching file..."})
-
-		// Start the actual fetch in a separate goroutine
-		fetchDone := make(chan error, 1)
-		go func() {
-			content, err := c.GetFile(ctx)
-			if err != nil {
-				fetchDone <- err
-				return
-			}
-			result = content
-			fetchDone <- nil
-		}()
+// FetchWithProgress fetches the configured file, reporting the bytes
+// downloaded out of the file size on the progress channel
+func (c *Client) FetchWithProgress(ctx context.Context, progress chan<- ui.ProgressMsg) ([]byte, error) {
+	owner, name := c.config.GetRepositoryOwnerAndName()
+	if owner == "" || name == "" {
+		return nil, fmt.Errorf("invalid repository path: %s", c.config.RepositoryPath)
+	}
 
-		// Simulate progress during fetch (50% to 80%)
-		for progress < 0.8 {
-			time.Sleep(400 * time.Millisecond)
-			progress += 0.05
-			if progress > 0.8 {
-				progress = 0.8
-			}
-			program.Send(ui.ProgressMsg{Progress: progress, Message: "Fetching file..."})
-		}
+	// The size comes from the metadata; the raw endpoint may not send a
+	// Content-Length
+	progress <- ui.ProgressMsg{Message: "Getting file size..."}
+	metadata, err := c.GetFileMetadata(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-		// Wait for fetch to complete
-		err := <-fetchDone
+	var content bytes.Buffer
+	resp, err := c.withRetry(ctx, func() (*gitlab.Response, error) {
+		req, err := c.client.NewRequest(
+			http.MethodGet,
+			fmt.Sprintf("projects/%s/repository/files/%s/raw", gitlab.PathEscape(owner+"/"+name), gitlab.PathEscape(c.config.FilePath)),
+			&gitlab.GetRawFileOptions{Ref: &c.config.Branch},
+			[]gitlab.RequestOptionFunc{gitlab.WithContext(ctx)},
+		)
 		if err != nil {
-			fetchError = err
-			program.Send(ui.CompleteMsg{Error: err})
-			return
+			return nil, err
 		}
 
-		// Step 4: Saving file (100%)
-		program.Send(ui.ProgressMsg{Progress: 0.9, Message: "Saving file..."})
-		time.Sleep(200 * time.Millisecond)
-		program.Send(ui.ProgressMsg{Progress: 1.0, Message: "File fetched successfully!"})
-		time.Sleep(300 * time.Millisecond)
-
-		program.Send(ui.CompleteMsg{Error: nil})
-	}()
-
-	err := ui.RunProgressBar(program)
+		// Start over on every attempt
+		content.Reset()
+		counter := ui.NewCountingWriter(progress, int64(metadata.Size))
+		defer counter.Flush()
+		return c.client.Do(req, io.MultiWriter(&content, counter))
+	})
 	if err != nil {
-		return nil, err
-	}
-
-	if fetchError != nil {
-		return nil, fetchError
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, c.fileNotFound(ctx, c.config.FilePath)
+		}
+		return nil, fmt.Errorf("failed to get file: %w", err)
 	}
 
-	return result, nil
+	return content.Bytes(), nil
 }
@@ -0,0 +1,49 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// ListGroupProjects lists the projects of a group, optionally including the
+// projects of its subgroups and archived projects
+func (c *Client) ListGroupProjects(ctx context.Context, group string, includeSubgroups, includeArchived bool) ([]*gitlab.Project, error) {
+	opts := &gitlab.ListGroupProjectsOptions{
+		ListOptions:      gitlab.ListOptions{PerPage: 100, Page: 1},
+		IncludeSubGroups: &includeSubgroups,
+		Simple:           gitlab.Ptr(true),
+		OrderBy:          gitlab.Ptr("path"),
+		Sort:             gitlab.Ptr("asc"),
+	}
+	if !includeArchived {
+		opts.Archived = gitlab.Ptr(false)
+	}
+
+	var projects []*gitlab.Project
+	for {
+		var page []*gitlab.Project
+		resp, err := c.withRetry(ctx, func() (*gitlab.Response, error) {
+			var resp *gitlab.Response
+			var err error
+			page, resp, err = c.client.Groups.ListGroupProjects(group, opts, gitlab.WithContext(ctx))
+			return resp, err
+		})
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return nil, fmt.Errorf("group not found: %s", group)
+			}
+			return nil, fmt.Errorf("failed to list group projects: %w", err)
+		}
+
+		projects = append(projects, page...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return projects, nil
+}
@@ -0,0 +1,222 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// FileRevision is a file of the configured branch with the last commit that
+// changed it, to update it without overwriting a concurrent change
+type FileRevision struct {
+	Path         string
+	Content      []byte
+	LastCommitID string
+}
+
+// GetFileRevision retrieves the file at the given path from the configured
+// repository and branch, with its last commit
+func (c *Client) GetFileRevision(ctx context.Context, path string) (*FileRevision, error) {
+	content, file, err := c.getFile(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return &FileRevision{Path: path, Content: content, LastCommitID: file.LastCommitID}, nil
+}
+
+// FileCommit is a commit changing files
+type FileCommit struct {
+	// Branch receives the commit. With StartBranch it is created from
+	// StartBranch, and must not exist yet.
+	Branch      string
+	StartBranch string
+	Message     string
+	// Files are the new contents of the files. A file whose LastCommitID is
+	// set makes the commit fail if it changed since.
+	Files []FileRevision
+	// Created are the paths of the files that do not exist yet
+	Created []string
+}
+
+// CommitFiles commits a change of files to the configured repository. Unlike
+// reads, the call is not retried, as a commit that timed out may have been
+// made anyway.
+func (c *Client) CommitFiles(ctx context.Context, commit FileCommit) (*gitlab.Commit, error) {
+	owner, name := c.config.GetRepositoryOwnerAndName()
+	if owner == "" || name == "" {
+		return nil, fmt.Errorf("invalid repository path: %s", c.config.RepositoryPath)
+	}
+
+	created := make(map[string]bool, len(commit.Created))
+	for _, path := range commit.Created {
+		created[path] = true
+	}
+	actions := make([]*gitlab.CommitActionOptions, 0, len(commit.Files))
+	for _, file := range commit.Files {
+		action := &gitlab.CommitActionOptions{
+			Action:   gitlab.Ptr(gitlab.FileUpdate),
+			FilePath: gitlab.Ptr(file.Path),
+			Content:  gitlab.Ptr(string(file.Content)),
+		}
+		if created[file.Path] {
+			action.Action = gitlab.Ptr(gitlab.FileCreate)
+		}
+		if file.LastCommitID != "" {
+			action.LastCommitID = gitlab.Ptr(file.LastCommitID)
+		}
+		actions = append(actions, action)
+	}
+	opts := &gitlab.CreateCommitOptions{
+		Branch:        gitlab.Ptr(commit.Branch),
+		CommitMessage: gitlab.Ptr(commit.Message),
+		Actions:       actions,
+	}
+	if commit.StartBranch != "" {
+		opts.StartBranch = gitlab.Ptr(commit.StartBranch)
+	}
+
+	createdCommit, _, err := c.client.Commits.CreateCommit(owner+"/"+name, opts, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit to branch %s: %w", commit.Branch, err)
+	}
+	return createdCommit, nil
+}
+
+// MergeRequestOptions describes a merge request to open
+type MergeRequestOptions struct {
+	SourceBranch string
+	TargetBranch string
+	Title        string
+	// Description is Markdown, e.g. release notes
+	Description string
+	Labels      []string
+	// ReviewerIDs are the IDs of the users asked to review, see UserIDs
+	ReviewerIDs []int
+	// RemoveSourceBranch deletes the source branch once merged
+	RemoveSourceBranch bool
+}
+
+// CreateMergeRequest opens a merge request in the configured repository. Like
+// commits, it is not retried.
+func (c *Client) CreateMergeRequest(ctx context.Context, opts MergeRequestOptions) (*gitlab.MergeRequest, error) {
+	owner, name := c.config.GetRepositoryOwnerAndName()
+	if owner == "" || name == "" {
+		return nil, fmt.Errorf("invalid repository path: %s", c.config.RepositoryPath)
+	}
+
+	create := &gitlab.CreateMergeRequestOptions{
+		SourceBranch:       gitlab.Ptr(opts.SourceBranch),
+		TargetBranch:       gitlab.Ptr(opts.TargetBranch),
+		Title:              gitlab.Ptr(opts.Title),
+		Description:        gitlab.Ptr(opts.Description),
+		RemoveSourceBranch: gitlab.Ptr(opts.RemoveSourceBranch),
+	}
+	if len(opts.Labels) > 0 {
+		labels := gitlab.LabelOptions(opts.Labels)
+		create.Labels = &labels
+	}
+	if len(opts.ReviewerIDs) > 0 {
+		create.ReviewerIDs = gitlab.Ptr(opts.ReviewerIDs)
+	}
+
+	mr, _, err := c.client.MergeRequests.CreateMergeRequest(owner+"/"+name, create, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open merge request from %s to %s: %w", opts.SourceBranch, opts.TargetBranch, err)
+	}
+	return mr, nil
+}
+
+// UserIDs returns the IDs of the users with the given usernames
+func (c *Client) UserIDs(ctx context.Context, usernames []string) ([]int, error) {
+	ids := make([]int, 0, len(usernames))
+	for _, username := range usernames {
+		username = strings.TrimPrefix(username, "@")
+		var users []*gitlab.User
+		_, err := c.withRetry(ctx, func() (*gitlab.Response, error) {
+			var resp *gitlab.Response
+			var err error
+			users, resp, err = c.client.Users.ListUsers(&gitlab.ListUsersOptions{Username: gitlab.Ptr(username)}, gitlab.WithContext(ctx))
+			return resp, err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up user %s: %w", username, err)
+		}
+		if len(users) == 0 {
+			return nil, fmt.Errorf("unknown user %s", username)
+		}
+		ids = append(ids, users[0].ID)
+	}
+	return ids, nil
+}
+
+// MergedMergeRequests returns the merge requests of the configured
+// repository merged into a branch between two times, oldest first
+func (c *Client) MergedMergeRequests(ctx context.Context, branch string, since, until time.Time) ([]*gitlab.BasicMergeRequest, error) {
+	project, err := c.project()
+	if err != nil {
+		return nil, err
+	}
+
+	// Merge requests are updated when merged, at the latest
+	opts := &gitlab.ListProjectMergeRequestsOptions{
+		ListOptions:  gitlab.ListOptions{PerPage: 100},
+		State:        gitlab.Ptr("merged"),
+		TargetBranch: gitlab.Ptr(branch),
+		UpdatedAfter: gitlab.Ptr(since),
+	}
+	var merged []*gitlab.BasicMergeRequest
+	for {
+		var mrs []*gitlab.BasicMergeRequest
+		resp, err := c.withRetry(ctx, func() (*gitlab.Response, error) {
+			var resp *gitlab.Response
+			var err error
+			mrs, resp, err = c.client.MergeRequests.ListProjectMergeRequests(project, opts, gitlab.WithContext(ctx))
+			return resp, err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the merge requests merged into %s: %w", branch, err)
+		}
+		for _, mr := range mrs {
+			if mr.MergedAt != nil && !mr.MergedAt.Before(since) && !mr.MergedAt.After(until) {
+				merged = append(merged, mr)
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	sort.Slice(merged, func(i, j int) bool { return merged[i].MergedAt.Before(*merged[j].MergedAt) })
+	return merged, nil
+}
+
+// MergeRequestApprovers returns the usernames of the users who approved a
+// merge request of the configured repository
+func (c *Client) MergeRequestApprovers(ctx context.Context, iid int) ([]string, error) {
+	project, err := c.project()
+	if err != nil {
+		return nil, err
+	}
+	var approvals *gitlab.MergeRequestApprovals
+	if _, err := c.withRetry(ctx, func() (*gitlab.Response, error) {
+		var resp *gitlab.Response
+		var err error
+		approvals, resp, err = c.client.MergeRequestApprovals.GetConfiguration(project, iid, gitlab.WithContext(ctx))
+		return resp, err
+	}); err != nil {
+		return nil, fmt.Errorf("failed to get the approvals of merge request !%d: %w", iid, err)
+	}
+
+	approvers := []string{}
+	for _, approver := range approvals.ApprovedBy {
+		if approver.User != nil {
+			approvers = append(approvers, approver.User.Username)
+		}
+	}
+	return approvers, nil
+}
@@ -0,0 +1,75 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// FindMergeRequestNote returns the first note of a merge request of the
+// configured repository whose body contains the marker, e.g. a hidden HTML
+// comment identifying the notes of a tool; nil when none does
+func (c *Client) FindMergeRequestNote(ctx context.Context, iid int, marker string) (*gitlab.Note, error) {
+	project, err := c.project()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &gitlab.ListMergeRequestNotesOptions{
+		ListOptions: gitlab.ListOptions{PerPage: 100},
+		OrderBy:     gitlab.Ptr("created_at"),
+		Sort:        gitlab.Ptr("asc"),
+	}
+	for {
+		var notes []*gitlab.Note
+		resp, err := c.withRetry(ctx, func() (*gitlab.Response, error) {
+			var resp *gitlab.Response
+			var err error
+			notes, resp, err = c.client.Notes.ListMergeRequestNotes(project, iid, opts, gitlab.WithContext(ctx))
+			return resp, err
+		})
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return nil, fmt.Errorf("%w: merge request !%d in %s", ErrNotFound, iid, project)
+			}
+			return nil, fmt.Errorf("failed to list the notes of merge request !%d: %w", iid, err)
+		}
+
+		for _, note := range notes {
+			if !note.System && strings.Contains(note.Body, marker) {
+				return note, nil
+			}
+		}
+
+		if resp.NextPage == 0 {
+			return nil, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// PostMergeRequestNote comments on a merge request of the configured
+// repository, replacing the body of the note noteID instead when it is not
+// 0. Like commits, writes are not retried.
+func (c *Client) PostMergeRequestNote(ctx context.Context, iid, noteID int, body string) (*gitlab.Note, error) {
+	project, err := c.project()
+	if err != nil {
+		return nil, err
+	}
+
+	if noteID != 0 {
+		note, _, err := c.client.Notes.UpdateMergeRequestNote(project, iid, noteID, &gitlab.UpdateMergeRequestNoteOptions{Body: gitlab.Ptr(body)}, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("failed to update note %d of merge request !%d: %w", noteID, iid, err)
+		}
+		return note, nil
+	}
+	note, _, err := c.client.Notes.CreateMergeRequestNote(project, iid, &gitlab.CreateMergeRequestNoteOptions{Body: gitlab.Ptr(body)}, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to comment on merge request !%d: %w", iid, err)
+	}
+	return note, nil
+}
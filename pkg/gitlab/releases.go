@@ -0,0 +1,169 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"drivio/pkg/httplog"
+	"drivio/pkg/retry"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// GetReleaseAsset downloads the asset link with the given name attached to
+// the release for the given tag
+func (c *Client) GetReleaseAsset(ctx context.Context, tag, assetName string) ([]byte, error) {
+	owner, name := c.config.GetRepositoryOwnerAndName()
+	if owner == "" || name == "" {
+		return nil, fmt.Errorf("invalid repository path: %s", c.config.RepositoryPath)
+	}
+
+	link, err := c.findReleaseLink(ctx, owner+"/"+name, tag, assetName)
+	if err != nil {
+		return nil, err
+	}
+
+	assetURL := link.DirectAssetURL
+	if assetURL == "" {
+		assetURL = link.URL
+	}
+
+	return c.downloadURL(ctx, assetURL)
+}
+
+// PublishRelease sets the description of the release of a tag of the
+// configured repository, creating the release when the tag has none. The
+// description of an existing release is only replaced when empty, or when
+// overwrite is set. It returns the release and whether it was created or
+// changed. Like commits, writes are not retried.
+func (c *Client) PublishRelease(ctx context.Context, tag, description string, overwrite bool) (*gitlab.Release, bool, error) {
+	owner, name := c.config.GetRepositoryOwnerAndName()
+	if owner == "" || name == "" {
+		return nil, false, fmt.Errorf("invalid repository path: %s", c.config.RepositoryPath)
+	}
+	project := owner + "/" + name
+
+	var release *gitlab.Release
+	resp, err := c.withRetry(ctx, func() (*gitlab.Response, error) {
+		var resp *gitlab.Response
+		var err error
+		release, resp, err = c.client.Releases.GetRelease(project, tag, gitlab.WithContext(ctx))
+		return resp, err
+	})
+	switch {
+	case resp != nil && resp.StatusCode == http.StatusNotFound:
+		created, _, err := c.client.Releases.CreateRelease(project, &gitlab.CreateReleaseOptions{
+			TagName:     gitlab.Ptr(tag),
+			Name:        gitlab.Ptr(tag),
+			Description: gitlab.Ptr(description),
+		}, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to create release %s: %w", tag, err)
+		}
+		return created, true, nil
+	case err != nil:
+		return nil, false, fmt.Errorf("failed to get release %s: %w", tag, err)
+	case release.Description != "" && !overwrite:
+		return release, false, nil
+	}
+
+	// The name is sent as is: it would be cleared otherwise
+	updated, _, err := c.client.Releases.UpdateRelease(project, tag, &gitlab.UpdateReleaseOptions{
+		Name:        gitlab.Ptr(release.Name),
+		Description: gitlab.Ptr(description),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to update release %s: %w", tag, err)
+	}
+	return updated, true, nil
+}
+
+// findReleaseLink looks up a release asset link by name
+func (c *Client) findReleaseLink(ctx context.Context, project, tag, assetName string) (*gitlab.ReleaseLink, error) {
+	opts := &gitlab.ListReleaseLinksOptions{PerPage: 100, Page: 1}
+	var available []string
+
+	for {
+		var links []*gitlab.ReleaseLink
+		resp, err := c.withRetry(ctx, func() (*gitlab.Response, error) {
+			var resp *gitlab.Response
+			var err error
+			links, resp, err = c.client.ReleaseLinks.ListReleaseLinks(project, tag, opts, gitlab.WithContext(ctx))
+			return resp, err
+		})
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return nil, fmt.Errorf("release not found: %s", tag)
+			}
+			return nil, fmt.Errorf("failed to list release assets: %w", err)
+		}
+
+		for _, link := range links {
+			if link.Name == assetName {
+				return link, nil
+			}
+			available = append(available, link.Name)
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	if len(available) == 0 {
+		return nil, fmt.Errorf("release %s has no asset links", tag)
+	}
+	return nil, fmt.Errorf("asset %q not found in release %s (available: %s)", assetName, tag, strings.Join(available, ", "))
+}
+
+// downloadURL downloads the content at the given URL. The token is only sent
+// when the URL points to the configured GitLab instance.
+func (c *Client) downloadURL(ctx context.Context, rawURL string) ([]byte, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid asset URL %q: %w", rawURL, err)
+	}
+
+	sendToken := false
+	if base, err := url.Parse(c.config.GitLabURL); err == nil {
+		sendToken = c.config.GitLabToken != "" && strings.EqualFold(base.Host, target.Host)
+	}
+
+	httpClient := httplog.NewClient(5 * time.Minute)
+
+	var content []byte
+	err = retry.Do(ctx, c.retryPolicy, func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", "drivio")
+		if sendToken {
+			req.Header.Set("PRIVATE-TOKEN", c.config.GitLabToken)
+		}
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			return resp, fmt.Errorf("download returned status %d", resp.StatusCode)
+		}
+
+		content, err = io.ReadAll(resp.Body)
+		return resp, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", rawURL, err)
+	}
+
+	return content, nil
+}
@@ -0,0 +1,74 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// ReadScopes are the token scopes that allow reading repositories through
+// the API; any one of them is enough
+var ReadScopes = []string{"read_api", "api"}
+
+// ScopeError is returned when the token has none of the required scopes
+type ScopeError struct {
+	// Accepted are the scopes that would satisfy the check
+	Accepted []string
+	// Scopes are the scopes of the token
+	Scopes []string
+}
+
+func (e *ScopeError) Error() string {
+	scopes := strings.Join(e.Scopes, ", ")
+	if scopes == "" {
+		scopes = "none"
+	}
+	return fmt.Sprintf("GitLab token is missing the %s scope (token scopes: %s)", strings.Join(e.Accepted, " or "), scopes)
+}
+
+// CheckTokenScopes verifies that the token is active and has one of the
+// accepted scopes (ReadScopes when none are given). Tokens whose scopes
+// cannot be inspected, like OAuth or CI job tokens, or tokens of instances
+// without the personal access token self endpoint, are accepted.
+func (c *Client) CheckTokenScopes(ctx context.Context, accepted ...string) error {
+	if c.config.GitLabToken == "" {
+		return nil
+	}
+	if len(accepted) == 0 {
+		accepted = ReadScopes
+	}
+
+	var token *gitlab.PersonalAccessToken
+	resp, err := c.withRetry(ctx, func() (*gitlab.Response, error) {
+		var resp *gitlab.Response
+		var err error
+		token, resp, err = c.client.PersonalAccessTokens.GetSinglePersonalAccessToken(gitlab.WithContext(ctx))
+		return resp, err
+	})
+	if err != nil {
+		if resp != nil {
+			switch resp.StatusCode {
+			case http.StatusUnauthorized:
+				return fmt.Errorf("invalid, expired or revoked GitLab token")
+			case http.StatusForbidden, http.StatusNotFound:
+				return nil
+			}
+		}
+		return fmt.Errorf("failed to check GitLab token scopes: %w", err)
+	}
+
+	if token.Revoked || !token.Active {
+		return fmt.Errorf("GitLab token %q is revoked or expired", token.Name)
+	}
+	for _, scope := range token.Scopes {
+		for _, a := range accepted {
+			if scope == a {
+				return nil
+			}
+		}
+	}
+	return &ScopeError{Accepted: accepted, Scopes: token.Scopes}
+}
@@ -0,0 +1,93 @@
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// ErrNotFound is returned when a tag, a commit or a project does not exist
+var ErrNotFound = errors.New("not found")
+
+// ResolveCommit returns the commit a branch, tag or commit of the configured
+// repository points to; ErrNotFound when it does not exist
+func (c *Client) ResolveCommit(ctx context.Context, ref string) (string, error) {
+	project, err := c.project()
+	if err != nil {
+		return "", err
+	}
+
+	var commit *gitlab.Commit
+	resp, err := c.withRetry(ctx, func() (*gitlab.Response, error) {
+		var resp *gitlab.Response
+		var err error
+		commit, resp, err = c.client.Commits.GetCommit(project, ref, nil, gitlab.WithContext(ctx))
+		return resp, err
+	})
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("%w: commit %s in %s", ErrNotFound, ref, project)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get commit %s: %w", ref, err)
+	}
+	return commit.ID, nil
+}
+
+// TagCommit returns the commit a tag of the configured repository points
+// to; ErrNotFound when the tag does not exist
+func (c *Client) TagCommit(ctx context.Context, name string) (string, error) {
+	project, err := c.project()
+	if err != nil {
+		return "", err
+	}
+
+	var tag *gitlab.Tag
+	resp, err := c.withRetry(ctx, func() (*gitlab.Response, error) {
+		var resp *gitlab.Response
+		var err error
+		tag, resp, err = c.client.Tags.GetTag(project, name, gitlab.WithContext(ctx))
+		return resp, err
+	})
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("%w: tag %s in %s", ErrNotFound, name, project)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get tag %s: %w", name, err)
+	}
+	if tag.Commit == nil {
+		return tag.Target, nil
+	}
+	return tag.Commit.ID, nil
+}
+
+// CreateTag creates a tag of a commit of the configured repository:
+// annotated with the message, lightweight without one. The API cannot sign
+// tags. Like commits, writes are not retried.
+func (c *Client) CreateTag(ctx context.Context, name, sha, message string) (*gitlab.Tag, error) {
+	project, err := c.project()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &gitlab.CreateTagOptions{TagName: gitlab.Ptr(name), Ref: gitlab.Ptr(sha)}
+	if message != "" {
+		opts.Message = gitlab.Ptr(message)
+	}
+	tag, _, err := c.client.Tags.CreateTag(project, opts, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tag %s: %w", name, err)
+	}
+	return tag, nil
+}
+
+// project returns the path of the configured repository
+func (c *Client) project() (string, error) {
+	owner, name := c.config.GetRepositoryOwnerAndName()
+	if owner == "" || name == "" {
+		return "", fmt.Errorf("invalid repository path: %s", c.config.RepositoryPath)
+	}
+	return owner + "/" + name, nil
+}
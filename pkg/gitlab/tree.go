@@ -0,0 +1,111 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// ListTree lists the files and directories under path at the configured
+// branch, descending into subdirectories when recursive is set
+func (c *Client) ListTree(ctx context.Context, path string, recursive bool) ([]*gitlab.TreeNode, error) {
+	owner, name := c.config.GetRepositoryOwnerAndName()
+	if owner == "" || name == "" {
+		return nil, fmt.Errorf("invalid repository path: %s", c.config.RepositoryPath)
+	}
+
+	opts := &gitlab.ListTreeOptions{
+		ListOptions: gitlab.ListOptions{PerPage: 100, Page: 1},
+		Ref:         &c.config.Branch,
+		Recursive:   &recursive,
+	}
+	if path != "" {
+		opts.Path = &path
+	}
+
+	var nodes []*gitlab.TreeNode
+	for {
+		var page []*gitlab.TreeNode
+		resp, err := c.withRetry(ctx, func() (*gitlab.Response, error) {
+			var resp *gitlab.Response
+			var err error
+			page, resp, err = c.client.Repositories.ListTree(owner+"/"+name, opts, gitlab.WithContext(ctx))
+			return resp, err
+		})
+		if err != nil {
+			if resp != nil && resp.StatusCode == http.StatusNotFound {
+				return nil, fmt.Errorf("path not found: %s in branch %s", path, c.config.Branch)
+			}
+			return nil, fmt.Errorf("failed to list repository tree: %w", err)
+		}
+
+		nodes = append(nodes, page...)
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return nodes, nil
+}
+
+// GetFileSize returns the size in bytes of a file at the configured branch
+func (c *Client) GetFileSize(ctx context.Context, path string) (int64, error) {
+	owner, name := c.config.GetRepositoryOwnerAndName()
+	if owner == "" || name == "" {
+		return 0, fmt.Errorf("invalid repository path: %s", c.config.RepositoryPath)
+	}
+
+	var file *gitlab.File
+	_, err := c.withRetry(ctx, func() (*gitlab.Response, error) {
+		var resp *gitlab.Response
+		var err error
+		file, resp, err = c.client.RepositoryFiles.GetFileMetaData(
+			owner+"/"+name,
+			path,
+			&gitlab.GetFileMetaDataOptions{Ref: &c.config.Branch},
+			gitlab.WithContext(ctx),
+		)
+		return resp, err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get file metadata: %w", err)
+	}
+
+	return int64(file.Size), nil
+}
+
+// GetLastCommit returns the last commit that touched path at the configured
+// branch
+func (c *Client) GetLastCommit(ctx context.Context, path string) (*gitlab.Commit, error) {
+	owner, name := c.config.GetRepositoryOwnerAndName()
+	if owner == "" || name == "" {
+		return nil, fmt.Errorf("invalid repository path: %s", c.config.RepositoryPath)
+	}
+
+	var commits []*gitlab.Commit
+	_, err := c.withRetry(ctx, func() (*gitlab.Response, error) {
+		var resp *gitlab.Response
+		var err error
+		commits, resp, err = c.client.Commits.ListCommits(
+			owner+"/"+name,
+			&gitlab.ListCommitsOptions{
+				ListOptions: gitlab.ListOptions{PerPage: 1, Page: 1},
+				RefName:     &c.config.Branch,
+				Path:        &path,
+			},
+			gitlab.WithContext(ctx),
+		)
+		return resp, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last commit: %w", err)
+	}
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("no commits found for %s in branch %s", path, c.config.Branch)
+	}
+
+	return commits[0], nil
+}
@@ -0,0 +1,74 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"drivio/pkg/config"
+
+	gitlab "gitlab.com/gitlab-org/api/client-go"
+)
+
+// ProjectVisibility returns the visibility of the configured repository:
+// public, internal or private. The project API is queried anonymously
+// first, so public repositories are recognized without a token; when that
+// fails and a token is configured, the query is repeated with the token.
+// An empty visibility means the repository could not be seen at all, either
+// because it does not exist or because a (valid) token is required.
+func ProjectVisibility(ctx context.Context, cfg *config.Config) (string, error) {
+	anonymous := *cfg
+	anonymous.GitLabToken = ""
+
+	visible, err := lookupProject(ctx, &anonymous)
+	if err != nil {
+		return "", err
+	}
+	if visible != nil {
+		// Anything readable without a token is public, whatever the
+		// instance reports in the (reduced) anonymous response
+		return string(gitlab.PublicVisibility), nil
+	}
+
+	if cfg.GitLabToken == "" {
+		return "", nil
+	}
+
+	project, err := lookupProject(ctx, cfg)
+	if err != nil || project == nil {
+		return "", err
+	}
+	return string(project.Visibility), nil
+}
+
+// lookupProject gets the configured repository, returning nil when the
+// credentials of cfg don't allow seeing it
+func lookupProject(ctx context.Context, cfg *config.Config) (*gitlab.Project, error) {
+	c, err := NewClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, name := cfg.GetRepositoryOwnerAndName()
+	if owner == "" || name == "" {
+		return nil, fmt.Errorf("invalid repository path: %s", cfg.RepositoryPath)
+	}
+
+	var project *gitlab.Project
+	resp, err := c.withRetry(ctx, func() (*gitlab.Response, error) {
+		var resp *gitlab.Response
+		var err error
+		project, resp, err = c.client.Projects.GetProject(owner+"/"+name, nil, gitlab.WithContext(ctx))
+		return resp, err
+	})
+	if err != nil {
+		if resp != nil {
+			switch resp.StatusCode {
+			case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+				return nil, nil
+			}
+		}
+		return nil, fmt.Errorf("failed to check repository visibility: %w", err)
+	}
+	return project, nil
+}
@@ -0,0 +1,122 @@
+package grafana
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"drivio/pkg/httplog"
+)
+
+// Client is a minimal Grafana HTTP API client
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	// token is a service account token, or an API key of older versions
+	token string
+	// orgID selects the organization of the requests when not zero, for
+	// tokens of users belonging to several
+	orgID int64
+}
+
+// NewClient creates a client of the Grafana instance at baseURL
+func NewClient(baseURL, token string, orgID int64) *Client {
+	return &Client{
+		httpClient: httplog.NewClient(30 * time.Second),
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      token,
+		orgID:      orgID,
+	}
+}
+
+// Annotation is an event marked on the graphs of dashboards
+type Annotation struct {
+	// DashboardUID and PanelID restrict the annotation to a dashboard, and
+	// a panel of it; without them, it is an organization annotation, shown
+	// by the dashboards querying its tags
+	DashboardUID string
+	PanelID      int64
+	// Time is when the event happened, and TimeEnd when it ended for a
+	// region, when not zero
+	Time    time.Time
+	TimeEnd time.Time
+	Tags    []string
+	// Text is the description of the annotation, in HTML
+	Text string
+}
+
+// CreateAnnotation adds an annotation and returns its ID. Like other writes,
+// it is not retried.
+func (c *Client) CreateAnnotation(ctx context.Context, annotation Annotation) (int64, error) {
+	body := map[string]interface{}{
+		"time": annotation.Time.UnixMilli(),
+		"tags": annotation.Tags,
+		"text": annotation.Text,
+	}
+	if annotation.DashboardUID != "" {
+		body["dashboardUID"] = annotation.DashboardUID
+	}
+	if annotation.PanelID != 0 {
+		body["panelId"] = annotation.PanelID
+	}
+	if !annotation.TimeEnd.IsZero() {
+		body["timeEnd"] = annotation.TimeEnd.UnixMilli()
+	}
+
+	var result struct {
+		ID int64 `json:"id"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/api/annotations", body, &result); err != nil {
+		return 0, fmt.Errorf("failed to create the annotation: %w", err)
+	}
+	return result.ID, nil
+}
+
+// do sends a request with body as JSON and decodes the JSON response into v,
+// unless nil
+func (c *Client) do(ctx context.Context, method, path string, body, v interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "drivio")
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if c.orgID != 0 {
+		req.Header.Set("X-Grafana-Org-Id", fmt.Sprint(c.orgID))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// Grafana explains rejected requests, e.g. a dashboard not found
+		var apiError struct {
+			Message string `json:"message"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&apiError) == nil && apiError.Message != "" {
+			return fmt.Errorf("Grafana API returned status %d: %s", resp.StatusCode, apiError.Message)
+		}
+		return fmt.Errorf("Grafana API returned status %d", resp.StatusCode)
+	}
+
+	if v == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response of %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"drivio/pkg/fileutil"
+	"drivio/pkg/verify"
+)
+
+// DirName is the name of the history directory inside the work directory
+const DirName = "history"
+
+// indexFileName is the name of the index file inside the history directory
+const indexFileName = "index.json"
+
+// Entry records a single fetch
+type Entry struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	Repo        string    `json:"repo"`
+	Ref         string    `json:"ref"`
+	File        string    `json:"file"`
+	Environment string    `json:"environment,omitempty"` // set when resolved with --env
+	SHA256      string    `json:"sha256"`
+	Size        int       `json:"size"`
+	FetchedAt   time.Time `json:"fetched_at"`
+	Object      string    `json:"object"` // stored content, relative to the history directory
+}
+
+// Store is the versioned history of fetched files in a work directory.
+// Content is stored once per digest, so repeated fetches of unchanged
+// files don't use additional space.
+type Store struct {
+	dir string
+}
+
+// NewStore returns the history store for the given work directory
+func NewStore(workDir string) *Store {
+	return &Store{dir: filepath.Join(workDir, DirName)}
+}
+
+// Record stores the content and appends an entry to the index
+func (s *Store) Record(entry Entry, content []byte) (*Entry, error) {
+	entry.SHA256 = verify.SHA256(content)
+	entry.Size = len(content)
+	if entry.FetchedAt.IsZero() {
+		entry.FetchedAt = time.Now()
+	}
+	entry.ID = fmt.Sprintf("%s-%s", entry.FetchedAt.UTC().Format("20060102T150405Z"), entry.SHA256[:8])
+	entry.Object = filepath.Join("objects", entry.SHA256[:2], entry.SHA256+filepath.Ext(entry.File))
+
+	objectPath := filepath.Join(s.dir, entry.Object)
+	if _, err := os.Stat(objectPath); os.IsNotExist(err) {
+		if err := fileutil.WriteFileAtomic(objectPath, content, 0644, false); err != nil {
+			return nil, fmt.Errorf("failed to store history object: %w", err)
+		}
+	}
+
+	entries, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	entries = append(entries, entry)
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode history index: %w", err)
+	}
+	if err := fileutil.WriteFileAtomic(filepath.Join(s.dir, indexFileName), data, 0644, false); err != nil {
+		return nil, fmt.Errorf("failed to write history index: %w", err)
+	}
+
+	return &entry, nil
+}
+
+// List returns all recorded entries, oldest first
+func (s *Store) List() ([]Entry, error) {
+	data, err := os.ReadFile(filepath.Join(s.dir, indexFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history index: %w", err)
+	}
+
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse history index: %w", err)
+	}
+	return entries, nil
+}
+
+// Find looks up an entry by ID, ID prefix, or content digest prefix and
+// returns it together with its stored content. When several entries match
+// a digest, the most recent one is returned.
+func (s *Store) Find(id string) (*Entry, []byte, error) {
+	entries, err := s.List()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	id = strings.TrimPrefix(strings.ToLower(id), "sha256:")
+	var match *Entry
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if strings.HasPrefix(strings.ToLower(entry.ID), id) || strings.HasPrefix(entry.SHA256, id) {
+			if match != nil && match.SHA256 != entry.SHA256 {
+				return nil, nil, fmt.Errorf("history entry %q is ambiguous", id)
+			}
+			if match == nil {
+				match = &entries[i]
+			}
+		}
+	}
+	if match == nil {
+		return nil, nil, fmt.Errorf("history entry not found: %s", id)
+	}
+
+	content, err := os.ReadFile(filepath.Join(s.dir, match.Object))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read history object: %w", err)
+	}
+	if err := verify.VerifySHA256(content, match.SHA256); err != nil {
+		return nil, nil, fmt.Errorf("history object for %s is corrupted: %w", match.ID, err)
+	}
+
+	return match, content, nil
+}
@@ -0,0 +1,88 @@
+package httplog
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"drivio/pkg/redact"
+	"drivio/pkg/telemetry"
+	"drivio/pkg/ui"
+)
+
+// Level is the verbosity at which requests are logged (-vv)
+const Level = 2
+
+// redactor masks the query parameters carrying credentials, e.g.
+// private_token or access_token
+var redactor, _ = redact.New(nil)
+
+// Transport is an http.RoundTripper logging every request with its method,
+// URL, status, duration and remaining rate limit as a debug message, on
+// stderr with -vv and in the log file.
+// Credentials in the URL are masked; headers are never logged. Requests are
+// traced and measured when telemetry is enabled.
+type Transport struct {
+	// Base performs the requests; http.DefaultTransport when nil
+	Base http.RoundTripper
+}
+
+// NewTransport creates a logging transport on top of base
+func NewTransport(base http.RoundTripper) *Transport {
+	return &Transport{Base: base}
+}
+
+// NewClient creates an HTTP client with a logging transport
+func NewClient(timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: NewTransport(nil)}
+}
+
+// RoundTrip performs the request and logs it
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if !ui.DebugEnabled(Level) {
+		return telemetry.RoundTrip(base, req)
+	}
+
+	start := time.Now()
+	resp, err := telemetry.RoundTrip(base, req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	target := RedactURL(req.URL)
+	if err != nil {
+		ui.Debugf(Level, "%s %s failed after %s: %v", req.Method, target, elapsed, err)
+		return resp, err
+	}
+
+	remaining := ""
+	for _, header := range telemetry.RateLimitHeaders {
+		if value := resp.Header.Get(header); value != "" {
+			remaining = ", rate limit remaining " + value
+			break
+		}
+	}
+	ui.Debugf(Level, "%s %s -> %s (%s%s)", req.Method, target, resp.Status, elapsed, remaining)
+	return resp, nil
+}
+
+// RedactURL returns the URL with its password and the values of its
+// sensitive query parameters masked
+func RedactURL(u *url.URL) string {
+	redacted := *u
+	query := redacted.Query()
+	masked := false
+	for key := range query {
+		if redactor.IsSensitive(key) {
+			query.Set(key, redact.Mask)
+			masked = true
+		}
+	}
+	if masked {
+		redacted.RawQuery = strings.ReplaceAll(query.Encode(), url.QueryEscape(redact.Mask), redact.Mask)
+	}
+	return redacted.Redacted()
+}
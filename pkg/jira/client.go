@@ -0,0 +1,196 @@
+package jira
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"drivio/pkg/httplog"
+)
+
+// DefaultURL is the Jira instance of the default ticket links of the
+// release notes
+const DefaultURL = "https://issues.redhat.com"
+
+// ErrNotFound is returned when an issue does not exist or is not visible
+// with the credentials
+var ErrNotFound = errors.New("not found")
+
+// Client is a minimal Jira REST API (v2) client
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	// user and token authenticate the requests: with a user, as basic
+	// authentication (Jira Cloud API tokens); without, as a bearer token
+	// (Jira Data Center personal access tokens)
+	user  string
+	token string
+}
+
+// NewClient creates a client of the Jira instance at baseURL
+func NewClient(baseURL, user, token string) *Client {
+	return &Client{
+		httpClient: httplog.NewClient(time.Minute),
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		user:       user,
+		token:      token,
+	}
+}
+
+// Issue is a Jira issue with its status
+type Issue struct {
+	Key     string
+	Summary string
+	Status  string
+}
+
+// Transition is a move of an issue to another status
+type Transition struct {
+	ID   string
+	Name string
+	// To is the status the transition moves the issue to
+	To string
+}
+
+// GetIssue returns an issue with its current status
+func (c *Client) GetIssue(ctx context.Context, key string) (*Issue, error) {
+	var issue struct {
+		Key    string `json:"key"`
+		Fields struct {
+			Summary string `json:"summary"`
+			Status  struct {
+				Name string `json:"name"`
+			} `json:"status"`
+		} `json:"fields"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/rest/api/2/issue/"+url.PathEscape(key)+"?fields=summary,status", nil, &issue); err != nil {
+		return nil, fmt.Errorf("failed to get issue %s: %w", key, err)
+	}
+	return &Issue{Key: issue.Key, Summary: issue.Fields.Summary, Status: issue.Fields.Status.Name}, nil
+}
+
+// Transitions returns the transitions available from the current status of
+// an issue
+func (c *Client) Transitions(ctx context.Context, key string) ([]Transition, error) {
+	var result struct {
+		Transitions []struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+			To   struct {
+				Name string `json:"name"`
+			} `json:"to"`
+		} `json:"transitions"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/rest/api/2/issue/"+url.PathEscape(key)+"/transitions", nil, &result); err != nil {
+		return nil, fmt.Errorf("failed to get transitions of %s: %w", key, err)
+	}
+	transitions := make([]Transition, 0, len(result.Transitions))
+	for _, t := range result.Transitions {
+		transitions = append(transitions, Transition{ID: t.ID, Name: t.Name, To: t.To.Name})
+	}
+	return transitions, nil
+}
+
+// FindTransition returns the transition to a status, matched by the name of
+// the status or of the transition, ignoring case
+func FindTransition(transitions []Transition, status string) (Transition, bool) {
+	for _, t := range transitions {
+		if strings.EqualFold(t.To, status) {
+			return t, true
+		}
+	}
+	for _, t := range transitions {
+		if strings.EqualFold(t.Name, status) {
+			return t, true
+		}
+	}
+	return Transition{}, false
+}
+
+// Transition moves an issue with a transition, adding a comment when not
+// empty. Like other writes, it is not retried.
+func (c *Client) Transition(ctx context.Context, key, transitionID, comment string) error {
+	body := map[string]interface{}{
+		"transition": map[string]string{"id": transitionID},
+	}
+	if comment != "" {
+		body["update"] = map[string]interface{}{
+			"comment": []map[string]interface{}{{"add": map[string]string{"body": comment}}},
+		}
+	}
+	if err := c.do(ctx, http.MethodPost, "/rest/api/2/issue/"+url.PathEscape(key)+"/transitions", body, nil); err != nil {
+		return fmt.Errorf("failed to transition %s: %w", key, err)
+	}
+	return nil
+}
+
+// do sends a request with body as JSON, unless nil, and decodes the JSON
+// response into v, unless nil
+func (c *Client) do(ctx context.Context, method, path string, body, v interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "drivio")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	switch {
+	case c.user != "":
+		req.SetBasicAuth(c.user, c.token)
+	case c.token != "":
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// Jira explains rejected requests, e.g. a field required by the
+		// transition
+		var apiError struct {
+			ErrorMessages []string          `json:"errorMessages"`
+			Errors        map[string]string `json:"errors"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&apiError) == nil {
+			messages := apiError.ErrorMessages
+			for field, message := range apiError.Errors {
+				messages = append(messages, field+": "+message)
+			}
+			if len(messages) > 0 {
+				return fmt.Errorf("Jira API returned status %d: %s", resp.StatusCode, strings.Join(messages, "; "))
+			}
+		}
+		return fmt.Errorf("Jira API returned status %d", resp.StatusCode)
+	}
+
+	if v == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response of %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// Kind is the kind of Kubernetes object holding a fetched file
+type Kind string
+
+const (
+	KindConfigMap Kind = "configmap"
+	KindSecret    Kind = "secret"
+)
+
+// ManagedByLabel marks the objects created by drivio
+const ManagedByLabel = "app.kubernetes.io/managed-by"
+
+// SourceAnnotation records where the data of an object was fetched from
+const SourceAnnotation = "drivio/source"
+
+// ObjectRef identifies a ConfigMap or Secret
+type ObjectRef struct {
+	Kind      Kind
+	Name      string
+	Namespace string
+}
+
+func (r ObjectRef) String() string {
+	return fmt.Sprintf("%s/%s", r.Kind, r.Name)
+}
+
+// ParseObjectRef parses a reference such as configmap/app-config or
+// secret/app-credentials (cm is accepted as a short form of configmap)
+func ParseObjectRef(ref string) (ObjectRef, error) {
+	kind, name, ok := strings.Cut(ref, "/")
+	if !ok || name == "" {
+		return ObjectRef{}, fmt.Errorf("invalid object reference %q (use configmap/NAME or secret/NAME)", ref)
+	}
+	switch strings.ToLower(kind) {
+	case "configmap", "configmaps", "cm":
+		return ObjectRef{Kind: KindConfigMap, Name: name}, nil
+	case "secret", "secrets":
+		return ObjectRef{Kind: KindSecret, Name: name}, nil
+	default:
+		return ObjectRef{}, fmt.Errorf("unsupported object kind %q (use configmap or secret)", kind)
+	}
+}
+
+// Client talks to a Kubernetes cluster
+type Client struct {
+	clientset *kubernetes.Clientset
+	namespace string
+}
+
+// NewClient creates a client from the kubeconfig (KUBECONFIG, ~/.kube/config
+// or the given path) and falls back to the in-cluster service account when
+// no kubeconfig is available
+func NewClient(kubeconfig, kubeContext string) (*Client, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if kubeconfig != "" {
+		rules.ExplicitPath = kubeconfig
+	}
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Kubernetes credentials: %w", err)
+	}
+	namespace, _, err := clientConfig.Namespace()
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine the namespace: %w", err)
+	}
+
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+
+	return &Client{clientset: clientset, namespace: namespace}, nil
+}
+
+// Namespace returns the namespace of the reference, or the namespace of the
+// current context when the reference has none
+func (c *Client) Namespace(ref ObjectRef) string {
+	if ref.Namespace != "" {
+		return ref.Namespace
+	}
+	return c.namespace
+}
+
+// Apply stores content under key in the referenced object, creating the
+// object when it does not exist. Other keys of an existing object are kept.
+// It reports whether the object was created.
+func (c *Client) Apply(ctx context.Context, ref ObjectRef, key string, content []byte, source string) (bool, error) {
+	namespace := c.Namespace(ref)
+	switch ref.Kind {
+	case KindConfigMap:
+		return c.applyConfigMap(ctx, namespace, ref.Name, key, content, source)
+	case KindSecret:
+		return c.applySecret(ctx, namespace, ref.Name, key, content, source)
+	default:
+		return false, fmt.Errorf("unsupported object kind %q", ref.Kind)
+	}
+}
+
+// ErrKeyNotFound is returned when an object exists but has no such key
+var ErrKeyNotFound = errors.New("key not found")
+
+// Data returns the content stored under key in the referenced object
+func (c *Client) Data(ctx context.Context, ref ObjectRef, key string) ([]byte, error) {
+	namespace := c.Namespace(ref)
+	switch ref.Kind {
+	case KindConfigMap:
+		configMap, err := c.clientset.CoreV1().ConfigMaps(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get configmap %s/%s: %w", namespace, ref.Name, err)
+		}
+		if value, ok := configMap.Data[key]; ok {
+			return []byte(value), nil
+		}
+		if value, ok := configMap.BinaryData[key]; ok {
+			return value, nil
+		}
+	case KindSecret:
+		secret, err := c.clientset.CoreV1().Secrets(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get secret %s/%s: %w", namespace, ref.Name, err)
+		}
+		if value, ok := secret.Data[key]; ok {
+			return value, nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported object kind %q", ref.Kind)
+	}
+	return nil, fmt.Errorf("%w: %s in %s/%s", ErrKeyNotFound, key, namespace, ref)
+}
+
+func (c *Client) applyConfigMap(ctx context.Context, namespace, name, key string, content []byte, source string) (bool, error) {
+	configMaps := c.clientset.CoreV1().ConfigMaps(namespace)
+
+	configMap, err := configMaps.Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		configMap = &corev1.ConfigMap{ObjectMeta: newObjectMeta(namespace, name)}
+		setConfigMapData(configMap, key, content)
+		configMap.Annotations[SourceAnnotation] = source
+		if _, err := configMaps.Create(ctx, configMap, metav1.CreateOptions{}); err != nil {
+			return false, fmt.Errorf("failed to create configmap %s/%s: %w", namespace, name, err)
+		}
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get configmap %s/%s: %w", namespace, name, err)
+	}
+
+	setConfigMapData(configMap, key, content)
+	setAnnotation(&configMap.ObjectMeta, SourceAnnotation, source)
+	if _, err := configMaps.Update(ctx, configMap, metav1.UpdateOptions{}); err != nil {
+		return false, fmt.Errorf("failed to update configmap %s/%s: %w", namespace, name, err)
+	}
+	return false, nil
+}
+
+func (c *Client) applySecret(ctx context.Context, namespace, name, key string, content []byte, source string) (bool, error) {
+	secrets := c.clientset.CoreV1().Secrets(namespace)
+
+	secret, err := secrets.Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		secret = &corev1.Secret{
+			ObjectMeta: newObjectMeta(namespace, name),
+			Type:       corev1.SecretTypeOpaque,
+			Data:       map[string][]byte{key: content},
+		}
+		secret.Annotations[SourceAnnotation] = source
+		if _, err := secrets.Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+			return false, fmt.Errorf("failed to create secret %s/%s: %w", namespace, name, err)
+		}
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get secret %s/%s: %w", namespace, name, err)
+	}
+
+	if secret.Data == nil {
+		secret.Data = make(map[string][]byte)
+	}
+	secret.Data[key] = content
+	setAnnotation(&secret.ObjectMeta, SourceAnnotation, source)
+	if _, err := secrets.Update(ctx, secret, metav1.UpdateOptions{}); err != nil {
+		return false, fmt.Errorf("failed to update secret %s/%s: %w", namespace, name, err)
+	}
+	return false, nil
+}
+
+// setConfigMapData stores text content in Data and anything that is not
+// valid UTF-8 in BinaryData, as the API server requires
+func setConfigMapData(configMap *corev1.ConfigMap, key string, content []byte) {
+	if utf8.Valid(content) {
+		if configMap.Data == nil {
+			configMap.Data = make(map[string]string)
+		}
+		configMap.Data[key] = string(content)
+		delete(configMap.BinaryData, key)
+		return
+	}
+	if configMap.BinaryData == nil {
+		configMap.BinaryData = make(map[string][]byte)
+	}
+	configMap.BinaryData[key] = content
+	delete(configMap.Data, key)
+}
+
+func newObjectMeta(namespace, name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:        name,
+		Namespace:   namespace,
+		Labels:      map[string]string{ManagedByLabel: "drivio"},
+		Annotations: map[string]string{},
+	}
+}
+
+func setAnnotation(meta *metav1.ObjectMeta, key, value string) {
+	if meta.Annotations == nil {
+		meta.Annotations = make(map[string]string)
+	}
+	meta.Annotations[key] = value
+}
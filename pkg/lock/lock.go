@@ -0,0 +1,248 @@
+package lock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileName is the name of the lock file inside a locked directory
+const FileName = ".drivio.lock"
+
+// StaleAfter is the age after which a lock held from another host is
+// considered abandoned, since its process cannot be checked
+const StaleAfter = 24 * time.Hour
+
+// pollInterval is how often a waiting process retries to take the lock
+const pollInterval = 250 * time.Millisecond
+
+// Info describes the process holding a lock
+type Info struct {
+	PID       int       `json:"pid"`
+	Hostname  string    `json:"hostname"`
+	Command   string    `json:"command"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+func (i Info) String() string {
+	return fmt.Sprintf("%q (pid %d on %s, since %s)", i.Command, i.PID, i.Hostname, i.StartedAt.Local().Format(time.RFC3339))
+}
+
+// LockedError is returned when the directory is locked by another process
+type LockedError struct {
+	Dir    string
+	Holder Info
+}
+
+func (e *LockedError) Error() string {
+	if e.Holder.PID == 0 {
+		return fmt.Sprintf("work directory %s is in use by another process (%s)", e.Dir, filepath.Join(e.Dir, FileName))
+	}
+	return fmt.Sprintf("work directory %s is in use by %s", e.Dir, e.Holder)
+}
+
+// Lock is an advisory lock on a directory
+type Lock struct {
+	path string
+	// slot is the in-process slot of the lock, freed on release
+	slot chan struct{}
+	once sync.Once
+}
+
+var (
+	slotsMu sync.Mutex
+	// slots serialize the holders of a lock inside the process, e.g. the
+	// workers of serve, by lock file path: the lock file only tells
+	// processes apart
+	slots = make(map[string]chan struct{})
+)
+
+// slotOf returns the in-process slot of a lock file
+func slotOf(path string) chan struct{} {
+	slotsMu.Lock()
+	defer slotsMu.Unlock()
+	slot, ok := slots[path]
+	if !ok {
+		slot = make(chan struct{}, 1)
+		slots[path] = slot
+	}
+	return slot
+}
+
+// Acquire takes the lock on dir. When the directory is locked by another
+// live process, it waits up to timeout for the lock to be released (a zero
+// timeout fails immediately, a negative one waits until ctx is done) and
+// calls onWait once before waiting. Locks left behind by processes that are
+// no longer running are removed. Holders inside the process wait for each
+// other until ctx is done, whatever the timeout.
+func Acquire(ctx context.Context, dir string, timeout time.Duration, onWait func(holder Info)) (*Lock, error) {
+	return AcquireFile(ctx, filepath.Join(dir, FileName), timeout, onWait)
+}
+
+// AcquireFile takes a lock held by the file at path, like Acquire, e.g. the
+// lock of a directory that may not exist yet kept next to it
+func AcquireFile(ctx context.Context, path string, timeout time.Duration, onWait func(holder Info)) (*Lock, error) {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	slot := slotOf(path)
+	select {
+	case slot <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	l, err := acquireFile(ctx, filepath.Dir(path), path, timeout, onWait)
+	if err != nil {
+		<-slot
+		return nil, err
+	}
+	l.slot = slot
+	return l, nil
+}
+
+// acquireFile creates the lock file, once the in-process slot of the lock is
+// taken
+func acquireFile(ctx context.Context, dir, path string, timeout time.Duration, onWait func(holder Info)) (*Lock, error) {
+	info := currentInfo()
+	deadline := time.Now().Add(timeout)
+	waiting := false
+
+	for {
+		err := create(path, info)
+		if err == nil {
+			return &Lock{path: path}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to create lock file: %w", err)
+		}
+
+		holder, err := readInfo(path)
+		if err == nil && isStale(holder) {
+			removeStale(path, holder)
+			continue
+		}
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				// Released between our attempt and the read
+				continue
+			}
+			if staleUnreadable(path) {
+				os.Remove(path)
+				continue
+			}
+		}
+
+		if timeout >= 0 && !time.Now().Before(deadline) {
+			return nil, &LockedError{Dir: dir, Holder: holder}
+		}
+		if !waiting {
+			waiting = true
+			if onWait != nil {
+				onWait(holder)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// Release releases the lock. Releasing it again does nothing.
+func (l *Lock) Release() error {
+	var err error
+	l.once.Do(func() {
+		if removeErr := os.Remove(l.path); removeErr != nil && !errors.Is(removeErr, os.ErrNotExist) {
+			err = fmt.Errorf("failed to release lock: %w", removeErr)
+		}
+		<-l.slot
+	})
+	return err
+}
+
+// create atomically creates the lock file, failing if it already exists
+func create(path string, info Info) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		os.Remove(path)
+		return err
+	}
+	return file.Close()
+}
+
+func readInfo(path string) (Info, error) {
+	var info Info
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return info, err
+	}
+	if err := json.Unmarshal(data, &info); err != nil {
+		return info, fmt.Errorf("invalid lock file: %w", err)
+	}
+	return info, nil
+}
+
+func currentInfo() Info {
+	hostname, _ := os.Hostname()
+	command := filepath.Base(os.Args[0])
+	if len(os.Args) > 1 {
+		command += " " + strings.Join(os.Args[1:], " ")
+	}
+	return Info{
+		PID:       os.Getpid(),
+		Hostname:  hostname,
+		Command:   command,
+		StartedAt: time.Now().UTC(),
+	}
+}
+
+// isStale reports whether the holder of a lock is gone: a process on this
+// host that is no longer running, or a lock from another host that is older
+// than StaleAfter. It is called with the in-process slot of the lock taken,
+// so a lock of this PID was left by an earlier process that had the same
+// PID, e.g. PID 1 of a container restarted.
+func isStale(holder Info) bool {
+	hostname, _ := os.Hostname()
+	if holder.Hostname == hostname {
+		return holder.PID == os.Getpid() || !processAlive(holder.PID)
+	}
+	return time.Since(holder.StartedAt) > StaleAfter
+}
+
+// staleUnreadable reports whether an unreadable lock file is old enough not
+// to be a lock that is still being written
+func staleUnreadable(path string) bool {
+	stat, err := os.Stat(path)
+	return err == nil && time.Since(stat.ModTime()) > time.Minute
+}
+
+// removeStale removes a stale lock. The lock is renamed first, so that a
+// lock taken by another process in the meantime is detected and put back.
+func removeStale(path string, stale Info) {
+	tmp := fmt.Sprintf("%s.stale-%d", path, os.Getpid())
+	if err := os.Rename(path, tmp); err != nil {
+		return
+	}
+	if current, err := readInfo(tmp); err == nil && current != stale {
+		os.Link(tmp, path)
+	}
+	os.Remove(tmp)
+}
@@ -0,0 +1,137 @@
+package manifest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFileName is the conventional name of a batch fetch manifest
+const DefaultFileName = "drivio.fetch.yaml"
+
+// StatusFileName is the conventional name of the manifest of drivio status,
+// whose sources are environments
+const StatusFileName = "drivio.status.yaml"
+
+// Manifest declares a set of files to fetch in a single run
+type Manifest struct {
+	// Defaults are applied to every source that leaves a field empty
+	Defaults Source   `yaml:"defaults"`
+	Sources  []Source `yaml:"sources"`
+	// Fields are the values drivio status extracts from every source
+	Fields []Field `yaml:"fields"`
+
+	// dir is the directory containing the manifest; relative destination and
+	// schema paths are resolved against it
+	dir string
+}
+
+// Source is a single file to fetch
+type Source struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+	// Instance is a GitLab instance of the drivio configuration file,
+	// providing the URL and token
+	Instance    string `yaml:"instance"`
+	Repo        string `yaml:"repo"`
+	Ref         string `yaml:"ref"`
+	File        string `yaml:"file"`
+	Destination string `yaml:"destination"`
+	Schema      string `yaml:"schema"`
+	// CUE is a CUE schema the file must satisfy, as FILE.cue#Definition
+	CUE string `yaml:"cue"`
+}
+
+// Field is a value extracted from the sources, e.g. the image tag
+type Field struct {
+	Name string `yaml:"name"`
+	// Query is the path of the value, as accepted by fetch --query
+	Query string `yaml:"query"`
+}
+
+// Load reads and validates a manifest file
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var m Manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+	m.dir = filepath.Dir(path)
+
+	if len(m.Sources) == 0 {
+		return nil, fmt.Errorf("manifest %s declares no sources", path)
+	}
+
+	for i := range m.Sources {
+		m.Sources[i] = m.resolve(m.Sources[i])
+		if err := m.Sources[i].validate(); err != nil {
+			return nil, fmt.Errorf("manifest %s: source #%d: %w", path, i+1, err)
+		}
+	}
+
+	for i, field := range m.Fields {
+		if field.Name == "" || field.Query == "" {
+			return nil, fmt.Errorf("manifest %s: field #%d: name and query are required", path, i+1)
+		}
+	}
+
+	return &m, nil
+}
+
+// resolve applies the defaults to a source and makes its paths relative to
+// the manifest directory
+func (m *Manifest) resolve(s Source) Source {
+	if s.URL == "" {
+		s.URL = m.Defaults.URL
+	}
+	if s.URL == "" && s.Instance == "" {
+		s.Instance = m.Defaults.Instance
+	}
+	if s.Repo == "" {
+		s.Repo = m.Defaults.Repo
+	}
+	if s.Ref == "" {
+		s.Ref = m.Defaults.Ref
+	}
+	if s.Schema == "" {
+		s.Schema = m.Defaults.Schema
+	}
+	if s.CUE == "" {
+		s.CUE = m.Defaults.CUE
+	}
+	if s.Name == "" {
+		s.Name = s.Repo + ":" + s.File
+	}
+
+	if s.Destination != "" && !filepath.IsAbs(s.Destination) {
+		s.Destination = filepath.Join(m.dir, s.Destination)
+	}
+	if s.Schema != "" && !filepath.IsAbs(s.Schema) {
+		s.Schema = filepath.Join(m.dir, s.Schema)
+	}
+	if s.CUE != "" && !filepath.IsAbs(s.CUE) {
+		s.CUE = filepath.Join(m.dir, s.CUE)
+	}
+
+	return s
+}
+
+// validate checks that the source has the required fields
+func (s Source) validate() error {
+	if s.Repo == "" {
+		return fmt.Errorf("repo is required")
+	}
+	if s.File == "" {
+		return fmt.Errorf("file is required")
+	}
+	if s.URL != "" && s.Instance != "" {
+		return fmt.Errorf("url and instance are mutually exclusive")
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package merge
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Strategy decides what happens when base and overlay disagree on a value
+type Strategy string
+
+const (
+	// StrategyOverride lets the overlay win; lists are replaced
+	StrategyOverride Strategy = "override"
+	// StrategyAppend lets the overlay win on scalars and concatenates lists
+	StrategyAppend Strategy = "append"
+	// StrategyKeep keeps the base value on conflicts
+	StrategyKeep Strategy = "keep"
+	// StrategyError fails on any conflicting value
+	StrategyError Strategy = "error"
+)
+
+// ParseStrategy validates a strategy name
+func ParseStrategy(name string) (Strategy, error) {
+	switch s := Strategy(strings.ToLower(name)); s {
+	case StrategyOverride, StrategyAppend, StrategyKeep, StrategyError:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unsupported merge strategy: %s (use override, append, keep or error)", name)
+	}
+}
+
+// ConflictError is returned by StrategyError when two documents disagree
+type ConflictError struct {
+	Path string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("merge conflict at %s", e.Path)
+}
+
+// YAML deep-merges the overlay documents onto the base document, in order,
+// and returns the effective document. Mappings are merged key by key; the
+// strategy decides how conflicting scalars and lists are resolved. Key order
+// and comments of the base document are preserved.
+func YAML(base []byte, overlays [][]byte, strategy Strategy) ([]byte, error) {
+	var result yaml.Node
+	if err := yaml.Unmarshal(base, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse base document: %w", err)
+	}
+
+	for i, overlay := range overlays {
+		var node yaml.Node
+		if err := yaml.Unmarshal(overlay, &node); err != nil {
+			return nil, fmt.Errorf("failed to parse overlay #%d: %w", i+1, err)
+		}
+		if err := mergeNodes(&result, &node, strategy, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&result); err != nil {
+		return nil, fmt.Errorf("failed to encode merged document: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// mergeNodes merges overlay into base in place
+func mergeNodes(base, overlay *yaml.Node, strategy Strategy, path string) error {
+	// Empty documents are neutral elements
+	if overlay.Kind == 0 || (overlay.Kind == yaml.DocumentNode && len(overlay.Content) == 0) {
+		return nil
+	}
+	if base.Kind == 0 || (base.Kind == yaml.DocumentNode && len(base.Content) == 0) {
+		*base = *overlay
+		return nil
+	}
+
+	if base.Kind == yaml.DocumentNode && overlay.Kind == yaml.DocumentNode {
+		return mergeNodes(base.Content[0], overlay.Content[0], strategy, path)
+	}
+
+	switch {
+	case base.Kind == yaml.MappingNode && overlay.Kind == yaml.MappingNode:
+		return mergeMappings(base, overlay, strategy, path)
+	case base.Kind == yaml.SequenceNode && overlay.Kind == yaml.SequenceNode && strategy == StrategyAppend:
+		base.Content = append(base.Content, overlay.Content...)
+		return nil
+	default:
+		return resolveConflict(base, overlay, strategy, path)
+	}
+}
+
+// mergeMappings merges the keys of overlay into base
+func mergeMappings(base, overlay *yaml.Node, strategy Strategy, path string) error {
+	for i := 0; i+1 < len(overlay.Content); i += 2 {
+		key, value := overlay.Content[i], overlay.Content[i+1]
+		childPath := path + "." + key.Value
+
+		if existing := lookup(base, key.Value); existing != nil {
+			if err := mergeNodes(existing, value, strategy, childPath); err != nil {
+				return err
+			}
+			continue
+		}
+		base.Content = append(base.Content, key, value)
+	}
+	return nil
+}
+
+// resolveConflict applies the strategy to two values that cannot be merged
+func resolveConflict(base, overlay *yaml.Node, strategy Strategy, path string) error {
+	if EqualNodes(base, overlay) {
+		return nil
+	}
+
+	switch strategy {
+	case StrategyKeep:
+		return nil
+	case StrategyError:
+		if path == "" {
+			path = "."
+		}
+		return &ConflictError{Path: path}
+	default:
+		// Keep the base comments when the overlay has none
+		headComment, lineComment := base.HeadComment, base.LineComment
+		*base = *overlay
+		if base.HeadComment == "" {
+			base.HeadComment = headComment
+		}
+		if base.LineComment == "" {
+			base.LineComment = lineComment
+		}
+		return nil
+	}
+}
+
+// lookup returns the value for key in a mapping node
+func lookup(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// EqualNodes reports whether two nodes hold the same data, ignoring style,
+// comments and aliases
+func EqualNodes(a, b *yaml.Node) bool {
+	a, b = dealias(a), dealias(b)
+	if a.Kind != b.Kind || len(a.Content) != len(b.Content) {
+		return false
+	}
+	if a.Kind == yaml.ScalarNode {
+		return a.Value == b.Value && a.ShortTag() == b.ShortTag()
+	}
+	for i := range a.Content {
+		if !EqualNodes(a.Content[i], b.Content[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// dealias returns the node an alias node refers to
+func dealias(node *yaml.Node) *yaml.Node {
+	for node != nil && node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
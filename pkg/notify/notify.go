@@ -0,0 +1,100 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"drivio/pkg/config"
+	"drivio/pkg/plugin"
+)
+
+// Types of the notification channels
+const (
+	TypeSlack   = "slack"
+	TypeTeams   = "teams"
+	TypeWebhook = "webhook"
+	TypeEmail   = "email"
+	TypeNotion  = "notion"
+	TypePlugin  = "plugin"
+)
+
+// Types lists the types of the notification channels
+var Types = []string{TypeSlack, TypeTeams, TypeWebhook, TypeEmail, TypeNotion, TypePlugin}
+
+// Message is a notification, e.g. of a release
+type Message struct {
+	Title string `json:"title"`
+	Text  string `json:"text"`
+	// URL links to the subject of the notification, e.g. the release page
+	URL string `json:"url,omitempty"`
+	// Notes are the release notes of a release, in Markdown: notion
+	// channels publish them instead of the text, chats only get the text
+	Notes string `json:"notes,omitempty"`
+}
+
+// Notifier sends messages to a channel
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+// New returns the notifier of a channel of the configuration file
+func New(channel *config.Channel) (Notifier, error) {
+	switch strings.ToLower(channel.Type) {
+	case TypeSlack, TypeTeams, TypeWebhook:
+		if channel.URL == "" {
+			return nil, fmt.Errorf("notification channel %q has no url", channel.Name)
+		}
+	}
+
+	switch strings.ToLower(channel.Type) {
+	case TypeSlack:
+		return slack{url: channel.URL}, nil
+	case TypeTeams:
+		return teams{url: channel.URL}, nil
+	case TypeWebhook:
+		return webhook{url: channel.URL}, nil
+	case TypeEmail:
+		if channel.SMTP == "" || channel.From == "" || len(channel.To) == 0 {
+			return nil, fmt.Errorf("notification channel %q needs smtp, from and to", channel.Name)
+		}
+		return email{
+			addr:     channel.SMTP,
+			from:     channel.From,
+			to:       channel.To,
+			username: channel.Username,
+			password: channel.Password,
+		}, nil
+	case TypeNotion:
+		if channel.Token == "" || (channel.Database == "") == (channel.Page == "") {
+			return nil, fmt.Errorf("notification channel %q needs a token, and a database or a page", channel.Name)
+		}
+		return notion{token: channel.Token, database: channel.Database, page: channel.Page}, nil
+	case TypePlugin:
+		if channel.Plugin == "" {
+			return nil, fmt.Errorf("notification channel %q has no plugin", channel.Name)
+		}
+		p, err := plugin.Find(channel.Plugin)
+		if err != nil {
+			return nil, fmt.Errorf("notification channel %q: %w", channel.Name, err)
+		}
+		if err := p.Require(context.Background(), plugin.CapabilityNotify); err != nil {
+			return nil, fmt.Errorf("notification channel %q: %w", channel.Name, err)
+		}
+		return pluginChannel{plugin: p, channel: channel.Name, options: channel.Options}, nil
+	}
+	return nil, fmt.Errorf("notification channel %q has unknown type %q (available: %s)", channel.Name, channel.Type, strings.Join(Types, ", "))
+}
+
+// plainText renders the message as text: the title, text and URL on
+// separate lines
+func plainText(msg Message) string {
+	text := msg.Title
+	if msg.Text != "" {
+		text += "\n" + msg.Text
+	}
+	if msg.URL != "" {
+		text += "\n" + msg.URL
+	}
+	return text
+}
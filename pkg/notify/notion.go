@@ -0,0 +1,573 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"drivio/pkg/httplog"
+	"drivio/pkg/retry"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// Limits of the Notion API
+const (
+	notionVersion = "2022-06-28"
+	// notionMaxBlocks is the number of blocks appended by a request
+	notionMaxBlocks = 100
+	// notionMaxDepth is the nesting of the blocks of a request
+	notionMaxDepth = 2
+	// notionMaxText is the length of the content of a rich text object, and
+	// notionMaxRichText the number of rich text objects of a block
+	notionMaxText     = 2000
+	notionMaxRichText = 100
+)
+
+// notionAPI is the base URL of the Notion API
+var notionAPI = "https://api.notion.com/v1"
+
+// notionIDPattern matches the ID ending the URL of a page or database
+var notionIDPattern = regexp.MustCompile(`[0-9a-f]{32}$`)
+
+// notion publishes messages as Notion pages, in a database or under a parent
+// page: the page titled like the message is created, or its content is
+// replaced
+type notion struct {
+	token    string
+	database string
+	page     string
+}
+
+// notionBlock is a block of a page
+type notionBlock map[string]interface{}
+
+func (n notion) Notify(ctx context.Context, msg Message) error {
+	content := msg.Notes
+	if content == "" {
+		content = msg.Text
+	}
+	var blocks []notionBlock
+	if msg.URL != "" {
+		blocks = append(blocks, notionBlock{"object": "block", "type": "bookmark", "bookmark": map[string]string{"url": msg.URL}})
+	}
+	blocks = append(blocks, markdownBlocks(content)...)
+
+	var parent map[string]string
+	var properties map[string]interface{}
+	var existing string
+	var err error
+	title := richTextObjects([]textRun{{content: msg.Title}})
+	if n.database != "" {
+		id := notionID(n.database)
+		var property string
+		if property, err = n.titleProperty(ctx, id); err != nil {
+			return err
+		}
+		if existing, err = n.findDatabasePage(ctx, id, property, msg.Title); err != nil {
+			return err
+		}
+		parent = map[string]string{"database_id": id}
+		properties = map[string]interface{}{property: map[string]interface{}{"title": title}}
+	} else {
+		id := notionID(n.page)
+		if existing, err = n.findChildPage(ctx, id, msg.Title); err != nil {
+			return err
+		}
+		parent = map[string]string{"page_id": id}
+		properties = map[string]interface{}{"title": map[string]interface{}{"title": title}}
+	}
+
+	if existing != "" {
+		if err := n.clearPage(ctx, existing); err != nil {
+			return err
+		}
+		return n.appendBlocks(ctx, existing, blocks)
+	}
+
+	first := blocks
+	if len(first) > notionMaxBlocks {
+		first = first[:notionMaxBlocks]
+	}
+	var page struct {
+		ID string `json:"id"`
+	}
+	if err := n.request(ctx, http.MethodPost, "/pages", map[string]interface{}{
+		"parent":     parent,
+		"properties": properties,
+		"children":   first,
+	}, &page); err != nil {
+		return fmt.Errorf("failed to create the page: %w", err)
+	}
+	return n.appendBlocks(ctx, page.ID, blocks[len(first):])
+}
+
+// titleProperty returns the name of the title property of a database
+func (n notion) titleProperty(ctx context.Context, database string) (string, error) {
+	var db struct {
+		Properties map[string]struct {
+			Type string `json:"type"`
+		} `json:"properties"`
+	}
+	if err := n.request(ctx, http.MethodGet, "/databases/"+database, nil, &db); err != nil {
+		return "", fmt.Errorf("failed to read the database: %w", err)
+	}
+	for name, property := range db.Properties {
+		if property.Type == "title" {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("database %s has no title property", database)
+}
+
+// findDatabasePage returns the ID of the page of a database with the given
+// title, or an empty string
+func (n notion) findDatabasePage(ctx context.Context, database, property, title string) (string, error) {
+	var result struct {
+		Results []struct {
+			ID string `json:"id"`
+		} `json:"results"`
+	}
+	if err := n.request(ctx, http.MethodPost, "/databases/"+database+"/query", map[string]interface{}{
+		"filter":    map[string]interface{}{"property": property, "title": map[string]string{"equals": title}},
+		"page_size": 1,
+	}, &result); err != nil {
+		return "", fmt.Errorf("failed to query the database: %w", err)
+	}
+	if len(result.Results) == 0 {
+		return "", nil
+	}
+	return result.Results[0].ID, nil
+}
+
+// findChildPage returns the ID of the child page of a page with the given
+// title, or an empty string
+func (n notion) findChildPage(ctx context.Context, page, title string) (string, error) {
+	var found string
+	err := n.children(ctx, page, func(id, kind string, block json.RawMessage) bool {
+		if kind != "child_page" {
+			return true
+		}
+		var child struct {
+			ChildPage struct {
+				Title string `json:"title"`
+			} `json:"child_page"`
+		}
+		if json.Unmarshal(block, &child) == nil && child.ChildPage.Title == title {
+			found = id
+			return false
+		}
+		return true
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list the pages under the parent page: %w", err)
+	}
+	return found, nil
+}
+
+// clearPage deletes the blocks of a page, their children going with them
+func (n notion) clearPage(ctx context.Context, page string) error {
+	var ids []string
+	if err := n.children(ctx, page, func(id, kind string, block json.RawMessage) bool {
+		ids = append(ids, id)
+		return true
+	}); err != nil {
+		return fmt.Errorf("failed to read the page: %w", err)
+	}
+	for _, id := range ids {
+		if err := n.request(ctx, http.MethodDelete, "/blocks/"+id, nil, nil); err != nil {
+			return fmt.Errorf("failed to replace the content of the page: %w", err)
+		}
+	}
+	return nil
+}
+
+// appendBlocks appends blocks to a page, as many as a request takes at a time
+func (n notion) appendBlocks(ctx context.Context, page string, blocks []notionBlock) error {
+	for len(blocks) > 0 {
+		batch := blocks
+		if len(batch) > notionMaxBlocks {
+			batch = batch[:notionMaxBlocks]
+		}
+		if err := n.request(ctx, http.MethodPatch, "/blocks/"+page+"/children", map[string]interface{}{"children": batch}, nil); err != nil {
+			return fmt.Errorf("failed to write the page: %w", err)
+		}
+		blocks = blocks[len(batch):]
+	}
+	return nil
+}
+
+// children calls visit with the blocks of a page, page after page of results,
+// until it returns false
+func (n notion) children(ctx context.Context, page string, visit func(id, kind string, block json.RawMessage) bool) error {
+	cursor := ""
+	for {
+		path := "/blocks/" + page + "/children?page_size=100"
+		if cursor != "" {
+			path += "&start_cursor=" + url.QueryEscape(cursor)
+		}
+		var result struct {
+			Results    []json.RawMessage `json:"results"`
+			HasMore    bool              `json:"has_more"`
+			NextCursor string            `json:"next_cursor"`
+		}
+		if err := n.request(ctx, http.MethodGet, path, nil, &result); err != nil {
+			return err
+		}
+		for _, raw := range result.Results {
+			var block struct {
+				ID   string `json:"id"`
+				Type string `json:"type"`
+			}
+			if err := json.Unmarshal(raw, &block); err != nil {
+				return err
+			}
+			if !visit(block.ID, block.Type, raw) {
+				return nil
+			}
+		}
+		if !result.HasMore || result.NextCursor == "" {
+			return nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+// request calls the Notion API, retrying rate limited and failed calls, and
+// decodes the response into result when set
+func (n notion) request(ctx context.Context, method, path string, payload, result interface{}) error {
+	var body []byte
+	if payload != nil {
+		var err error
+		if body, err = json.Marshal(payload); err != nil {
+			return err
+		}
+	}
+
+	client := httplog.NewClient(30 * time.Second)
+	var data []byte
+	err := retry.Do(ctx, retry.DefaultPolicy(), func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, method, notionAPI+path, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+n.token)
+		req.Header.Set("Notion-Version", notionVersion)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "drivio")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if data, err = io.ReadAll(resp.Body); err != nil {
+			return resp, err
+		}
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			var apiErr struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			}
+			if json.Unmarshal(data, &apiErr) == nil && apiErr.Message != "" {
+				return resp, fmt.Errorf("Notion API returned status %d: %s (%s)", resp.StatusCode, apiErr.Message, apiErr.Code)
+			}
+			return resp, fmt.Errorf("Notion API returned status %d: %s", resp.StatusCode, bytes.TrimSpace(data))
+		}
+		return resp, nil
+	})
+	if err != nil || result == nil {
+		return err
+	}
+	return json.Unmarshal(data, result)
+}
+
+// notionID returns the ID of a page or database given by ID or URL, e.g.
+// https://www.notion.so/myorg/Releases-0123456789abcdef0123456789abcdef?v=...
+func notionID(value string) string {
+	id, _, _ := strings.Cut(value, "?")
+	id = strings.ReplaceAll(id, "-", "")
+	if match := notionIDPattern.FindString(id); match != "" {
+		return match
+	}
+	return value
+}
+
+// markdownBlocks converts Markdown, e.g. release notes, to Notion blocks:
+// headings, paragraphs, lists, quotes, code blocks, tables and dividers
+func markdownBlocks(markdown string) []notionBlock {
+	source := []byte(markdown)
+	parser := goldmark.New(goldmark.WithExtensions(extension.Table, extension.Strikethrough, extension.Linkify)).Parser()
+	doc := parser.Parse(text.NewReader(source))
+	return (&notionConverter{source: source}).blocks(doc.FirstChild(), 0)
+}
+
+// notionConverter converts the nodes of a Markdown document to blocks
+type notionConverter struct {
+	source []byte
+}
+
+// notionStyle is the annotations and link of a run of text
+type notionStyle struct {
+	bold, italic, strikethrough, code bool
+	link                              string
+}
+
+// textRun is text of a single style
+type textRun struct {
+	content string
+	style   notionStyle
+}
+
+// blocks converts a node and the siblings following it to blocks, at the
+// given depth of nesting; the children of blocks too deep for a request
+// follow them
+func (c *notionConverter) blocks(node ast.Node, depth int) []notionBlock {
+	var blocks []notionBlock
+	for ; node != nil; node = node.NextSibling() {
+		switch node := node.(type) {
+		case *ast.Heading:
+			level := node.Level
+			if level > 3 {
+				level = 3
+			}
+			blocks = append(blocks, textBlock(fmt.Sprintf("heading_%d", level), richTextObjects(c.runs(node))))
+		case *ast.Paragraph, *ast.TextBlock:
+			if runs := c.runs(node); len(runs) > 0 {
+				blocks = append(blocks, textBlock("paragraph", richTextObjects(runs)))
+			}
+		case *ast.List:
+			kind := "bulleted_list_item"
+			if node.IsOrdered() {
+				kind = "numbered_list_item"
+			}
+			for item := node.FirstChild(); item != nil; item = item.NextSibling() {
+				var runs []textRun
+				children := item.FirstChild()
+				switch children.(type) {
+				case *ast.Paragraph, *ast.TextBlock:
+					runs = c.runs(children)
+					children = children.NextSibling()
+				}
+				block := textBlock(kind, richTextObjects(runs))
+				blocks = append(blocks, block)
+				nested := c.blocks(children, depth+1)
+				if len(nested) > 0 && depth+1 < notionMaxDepth {
+					block[kind].(map[string]interface{})["children"] = nested
+				} else {
+					blocks = append(blocks, nested...)
+				}
+			}
+		case *ast.Blockquote:
+			var runs []textRun
+			for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+				if len(runs) > 0 {
+					runs = append(runs, textRun{content: "\n"})
+				}
+				runs = append(runs, c.runs(child)...)
+			}
+			blocks = append(blocks, textBlock("quote", richTextObjects(runs)))
+		case *ast.FencedCodeBlock, *ast.CodeBlock:
+			var code strings.Builder
+			lines := node.Lines()
+			for i := 0; i < lines.Len(); i++ {
+				segment := lines.At(i)
+				code.Write(segment.Value(c.source))
+			}
+			language := "plain text"
+			if fenced, ok := node.(*ast.FencedCodeBlock); ok {
+				language = notionLanguage(string(fenced.Language(c.source)))
+			}
+			blocks = append(blocks, notionBlock{
+				"object": "block",
+				"type":   "code",
+				"code": map[string]interface{}{
+					"rich_text": richTextObjects([]textRun{{content: strings.TrimSuffix(code.String(), "\n")}}),
+					"language":  language,
+				},
+			})
+		case *ast.ThematicBreak:
+			blocks = append(blocks, notionBlock{"object": "block", "type": "divider", "divider": map[string]interface{}{}})
+		case *east.Table:
+			blocks = append(blocks, c.table(node))
+		case *ast.HTMLBlock:
+			// Comments and markup have no equivalent: left out
+		default:
+			blocks = append(blocks, c.blocks(node.FirstChild(), depth)...)
+		}
+	}
+	return blocks
+}
+
+// table converts a table, its first row being the header
+func (c *notionConverter) table(table *east.Table) notionBlock {
+	var rows [][][]map[string]interface{}
+	width := 0
+	for row := table.FirstChild(); row != nil; row = row.NextSibling() {
+		var cells [][]map[string]interface{}
+		for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			cells = append(cells, richTextObjects(c.runs(cell)))
+		}
+		width = max(width, len(cells))
+		rows = append(rows, cells)
+	}
+
+	var children []notionBlock
+	for _, cells := range rows {
+		// Rows have as many cells as the table is wide
+		for len(cells) < width {
+			cells = append(cells, []map[string]interface{}{})
+		}
+		children = append(children, notionBlock{"object": "block", "type": "table_row", "table_row": map[string]interface{}{"cells": cells}})
+	}
+	return notionBlock{
+		"object": "block",
+		"type":   "table",
+		"table": map[string]interface{}{
+			"table_width":       width,
+			"has_column_header": true,
+			"has_row_header":    false,
+			"children":          children,
+		},
+	}
+}
+
+// runs returns the inline content of a node as runs of text, adjacent text
+// of the same style merged
+func (c *notionConverter) runs(node ast.Node) []textRun {
+	var runs []textRun
+	add := func(content string, style notionStyle) {
+		if content == "" {
+			return
+		}
+		if len(runs) > 0 && runs[len(runs)-1].style == style {
+			runs[len(runs)-1].content += content
+			return
+		}
+		runs = append(runs, textRun{content: content, style: style})
+	}
+
+	var walk func(node ast.Node, style notionStyle)
+	walk = func(node ast.Node, style notionStyle) {
+		for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+			switch child := child.(type) {
+			case *ast.Text:
+				content := string(child.Segment.Value(c.source))
+				switch {
+				case child.HardLineBreak():
+					content += "\n"
+				case child.SoftLineBreak():
+					content += " "
+				}
+				add(content, style)
+			case *ast.String:
+				add(string(child.Value), style)
+			case *ast.CodeSpan:
+				code := style
+				code.code = true
+				walk(child, code)
+			case *ast.Emphasis:
+				emphasis := style
+				if child.Level >= 2 {
+					emphasis.bold = true
+				} else {
+					emphasis.italic = true
+				}
+				walk(child, emphasis)
+			case *east.Strikethrough:
+				struck := style
+				struck.strikethrough = true
+				walk(child, struck)
+			case *ast.Link:
+				linked := style
+				linked.link = string(child.Destination)
+				walk(child, linked)
+			case *ast.AutoLink:
+				linked := style
+				linked.link = string(child.URL(c.source))
+				add(string(child.Label(c.source)), linked)
+			case *ast.Image:
+				// The alternative text, linking to the image
+				linked := style
+				linked.link = string(child.Destination)
+				walk(child, linked)
+			case *ast.RawHTML:
+				// Inline markup has no equivalent: left out
+			default:
+				walk(child, style)
+			}
+		}
+	}
+	walk(node, notionStyle{})
+	return runs
+}
+
+// richTextObjects returns the rich text objects of runs of text, split in
+// objects of the maximum length, as many as a block takes
+func richTextObjects(runs []textRun) []map[string]interface{} {
+	rich := []map[string]interface{}{}
+	for _, run := range runs {
+		content := []rune(run.content)
+		for len(content) > 0 && len(rich) < notionMaxRichText {
+			part := content[:min(len(content), notionMaxText)]
+			content = content[len(part):]
+
+			object := map[string]interface{}{"content": string(part)}
+			// Only absolute links are valid
+			if link := run.style.link; strings.HasPrefix(link, "http://") || strings.HasPrefix(link, "https://") || strings.HasPrefix(link, "mailto:") {
+				object["link"] = map[string]string{"url": link}
+			}
+			text := map[string]interface{}{"type": "text", "text": object}
+			if style := run.style; style.bold || style.italic || style.strikethrough || style.code {
+				text["annotations"] = map[string]bool{
+					"bold":          style.bold,
+					"italic":        style.italic,
+					"strikethrough": style.strikethrough,
+					"code":          style.code,
+				}
+			}
+			rich = append(rich, text)
+		}
+	}
+	return rich
+}
+
+// textBlock returns a block of rich text of the given type
+func textBlock(kind string, rich []map[string]interface{}) notionBlock {
+	return notionBlock{"object": "block", "type": kind, kind: map[string]interface{}{"rich_text": rich}}
+}
+
+// notionLanguage returns the Notion language of the info string of a fenced
+// code block
+func notionLanguage(info string) string {
+	switch language := strings.ToLower(info); language {
+	case "":
+		return "plain text"
+	case "sh", "bash", "zsh", "console", "shell":
+		return "shell"
+	case "yml":
+		return "yaml"
+	case "js":
+		return "javascript"
+	case "ts":
+		return "typescript"
+	case "py":
+		return "python"
+	case "golang":
+		return "go"
+	case "go", "yaml", "json", "javascript", "typescript", "python", "diff", "markdown", "sql", "toml", "dockerfile", "makefile", "html", "css", "java", "rust", "ruby", "c", "c++":
+		return language
+	}
+	return "plain text"
+}
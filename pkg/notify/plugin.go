@@ -0,0 +1,25 @@
+package notify
+
+import (
+	"context"
+
+	"drivio/pkg/plugin"
+)
+
+// pluginChannel is a channel of a plugin, e.g. a chat drivio has no channel
+// type for
+type pluginChannel struct {
+	plugin  *plugin.Plugin
+	channel string
+	options map[string]string
+}
+
+func (p pluginChannel) Notify(ctx context.Context, msg Message) error {
+	return p.plugin.Call(ctx, plugin.MethodNotify, plugin.NotifyParams{
+		Channel: p.channel,
+		Options: p.options,
+		Title:   msg.Title,
+		Text:    msg.Text,
+		URL:     msg.URL,
+	}, nil)
+}
@@ -0,0 +1,44 @@
+package notify
+
+import (
+	"context"
+	"strings"
+)
+
+// slack is a Slack incoming webhook
+type slack struct {
+	url string
+}
+
+func (s slack) Notify(ctx context.Context, msg Message) error {
+	title := "*" + slackEscape(msg.Title) + "*"
+	if msg.URL != "" {
+		title = "*<" + msg.URL + "|" + slackEscape(msg.Title) + ">*"
+	}
+	blocks := []map[string]interface{}{section(title)}
+	if msg.Text != "" {
+		blocks = append(blocks, section(slackEscape(msg.Text)))
+	}
+	return postJSON(ctx, s.url, map[string]interface{}{
+		// Shown in notifications, where blocks are not rendered
+		"text":   slackEscape(plainText(msg)),
+		"blocks": blocks,
+	})
+}
+
+// section is a Slack block of mrkdwn text
+func section(text string) map[string]interface{} {
+	// Section texts are limited to 3000 characters
+	if len(text) > 3000 {
+		text = text[:2997] + "..."
+	}
+	return map[string]interface{}{
+		"type": "section",
+		"text": map[string]string{"type": "mrkdwn", "text": text},
+	}
+}
+
+// slackEscape escapes the characters Slack reserves for links and mentions
+func slackEscape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
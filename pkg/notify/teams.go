@@ -0,0 +1,38 @@
+package notify
+
+import "context"
+
+// teams is a Microsoft Teams webhook, of a Workflows flow or of an incoming
+// webhook connector; both accept an Adaptive Card
+type teams struct {
+	url string
+}
+
+func (t teams) Notify(ctx context.Context, msg Message) error {
+	body := []map[string]interface{}{{
+		"type":   "TextBlock",
+		"text":   msg.Title,
+		"weight": "Bolder",
+		"size":   "Medium",
+		"wrap":   true,
+	}}
+	if msg.Text != "" {
+		body = append(body, map[string]interface{}{"type": "TextBlock", "text": msg.Text, "wrap": true})
+	}
+	card := map[string]interface{}{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body":    body,
+	}
+	if msg.URL != "" {
+		card["actions"] = []map[string]string{{"type": "Action.OpenUrl", "title": "Open", "url": msg.URL}}
+	}
+	return postJSON(ctx, t.url, map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content":     card,
+		}},
+	})
+}
@@ -0,0 +1,56 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"drivio/pkg/httplog"
+)
+
+// webhook is a generic webhook channel
+type webhook struct {
+	url string
+}
+
+func (w webhook) Notify(ctx context.Context, msg Message) error {
+	return Webhook(ctx, w.url, msg)
+}
+
+// Webhook posts the message as JSON to a webhook URL. The text field holds
+// the title, text and URL on separate lines, as Slack and Mattermost incoming
+// webhooks display it; other receivers can use the separate fields.
+func Webhook(ctx context.Context, webhookURL string, msg Message) error {
+	return postJSON(ctx, webhookURL, Message{Title: msg.Title, Text: plainText(msg), URL: msg.URL})
+}
+
+// postJSON posts a payload as JSON to a webhook URL
+func postJSON(ctx context.Context, webhookURL string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "drivio")
+
+	resp, err := httplog.NewClient(30 * time.Second).Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send notification: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+// Package payload reads the metadata of OpenShift release images, the
+// payloads of OpenShift and HyperShift clusters: the image of each component
+// of the release, and the repository and commit it was built from, as listed
+// by oc adm release info --commits.
+package payload
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"drivio/pkg/diff"
+)
+
+// ImageReferencesPath is the file of a release image listing its components:
+// an image stream with a tag per component
+const ImageReferencesPath = "release-manifests/image-references"
+
+// Annotations of the image stream tags naming the source of a component
+const (
+	SourceAnnotation = "io.openshift.build.source-location"
+	CommitAnnotation = "io.openshift.build.commit.id"
+)
+
+// Component is a component of a release, e.g. hypershift
+type Component struct {
+	// Name is the tag of the component in the release, e.g. hypershift
+	Name string `json:"name"`
+	// Image is the pull spec of the image of the component
+	Image string `json:"image"`
+	// Source is the repository the image is built from, e.g.
+	// https://github.com/openshift/hypershift; empty when not known
+	Source string `json:"source,omitempty"`
+	// Commit is the commit of Source the image is built from
+	Commit string `json:"commit,omitempty"`
+}
+
+// Release is the metadata of a release image
+type Release struct {
+	// Version is the version of the release, e.g. 4.15.2
+	Version string `json:"version"`
+	// Image is the pull spec of the release image, when read from a registry
+	Image      string      `json:"image,omitempty"`
+	Components []Component `json:"components"`
+}
+
+// imageStream is the image-references file of a release image
+type imageStream struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Spec struct {
+		Tags []struct {
+			Name        string            `json:"name"`
+			Annotations map[string]string `json:"annotations"`
+			From        struct {
+				Name string `json:"name"`
+			} `json:"from"`
+		} `json:"tags"`
+	} `json:"spec"`
+}
+
+// releaseInfo is the output of oc adm release info -o json
+type releaseInfo struct {
+	Image      string       `json:"image"`
+	References *imageStream `json:"references"`
+	Metadata   struct {
+		Version string `json:"version"`
+	} `json:"metadata"`
+}
+
+// Parse parses the metadata of a release: the image-references file of a
+// release image, or the output of oc adm release info -o json
+func Parse(content []byte) (*Release, error) {
+	var info releaseInfo
+	if err := json.Unmarshal(content, &info); err != nil {
+		return nil, fmt.Errorf("failed to parse release metadata: %w", err)
+	}
+	if info.References != nil {
+		release := fromImageStream(info.References)
+		release.Image = info.Image
+		if info.Metadata.Version != "" {
+			release.Version = info.Metadata.Version
+		}
+		return release, nil
+	}
+
+	var stream imageStream
+	if err := json.Unmarshal(content, &stream); err != nil {
+		return nil, fmt.Errorf("failed to parse release metadata: %w", err)
+	}
+	if stream.Kind != "ImageStream" {
+		return nil, fmt.Errorf("release metadata is neither an image-references image stream nor the output of oc adm release info -o json")
+	}
+	return fromImageStream(&stream), nil
+}
+
+// fromImageStream returns the release listed by an image-references file,
+// its components sorted by name
+func fromImageStream(stream *imageStream) *Release {
+	release := &Release{Version: stream.Metadata.Name, Components: []Component{}}
+	for _, tag := range stream.Spec.Tags {
+		release.Components = append(release.Components, Component{
+			Name:   tag.Name,
+			Image:  tag.From.Name,
+			Source: strings.TrimSpace(tag.Annotations[SourceAnnotation]),
+			Commit: strings.TrimSpace(tag.Annotations[CommitAnnotation]),
+		})
+	}
+	sort.Slice(release.Components, func(i, j int) bool { return release.Components[i].Name < release.Components[j].Name })
+	return release
+}
+
+// GitHubRepository returns the owner and name of the GitHub repository a
+// component is built from; false when it is built elsewhere or not known
+func (c Component) GitHubRepository() (owner, repo string, ok bool) {
+	source := strings.TrimSuffix(strings.TrimSuffix(c.Source, "/"), ".git")
+	for _, prefix := range []string{"https://github.com/", "http://github.com/", "git@github.com:"} {
+		if rest, found := strings.CutPrefix(source, prefix); found {
+			parts := strings.Split(rest, "/")
+			if len(parts) == 2 && parts[0] != "" && parts[1] != "" {
+				return parts[0], parts[1], true
+			}
+		}
+	}
+	return "", "", false
+}
+
+// SourceChange is a repository whose commit differs between two releases,
+// with the components built from it
+type SourceChange struct {
+	Kind diff.ChangeKind `json:"kind"`
+	// Source is the repository the components are built from
+	Source string `json:"source"`
+	// Components are the names of the components built from the commits
+	Components []string `json:"components"`
+	// From and To are the commits of each release; empty on the side the
+	// components are missing from
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Compare returns the repositories whose commit differs between two
+// releases, sorted by repository. The components of a repository built from
+// the same commits are aggregated, as images like the operator and its CLI
+// often are. Components without a source are not compared.
+func Compare(from, to *Release) []SourceChange {
+	type key struct{ source, from, to string }
+	commits := func(release *Release) map[string]string {
+		byName := make(map[string]string)
+		for _, component := range release.Components {
+			if component.Source != "" && component.Commit != "" {
+				byName[component.Name] = component.Commit
+			}
+		}
+		return byName
+	}
+	sources := make(map[string]string)
+	for _, release := range []*Release{from, to} {
+		for _, component := range release.Components {
+			if component.Source != "" && component.Commit != "" {
+				sources[component.Name] = normalizeSource(component.Source)
+			}
+		}
+	}
+	fromCommits, toCommits := commits(from), commits(to)
+
+	changes := make(map[key]*SourceChange)
+	for name, source := range sources {
+		k := key{source, fromCommits[name], toCommits[name]}
+		if k.from == k.to {
+			continue
+		}
+		change, ok := changes[k]
+		if !ok {
+			change = &SourceChange{Kind: diff.Changed, Source: source, From: k.from, To: k.to}
+			switch {
+			case k.from == "":
+				change.Kind = diff.Added
+			case k.to == "":
+				change.Kind = diff.Removed
+			}
+			changes[k] = change
+		}
+		change.Components = append(change.Components, name)
+	}
+
+	list := make([]SourceChange, 0, len(changes))
+	for _, change := range changes {
+		sort.Strings(change.Components)
+		list = append(list, *change)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Source != list[j].Source {
+			return list[i].Source < list[j].Source
+		}
+		return list[i].Components[0] < list[j].Components[0]
+	})
+	return list
+}
+
+// GitHubRepository returns the owner and name of the GitHub repository of
+// the change; false when it is not on GitHub
+func (c SourceChange) GitHubRepository() (owner, repo string, ok bool) {
+	return Component{Source: c.Source}.GitHubRepository()
+}
+
+// normalizeSource returns the address of a repository without the
+// trailing slash or .git, so both spellings are the same source
+func normalizeSource(source string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(source, "/"), ".git")
+}
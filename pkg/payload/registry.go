@@ -0,0 +1,366 @@
+package payload
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"drivio/pkg/diff"
+	"drivio/pkg/httplog"
+)
+
+// DefaultRepository is the repository of the OpenShift release images
+const DefaultRepository = "quay.io/openshift-release-dev/ocp-release"
+
+// maxImageReferences bounds the size of the image-references file read from
+// a layer
+const maxImageReferences = 64 << 20
+
+// Media types of the manifests of a release image
+const (
+	mediaTypeOCIIndex      = "application/vnd.oci.image.index.v1+json"
+	mediaTypeDockerList    = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeOCIManifest   = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeDockerImageV2 = "application/vnd.docker.distribution.manifest.v2+json"
+)
+
+// Registry reads release images from their container registry, through the
+// registry HTTP API: no container runtime nor oc is needed
+type Registry struct {
+	httpClient *http.Client
+	// Architecture selects the image of multi-architecture releases, e.g.
+	// amd64
+	Architecture string
+	// auths are the credentials of the registries, base64 user:password
+	// by host, as in the pull secret of a cluster
+	auths map[string]string
+	// tokens are the bearer tokens obtained for each repository
+	tokens map[string]string
+}
+
+// NewRegistry creates a registry client reading the amd64 image of
+// multi-architecture releases, anonymously until credentials are loaded
+func NewRegistry() *Registry {
+	return &Registry{
+		httpClient:   httplog.NewClient(5 * time.Minute),
+		Architecture: "amd64",
+		auths:        make(map[string]string),
+		tokens:       make(map[string]string),
+	}
+}
+
+// LoadAuthFile loads the registry credentials of a pull secret or a
+// container auth file (config.json, auth.json): an auths object whose
+// entries have an auth, or a username and password
+func (r *Registry) LoadAuthFile(file string) error {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read auth file: %w", err)
+	}
+	var config struct {
+		Auths map[string]struct {
+			Auth     string `json:"auth"`
+			Username string `json:"username"`
+			Password string `json:"password"`
+		} `json:"auths"`
+	}
+	if err := json.Unmarshal(content, &config); err != nil {
+		return fmt.Errorf("failed to parse auth file %s: %w", file, err)
+	}
+	for host, entry := range config.Auths {
+		auth := entry.Auth
+		if auth == "" && entry.Username != "" {
+			auth = base64.StdEncoding.EncodeToString([]byte(entry.Username + ":" + entry.Password))
+		}
+		if auth != "" {
+			// Entries may be addresses, e.g. https://index.docker.io/v1/
+			host = strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://")
+			host, _, _ = strings.Cut(host, "/")
+			r.auths[host] = auth
+		}
+	}
+	return nil
+}
+
+// Release reads the metadata of a release image, e.g.
+// quay.io/openshift-release-dev/ocp-release:4.15.2-x86_64, from the
+// image-references file of its layers, the last layer first as the file is
+// in one of the release layers on top of the base image
+func (r *Registry) Release(ctx context.Context, pullSpec string) (*Release, error) {
+	image, ok := diff.ParseImage(pullSpec, true)
+	if !ok {
+		return nil, fmt.Errorf("invalid release image %q", pullSpec)
+	}
+	host, repository := splitRepository(image.Repository)
+	reference := image.Digest
+	if reference == "" {
+		reference = image.Tag
+	}
+	if reference == "" {
+		reference = "latest"
+	}
+
+	manifest, err := r.manifest(ctx, host, repository, reference)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read release image %s: %w", pullSpec, err)
+	}
+	for i := len(manifest.Layers) - 1; i >= 0; i-- {
+		content, err := r.findInLayer(ctx, host, repository, manifest.Layers[i].Digest, manifest.Layers[i].MediaType)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read release image %s: %w", pullSpec, err)
+		}
+		if content != nil {
+			release, err := Parse(content)
+			if err != nil {
+				return nil, err
+			}
+			release.Image = pullSpec
+			return release, nil
+		}
+	}
+	return nil, fmt.Errorf("%s is not a release image: none of its layers has %s", pullSpec, ImageReferencesPath)
+}
+
+// manifest is an image manifest, or an index of the manifests of each
+// platform
+type manifest struct {
+	MediaType string `json:"mediaType"`
+	Manifests []struct {
+		Digest   string `json:"digest"`
+		Platform struct {
+			OS           string `json:"os"`
+			Architecture string `json:"architecture"`
+		} `json:"platform"`
+	} `json:"manifests"`
+	Layers []struct {
+		Digest    string `json:"digest"`
+		MediaType string `json:"mediaType"`
+	} `json:"layers"`
+}
+
+// manifest returns the image manifest of a reference, the one of the
+// architecture for a multi-architecture image
+func (r *Registry) manifest(ctx context.Context, host, repository, reference string) (*manifest, error) {
+	resp, err := r.get(ctx, host, repository, "manifests/"+reference, strings.Join([]string{mediaTypeOCIIndex, mediaTypeDockerList, mediaTypeOCIManifest, mediaTypeDockerImageV2}, ", "))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var m manifest
+	if err := json.NewDecoder(resp.Body).Decode(&m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", reference, err)
+	}
+	if len(m.Manifests) == 0 {
+		return &m, nil
+	}
+	for _, platform := range m.Manifests {
+		if platform.Platform.OS == "linux" && platform.Platform.Architecture == r.Architecture {
+			return r.manifest(ctx, host, repository, platform.Digest)
+		}
+	}
+	return nil, fmt.Errorf("%s has no linux/%s image", reference, r.Architecture)
+}
+
+// findInLayer returns the image-references file of a layer, nil when the
+// layer does not have it
+func (r *Registry) findInLayer(ctx context.Context, host, repository, digest, mediaType string) ([]byte, error) {
+	if strings.Contains(mediaType, "zstd") {
+		// Release layers are gzip-compressed; zstd ones are other layers
+		return nil, nil
+	}
+	resp, err := r.get(ctx, host, repository, "blobs/"+digest, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var layer io.Reader = resp.Body
+	if !strings.HasSuffix(mediaType, ".tar") {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress layer %s: %w", digest, err)
+		}
+		defer gz.Close()
+		layer = gz
+	}
+	tr := tar.NewReader(layer)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read layer %s: %w", digest, err)
+		}
+		if header.Typeflag == tar.TypeReg && path.Clean(strings.TrimPrefix(header.Name, "/")) == ImageReferencesPath {
+			return io.ReadAll(io.LimitReader(tr, maxImageReferences))
+		}
+	}
+}
+
+// get sends a GET request to the registry API of a repository, e.g. for
+// manifests/4.15.2-x86_64, obtaining a bearer token first when the
+// registry asks for one
+func (r *Registry) get(ctx context.Context, host, repository, endpoint, accept string) (*http.Response, error) {
+	do := func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s://%s/v2/%s/%s", scheme(host), host, repository, endpoint), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", "drivio")
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if token := r.tokens[host+"/"+repository]; token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		} else if auth := r.auths[host]; auth != "" {
+			req.Header.Set("Authorization", "Basic "+auth)
+		}
+		return r.httpClient.Do(req)
+	}
+
+	resp, err := do()
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && r.tokens[host+"/"+repository] == "" {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		if err := r.authenticate(ctx, host, repository, challenge); err != nil {
+			return nil, err
+		}
+		if resp, err = do(); err != nil {
+			return nil, err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		switch resp.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return nil, fmt.Errorf("access to %s/%s denied (%d): use a pull secret with access to it", host, repository, resp.StatusCode)
+		case http.StatusNotFound:
+			return nil, fmt.Errorf("%s not found in %s/%s", path.Base(endpoint), host, repository)
+		}
+		return nil, fmt.Errorf("registry API returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return resp, nil
+}
+
+// challengeParam matches a parameter of a WWW-Authenticate challenge, e.g.
+// realm="https://quay.io/v2/auth"
+var challengeParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// authenticate obtains a bearer token to pull from a repository, as asked
+// for by the challenge of the registry, with the credentials of the host
+// when loaded and anonymously otherwise
+func (r *Registry) authenticate(ctx context.Context, host, repository, challenge string) error {
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		return fmt.Errorf("access to %s/%s denied: use a pull secret with access to it", host, repository)
+	}
+	params := make(map[string]string)
+	for _, m := range challengeParam.FindAllStringSubmatch(challenge, -1) {
+		params[strings.ToLower(m[1])] = m[2]
+	}
+	if params["realm"] == "" {
+		return fmt.Errorf("registry %s asks for a token without a realm", host)
+	}
+
+	query := url.Values{}
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	query.Set("scope", "repository:"+repository+":pull")
+	realm := params["realm"]
+	if strings.Contains(realm, "?") {
+		realm += "&" + query.Encode()
+	} else {
+		realm += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", "drivio")
+	if auth := r.auths[host]; auth != "" {
+		req.Header.Set("Authorization", "Basic "+auth)
+	}
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to authenticate to %s: %w", host, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to authenticate to %s: status %d", host, resp.StatusCode)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return fmt.Errorf("failed to authenticate to %s: %w", host, err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	r.tokens[host+"/"+repository] = token.Token
+	return nil
+}
+
+// splitRepository splits an image repository into the host of its registry
+// and its path; images without a registry are on Docker Hub
+func splitRepository(repository string) (host, name string) {
+	host, name, found := strings.Cut(repository, "/")
+	if !found || !strings.ContainsAny(host, ".:") && host != "localhost" {
+		host, name = "registry-1.docker.io", repository
+		if !strings.Contains(name, "/") {
+			name = "library/" + name
+		}
+	}
+	if host == "docker.io" {
+		host = "registry-1.docker.io"
+	}
+	return host, name
+}
+
+// scheme returns the scheme of a registry: registries on the local host
+// are served over plain HTTP, as container runtimes allow by default
+func scheme(host string) string {
+	name, _, _ := strings.Cut(host, ":")
+	if name == "localhost" || name == "127.0.0.1" {
+		return "http"
+	}
+	return "https"
+}
+
+// ReleaseImage returns the pull spec of a release: versions like 4.15.2 are
+// the release images of DefaultRepository for the architecture, e.g.
+// quay.io/openshift-release-dev/ocp-release:4.15.2-x86_64, and pull specs
+// are kept as they are
+func ReleaseImage(release, architecture string) string {
+	if !releaseVersion.MatchString(release) {
+		return release
+	}
+	suffix := map[string]string{"amd64": "x86_64", "arm64": "aarch64"}[architecture]
+	if suffix == "" {
+		suffix = architecture
+	}
+	return DefaultRepository + ":" + release + "-" + suffix
+}
+
+// releaseVersion matches the versions of OpenShift releases, e.g. 4.15.2 or
+// 4.16.0-rc.1
+var releaseVersion = regexp.MustCompile(`^\d+\.\d+\.\d+(-[0-9A-Za-z.]+)?$`)
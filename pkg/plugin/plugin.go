@@ -0,0 +1,263 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Prefix is the prefix of the names of the plugin executables: the plugin
+// mychat is the drivio-mychat executable on PATH
+const Prefix = "drivio-"
+
+// ProtocolVersion is the version of the protocol sent with every request
+const ProtocolVersion = 1
+
+// ProtocolArg is the argument plugins are run with to answer a request: the
+// request is a JSON object on stdin, the response a JSON object on stdout.
+// Plugins run without it are subcommands of drivio.
+const ProtocolArg = "--drivio-plugin"
+
+// Capabilities a plugin declares in its description
+const (
+	// CapabilityNotify is a publish target, answering notify
+	CapabilityNotify = "notify"
+	// CapabilityTickets is a ticket system, answering tickets.transition
+	CapabilityTickets = "tickets"
+	// CapabilitySCM is a repository provider, answering scm.archive
+	CapabilitySCM = "scm"
+)
+
+// Methods of the protocol
+const (
+	MethodDescribe   = "describe"
+	MethodNotify     = "notify"
+	MethodTransition = "tickets.transition"
+	MethodArchive    = "scm.archive"
+)
+
+// DescribeTimeout bounds the describe requests, which should answer at once
+const DescribeTimeout = 5 * time.Second
+
+// ErrNotFound is returned for plugins without an executable on PATH
+var ErrNotFound = errors.New("plugin not found")
+
+// Stderr receives the messages plugins write on their stderr
+var Stderr io.Writer = os.Stderr
+
+// Plugin is a drivio-NAME executable
+type Plugin struct {
+	Name string
+	Path string
+	// Shadowed is set for executables hidden by another one with the same
+	// name earlier on PATH
+	Shadowed bool
+}
+
+// Info is the description of a plugin, its answer to describe
+type Info struct {
+	Name         string   `json:"name"`
+	Version      string   `json:"version,omitempty"`
+	Description  string   `json:"description,omitempty"`
+	Capabilities []string `json:"capabilities,omitempty"`
+}
+
+// Supports reports whether the plugin declares a capability
+func (i *Info) Supports(capability string) bool {
+	for _, c := range i.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// NotifyParams are the parameters of notify: a message for a channel of the
+// configuration file, with its settings
+type NotifyParams struct {
+	Channel string            `json:"channel"`
+	Options map[string]string `json:"options,omitempty"`
+	Title   string            `json:"title"`
+	Text    string            `json:"text,omitempty"`
+	URL     string            `json:"url,omitempty"`
+}
+
+// TransitionParams are the parameters of tickets.transition: move a ticket
+// to a status, or with DryRun tell whether it can
+type TransitionParams struct {
+	Ticket  string `json:"ticket"`
+	Status  string `json:"status"`
+	Comment string `json:"comment,omitempty"`
+	DryRun  bool   `json:"dry_run,omitempty"`
+}
+
+// TransitionResult is the result of tickets.transition
+type TransitionResult struct {
+	// From is the status of the ticket before the transition
+	From string `json:"from"`
+	// To is the status it moved, or would move, to; empty when it was in
+	// the status already
+	To string `json:"to,omitempty"`
+}
+
+// ArchiveParams are the parameters of scm.archive: write the tar.gz archive
+// of a repository at a reference to Path
+type ArchiveParams struct {
+	Repo string `json:"repo"`
+	Ref  string `json:"ref"`
+	Path string `json:"path"`
+}
+
+// request is a request sent on the stdin of a plugin
+type request struct {
+	Protocol int         `json:"protocol"`
+	Method   string      `json:"method"`
+	Params   interface{} `json:"params,omitempty"`
+}
+
+// response is the answer of a plugin on its stdout
+type response struct {
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// Find returns the plugin of a name, the first drivio-NAME executable on
+// PATH
+func Find(name string) (*Plugin, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return nil, fmt.Errorf("invalid plugin name %q", name)
+	}
+	path, err := exec.LookPath(Prefix + name)
+	if err != nil {
+		return nil, fmt.Errorf("%w: no %s%s executable on PATH", ErrNotFound, Prefix, name)
+	}
+	return &Plugin{Name: name, Path: path}, nil
+}
+
+// Discover returns the plugins on PATH, in the order of PATH, including
+// those shadowed by a plugin with the same name
+func Discover() []Plugin {
+	var plugins []Plugin
+	seen := make(map[string]bool)
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		// An empty entry is the current directory, which is not searched
+		if dir == "" {
+			continue
+		}
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name, ok := pluginName(entry.Name())
+			if !ok {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			info, err := os.Stat(path)
+			if err != nil || info.IsDir() || !executable(info) {
+				continue
+			}
+			plugins = append(plugins, Plugin{Name: name, Path: path, Shadowed: seen[name]})
+			seen[name] = true
+		}
+	}
+	return plugins
+}
+
+// pluginName returns the name of the plugin of an executable file name
+func pluginName(file string) (string, bool) {
+	name, ok := strings.CutPrefix(file, Prefix)
+	if !ok {
+		return "", false
+	}
+	if runtime.GOOS == "windows" {
+		ext := strings.ToLower(filepath.Ext(name))
+		if ext != ".exe" && ext != ".bat" && ext != ".cmd" {
+			return "", false
+		}
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	return name, name != ""
+}
+
+// executable reports whether a file can be run; on Windows, any file with
+// an executable extension can
+func executable(info os.FileInfo) bool {
+	return runtime.GOOS == "windows" || info.Mode()&0111 != 0
+}
+
+// Describe asks the plugin for its description
+func (p *Plugin) Describe(ctx context.Context) (*Info, error) {
+	ctx, cancel := context.WithTimeout(ctx, DescribeTimeout)
+	defer cancel()
+	var info Info
+	if err := p.Call(ctx, MethodDescribe, nil, &info); err != nil {
+		return nil, err
+	}
+	if info.Name == "" {
+		info.Name = p.Name
+	}
+	return &info, nil
+}
+
+// Require returns an error unless the plugin declares a capability
+func (p *Plugin) Require(ctx context.Context, capability string) error {
+	info, err := p.Describe(ctx)
+	if err != nil {
+		return err
+	}
+	if !info.Supports(capability) {
+		return fmt.Errorf("plugin %s does not support %s (capabilities: %s)", p.Name, capability, strings.Join(info.Capabilities, ", "))
+	}
+	return nil
+}
+
+// Call runs the plugin with a request and decodes the result of its
+// response into result, unless nil. Errors in the response are returned as
+// errors.
+func (p *Plugin) Call(ctx context.Context, method string, params, result interface{}) error {
+	body, err := json.Marshal(request{Protocol: ProtocolVersion, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.Path, ProtocolArg)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Stdout = &stdout
+	cmd.Stderr = Stderr
+	runErr := cmd.Run()
+	if ctx.Err() != nil {
+		return fmt.Errorf("plugin %s: %s: %w", p.Name, method, ctx.Err())
+	}
+
+	var resp response
+	if err := json.Unmarshal(stdout.Bytes(), &resp); err != nil {
+		if runErr != nil {
+			return fmt.Errorf("plugin %s: %s: %w", p.Name, method, runErr)
+		}
+		return fmt.Errorf("plugin %s: %s: invalid response: %w", p.Name, method, err)
+	}
+	if resp.Error != "" {
+		return fmt.Errorf("plugin %s: %s", p.Name, resp.Error)
+	}
+	if runErr != nil {
+		return fmt.Errorf("plugin %s: %s: %w", p.Name, method, runErr)
+	}
+	if result != nil && len(resp.Result) > 0 {
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("plugin %s: %s: invalid result: %w", p.Name, method, err)
+		}
+	}
+	return nil
+}
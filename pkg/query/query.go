@@ -0,0 +1,170 @@
+package query
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNotFound is returned when a path does not exist in a document
+var ErrNotFound = errors.New("not found")
+
+// segment is a single step of a path: a mapping key or a sequence index
+type segment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+func (s segment) String() string {
+	if s.isIndex {
+		return fmt.Sprintf("[%d]", s.index)
+	}
+	return "." + s.key
+}
+
+// parse parses a yq-style path such as .database.connections.ci.host,
+// .servers[0].name or .annotations["app.kubernetes.io/name"]
+func parse(path string) ([]segment, error) {
+	path = strings.TrimSpace(path)
+	if path == "" || path == "." {
+		return nil, nil
+	}
+	if !strings.HasPrefix(path, ".") && !strings.HasPrefix(path, "[") {
+		path = "." + path
+	}
+
+	var segments []segment
+	for i := 0; i < len(path); {
+		switch path[i] {
+		case '.':
+			i++
+			if i < len(path) && path[i] == '[' {
+				continue
+			}
+			start := i
+			for i < len(path) && path[i] != '.' && path[i] != '[' {
+				i++
+			}
+			if start == i {
+				return nil, fmt.Errorf("invalid path %q: empty key at position %d", path, start)
+			}
+			segments = append(segments, segment{key: path[start:i]})
+		case '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %q: missing ]", path)
+			}
+			inner := path[i+1 : i+end]
+			i += end + 1
+
+			if len(inner) >= 2 && (inner[0] == '"' || inner[0] == '\'') && inner[len(inner)-1] == inner[0] {
+				segments = append(segments, segment{key: inner[1 : len(inner)-1]})
+				continue
+			}
+			index, err := strconv.Atoi(inner)
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %q: %q is not an index or a quoted key", path, inner)
+			}
+			segments = append(segments, segment{index: index, isIndex: true})
+		default:
+			return nil, fmt.Errorf("invalid path %q: unexpected %q at position %d", path, path[i], i)
+		}
+	}
+
+	return segments, nil
+}
+
+// Validate checks the syntax of a path without evaluating it
+func Validate(path string) error {
+	_, err := parse(path)
+	return err
+}
+
+// Extract evaluates the path against a YAML (or JSON) document. Scalars are
+// returned as their raw value followed by a newline; mappings and sequences
+// are returned as YAML documents.
+func Extract(content []byte, path string) ([]byte, error) {
+	segments, err := parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("failed to parse document: %w", err)
+	}
+	if len(document.Content) == 0 {
+		return nil, fmt.Errorf("document is empty")
+	}
+
+	node := document.Content[0]
+	walked := ""
+	for _, seg := range segments {
+		node = resolveAlias(node)
+		next, err := step(node, seg)
+		if err != nil {
+			if walked == "" {
+				walked = "."
+			}
+			return nil, fmt.Errorf("path %s %w: %v (at %s)", path, ErrNotFound, err, walked)
+		}
+		node = next
+		walked += seg.String()
+	}
+	node = resolveAlias(node)
+
+	if node.Kind == yaml.ScalarNode {
+		return []byte(node.Value + "\n"), nil
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return nil, fmt.Errorf("failed to encode result: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// step moves from node to the child designated by the segment
+func step(node *yaml.Node, seg segment) (*yaml.Node, error) {
+	if seg.isIndex {
+		if node.Kind != yaml.SequenceNode {
+			return nil, fmt.Errorf("cannot index a non-list value with [%d]", seg.index)
+		}
+		index := seg.index
+		if index < 0 {
+			index += len(node.Content)
+		}
+		if index < 0 || index >= len(node.Content) {
+			return nil, fmt.Errorf("index [%d] out of range (length %d)", seg.index, len(node.Content))
+		}
+		return node.Content[index], nil
+	}
+
+	if node.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("cannot look up key %q in a non-mapping value", seg.key)
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == seg.key {
+			return node.Content[i+1], nil
+		}
+	}
+	return nil, fmt.Errorf("key %q does not exist", seg.key)
+}
+
+// resolveAlias follows YAML aliases (*anchor) to the anchored node
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
@@ -0,0 +1,161 @@
+package query
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Set replaces the scalar at path, e.g. .image.tag, with value and returns
+// the updated document with the previous value. Only the bytes of the
+// scalar change, so comments, indentation and key order are kept, as in a
+// change made by hand. The quoting of the scalar is kept too, except that a
+// plain scalar is double-quoted when the value would otherwise change its
+// type, e.g. true replacing a version.
+func Set(content []byte, path, value string) ([]byte, string, error) {
+	segments, err := parse(path)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(segments) == 0 {
+		return nil, "", fmt.Errorf("cannot replace the whole document")
+	}
+
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, "", fmt.Errorf("failed to parse document: %w", err)
+	}
+	if len(document.Content) == 0 {
+		return nil, "", fmt.Errorf("document is empty")
+	}
+
+	node := document.Content[0]
+	for _, seg := range segments {
+		next, err := step(resolveAlias(node), seg)
+		if err != nil {
+			return nil, "", fmt.Errorf("path %s not found: %v", path, err)
+		}
+		node = next
+	}
+	if node.Kind == yaml.AliasNode {
+		return nil, "", fmt.Errorf("%s is an alias; set the anchored value instead", path)
+	}
+	if node.Kind != yaml.ScalarNode {
+		return nil, "", fmt.Errorf("%s is not a scalar value", path)
+	}
+
+	start, end, err := scalarSpan(content, node)
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot replace %s: %w", path, err)
+	}
+
+	var replacement string
+	switch node.Style {
+	case yaml.DoubleQuotedStyle:
+		replacement = strconv.Quote(value)
+	case yaml.SingleQuotedStyle:
+		replacement = "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	default:
+		replacement = plainOrQuoted(value, node.Tag)
+	}
+
+	updated := make([]byte, 0, len(content)+len(replacement))
+	updated = append(updated, content[:start]...)
+	updated = append(updated, replacement...)
+	updated = append(updated, content[end:]...)
+	return updated, node.Value, nil
+}
+
+// scalarSpan returns the offsets of a single-line scalar in the document
+func scalarSpan(content []byte, node *yaml.Node) (int, int, error) {
+	if node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		return 0, 0, fmt.Errorf("block scalars are not supported")
+	}
+
+	// Line and Column are 1-based and count characters; YAML keys of
+	// configuration files are ASCII in practice, but count runes anyway
+	lines := bytes.SplitAfter(content, []byte("\n"))
+	if node.Line < 1 || node.Line > len(lines) {
+		return 0, 0, fmt.Errorf("scalar position out of the document")
+	}
+	offset := 0
+	for _, line := range lines[:node.Line-1] {
+		offset += len(line)
+	}
+	line := lines[node.Line-1]
+	column := 0
+	for i := 0; i < node.Column-1 && column < len(line); i++ {
+		_, size := utf8.DecodeRune(line[column:])
+		column += size
+	}
+	rest := line[column:]
+
+	// The position is that of the anchor or tag preceding the value, e.g.
+	// &default v1 or !!str 1.10
+	for len(rest) > 0 && (rest[0] == '&' || rest[0] == '!') {
+		token := bytes.IndexAny(rest, " \t")
+		if token < 0 {
+			return 0, 0, fmt.Errorf("scalar not found after %s", rest)
+		}
+		spaces := len(rest[token:]) - len(bytes.TrimLeft(rest[token:], " \t"))
+		column += token + spaces
+		rest = line[column:]
+	}
+
+	var length int
+	switch node.Style {
+	case yaml.DoubleQuotedStyle:
+		length = quotedLength(rest, '"', true)
+	case yaml.SingleQuotedStyle:
+		length = quotedLength(rest, '\'', false)
+	default:
+		if !bytes.HasPrefix(rest, []byte(node.Value)) {
+			return 0, 0, fmt.Errorf("multi-line scalars are not supported")
+		}
+		length = len(node.Value)
+	}
+	if length < 0 {
+		return 0, 0, fmt.Errorf("multi-line scalars are not supported")
+	}
+	return offset + column, offset + column + length, nil
+}
+
+// quotedLength returns the length of the quoted scalar at the start of s,
+// quotes included, or -1 when it does not end on the same line
+func quotedLength(s []byte, quote byte, backslashEscapes bool) int {
+	for i := 1; i < len(s); i++ {
+		switch {
+		case s[i] == '\n':
+			return -1
+		case backslashEscapes && s[i] == '\\':
+			i++
+		case s[i] == quote:
+			// '' is an escaped quote in single-quoted scalars
+			if !backslashEscapes && i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// plainOrQuoted returns value as a plain scalar when it reads back as the
+// same string, or as a string when the previous value, of tag, was not one;
+// double-quoted otherwise
+func plainOrQuoted(value, tag string) string {
+	var decoded yaml.Node
+	if strings.TrimSpace(value) == value && !strings.ContainsAny(value, "\n#") &&
+		yaml.Unmarshal([]byte("v: "+value), &decoded) == nil && len(decoded.Content) == 1 {
+		scalar := decoded.Content[0].Content[1]
+		if scalar.Kind == yaml.ScalarNode && scalar.Value == value && (scalar.Tag == "!!str" || scalar.Tag == tag) {
+			return value
+		}
+	}
+	return strconv.Quote(value)
+}
@@ -0,0 +1,112 @@
+package redact
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Mask replaces redacted values
+const Mask = "********"
+
+// DefaultPatterns match the keys whose values are redacted by default
+var DefaultPatterns = []string{"password", "passwd", "token", "secret", "key", "credential"}
+
+// Redactor hides the values of sensitive keys in documents
+type Redactor struct {
+	keyPattern  *regexp.Regexp
+	linePattern *regexp.Regexp
+}
+
+// New creates a redactor for keys matching any of the given patterns
+// (case-insensitive regular expressions)
+func New(patterns []string) (*Redactor, error) {
+	if len(patterns) == 0 {
+		patterns = DefaultPatterns
+	}
+
+	for _, p := range patterns {
+		if _, err := regexp.Compile(p); err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %w", p, err)
+		}
+	}
+	alternatives := "(?:" + strings.Join(patterns, "|") + ")"
+
+	return &Redactor{
+		keyPattern: regexp.MustCompile(`(?i)` + alternatives),
+		// KEY=value and key: value lines of non-YAML files (.env, properties)
+		linePattern: regexp.MustCompile(`(?i)^(\s*(?:export\s+)?[\w.-]*` + alternatives + `[\w.-]*\s*[:=]\s*)(\S.*)$`),
+	}, nil
+}
+
+// IsSensitive reports whether values of the key are redacted
+func (r *Redactor) IsSensitive(key string) bool {
+	return r.keyPattern.MatchString(key)
+}
+
+// Redact returns the content with the values of sensitive keys masked, and
+// the number of values redacted. YAML and JSON documents are redacted
+// structurally, other content line by line.
+func (r *Redactor) Redact(content []byte) ([]byte, int) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err == nil && len(document.Content) > 0 {
+		if kind := document.Content[0].Kind; kind == yaml.MappingNode || kind == yaml.SequenceNode {
+			count := r.redactNode(&document, false)
+			if count == 0 {
+				return content, 0
+			}
+
+			var buf bytes.Buffer
+			encoder := yaml.NewEncoder(&buf)
+			encoder.SetIndent(2)
+			if err := encoder.Encode(&document); err == nil && encoder.Close() == nil {
+				return buf.Bytes(), count
+			}
+		}
+	}
+
+	return r.redactLines(content)
+}
+
+// redactNode masks scalar values below sensitive keys
+func (r *Redactor) redactNode(node *yaml.Node, sensitive bool) int {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if sensitive && node.Value != "" {
+			node.Value = Mask
+			node.Tag = "!!str"
+			node.Style = yaml.DoubleQuotedStyle
+			return 1
+		}
+		return 0
+	case yaml.MappingNode:
+		count := 0
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keySensitive := sensitive || r.keyPattern.MatchString(node.Content[i].Value)
+			count += r.redactNode(node.Content[i+1], keySensitive)
+		}
+		return count
+	default:
+		count := 0
+		for _, child := range node.Content {
+			count += r.redactNode(child, sensitive)
+		}
+		return count
+	}
+}
+
+// redactLines masks values of KEY=value and key: value lines
+func (r *Redactor) redactLines(content []byte) ([]byte, int) {
+	count := 0
+	lines := strings.Split(string(content), "\n")
+	for i, line := range lines {
+		if m := r.linePattern.FindStringSubmatch(line); m != nil {
+			lines[i] = m[1] + Mask
+			count++
+		}
+	}
+	return []byte(strings.Join(lines, "\n")), count
+}
@@ -0,0 +1,88 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Data is the data made available to templates.
+// Values are accessible as {{ .Values.key }} and environment
+// variables as {{ .Env.NAME }}.
+type Data struct {
+	Values map[string]interface{}
+	Env    map[string]string
+}
+
+// LoadValues reads a YAML values file
+func LoadValues(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read values file: %w", err)
+	}
+
+	values := make(map[string]interface{})
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse values file %s: %w", path, err)
+	}
+
+	return values, nil
+}
+
+// NewData builds template data from the given values and the process environment
+func NewData(values map[string]interface{}) Data {
+	if values == nil {
+		values = make(map[string]interface{})
+	}
+
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) == 2 {
+			env[parts[0]] = parts[1]
+		}
+	}
+
+	return Data{Values: values, Env: env}
+}
+
+// Render treats content as a Go template and executes it with the given data.
+// Missing keys are reported as errors instead of rendering "<no value>".
+func Render(name string, content []byte, data Data) ([]byte, error) {
+	tmpl, err := template.New(name).
+		Option("missingkey=error").
+		Funcs(funcMap()).
+		Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render template: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// funcMap returns the helper functions available in templates
+func funcMap() template.FuncMap {
+	return template.FuncMap{
+		"env": os.Getenv,
+		"default": func(def, value interface{}) interface{} {
+			if value == nil || value == "" {
+				return def
+			}
+			return value
+		},
+		"quote": func(value interface{}) string {
+			return fmt.Sprintf("%q", fmt.Sprint(value))
+		},
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+	}
+}
@@ -0,0 +1,127 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"drivio/pkg/telemetry"
+)
+
+// Default values for the retry policy
+const (
+	DefaultMaxAttempts    = 3
+	DefaultInitialBackoff = 1 * time.Second
+	DefaultMaxBackoff     = 30 * time.Second
+)
+
+// Policy describes how transient failures are retried
+type Policy struct {
+	// MaxAttempts is the total number of attempts, including the first one
+	MaxAttempts int
+	// InitialBackoff is the wait time before the first retry; it doubles on
+	// every subsequent retry up to MaxBackoff
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// OnRetry, if set, is called before waiting for the next attempt
+	OnRetry func(attempt int, err error, wait time.Duration)
+}
+
+// DefaultPolicy returns the default retry policy
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts:    DefaultMaxAttempts,
+		InitialBackoff: DefaultInitialBackoff,
+		MaxBackoff:     DefaultMaxBackoff,
+	}
+}
+
+// Operation is a single attempt of a retryable call. It returns the HTTP
+// response (which may be nil) so the status code can be inspected.
+type Operation func() (*http.Response, error)
+
+// Do runs the operation, retrying it according to the policy while it fails
+// with a transient error
+func Do(ctx context.Context, policy Policy, operation Operation) error {
+	attempts := policy.MaxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		var resp *http.Response
+		resp, err = operation()
+		if err == nil || ctx.Err() != nil || !IsTransient(resp, err) || attempt == attempts {
+			return err
+		}
+
+		wait := policy.backoff(attempt)
+		if policy.OnRetry != nil {
+			policy.OnRetry(attempt, err, wait)
+		}
+		telemetry.RecordRetryWait(ctx, resp != nil && resp.StatusCode == http.StatusTooManyRequests, attempt, err, wait)
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w (giving up after %d attempts: %v)", ctx.Err(), attempt, err)
+		case <-time.After(wait):
+		}
+	}
+
+	return err
+}
+
+// backoff returns the wait time before the retry following the given
+// attempt, using exponential backoff with jitter
+func (p Policy) backoff(attempt int) time.Duration {
+	wait := p.InitialBackoff
+	if wait <= 0 {
+		wait = DefaultInitialBackoff
+	}
+	for i := 1; i < attempt; i++ {
+		wait *= 2
+		if p.MaxBackoff > 0 && wait >= p.MaxBackoff {
+			wait = p.MaxBackoff
+			break
+		}
+	}
+
+	// Add up to 20% jitter so concurrent clients don't retry in lockstep
+	jitter := time.Duration(rand.Int63n(int64(wait)/5 + 1))
+	return wait + jitter
+}
+
+// IsTransient reports whether a failed call is worth retrying: server errors
+// (5xx), rate limiting (429), timeouts, and dropped connections
+func IsTransient(resp *http.Response, err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	if resp != nil {
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			return true
+		}
+		if resp.StatusCode >= 400 {
+			return false
+		}
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF)
+}
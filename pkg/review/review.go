@@ -0,0 +1,226 @@
+package review
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"drivio/pkg/github"
+	"drivio/pkg/gitlab"
+)
+
+// File is a file changed by a request
+type File struct {
+	Path    string
+	Content []byte
+	// Revision is the revision of the file the change was made on: on GitLab
+	// the last commit of the file, on GitHub its blob SHA. When set, opening
+	// the request fails if the file changed since.
+	Revision string
+	// New is set for files that do not exist yet
+	New bool
+}
+
+// Request describes changes to land through review: committed to a new
+// source branch created from the target branch, and proposed for merging
+// into it
+type Request struct {
+	SourceBranch string
+	TargetBranch string
+	Title        string
+	// Message is the message of the commit; the title when empty
+	Message string
+	// Description is Markdown, e.g. release notes
+	Description string
+	Files       []File
+	Labels      []string
+	// Reviewers are usernames; on GitHub, org/team names a team
+	Reviewers []string
+	// RemoveSourceBranch deletes the source branch once merged, where the
+	// provider supports it per request
+	RemoveSourceBranch bool
+}
+
+// Result is an opened request
+type Result struct {
+	URL string
+	// Commit is the commit of the changes on the source branch
+	Commit string
+}
+
+// Provider opens requests for review on a hosting service: merge requests on
+// GitLab, pull requests on GitHub
+type Provider interface {
+	// Kind names the requests of the provider, e.g. "merge request"
+	Kind() string
+	// Open commits the files to the new source branch and opens the request
+	Open(ctx context.Context, req Request) (*Result, error)
+}
+
+// gitLabProvider opens merge requests in the repository a GitLab client is
+// configured with
+type gitLabProvider struct {
+	client *gitlab.Client
+}
+
+// NewGitLab returns the provider of merge requests in the repository of a
+// GitLab client
+func NewGitLab(client *gitlab.Client) Provider {
+	return gitLabProvider{client: client}
+}
+
+func (p gitLabProvider) Kind() string {
+	return "merge request"
+}
+
+func (p gitLabProvider) Open(ctx context.Context, req Request) (*Result, error) {
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+
+	// Reviewers are looked up first, so a typo does not leave a branch behind
+	reviewers, err := p.client.UserIDs(ctx, req.Reviewers)
+	if err != nil {
+		return nil, err
+	}
+
+	commit := gitlab.FileCommit{
+		Branch:      req.SourceBranch,
+		StartBranch: req.TargetBranch,
+		Message:     req.message(),
+	}
+	for _, file := range req.Files {
+		commit.Files = append(commit.Files, gitlab.FileRevision{Path: file.Path, Content: file.Content, LastCommitID: file.Revision})
+		if file.New {
+			commit.Created = append(commit.Created, file.Path)
+		}
+	}
+	created, err := p.client.CommitFiles(ctx, commit)
+	if err != nil {
+		return nil, err
+	}
+
+	mr, err := p.client.CreateMergeRequest(ctx, gitlab.MergeRequestOptions{
+		SourceBranch:       req.SourceBranch,
+		TargetBranch:       req.TargetBranch,
+		Title:              req.Title,
+		Description:        req.Description,
+		Labels:             req.Labels,
+		ReviewerIDs:        reviewers,
+		RemoveSourceBranch: req.RemoveSourceBranch,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Result{URL: mr.WebURL, Commit: created.ID}, nil
+}
+
+// gitHubProvider opens pull requests in a GitHub repository
+type gitHubProvider struct {
+	client      *github.Client
+	owner, repo string
+}
+
+// NewGitHub returns the provider of pull requests in a GitHub repository. An
+// empty target branch is the default branch of the repository. Files are
+// committed as text.
+func NewGitHub(client *github.Client, owner, repo string) Provider {
+	return gitHubProvider{client: client, owner: owner, repo: repo}
+}
+
+func (p gitHubProvider) Kind() string {
+	return "pull request"
+}
+
+func (p gitHubProvider) Open(ctx context.Context, req Request) (*Result, error) {
+	if req.TargetBranch == "" {
+		branch, err := p.client.DefaultBranch(ctx, p.owner, p.repo)
+		if err != nil {
+			return nil, err
+		}
+		req.TargetBranch = branch
+	}
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+
+	head, err := p.client.BranchHead(ctx, p.owner, p.repo, req.TargetBranch)
+	if err != nil {
+		return nil, err
+	}
+	files := make([]github.TreeFile, 0, len(req.Files))
+	for _, file := range req.Files {
+		if err := p.checkRevision(ctx, req.TargetBranch, file); err != nil {
+			return nil, err
+		}
+		files = append(files, github.TreeFile{Path: file.Path, Content: file.Content})
+	}
+
+	sha, err := p.client.CommitFiles(ctx, p.owner, p.repo, github.CommitOptions{Parent: head, Message: req.message(), Files: files})
+	if err != nil {
+		return nil, err
+	}
+	if err := p.client.CreateBranch(ctx, p.owner, p.repo, req.SourceBranch, sha); err != nil {
+		return nil, err
+	}
+
+	pr, err := p.client.CreatePullRequest(ctx, p.owner, p.repo, github.PullRequestOptions{
+		Head:      req.SourceBranch,
+		Base:      req.TargetBranch,
+		Title:     req.Title,
+		Body:      req.Description,
+		Labels:    req.Labels,
+		Reviewers: req.Reviewers,
+	})
+	if err != nil {
+		if pr != nil {
+			// Opened, but not labeled or without reviewers
+			return &Result{URL: pr.HTMLURL, Commit: sha}, err
+		}
+		return nil, err
+	}
+	return &Result{URL: pr.HTMLURL, Commit: sha}, nil
+}
+
+// checkRevision fails when a file changed in the branch since its revision,
+// or exists when it is new
+func (p gitHubProvider) checkRevision(ctx context.Context, branch string, file File) error {
+	if file.Revision == "" && !file.New {
+		return nil
+	}
+	current, err := p.client.FileSHA(ctx, p.owner, p.repo, branch, file.Path)
+	switch {
+	case errors.Is(err, github.ErrNotFound):
+		if file.New {
+			return nil
+		}
+		return fmt.Errorf("%s was removed from branch %s", file.Path, branch)
+	case err != nil:
+		return err
+	case file.New:
+		return fmt.Errorf("%s was created in branch %s", file.Path, branch)
+	case current != file.Revision:
+		return fmt.Errorf("%s changed in branch %s since it was read", file.Path, branch)
+	}
+	return nil
+}
+
+func (r Request) validate() error {
+	if r.SourceBranch == "" || r.TargetBranch == "" {
+		return fmt.Errorf("source and target branches are required")
+	}
+	if r.SourceBranch == r.TargetBranch {
+		return fmt.Errorf("source branch %s is the target branch", r.SourceBranch)
+	}
+	if len(r.Files) == 0 {
+		return fmt.Errorf("no files to commit")
+	}
+	return nil
+}
+
+func (r Request) message() string {
+	if r.Message != "" {
+		return r.Message
+	}
+	return r.Title
+}
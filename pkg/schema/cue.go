@@ -0,0 +1,106 @@
+package schema
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"cuelang.org/go/cue"
+	"cuelang.org/go/cue/cuecontext"
+	cueerrors "cuelang.org/go/cue/errors"
+	"cuelang.org/go/cue/load"
+	cueyaml "cuelang.org/go/encoding/yaml"
+)
+
+// ValidateCUE validates a YAML (or JSON) document against a CUE definition.
+// The spec is the path of a CUE file, optionally followed by the definition
+// to validate against (e.g. schema.cue#Config); without a definition the
+// document is unified with the whole file.
+func ValidateCUE(content []byte, spec string) error {
+	validator, err := NewCUEValidator(spec)
+	if err != nil {
+		return err
+	}
+	return validator.Validate(content)
+}
+
+// NewCUEValidator loads a CUE definition, given as for ValidateCUE
+func NewCUEValidator(spec string) (*Validator, error) {
+	path, definition, _ := strings.Cut(spec, "#")
+	if path == "" {
+		return nil, fmt.Errorf("invalid CUE schema %q (use FILE.cue#Definition)", spec)
+	}
+
+	ctx := cuecontext.New()
+	schemaValue, err := loadCUE(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if definition != "" {
+		schemaValue = schemaValue.LookupPath(cue.MakePath(cue.Def(definition)))
+		if !schemaValue.Exists() {
+			return nil, fmt.Errorf("definition #%s not found in %s", definition, path)
+		}
+	}
+	return &Validator{validate: func(content []byte) error {
+		return validateCUE(ctx, schemaValue, content)
+	}}, nil
+}
+
+func validateCUE(ctx *cue.Context, schemaValue cue.Value, content []byte) error {
+	file, err := cueyaml.Extract("document.yaml", content)
+	if err != nil {
+		return fmt.Errorf("failed to parse document: %w", err)
+	}
+	document := ctx.BuildFile(file)
+	if err := document.Err(); err != nil {
+		return fmt.Errorf("failed to parse document: %w", err)
+	}
+
+	if err := schemaValue.Unify(document).Validate(cue.Concrete(true)); err != nil {
+		return &ValidationError{Violations: cueViolations(err)}
+	}
+	return nil
+}
+
+// loadCUE loads and builds a CUE file, resolving its imports from the CUE
+// module it belongs to, if any
+func loadCUE(ctx *cue.Context, path string) (cue.Value, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return cue.Value{}, fmt.Errorf("failed to resolve CUE schema path: %w", err)
+	}
+
+	instances := load.Instances([]string{absPath}, &load.Config{Dir: filepath.Dir(absPath)})
+	if len(instances) == 0 {
+		return cue.Value{}, fmt.Errorf("failed to load CUE schema %s", path)
+	}
+	if err := instances[0].Err; err != nil {
+		return cue.Value{}, fmt.Errorf("failed to load CUE schema: %w", err)
+	}
+
+	value := ctx.BuildInstance(instances[0])
+	if err := value.Err(); err != nil {
+		return cue.Value{}, fmt.Errorf("failed to compile CUE schema: %w", err)
+	}
+	return value, nil
+}
+
+// cueViolations flattens CUE errors into violations with JSON pointer paths
+func cueViolations(err error) []Violation {
+	var violations []Violation
+	for _, e := range cueerrors.Errors(err) {
+		format, args := e.Msg()
+		path := "/" + strings.Join(e.Path(), "/")
+		// Paths inside the schema start with the definition name, which
+		// means nothing to the reader of the document
+		if parts := e.Path(); len(parts) > 0 && strings.HasPrefix(parts[0], "#") {
+			path = "/" + strings.Join(parts[1:], "/")
+		}
+		violations = append(violations, Violation{
+			Path:    path,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+	return violations
+}
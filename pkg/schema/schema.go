@@ -0,0 +1,163 @@
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// Violation describes a single schema violation
+type Violation struct {
+	// Path is the JSON pointer of the offending value (e.g. /database/port)
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned when a document does not satisfy its schema
+type ValidationError struct {
+	Violations []Violation
+}
+
+func (e *ValidationError) Error() string {
+	var messages []string
+	for _, v := range e.Violations {
+		messages = append(messages, fmt.Sprintf("%s: %s", v.Path, v.Message))
+	}
+	return fmt.Sprintf("schema validation failed: %s", strings.Join(messages, "; "))
+}
+
+// ValidateYAML validates a YAML (or JSON) document against a JSON Schema file.
+// The schema itself may be written in JSON or YAML.
+func ValidateYAML(content []byte, schemaPath string) error {
+	validator, err := NewJSONSchemaValidator(schemaPath)
+	if err != nil {
+		return err
+	}
+	return validator.Validate(content)
+}
+
+// Validator validates documents against a schema compiled once, e.g. all
+// the files of a config repository
+type Validator struct {
+	validate func(content []byte) error
+}
+
+// Validate validates a YAML (or JSON) document, returning a
+// *ValidationError listing the violations when it does not satisfy the
+// schema
+func (v *Validator) Validate(content []byte) error {
+	return v.validate(content)
+}
+
+// NewJSONSchemaValidator compiles a JSON Schema file, written in JSON or
+// YAML
+func NewJSONSchemaValidator(schemaPath string) (*Validator, error) {
+	compiled, err := compileJSONSchema(schemaPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Validator{validate: func(content []byte) error {
+		return validateJSONSchema(compiled, content)
+	}}, nil
+}
+
+func validateJSONSchema(compiled *jsonschema.Schema, content []byte) error {
+	document, err := toJSONValue(content)
+	if err != nil {
+		return fmt.Errorf("failed to parse document: %w", err)
+	}
+
+	if err := compiled.Validate(document); err != nil {
+		var validationErr *jsonschema.ValidationError
+		if errors.As(err, &validationErr) {
+			return &ValidationError{Violations: collectViolations(validationErr)}
+		}
+		return err
+	}
+
+	return nil
+}
+
+// compileJSONSchema loads and compiles a JSON Schema from a JSON or YAML file
+func compileJSONSchema(schemaPath string) (*jsonschema.Schema, error) {
+	data, err := os.ReadFile(schemaPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+
+	ext := strings.ToLower(filepath.Ext(schemaPath))
+	if ext == ".yaml" || ext == ".yml" {
+		value, err := toJSONValue(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse schema %s: %w", schemaPath, err)
+		}
+		data, err = json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert schema %s: %w", schemaPath, err)
+		}
+	}
+
+	absPath, err := filepath.Abs(schemaPath)
+	if err != nil {
+		return nil, err
+	}
+
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(absPath, bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("failed to load schema %s: %w", schemaPath, err)
+	}
+
+	compiled, err := compiler.Compile(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile schema %s: %w", schemaPath, err)
+	}
+
+	return compiled, nil
+}
+
+// toJSONValue decodes YAML into the generic values produced by encoding/json,
+// which is what the JSON Schema validator expects
+func toJSONValue(content []byte) (interface{}, error) {
+	var value interface{}
+	if err := yaml.Unmarshal(content, &value); err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var result interface{}
+	if err := decoder.Decode(&result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// collectViolations flattens the validator's error tree into leaf violations
+func collectViolations(err *jsonschema.ValidationError) []Violation {
+	if len(err.Causes) == 0 {
+		path := err.InstanceLocation
+		if path == "" {
+			path = "/"
+		}
+		return []Violation{{Path: path, Message: err.Message}}
+	}
+
+	var violations []Violation
+	for _, cause := range err.Causes {
+		violations = append(violations, collectViolations(cause)...)
+	}
+	return violations
+}
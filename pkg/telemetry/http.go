@@ -0,0 +1,63 @@
+package telemetry
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RateLimitHeaders hold the remaining requests of the rate limit: GitLab
+// sends RateLimit-Remaining, GitHub X-RateLimit-Remaining
+var RateLimitHeaders = []string{"RateLimit-Remaining", "X-RateLimit-Remaining"}
+
+// RoundTrip performs a request with base, as a client span counted and
+// measured in the metrics. Only the path of the URL is recorded: its query
+// may carry credentials.
+func RoundTrip(base http.RoundTripper, req *http.Request) (*http.Response, error) {
+	if !enabled {
+		return base.RoundTrip(req)
+	}
+
+	attrs := []attribute.KeyValue{
+		attribute.String("http.request.method", req.Method),
+		attribute.String("server.address", req.URL.Hostname()),
+	}
+	ctx, span := tracer.Start(parent(req.Context()), req.Method+" "+req.URL.Hostname(),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+		trace.WithAttributes(attribute.String("url.path", req.URL.Path)))
+	defer span.End()
+
+	start := time.Now()
+	resp, err := base.RoundTrip(req.WithContext(ctx))
+	elapsed := time.Since(start).Seconds()
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		attrs = append(attrs, attribute.String("error.type", fmt.Sprintf("%T", err)))
+	} else {
+		attrs = append(attrs, attribute.Int("http.response.status_code", resp.StatusCode))
+		span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+		if resp.StatusCode >= 400 {
+			span.SetStatus(codes.Error, resp.Status)
+		}
+		for _, header := range RateLimitHeaders {
+			if remaining, convErr := strconv.ParseInt(resp.Header.Get(header), 10, 64); convErr == nil {
+				span.SetAttributes(attribute.Int64("drivio.rate_limit.remaining", remaining))
+				rateLimitRemaining.Record(ctx, remaining, metric.WithAttributes(attrs[1]))
+				break
+			}
+		}
+	}
+
+	requests.Add(ctx, 1, metric.WithAttributes(attrs...))
+	requestDuration.Record(ctx, elapsed, metric.WithAttributes(attrs...))
+	return resp, err
+}
@@ -0,0 +1,255 @@
+// Package telemetry exports traces and metrics of drivio with OpenTelemetry
+// over OTLP/HTTP. It is opt-in: nothing is recorded unless an OTLP endpoint
+// is configured with the standard OTEL_EXPORTER_OTLP_* environment
+// variables.
+//
+// A command run is a span, the parent of the spans of its API calls, and of
+// the runs of drivio it starts with TRACEPARENT set. The metrics count the
+// API calls and measure their durations, the waits before retries, e.g. on
+// rate limits, and the durations of the command runs.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Name is the name of the instrumentation and the default service name
+const Name = "drivio"
+
+// Environment variables configuring the export, read by the exporters
+const (
+	EnvEndpoint        = "OTEL_EXPORTER_OTLP_ENDPOINT"
+	EnvTracesEndpoint  = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+	EnvMetricsEndpoint = "OTEL_EXPORTER_OTLP_METRICS_ENDPOINT"
+	EnvProtocol        = "OTEL_EXPORTER_OTLP_PROTOCOL"
+	EnvDisabled        = "OTEL_SDK_DISABLED"
+	// EnvTraceParent carries the span of a run of drivio to the runs it
+	// starts, in the W3C traceparent format
+	EnvTraceParent = "TRACEPARENT"
+)
+
+var (
+	tracer = otel.Tracer(Name)
+	meter  = otel.Meter(Name)
+
+	enabled bool
+	// command is the span of the command run, the parent of the spans
+	// started without one
+	command      trace.Span
+	commandStart time.Time
+	commandName  string
+
+	requests, _ = meter.Int64Counter("drivio.http.client.requests",
+		metric.WithDescription("API calls, by method, server and status code"),
+		metric.WithUnit("{request}"))
+	requestDuration, _ = meter.Float64Histogram("http.client.request.duration",
+		metric.WithDescription("Duration of the API calls"),
+		metric.WithUnit("s"))
+	rateLimitRemaining, _ = meter.Int64Gauge("drivio.http.client.rate_limit.remaining",
+		metric.WithDescription("Requests left in the rate limit of the server, as last reported"),
+		metric.WithUnit("{request}"))
+	retryWait, _ = meter.Float64Histogram("drivio.retry.wait",
+		metric.WithDescription("Waits before retrying failed API calls, by reason: rate_limit or transient"),
+		metric.WithUnit("s"))
+	commandDuration, _ = meter.Float64Histogram("drivio.command.duration",
+		metric.WithDescription("Duration of the command runs, by command and status"),
+		metric.WithUnit("s"))
+)
+
+// Configured reports whether the export is configured: an OTLP endpoint is
+// set and the SDK is not disabled
+func Configured() bool {
+	if strings.EqualFold(os.Getenv(EnvDisabled), "true") {
+		return false
+	}
+	return os.Getenv(EnvEndpoint) != "" || os.Getenv(EnvTracesEndpoint) != "" || os.Getenv(EnvMetricsEndpoint) != ""
+}
+
+// Enabled reports whether telemetry is recorded
+func Enabled() bool {
+	return enabled
+}
+
+// Setup starts exporting the traces and metrics when the export is
+// configured, and returns the function flushing them and stopping the
+// export; a no-op otherwise. Export errors are passed to onError.
+func Setup(ctx context.Context, version string, onError func(error)) (func(context.Context) error, error) {
+	noop := func(context.Context) error { return nil }
+	if !Configured() {
+		return noop, nil
+	}
+	if protocol := os.Getenv(EnvProtocol); protocol != "" && protocol != "http/protobuf" {
+		return noop, fmt.Errorf("%s=%s is not supported: telemetry is exported over http/protobuf", EnvProtocol, protocol)
+	}
+
+	// The attributes and service name of the environment win
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			attribute.String("service.name", Name),
+			attribute.String("service.version", version),
+		),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return noop, fmt.Errorf("failed to describe the telemetry resource: %w", err)
+	}
+
+	var shutdowns []func(context.Context) error
+	shutdown := func(ctx context.Context) error {
+		var errs []error
+		for _, s := range shutdowns {
+			errs = append(errs, s(ctx))
+		}
+		return errors.Join(errs...)
+	}
+
+	if os.Getenv(EnvEndpoint) != "" || os.Getenv(EnvTracesEndpoint) != "" {
+		exporter, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return noop, fmt.Errorf("failed to create the trace exporter: %w", err)
+		}
+		provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+		otel.SetTracerProvider(provider)
+		shutdowns = append(shutdowns, provider.Shutdown)
+	}
+	if os.Getenv(EnvEndpoint) != "" || os.Getenv(EnvMetricsEndpoint) != "" {
+		exporter, err := otlpmetrichttp.New(ctx)
+		if err != nil {
+			shutdown(ctx)
+			return noop, fmt.Errorf("failed to create the metric exporter: %w", err)
+		}
+		provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)), sdkmetric.WithResource(res))
+		otel.SetMeterProvider(provider)
+		shutdowns = append(shutdowns, provider.Shutdown)
+	}
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	if onError != nil {
+		otel.SetErrorHandler(otel.ErrorHandlerFunc(onError))
+	}
+
+	enabled = true
+	return shutdown, nil
+}
+
+// StartCommand starts the span of a command run, e.g. "drivio fetch
+// archive", continuing the trace of TRACEPARENT when set
+func StartCommand(name string) {
+	if !enabled || command != nil {
+		return
+	}
+	ctx := context.Background()
+	if traceParent := os.Getenv(EnvTraceParent); traceParent != "" {
+		ctx = propagation.TraceContext{}.Extract(ctx, propagation.MapCarrier{"traceparent": traceParent})
+	}
+	_, command = tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindInternal))
+	commandStart = time.Now()
+	commandName = name
+}
+
+// EndCommand ends the span of the command run, recording its error and
+// duration
+func EndCommand(err error) {
+	if command == nil {
+		return
+	}
+	status := "ok"
+	if err != nil {
+		status = "error"
+		command.RecordError(err)
+		command.SetStatus(codes.Error, err.Error())
+	}
+	command.End()
+	commandDuration.Record(context.Background(), time.Since(commandStart).Seconds(), metric.WithAttributes(
+		attribute.String("command", commandName),
+		attribute.String("status", status),
+	))
+	command = nil
+}
+
+// Start starts a span, the child of the span of ctx, or of the span of the
+// command run when ctx has none. The returned function ends it, recording
+// its error.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, func(error)) {
+	return start(parent(ctx), name, trace.WithAttributes(attrs...))
+}
+
+// StartRoot starts a span beginning a new trace, e.g. the processing of a
+// webhook by a server, and returns the function ending it
+func StartRoot(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, func(error)) {
+	return start(ctx, name, trace.WithNewRoot(), trace.WithAttributes(attrs...))
+}
+
+func start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, func(error)) {
+	if !enabled {
+		return ctx, func(error) {}
+	}
+	ctx, span := tracer.Start(ctx, name, opts...)
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+// Environ returns the environment variable passing the span of ctx, or of
+// the command run, to a run of drivio it starts; nil when telemetry is not
+// recorded
+func Environ(ctx context.Context) []string {
+	if !enabled {
+		return nil
+	}
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(parent(ctx), carrier)
+	if carrier["traceparent"] == "" {
+		return nil
+	}
+	return []string{EnvTraceParent + "=" + carrier["traceparent"]}
+}
+
+// RecordRetryWait records the wait before retrying a failed call, on the
+// span of ctx and in the metrics; the reason is rate_limit when the server
+// throttled the call, transient otherwise
+func RecordRetryWait(ctx context.Context, rateLimited bool, attempt int, err error, wait time.Duration) {
+	if !enabled {
+		return
+	}
+	reason := "transient"
+	if rateLimited {
+		reason = "rate_limit"
+	}
+	trace.SpanFromContext(parent(ctx)).AddEvent("retry", trace.WithAttributes(
+		attribute.String("reason", reason),
+		attribute.Int("attempt", attempt),
+		attribute.String("error", err.Error()),
+		attribute.Float64("wait", wait.Seconds()),
+	))
+	retryWait.Record(ctx, wait.Seconds(), metric.WithAttributes(attribute.String("reason", reason)))
+}
+
+// parent returns ctx, with the span of the command run when it has none
+func parent(ctx context.Context) context.Context {
+	if command != nil && !trace.SpanContextFromContext(ctx).IsValid() {
+		return trace.ContextWithSpan(ctx, command)
+	}
+	return ctx
+}
@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"os"
+	"runtime"
+	"strings"
+)
+
+// ASCIISpinnerFrames replace SpinnerFrames on terminals without Unicode
+var ASCIISpinnerFrames = []string{"|", "/", "-", "\\"}
+
+var asciiOnly bool
+
+// SetASCII restricts spinners, progress bars and status messages to ASCII
+// characters, for terminals rendering braille and block characters as garbage
+func SetASCII(enabled bool) {
+	asciiOnly = enabled
+}
+
+// DetectASCII reports whether the terminal is unlikely to render Unicode:
+// a locale without UTF-8, the Linux console, a dumb terminal, or a Windows
+// console other than Windows Terminal
+func DetectASCII() bool {
+	switch os.Getenv("TERM") {
+	case "linux", "dumb":
+		return true
+	}
+	if runtime.GOOS == "windows" {
+		return os.Getenv("WT_SESSION") == ""
+	}
+
+	// The first of these set wins, as for setlocale
+	for _, name := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if locale := os.Getenv(name); locale != "" {
+			locale = strings.ToLower(locale)
+			return !strings.Contains(locale, "utf-8") && !strings.Contains(locale, "utf8")
+		}
+	}
+	return false
+}
+
+// spinnerFrames returns the frames of the spinners
+func spinnerFrames() []string {
+	if asciiOnly {
+		return ASCIISpinnerFrames
+	}
+	return SpinnerFrames
+}
+
+// barChars returns the characters of the filled and empty parts of the
+// progress bar
+func barChars() (filled, empty string) {
+	if asciiOnly {
+		return "#", "-"
+	}
+	return "█", "░"
+}
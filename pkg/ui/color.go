@@ -0,0 +1,83 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+)
+
+var colorEnabled = true
+
+// statusMarkers replace the emoji of status messages when color is
+// disabled; other emoji are dropped
+var statusMarkers = map[string]string{
+	"✅": "[ok]",
+	"❌": "[error]",
+	"⚠": "[warning]",
+	"❓": "[?]",
+	"⏭": "[skipped]",
+	"💡": "[hint]",
+	"ℹ": "[info]",
+}
+
+// emojiPattern matches an emoji with the spaces that follow it
+var emojiPattern = regexp.MustCompile(`([\x{1F000}-\x{1FAFF}\x{2139}\x{2190}-\x{21FF}\x{2300}-\x{23FF}\x{2600}-\x{27BF}\x{2B00}-\x{2BFF}])\x{FE0F}?( *)`)
+
+// NoColorRequested reports whether the NO_COLOR environment variable asks
+// for output without color (any non-empty value, see https://no-color.org)
+func NoColorRequested() bool {
+	return os.Getenv("NO_COLOR") != ""
+}
+
+// SetColor enables or disables color: lipgloss styles render as plain text
+// and the emoji of status messages are replaced by text markers
+func SetColor(enabled bool) {
+	colorEnabled = enabled
+	if !enabled {
+		lipgloss.SetColorProfile(termenv.Ascii)
+	}
+}
+
+// Text returns s as printed: unchanged when color and Unicode are enabled,
+// otherwise with its emoji replaced by text markers or dropped
+func Text(s string) string {
+	if colorEnabled && !asciiOnly {
+		return s
+	}
+	return emojiPattern.ReplaceAllStringFunc(s, func(emoji string) string {
+		match := emojiPattern.FindStringSubmatch(emoji)
+		marker, ok := statusMarkers[match[1]]
+		if !ok {
+			return ""
+		}
+		if match[2] != "" {
+			marker += " "
+		}
+		return marker
+	})
+}
+
+// Printf prints a status message, which may start with an emoji, on the
+// StatusWriter
+func Printf(format string, a ...interface{}) {
+	message := fmt.Sprintf(format, a...)
+	logStatus(message)
+	fmt.Fprint(StatusWriter(), Text(message))
+}
+
+// Println prints a status message, which may start with an emoji, on the
+// StatusWriter
+func Println(a ...interface{}) {
+	message := fmt.Sprintln(a...)
+	logStatus(message)
+	fmt.Fprint(StatusWriter(), Text(message))
+}
+
+// Fprintf is fmt.Fprintf for messages that may start with an emoji
+func Fprintf(w io.Writer, format string, a ...interface{}) {
+	fmt.Fprint(w, Text(fmt.Sprintf(format, a...)))
+}
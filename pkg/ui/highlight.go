@@ -0,0 +1,215 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/mattn/go-isatty"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is the detected format of a document
+type Format string
+
+const (
+	FormatJSON  Format = "json"
+	FormatYAML  Format = "yaml"
+	FormatPlain Format = "plain"
+)
+
+var (
+	keyStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("#74c0fc")).Bold(true)
+	stringStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#51cf66"))
+	numberStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("#ffa94d"))
+	literalStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#da77f2"))
+	commentStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#868e96")).Italic(true)
+
+	yamlKeyPattern     = regexp.MustCompile(`^(\s*(?:-\s+)?)((?:"[^"]*"|'[^']*'|[^\s#'"][^:#]*?)):(\s+|$)(.*)$`)
+	yamlItemPattern    = regexp.MustCompile(`^(\s*-\s+)(.*)$`)
+	numberPattern      = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+	yamlCommentPattern = regexp.MustCompile(`^(.*?)(\s+#.*)$`)
+)
+
+// IsTerminal reports whether stdout is a terminal
+func IsTerminal() bool {
+	return isTerminal(os.Stdout)
+}
+
+// IsStderrTerminal reports whether stderr is a terminal
+func IsStderrTerminal() bool {
+	return isTerminal(os.Stderr)
+}
+
+func isTerminal(f *os.File) bool {
+	fd := f.Fd()
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}
+
+// DetectFormat detects whether content is JSON, YAML or plain text
+func DetectFormat(content []byte) Format {
+	trimmed := bytes.TrimSpace(content)
+	if len(trimmed) == 0 {
+		return FormatPlain
+	}
+	if (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+		return FormatJSON
+	}
+
+	var node yaml.Node
+	if err := yaml.Unmarshal(trimmed, &node); err == nil && len(node.Content) > 0 {
+		if kind := node.Content[0].Kind; kind == yaml.MappingNode || kind == yaml.SequenceNode {
+			return FormatYAML
+		}
+	}
+	return FormatPlain
+}
+
+// Highlight pretty-prints and colorizes a JSON or YAML document for display
+// in a terminal. Plain text is returned unchanged.
+func Highlight(content []byte) string {
+	switch DetectFormat(content) {
+	case FormatJSON:
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, bytes.TrimSpace(content), "", "  "); err == nil {
+			content = indented.Bytes()
+		}
+		return highlightJSON(string(content))
+	case FormatYAML:
+		return highlightYAML(string(content))
+	default:
+		return string(content)
+	}
+}
+
+// highlightYAML colorizes YAML line by line
+func highlightYAML(content string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		lines[i] = highlightYAMLLine(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// highlightYAMLLine colorizes a single YAML line
+func highlightYAMLLine(line string) string {
+	trimmed := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmed, "#") {
+		return commentStyle.Render(line)
+	}
+	if trimmed == "---" || trimmed == "..." {
+		return literalStyle.Render(line)
+	}
+
+	if m := yamlKeyPattern.FindStringSubmatch(line); m != nil {
+		return m[1] + keyStyle.Render(m[2]) + ":" + m[3] + highlightYAMLValue(m[4])
+	}
+	if m := yamlItemPattern.FindStringSubmatch(line); m != nil {
+		return m[1] + highlightYAMLValue(m[2])
+	}
+	return line
+}
+
+// highlightYAMLValue colorizes a scalar value with an optional trailing comment
+func highlightYAMLValue(value string) string {
+	comment := ""
+	if !strings.HasPrefix(value, `"`) && !strings.HasPrefix(value, "'") {
+		if m := yamlCommentPattern.FindStringSubmatch(value); m != nil {
+			value, comment = m[1], commentStyle.Render(m[2])
+		}
+	}
+	return styleScalar(value) + comment
+}
+
+// styleScalar picks the style of a scalar based on its type
+func styleScalar(value string) string {
+	switch {
+	case value == "" || value == "|" || value == ">" || value == "|-" || value == ">-" ||
+		strings.HasPrefix(value, "&") || strings.HasPrefix(value, "*"):
+		return value
+	case numberPattern.MatchString(value):
+		return numberStyle.Render(value)
+	}
+
+	switch strings.ToLower(value) {
+	case "true", "false", "null", "~", "yes", "no", "on", "off":
+		return literalStyle.Render(value)
+	}
+	return stringStyle.Render(value)
+}
+
+// highlightJSON colorizes an indented JSON document
+func highlightJSON(content string) string {
+	var sb strings.Builder
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == '"':
+			end := endOfJSONString(content, i)
+			token := content[i:end]
+			// A string followed by a colon is an object key
+			rest := strings.TrimLeft(content[end:], " \t")
+			if strings.HasPrefix(rest, ":") {
+				sb.WriteString(keyStyle.Render(token))
+			} else {
+				sb.WriteString(stringStyle.Render(token))
+			}
+			i = end
+		case c == '-' || (c >= '0' && c <= '9'):
+			end := i + 1
+			for end < len(content) && strings.ContainsRune("0123456789.eE+-", rune(content[end])) {
+				end++
+			}
+			sb.WriteString(numberStyle.Render(content[i:end]))
+			i = end
+		case strings.HasPrefix(content[i:], "true"), strings.HasPrefix(content[i:], "null"):
+			sb.WriteString(literalStyle.Render(content[i : i+4]))
+			i += 4
+		case strings.HasPrefix(content[i:], "false"):
+			sb.WriteString(literalStyle.Render(content[i : i+5]))
+			i += 5
+		default:
+			sb.WriteByte(c)
+			i++
+		}
+	}
+	return sb.String()
+}
+
+// endOfJSONString returns the index just after the string starting at start
+func endOfJSONString(content string, start int) int {
+	for i := start + 1; i < len(content); i++ {
+		switch content[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return len(content)
+}
+
+var (
+	addedStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("#51cf66"))
+	removedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#ff6b6b"))
+	changedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#ffa94d"))
+)
+
+// HighlightDiffLine colorizes a line of a diff by its marker: + added,
+// - removed and ~ changed
+func HighlightDiffLine(line string) string {
+	switch {
+	case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+		return keyStyle.Render(line)
+	case strings.HasPrefix(line, "+"):
+		return addedStyle.Render(line)
+	case strings.HasPrefix(line, "-"):
+		return removedStyle.Render(line)
+	case strings.HasPrefix(line, "~"):
+		return changedStyle.Render(line)
+	}
+	return line
+}
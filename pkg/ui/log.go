@@ -0,0 +1,75 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	logMu   sync.Mutex
+	logFile *os.File
+)
+
+// OpenLogFile appends every debug message, step and HTTP request to the
+// file at path, whatever the verbosity, creating it and its directory when
+// needed
+func OpenLogFile(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+	// The log holds URLs and configuration details, so keep it private
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	logMu.Lock()
+	defer logMu.Unlock()
+	if logFile != nil {
+		logFile.Close()
+	}
+	logFile = f
+	return nil
+}
+
+// CloseLogFile closes the log file, if any
+func CloseLogFile() error {
+	logMu.Lock()
+	defer logMu.Unlock()
+	if logFile == nil {
+		return nil
+	}
+	err := logFile.Close()
+	logFile = nil
+	return err
+}
+
+// Logf writes a timestamped line to the log file, if any
+func Logf(format string, a ...interface{}) {
+	logMu.Lock()
+	defer logMu.Unlock()
+	if logFile == nil {
+		return
+	}
+	fmt.Fprintf(logFile, "%s %s\n", time.Now().Format("2006-01-02T15:04:05.000Z07:00"), fmt.Sprintf(format, a...))
+}
+
+// logStep records the outcome of a step in the log file
+func logStep(err error, message string) {
+	if err != nil {
+		Logf("step failed: %s: %v", message, err)
+		return
+	}
+	Logf("step done: %s", message)
+}
+
+// logStatus records a status message in the log file
+func logStatus(message string) {
+	if message = strings.TrimSpace(message); message != "" {
+		Logf("%s", message)
+	}
+}
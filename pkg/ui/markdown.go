@@ -0,0 +1,105 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/lipgloss"
+	"golang.org/x/term"
+)
+
+// Styles of RenderMarkdown
+const (
+	StyleAuto  = "auto"
+	StyleDark  = "dark"
+	StyleLight = "light"
+	// StyleNoTTY renders without colors, e.g. for a file or a pipe
+	StyleNoTTY = "notty"
+)
+
+// MarkdownStyles lists the styles of RenderMarkdown
+var MarkdownStyles = []string{StyleAuto, StyleDark, StyleLight, StyleNoTTY}
+
+// defaultWidth is the width of the terminal when it is not known, and the
+// widest text is wrapped to for readability
+const defaultWidth = 100
+
+// RenderMarkdown renders Markdown for a terminal, wrapped at width columns
+// (0 for the width of the terminal). The auto style picks dark or light
+// from the background of the terminal, and is notty without color.
+func RenderMarkdown(markdown, style string, width int) (string, error) {
+	if width <= 0 {
+		width = TerminalWidth()
+	}
+	if style == "" || style == StyleAuto {
+		style = StyleDark
+		switch {
+		case !colorEnabled || !IsTerminal():
+			style = StyleNoTTY
+		case !lipgloss.HasDarkBackground():
+			style = StyleLight
+		}
+	}
+
+	renderer, err := glamour.NewTermRenderer(
+		glamour.WithStandardStyle(style),
+		glamour.WithWordWrap(width),
+		glamour.WithEmoji(),
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to create the Markdown renderer: %w", err)
+	}
+	rendered, err := renderer.Render(markdown)
+	if err != nil {
+		return "", fmt.Errorf("failed to render Markdown: %w", err)
+	}
+	return rendered, nil
+}
+
+// TerminalWidth returns the width of the terminal of stdout, up to a
+// readable width; the readable width when stdout is not a terminal
+func TerminalWidth() int {
+	width, _, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 || width > defaultWidth {
+		return defaultWidth
+	}
+	return width
+}
+
+// Page prints content through the pager of $PAGER (less -R by default)
+// when stdout is a terminal and the content is taller than it, and
+// directly otherwise, or when the pager cannot be run
+func Page(content string) error {
+	_, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || !IsTerminal() || strings.Count(content, "\n") < height {
+		_, err := io.WriteString(os.Stdout, content)
+		return err
+	}
+
+	pager := strings.Fields(os.Getenv("PAGER"))
+	if len(pager) == 0 {
+		pager = []string{"less", "-R"}
+	}
+	path, err := exec.LookPath(pager[0])
+	if err != nil {
+		Debugf(1, "Pager %s not found: %v", pager[0], err)
+		_, err := io.WriteString(os.Stdout, content)
+		return err
+	}
+
+	cmd := exec.Command(path, pager[1:]...)
+	cmd.Stdin = strings.NewReader(content)
+	cmd.Stdout, cmd.Stderr = os.Stdout, os.Stderr
+	// less quits at once when the content fits, and keeps the colors
+	if os.Getenv("LESS") == "" {
+		cmd.Env = append(os.Environ(), "LESS=FRX")
+	}
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("pager %s failed: %w", pager[0], err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// OutputMode controls how spinners and progress bars are rendered
+type OutputMode int
+
+const (
+	// OutputInteractive animates spinners and progress bars
+	OutputInteractive OutputMode = iota
+	// OutputSpinner animates a simple spinner line on stderr, used when
+	// stdout is redirected but stderr is still a terminal
+	OutputSpinner
+	// OutputPlain prints a single timestamped line per step, without
+	// control sequences, which keeps CI logs readable
+	OutputPlain
+	// OutputQuiet prints nothing for steps; their errors are still returned
+	OutputQuiet
+)
+
+var outputMode = OutputInteractive
+
+// verbosity is the level of the debug messages printed on stderr: 0 for
+// none, 1 for -v and 2 for -vv
+var verbosity int
+
+// SetOutputMode sets how spinners and progress bars are rendered
+func SetOutputMode(mode OutputMode) {
+	outputMode = mode
+}
+
+// DetectOutputMode chooses the output mode from the flags and the
+// terminals: the progress bars when stdout is a terminal, a spinner on
+// stderr when only stderr is one, and timestamped lines otherwise
+func DetectOutputMode(quiet, noProgress bool) OutputMode {
+	switch {
+	case quiet:
+		return OutputQuiet
+	case noProgress:
+		return OutputPlain
+	case IsTerminal():
+		return OutputInteractive
+	case IsStderrTerminal():
+		return OutputSpinner
+	default:
+		return OutputPlain
+	}
+}
+
+// StatusWriter returns where spinners, steps and status messages are
+// written: stdout when it is a terminal, otherwise stderr so they never mix
+// with the results redirected to a file or a pipe
+func StatusWriter() io.Writer {
+	if IsTerminal() {
+		return os.Stdout
+	}
+	return os.Stderr
+}
+
+// Quiet reports whether status messages should be suppressed
+func Quiet() bool {
+	return outputMode == OutputQuiet
+}
+
+// SetVerbosity sets the level of the debug messages printed on stderr
+func SetVerbosity(level int) {
+	verbosity = level
+}
+
+// Verbose reports whether debug messages of the given level are printed
+func Verbose(level int) bool {
+	return verbosity >= level
+}
+
+// DebugEnabled reports whether debug messages of the given level are
+// printed or written to the log file, for callers with costly messages
+func DebugEnabled(level int) bool {
+	return Verbose(level) || logFile != nil
+}
+
+// Debugf prints a timestamped debug message on stderr when the verbosity
+// is at least level. The log file receives every debug message.
+func Debugf(level int, format string, a ...interface{}) {
+	if !DebugEnabled(level) {
+		return
+	}
+	message := fmt.Sprintf(format, a...)
+	Logf("%s", message)
+	if Verbose(level) {
+		fmt.Fprintf(os.Stderr, "%s [debug] %s\n", time.Now().Format("15:04:05.000"), message)
+	}
+}
+
+// printStep prints the outcome of a step as a single timestamped line
+func printStep(err error, message string) {
+	logStep(err, message)
+	status := "✅"
+	if err != nil {
+		status = "❌"
+	}
+	Fprintf(StatusWriter(), "%s %s %s\n", time.Now().Format(time.RFC3339), status, message)
+}
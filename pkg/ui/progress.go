@@ -3,16 +3,26 @@ package ui
 import (
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
+// Units of work of a progress bar
+const (
+	UnitBytes = "bytes"
+	UnitItems = ""
+)
+
 // ProgressBar represents a progress bar model
 type ProgressBar struct {
 	width     int
-	progress  float64
+	title     string
+	unit      string
+	current   int64
+	total     int64
 	message   string
 	startTime time.Time
 	completed bool
@@ -20,10 +30,13 @@ type ProgressBar struct {
 	frame     int
 }
 
-// ProgressMsg represents a progress update message
+// ProgressMsg reports the progress of a task: Current out of Total units of
+// work done. Total is 0 when unknown, e.g. for a download without a length.
 type ProgressMsg struct {
-	Progress float64
-	Message  string
+	Current int64
+	Total   int64
+	// Message describes the work in progress, e.g. the item being processed
+	Message string
 }
 
 // CompleteMsg represents a completion message
@@ -52,7 +65,8 @@ func (p ProgressBar) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return p, tea.Quit
 		}
 	case ProgressMsg:
-		p.progress = msg.Progress
+		p.current = msg.Current
+		p.total = msg.Total
 		p.message = msg.Message
 		return p, nil
 	case CompleteMsg:
@@ -76,40 +90,78 @@ func (p ProgressBar) View() string {
 		if p.error != nil {
 			return lipgloss.NewStyle().
 				Foreground(lipgloss.Color("#ff6b6b")).
-				Render(fmt.Sprintf("❌ Error: %s", p.error.Error()))
+				Render(Text(fmt.Sprintf("❌ %s", p.title))) + "\n"
 		}
 		return lipgloss.NewStyle().
 			Foreground(lipgloss.Color("#51cf66")).
-			Render("✅ Completed successfully")
+			Render(Text(fmt.Sprintf("✅ %s (%s, %s)", p.title, p.counter(), time.Since(p.startTime).Round(100*time.Millisecond)))) + "\n"
 	}
 
-	barWidth := 30 // Fixed width for consistency
-	filled := int(float64(barWidth) * p.progress)
-	empty := barWidth - filled
-
 	// Animated spinner
-	spinner := animatedFrames[p.frame]
-
-	bar := strings.Repeat("█", filled) + strings.Repeat("░", empty)
-
-	barStyle := lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#74c0fc")).
-		Bold(true)
+	spinner := p.frames()[p.frame]
 
 	progressText := lipgloss.NewStyle().
 		Foreground(lipgloss.Color("#868e96")).
-		Render(fmt.Sprintf("%3.0f%%", p.progress*100))
+		Render(p.counter())
+
+	line := fmt.Sprintf("%s %s", spinner, p.title)
+	if p.total > 0 {
+		barWidth := 30 // Fixed width for consistency
+		filled := int(float64(barWidth) * p.fraction())
+		filledChar, emptyChar := barChars()
+		bar := strings.Repeat(filledChar, filled) + strings.Repeat(emptyChar, barWidth-filled)
+
+		barStyle := lipgloss.NewStyle().
+			Foreground(lipgloss.Color("#74c0fc")).
+			Bold(true)
 
-	// Show the progress message above the bar
-	return fmt.Sprintf("\n%s\n%s %s %s", p.message, spinner, barStyle.Render(bar), progressText)
+		line = fmt.Sprintf("%s %s %3.0f%%", line, barStyle.Render(bar), p.fraction()*100)
+	}
+	line += " " + progressText
+
+	// Show the progress message below the bar
+	if p.message != "" {
+		line += "\n  " + lipgloss.NewStyle().Foreground(lipgloss.Color("#868e96")).Render(p.message)
+	}
+	return line
 }
 
-// NewProgressBar creates a new progress bar
-func NewProgressBar() ProgressBar {
+// frames returns the frames of the animated spinner
+func (p ProgressBar) frames() []string {
+	if asciiOnly {
+		return ASCIISpinnerFrames
+	}
+	return animatedFrames
+}
+
+// fraction returns the share of the work done, between 0 and 1
+func (p ProgressBar) fraction() float64 {
+	if p.total <= 0 {
+		return 0
+	}
+	return min(float64(p.current)/float64(p.total), 1)
+}
+
+// counter renders the amount of work done, e.g. "12/40" or "3.2 MiB"
+func (p ProgressBar) counter() string {
+	format := func(n int64) string {
+		if p.unit == UnitBytes {
+			return FormatSize(n)
+		}
+		return fmt.Sprintf("%d", n)
+	}
+	if p.total > 0 {
+		return fmt.Sprintf("%s/%s", format(p.current), format(p.total))
+	}
+	return format(p.current)
+}
+
+// NewProgressBar creates a new progress bar for work counted in unit
+func NewProgressBar(title, unit string) ProgressBar {
 	return ProgressBar{
 		width:     80,
-		progress:  0,
-		message:   "Initializing...",
+		title:     title,
+		unit:      unit,
 		startTime: time.Now(),
 		completed: false,
 		frame:     0,
@@ -121,3 +173,143 @@ func RunProgressBar(program *tea.Program) error {
 	_, err := program.Run()
 	return err
 }
+
+// RunProgress runs a task that reports its progress through the channel it
+// is given, and renders a progress bar from those reports until the task
+// completes. The task must not close the channel. With OutputSpinner a
+// spinner line shows the amount of work done; in the other modes only the
+// outcome and the amount of work done are printed.
+func RunProgress(title, unit string, task func(progress chan<- ProgressMsg) error) error {
+	if outputMode != OutputInteractive {
+		var mu sync.Mutex
+		bar := NewProgressBar(title, unit)
+		describe := func() string {
+			mu.Lock()
+			defer mu.Unlock()
+			return fmt.Sprintf("%s (%s)", title, bar.counter())
+		}
+
+		progress := make(chan ProgressMsg)
+		received := make(chan struct{})
+		go func() {
+			defer close(received)
+			for msg := range progress {
+				mu.Lock()
+				bar.current, bar.total = msg.Current, msg.Total
+				mu.Unlock()
+			}
+		}()
+
+		run := func() error {
+			err := task(progress)
+			close(progress)
+			<-received
+			return err
+		}
+
+		switch outputMode {
+		case OutputSpinner:
+			done := make(chan error, 1)
+			go func() {
+				done <- run()
+			}()
+			return spin(spinnerOutput(), describe, done)
+		case OutputPlain:
+			err := run()
+			printStep(err, describe())
+			return err
+		default:
+			err := run()
+			logStep(err, describe())
+			return err
+		}
+	}
+
+	program := tea.NewProgram(NewProgressBar(title, unit), tea.WithInput(nil))
+
+	progress := make(chan ProgressMsg)
+	forwarded := make(chan struct{})
+	go func() {
+		defer close(forwarded)
+		for msg := range progress {
+			program.Send(msg)
+		}
+	}()
+
+	done := make(chan error, 1)
+	go func() {
+		err := task(progress)
+		close(progress)
+		<-forwarded
+		program.Send(CompleteMsg{Error: err})
+		logStep(err, title)
+		done <- err
+	}()
+
+	if err := RunProgressBar(program); err != nil {
+		return err
+	}
+	return <-done
+}
+
+// RunWithoutProgress runs a task written for RunProgress without rendering
+// anything, discarding the progress it reports
+func RunWithoutProgress(task func(progress chan<- ProgressMsg) error) error {
+	progress := make(chan ProgressMsg)
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		for range progress {
+		}
+	}()
+
+	err := task(progress)
+	close(progress)
+	<-drained
+	return err
+}
+
+// CountingWriter counts the bytes written through it and reports them on a
+// progress channel
+type CountingWriter struct {
+	progress chan<- ProgressMsg
+	total    int64
+	written  int64
+	reported time.Time
+}
+
+// NewCountingWriter creates a writer reporting progress towards total bytes
+// (0 when unknown)
+func NewCountingWriter(progress chan<- ProgressMsg, total int64) *CountingWriter {
+	return &CountingWriter{progress: progress, total: total}
+}
+
+// Write counts p, reporting progress at most every 100ms
+func (w *CountingWriter) Write(p []byte) (int, error) {
+	w.written += int64(len(p))
+	if time.Since(w.reported) >= 100*time.Millisecond {
+		w.reported = time.Now()
+		w.progress <- ProgressMsg{Current: w.written, Total: w.total}
+	}
+	return len(p), nil
+}
+
+// Flush reports the bytes written so far
+func (w *CountingWriter) Flush() {
+	w.reported = time.Now()
+	w.progress <- ProgressMsg{Current: w.written, Total: w.total}
+}
+
+// FormatSize formats a size in bytes with a binary unit
+func FormatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
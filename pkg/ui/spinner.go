@@ -2,31 +2,63 @@ package ui
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"time"
 )
 
 // SpinnerFrames contains the frames for simple animation
 var SpinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
 
-// RunSpinner runs a simple spinner with a message until the task completes
+// RunSpinner runs a simple spinner with a message until the task completes.
+// Outside the interactive output modes only the outcome is printed.
 func RunSpinner(message string, task func() error) error {
+	switch outputMode {
+	case OutputQuiet:
+		err := task()
+		logStep(err, message)
+		return err
+	case OutputPlain:
+		err := task()
+		printStep(err, message)
+		return err
+	}
+
 	done := make(chan error, 1)
 	go func() {
 		done <- task()
 	}()
+	return spin(spinnerOutput(), func() string { return message }, done)
+}
+
+// spinnerOutput returns where the spinner is drawn
+func spinnerOutput() io.Writer {
+	if outputMode == OutputSpinner {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// spin animates a spinner line on w, redrawing the message it returns on
+// every frame, until an error or nil is received from done
+func spin(w io.Writer, message func() string, done <-chan error) error {
+	frames := spinnerFrames()
 	frame := 0
 	for {
 		select {
 		case err := <-done:
+			logStep(err, message())
+			// Clear what is left of a longer line
+			fmt.Fprint(w, "\r\033[K")
 			if err != nil {
-				fmt.Printf("\r❌ %s\n", message)
+				Fprintf(w, "❌ %s\n", message())
 				return err
 			}
-			fmt.Printf("\r✅ %s\n", message)
+			Fprintf(w, "✅ %s\n", message())
 			return nil
 		default:
-			fmt.Printf("\r%s %s", SpinnerFrames[frame], message)
-			frame = (frame + 1) % len(SpinnerFrames)
+			fmt.Fprintf(w, "\r%s %s", frames[frame], message())
+			frame = (frame + 1) % len(frames)
 			time.Sleep(100 * time.Millisecond)
 		}
 	}
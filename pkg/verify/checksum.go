@@ -0,0 +1,80 @@
+package verify
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SHA256 returns the hex-encoded SHA-256 digest of the given content
+func SHA256(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifySHA256 checks that content matches the expected SHA-256 digest.
+// The digest may optionally be prefixed with "sha256:".
+func VerifySHA256(content []byte, expected string) error {
+	expected = strings.ToLower(strings.TrimSpace(expected))
+	expected = strings.TrimPrefix(expected, "sha256:")
+
+	if len(expected) != sha256.Size*2 {
+		return fmt.Errorf("invalid SHA-256 digest: %q", expected)
+	}
+	if _, err := hex.DecodeString(expected); err != nil {
+		return fmt.Errorf("invalid SHA-256 digest: %q", expected)
+	}
+
+	actual := SHA256(content)
+	if actual != expected {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", expected, actual)
+	}
+	return nil
+}
+
+// ReadChecksumFile reads a sidecar checksum file in the format produced by
+// sha256sum ("<digest>  <name>") and returns the digest for the given file.
+// A file containing a single bare digest is also accepted.
+func ReadChecksumFile(path, fileName string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open checksum file: %w", err)
+	}
+	defer file.Close()
+
+	baseName := filepath.Base(fileName)
+	var entries []string
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 1 {
+			entries = append(entries, fields[0])
+			continue
+		}
+
+		// sha256sum marks binary mode with a leading '*'
+		name := strings.TrimPrefix(fields[1], "*")
+		if name == fileName || filepath.Base(name) == baseName {
+			return fields[0], nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read checksum file: %w", err)
+	}
+
+	if len(entries) == 1 {
+		return entries[0], nil
+	}
+
+	return "", fmt.Errorf("no checksum found for %s in %s", fileName, path)
+}
@@ -0,0 +1,77 @@
+package verify
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// SigningKey is an OpenPGP private key signing content in-process, without
+// gpg
+type SigningKey struct {
+	entity *openpgp.Entity
+}
+
+// LoadSigningKey reads an OpenPGP private key, either ASCII-armored (gpg
+// --export-secret-keys --armor) or binary, decrypting it with the passphrase
+// when it is protected
+func LoadSigningKey(path, passphrase string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key: %w", err)
+	}
+
+	var keyring openpgp.EntityList
+	if isArmored(data) {
+		keyring, err = openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	} else {
+		keyring, err = openpgp.ReadKeyRing(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key %s: %w", path, err)
+	}
+	if len(keyring) == 0 || keyring[0].PrivateKey == nil {
+		return nil, fmt.Errorf("%s holds no private key", path)
+	}
+
+	entity := keyring[0]
+	if entity.PrivateKey.Encrypted {
+		if passphrase == "" {
+			return nil, fmt.Errorf("signing key %s is protected by a passphrase", path)
+		}
+		if err := entity.DecryptPrivateKeys([]byte(passphrase)); err != nil {
+			return nil, fmt.Errorf("failed to decrypt signing key %s: %w", path, err)
+		}
+	}
+	return &SigningKey{entity: entity}, nil
+}
+
+// Identity returns the name and email of the primary user ID of the key,
+// e.g. Jane Doe and jane@example.com
+func (k *SigningKey) Identity() (name, email string) {
+	identity := k.entity.PrimaryIdentity()
+	if identity == nil || identity.UserId == nil {
+		return "", ""
+	}
+	return identity.UserId.Name, identity.UserId.Email
+}
+
+// String identifies the key by its primary user ID and key ID
+func (k *SigningKey) String() string {
+	return signerIdentity(k.entity)
+}
+
+// SignDetached returns an ASCII-armored detached signature of content, as
+// appended to git tag and commit objects, ending with a newline
+func (k *SigningKey) SignDetached(content []byte) ([]byte, error) {
+	var signature bytes.Buffer
+	if err := openpgp.ArmoredDetachSign(&signature, k.entity, bytes.NewReader(content), nil); err != nil {
+		return nil, fmt.Errorf("failed to sign: %w", err)
+	}
+	if !bytes.HasSuffix(signature.Bytes(), []byte("\n")) {
+		signature.WriteByte('\n')
+	}
+	return signature.Bytes(), nil
+}
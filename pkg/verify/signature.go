@@ -0,0 +1,69 @@
+package verify
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+)
+
+// armorPrefix identifies ASCII-armored OpenPGP data
+const armorPrefix = "-----BEGIN PGP"
+
+// LoadKeyring reads a keyring of trusted public keys, either ASCII-armored
+// (gpg --export --armor) or binary (gpg --export)
+func LoadKeyring(path string) (openpgp.EntityList, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring: %w", err)
+	}
+
+	var keyring openpgp.EntityList
+	if isArmored(data) {
+		keyring, err = openpgp.ReadArmoredKeyRing(bytes.NewReader(data))
+	} else {
+		keyring, err = openpgp.ReadKeyRing(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse keyring %s: %w", path, err)
+	}
+	if len(keyring) == 0 {
+		return nil, fmt.Errorf("keyring %s contains no keys", path)
+	}
+
+	return keyring, nil
+}
+
+// VerifyDetachedSignature checks a detached signature (.asc or .sig) over
+// content against the trusted keyring and returns the signer identity
+func VerifyDetachedSignature(content, signature []byte, keyring openpgp.EntityList) (string, error) {
+	var signer *openpgp.Entity
+	var err error
+	if isArmored(signature) {
+		signer, err = openpgp.CheckArmoredDetachedSignature(keyring, bytes.NewReader(content), bytes.NewReader(signature), nil)
+	} else {
+		signer, err = openpgp.CheckDetachedSignature(keyring, bytes.NewReader(content), bytes.NewReader(signature), nil)
+	}
+	if err != nil {
+		return "", fmt.Errorf("signature verification failed: %w", err)
+	}
+
+	return signerIdentity(signer), nil
+}
+
+// signerIdentity returns a printable identity for a key: its primary user ID
+// and key ID
+func signerIdentity(entity *openpgp.Entity) string {
+	keyID := fmt.Sprintf("%016X", entity.PrimaryKey.KeyId)
+	for name := range entity.Identities {
+		return fmt.Sprintf("%s (%s)", name, keyID)
+	}
+	return keyID
+}
+
+// isArmored reports whether data is ASCII-armored
+func isArmored(data []byte) bool {
+	return strings.HasPrefix(strings.TrimSpace(string(data)), armorPrefix)
+}
@@ -0,0 +1,187 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Providers sending webhooks
+const (
+	GitHub = "github"
+	GitLab = "gitlab"
+)
+
+// Kinds of events
+const (
+	// KindTag is a tag pushed
+	KindTag = "tag"
+	// KindRelease is a release published
+	KindRelease = "release"
+)
+
+// maxPayloadSize is the size of the largest payload read; GitHub caps its
+// payloads at 25 MB
+const maxPayloadSize = 25 << 20
+
+// zeroSHA is the commit a deleted ref is pushed to
+const zeroSHA = "0000000000000000000000000000000000000000"
+
+var (
+	// ErrUnauthorized is returned for requests whose signature or token
+	// does not match the secret of their provider
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrIgnored wraps the reason why a verified request is not an event
+	// to act on, e.g. a branch push
+	ErrIgnored = errors.New("ignored")
+)
+
+// Event is a tag pushed or a release published in a repository
+type Event struct {
+	// Provider is github or gitlab
+	Provider string
+	// Kind is tag or release
+	Kind string
+	// Repo is owner/repo on GitHub, the path of the project on GitLab,
+	// e.g. group/subgroup/project
+	Repo string
+	Tag  string
+	// ID identifies the delivery of the event, when the provider gives one
+	ID string
+}
+
+// Secrets are the secrets shared with the providers: the key of the
+// signatures of GitHub and the token GitLab sends. Requests from a provider
+// without a secret are refused.
+type Secrets struct {
+	GitHub string
+	GitLab string
+}
+
+// Parse verifies a webhook request and returns its event. Requests that are
+// not from a provider with a secret, or fail verification, are refused with
+// ErrUnauthorized; verified requests that are not tag pushes or published
+// releases, e.g. GitHub pings, with an error wrapping ErrIgnored.
+func Parse(r *http.Request, secrets Secrets) (*Event, error) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPayloadSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read payload: %w", err)
+	}
+	if len(body) > maxPayloadSize {
+		return nil, fmt.Errorf("payload larger than %d bytes", maxPayloadSize)
+	}
+
+	switch {
+	case r.Header.Get("X-GitHub-Event") != "":
+		if secrets.GitHub == "" || !validSignature(body, r.Header.Get("X-Hub-Signature-256"), secrets.GitHub) {
+			return nil, ErrUnauthorized
+		}
+		return parseGitHub(r.Header.Get("X-GitHub-Event"), r.Header.Get("X-GitHub-Delivery"), body)
+	case r.Header.Get("X-Gitlab-Event") != "":
+		token := r.Header.Get("X-Gitlab-Token")
+		if secrets.GitLab == "" || subtle.ConstantTimeCompare([]byte(token), []byte(secrets.GitLab)) != 1 {
+			return nil, ErrUnauthorized
+		}
+		return parseGitLab(r.Header.Get("X-Gitlab-Event"), r.Header.Get("X-Gitlab-Event-UUID"), body)
+	}
+	return nil, fmt.Errorf("not a GitHub or GitLab webhook")
+}
+
+// validSignature reports whether signature, "sha256=<hex>", is the HMAC of
+// the body with the secret
+func validSignature(body []byte, signature, secret string) bool {
+	hexDigest, ok := strings.CutPrefix(signature, "sha256=")
+	if !ok {
+		return false
+	}
+	digest, err := hex.DecodeString(hexDigest)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(digest, mac.Sum(nil))
+}
+
+// parseGitHub parses the push and release events of GitHub
+func parseGitHub(event, id string, body []byte) (*Event, error) {
+	var payload struct {
+		Ref     string `json:"ref"`
+		Deleted bool   `json:"deleted"`
+		Action  string `json:"action"`
+		Release struct {
+			TagName string `json:"tag_name"`
+		} `json:"release"`
+		Repository struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid %s payload: %w", event, err)
+	}
+
+	e := &Event{Provider: GitHub, Repo: payload.Repository.FullName, ID: id}
+	switch event {
+	case "push":
+		tag, ok := strings.CutPrefix(payload.Ref, "refs/tags/")
+		if !ok || payload.Deleted {
+			return nil, fmt.Errorf("%w: push of %s is not a new tag", ErrIgnored, payload.Ref)
+		}
+		e.Kind, e.Tag = KindTag, tag
+	case "release":
+		if payload.Action != "published" {
+			return nil, fmt.Errorf("%w: release %s", ErrIgnored, payload.Action)
+		}
+		e.Kind, e.Tag = KindRelease, payload.Release.TagName
+	default:
+		return nil, fmt.Errorf("%w: %s event", ErrIgnored, event)
+	}
+	if e.Repo == "" || e.Tag == "" {
+		return nil, fmt.Errorf("invalid %s payload: missing repository or tag", event)
+	}
+	return e, nil
+}
+
+// parseGitLab parses the tag push and release events of GitLab
+func parseGitLab(event, id string, body []byte) (*Event, error) {
+	var payload struct {
+		Ref     string `json:"ref"`
+		After   string `json:"after"`
+		Action  string `json:"action"`
+		Tag     string `json:"tag"`
+		Project struct {
+			PathWithNamespace string `json:"path_with_namespace"`
+		} `json:"project"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid %s payload: %w", event, err)
+	}
+
+	e := &Event{Provider: GitLab, Repo: payload.Project.PathWithNamespace, ID: id}
+	switch event {
+	case "Tag Push Hook":
+		tag, ok := strings.CutPrefix(payload.Ref, "refs/tags/")
+		if !ok || payload.After == zeroSHA {
+			return nil, fmt.Errorf("%w: push of %s is not a new tag", ErrIgnored, payload.Ref)
+		}
+		e.Kind, e.Tag = KindTag, tag
+	case "Release Hook":
+		if payload.Action != "create" {
+			return nil, fmt.Errorf("%w: release %s", ErrIgnored, payload.Action)
+		}
+		e.Kind, e.Tag = KindRelease, payload.Tag
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrIgnored, event)
+	}
+	if e.Repo == "" || e.Tag == "" {
+		return nil, fmt.Errorf("invalid %s payload: missing project or tag", event)
+	}
+	return e, nil
+}
@@ -0,0 +1,325 @@
+package workdir
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"drivio/pkg/fileutil"
+	"drivio/pkg/verify"
+)
+
+// IndexFileName is the name of the artifact index inside the work directory
+const IndexFileName = ".drivio-index.json"
+
+// Record is a run of drivio that wrote artifacts
+type Record struct {
+	ID string `json:"id"`
+	// Command is the command that wrote the artifacts, e.g. "fetch archive"
+	Command string `json:"command"`
+	// Inputs are the source of the artifacts, e.g. repo, ref and file. They
+	// never hold tokens.
+	Inputs  map[string]string `json:"inputs,omitempty"`
+	Outputs []Output          `json:"outputs"`
+	// Entries is the number of changes of generated release notes
+	Entries   int       `json:"entries,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Output is an artifact written by a run
+type Output struct {
+	// Path is relative to the work directory when inside it, absolute
+	// otherwise
+	Path string `json:"path"`
+	// SHA256 is the digest of a file; directories have none
+	SHA256 string `json:"sha256,omitempty"`
+	Size   int64  `json:"size"`
+	IsDir  bool   `json:"is_dir,omitempty"`
+}
+
+// Index records every artifact drivio writes to a work directory, making
+// runs reproducible and auditable
+type Index struct {
+	dir string
+}
+
+// NewIndex returns the artifact index of the given work directory
+func NewIndex(workDir string) *Index {
+	return &Index{dir: workDir}
+}
+
+// Add measures the given outputs and appends a record of the run. Empty
+// inputs are left out.
+func (ix *Index) Add(command string, inputs map[string]string, paths ...string) (*Record, error) {
+	return ix.AddNotes(command, inputs, 0, paths...)
+}
+
+// AddNotes is Add for a run generating release notes of the given number
+// of changes
+func (ix *Index) AddNotes(command string, inputs map[string]string, entries int, paths ...string) (*Record, error) {
+	record := Record{
+		Command:   command,
+		Inputs:    make(map[string]string),
+		Entries:   entries,
+		CreatedAt: time.Now(),
+	}
+	for key, value := range inputs {
+		if value != "" {
+			record.Inputs[key] = value
+		}
+	}
+	for _, path := range paths {
+		output, err := ix.measure(path)
+		if err != nil {
+			return nil, err
+		}
+		record.Outputs = append(record.Outputs, *output)
+	}
+	record.ID = fmt.Sprintf("%s-%d", record.CreatedAt.UTC().Format("20060102T150405.000Z"), os.Getpid())
+
+	records, err := ix.List()
+	if err != nil {
+		return nil, err
+	}
+	if err := ix.write(append(records, record)); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// List returns all records, oldest first
+func (ix *Index) List() ([]Record, error) {
+	data, err := os.ReadFile(filepath.Join(ix.dir, IndexFileName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artifact index: %w", err)
+	}
+
+	var records []Record
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("failed to parse artifact index: %w", err)
+	}
+	return records, nil
+}
+
+// Lookup returns the most recent record with an output at the given path
+func (ix *Index) Lookup(records []Record, path string) *Record {
+	rel := ix.rel(path)
+	for i := len(records) - 1; i >= 0; i-- {
+		for _, output := range records[i].Outputs {
+			if output.Path == rel {
+				return &records[i]
+			}
+		}
+	}
+	return nil
+}
+
+// Prune drops the outputs that no longer exist, and the records left
+// without outputs, removing the index once empty. It returns the number of
+// outputs dropped.
+func (ix *Index) Prune() (int, error) {
+	records, err := ix.List()
+	if err != nil || records == nil {
+		return 0, err
+	}
+
+	pruned := 0
+	kept := records[:0]
+	for _, record := range records {
+		outputs := record.Outputs[:0]
+		for _, output := range record.Outputs {
+			if _, err := os.Lstat(ix.abs(output.Path)); err == nil {
+				outputs = append(outputs, output)
+			} else {
+				pruned++
+			}
+		}
+		if len(outputs) > 0 {
+			record.Outputs = outputs
+			kept = append(kept, record)
+		}
+	}
+	if pruned == 0 {
+		return 0, nil
+	}
+	if len(kept) == 0 {
+		if err := os.Remove(filepath.Join(ix.dir, IndexFileName)); err != nil {
+			return 0, fmt.Errorf("failed to remove artifact index: %w", err)
+		}
+		return pruned, nil
+	}
+	return pruned, ix.write(kept)
+}
+
+// Detach drops the outputs at or under the given paths from the index, and
+// the records left without outputs, like Prune for entries about to be
+// removed. It returns the records of the outputs dropped, for Attach.
+func (ix *Index) Detach(paths ...string) ([]Record, error) {
+	records, err := ix.List()
+	if err != nil || records == nil {
+		return nil, err
+	}
+
+	var detached []Record
+	kept := records[:0]
+	for _, record := range records {
+		var outputs, dropped []Output
+		for _, output := range record.Outputs {
+			if ix.under(output, paths) {
+				dropped = append(dropped, output)
+			} else {
+				outputs = append(outputs, output)
+			}
+		}
+		if len(dropped) > 0 {
+			gone := record
+			gone.Outputs = dropped
+			detached = append(detached, gone)
+		}
+		if len(outputs) > 0 {
+			record.Outputs = outputs
+			kept = append(kept, record)
+		}
+	}
+	if len(detached) == 0 {
+		return nil, nil
+	}
+	if len(kept) == 0 {
+		if err := os.Remove(filepath.Join(ix.dir, IndexFileName)); err != nil {
+			return nil, fmt.Errorf("failed to remove artifact index: %w", err)
+		}
+		return detached, nil
+	}
+	return detached, ix.write(kept)
+}
+
+// Attach puts records dropped by Detach back: their outputs join the record
+// they were dropped from when it is still there, and the other records are
+// put back in order
+func (ix *Index) Attach(detached []Record) error {
+	if len(detached) == 0 {
+		return nil
+	}
+	records, err := ix.List()
+	if err != nil {
+		return err
+	}
+	for _, record := range detached {
+		if i := slices.IndexFunc(records, func(r Record) bool { return r.ID == record.ID }); i >= 0 {
+			records[i].Outputs = append(records[i].Outputs, record.Outputs...)
+			continue
+		}
+		records = append(records, record)
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].CreatedAt.Before(records[j].CreatedAt)
+	})
+	return ix.write(records)
+}
+
+// under reports whether an output is at or under one of the paths
+func (ix *Index) under(output Output, paths []string) bool {
+	path, err := filepath.Abs(ix.abs(output.Path))
+	if err != nil {
+		return false
+	}
+	for _, p := range paths {
+		p, err := filepath.Abs(p)
+		if err == nil && (path == p || strings.HasPrefix(path, p+string(filepath.Separator))) {
+			return true
+		}
+	}
+	return false
+}
+
+// measure returns the size and digest of an output, walking directories
+func (ix *Index) measure(path string) (*Output, error) {
+	entry, err := stat(path)
+	if err != nil {
+		return nil, err
+	}
+	output := &Output{Path: ix.rel(path), Size: entry.Size, IsDir: entry.IsDir}
+	if !entry.IsDir {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		output.SHA256 = verify.SHA256(content)
+	}
+	return output, nil
+}
+
+// rel returns the path of an output as recorded in the index
+func (ix *Index) rel(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	dir, err := filepath.Abs(ix.dir)
+	if err != nil {
+		return abs
+	}
+	rel, err := filepath.Rel(dir, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return abs
+	}
+	return rel
+}
+
+// Path returns the path of a recorded output on disk
+func (ix *Index) Path(output Output) string {
+	return ix.abs(output.Path)
+}
+
+// abs returns the path of a recorded output on disk
+func (ix *Index) abs(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(ix.dir, path)
+}
+
+func (ix *Index) write(records []Record) error {
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode artifact index: %w", err)
+	}
+	if err := fileutil.WriteFileAtomic(filepath.Join(ix.dir, IndexFileName), data, 0644, false); err != nil {
+		return fmt.Errorf("failed to write artifact index: %w", err)
+	}
+	return nil
+}
+
+// Annotate fills in the source of the artifacts from the records of the
+// runs that wrote them, when the inventory could not tell
+func (ix *Index) Annotate(artifacts []Artifact) error {
+	records, err := ix.List()
+	if err != nil {
+		return err
+	}
+	for i := range artifacts {
+		record := ix.Lookup(records, artifacts[i].Path)
+		if record == nil {
+			continue
+		}
+		artifacts[i].Command = record.Command
+		if artifacts[i].Repo == "" {
+			artifacts[i].Repo = record.Inputs["repo"]
+		}
+		if artifacts[i].Ref == "" {
+			artifacts[i].Ref = record.Inputs["ref"]
+		}
+		if artifacts[i].File == "" {
+			artifacts[i].File = record.Inputs["file"]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package workdir
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"drivio/pkg/history"
+)
+
+// Artifact is an item of the work directory inventory
+type Artifact struct {
+	Type string `json:"type"`
+	// Path includes the directory listed, e.g. .drivio-work/fetched_file.yaml
+	Path string `json:"path"`
+	// Repo and Ref are the source of the artifact, empty when unknown
+	Repo string `json:"repo,omitempty"`
+	Ref  string `json:"ref,omitempty"`
+	// File is the path of the fetched file in the repository
+	File string `json:"file,omitempty"`
+	// Command is the command that wrote the artifact, from the artifact
+	// index (see Index.Annotate)
+	Command string `json:"command,omitempty"`
+	Size    int64  `json:"size"`
+	// Created is when drivio wrote the artifact: the fetch time for
+	// history entries, the modification time otherwise
+	Created time.Time `json:"created"`
+}
+
+// Inventory lists the artifacts of a work, cache or data directory. History
+// entries, archives, clones and release assets are listed one by one with
+// their source; the other entries as a whole. The source of fetched files is
+// looked up in fetches, the fetch history.
+func Inventory(dir string, fetches []history.Entry) ([]Artifact, error) {
+	entries, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var artifacts []Artifact
+	for _, entry := range entries {
+		switch entry.Type {
+		case TypeHistory:
+			stored, err := history.NewStore(dir).List()
+			if err != nil {
+				return nil, err
+			}
+			for _, fetch := range stored {
+				artifacts = append(artifacts, Artifact{
+					Type:    TypeHistory,
+					Path:    filepath.Join(dir, history.DirName, fetch.Object),
+					Repo:    fetch.Repo,
+					Ref:     fetch.Ref,
+					File:    fetch.File,
+					Size:    int64(fetch.Size),
+					Created: fetch.FetchedAt,
+				})
+			}
+		case TypeArchives:
+			// archives/<owner>/<repo>/<ref>
+			nested, err := subdirectories(entry.Path, 3)
+			if err != nil {
+				return nil, err
+			}
+			for _, archive := range nested {
+				parts := strings.Split(filepath.ToSlash(archive.rel), "/")
+				artifacts = append(artifacts, artifactOf(archive.entry, parts[0]+"/"+parts[1], parts[2]))
+			}
+		case TypeClones:
+			// clones/<owner>/<repo>[@<ref>][.git]; the ref comes from the
+			// artifact index, as branch names lose their slashes
+			nested, err := subdirectories(entry.Path, 2)
+			if err != nil {
+				return nil, err
+			}
+			for _, clone := range nested {
+				repo, _, _ := strings.Cut(strings.TrimSuffix(filepath.ToSlash(clone.rel), ".git"), "@")
+				artifacts = append(artifacts, artifactOf(clone.entry, repo, ""))
+			}
+		case TypeReleases:
+			// releases/<tag>
+			nested, err := subdirectories(entry.Path, 1)
+			if err != nil {
+				return nil, err
+			}
+			for _, release := range nested {
+				artifacts = append(artifacts, artifactOf(release.entry, "", release.rel))
+			}
+		case TypeFiles:
+			artifact := artifactOf(entry, "", "")
+			if fetch := latestFetch(fetches, entry.Name); fetch != nil {
+				artifact.Repo, artifact.Ref, artifact.File = fetch.Repo, fetch.Ref, fetch.File
+			}
+			artifacts = append(artifacts, artifact)
+		default:
+			artifacts = append(artifacts, artifactOf(entry, "", ""))
+		}
+	}
+	return artifacts, nil
+}
+
+func artifactOf(entry Entry, repo, ref string) Artifact {
+	return Artifact{
+		Type:    entry.Type,
+		Path:    entry.Path,
+		Repo:    repo,
+		Ref:     ref,
+		Size:    entry.Size,
+		Created: entry.ModTime,
+	}
+}
+
+// nestedEntry is a directory found by subdirectories, with its path relative
+// to the directory walked
+type nestedEntry struct {
+	rel   string
+	entry Entry
+}
+
+// subdirectories returns the directories found depth levels below dir,
+// measured like the entries of List
+func subdirectories(dir string, depth int) ([]nestedEntry, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, strings.Repeat("*/", depth-1)+"*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(matches)
+
+	var nested []nestedEntry
+	for _, match := range matches {
+		if info, err := os.Stat(match); err != nil || !info.IsDir() {
+			continue
+		}
+		entry, err := stat(match)
+		if err != nil {
+			return nil, err
+		}
+		entry.Type = Classify(filepath.Base(dir), true)
+		rel, _ := filepath.Rel(dir, match)
+		nested = append(nested, nestedEntry{rel: rel, entry: *entry})
+	}
+	return nested, nil
+}
+
+// latestFetch returns the most recent fetch written to the given fetched
+// file name, e.g. fetched_file.prod.yaml for the prod environment
+func latestFetch(fetches []history.Entry, name string) *history.Entry {
+	env := strings.TrimSuffix(strings.TrimPrefix(name, "fetched_file"), ".yaml")
+	env = strings.TrimPrefix(env, ".")
+	for i := len(fetches) - 1; i >= 0; i-- {
+		if fetches[i].Environment == env {
+			return &fetches[i]
+		}
+	}
+	return nil
+}
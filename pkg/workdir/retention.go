@@ -0,0 +1,78 @@
+package workdir
+
+import (
+	"sort"
+	"time"
+)
+
+// Limits bound the age and size of work directory entries. Zero values
+// mean no limit.
+type Limits struct {
+	MaxAge  time.Duration
+	MaxSize int64
+}
+
+// IsZero reports whether the limits don't restrict anything
+func (l Limits) IsZero() bool {
+	return l.MaxAge == 0 && l.MaxSize == 0
+}
+
+// Policy is a retention policy for the work directory. Its default max age
+// applies to the entries of every type without a max age of their own; its
+// default max size bounds the whole work directory, while the max size of a
+// type bounds the entries of that type.
+type Policy struct {
+	Limits
+	// Types holds the limits of each entry type (see Classify)
+	Types map[string]Limits
+}
+
+// IsZero reports whether the policy doesn't remove anything
+func (p Policy) IsZero() bool {
+	if !p.Limits.IsZero() {
+		return false
+	}
+	for _, limits := range p.Types {
+		if !limits.IsZero() {
+			return false
+		}
+	}
+	return true
+}
+
+// Select returns the entries the policy removes
+func (p Policy) Select(entries []Entry, now time.Time) []Entry {
+	byType := make(map[string][]Entry)
+	var types []string
+	for _, entry := range entries {
+		if _, ok := byType[entry.Type]; !ok {
+			types = append(types, entry.Type)
+		}
+		byType[entry.Type] = append(byType[entry.Type], entry)
+	}
+	sort.Strings(types)
+
+	var selected []Entry
+	for _, t := range types {
+		group := byType[t]
+		limits := p.Types[t]
+		maxAge := limits.MaxAge
+		if maxAge == 0 {
+			maxAge = p.MaxAge
+		}
+
+		var old []Entry
+		if maxAge > 0 {
+			old = OlderThan(group, maxAge, now)
+			selected = append(selected, old...)
+		}
+		if limits.MaxSize > 0 {
+			selected = append(selected, OverSize(Without(group, old), limits.MaxSize)...)
+		}
+	}
+
+	if p.MaxSize > 0 {
+		selected = append(selected, OverSize(Without(entries, selected), p.MaxSize)...)
+	}
+	return selected
+}
@@ -0,0 +1,184 @@
+package workdir
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// TrashDir is the directory of a work directory holding the entries removed
+// by clean until they expire, one subdirectory per clean run named after
+// its time, e.g. .trash/20250901T101203.000Z/release-notes-v1-v2.md. The
+// artifact index records of the entries are kept in the .drivio-index.json
+// file of the run, until restored with them.
+const TrashDir = ".trash"
+
+// trashTimeFormat names the subdirectories of the trash
+const trashTimeFormat = "20060102T150405.000Z"
+
+// TrashedEntry is an entry of the trash
+type TrashedEntry struct {
+	Entry
+	// TrashedAt is when the entry was moved to the trash
+	TrashedAt time.Time
+}
+
+// MoveToTrash moves an entry of dir to its trash, in the subdirectory of
+// the clean run started at now, along with its records of the artifact
+// index ix
+func MoveToTrash(dir string, entry Entry, now time.Time, ix *Index) error {
+	run := filepath.Join(dir, TrashDir, now.UTC().Format(trashTimeFormat))
+	if err := os.MkdirAll(run, 0755); err != nil {
+		return fmt.Errorf("failed to create trash directory: %w", err)
+	}
+	if err := os.Rename(entry.Path, filepath.Join(run, entry.Name)); err != nil {
+		return fmt.Errorf("failed to move %s to the trash: %w", entry.Path, err)
+	}
+
+	detached, err := ix.Detach(entry.Path)
+	if err != nil || len(detached) == 0 {
+		return err
+	}
+	trashIndex := NewIndex(run)
+	records, err := trashIndex.List()
+	if err != nil {
+		return err
+	}
+	return trashIndex.write(append(records, detached...))
+}
+
+// ListTrash returns the entries of the trash of dir, oldest first
+func ListTrash(dir string) ([]TrashedEntry, error) {
+	runs, err := os.ReadDir(filepath.Join(dir, TrashDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trash: %w", err)
+	}
+
+	var trashed []TrashedEntry
+	for _, run := range runs {
+		at, err := time.Parse(trashTimeFormat, run.Name())
+		if err != nil || !run.IsDir() {
+			continue
+		}
+		entries, err := List(filepath.Join(dir, TrashDir, run.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			trashed = append(trashed, TrashedEntry{Entry: entry, TrashedAt: at})
+		}
+	}
+	sort.SliceStable(trashed, func(i, j int) bool {
+		return trashed[i].TrashedAt.Before(trashed[j].TrashedAt)
+	})
+	return trashed, nil
+}
+
+// PurgeTrash permanently removes the entries of the trash of dir trashed
+// longer ago than window, returning them
+func PurgeTrash(dir string, window time.Duration, now time.Time) ([]TrashedEntry, error) {
+	trashed, err := ListTrash(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var purged []TrashedEntry
+	for _, entry := range trashed {
+		if now.Sub(entry.TrashedAt) <= window {
+			continue
+		}
+		if err := Purge(entry); err != nil {
+			return purged, err
+		}
+		purged = append(purged, entry)
+	}
+	return purged, nil
+}
+
+// TrashOver returns the entries of the trash of dir to purge, cleaned first,
+// for the trash to fit in size bytes
+func TrashOver(dir string, size int64) ([]TrashedEntry, error) {
+	trashed, err := ListTrash(dir)
+	if err != nil {
+		return nil, err
+	}
+	var total int64
+	for _, entry := range trashed {
+		total += entry.Size
+	}
+
+	var over []TrashedEntry
+	for _, entry := range trashed {
+		if total <= size {
+			break
+		}
+		over = append(over, entry)
+		total -= entry.Size
+	}
+	return over, nil
+}
+
+// Purge permanently removes an entry of the trash
+func Purge(entry TrashedEntry) error {
+	if err := os.RemoveAll(entry.Path); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", entry.Path, err)
+	}
+	removeEmptyDir(filepath.Dir(entry.Path))
+	return nil
+}
+
+// Restore moves an entry of the trash of dir back to its place, and its
+// records back to the artifact index ix. It fails when an entry with the
+// same name exists.
+func Restore(dir string, entry TrashedEntry, ix *Index) (string, error) {
+	target := filepath.Join(dir, entry.Name)
+	if _, err := os.Lstat(target); err == nil {
+		return "", fmt.Errorf("cannot restore %s: %s already exists", entry.Name, target)
+	}
+	if err := os.Rename(entry.Path, target); err != nil {
+		return "", fmt.Errorf("failed to restore %s: %w", entry.Name, err)
+	}
+
+	run := filepath.Dir(entry.Path)
+	trashIndex := NewIndex(run)
+	records, err := trashIndex.List()
+	if err == nil && len(records) > 0 {
+		// The records are those of ix, with their paths before the clean
+		var restored, kept []Record
+		for _, record := range records {
+			if ix.under(record.Outputs[0], []string{target}) {
+				restored = append(restored, record)
+			} else {
+				kept = append(kept, record)
+			}
+		}
+		if err = ix.Attach(restored); err == nil && len(restored) > 0 {
+			if len(kept) == 0 {
+				err = os.Remove(filepath.Join(run, IndexFileName))
+			} else {
+				err = trashIndex.write(kept)
+			}
+		}
+	}
+	removeEmptyDir(run)
+	if err != nil {
+		return target, fmt.Errorf("restored %s, but not its artifact index records: %w", entry.Name, err)
+	}
+	return target, nil
+}
+
+// removeEmptyDir removes a trash run once its last entry is gone, with the
+// records left, and the trash once its last run is
+func removeEmptyDir(run string) {
+	if entries, err := os.ReadDir(run); err == nil && len(entries) == 1 && entries[0].Name() == IndexFileName {
+		os.Remove(filepath.Join(run, IndexFileName))
+	}
+	if os.Remove(run) == nil {
+		os.Remove(filepath.Dir(run))
+	}
+}
@@ -0,0 +1,131 @@
+package workdir
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"drivio/pkg/history"
+)
+
+// Directories of the work directory holding each kind of artifact
+const (
+	ArchivesDir   = "archives"
+	ArtifactsDir  = "artifacts"
+	ClonesDir     = "clones"
+	GroupsDir     = "groups"
+	ManifestDir   = "manifest"
+	PromotionsDir = "promotions"
+	ReleasesDir   = "releases"
+)
+
+// Types of work directory entries, as accepted by clean --only
+const (
+	TypeArchives   = "archives"
+	TypeArtifacts  = "artifacts"
+	TypeClones     = "clones"
+	TypeFiles      = "files"
+	TypeGroups     = "groups"
+	TypeHistory    = "history"
+	TypeManifest   = "manifest"
+	TypeNotes      = "notes"
+	TypePromotions = "promotions"
+	TypeReleases   = "releases"
+	TypeOther      = "other"
+)
+
+// typeDirs maps the directories of the work directory to their type
+var typeDirs = map[string]string{
+	ArchivesDir:     TypeArchives,
+	ArtifactsDir:    TypeArtifacts,
+	ClonesDir:       TypeClones,
+	GroupsDir:       TypeGroups,
+	history.DirName: TypeHistory,
+	ManifestDir:     TypeManifest,
+	PromotionsDir:   TypePromotions,
+	ReleasesDir:     TypeReleases,
+}
+
+// Types returns the known entry types
+func Types() []string {
+	types := []string{TypeFiles, TypeNotes, TypeOther}
+	for _, t := range typeDirs {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// CheckTypes returns an error naming the first unknown type
+func CheckTypes(types []string) error {
+	known := Types()
+	for _, t := range types {
+		t = strings.ToLower(t)
+		if i := sort.SearchStrings(known, t); i == len(known) || known[i] != t {
+			return fmt.Errorf("unknown type %q (available: %s)", t, strings.Join(known, ", "))
+		}
+	}
+	return nil
+}
+
+// Classify returns the type of a top-level entry of the work directory
+func Classify(name string, isDir bool) string {
+	if isDir {
+		if t, ok := typeDirs[name]; ok {
+			return t
+		}
+		return TypeOther
+	}
+	switch {
+	case strings.HasPrefix(name, "release-notes-") && isNotesExtension(filepath.Ext(name)):
+		return TypeNotes
+	case strings.HasPrefix(name, "fetched_file."):
+		return TypeFiles
+	}
+	return TypeOther
+}
+
+// Filter returns the entries of one of the types whose name matches one of
+// the glob patterns. Empty types or patterns match every entry.
+func Filter(entries []Entry, types, patterns []string) ([]Entry, error) {
+	if err := CheckTypes(types); err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool, len(types))
+	for _, t := range types {
+		wanted[strings.ToLower(t)] = true
+	}
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	var filtered []Entry
+	for _, entry := range entries {
+		if len(wanted) > 0 && !wanted[entry.Type] {
+			continue
+		}
+		if len(patterns) > 0 && !matchesAny(entry.Name, patterns) {
+			continue
+		}
+		filtered = append(filtered, entry)
+	}
+	return filtered, nil
+}
+
+func matchesAny(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isNotesExtension reports whether ext is the extension of a release notes
+// format: markdown, JSON or text
+func isNotesExtension(ext string) bool {
+	return ext == ".md" || ext == ".json" || ext == ".txt"
+}
@@ -0,0 +1,194 @@
+package workdir
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"drivio/pkg/audit"
+	"drivio/pkg/lock"
+)
+
+// Entry is a top-level entry of the work directory
+type Entry struct {
+	Name  string
+	Path  string
+	IsDir bool
+	// Type is the kind of artifact, e.g. notes or archives (see Classify)
+	Type string
+	// Size is the size of the file, or the total size of the files in the
+	// directory
+	Size int64
+	// ModTime is the modification time of the file, or the most recent one
+	// of the files in the directory
+	ModTime time.Time
+}
+
+// List returns the top-level entries of the work directory, leaving out the
+// lock file, the artifact index, the audit log and the trash of drivio
+func List(dir string) ([]Entry, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read work directory: %w", err)
+	}
+
+	var entries []Entry
+	for _, dirEntry := range dirEntries {
+		switch dirEntry.Name() {
+		case lock.FileName, IndexFileName, audit.FileName, TrashDir:
+			continue
+		}
+		entry, err := stat(filepath.Join(dir, dirEntry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *entry)
+	}
+	return entries, nil
+}
+
+// stat measures an entry, walking directories
+func stat(path string) (*Entry, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	entry := &Entry{
+		Name:    filepath.Base(path),
+		Path:    path,
+		IsDir:   info.IsDir(),
+		Type:    Classify(filepath.Base(path), info.IsDir()),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	}
+	if !entry.IsDir {
+		return entry, nil
+	}
+
+	entry.Size = 0
+	err = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			entry.Size += info.Size()
+		}
+		if info.ModTime().After(entry.ModTime) {
+			entry.ModTime = info.ModTime()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %w", path, err)
+	}
+	return entry, nil
+}
+
+// OlderThan returns the entries last modified more than age ago
+func OlderThan(entries []Entry, age time.Duration, now time.Time) []Entry {
+	var old []Entry
+	for _, entry := range entries {
+		if now.Sub(entry.ModTime) > age {
+			old = append(old, entry)
+		}
+	}
+	return old
+}
+
+// OverSize returns the oldest entries to remove for the total size of the
+// entries to fit in maxSize bytes
+func OverSize(entries []Entry, maxSize int64) []Entry {
+	var total int64
+	for _, entry := range entries {
+		total += entry.Size
+	}
+
+	oldestFirst := make([]Entry, len(entries))
+	copy(oldestFirst, entries)
+	sort.SliceStable(oldestFirst, func(i, j int) bool {
+		return oldestFirst[i].ModTime.Before(oldestFirst[j].ModTime)
+	})
+
+	var over []Entry
+	for _, entry := range oldestFirst {
+		if total <= maxSize {
+			break
+		}
+		over = append(over, entry)
+		total -= entry.Size
+	}
+	return over
+}
+
+// Without returns the entries that are not in removed
+func Without(entries, removed []Entry) []Entry {
+	skip := make(map[string]bool, len(removed))
+	for _, entry := range removed {
+		skip[entry.Path] = true
+	}
+	var kept []Entry
+	for _, entry := range entries {
+		if !skip[entry.Path] {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// ParseSize parses a size such as 500MB, 2GiB or 1024. Units are powers of
+// 1024 with or without the i, as printed by drivio.
+func ParseSize(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		size   int64
+	}{
+		{"TIB", 1 << 40}, {"GIB", 1 << 30}, {"MIB", 1 << 20}, {"KIB", 1 << 10},
+		{"TB", 1 << 40}, {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10},
+		{"T", 1 << 40}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10},
+		{"B", 1},
+	}
+
+	number, unit := strings.ToUpper(strings.TrimSpace(s)), int64(1)
+	for _, u := range units {
+		if trimmed, ok := strings.CutSuffix(number, u.suffix); ok {
+			number, unit = strings.TrimSpace(trimmed), u.size
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q: use a number followed by B, KB, MB, GB or TB", s)
+	}
+	return int64(n * float64(unit)), nil
+}
+
+// ParseAge parses an age such as 7d, 2w or 36h. Days (d) and weeks (w) are
+// accepted on top of the units of time.ParseDuration.
+func ParseAge(s string) (time.Duration, error) {
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if number, ok := strings.CutSuffix(s, suffix); ok {
+			n, err := strconv.ParseFloat(number, 64)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid age %q", s)
+			}
+			return time.Duration(n * float64(unit)), nil
+		}
+	}
+
+	age, err := time.ParseDuration(s)
+	if err != nil || age < 0 {
+		return 0, fmt.Errorf("invalid age %q: use a number followed by s, m, h, d or w", s)
+	}
+	return age, nil
+}
@@ -0,0 +1,33 @@
+package workdir
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// appName is the name of drivio's directories in the XDG base directories
+const appName = "drivio"
+
+// XDGCacheDir returns the cache directory of drivio: $XDG_CACHE_HOME/drivio,
+// or the platform's user cache directory, e.g. ~/.cache/drivio
+func XDGCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate the cache directory: %w", err)
+	}
+	return filepath.Join(dir, appName), nil
+}
+
+// XDGDataDir returns the data directory of drivio: $XDG_DATA_HOME/drivio,
+// or ~/.local/share/drivio
+func XDGDataDir() (string, error) {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, appName), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate the data directory: %w", err)
+	}
+	return filepath.Join(home, ".local", "share", appName), nil
+}